@@ -0,0 +1,227 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package aasclient
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	commLog "intel/isecl/lib/common/v4/log"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/truststore"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var log = commLog.GetDefaultLogger()
+
+const (
+	// tokenLifetime is assumed for the tokens without an expiry
+	tokenLifetime = 30 * time.Minute
+	// retryInterval spaces the background renewals that failed
+	retryInterval = 30 * time.Second
+)
+
+var (
+	defaultMu     sync.RWMutex
+	defaultClient *Client
+)
+
+// Default returns the client of the bearer tokens of the outbound calls of SQVS, nil when no service
+// account is configured
+func Default() *Client {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultClient
+}
+
+// SetDefault replaces the client of the bearer tokens of the outbound calls of SQVS
+func SetDefault(c *Client) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultClient = c
+}
+
+// Authorize sets the bearer token of the default client on the request, it leaves the request untouched
+// when no service account is configured
+func Authorize(req *http.Request) error {
+	return Default().Authorize(req)
+}
+
+// Client obtains the bearer tokens of the service account of SQVS from AAS and caches them, a token is
+// renewed renewBefore its expiry so outbound calls never carry an expired token
+type Client struct {
+	tokenURL    string
+	username    string
+	password    string
+	httpClient  *http.Client
+	renewBefore time.Duration
+	now         func() time.Time
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// New creates the client of the service account username, whose tokens are requested from the AAS at
+// aasBaseURL with httpClient
+func New(aasBaseURL, username, password string, httpClient *http.Client, renewBefore time.Duration) *Client {
+	if renewBefore <= 0 {
+		renewBefore = constants.DefaultTokenRenewBefore
+	}
+	return &Client{
+		tokenURL:    strings.TrimSuffix(aasBaseURL, "/") + "/token",
+		username:    username,
+		password:    password,
+		httpClient:  httpClient,
+		renewBefore: renewBefore,
+		now:         time.Now,
+	}
+}
+
+// FromConfig creates the client of the service account of the configuration, nil when none is configured
+func FromConfig(conf *config.Configuration) (*Client, error) {
+	if conf.ServiceUsername == "" {
+		return nil, nil
+	}
+	password, err := ioutil.ReadFile(conf.ServicePasswordFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "aasclient/aasclient:FromConfig() Error reading service account password")
+	}
+	httpClient, err := truststore.HTTPClient(constants.TrustedCAsStoreDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "aasclient/aasclient:FromConfig() Error in getting client object")
+	}
+	return New(conf.AuthServiceURL, conf.ServiceUsername, strings.TrimSpace(string(password)), httpClient,
+		conf.TokenRenewBefore), nil
+}
+
+// Token returns the cached token, a new one when it is missing or about to expire
+func (c *Client) Token() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && c.now().Before(c.expiry.Add(-c.renewBefore)) {
+		return c.token, nil
+	}
+	token, expiry, err := c.requestToken()
+	if err != nil {
+		// keep using the cached token until it actually expires
+		if c.token != "" && c.now().Before(c.expiry) {
+			log.WithError(err).Warn("aasclient/aasclient:Token() Error renewing token, using the cached one")
+			return c.token, nil
+		}
+		return "", err
+	}
+	c.token, c.expiry = token, expiry
+	return token, nil
+}
+
+// Invalidate drops the cached token, after a service rejected it
+func (c *Client) Invalidate() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = ""
+}
+
+// Authorize sets the bearer token on the request, a nil client leaves the request untouched
+func (c *Client) Authorize(req *http.Request) error {
+	if c == nil {
+		return nil
+	}
+	token, err := c.Token()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// KeepFresh renews the token ahead of its expiry until stop is closed, so calls do not wait on AAS
+func (c *Client) KeepFresh(stop <-chan struct{}) {
+	for {
+		wait := retryInterval
+		if _, err := c.Token(); err != nil {
+			log.WithError(err).Error("aasclient/aasclient:KeepFresh() Error renewing token")
+		} else {
+			c.mu.Lock()
+			wait = c.expiry.Add(-c.renewBefore).Sub(c.now())
+			c.mu.Unlock()
+			if wait < time.Second {
+				wait = time.Second
+			}
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (c *Client) requestToken() (string, time.Time, error) {
+	body, err := json.Marshal(map[string]string{"username": c.username, "password": c.password})
+	if err != nil {
+		return "", time.Time{}, errors.Wrap(err, "aasclient/aasclient:requestToken() Error encoding token request")
+	}
+	req, err := http.NewRequest(http.MethodPost, c.tokenURL, bytes.NewReader(body))
+	if err != nil {
+		return "", time.Time{}, errors.Wrap(err, "aasclient/aasclient:requestToken() Error creating token request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/jwt")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, errors.Wrap(err, "aasclient/aasclient:requestToken() Error requesting token from AAS")
+	}
+	defer func() {
+		derr := resp.Body.Close()
+		if derr != nil {
+			log.WithError(derr).Error("Error closing AAS response")
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, errors.Errorf("aasclient/aasclient:requestToken() AAS returned status code %d",
+			resp.StatusCode)
+	}
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", time.Time{}, errors.Wrap(err, "aasclient/aasclient:requestToken() Error reading token")
+	}
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return "", time.Time{}, errors.New("aasclient/aasclient:requestToken() AAS returned an empty token")
+	}
+	log.Info("aasclient/aasclient:requestToken() Obtained bearer token from AAS")
+	return token, expiryOf(token, c.now()), nil
+}
+
+// expiryOf reads the expiry of the JWT, the token is not verified as SQVS is not its audience. Tokens
+// without an expiry are renewed after tokenLifetime.
+func expiryOf(token string, now time.Time) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) == 3 {
+		payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+		if err == nil {
+			var claims struct {
+				ExpiresAt int64 `json:"exp"`
+			}
+			if json.Unmarshal(payload, &claims) == nil && claims.ExpiresAt > 0 {
+				return time.Unix(claims.ExpiresAt, 0)
+			}
+		}
+	}
+	return now.Add(tokenLifetime)
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package aasclient
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testToken(n int, expiry time.Time) string {
+	payload, _ := json.Marshal(map[string]interface{}{"sub": "sqvs", "exp": expiry.Unix(), "n": n})
+	return "eyJhbGciOiJSUzM4NCJ9." + base64.RawURLEncoding.EncodeToString(payload) + ".c2lnbmF0dXJl"
+}
+
+func TestTokenRenewal(t *testing.T) {
+	now := time.Date(2021, 6, 15, 10, 0, 0, 0, time.UTC)
+	issued := 0
+	fail := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var credentials map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&credentials))
+		if fail || credentials["password"] != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		issued++
+		fmt.Fprint(w, testToken(issued, now.Add(time.Hour)))
+	}))
+	defer server.Close()
+
+	client := New(server.URL+"/aas/v1/", "sqvs", "secret", server.Client(), 10*time.Minute)
+	client.now = func() time.Time { return now }
+
+	token, err := client.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, testToken(1, now.Add(time.Hour)), token)
+	token, err = client.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, issued)
+
+	// the token is renewed before it expires
+	now = now.Add(55 * time.Minute)
+	token, err = client.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, issued)
+
+	// the cached token is used while it is valid when AAS fails
+	client.Invalidate()
+	_, err = client.Token()
+	assert.NoError(t, err)
+	fail = true
+	client.expiry = now.Add(5 * time.Minute)
+	cached, err := client.Token()
+	assert.NoError(t, err)
+	now = now.Add(6 * time.Minute)
+	_, err = client.Token()
+	assert.Error(t, err)
+	assert.NotEmpty(t, cached)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	assert.NoError(t, (*Client)(nil).Authorize(req))
+	assert.Empty(t, req.Header.Get("Authorization"))
+}
+
+func TestExpiryOf(t *testing.T) {
+	now := time.Unix(1623751200, 0)
+	assert.Equal(t, now.Add(time.Hour), expiryOf(testToken(1, now.Add(time.Hour)), now))
+	assert.Equal(t, now.Add(tokenLifetime), expiryOf("opaque-token", now))
+}
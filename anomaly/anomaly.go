@@ -0,0 +1,177 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package anomaly
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// FailedVerifications flags a caller whose verifications keep failing, a caller probing the verifier
+	FailedVerifications = "repeated-failed-verifications"
+	// DebugEnclaveSurge flags a surge of quotes of enclaves running in DEBUG mode
+	DebugEnclaveSurge = "debug-enclave-surge"
+	// DistinctMeasurements flags a tenant verifying many different enclaves, a tenant trying enclaves out
+	DistinctMeasurements = "distinct-enclave-measurements"
+)
+
+// Thresholds are the number of events within Window an anomaly is flagged at, a zero threshold disables its
+// heuristic
+type Thresholds struct {
+	Window               time.Duration
+	FailedVerifications  int
+	DebugQuotes          int
+	DistinctMeasurements int
+}
+
+// Observation is the outcome of a quote verification
+type Observation struct {
+	Time        time.Time
+	Caller      string
+	Tenant      string
+	Measurement string
+	Failed      bool
+	Debug       bool
+}
+
+// Anomaly is a quote pattern that reached the threshold of a heuristic, Subject is the caller, the tenant or
+// empty for the debug enclave surge
+type Anomaly struct {
+	Type      string    `json:"type"`
+	Subject   string    `json:"subject,omitempty"`
+	Count     int       `json:"count"`
+	Threshold int       `json:"threshold"`
+	Window    string    `json:"window"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// Detector counts the observations within a sliding window. Once flagged, an anomaly of the same subject is
+// not flagged again for a window, so a single incident does not flood the SOC with events.
+type Detector struct {
+	thresholds Thresholds
+
+	mu           sync.Mutex
+	failures     map[string][]time.Time
+	debugQuotes  []time.Time
+	measurements map[string]map[string]time.Time
+	flagged      map[string]time.Time
+	lastSweep    time.Time
+}
+
+func NewDetector(thresholds Thresholds) *Detector {
+	return &Detector{
+		thresholds:   thresholds,
+		failures:     map[string][]time.Time{},
+		measurements: map[string]map[string]time.Time{},
+		flagged:      map[string]time.Time{},
+	}
+}
+
+// Observe counts the observation and returns the anomalies it makes reach their threshold
+func (d *Detector) Observe(o Observation) []Anomaly {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	since := o.Time.Add(-d.thresholds.Window)
+	d.sweep(o.Time, since)
+
+	var anomalies []Anomaly
+	if d.thresholds.FailedVerifications > 0 && o.Failed && o.Caller != "" {
+		failures := append(prune(d.failures[o.Caller], since), o.Time)
+		d.failures[o.Caller] = failures
+		if len(failures) >= d.thresholds.FailedVerifications {
+			anomalies = d.flag(anomalies, FailedVerifications, o.Caller, len(failures),
+				d.thresholds.FailedVerifications, failures[0], o.Time)
+		}
+	}
+	if d.thresholds.DebugQuotes > 0 && o.Debug {
+		d.debugQuotes = append(prune(d.debugQuotes, since), o.Time)
+		if len(d.debugQuotes) >= d.thresholds.DebugQuotes {
+			anomalies = d.flag(anomalies, DebugEnclaveSurge, "", len(d.debugQuotes), d.thresholds.DebugQuotes,
+				d.debugQuotes[0], o.Time)
+		}
+	}
+	if d.thresholds.DistinctMeasurements > 0 && o.Measurement != "" && o.Tenant != "" {
+		seen := d.measurements[o.Tenant]
+		if seen == nil {
+			seen = map[string]time.Time{}
+			d.measurements[o.Tenant] = seen
+		}
+		seen[o.Measurement] = o.Time
+		first := o.Time
+		for measurement, at := range seen {
+			if at.Before(since) {
+				delete(seen, measurement)
+			} else if at.Before(first) {
+				first = at
+			}
+		}
+		if len(seen) >= d.thresholds.DistinctMeasurements {
+			anomalies = d.flag(anomalies, DistinctMeasurements, o.Tenant, len(seen),
+				d.thresholds.DistinctMeasurements, first, o.Time)
+		}
+	}
+	return anomalies
+}
+
+// flag appends the anomaly unless it was flagged within the window
+func (d *Detector) flag(anomalies []Anomaly, anomalyType, subject string, count, threshold int,
+	firstSeen, lastSeen time.Time) []Anomaly {
+	key := anomalyType + "\x00" + subject
+	if at, ok := d.flagged[key]; ok && lastSeen.Sub(at) < d.thresholds.Window {
+		return anomalies
+	}
+	d.flagged[key] = lastSeen
+	return append(anomalies, Anomaly{
+		Type:      anomalyType,
+		Subject:   subject,
+		Count:     count,
+		Threshold: threshold,
+		Window:    d.thresholds.Window.String(),
+		FirstSeen: firstSeen,
+		LastSeen:  lastSeen,
+	})
+}
+
+// sweep drops the callers and tenants without observations within the window, at most once per window
+func (d *Detector) sweep(now, since time.Time) {
+	if now.Sub(d.lastSweep) < d.thresholds.Window {
+		return
+	}
+	d.lastSweep = now
+	for caller, failures := range d.failures {
+		if failures = prune(failures, since); len(failures) == 0 {
+			delete(d.failures, caller)
+		} else {
+			d.failures[caller] = failures
+		}
+	}
+	for tenant, seen := range d.measurements {
+		for measurement, at := range seen {
+			if at.Before(since) {
+				delete(seen, measurement)
+			}
+		}
+		if len(seen) == 0 {
+			delete(d.measurements, tenant)
+		}
+	}
+	for key, at := range d.flagged {
+		if at.Before(since) {
+			delete(d.flagged, key)
+		}
+	}
+}
+
+// prune drops the times before since, times are in ascending order
+func prune(times []time.Time, since time.Time) []time.Time {
+	i := 0
+	for i < len(times) && times[i].Before(since) {
+		i++
+	}
+	return times[i:]
+}
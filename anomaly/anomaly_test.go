@@ -0,0 +1,65 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package anomaly
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailedVerifications(t *testing.T) {
+	d := NewDetector(Thresholds{Window: time.Minute, FailedVerifications: 3})
+	now := time.Date(2021, 6, 15, 10, 0, 0, 0, time.UTC)
+
+	assert.Empty(t, d.Observe(Observation{Time: now, Caller: "10.0.0.1", Failed: true}))
+	assert.Empty(t, d.Observe(Observation{Time: now.Add(10 * time.Second), Caller: "10.0.0.1", Failed: true}))
+	assert.Empty(t, d.Observe(Observation{Time: now.Add(20 * time.Second), Caller: "10.0.0.2", Failed: true}))
+	assert.Empty(t, d.Observe(Observation{Time: now.Add(30 * time.Second), Caller: "10.0.0.1"}))
+	anomalies := d.Observe(Observation{Time: now.Add(40 * time.Second), Caller: "10.0.0.1", Failed: true})
+	assert.Equal(t, []Anomaly{{Type: FailedVerifications, Subject: "10.0.0.1", Count: 3, Threshold: 3,
+		Window: "1m0s", FirstSeen: now, LastSeen: now.Add(40 * time.Second)}}, anomalies)
+
+	// the anomaly is not flagged again within the window
+	assert.Empty(t, d.Observe(Observation{Time: now.Add(50 * time.Second), Caller: "10.0.0.1", Failed: true}))
+	// failures outside of the window are not counted
+	assert.Empty(t, d.Observe(Observation{Time: now.Add(3 * time.Minute), Caller: "10.0.0.1", Failed: true}))
+}
+
+func TestDebugEnclaveSurge(t *testing.T) {
+	d := NewDetector(Thresholds{Window: time.Minute, DebugQuotes: 2})
+	now := time.Date(2021, 6, 15, 10, 0, 0, 0, time.UTC)
+
+	assert.Empty(t, d.Observe(Observation{Time: now, Caller: "a", Debug: true}))
+	assert.Empty(t, d.Observe(Observation{Time: now.Add(2 * time.Minute), Caller: "b", Debug: true}))
+	anomalies := d.Observe(Observation{Time: now.Add(150 * time.Second), Caller: "c", Debug: true})
+	assert.Len(t, anomalies, 1)
+	assert.Equal(t, DebugEnclaveSurge, anomalies[0].Type)
+	assert.Equal(t, 2, anomalies[0].Count)
+}
+
+func TestDistinctMeasurements(t *testing.T) {
+	d := NewDetector(Thresholds{Window: time.Hour, DistinctMeasurements: 3})
+	now := time.Date(2021, 6, 15, 10, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		assert.Empty(t, d.Observe(Observation{Time: now, Tenant: "acme", Measurement: "mrenclave-1"}))
+	}
+	assert.Empty(t, d.Observe(Observation{Time: now, Tenant: "acme", Measurement: "mrenclave-2"}))
+	assert.Empty(t, d.Observe(Observation{Time: now, Tenant: "other", Measurement: "mrenclave-3"}))
+	anomalies := d.Observe(Observation{Time: now.Add(time.Minute), Tenant: "acme", Measurement: "mrenclave-3"})
+	assert.Len(t, anomalies, 1)
+	assert.Equal(t, "acme", anomalies[0].Subject)
+	assert.Equal(t, now, anomalies[0].FirstSeen)
+
+	// disabled heuristics flag nothing
+	d = NewDetector(Thresholds{Window: time.Hour})
+	for i := 0; i < 10; i++ {
+		assert.Empty(t, d.Observe(Observation{Time: now, Caller: "a", Tenant: "acme", Failed: true, Debug: true,
+			Measurement: strconv.Itoa(i)}))
+	}
+}
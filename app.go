@@ -6,40 +6,38 @@ package main
 
 import (
 	"context"
-	"crypto/tls"
-	"crypto/x509"
 	"crypto/x509/pkix"
 	"flag"
 	"fmt"
-	"intel/isecl/lib/common/v4/crypt"
 	e "intel/isecl/lib/common/v4/exec"
 	commLog "intel/isecl/lib/common/v4/log"
 	commLogMsg "intel/isecl/lib/common/v4/log/message"
 	commLogInt "intel/isecl/lib/common/v4/log/setup"
-	"intel/isecl/lib/common/v4/middleware"
 	cos "intel/isecl/lib/common/v4/os"
 	"intel/isecl/lib/common/v4/setup"
+	"intel/isecl/sqvs/v4/bundle"
 	"intel/isecl/sqvs/v4/config"
 	"intel/isecl/sqvs/v4/constants"
-	"intel/isecl/sqvs/v4/resource"
+	"intel/isecl/sqvs/v4/logformat"
+	_ "intel/isecl/sqvs/v4/repository/memory"
+	_ "intel/isecl/sqvs/v4/repository/postgres"
+	_ "intel/isecl/sqvs/v4/repository/sqlite"
+	"intel/isecl/sqvs/v4/server"
 	"intel/isecl/sqvs/v4/tasks"
 	"intel/isecl/sqvs/v4/version"
 	"io"
-	"io/ioutil"
-	stdlog "log"
-	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
 	"os/user"
+	"path"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
-	"github.com/gorilla/handlers"
-	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 )
 
 type App struct {
@@ -54,6 +52,10 @@ type App struct {
 	LogWriter      io.Writer
 	HTTPLogWriter  io.Writer
 	SecLogWriter   io.Writer
+
+	outputFormat string
+	// configBundle is the version of the signed configuration bundle installed at startup
+	configBundle string
 }
 
 func (a *App) printUsage() {
@@ -63,15 +65,55 @@ func (a *App) printUsage() {
 	fmt.Fprintln(w, "    sqvs <command> [arguments]")
 	fmt.Fprintln(w, "")
 	fmt.Fprintln(w, "Available Commands:")
+	fmt.Fprintln(w, "    bench --quotes=<dir> [--concurrency=N] [--duration=60s] [--url=<svs url>]	Verify the quotes of the directory, in process or with the SQVS at the URL, and report the throughput and latency percentiles")
+	fmt.Fprintln(w, "    bootstrap [--listen=127.0.0.1:12001]	Serve the one-time bootstrap API on a loopback address until a POST /bootstrap request with the printed bootstrap token and a JSON body carrying the CMS root CA, AAS URL and bearer token completes setup, returns at once when setup is already completed")
+	fmt.Fprintln(w, "    completion bash|zsh	Generate the shell completion script")
+	fmt.Fprintln(w, "    config show [--effective]	Show config.yml or, with --effective, the configuration once overridden by the SVS_ environment variables")
+	fmt.Fprintln(w, "    conformance run --corpus=<dir> [--report=<file>]	Verify the test vectors of the corpus in process against their own collateral and report whether each outcome matches the reference one")
+	fmt.Fprintln(w, "    crl import <file> [--issuer-chain=<pem file>]	Import a PCK CRL used instead of fetching the CRL of its CA until it expires")
+	fmt.Fprintln(w, "    enclaves import [--description=<text>] <file>...|list	Register the enclaves signed by SIGSTRUCT files or signed enclave binaries, MRENCLAVE, MRSIGNER, ISVPRODID, ISVSVN and attributes, or list the registered enclaves, sqvs must be stopped to import with the memory storage driver")
 	fmt.Fprintln(w, "    help|-h|--help		Show this help message")
+	fmt.Fprintln(w, "    history prune --before=<date>	Export and delete the verification history recorded before the date, sqvs must be stopped with the memory storage driver")
+	fmt.Fprintln(w, "    install [--force] [--no-systemd]	Install sqvs, its user, directories, systemd unit and log rotation configuration, --force overwrites the unit and log rotation files")
+	fmt.Fprintln(w, "    migrate up|down|status [--to=<version>]	Upgrade, downgrade or show the schema version of the SQVS database, sqvs only starts once it is at the latest version")
+	fmt.Fprintln(w, "    maintenance on [--message=<message>]|off|status	Turn maintenance mode on or off, verification requests are rejected with 503 while on")
 	fmt.Fprintln(w, "    setup [task]		Run setup task")
 	fmt.Fprintln(w, "    start			Start sqvs")
 	fmt.Fprintln(w, "    status			Show the status of sqvs")
 	fmt.Fprintln(w, "    stop			Stop sqvs")
+	fmt.Fprintln(w, "    tlscertsha384		Show the SHA384 digest of the TLS certificate")
 	fmt.Fprintln(w, "    uninstall [--purge]	Uninstall SQVS. --purge option needs to be applied to remove configuration and data files")
+	fmt.Fprintln(w, "    upgrade			Hand the listening socket of the running sqvs over to the installed sqvs binary, which serves before the running one drains its requests and exits, refused while the SQVS store of the memory driver is in use")
 	fmt.Fprintln(w, "    version|-v|--version	Show the version of sqvs")
 	fmt.Fprintln(w, "")
-	fmt.Fprintln(w, "Setup command usage:     sqvs setup [task] [--arguments=<argument_value>] [--force]")
+	fmt.Fprintln(w, "Exit codes:")
+	fmt.Fprintln(w, "    0	Success")
+	fmt.Fprintln(w, "    1	Failure")
+	fmt.Fprintln(w, "    2	Invalid configuration, environment variables or setup answers")
+	fmt.Fprintln(w, "    3	A dependency, CMS, AAS, SCS, the SQVS store, the key store or the trusted time source, is unavailable")
+	fmt.Fprintln(w, "    4	Setup has not been completed, config.yml, the TLS certificate or the trusted SGX root CA is missing")
+	fmt.Fprintln(w, "    5	A setup task failed")
+	fmt.Fprintln(w, "    64	Invalid command or arguments")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Global flags:")
+	fmt.Fprintln(w, "    --output=text|json		Output format of the bench, config, conformance, crl, history, maintenance, migrate, status, tlscertsha384 and version commands")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Runtime configuration:   every config.yml field is overridden, when sqvs runs, by the SVS_ environment variable named after")
+	fmt.Fprintln(w, "                         its path in upper snake case, e.g. SVS_PORT, SVS_LOG_LEVEL, SVS_QUOTA_TENANT_CLAIM or SVS_OUTBOUND_MAX_ATTEMPTS.")
+	fmt.Fprintln(w, "                         Durations are Go durations such as 30s and lists are comma separated")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Configuration bundles:   when /etc/sqvs/config-bundle-pub-key.pem holds the operator public key, sqvs run requires")
+	fmt.Fprintln(w, "                         /etc/sqvs/config-bundle.tar.gz, signed with SHA-384 in config-bundle.tar.gz.sig, and installs its")
+	fmt.Fprintln(w, "                         config.yml, custom-claims.yml and trust stores. Tampered bundles are refused, the version of the")
+	fmt.Fprintln(w, "                         manifest.yml of the bundle is logged in every audit record")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Setup command usage:     sqvs setup [task] [--arguments=<argument_value>] [--file=<answers.yml>] [--force]")
+	fmt.Fprintln(w, "                         - Option [--file] reads the env variables of the tasks from a YAML answers file, env variables")
+	fmt.Fprintln(w, "                           already set take precedence. Variables are listed at the top level or grouped by task name")
+	fmt.Fprintln(w, "                         - Tasks already completed are skipped unless [--force] is given, a summary of the tasks")
+	fmt.Fprintln(w, "                           changed, unchanged and skipped and the time each took is printed at the end (in JSON with --output=json)")
+	fmt.Fprintln(w, "                         - Tasks run once the tasks they depend on succeeded, independent tasks in parallel:")
+	fmt.Fprintln(w, "                           download_cert after download_ca_cert, create_signing_key_pair after download_ca_cert and update_service_config")
 	fmt.Fprintln(w, "")
 	fmt.Fprintln(w, "Available Tasks for setup:")
 	fmt.Fprintln(w, "                              Required env variables:")
@@ -87,10 +129,161 @@ func (a *App) printUsage() {
 	fmt.Fprintln(w, "                                 - SQVS_SERVER_WRITE_TIMEOUT                         : SGX Verification Service Request Write Timeout Duration")
 	fmt.Fprintln(w, "                                 - SQVS_SERVER_IDLE_TIMEOUT                          : SGX Verification Service Request Idle Timeout")
 	fmt.Fprintln(w, "                                 - SQVS_SERVER_MAX_HEADER_BYTES                      : SGX Verification Service Max Length Of Request Header Bytes")
+	fmt.Fprintln(w, "                                 - SQVS_MAX_CONCURRENT_REQUESTS                      : Maximum number of verification requests processed concurrently, 0 disables the limit (default 100)")
+	fmt.Fprintln(w, "                                 - SQVS_MAX_QUEUED_REQUESTS                          : Maximum number of verification requests waiting to be processed, 0 rejects the requests beyond SQVS_MAX_CONCURRENT_REQUESTS at once and -1 queues them all (default 200)")
+	fmt.Fprintln(w, "                                 - SQVS_MAX_QUEUE_WAIT                               : Maximum time a verification request waits to be processed before it is rejected with 503 (default 5s)")
+	fmt.Fprintln(w, "                                 - SQVS_TARGET_UTILIZATION                           : Share of SQVS_MAX_CONCURRENT_REQUESTS in flight or queued each replica is scaled to by the replica count suggested on /admin/capacity (default 0.7)")
+	fmt.Fprintln(w, "                                 - SQVS_BATCH_WORKERS                                : Number of quotes of a batch request verified in parallel (default all CPUs if they accelerate ECDSA, 1 otherwise)")
+	fmt.Fprintln(w, "                                 - SQVS_CLOCK_SKEW_TOLERANCE_SECONDS                 : Number of seconds certificate and collateral validity periods may be missed by and still pass (default 0)")
+	fmt.Fprintln(w, "                                 - SQVS_WAIT_FOR_DEPENDENCIES                        : Boolean value to wait until CMS, AAS and SCS are reachable before starting")
+	fmt.Fprintln(w, "                                 - SQVS_DEPENDENCY_WAIT_TIMEOUT                      : Maximum time to wait for the dependencies (default 5m)")
+	fmt.Fprintln(w, "                                 - SQVS_DEPENDENCY_RETRY_INTERVAL                    : Delay before checking the dependencies again, doubled on every check (default 1s)")
+	fmt.Fprintln(w, "                                 - SQVS_DEPENDENCY_MAX_RETRY_INTERVAL                : Maximum delay between dependency checks (default 30s)")
+	fmt.Fprintln(w, "                                 - SQVS_SERVICE_USERNAME                             : AAS service account the bearer tokens of the calls to SCS and CMS are obtained for")
+	fmt.Fprintln(w, "                                 - SQVS_SERVICE_PASSWORD_FILE                        : File holding the password of the AAS service account")
+	fmt.Fprintln(w, "                                 - SQVS_TOKEN_RENEW_BEFORE                           : Time before their expiry the cached bearer tokens are renewed (default 5m)")
+	fmt.Fprintln(w, "                                 - SQVS_OUTBOUND_MAX_ATTEMPTS                        : Maximum number of attempts for collateral requests to SCS (default 3)")
+	fmt.Fprintln(w, "                                 - SQVS_OUTBOUND_INITIAL_BACKOFF                     : Delay before the first retry of a collateral request, doubled on every retry (default 200ms)")
+	fmt.Fprintln(w, "                                 - SQVS_OUTBOUND_MAX_BACKOFF                         : Maximum delay between retries of a collateral request (default 2s)")
+	fmt.Fprintln(w, "                                 - SQVS_OUTBOUND_ATTEMPT_TIMEOUT                     : Timeout of a single collateral request attempt (default 10s)")
+	fmt.Fprintln(w, "                                 - SQVS_COLLATERAL_FETCH_TIMEOUT                     : Timeout of the TCB info and QE identity fetch of a quote (default 30s)")
+	fmt.Fprintln(w, "                                 - SQVS_CRL_FETCH_TIMEOUT                            : Timeout of the PCK CRL fetch of a quote (default 30s)")
+	fmt.Fprintln(w, "                                 - SQVS_VERIFICATION_COMPUTE_TIMEOUT                 : Timeout of the verification of a quote, fetches excluded (default 10s)")
+	fmt.Fprintln(w, "                                 - SQVS_PCK_CHAIN_CACHE_TTL                          : Time a verified PCK certificate chain is reused without fetching its CRLs, 0s disables it (default 5m)")
+	fmt.Fprintln(w, "                                 - SQVS_OUTBOUND_RETRY_BUDGET                        : Ratio of retries to collateral requests allowed (default 0.2)")
+	fmt.Fprintln(w, "                                 - SQVS_OUTBOUND_BREAKER_THRESHOLD                   : Consecutive failures after which requests to a host are stopped (default 5)")
+	fmt.Fprintln(w, "                                 - SQVS_OUTBOUND_BREAKER_OPEN_DURATION               : Time requests to a failing host are stopped before it is probed again (default 30s)")
+	fmt.Fprintln(w, "                                 - SQVS_OUTBOUND_RETRY_LOG_INTERVAL                  : Interval a retry to a host is logged once per, the retries in between being summarized (default 1m)")
+	fmt.Fprintln(w, "                                 - SQVS_TRUSTED_TIME_SOURCE                          : Time source of certificate and collateral validity checks, system or roughtime (default system)")
+	fmt.Fprintln(w, "                                 - SQVS_TRUSTED_TIME_OFFSET                          : Offset added to the time of the trusted time source (default 0s)")
+	fmt.Fprintln(w, "                                 - SQVS_TRUSTED_TIME_ROUGHTIME_SERVER                : Host and port of the Roughtime server, required for the roughtime source")
+	fmt.Fprintln(w, "                                 - SQVS_TRUSTED_TIME_ROUGHTIME_PUBLIC_KEY            : Base64 encoded Ed25519 public key of the Roughtime server, required for the roughtime source")
+	fmt.Fprintln(w, "                                 - SQVS_TRUSTED_TIME_ROUGHTIME_REFRESH               : Interval at which the Roughtime server is queried (default 10m)")
 	fmt.Fprintln(w, "                                 - SQVS_LOGLEVEL                                    : SGX Verification Service Log Level")
 	fmt.Fprintln(w, "                                 - SQVS_LOG_MAX_LENGTH                               : SGX Verification Service Log maximum length")
+	fmt.Fprintln(w, "                                 - SQVS_LOG_FORMAT                                   : Format of the console and service log records, text, json, cef (ArcSight CEF) or leef (QRadar LEEF) (default text)")
+	fmt.Fprintln(w, "                                 - SQVS_LOG_FILE_FORMAT                              : Format of the service log records when it differs from the console one, text, json, cef or leef (default SQVS_LOG_FORMAT)")
+	fmt.Fprintln(w, "                                 - SQVS_SECURITY_LOG_FORMAT                          : Format of the security log records, text, json, cef (ArcSight CEF) or leef (QRadar LEEF) (default text)")
+	fmt.Fprintln(w, "                                 - SQVS_SECURITY_LOG_SINKS                           : Comma separated list of additional security log sinks by event category, file=<file>;events=<event>|...;outcome=success|failure;level=<level>;format=<format>, events among generic, verification, auth, anomaly, egress, policy, admin and config")
+	fmt.Fprintln(w, "                                 - SQVS_LOG_SAMPLE_SUCCESSES                         : Log 1 in N of the successful verification and authorization records, failures are always logged (default 1)")
+	fmt.Fprintln(w, "                                 - SQVS_ACCESS_LOG_SAMPLE_SUCCESSES                  : Log 1 in N of the requests answered below 400 in the access log, the others are always logged (default 1)")
 	fmt.Fprintln(w, "                                 - SQVS_ENABLE_CONSOLE_LOG                           : SGX Verification Service Enable standard output")
 	fmt.Fprintln(w, "                                 - SQVS_INCLUDE_TOKEN                                : Boolean value to decide whether to use token based auth or no auth for quote verifier API")
+	fmt.Fprintln(w, "                                 - SQVS_ALLOW_DEBUG_ENCLAVES                         : Boolean value to accept quotes from enclaves running in debug mode (default false)")
+	fmt.Fprintln(w, "                                 - SQVS_PCK_ALLOWED_FMSPCS                           : Comma separated list of the FMSPCs of the platforms whose quotes are accepted, all when not set")
+	fmt.Fprintln(w, "                                 - SQVS_PCK_ALLOWED_SGX_TYPES                        : Comma separated list of the SGX types (Standard, Scalable, ScalableWithIntegrity) of the platforms whose quotes are accepted")
+	fmt.Fprintln(w, "                                 - SQVS_TCB_STATUS_VERDICTS                          : Comma separated list of Status=allow|warn|deny entries, e.g. OutOfDate=warn,Revoked=deny, statuses not listed are allowed")
+	fmt.Fprintln(w, "                                 - SQVS_ENABLE_TCB_DOWNGRADE_DETECTION               : Boolean value to enable alerts when a platform's TCB status worsens between verifications")
+	fmt.Fprintln(w, "                                 - SQVS_FIPS_MODE                                    : Boolean value to restrict TLS, token and signature algorithms to FIPS approved ones, requires a FIPS validated crypto module")
+	fmt.Fprintln(w, "                                 - SQVS_TLS_POLICY_MODE                              : Whether TLS and trusted CA certificates below the TLS policy refuse the start, enforce, are logged, warn, or are not checked, off (default warn)")
+	fmt.Fprintln(w, "                                 - SQVS_TLS_MIN_RSA_KEY_SIZE                         : Minimum size in bits of the RSA keys of the TLS and trusted CA certificates (default 3072)")
+	fmt.Fprintln(w, "                                 - SQVS_TLS_MIN_ECDSA_KEY_SIZE                       : Minimum size in bits of the ECDSA keys of the TLS and trusted CA certificates (default 256)")
+	fmt.Fprintln(w, "                                 - SQVS_TLS_MIN_VALIDITY                             : Minimum remaining validity of the TLS and trusted CA certificates, e.g. 720h, not checked when not set")
+	fmt.Fprintln(w, "                                 - SQVS_TLS_DISABLE_LEGACY_CIPHERS                   : Boolean value to restrict the TLS 1.2 connections to the dependencies to ECDHE AEAD cipher suites, without CBC or RSA key exchange fallbacks")
+	fmt.Fprintln(w, "                                 - SQVS_REQUIRE_PLATFORM_ENROLLMENT                  : Boolean value to reject quotes from platforms not enrolled through /admin/platforms")
+	fmt.Fprintln(w, "                                 - SQVS_REQUIRE_SIGNED_REQUESTS                      : Boolean value to reject quote appraisal requests not signed by a relying party registered in /etc/sqvs/certs/relying-parties/")
+	fmt.Fprintln(w, "                                 - SQVS_REQUEST_SIGNATURE_MAX_AGE                    : Time a request signature is accepted for before and after its iat (default 5m)")
+	fmt.Fprintln(w, "                                 - SQVS_REQUIRE_SIGNED_POLICIES                      : Boolean value to reject the custom claims policy unless its detached JWS, <policy file>.jws, is signed by a policy author registered in /etc/sqvs/certs/policy-authors/")
+	fmt.Fprintln(w, "                                 - SQVS_REQUIRE_REGISTERED_ENCLAVES                  : Boolean value to reject quotes from enclaves not imported through /admin/enclaves or sqvs enclaves import")
+	fmt.Fprintln(w, "                                 - SQVS_ENABLE_VERIFICATION_HISTORY                  : Boolean value to record quote verification outcomes, listed at /svs/v1/verifications")
+	fmt.Fprintln(w, "                                 - SQVS_ENABLE_TEST_MODE                             : Boolean value to serve canned verdicts of test quotes under /svs/test/v1/, only in builds made with the sqvs_testmode tag")
+	fmt.Fprintln(w, "                                 - SQVS_RETAIN_RAW_QUOTES                            : Boolean value to keep the raw quote of each recorded verification, re-verified at /svs/v1/verifications/{id}/reverify")
+	fmt.Fprintln(w, "                                 - SQVS_RAW_QUOTE_TENANTS                            : Comma separated tenants whose raw quotes are retained, all tenants when not set")
+	fmt.Fprintln(w, "                                 - SQVS_RAW_QUOTE_KEY_FILE                           : File with the base64 encoded AES-256 key the retained raw quotes are encrypted with")
+	fmt.Fprintln(w, "                                 - SQVS_RAW_QUOTE_VAULT_TRANSIT_KEY                  : Vault transit key the retained raw quotes are encrypted with, instead of a key file")
+	fmt.Fprintln(w, "                                 - SQVS_RAW_QUOTE_VAULT_TRANSIT_MOUNT                : Vault transit secrets engine mount of the raw quote key (default transit)")
+	fmt.Fprintln(w, "                                 - SQVS_ENABLE_RESULT_REVOCATION                     : Boolean value to revoke signed results at /svs/v1/admin/revocations and publish them at /svs/v1/.well-known/revocations.json")
+	fmt.Fprintln(w, "                                 - SQVS_ENABLE_COLLATERAL_HISTORY                    : Boolean value to keep the TCB info and QE identity versions quotes are verified with, to re-evaluate quotes with tcbEvaluationDate or collateralVersion")
+	fmt.Fprintln(w, "                                 - SQVS_QUOTA_ENABLED                                : Boolean value to count the verification requests of every tenant, reported at /svs/v1/usage")
+	fmt.Fprintln(w, "                                 - SQVS_QUOTA_TENANT_CLAIM                           : Token claim identifying the tenant of a request (default tenant)")
+	fmt.Fprintln(w, "                                 - SQVS_QUOTA_LIMITS                                 : Comma separated list of [route:]daily=N or [route:]monthly=N quota limits")
+	fmt.Fprintln(w, "                                 - SQVS_QUOTA_ENFORCEMENT                            : Action taken on requests beyond a quota, warn or reject (default warn)")
+	fmt.Fprintln(w, "                                 - SQVS_ANOMALY_DETECTION_ENABLED                    : Boolean value to flag anomalous quote patterns as security events and webhook posts")
+	fmt.Fprintln(w, "                                 - SQVS_ANOMALY_WINDOW                               : Sliding window anomalous quote patterns are detected in (default 5m)")
+	fmt.Fprintln(w, "                                 - SQVS_ANOMALY_FAILED_VERIFICATIONS                 : Failed verifications from one caller within the window flagged as anomalous, 0 disables (default 20)")
+	fmt.Fprintln(w, "                                 - SQVS_ANOMALY_DEBUG_QUOTES                         : Quotes of DEBUG enclaves within the window flagged as anomalous, 0 disables (default 50)")
+	fmt.Fprintln(w, "                                 - SQVS_ANOMALY_DISTINCT_MEASUREMENTS                : Distinct MRENCLAVEs verified by one tenant within the window flagged as anomalous, 0 disables (default 10)")
+	fmt.Fprintln(w, "                                 - SQVS_ANOMALY_WEBHOOK_URL                          : Webhook URL to which anomalous quote patterns are posted (default SQVS_WEBHOOK_URL)")
+	fmt.Fprintln(w, "                                 - SQVS_EXPIRY_ALERT_THRESHOLDS                      : Comma separated durations before certificate and collateral expiry alerts are raised at (default 720h,168h,24h)")
+	fmt.Fprintln(w, "                                 - SQVS_EXPIRY_CHECK_INTERVAL                        : Interval certificates and collateral are checked for expiry at, 0 to disable (default 1h)")
+	fmt.Fprintln(w, "                                 - SQVS_COLLATERAL_CHECK_URL                         : Base URL of Intel PCS, e.g. https://api.trustedservices.intel.com/sgx/certification/v3, the TCB info and QE identity of SCS are cross-checked against")
+	fmt.Fprintln(w, "                                 - SQVS_COLLATERAL_CHECK_MODE                        : Action taken on collateral that does not match or cannot be cross-checked, enforce or warn (default enforce)")
+	fmt.Fprintln(w, "                                 - SQVS_COLLATERAL_CHECK_CACHE_TTL                   : Time the collateral of Intel PCS is cached for (default 10m)")
+	fmt.Fprintln(w, "                                 - SQVS_COLLATERAL_FAILURE_MODE                      : How quotes are verified when SCS cannot be reached for fresh collateral, fail-closed, use-cached or fail-open (default fail-closed), use-cached and fail-open needing collateral verified since sqvs started")
+	fmt.Fprintln(w, "                                 - SQVS_COLLATERAL_GRACE_PERIOD                      : Time cached collateral is used for past its next update in use-cached mode (default 24h)")
+	fmt.Fprintln(w, "                                 - SQVS_COLLATERAL_CACHE_TTL                         : Time the collateral served by the /collateral endpoints is cached for, never past its next update (default 10m)")
+	fmt.Fprintln(w, "                                 - SQVS_SESSION_TTL                                  : Time a verification session opened with /svs/v2/sessions is open for (default 5m)")
+	fmt.Fprintln(w, "                                 - SQVS_PLATFORM_IDENTIFIERS                         : Redaction profile of the PPID and platform instance ID in the results of clients not asking for them with platform_identifiers=true, redacted, pseudonymized or full (default redacted)")
+	fmt.Fprintln(w, "                                 - SQVS_FEATURES                                     : Comma separated <flag>=<true|false> feature flags, batch (default true) and verification-sessions (experimental, default false)")
+	fmt.Fprintln(w, "                                 - SQVS_DB_DRIVER                                    : Storage driver of the verification history, memory, sqlite or postgres (default memory)")
+	fmt.Fprintln(w, "                                 - SQVS_DB_FILE                                      : Snapshot file of the memory driver or database file of the sqlite driver")
+	fmt.Fprintln(w, "                                 - SQVS_DB_HOSTNAME                                  : Postgres database hostname")
+	fmt.Fprintln(w, "                                 - SQVS_DB_PORT                                      : Postgres database port (default 5432)")
+	fmt.Fprintln(w, "                                 - SQVS_DB_NAME                                      : Postgres database name")
+	fmt.Fprintln(w, "                                 - SQVS_DB_USERNAME                                  : Postgres database username")
+	fmt.Fprintln(w, "                                 - SQVS_DB_PASSWORD_FILE                             : File holding the Postgres database password")
+	fmt.Fprintln(w, "                                 - SQVS_DB_SSLMODE                                   : Postgres SSL mode (default verify-full)")
+	fmt.Fprintln(w, "                                 - SQVS_DB_SSLCERT                                   : CA certificate file the Postgres server certificate is verified with")
+	fmt.Fprintln(w, "                                 - SQVS_HISTORY_RETENTION_DAYS                       : Number of days verification history is kept, unlimited when not set")
+	fmt.Fprintln(w, "                                 - SQVS_HISTORY_MAX_RECORDS                          : Maximum number of verification history records kept, unlimited when not set, except with the memory driver that keeps the newest 100000")
+	fmt.Fprintln(w, "                                 - SQVS_HISTORY_PRUNE_INTERVAL                       : Interval at which the verification history is pruned (default 1h)")
+	fmt.Fprintln(w, "                                 - SQVS_HISTORY_EXPORT_DIR                           : Directory, for example an NFS mount, pruned verification records are exported to")
+	fmt.Fprintln(w, "                                 - SQVS_HISTORY_EXPORT_S3_URL                        : S3 URL, https://<endpoint>/<bucket>/<prefix>, pruned verification records are exported to")
+	fmt.Fprintln(w, "                                 - SQVS_HISTORY_EXPORT_S3_REGION                     : Region of the S3 export bucket")
+	fmt.Fprintln(w, "                                 - SQVS_HISTORY_EXPORT_S3_CREDENTIALS_FILE           : File holding the S3 access key ID and secret access key on separate lines")
+	fmt.Fprintln(w, "                                 - SQVS_VERIFIER_QUOTE_SOURCE                        : Source of the quotes of the SQVS enclave served at /svs/v1/verifier-evidence, helper or gramine")
+	fmt.Fprintln(w, "                                 - SQVS_VERIFIER_QUOTE_HELPER                        : Program printing the base64 quote for the hex report data given as argument")
+	fmt.Fprintln(w, "                                 - SQVS_VERIFIER_EVIDENCE_REFRESH                    : Interval at which verifier evidence without a nonce is regenerated (default 1h)")
+	fmt.Fprintln(w, "                                 - SQVS_WEBHOOK_URL                                  : Webhook URL to which SQVS alerts are posted")
+	fmt.Fprintln(w, "                                 - SQVS_RESULT_EVENTS_BROKER                         : Message queue the result of every quote verification is published to, kafka or nats")
+	fmt.Fprintln(w, "                                 - SQVS_RESULT_EVENTS_ADDRESSES                      : Comma separated list of the Kafka brokers or NATS server URLs")
+	fmt.Fprintln(w, "                                 - SQVS_RESULT_EVENTS_TOPIC                          : Kafka topic or NATS JetStream subject verification results are published to")
+	fmt.Fprintln(w, "                                 - SQVS_RESULT_EVENTS_TLS                            : Boolean value to connect to the message queue over TLS (default false)")
+	fmt.Fprintln(w, "                                 - SQVS_RESULT_EVENTS_ENCODING                       : Encoding of the verification result events, json or cbor (default json)")
+	fmt.Fprintln(w, "                                 - SQVS_RESULT_EVENTS_BATCH_SIZE                     : Maximum number of verification results sent in a batch (default 100)")
+	fmt.Fprintln(w, "                                 - SQVS_RESULT_EVENTS_FLUSH_INTERVAL                 : Interval at which batches of verification results are sent (default 1s)")
+	fmt.Fprintln(w, "                                 - SQVS_RESULT_EVENTS_QUEUE_SIZE                     : Maximum number of verification results buffered while the message queue is unreachable (default 10000)")
+	fmt.Fprintln(w, "                                 - SQVS_KEY_RELEASE_BROKER                           : Key broker plugin keys are released to verified enclaves with, webhook, key release is disabled when not set")
+	fmt.Fprintln(w, "                                 - SQVS_KEY_RELEASE_URL                              : URL of the key broker, a KBS or KMIP gateway, the verified enclave identities are posted to")
+	fmt.Fprintln(w, "                                 - SQVS_KEY_RELEASE_TIMEOUT                          : Time the key broker is waited for (default 10s)")
+	fmt.Fprintln(w, "                                 - SQVS_PAYLOAD_CAPTURE_ENABLED                      : Boolean value to allow holders of the PayloadCapture role to capture redacted request and response payloads for a limited time (default false)")
+	fmt.Fprintln(w, "                                 - SQVS_PAYLOAD_CAPTURE_FILE                         : File the captured payloads are appended to (default /var/log/sqvs/sqvs-payload-capture.log)")
+	fmt.Fprintln(w, "                                 - SQVS_PAYLOAD_CAPTURE_MAX_DURATION                 : Longest payload capture that can be started (default 30m)")
+	fmt.Fprintln(w, "                                 - SQVS_PAYLOAD_CAPTURE_MAX_BODY_SIZE                : Size in bytes the captured bodies are truncated at (default 65536)")
+	fmt.Fprintln(w, "                                 - SQVS_PAYLOAD_CAPTURE_REDACT_FIELDS                : Comma separated list of headers, query parameters and JSON fields redacted in addition to tokens, session tokens, request signatures, raw quotes and released keys")
+	fmt.Fprintln(w, "                                 - SQVS_IP_ALLOW_LIST                                : Comma separated list of CIDR blocks or addresses of the clients allowed to reach SQVS, all when not set")
+	fmt.Fprintln(w, "                                 - SQVS_IP_DENY_LIST                                 : Comma separated list of CIDR blocks or addresses of the clients rejected by SQVS")
+	fmt.Fprintln(w, "                                 - SQVS_TRUSTED_PROXIES                              : Comma separated list of CIDR blocks or addresses of the load balancers X-Forwarded-For is honored from")
+	fmt.Fprintln(w, "                                 - SQVS_PROXY_PROTOCOL                               : Boolean value to require a PROXY protocol v2 header from the trusted proxies, from every peer when there are none")
+	fmt.Fprintln(w, "                                 - SQVS_REUSE_PORT                                   : Boolean value to listen with SO_REUSEPORT, so the binary of an upgrade can be started on the same port before the running one is stopped")
+	fmt.Fprintln(w, "                                 - SQVS_EGRESS_ALLOW_LIST                            : Comma separated list of host names, *. domains, addresses or CIDR blocks SQVS may connect to, all other outbound connections being blocked")
+	fmt.Fprintln(w, "                                 - SQVS_DNS_HOST_OVERRIDES                           : Comma separated list of host=address entries pinning the host names of SCS, AAS, CMS or any other dependency to addresses, bypassing DNS")
+	fmt.Fprintln(w, "                                 - SQVS_DNS_RESOLVER                                 : Resolver of the dependency host names, system or go (default system)")
+	fmt.Fprintln(w, "                                 - SQVS_DNS_SERVERS                                  : Comma separated list of DNS servers, address[:port], queried by the go resolver instead of those of /etc/resolv.conf")
+	fmt.Fprintln(w, "                                 - SQVS_DNS_CACHE_TTL                                : Time resolved dependency addresses are reused for, 0 resolving them for every connection (default 0)")
+	fmt.Fprintln(w, "                                 - SQVS_SGX_ROOT_KEY_PINS                            : Comma separated list of the hex encoded SHA-256 digests of the public keys the Intel SGX root CA may have")
+	fmt.Fprintln(w, "                                 - SQVS_LISTEN_ADDRESS                               : IPv4 or IPv6 address, optionally bracketed, or host name SQVS listens on, all addresses when not set")
+	fmt.Fprintln(w, "                                 - SQVS_ADDRESS_FAMILY                               : Address family to listen and connect over, ipv4, ipv6, prefer-ipv4 or prefer-ipv6 (default dual-stack)")
+	fmt.Fprintln(w, "                                 - SQVS_CRL_URL_OVERRIDES                            : Comma separated list of processor=<url> or platform=<url> entries overriding the CRL distribution point of the PCK CAs")
+	fmt.Fprintln(w, "                                 - SQVS_CORS_ALLOWED_ORIGINS                         : Comma separated list of origins allowed to make cross-origin requests. CORS is disabled when not set")
+	fmt.Fprintln(w, "                                 - SQVS_CORS_ALLOWED_METHODS                         : Comma separated list of methods allowed in cross-origin requests (default \"GET,POST\")")
+	fmt.Fprintln(w, "                                 - SQVS_CORS_ALLOWED_HEADERS                         : Comma separated list of headers allowed in cross-origin requests (default \"Accept,Authorization,Content-Type\")")
+	fmt.Fprintln(w, "                                 - SQVS_KEYSTORE_TYPE                                : Backend TLS and signing keys are loaded from: file, vault-kv, vault-transit or pkcs11 (default \"file\")")
+	fmt.Fprintln(w, "                                 - SQVS_SIGNING_KEY_ID                               : Response signing key ID in the key store: file path, Vault secret path/transit key name or PKCS#11 label")
+	fmt.Fprintln(w, "                                 - SQVS_RESULT_SIGNING_ALGORITHM                     : Algorithm signed results are signed with unless the request names one, RS384, PS384, ES384 or EdDSA (default RS384, PS384 with USE_PSS_PADDING)")
+	fmt.Fprintln(w, "                                 - SQVS_RESULT_SIGNING_KEYS                          : Comma separated list of <algorithm>=<key ID>:<certificate file> signing keys dedicated to an algorithm, published in the JWKS")
+	fmt.Fprintln(w, "                                 - SQVS_SIGNING_KEY_OVERLAP                          : Time a rotated response signing key remains available for verification (default 24h)")
+	fmt.Fprintln(w, "                                 - SQVS_RESULT_VALIDITY                              : Time verification results are valid for, e.g. 24h, ExpiresAt being the earliest of it, the collateral next update and the result policy TTL, not limited when not set")
+	fmt.Fprintln(w, "                                 - SQVS_CUSTOM_CLAIMS_FILE                           : YAML file of the custom claims embedded in signed responses (default \"/etc/sqvs/custom-claims.yml\")")
+	fmt.Fprintln(w, "                                 - SQVS_TLS_KEY_ID                                   : TLS key ID in the key store (defaults to the TLS key file)")
+	fmt.Fprintln(w, "                                 - SQVS_TLS_SECONDARY_CERT_FILE                      : Second TLS certificate chain, RSA or ECDSA whichever the TLS certificate is not, served to clients not supporting the ECDSA one")
+	fmt.Fprintln(w, "                                 - SQVS_TLS_SECONDARY_KEY_ID                         : Key ID of the second TLS certificate chain in the key store, required with SQVS_TLS_SECONDARY_CERT_FILE")
+	fmt.Fprintln(w, "                                 - SQVS_VAULT_ADDR                                   : Vault server address")
+	fmt.Fprintln(w, "                                 - SQVS_VAULT_MOUNT                                  : Vault KV v2 or transit secrets engine mount path")
+	fmt.Fprintln(w, "                                 - SQVS_VAULT_TOKEN_FILE                             : File holding the Vault token")
+	fmt.Fprintln(w, "                                 - SQVS_PKCS11_MODULE                                : PKCS#11 module path")
+	fmt.Fprintln(w, "                                 - SQVS_PKCS11_TOKEN_LABEL                           : PKCS#11 token label")
+	fmt.Fprintln(w, "                                 - SQVS_PKCS11_PIN_FILE                              : File holding the PKCS#11 user PIN")
 	fmt.Fprintln(w, "                                 - SGX_TRUSTED_ROOT_CA_PATH                          : SQVS Trusted Root CA")
 	fmt.Fprintln(w, "                                 - SCS_BASE_URL                                      : SGX Caching Service URL")
 	fmt.Fprintln(w, "                                 - AAS_API_URL                                       : AAS API URL")
@@ -107,18 +300,19 @@ func (a *App) printUsage() {
 	fmt.Fprintln(w, "                                 - CMS_TLS_CERT_SHA384=<CMS TLS cert sha384 hash>      : to ensure that AAS is talking to the right CMS instance")
 	fmt.Fprintln(w, "                             Required env variables specific to setup task are:")
 	fmt.Fprintln(w, "                                 - CMS_BASE_URL=<url>               : for CMS API url")
-	fmt.Fprintln(w, "                                 - BEARER_TOKEN=<token>             : for authenticating with CMS")
-	fmt.Fprintln(w, "                                 - SAN_LIST=<san>                   : list of hosts which needs access to service")
+	fmt.Fprintln(w, "                                 - BEARER_TOKEN=<token>             : for authenticating with CMS, a token of SQVS_SERVICE_USERNAME is obtained from AAS when unset")
+	fmt.Fprintln(w, "                                 - SAN_LIST=<san>                   : list of hosts which needs access to service, IPv6 addresses may be bracketed")
 	fmt.Fprintln(w, "                             Optional env variables specific to setup task are:")
 	fmt.Fprintln(w, "                                - KEY_PATH=<key_path>              : Path of file where TLS key needs to be stored")
 	fmt.Fprintln(w, "                                - CERT_PATH=<cert_path>            : Path of file/directory where TLS certificate needs to be stored")
+	fmt.Fprintln(w, "                                - SAN_AUTO_DETECT=<true|false>     : Add the host name and the non-loopback, non-link-local addresses of the host to SAN_LIST (default true)")
 	fmt.Fprintln(w, "    create_signing_key_pair  Generates Key pair and CSR and downloads Signing certificate from CMS")
 	fmt.Fprintln(w, "                             - Option [--force] overwrites any existing files and always downloads new Signing cert")
 	fmt.Fprintln(w, "                             Required env variable if SQVS_NOSETUP=true or variable not set in config.yml:")
 	fmt.Fprintln(w, "                                 - CMS_TLS_CERT_SHA384=<CMS TLS cert sha384 hash>      : to ensure that AAS is talking to the right CMS instance")
 	fmt.Fprintln(w, "                             Required env variables specific to setup task are:")
 	fmt.Fprintln(w, "                                 - CMS_BASE_URL=<url>               : for CMS API url")
-	fmt.Fprintln(w, "                                 - BEARER_TOKEN=<token>             : for authenticating with CMS")
+	fmt.Fprintln(w, "                                 - BEARER_TOKEN=<token>             : for authenticating with CMS, a token of SQVS_SERVICE_USERNAME is obtained from AAS when unset")
 	fmt.Fprintln(w, "")
 }
 
@@ -209,42 +403,157 @@ var log = commLog.GetDefaultLogger()
 var slog = commLog.GetSecurityLogger()
 
 func (a *App) configureLogs(stdOut, logFile bool) {
-	var ioWriterDefault io.Writer
-	ioWriterDefault = a.LogWriter
-	if stdOut {
-		if logFile {
-			ioWriterDefault = io.MultiWriter(os.Stdout, a.logWriter())
-		} else {
-			ioWriterDefault = os.Stdout
+	conf := a.configuration()
+	f := commLog.LogFormatter{MaxLength: conf.LogMaxLength}
+	consoleFormatter, err := logformat.New(conf.LogFormat, &f, version.Version)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid log format, using text:", err)
+		consoleFormatter = &f
+	}
+	fileFormatter := consoleFormatter
+	if conf.LogFileFormat != "" && conf.LogFileFormat != conf.LogFormat {
+		fileFormatter, err = logformat.New(conf.LogFileFormat, &f, version.Version)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid log file format, using text:", err)
+			fileFormatter = &f
 		}
 	}
+	secLogFormatter, err := logformat.New(conf.SecurityLogFormat, &f, version.Version)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid security log format, using text:", err)
+		secLogFormatter = &f
+	}
+	if conf.SecurityLogFormat == conf.LogFormat {
+		secLogFormatter = consoleFormatter
+	}
 
-	ioWriterSecurity := io.MultiWriter(ioWriterDefault, a.secLogWriter())
+	// the console and the service log are sinks of their own, each formatted independently
+	var sinks []logformat.Sink
+	if stdOut {
+		sinks = append(sinks, logformat.Sink{Writer: os.Stdout, Formatter: consoleFormatter})
+	}
+	if logFile || !stdOut {
+		sinks = append(sinks, logformat.Sink{Writer: a.logWriter(), Formatter: fileFormatter})
+	}
+	logFormatter, ioWriterDefault := logformat.Output(sinks)
+	if conf.LogSampleSuccesses > 1 {
+		logFormatter = &logformat.Sampler{Formatter: logFormatter, Every: uint64(conf.LogSampleSuccesses)}
+	}
+	commLogInt.SetLogger(commLog.DefaultLoggerName, conf.LogLevel, logFormatter, ioWriterDefault, false)
 
-	f := commLog.LogFormatter{MaxLength: a.configuration().LogMaxLength}
-	commLogInt.SetLogger(commLog.DefaultLoggerName, a.configuration().LogLevel, &f, ioWriterDefault, false)
-	commLogInt.SetLogger(commLog.SecurityLoggerName, a.configuration().LogLevel, &f, ioWriterSecurity, false)
+	secSinks := append(sinks[:len(sinks):len(sinks)], logformat.Sink{Writer: a.secLogWriter(), Formatter: secLogFormatter})
+	secLevel := conf.LogLevel
+	if extraSinks := a.securityLogSinks(&f); len(extraSinks) != 0 {
+		// the security logger records at the most verbose level of its sinks, each keeping its own level
+		for i := range secSinks {
+			secSinks[i].Filter = &logformat.Filter{Level: conf.LogLevel}
+		}
+		for _, sink := range extraSinks {
+			if sink.Filter.Level > secLevel {
+				secLevel = sink.Filter.Level
+			}
+		}
+		secSinks = append(secSinks, extraSinks...)
+	}
+	secFormatter, ioWriterSecurity := logformat.Output(secSinks)
+	if a.configBundle != "" {
+		// every audit record names the configuration it was produced under
+		secFormatter = &logformat.Fields{Formatter: secFormatter, Fields: logrus.Fields{"configBundle": a.configBundle}}
+	}
+	if conf.LogSampleSuccesses > 1 {
+		secFormatter = &logformat.Sampler{Formatter: secFormatter, Every: uint64(conf.LogSampleSuccesses)}
+	}
+	commLogInt.SetLogger(commLog.SecurityLoggerName, secLevel, secFormatter, ioWriterSecurity, false)
 
 	slog.Info(commLogMsg.LogInit)
 	log.Info(commLogMsg.LogInit)
 }
 
+// securityLogSinks opens the additional security log sinks of the configuration, skipping the invalid ones
+func (a *App) securityLogSinks(text logrus.Formatter) []logformat.Sink {
+	var sinks []logformat.Sink
+	for _, spec := range a.configuration().SecurityLogSinks {
+		sink, err := logformat.ParseSinkSpec(spec)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid security log sink, skipping it:", err)
+			continue
+		}
+		formatter, _ := logformat.New(sink.Format, text, version.Version)
+		file := sink.File
+		if !path.IsAbs(file) {
+			file = path.Join(constants.LogDir, file)
+		}
+		writer, err := os.OpenFile(file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error opening security log sink, skipping it:", err)
+			continue
+		}
+		filter := sink.Filter
+		sinks = append(sinks, logformat.Sink{Writer: writer, Formatter: formatter, Filter: &filter})
+	}
+	return sinks
+}
+
+// applyConfigBundle verifies the signed configuration bundle against the operator public key and installs it
+// before the configuration is loaded, refusing to start on a missing or tampered bundle
+func (a *App) applyConfigBundle() error {
+	manifest, err := bundle.Apply(constants.ConfigBundleFile, constants.ConfigBundleSignatureFile,
+		constants.ConfigBundlePublicKeyFile, constants.ConfigDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: configuration bundle refused - ", err.Error())
+		return errors.Wrap(err, "app:applyConfigBundle() Invalid configuration bundle")
+	}
+	if manifest != nil {
+		a.configBundle = manifest.Version
+	}
+	return nil
+}
+
 func (a *App) Run(args []string) error {
 
+	var err error
+	a.outputFormat, args, err = parseOutputFlag(args)
+	if err != nil {
+		a.printUsage()
+		return usageError(errors.Wrap(err, "app:Run() Invalid output format"))
+	}
+
 	if len(args) < 2 {
 		a.printUsage()
-		os.Exit(1)
+		return usageError(errors.New("app:Run() No command given"))
 	}
 
 	cmd := args[1]
 	switch cmd {
 	default:
 		a.printUsage()
-		fmt.Fprintf(os.Stderr, "Unrecognized command: %s\n", args[1])
-		os.Exit(1)
+		return usageError(errors.Errorf("app:Run() Unrecognized command: %s", args[1]))
 	case "run":
+		if err := a.applyConfigBundle(); err != nil {
+			return configError(err)
+		}
+		overrides, err := a.applyEnvOverrides()
+		if err != nil {
+			return configError(err)
+		}
 		a.configureLogs(config.Global().LogEnableStdout, true)
-		if err := a.startServer(); err != nil {
+		for _, override := range overrides {
+			log.Infof("app:Run() Configuration %s set by %s", override.Field, override.Variable)
+		}
+		if a.configBundle != "" {
+			slog.Infof("app:Run() Configuration bundle %s verified and installed", a.configBundle)
+		}
+		// the service stops gracefully on termination
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		srv := server.New(a.configuration())
+		srv.HTTPLogWriter = a.httpLogWriter()
+		// SIGUSR2 hands the service over to the binary installed by an upgrade
+		upgrade := make(chan os.Signal, 1)
+		signal.Notify(upgrade, syscall.SIGUSR2)
+		defer signal.Stop(upgrade)
+		srv.Upgrade = upgrade
+		if err := srv.Start(ctx); err != nil {
 			fmt.Fprintln(os.Stderr, "Error: daemon did not start - ", err.Error())
 			// wait some time for logs to flush - otherwise, there will be no entry in syslog
 			time.Sleep(5 * time.Millisecond)
@@ -259,35 +568,114 @@ func (a *App) Run(args []string) error {
 	case "stop":
 		a.configureLogs(a.configuration().LogEnableStdout, true)
 		return a.stop()
+	case "upgrade":
+		a.configureLogs(a.configuration().LogEnableStdout, true)
+		return a.upgrade()
 	case "status":
 		a.configureLogs(a.configuration().LogEnableStdout, true)
+		if a.outputFormat == outputJSON {
+			return a.jsonStatus()
+		}
 		return a.status()
+	case "tlscertsha384":
+		return a.tlsCertSha384()
+	case "config":
+		return a.configCommand(args[2:])
+	case "bootstrap":
+		a.configureLogs(a.configuration().LogEnableStdout, true)
+		return a.bootstrapCommand(args[2:])
+	case "conformance":
+		if _, err := a.applyEnvOverrides(); err != nil {
+			return configError(err)
+		}
+		a.configureLogs(false, true)
+		return a.conformanceCommand(args[2:])
+	case "crl":
+		return a.crl(args[2:])
+	case "bench":
+		if _, err := a.applyEnvOverrides(); err != nil {
+			return configError(err)
+		}
+		a.configureLogs(false, true)
+		return a.benchCommand(args[2:])
+	case "enclaves":
+		if _, err := a.applyEnvOverrides(); err != nil {
+			return configError(err)
+		}
+		a.configureLogs(a.configuration().LogEnableStdout, true)
+		return a.enclaves(args[2:])
+	case "history":
+		if _, err := a.applyEnvOverrides(); err != nil {
+			return configError(err)
+		}
+		a.configureLogs(a.configuration().LogEnableStdout, true)
+		return a.history(args[2:])
+	case "migrate":
+		if _, err := a.applyEnvOverrides(); err != nil {
+			return configError(err)
+		}
+		a.configureLogs(a.configuration().LogEnableStdout, true)
+		return a.migrate(args[2:])
+	case "maintenance":
+		return a.maintenance(args[2:])
+	case "completion":
+		if len(args) != 3 {
+			a.printUsage()
+			return usageError(errors.New("app:Run() completion requires the shell name, bash or zsh"))
+		}
+		return a.printCompletion(args[2])
+	case "install":
+		return a.install(args[2:])
 	case "uninstall":
 		var purge bool
 		flag.CommandLine.BoolVar(&purge, "purge", false, "purge config when uninstalling")
 		err := flag.CommandLine.Parse(args[2:])
 		if err != nil {
-			return err
+			return usageError(err)
 		}
 		a.uninstall(purge)
 		log.Info("app:Run() Uninstalled SGX Verification Service")
-		os.Exit(0)
-	case "version", "--version", "-v":
-		fmt.Println(version.GetVersion())
 		return nil
+	case "version", "--version", "-v":
+		return a.printVersion()
 	case "setup":
 		a.configureLogs(a.configuration().LogEnableStdout, true)
 		var setupContext setup.Context
 		if len(args) <= 2 {
 			a.printUsage()
-			os.Exit(1)
+			return usageError(errors.New("app:Run() setup requires the task name"))
+		}
+
+		answersFile, force, setupArgs, err := parseSetupFlags(args[3:])
+		if err != nil {
+			a.printUsage()
+			return usageError(errors.Wrap(err, "app:Run() Invalid setup task arguments"))
 		}
+		args = append(args[:3], setupArgs...)
 
-		err := validateSetupArgs(args[2], args[3:])
+		err = validateSetupArgs(args[2], args[3:])
 		if err != nil {
 			errMessage := "app:Run() Invalid setup task arguments"
 			a.printUsage()
-			return errors.Wrap(err, errMessage)
+			return usageError(errors.Wrap(err, errMessage))
+		}
+
+		if answersFile != "" {
+			answers, err := tasks.LoadAnswers(answersFile)
+			if err != nil {
+				return configError(errors.Wrap(err, "app:Run() Error loading setup answers"))
+			}
+			applied, err := tasks.ApplyAnswers(answers)
+			if err != nil {
+				return configError(errors.Wrap(err, "app:Run() Error applying setup answers"))
+			}
+			log.Infof("app:Run() Setup inputs read from %s: %s", answersFile, strings.Join(applied, ", "))
+		}
+
+		// task progress goes to stderr when the summary is printed in JSON
+		setupWriter := a.consoleWriter()
+		if a.outputFormat == outputJSON {
+			setupWriter = os.Stderr
 		}
 
 		taskName := args[2]
@@ -295,8 +683,7 @@ func (a *App) Run(args []string) error {
 		a.Config = config.Global()
 		err = a.Config.SaveConfiguration(taskName, setupContext)
 		if err != nil {
-			fmt.Println("Error saving configuration: " + err.Error())
-			os.Exit(1)
+			return configError(errors.Wrap(err, "app:Run() Error saving configuration"))
 		}
 		task := strings.ToLower(args[2])
 		flags := args[3:]
@@ -306,190 +693,28 @@ func (a *App) Run(args []string) error {
 		}
 
 		a.Config = config.Global()
-		setupRunner := &setup.Runner{
-			Tasks: []setup.Task{
-				setup.Download_Ca_Cert{
-					Flags:                args,
-					CmsBaseURL:           a.Config.CMSBaseURL,
-					CaCertDirPath:        constants.TrustedCAsStoreDir,
-					TrustedTlsCertDigest: a.Config.CmsTLSCertDigest,
-					ConsoleWriter:        os.Stdout,
-				},
-				setup.Download_Cert{
-					Flags:              flags,
-					KeyFile:            a.Config.TLSKeyFile,
-					CertFile:           a.Config.TLSCertFile,
-					KeyAlgorithm:       constants.DefaultKeyAlgorithm,
-					KeyAlgorithmLength: constants.DefaultKeyAlgorithmLength,
-					CmsBaseURL:         a.Config.CMSBaseURL,
-					Subject: pkix.Name{
-						CommonName: a.Config.Subject.TLSCertCommonName,
-					},
-					SanList:       a.Config.CertSANList,
-					CertType:      "TLS",
-					CaCertsDir:    constants.TrustedCAsStoreDir,
-					BearerToken:   "",
-					ConsoleWriter: os.Stdout,
-				},
-				tasks.Update_Service_Config{
-					Flags:                    flags,
-					Config:                   a.configuration(),
-					ConsoleWriter:            os.Stdout,
-					TrustedSGXRootCAFilePath: constants.TrustedSGXRootCAFile,
-				},
-				tasks.Create_Signing_Key_Pair{
-					Flags:         flags,
-					Config:        a.configuration(),
-					ConsoleWriter: os.Stdout,
-				},
-			},
-			AskInput: false,
-		}
+		setupRunner := a.setupRunner(args, flags, force, setupWriter)
+		var summary tasks.Summary
 		if task == "all" {
-			err = setupRunner.RunTasks()
+			summary, err = setupRunner.RunTasks()
 		} else {
-			err = setupRunner.RunTasks(task)
-		}
-		if err != nil {
-			log.WithError(err).Errorf("Setup task %s failed", task)
-			return err
-		}
-
-		// Containers are always run as non root users, does not require changing ownership of config directories
-		if _, err := os.Stat("/.container-env"); err == nil {
-			return nil
-		}
-
-		sqvsUser, err := user.Lookup(constants.SQVSUserName)
-		if err != nil {
-			return errors.Wrapf(err, "Could not find user '%s'", constants.SQVSUserName)
-		}
-
-		uid, err := strconv.Atoi(sqvsUser.Uid)
-		if err != nil {
-			return errors.Wrapf(err, "Could not parse sqvs user uid '%s'", sqvsUser.Uid)
-		}
-
-		gid, err := strconv.Atoi(sqvsUser.Gid)
-		if err != nil {
-			return errors.Wrapf(err, "Could not parse sqvs user gid '%s'", sqvsUser.Gid)
+			summary, err = setupRunner.RunTasks(task)
 		}
-
-		// Change the file ownership to sqvs user
-		err = cos.ChownR(constants.ConfigDir, uid, gid)
-		if err != nil {
-			return errors.Wrap(err, "Error while changing file ownership")
-		}
-		if task == "download_cert" {
-			err = os.Chown(a.Config.TLSKeyFile, uid, gid)
-			if err != nil {
-				return errors.Wrap(err, "Error while changing ownership of TLS Key file")
-			}
-
-			err = os.Chown(a.Config.TLSCertFile, uid, gid)
-			if err != nil {
-				return errors.Wrap(err, "Error while changing ownership of TLS Cert file")
-			}
-		}
-		if task == "create_signing_key_pair" {
-			err = os.Chown(constants.PrivateKeyLocation, uid, gid)
-			if err != nil {
-				return errors.Wrap(err, "Error while changing ownership of TLS Key file")
-			}
-
-			err = os.Chown(constants.PublicKeyLocation, uid, gid)
-			if err != nil {
-				return errors.Wrap(err, "Error while changing ownership of TLS Cert file")
+		if a.outputFormat == outputJSON {
+			perr := a.printJSON(summary)
+			if perr != nil {
+				log.WithError(perr).Error("Error printing setup summary")
 			}
+		} else {
+			summary.Print(a.consoleWriter())
 		}
-	}
-	return nil
-}
-
-func (a *App) startServer() error {
-	c := a.configuration()
-	log.Info("Starting SGX Quote Verification Server")
-	// Create Router, set routes
-	r := mux.NewRouter()
-	r.SkipClean(true)
-
-	// set version endpoint
-	sr := r.PathPrefix("/svs/v{version:[1-2]}/").Subrouter()
-	func(setters ...func(*mux.Router)) {
-		for _, setter := range setters {
-			setter(sr)
-		}
-	}(resource.SetVersionRoutes)
-
-	sr = r.PathPrefix("/svs/v1/").Subrouter()
-	if c.IncludeToken {
-		sr.Use(middleware.NewTokenAuth(constants.TrustedJWTSigningCertsDir, constants.TrustedCAsStoreDir, fnGetJwtCerts,
-			time.Minute*constants.DefaultJwtValidateCacheKeyMins))
-	}
-
-	func(setters ...func(*mux.Router)) {
-		for _, setter := range setters {
-			setter(sr)
-		}
-	}(resource.QuoteVerifyCB)
-
-	sr = r.PathPrefix("/svs/v2/").Subrouter()
-	if c.IncludeToken {
-		sr.Use(middleware.NewTokenAuth(constants.TrustedJWTSigningCertsDir, constants.TrustedCAsStoreDir, fnGetJwtCerts,
-			time.Minute*constants.DefaultJwtValidateCacheKeyMins))
-	}
-	func(setters ...func(*mux.Router)) {
-		for _, setter := range setters {
-			setter(sr)
-		}
-	}(resource.QuoteVerifyCBAndSign)
-
-	tlsconfig := &tls.Config{
-		MinVersion: tls.VersionTLS13,
-		CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
-			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
-	}
-	// Setup signal handlers to gracefully handle termination
-	stop := make(chan os.Signal)
-	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
-	httpLog := stdlog.New(a.httpLogWriter(), "", 0)
-	h := &http.Server{
-		Addr:              fmt.Sprintf(":%d", c.Port),
-		Handler:           handlers.RecoveryHandler(handlers.RecoveryLogger(httpLog), handlers.PrintRecoveryStack(true))(handlers.CombinedLoggingHandler(a.httpLogWriter(), r)),
-		ErrorLog:          httpLog,
-		TLSConfig:         tlsconfig,
-		ReadTimeout:       c.ReadTimeout,
-		ReadHeaderTimeout: c.ReadHeaderTimeout,
-		WriteTimeout:      c.WriteTimeout,
-		IdleTimeout:       c.IdleTimeout,
-		MaxHeaderBytes:    c.MaxHeaderBytes,
-	}
-
-	// dispatch web server go routine
-	go func() {
-		conf := config.Global()
-		if conf != nil {
-			tlsCert := conf.TLSCertFile
-			tlsKey := conf.TLSKeyFile
-			if err := h.ListenAndServeTLS(tlsCert, tlsKey); err != nil {
-				log.WithError(err).Info("Failed to start HTTPS server")
-				stop <- syscall.SIGTERM
-			}
+		if err != nil {
+			log.WithError(err).Errorf("Setup task %s failed", task)
+			return withExitCode(ExitSetupFailed, err)
 		}
-	}()
 
-	slog.Info(commLogMsg.ServiceStart)
-	// TODO dispatch Service status checker goroutine
-	<-stop
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := h.Shutdown(ctx); err != nil {
-		log.WithError(err).Info("Failed to gracefully shutdown webserver")
-		return err
+		return a.chownSetupOutputs(task)
 	}
-	slog.Info(commLogMsg.ServiceStop)
 	return nil
 }
 
@@ -519,6 +744,20 @@ func (a *App) stop() error {
 	return cmd.Run()
 }
 
+// upgrade has the running service hand its listening socket over to the installed binary
+func (a *App) upgrade() error {
+	fmt.Fprintln(a.consoleWriter(), `Forwarding to "systemctl kill -s USR2 sqvs"`)
+	systemctl, err := exec.LookPath("systemctl")
+	if err != nil {
+		return errors.Wrap(err, "app:upgrade() Could not locate systemctl to upgrade application service")
+	}
+	cmd := exec.Command(systemctl, "kill", "-s", "USR2", "--kill-who=main", "sqvs")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	return cmd.Run()
+}
+
 func (a *App) status() error {
 	fmt.Fprintln(a.consoleWriter(), `Forwarding to "systemctl status sqvs"`)
 	systemctl, err := exec.LookPath("systemctl")
@@ -585,6 +824,123 @@ func removeService() {
 	}
 }
 
+// setupRunner returns the runner of the setup tasks, args being the arguments of the setup command and flags
+// those of the tasks, with their progress written to w
+func (a *App) setupRunner(args, flags []string, force bool, setupWriter io.Writer) *tasks.Runner {
+	return &tasks.Runner{
+		Steps: []tasks.Step{
+			{
+				Name: "download_ca_cert",
+				Task: setup.Download_Ca_Cert{
+					Flags:                args,
+					CmsBaseURL:           a.Config.CMSBaseURL,
+					CaCertDirPath:        constants.TrustedCAsStoreDir,
+					TrustedTlsCertDigest: a.Config.CmsTLSCertDigest,
+					ConsoleWriter:        setupWriter,
+				},
+				Outputs: []string{constants.TrustedCAsStoreDir},
+			},
+			{
+				Name: "download_cert",
+				Task: setup.Download_Cert{
+					Flags:              flags,
+					KeyFile:            a.Config.TLSKeyFile,
+					CertFile:           a.Config.TLSCertFile,
+					KeyAlgorithm:       constants.DefaultKeyAlgorithm,
+					KeyAlgorithmLength: constants.DefaultKeyAlgorithmLength,
+					CmsBaseURL:         a.Config.CMSBaseURL,
+					Subject: pkix.Name{
+						CommonName: a.Config.Subject.TLSCertCommonName,
+					},
+					SanList:       a.Config.TLSCertSANList(),
+					CertType:      "TLS",
+					CaCertsDir:    constants.TrustedCAsStoreDir,
+					BearerToken:   "",
+					ConsoleWriter: setupWriter,
+				},
+				Outputs:   []string{a.Config.TLSKeyFile, a.Config.TLSCertFile},
+				DependsOn: []string{"download_ca_cert"},
+			},
+			{
+				Name: "update_service_config",
+				Task: tasks.Update_Service_Config{
+					Flags:                    flags,
+					Config:                   a.configuration(),
+					ConsoleWriter:            setupWriter,
+					TrustedSGXRootCAFilePath: constants.TrustedSGXRootCAFile,
+				},
+				Outputs:   []string{path.Join(constants.ConfigDir, constants.ConfigFile)},
+				Reconcile: true,
+			},
+			{
+				Name: "create_signing_key_pair",
+				Task: tasks.Create_Signing_Key_Pair{
+					Flags:         flags,
+					Config:        a.configuration(),
+					ConsoleWriter: setupWriter,
+				},
+				Outputs:   []string{constants.PrivateKeyLocation, constants.PublicKeyLocation},
+				DependsOn: []string{"download_ca_cert", "update_service_config"},
+			},
+		},
+		Force:         force,
+		ConsoleWriter: setupWriter,
+	}
+}
+
+// chownSetupOutputs gives the sqvs user the ownership of the configuration directory and of the files written by
+// the setup task, outside containers where sqvs never runs as root
+func (a *App) chownSetupOutputs(task string) error {
+	// Containers are always run as non root users, does not require changing ownership of config directories
+	if _, err := os.Stat("/.container-env"); err == nil {
+		return nil
+	}
+
+	sqvsUser, err := user.Lookup(constants.SQVSUserName)
+	if err != nil {
+		return errors.Wrapf(err, "Could not find user '%s'", constants.SQVSUserName)
+	}
+
+	uid, err := strconv.Atoi(sqvsUser.Uid)
+	if err != nil {
+		return errors.Wrapf(err, "Could not parse sqvs user uid '%s'", sqvsUser.Uid)
+	}
+
+	gid, err := strconv.Atoi(sqvsUser.Gid)
+	if err != nil {
+		return errors.Wrapf(err, "Could not parse sqvs user gid '%s'", sqvsUser.Gid)
+	}
+
+	// Change the file ownership to sqvs user
+	err = cos.ChownR(constants.ConfigDir, uid, gid)
+	if err != nil {
+		return errors.Wrap(err, "Error while changing file ownership")
+	}
+	if task == "download_cert" {
+		err = os.Chown(a.Config.TLSKeyFile, uid, gid)
+		if err != nil {
+			return errors.Wrap(err, "Error while changing ownership of TLS Key file")
+		}
+
+		err = os.Chown(a.Config.TLSCertFile, uid, gid)
+		if err != nil {
+			return errors.Wrap(err, "Error while changing ownership of TLS Cert file")
+		}
+	}
+	if task == "create_signing_key_pair" {
+		err = os.Chown(constants.PrivateKeyLocation, uid, gid)
+		if err != nil {
+			return errors.Wrap(err, "Error while changing ownership of TLS Key file")
+		}
+
+		err = os.Chown(constants.PublicKeyLocation, uid, gid)
+		if err != nil {
+			return errors.Wrap(err, "Error while changing ownership of TLS Cert file")
+		}
+	}
+	return nil
+}
+
 func validateSetupArgs(cmd string, args []string) error {
 	switch cmd {
 	default:
@@ -603,69 +959,38 @@ func validateSetupArgs(cmd string, args []string) error {
 		return nil
 
 	case "all":
-		if len(args) != 0 {
-			return errors.New("Please setup the arguments with env")
+		for _, arg := range args {
+			if arg != "--force" {
+				return errors.New("Please setup the arguments with env or an answers file")
+			}
 		}
 	}
 	return nil
 }
 
-func fnGetJwtCerts() error {
-	conf := config.Global()
-	if conf == nil {
-		return errors.New("failed to read config")
-	}
-	if !strings.HasSuffix(conf.AuthServiceURL, "/") {
-		conf.AuthServiceURL += "/"
-	}
-	url := conf.AuthServiceURL + "jwt-certificates"
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return errors.Wrap(err, "Could not create http request")
-	}
-	req.Header.Add("accept", "application/x-pem-file")
-	rootCaCertPems, err := cos.GetDirFileContents(constants.TrustedCAsStoreDir, "*.pem")
-	if err != nil {
-		return errors.Wrap(err, "Could not read root CA certificate")
-	}
-	// Get the SystemCertPool, continue with an empty pool on error
-	rootCAs, _ := x509.SystemCertPool()
-	if rootCAs == nil {
-		rootCAs = x509.NewCertPool()
-	}
-	for _, rootCACert := range rootCaCertPems {
-		if ok := rootCAs.AppendCertsFromPEM(rootCACert); !ok {
-			return err
-		}
-	}
-	httpClient := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: false,
-				RootCAs:            rootCAs,
-			},
-		},
-	}
-
-	res, err := httpClient.Do(req)
-	if err != nil {
-		log.Error("Failed to fetch JWT cert")
-		return errors.Wrap(err, "Could not retrieve jwt certificate")
-	}
-	if res != nil {
-		defer func() {
-			derr := res.Body.Close()
-			if derr != nil {
-				log.WithError(derr).Error("Error closing jwtcert response")
+// parseSetupFlags removes the --file answers file flag from the setup arguments, and reports whether
+// --force is set. --force is kept in the returned arguments for the tasks parsing it themselves.
+func parseSetupFlags(args []string) (string, bool, []string, error) {
+	var answersFile string
+	var force bool
+	remaining := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case strings.HasPrefix(arg, "--file="):
+			answersFile = strings.TrimPrefix(arg, "--file=")
+		case arg == "--file":
+			if i+1 >= len(args) {
+				return "", false, nil, errors.New("--file requires a value")
 			}
-		}()
-	}
-
-	body, _ := ioutil.ReadAll(res.Body)
-	err = crypt.SavePemCertWithShortSha1FileName(body, constants.TrustedJWTSigningCertsDir)
-	if err != nil {
-		return errors.Wrap(err, "Could not store Certificate")
+			i++
+			answersFile = args[i]
+		default:
+			if arg == "--force" || arg == "-force" {
+				force = true
+			}
+			remaining = append(remaining, arg)
+		}
 	}
-
-	return nil
+	return answersFile, force, remaining, nil
 }
@@ -0,0 +1,152 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"intel/isecl/sqvs/v4/bench"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/resilience"
+	"intel/isecl/sqvs/v4/resource"
+	"intel/isecl/sqvs/v4/trustedtime"
+	"intel/isecl/sqvs/v4/truststore"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// benchCommand drives the quote verifier with the quotes of a directory and reports the throughput and latency
+// percentiles. Quotes are verified in process, with the configuration of the service, or by the SQVS at --url.
+func (a *App) benchCommand(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	var quotesDir, target string
+	var opts bench.Options
+	fs.StringVar(&quotesDir, "quotes", "", "directory of the quotes to verify")
+	fs.IntVar(&opts.Concurrency, "concurrency", 1, "number of concurrent requests")
+	fs.DurationVar(&opts.Duration, "duration", 60*time.Second, "duration of the benchmark")
+	fs.StringVar(&target, "url", "", "base URL of a remote SQVS, https://<host>:<port>/svs/v1")
+	err := fs.Parse(args)
+	if err != nil {
+		return errors.Wrap(err, "app:benchCommand() Invalid bench arguments")
+	}
+	if quotesDir == "" || opts.Concurrency < 1 || opts.Duration <= 0 {
+		a.printUsage()
+		return errors.New("app:benchCommand() bench requires --quotes, a positive --concurrency and --duration")
+	}
+
+	requests, err := bench.LoadQuotes(quotesDir)
+	if err != nil {
+		return err
+	}
+
+	var verify func([]byte) error
+	if target != "" {
+		verify, err = remoteVerifier(target)
+	} else {
+		verify, err = a.inProcessVerifier()
+	}
+	if err != nil {
+		return err
+	}
+
+	report := bench.Run(opts, requests, verify)
+	report.Target = target
+	if report.Target == "" {
+		report.Target = "in-process"
+	}
+	if a.outputFormat == outputJSON {
+		return a.printJSON(report)
+	}
+	w := a.consoleWriter()
+	fmt.Fprintf(w, "Target:      %s\n", report.Target)
+	fmt.Fprintf(w, "Concurrency: %d\n", report.Concurrency)
+	fmt.Fprintf(w, "Duration:    %s\n", report.Duration.Round(time.Millisecond))
+	fmt.Fprintf(w, "Requests:    %d (%d failed)\n", report.Requests, report.Failures)
+	fmt.Fprintf(w, "Throughput:  %.1f requests/s\n", report.Throughput)
+	fmt.Fprintf(w, "Latency:     min %s, mean %s, p50 %s, p90 %s, p95 %s, p99 %s, max %s\n",
+		report.Latency.Min, report.Latency.Mean, report.Latency.P50, report.Latency.P90, report.Latency.P95,
+		report.Latency.P99, report.Latency.Max)
+	for message, count := range report.Errors {
+		fmt.Fprintf(w, "Error:       %d x %s\n", count, message)
+	}
+	return nil
+}
+
+// inProcessVerifier verifies the requests in process, once its dependencies are initialized
+func (a *App) inProcessVerifier() (func([]byte) error, error) {
+	err := a.initInProcessVerification()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(request []byte) error {
+		var data resource.QuoteDataWithChallenge
+		err := json.Unmarshal(request, &data)
+		if err != nil {
+			return err
+		}
+		_, err = resource.SgxEcdsaQuoteVerify(context.Background(), data)
+		return err
+	}, nil
+}
+
+// initInProcessVerification initializes the dependencies of the quote verifier as the service does
+func (a *App) initInProcessVerification() error {
+	c := a.configuration()
+	caStore, err := truststore.New(constants.TrustedCAsStoreDir)
+	if err != nil {
+		return errors.Wrap(err, "app:initInProcessVerification() Error loading trusted CA certificates")
+	}
+	truststore.Register(caStore)
+	resilience.SetDefault(resilience.NewPolicy(c.Outbound))
+	clock, err := trustedtime.New(c.TrustedTime, make(chan struct{}))
+	if err != nil {
+		return errors.Wrap(err, "app:initInProcessVerification() Error initializing the trusted time source")
+	}
+	trustedtime.SetDefault(clock)
+	return nil
+}
+
+// remoteVerifier posts the requests to the quote verification endpoint of the SQVS at baseURL, authenticated
+// with the BEARER_TOKEN environment variable when set
+func remoteVerifier(baseURL string) (func([]byte) error, error) {
+	client, err := truststore.HTTPClient(constants.TrustedCAsStoreDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "app:remoteVerifier() Error loading trusted CA certificates")
+	}
+	endpoint := strings.TrimSuffix(baseURL, "/") + "/sgx_qv_verify_quote"
+	token := os.Getenv("BEARER_TOKEN")
+
+	return func(request []byte) error {
+		req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(request))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			return errors.Errorf("status %d", resp.StatusCode)
+		}
+		return nil
+	}, nil
+}
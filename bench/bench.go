@@ -0,0 +1,171 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package bench
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maxErrorKinds bounds the distinct error messages counted in a report
+const maxErrorKinds = 20
+
+// Options drive a benchmark: Concurrency workers send requests, as fast as they are answered, for Duration
+type Options struct {
+	Concurrency int
+	Duration    time.Duration
+}
+
+// Latencies are the percentiles of the latency of the requests of a benchmark
+type Latencies struct {
+	Min  time.Duration `json:"min"`
+	Mean time.Duration `json:"mean"`
+	P50  time.Duration `json:"p50"`
+	P90  time.Duration `json:"p90"`
+	P95  time.Duration `json:"p95"`
+	P99  time.Duration `json:"p99"`
+	Max  time.Duration `json:"max"`
+}
+
+// Report is the outcome of a benchmark, Throughput being the number of requests completed per second
+type Report struct {
+	Target      string         `json:"target"`
+	Concurrency int            `json:"concurrency"`
+	Duration    time.Duration  `json:"duration"`
+	Requests    int            `json:"requests"`
+	Failures    int            `json:"failures"`
+	Throughput  float64        `json:"throughput"`
+	Latency     Latencies      `json:"latency"`
+	Errors      map[string]int `json:"errors,omitempty"`
+}
+
+// LoadQuotes reads the quote verification requests of the files of dir. JSON files are request bodies, the
+// other files are quotes, binary or base64 encoded, sent without user data.
+func LoadQuotes(dir string) ([][]byte, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "bench/bench:LoadQuotes() Error reading quotes directory")
+	}
+	var requests [][]byte
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		content, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, errors.Wrap(err, "bench/bench:LoadQuotes() Error reading quote")
+		}
+		if strings.HasSuffix(file.Name(), ".json") {
+			if !json.Valid(content) {
+				return nil, errors.Errorf("bench/bench:LoadQuotes() %s is not a valid JSON request", file.Name())
+			}
+			requests = append(requests, content)
+			continue
+		}
+		quote := strings.TrimSpace(string(content))
+		if _, err := base64.StdEncoding.DecodeString(quote); err != nil {
+			quote = base64.StdEncoding.EncodeToString(content)
+		}
+		request, err := json.Marshal(map[string]string{"quote": quote})
+		if err != nil {
+			return nil, errors.Wrap(err, "bench/bench:LoadQuotes() Error encoding request")
+		}
+		requests = append(requests, request)
+	}
+	if len(requests) == 0 {
+		return nil, errors.New("bench/bench:LoadQuotes() No quote found in " + dir)
+	}
+	return requests, nil
+}
+
+// Run sends the requests in turn, from opts.Concurrency workers, to verify until opts.Duration has elapsed and
+// reports the throughput and latencies. A request fails when verify returns an error.
+func Run(opts Options, requests [][]byte, verify func(request []byte) error) Report {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	var (
+		next      uint64
+		mu        sync.Mutex
+		latencies []time.Duration
+		failures  int
+		errs      = make(map[string]int)
+		wg        sync.WaitGroup
+	)
+
+	start := time.Now()
+	deadline := start.Add(opts.Duration)
+	for w := 0; w < opts.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				request := requests[atomic.AddUint64(&next, 1)%uint64(len(requests))]
+				sent := time.Now()
+				err := verify(request)
+				latency := time.Since(sent)
+
+				mu.Lock()
+				latencies = append(latencies, latency)
+				if err != nil {
+					failures++
+					if _, ok := errs[err.Error()]; ok || len(errs) < maxErrorKinds {
+						errs[err.Error()]++
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	report := Report{
+		Concurrency: opts.Concurrency,
+		Duration:    elapsed,
+		Requests:    len(latencies),
+		Failures:    failures,
+		Latency:     percentiles(latencies),
+	}
+	if elapsed > 0 {
+		report.Throughput = float64(len(latencies)) / elapsed.Seconds()
+	}
+	if len(errs) > 0 {
+		report.Errors = errs
+	}
+	return report
+}
+
+func percentiles(latencies []time.Duration) Latencies {
+	if len(latencies) == 0 {
+		return Latencies{}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+	at := func(p float64) time.Duration {
+		return latencies[int(p*float64(len(latencies)-1))]
+	}
+	return Latencies{
+		Min:  latencies[0],
+		Mean: total / time.Duration(len(latencies)),
+		P50:  at(0.50),
+		P90:  at(0.90),
+		P95:  at(0.95),
+		P99:  at(0.99),
+		Max:  latencies[len(latencies)-1],
+	}
+}
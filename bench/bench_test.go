@@ -0,0 +1,59 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package bench
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadQuotes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quotes")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a.json"), []byte(`{"quote":"AwACAA==","userData":""}`), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "b.dat"), []byte{0x03, 0x00, 0x02, 0xff}, 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "c.b64"), []byte("AwACAA==\n"), 0600))
+
+	requests, err := LoadQuotes(dir)
+	assert.NoError(t, err)
+	assert.Len(t, requests, 3)
+	var request map[string]string
+	assert.NoError(t, json.Unmarshal(requests[1], &request))
+	assert.Equal(t, "AwAC/w==", request["quote"])
+	assert.NoError(t, json.Unmarshal(requests[2], &request))
+	assert.Equal(t, "AwACAA==", request["quote"])
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "d.json"), []byte(`{"quote"`), 0600))
+	_, err = LoadQuotes(dir)
+	assert.Error(t, err)
+}
+
+func TestRun(t *testing.T) {
+	var calls int
+	report := Run(Options{Concurrency: 1, Duration: 50 * time.Millisecond}, [][]byte{[]byte("a"), []byte("b")},
+		func(request []byte) error {
+			calls++
+			time.Sleep(time.Millisecond)
+			if string(request) == "b" {
+				return errors.New("invalid quote")
+			}
+			return nil
+		})
+	assert.Equal(t, calls, report.Requests)
+	assert.True(t, report.Failures > 0 && report.Failures < report.Requests)
+	assert.Equal(t, report.Failures, report.Errors["invalid quote"])
+	assert.True(t, report.Throughput > 0)
+	assert.True(t, report.Latency.Min >= time.Millisecond)
+	assert.True(t, report.Latency.Min <= report.Latency.P50 && report.Latency.P99 <= report.Latency.Max)
+}
@@ -0,0 +1,141 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"intel/isecl/lib/common/v4/crypt"
+	"intel/isecl/lib/common/v4/setup"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/server"
+	"intel/isecl/sqvs/v4/tasks"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// bootstrapCommand serves the one-time bootstrap API on a loopback address until a bootstrap request completes
+// setup, so sqvs is provisioned through an API instead of running setup from a shell. It returns at once
+// when setup has already been completed.
+func (a *App) bootstrapCommand(args []string) error {
+	fs := flag.NewFlagSet("bootstrap", flag.ContinueOnError)
+	var listen string
+	fs.StringVar(&listen, "listen", constants.DefaultBootstrapListenAddr, "loopback address the bootstrap API listens on")
+	err := fs.Parse(args)
+	if err != nil {
+		return usageError(errors.Wrap(err, "app:bootstrapCommand() Invalid bootstrap arguments"))
+	}
+	err = tasks.CheckLoopbackAddr(listen)
+	if err != nil {
+		return usageError(err)
+	}
+
+	w := a.consoleWriter()
+	if setupCompleted(a.configuration()) {
+		fmt.Fprintln(w, "Setup has already been completed, the bootstrap API is disabled")
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return errors.Wrapf(err, "app:bootstrapCommand() Error listening on %s", listen)
+	}
+	token, err := tasks.NewBootstrapToken()
+	if err != nil {
+		return errors.Wrap(err, "app:bootstrapCommand() Error generating the bootstrap token")
+	}
+	bootstrapper := tasks.NewBootstrapper(token, a.runBootstrap)
+	mux := http.NewServeMux()
+	mux.Handle("/bootstrap", bootstrapper)
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: constants.DefaultReadHeaderTimeout}
+	go func() {
+		serr := srv.Serve(listener)
+		if serr != nil && serr != http.ErrServerClosed {
+			log.WithError(serr).Error("app:bootstrapCommand() Error serving the bootstrap API")
+		}
+	}()
+	slog.Infof("app:bootstrapCommand() Bootstrap API listening on %s", listen)
+	fmt.Fprintf(w, "Waiting for the bootstrap request on http://%s/bootstrap\n", listen)
+	fmt.Fprintf(w, "Bootstrap token (send as \"Authorization: Bearer <token>\"): %s\n", token)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	select {
+	case <-bootstrapper.Done():
+		fmt.Fprintln(w, "Bootstrap completed, sqvs can be started")
+	case <-ctx.Done():
+		err = withExitCode(ExitSetupIncomplete, errors.New("app:bootstrapCommand() Bootstrap interrupted "+
+			"before setup was completed"))
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), constants.DefaultWriteTimeout)
+	defer cancel()
+	if serr := srv.Shutdown(shutdownCtx); serr != nil {
+		log.WithError(serr).Error("app:bootstrapCommand() Error shutting down the bootstrap API")
+	}
+	return err
+}
+
+// runBootstrap runs the setup tasks with the inputs of the bootstrap request, trusting its CMS root CA
+// instead of downloading it from CMS
+func (a *App) runBootstrap(req tasks.BootstrapRequest) (tasks.Summary, error) {
+	if setupCompleted(a.configuration()) {
+		return tasks.Summary{}, errors.New("app:runBootstrap() Setup has already been completed")
+	}
+	rootCA, err := req.RootCA()
+	if err != nil {
+		return tasks.Summary{}, err
+	}
+	err = os.MkdirAll(constants.TrustedCAsStoreDir, 0755)
+	if err != nil {
+		return tasks.Summary{}, errors.Wrap(err, "app:runBootstrap() Error creating the trusted CAs directory")
+	}
+	err = crypt.SavePemCertWithShortSha1FileName(rootCA, constants.TrustedCAsStoreDir)
+	if err != nil {
+		return tasks.Summary{}, errors.Wrap(err, "app:runBootstrap() Error saving the CMS root CA")
+	}
+	applied, err := req.Apply()
+	if err != nil {
+		return tasks.Summary{}, err
+	}
+	slog.Infof("app:runBootstrap() Setup inputs set by the bootstrap request: %s", strings.Join(applied, ", "))
+
+	a.Config = config.Global()
+	err = a.Config.SaveConfiguration("all", setup.Context{})
+	if err != nil {
+		return tasks.Summary{}, errors.Wrap(err, "app:runBootstrap() Error saving configuration")
+	}
+	w := a.consoleWriter()
+	// the CMS root CA of the request replaces download_ca_cert
+	summary, err := a.setupRunner(nil, nil, false, w).RunTasks("download_cert", "update_service_config",
+		"create_signing_key_pair")
+	summary.Print(w)
+	if err != nil {
+		slog.WithError(err).Error("app:runBootstrap() Bootstrap setup failed")
+		return summary, err
+	}
+	err = a.chownSetupOutputs("all")
+	if err != nil {
+		return summary, err
+	}
+	slog.Info("app:runBootstrap() Setup completed by the bootstrap request")
+	return summary, nil
+}
+
+// setupCompleted tells whether the files created by setup exist, the bootstrap API being disabled once they do
+func setupCompleted(c *config.Configuration) bool {
+	tlsCertFile := c.TLSCertFile
+	if tlsCertFile == "" {
+		tlsCertFile = constants.DefaultTLSCertFile
+	}
+	return server.CheckSetupComplete(tlsCertFile) == nil
+}
@@ -0,0 +1,232 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// ManifestFile is the entry of the bundle describing it
+const ManifestFile = "manifest.yml"
+
+// maxEntrySize bounds the size of an extracted entry, configuration and trust stores being small
+const maxEntrySize = 16 << 20
+
+// Entries lists the files and directories, relative to the configuration directory, a bundle may carry: the
+// configuration, the custom claims policy, the trust stores and the relying party keys. Directories end with
+// a slash.
+var Entries = []string{
+	"config.yml",
+	"custom-claims.yml",
+	"custom-claims.yml.jws",
+	"certs/trustedSGXRootCA.pem",
+	"certs/trustedca/",
+	"certs/trustedjwt/",
+	"certs/relying-parties/",
+	"certs/policy-authors/",
+}
+
+// Manifest describes a configuration bundle
+type Manifest struct {
+	Version     string `yaml:"version"`
+	Description string `yaml:"description,omitempty"`
+}
+
+// Bundle is a verified configuration bundle
+type Bundle struct {
+	Manifest Manifest
+	Files    map[string][]byte
+}
+
+// LoadPublicKey reads the operator public key, a PEM encoded PKIX public key or certificate, the bundles are
+// signed with
+func LoadPublicKey(file string) (crypto.PublicKey, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "bundle/bundle:LoadPublicKey() Error reading public key")
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("bundle/bundle:LoadPublicKey() Public key is not PEM encoded")
+	}
+	var key crypto.PublicKey
+	if block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "bundle/bundle:LoadPublicKey() Invalid certificate")
+		}
+		key = cert.PublicKey
+	} else {
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "bundle/bundle:LoadPublicKey() Invalid public key")
+		}
+	}
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	}
+	return nil, errors.New("bundle/bundle:LoadPublicKey() Public key must be an RSA or ECDSA key")
+}
+
+// Verify checks the detached signature of the archive, a SHA-384 RSA PKCS#1 v1.5, RSA-PSS or ASN.1 ECDSA
+// signature raw or base64 encoded as written by openssl dgst -sha384 -sign, then reads the archive
+func Verify(archive, signature []byte, publicKey crypto.PublicKey) (*Bundle, error) {
+	digest := sha512.Sum384(archive)
+	if !verifySignature(publicKey, digest[:], signature) {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+		if err != nil || !verifySignature(publicKey, digest[:], decoded) {
+			return nil, errors.New("bundle/bundle:Verify() Invalid bundle signature")
+		}
+	}
+	return read(archive)
+}
+
+func verifySignature(publicKey crypto.PublicKey, digest, signature []byte) bool {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA384, digest, signature) == nil ||
+			rsa.VerifyPSS(key, crypto.SHA384, digest, signature, nil) == nil
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, digest, signature)
+	}
+	return false
+}
+
+// read reads the entries of the gzip compressed tar archive, refusing any it may not carry
+func read(archive []byte) (*Bundle, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, errors.Wrap(err, "bundle/bundle:read() Bundle is not gzip compressed")
+	}
+	b := &Bundle{Files: map[string][]byte{}}
+	var manifest []byte
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "bundle/bundle:read() Invalid bundle archive")
+		}
+		if hdr.Typeflag == tar.TypeDir {
+			continue
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return nil, errors.Errorf("bundle/bundle:read() Bundle entry %s is not a regular file", hdr.Name)
+		}
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		if name != ManifestFile && !Allowed(name) {
+			return nil, errors.Errorf("bundle/bundle:read() Bundle entry %s is not allowed", hdr.Name)
+		}
+		if hdr.Size > maxEntrySize {
+			return nil, errors.Errorf("bundle/bundle:read() Bundle entry %s is too large", hdr.Name)
+		}
+		content, err := ioutil.ReadAll(io.LimitReader(tr, maxEntrySize))
+		if err != nil {
+			return nil, errors.Wrapf(err, "bundle/bundle:read() Error reading bundle entry %s", hdr.Name)
+		}
+		if name == ManifestFile {
+			manifest = content
+			continue
+		}
+		b.Files[name] = content
+	}
+	if manifest == nil {
+		return nil, errors.New("bundle/bundle:read() Bundle has no manifest")
+	}
+	if err := yaml.Unmarshal(manifest, &b.Manifest); err != nil {
+		return nil, errors.Wrap(err, "bundle/bundle:read() Invalid bundle manifest")
+	}
+	if strings.TrimSpace(b.Manifest.Version) == "" {
+		return nil, errors.New("bundle/bundle:read() Bundle manifest has no version")
+	}
+	return b, nil
+}
+
+// Allowed tells whether a bundle may carry the file, a clean path relative to the configuration directory
+func Allowed(name string) bool {
+	if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+		return false
+	}
+	for _, entry := range Entries {
+		if strings.HasSuffix(entry, "/") {
+			if strings.HasPrefix(name, entry) && !strings.Contains(strings.TrimPrefix(name, entry), "/") {
+				return true
+			}
+		} else if name == entry {
+			return true
+		}
+	}
+	return false
+}
+
+// Install writes the files of the bundle to the configuration directory, replacing the ones there
+func (b *Bundle) Install(dir string) error {
+	for name, content := range b.Files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return errors.Wrapf(err, "bundle/bundle:Install() Error creating directory of %s", name)
+		}
+		// written aside then renamed so a failure never leaves a partial file behind
+		tmp := file + ".bundle"
+		if err := ioutil.WriteFile(tmp, content, 0640); err != nil {
+			return errors.Wrapf(err, "bundle/bundle:Install() Error writing %s", name)
+		}
+		if err := os.Rename(tmp, file); err != nil {
+			_ = os.Remove(tmp)
+			return errors.Wrapf(err, "bundle/bundle:Install() Error installing %s", name)
+		}
+	}
+	return nil
+}
+
+// Apply verifies the bundle against the operator public key and installs it into the configuration
+// directory. Bundles are not in use, and nil is returned, when there is no public key. Once there is one the
+// bundle is required, a missing, tampered or unsigned bundle being refused.
+func Apply(bundleFile, signatureFile, publicKeyFile, dir string) (*Manifest, error) {
+	if _, err := os.Stat(publicKeyFile); os.IsNotExist(err) {
+		return nil, nil
+	}
+	publicKey, err := LoadPublicKey(publicKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := ioutil.ReadFile(bundleFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "bundle/bundle:Apply() Error reading configuration bundle")
+	}
+	signature, err := ioutil.ReadFile(signatureFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "bundle/bundle:Apply() Error reading configuration bundle signature")
+	}
+	b, err := Verify(archive, signature, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.Install(dir); err != nil {
+		return nil, err
+	}
+	return &b.Manifest, nil
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func archive(t *testing.T, files map[string]string) []byte {
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0640, Size: int64(len(content)),
+			Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gz.Close())
+	return b.Bytes()
+}
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, content []byte) []byte {
+	digest := sha512.Sum384(content)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	assert.NoError(t, err)
+	return signature
+}
+
+func writePublicKey(t *testing.T, file string, key *ecdsa.PublicKey) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+}
+
+func TestVerify(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+	content := archive(t, map[string]string{
+		ManifestFile:                 "version: 2021.10-3\n",
+		"config.yml":                 "loglevel: info\n",
+		"./certs/trustedca/root.pem": "root",
+	})
+
+	b, err := Verify(content, sign(t, key, content), &key.PublicKey)
+	assert.NoError(t, err)
+	assert.Equal(t, "2021.10-3", b.Manifest.Version)
+	assert.Equal(t, "root", string(b.Files["certs/trustedca/root.pem"]))
+
+	signature := []byte(base64.StdEncoding.EncodeToString(sign(t, key, content)) + "\n")
+	_, err = Verify(content, signature, &key.PublicKey)
+	assert.NoError(t, err)
+
+	tampered := append([]byte{}, content...)
+	tampered[len(tampered)-1] ^= 1
+	_, err = Verify(tampered, sign(t, key, content), &key.PublicKey)
+	assert.Error(t, err)
+
+	other, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+	_, err = Verify(content, sign(t, other, content), &key.PublicKey)
+	assert.Error(t, err)
+
+	for _, files := range []map[string]string{
+		{"config.yml": "loglevel: info\n"},
+		{ManifestFile: "description: no version\n"},
+		{ManifestFile: "version: 1\n", "../etc/passwd": "root"},
+		{ManifestFile: "version: 1\n", "tls.key": "key"},
+		{ManifestFile: "version: 1\n", "certs/trustedca/sub/root.pem": "root"},
+	} {
+		content := archive(t, files)
+		_, err = Verify(content, sign(t, key, content), &key.PublicKey)
+		assert.Error(t, err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bundle")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	bundleFile := filepath.Join(dir, "config-bundle.tar.gz")
+	signatureFile := bundleFile + ".sig"
+	publicKeyFile := filepath.Join(dir, "config-bundle-pub-key.pem")
+
+	manifest, err := Apply(bundleFile, signatureFile, publicKeyFile, dir)
+	assert.NoError(t, err)
+	assert.Nil(t, manifest)
+
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+	writePublicKey(t, publicKeyFile, &key.PublicKey)
+	_, err = Apply(bundleFile, signatureFile, publicKeyFile, dir)
+	assert.Error(t, err)
+
+	content := archive(t, map[string]string{ManifestFile: "version: \"7\"\n", "config.yml": "loglevel: debug\n"})
+	assert.NoError(t, ioutil.WriteFile(bundleFile, content, 0600))
+	assert.NoError(t, ioutil.WriteFile(signatureFile, sign(t, key, content), 0600))
+	manifest, err = Apply(bundleFile, signatureFile, publicKeyFile, dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "7", manifest.Version)
+	config, err := ioutil.ReadFile(filepath.Join(dir, "config.yml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "loglevel: debug\n", string(config))
+}
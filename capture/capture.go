@@ -0,0 +1,257 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package capture
+
+import (
+	"bytes"
+	"encoding/json"
+	commLog "intel/isecl/lib/common/v4/log"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var log = commLog.GetDefaultLogger()
+
+// Session is a payload capture, recording the requests of Client, when set, to the paths under PathPrefix,
+// when set, until Until
+type Session struct {
+	Started    time.Time `json:"started"`
+	Until      time.Time `json:"until"`
+	Client     string    `json:"client,omitempty"`
+	PathPrefix string    `json:"pathPrefix,omitempty"`
+	StartedBy  string    `json:"startedBy,omitempty"`
+	File       string    `json:"file"`
+	Captured   int64     `json:"captured"`
+}
+
+// Record is a captured request and its response, redacted
+type Record struct {
+	Time            time.Time         `json:"time"`
+	Client          string            `json:"client"`
+	Method          string            `json:"method"`
+	Path            string            `json:"path"`
+	Query           string            `json:"query,omitempty"`
+	RequestHeaders  map[string]string `json:"requestHeaders"`
+	RequestBody     interface{}       `json:"requestBody,omitempty"`
+	Status          int               `json:"status"`
+	Duration        string            `json:"duration"`
+	ResponseHeaders map[string]string `json:"responseHeaders"`
+	ResponseBody    interface{}       `json:"responseBody,omitempty"`
+	Truncated       bool              `json:"truncated,omitempty"`
+}
+
+// Recorder captures the requests and responses matching the current session as JSON lines appended to its
+// file, redacted by its redactor, bodies being truncated at maxBodySize bytes. Nothing is captured outside of
+// a session, sessions end on their own once their time is up.
+type Recorder struct {
+	file        string
+	maxBodySize int
+	redactor    *Redactor
+	now         func() time.Time
+
+	mu      sync.Mutex
+	session *Session
+	out     *os.File
+	timer   *time.Timer
+}
+
+// NewRecorder returns the recorder capturing to file
+func NewRecorder(file string, maxBodySize int, redactor *Redactor) *Recorder {
+	return &Recorder{file: file, maxBodySize: maxBodySize, redactor: redactor, now: time.Now}
+}
+
+// Start starts a session capturing for duration, replacing the current one
+func (c *Recorder) Start(duration time.Duration, client, pathPrefix, startedBy string) (Session, error) {
+	if duration <= 0 {
+		return Session{}, errors.New("capture/capture:Start() Capture duration must be positive")
+	}
+	out, err := os.OpenFile(c.file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return Session{}, errors.Wrap(err, "capture/capture:Start() Error opening capture file")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopLocked()
+	now := c.now()
+	c.session = &Session{Started: now.UTC(), Until: now.Add(duration).UTC(), Client: client, PathPrefix: pathPrefix,
+		StartedBy: startedBy, File: c.file}
+	c.out = out
+	session := c.session
+	c.timer = time.AfterFunc(duration, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.session == session {
+			log.Infof("capture/capture:Start() Payload capture to %s ended after %d requests", c.file,
+				session.Captured)
+			c.stopLocked()
+		}
+	})
+	return *c.session, nil
+}
+
+// Stop ends the current session, it returns false when none is running
+func (c *Recorder) Stop() (Session, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.session == nil {
+		return Session{}, false
+	}
+	session := *c.session
+	c.stopLocked()
+	return session, true
+}
+
+// Session returns the current session, false when none is running
+func (c *Recorder) Session() (Session, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.session == nil || !c.now().Before(c.session.Until) {
+		return Session{}, false
+	}
+	return *c.session, true
+}
+
+func (c *Recorder) stopLocked() {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if c.out != nil {
+		err := c.out.Close()
+		if err != nil {
+			log.WithError(err).Error("capture/capture:stopLocked() Error closing capture file")
+		}
+		c.out = nil
+	}
+	c.session = nil
+}
+
+// matches tells whether the request of client is captured by the current session
+func (c *Recorder) matches(r *http.Request, client string) bool {
+	session, ok := c.Session()
+	if !ok {
+		return false
+	}
+	return (session.Client == "" || session.Client == client) &&
+		(session.PathPrefix == "" || strings.HasPrefix(r.URL.Path, session.PathPrefix))
+}
+
+// Middleware captures the requests matching the current session, clientOf returning the address of the client
+// of a request
+func (c *Recorder) Middleware(clientOf func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client := clientOf(r)
+			if !c.matches(r, client) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			started := c.now()
+			requestBody := &cappedBuffer{max: c.maxBodySize}
+			if r.Body != nil {
+				r.Body = readCloser{Reader: io.TeeReader(r.Body, requestBody), Closer: r.Body}
+			}
+			cw := &captureWriter{ResponseWriter: w, body: cappedBuffer{max: c.maxBodySize}}
+			next.ServeHTTP(cw, r)
+
+			if cw.status == 0 {
+				cw.status = http.StatusOK
+			}
+			c.write(Record{
+				Time:            started.UTC(),
+				Client:          client,
+				Method:          r.Method,
+				Path:            r.URL.Path,
+				Query:           c.redactor.Query(r.URL.RawQuery),
+				RequestHeaders:  c.redactor.Headers(r.Header),
+				RequestBody:     c.redactor.Body(r.Header.Get("Content-Type"), requestBody.Bytes(), requestBody.truncated),
+				Status:          cw.status,
+				Duration:        c.now().Sub(started).String(),
+				ResponseHeaders: c.redactor.Headers(w.Header()),
+				ResponseBody:    c.redactor.Body(w.Header().Get("Content-Type"), cw.body.Bytes(), cw.body.truncated),
+				Truncated:       requestBody.truncated || cw.body.truncated,
+			})
+		})
+	}
+}
+
+func (c *Recorder) write(record Record) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		log.WithError(err).Error("capture/capture:write() Error marshalling captured request")
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.out == nil {
+		return
+	}
+	_, err = c.out.Write(append(line, '\n'))
+	if err != nil {
+		log.WithError(err).Error("capture/capture:write() Error writing captured request")
+		return
+	}
+	c.session.Captured++
+}
+
+// cappedBuffer keeps the first max bytes written to it
+type cappedBuffer struct {
+	bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// captureWriter copies the response written to the client
+type captureWriter struct {
+	http.ResponseWriter
+	status int
+	body   cappedBuffer
+}
+
+func (w *captureWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *captureWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	_, _ = w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush keeps streamed responses flowing to the client while they are captured
+func (w *captureWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
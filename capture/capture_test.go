@@ -0,0 +1,124 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package capture
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactor(t *testing.T) {
+	r := NewRedactor([]string{"tenantSecret"})
+
+	headers := r.Headers(http.Header{"Authorization": {"Bearer eyJ..."}, "Content-Type": {"application/json"}})
+	assert.Equal(t, map[string]string{"Authorization": Redacted, "Content-Type": "application/json"}, headers)
+	assert.Equal(t, "challenge=abc&token=%5BREDACTED%5D", r.Query("token=secret&challenge=abc"))
+
+	body := r.Body("application/json", []byte(`{"quote":"AwAC","userData":"dXNlcg==",`+
+		`"nested":[{"TenantSecret":"s","Quote":"AwAC"}]}`), false)
+	assert.Equal(t, map[string]interface{}{"quote": Redacted, "userData": "dXNlcg==",
+		"nested": []interface{}{map[string]interface{}{"TenantSecret": Redacted, "Quote": Redacted}}}, body)
+
+	// the quote data of signed responses is base64 encoded JSON
+	signed := base64.StdEncoding.EncodeToString([]byte(`{"Quote":"AwAC","TcbLevel":"UpToDate"}`))
+	assert.Equal(t, map[string]interface{}{"quoteData": map[string]interface{}{"Quote": Redacted,
+		"TcbLevel": "UpToDate"}}, r.Body("application/json", []byte(`{"quoteData":"`+signed+`"}`), false))
+
+	assert.Equal(t, "[REDACTED 4 bytes of application/octet-stream]",
+		r.Body("application/octet-stream", []byte{3, 0, 2, 0}, false))
+	assert.Equal(t, "[REDACTED 8 bytes of application/json]", r.Body("application/json", []byte(`{"quote"`), true))
+	assert.Nil(t, r.Body("application/json", nil, false))
+}
+
+func TestRedactorSessionToken(t *testing.T) {
+	r := NewRedactor(nil)
+
+	// the session token is returned by /sessions and sent back with the quotes in JSON bodies and queries
+	assert.Equal(t, map[string]interface{}{"sessionToken": Redacted, "expiresAt": "2021-06-01T10:05:00Z"},
+		r.Body("application/json", []byte(`{"sessionToken":"eyJ...","expiresAt":"2021-06-01T10:05:00Z"}`), false))
+	assert.Equal(t, map[string]interface{}{"quote": Redacted, "sessionToken": Redacted},
+		r.Body("application/json", []byte(`{"quote":"AwAC","sessionToken":"eyJ..."}`), false))
+	assert.Equal(t, "sessionToken=%5BREDACTED%5D&userData=dXNlcg%3D%3D",
+		r.Query("sessionToken=eyJ...&userData=dXNlcg%3D%3D"))
+}
+
+func TestRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqvs-capture")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "capture.log")
+
+	recorder := NewRecorder(file, 1024, NewRedactor(nil))
+	handler := recorder.Middleware(func(r *http.Request) string {
+		return r.Header.Get("X-Client")
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"echo":` + string(body) + `}`))
+	}))
+	send := func(client, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"quote":"AwAC"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Client", client)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// nothing is captured outside of a session
+	send("10.0.0.1", "/svs/v2/sgx_qv_verify_quote")
+	_, err = os.Stat(file)
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = recorder.Start(time.Minute, "10.0.0.1", "/svs/v2/", "admin")
+	assert.NoError(t, err)
+	w := send("10.0.0.1", "/svs/v2/sgx_qv_verify_quote")
+	assert.Equal(t, `{"echo":{"quote":"AwAC"}}`, w.Body.String())
+	send("10.0.0.2", "/svs/v2/sgx_qv_verify_quote")
+	send("10.0.0.1", "/svs/v1/sgx_qv_verify_quote")
+	session, ok := recorder.Session()
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), session.Captured)
+
+	session, ok = recorder.Stop()
+	assert.True(t, ok)
+	send("10.0.0.1", "/svs/v2/sgx_qv_verify_quote")
+	_, ok = recorder.Session()
+	assert.False(t, ok)
+
+	f, err := os.Open(file)
+	assert.NoError(t, err)
+	defer f.Close()
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record Record
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	assert.Len(t, records, 1)
+	assert.Equal(t, http.StatusBadRequest, records[0].Status)
+	assert.Equal(t, map[string]interface{}{"quote": Redacted}, records[0].RequestBody)
+	assert.Equal(t, map[string]interface{}{"echo": map[string]interface{}{"quote": Redacted}}, records[0].ResponseBody)
+
+	// sessions end on their own
+	_, err = recorder.Start(10*time.Millisecond, "", "", "admin")
+	assert.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	_, ok = recorder.Session()
+	assert.False(t, ok)
+}
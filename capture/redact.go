@@ -0,0 +1,138 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package capture
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces the redacted values
+const Redacted = "[REDACTED]"
+
+// DefaultProfile is the redaction profile every capture applies: the headers, query parameters and JSON fields,
+// at any depth, holding credentials, raw quotes, released keys or platform identifiers
+var DefaultProfile = []string{
+	"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-SQVS-Request-Signature",
+	"quote", "token", "access_token", "refresh_token", "sessionToken", "password", "secret", "privateKey",
+	"wrappedKey", "ppid", "PckPpid", "PlatformInstanceID",
+}
+
+// Redactor masks the values of the names of its profile, matched case insensitively, in the headers, queries
+// and bodies of captured requests and responses
+type Redactor struct {
+	names map[string]bool
+}
+
+// NewRedactor returns the redactor of the default profile extended with names
+func NewRedactor(names []string) *Redactor {
+	r := &Redactor{names: map[string]bool{}}
+	for _, name := range append(DefaultProfile[:len(DefaultProfile):len(DefaultProfile)], names...) {
+		if name = strings.TrimSpace(name); name != "" {
+			r.names[strings.ToLower(name)] = true
+		}
+	}
+	return r
+}
+
+func (r *Redactor) redacts(name string) bool {
+	return r.names[strings.ToLower(name)]
+}
+
+// Headers returns the headers with the values of the profile redacted
+func (r *Redactor) Headers(h http.Header) map[string]string {
+	headers := make(map[string]string, len(h))
+	for name, values := range h {
+		if r.redacts(name) {
+			headers[name] = Redacted
+			continue
+		}
+		headers[name] = strings.Join(values, ", ")
+	}
+	return headers
+}
+
+// Query returns the raw query with the parameters of the profile redacted
+func (r *Redactor) Query(rawQuery string) string {
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return Redacted
+	}
+	for name := range values {
+		if r.redacts(name) {
+			values[name] = []string{Redacted}
+		}
+	}
+	return values.Encode()
+}
+
+// Body returns the body of the content type with the fields of the profile redacted. JSON bodies are redacted
+// field by field, text is kept and any other body, raw quotes and multipart forms included, is replaced by its
+// size. Truncated JSON cannot be parsed and is replaced by its size as well.
+func (r *Redactor) Body(contentType string, body []byte, truncated bool) interface{} {
+	if len(body) == 0 {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.HasSuffix(mediaType, "ndjson"):
+		var lines []interface{}
+		for _, line := range bytes.Split(bytes.TrimSpace(body), []byte("\n")) {
+			var value interface{}
+			if json.Unmarshal(line, &value) != nil {
+				lines = append(lines, fmt.Sprintf("[REDACTED %d bytes]", len(line)))
+				continue
+			}
+			lines = append(lines, r.value(value))
+		}
+		return lines
+	case strings.HasSuffix(mediaType, "json"):
+		var value interface{}
+		if !truncated && json.Unmarshal(body, &value) == nil {
+			return r.value(value)
+		}
+	case strings.HasPrefix(mediaType, "text/") && !truncated:
+		return string(body)
+	}
+	return fmt.Sprintf("[REDACTED %d bytes of %s]", len(body), mediaType)
+}
+
+// value redacts the fields of the profile of a decoded JSON value. Strings holding base64 encoded JSON objects,
+// as the quote data of signed responses, are decoded and redacted as well.
+func (r *Redactor) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for name, field := range v {
+			if r.redacts(name) {
+				v[name] = Redacted
+				continue
+			}
+			v[name] = r.value(field)
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = r.value(v[i])
+		}
+		return v
+	case string:
+		decoded, err := base64.StdEncoding.DecodeString(v)
+		if err != nil || len(decoded) == 0 || decoded[0] != '{' {
+			return v
+		}
+		var object map[string]interface{}
+		if json.Unmarshal(decoded, &object) != nil {
+			return v
+		}
+		return r.value(object)
+	}
+	return v
+}
@@ -0,0 +1,205 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package claims
+
+import (
+	"bytes"
+	"encoding/hex"
+	commLog "intel/isecl/lib/common/v4/log"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+var log = commLog.GetDefaultLogger()
+
+// Policy holds the custom claims embedded in the signed verification results, so that the systems
+// consuming the results get claims they can authorize on directly
+type Policy struct {
+	Claims  []Claim        `yaml:"claims"`
+	Results []ResultPolicy `yaml:"results,omitempty"`
+}
+
+// ResultPolicy scopes and times the signed results issued for a relying party, such as a key broker, a
+// secret store or an orchestrator. Results carry its Issuer and Audience as iss and aud, and expire TTL after
+// they are issued. A request names the policy its result is issued under, otherwise the first one matching
+// the enclave by MrEnclave and MrSigner is used.
+type ResultPolicy struct {
+	Name      string        `yaml:"name"`
+	Issuer    string        `yaml:"issuer,omitempty"`
+	Audience  []string      `yaml:"audience,omitempty"`
+	TTL       time.Duration `yaml:"ttl,omitempty"`
+	MrEnclave string        `yaml:"mrEnclave,omitempty"`
+	MrSigner  string        `yaml:"mrSigner,omitempty"`
+}
+
+// ErrUnknownResultPolicy is returned for requests naming a result policy that does not exist
+var ErrUnknownResultPolicy = errors.New("unknown result policy")
+
+// Claim is a custom claim of the signed results. Value is a text/template evaluated against the Result of
+// the verification, a value without actions is a static claim. MrEnclave and MrSigner restrict the claim to
+// the enclaves they match. When several claims share a name the first one matching the enclave is used,
+// which maps enclaves to values with a default listed last.
+type Claim struct {
+	Name      string `yaml:"name"`
+	Value     string `yaml:"value"`
+	MrEnclave string `yaml:"mrEnclave,omitempty"`
+	MrSigner  string `yaml:"mrSigner,omitempty"`
+
+	value *template.Template
+}
+
+// Result is the outcome of a verification the claim templates are evaluated against
+type Result struct {
+	EnclaveMeasurement  string
+	EnclaveIssuer       string
+	EnclaveIssuerProdID string
+	IsvSvn              string
+	TcbLevel            string
+	EnclaveDebugMode    bool
+	ReportData          string
+}
+
+// Load reads the claims policy file. A missing file is an empty policy.
+func Load(file string) (*Policy, error) {
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return &Policy{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "claims/claims:Load() Error reading custom claims file")
+	}
+
+	var p Policy
+	err = yaml.UnmarshalStrict(data, &p)
+	if err != nil {
+		return nil, errors.Wrap(err, "claims/claims:Load() Error decoding custom claims file")
+	}
+	for i := range p.Claims {
+		err = p.Claims[i].compile()
+		if err != nil {
+			return nil, err
+		}
+	}
+	names := map[string]bool{}
+	for _, result := range p.Results {
+		err = result.validate()
+		if err != nil {
+			return nil, err
+		}
+		if names[result.Name] {
+			return nil, errors.Errorf("claims/claims:Load() Duplicate result policy %s", result.Name)
+		}
+		names[result.Name] = true
+	}
+	log.Infof("claims/claims:Load() Loaded %d custom claims and %d result policies from %s", len(p.Claims),
+		len(p.Results), file)
+	return &p, nil
+}
+
+func (c *Claim) compile() error {
+	if strings.TrimSpace(c.Name) == "" {
+		return errors.New("claims/claims:compile() Custom claim without a name")
+	}
+	if !validMeasurement(c.MrEnclave) || !validMeasurement(c.MrSigner) {
+		return errors.Errorf("claims/claims:compile() Invalid enclave measurement of custom claim %s", c.Name)
+	}
+
+	var err error
+	c.value, err = template.New(c.Name).Option("missingkey=error").Parse(c.Value)
+	return errors.Wrapf(err, "claims/claims:compile() Invalid value of custom claim %s", c.Name)
+}
+
+// validMeasurement tells whether the measurement is empty or a hex encoded SHA-256 digest
+func validMeasurement(measurement string) bool {
+	if measurement == "" {
+		return true
+	}
+	value, err := hex.DecodeString(measurement)
+	return err == nil && len(value) == 32
+}
+
+func matchesEnclave(mrEnclave, mrSigner string, r Result) bool {
+	return (mrEnclave == "" || strings.EqualFold(mrEnclave, r.EnclaveMeasurement)) &&
+		(mrSigner == "" || strings.EqualFold(mrSigner, r.EnclaveIssuer))
+}
+
+func (c *Claim) matches(r Result) bool {
+	return matchesEnclave(c.MrEnclave, c.MrSigner, r)
+}
+
+func (rp *ResultPolicy) validate() error {
+	if strings.TrimSpace(rp.Name) == "" {
+		return errors.New("claims/claims:validate() Result policy without a name")
+	}
+	if !validMeasurement(rp.MrEnclave) || !validMeasurement(rp.MrSigner) {
+		return errors.Errorf("claims/claims:validate() Invalid enclave measurement of result policy %s", rp.Name)
+	}
+	if rp.TTL < 0 {
+		return errors.Errorf("claims/claims:validate() Negative ttl of result policy %s", rp.Name)
+	}
+	return nil
+}
+
+// ResultPolicy returns the result policy named name, ErrUnknownResultPolicy when there is none or when it
+// does not apply to the enclave. Without a name it returns the first result policy applying to the enclave,
+// nil when none does.
+func (p *Policy) ResultPolicy(name string, r Result) (*ResultPolicy, error) {
+	if p == nil {
+		if name != "" {
+			return nil, ErrUnknownResultPolicy
+		}
+		return nil, nil
+	}
+	for i := range p.Results {
+		rp := &p.Results[i]
+		if name != "" && rp.Name != name {
+			continue
+		}
+		if matchesEnclave(rp.MrEnclave, rp.MrSigner, r) {
+			return rp, nil
+		}
+		if name != "" {
+			return nil, errors.Wrapf(ErrUnknownResultPolicy, "result policy %s does not apply to the enclave", name)
+		}
+	}
+	if name != "" {
+		return nil, ErrUnknownResultPolicy
+	}
+	return nil, nil
+}
+
+// Evaluate returns the custom claims of the result, nil when no claim applies to the enclave
+func (p *Policy) Evaluate(r Result) (map[string]string, error) {
+	if p == nil {
+		return nil, nil
+	}
+	var claims map[string]string
+	for i := range p.Claims {
+		c := &p.Claims[i]
+		if _, ok := claims[c.Name]; ok || !c.matches(r) {
+			continue
+		}
+		if c.value == nil {
+			return nil, errors.Errorf("claims/claims:Evaluate() Custom claim %s was not loaded", c.Name)
+		}
+
+		var value bytes.Buffer
+		err := c.value.Execute(&value, r)
+		if err != nil {
+			return nil, errors.Wrapf(err, "claims/claims:Evaluate() Error evaluating custom claim %s", c.Name)
+		}
+		if claims == nil {
+			claims = map[string]string{}
+		}
+		claims[c.Name] = value.String()
+	}
+	return claims, nil
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package claims
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	paymentsEnclave = "ad46749ed41ebaa2327252041ee746d3791a9f2431830fee0883f7993caf316a"
+	otherEnclave    = "83d719e77deaca1470f6baf62a4d774303c899db69020f9c70ee1dfc08c7ce9e"
+)
+
+func loadPolicy(t *testing.T, policy string) (*Policy, error) {
+	dir, err := ioutil.TempDir("", "claims")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "custom-claims.yml")
+	assert.NoError(t, ioutil.WriteFile(file, []byte(policy), 0600))
+	return Load(file)
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	p, err := loadPolicy(t, strings.Join([]string{
+		"claims:",
+		"- name: deployment_env",
+		"  value: production",
+		"- name: service_name",
+		"  value: payments",
+		"  mrEnclave: " + strings.ToUpper(paymentsEnclave),
+		"- name: service_name",
+		"  value: unknown",
+		"- name: relying_party_id",
+		"  value: 'rp-{{.EnclaveIssuerProdID}}-{{.TcbLevel}}'",
+	}, "\n"))
+	assert.NoError(t, err)
+
+	values, err := p.Evaluate(Result{EnclaveMeasurement: paymentsEnclave, EnclaveIssuerProdID: "01",
+		TcbLevel: "UpToDate"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"deployment_env":   "production",
+		"service_name":     "payments",
+		"relying_party_id": "rp-01-UpToDate",
+	}, values)
+
+	values, err = p.Evaluate(Result{EnclaveMeasurement: otherEnclave})
+	assert.NoError(t, err)
+	assert.Equal(t, "unknown", values["service_name"])
+}
+
+func TestLoadPolicy(t *testing.T) {
+	p, err := Load(filepath.Join(os.TempDir(), "missing-custom-claims.yml"))
+	assert.NoError(t, err)
+	values, err := p.Evaluate(Result{})
+	assert.NoError(t, err)
+	assert.Nil(t, values)
+
+	_, err = loadPolicy(t, "claims:\n- name: service_name\n  value: payments\n  mrEnclave: abcd\n")
+	assert.Error(t, err)
+	_, err = loadPolicy(t, "claims:\n- name: service_name\n  value: '{{.Unknown'\n")
+	assert.Error(t, err)
+	_, err = loadPolicy(t, "claims:\n- value: payments\n")
+	assert.Error(t, err)
+}
+
+func TestPolicyResultPolicy(t *testing.T) {
+	p, err := loadPolicy(t, strings.Join([]string{
+		"results:",
+		"- name: key-broker",
+		"  issuer: https://sqvs.example.com",
+		"  audience: [kbs]",
+		"  ttl: 5m",
+		"  mrEnclave: " + paymentsEnclave,
+		"- name: orchestrator",
+		"  audience: [orchestrator, scheduler]",
+		"  ttl: 24h",
+	}, "\n"))
+	assert.NoError(t, err)
+
+	rp, err := p.ResultPolicy("", Result{EnclaveMeasurement: paymentsEnclave})
+	assert.NoError(t, err)
+	assert.Equal(t, "key-broker", rp.Name)
+	assert.Equal(t, 5*time.Minute, rp.TTL)
+	assert.Equal(t, []string{"kbs"}, rp.Audience)
+
+	rp, err = p.ResultPolicy("", Result{EnclaveMeasurement: otherEnclave})
+	assert.NoError(t, err)
+	assert.Equal(t, "orchestrator", rp.Name)
+
+	rp, err = p.ResultPolicy("orchestrator", Result{EnclaveMeasurement: paymentsEnclave})
+	assert.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, rp.TTL)
+
+	_, err = p.ResultPolicy("key-broker", Result{EnclaveMeasurement: otherEnclave})
+	assert.Equal(t, ErrUnknownResultPolicy, errors.Cause(err))
+	_, err = p.ResultPolicy("secret-store", Result{EnclaveMeasurement: paymentsEnclave})
+	assert.Equal(t, ErrUnknownResultPolicy, err)
+
+	_, err = loadPolicy(t, "results:\n- name: a\n- name: a\n")
+	assert.Error(t, err)
+	_, err = loadPolicy(t, "results:\n- name: a\n  ttl: -1h\n")
+	assert.Error(t, err)
+}
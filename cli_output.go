@@ -0,0 +1,135 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"intel/isecl/sqvs/v4/resource"
+	"intel/isecl/sqvs/v4/version"
+	"io/ioutil"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// ServiceStatus is the machine readable output of the status command
+type ServiceStatus struct {
+	Service     string `json:"service"`
+	ActiveState string `json:"activeState"`
+	SubState    string `json:"subState"`
+	MainPID     int    `json:"mainPid"`
+	ActiveSince string `json:"activeSince,omitempty"`
+}
+
+// parseOutputFlag removes the global --output flag from the command line arguments and returns the
+// requested output format along with the remaining arguments
+func parseOutputFlag(args []string) (string, []string, error) {
+	format := outputText
+	remaining := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case strings.HasPrefix(arg, "--output="):
+			format = strings.TrimPrefix(arg, "--output=")
+		case arg == "--output" || arg == "-o":
+			if i+1 >= len(args) {
+				return "", nil, errors.New("--output requires a value")
+			}
+			i++
+			format = args[i]
+		default:
+			remaining = append(remaining, arg)
+			continue
+		}
+		if format != outputText && format != outputJSON {
+			return "", nil, errors.Errorf("unsupported output format %s, must be text or json", format)
+		}
+	}
+	return format, remaining, nil
+}
+
+func (a *App) printJSON(v interface{}) error {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "app:printJSON() Error marshalling command output")
+	}
+	fmt.Fprintln(a.consoleWriter(), string(out))
+	return nil
+}
+
+func (a *App) printVersion() error {
+	provenance := resource.BuildProvenance(a.configuration())
+	if a.outputFormat == outputJSON {
+		return a.printJSON(provenance)
+	}
+	verStr := version.GetVersion()
+	if len(provenance.Features) > 0 {
+		verStr = verStr + fmt.Sprintf("Features: %s\n", strings.Join(provenance.Features, ", "))
+	}
+	fmt.Fprintln(a.consoleWriter(), verStr)
+	return nil
+}
+
+func (a *App) jsonStatus() error {
+	systemctl, err := exec.LookPath("systemctl")
+	if err != nil {
+		return errors.Wrap(err, "app:jsonStatus() Could not locate systemctl to check status of application service")
+	}
+	out, err := exec.Command(systemctl, "show", "sqvs", "--property=ActiveState,SubState,MainPID,ActiveEnterTimestamp").Output()
+	if err != nil {
+		return errors.Wrap(err, "app:jsonStatus() Could not get status of application service")
+	}
+
+	status := ServiceStatus{Service: "sqvs"}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "ActiveState":
+			status.ActiveState = kv[1]
+		case "SubState":
+			status.SubState = kv[1]
+		case "MainPID":
+			status.MainPID, _ = strconv.Atoi(kv[1])
+		case "ActiveEnterTimestamp":
+			status.ActiveSince = kv[1]
+		}
+	}
+	return a.printJSON(status)
+}
+
+// tlsCertSha384 prints the SHA-384 digest of the TLS certificate, used by clients to pin the SQVS certificate
+func (a *App) tlsCertSha384() error {
+	certPem, err := ioutil.ReadFile(a.configuration().TLSCertFile)
+	if err != nil {
+		return errors.Wrap(err, "app:tlsCertSha384() Error reading TLS certificate")
+	}
+	block, _ := pem.Decode(certPem)
+	if block == nil {
+		return errors.New("app:tlsCertSha384() Failed to decode TLS certificate")
+	}
+	digest := sha512.Sum384(block.Bytes)
+	if a.outputFormat == outputJSON {
+		return a.printJSON(map[string]string{"tlsCertSha384": hex.EncodeToString(digest[:])})
+	}
+	fmt.Fprintln(a.consoleWriter(), hex.EncodeToString(digest[:]))
+	return nil
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	cliCommands = []string{"bench", "bootstrap", "completion", "config", "conformance", "crl", "enclaves", "help", "history", "install", "maintenance", "migrate", "run", "setup", "start", "status", "stop", "tlscertsha384",
+		"uninstall", "upgrade", "version"}
+	cliSetupTasks = []string{"all", "create_signing_key_pair", "download_ca_cert", "download_cert",
+		"update_service_config"}
+)
+
+const bashCompletion = `# bash completion for sqvs
+_sqvs() {
+    local cur prev
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+    case "${prev}" in
+        setup)
+            COMPREPLY=($(compgen -W "%[2]s" -- "${cur}"))
+            return ;;
+        completion)
+            COMPREPLY=($(compgen -W "bash zsh" -- "${cur}"))
+            return ;;
+        config)
+            COMPREPLY=($(compgen -W "show" -- "${cur}"))
+            return ;;
+        conformance)
+            COMPREPLY=($(compgen -W "run" -- "${cur}"))
+            return ;;
+        crl)
+            COMPREPLY=($(compgen -W "import" -- "${cur}"))
+            return ;;
+        enclaves)
+            COMPREPLY=($(compgen -W "import list" -- "${cur}"))
+            return ;;
+        history)
+            COMPREPLY=($(compgen -W "prune" -- "${cur}"))
+            return ;;
+        maintenance)
+            COMPREPLY=($(compgen -W "on off status" -- "${cur}"))
+            return ;;
+        migrate)
+            COMPREPLY=($(compgen -W "up down status" -- "${cur}"))
+            return ;;
+        --output|-o)
+            COMPREPLY=($(compgen -W "text json" -- "${cur}"))
+            return ;;
+    esac
+    if [[ ${COMP_CWORD} -eq 1 ]]; then
+        COMPREPLY=($(compgen -W "%[1]s" -- "${cur}"))
+    else
+        COMPREPLY=($(compgen -W "--output=text --output=json --force --file= --purge --before= --message= --effective --issuer-chain= --quotes= --concurrency= --duration= --url= --to= --corpus= --report= --listen= --description=" -- "${cur}"))
+    fi
+}
+complete -F _sqvs sqvs
+`
+
+const zshCompletion = `#compdef sqvs
+# zsh completion for sqvs
+_sqvs() {
+    local -a commands tasks
+    commands=(%[1]s)
+    tasks=(%[2]s)
+    case $CURRENT in
+        2)
+            _describe 'command' commands ;;
+        *)
+            case ${words[2]} in
+                setup) _describe 'task' tasks ;;
+                completion) _values 'shell' bash zsh ;;
+                config) _values 'subcommand' show ;;
+                conformance) _values 'subcommand' run ;;
+                crl) _values 'subcommand' import ;;
+                enclaves) _values 'subcommand' import list ;;
+                history) _values 'subcommand' prune ;;
+                maintenance) _values 'subcommand' on off status ;;
+                migrate) _values 'subcommand' up down status ;;
+                *) _values 'flag' --output=text --output=json --force --file= --purge --before= --message= --effective --issuer-chain= --quotes= --concurrency= --duration= --url= --to= --corpus= --report= --listen= --description= ;;
+            esac ;;
+    esac
+}
+compdef _sqvs sqvs
+`
+
+// printCompletion writes the shell completion script for the requested shell
+func (a *App) printCompletion(shell string) error {
+	var script string
+	switch shell {
+	case "bash":
+		script = fmt.Sprintf(bashCompletion, strings.Join(cliCommands, " "), strings.Join(cliSetupTasks, " "))
+	case "zsh":
+		script = fmt.Sprintf(zshCompletion, strings.Join(cliCommands, " "), strings.Join(cliSetupTasks, " "))
+	default:
+		return errors.Errorf("app:printCompletion() Unsupported shell %s, must be bash or zsh", shell)
+	}
+	fmt.Fprint(a.consoleWriter(), script)
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	commLog "intel/isecl/lib/common/v4/log"
 	"intel/isecl/lib/common/v4/setup"
 	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/netfamily"
 	"net/url"
 	"os"
 	"path"
@@ -24,31 +25,435 @@ var log = commLog.GetDefaultLogger()
 
 // Configuration is the global configuration struct that is marshalled/unmarshalled to a persisted yaml file
 type Configuration struct {
-	configFile       string
-	Port             int
+	configFile string
+	Port       int
+	// ListenAddress is the IPv4 or IPv6 address, optionally bracketed, or host name the service listens on,
+	// all the addresses of the address family when not set
+	ListenAddress string
+	// AddressFamily selects the addresses the service listens on and connects to its dependencies over, ipv4 or
+	// ipv6 to force one, prefer-ipv4 or prefer-ipv6 to try one first. Both are used when not set.
+	AddressFamily    string
 	CmsTLSCertDigest string
+	// ReusePort listens with SO_REUSEPORT, so the service binary of an upgrade can be started on the same port
+	// before the previous one is stopped
+	ReusePort bool
 
 	LogMaxLength    int
 	LogEnableStdout bool
 	LogLevel        logrus.Level
-	IncludeToken    bool
-	CMSBaseURL      string
-	AuthServiceURL  string
-	SCSBaseURL      string
-	Subject         struct {
+
+	// LogFormat is the format, text, json, cef or leef, of the records written to the console and the service
+	// log, SecurityLogFormat the format of those written to the security log
+	LogFormat         string
+	SecurityLogFormat string
+
+	// LogFileFormat is the format of the records written to the service log when it differs from the console
+	// one, json records for log pipelines while the console stays human-readable. LogFormat when empty.
+	LogFileFormat string
+	// SecurityLogSinks are additional sinks of the security log, each receiving the events of its categories at
+	// its own level in its own format: file=<file>;events=<event>|<event>;outcome=success|failure;level=<level>;
+	// format=<format>. Relative files are in the log directory.
+	SecurityLogSinks []string
+	// LogSampleSuccesses keeps 1 in LogSampleSuccesses of the records of successful verifications and
+	// authorizations, failures are always logged. AccessLogSampleSuccesses keeps 1 in AccessLogSampleSuccesses of
+	// the access log records of the requests answered with a status below 400. All are logged when 0 or 1.
+	LogSampleSuccesses       int
+	AccessLogSampleSuccesses int
+
+	IncludeToken   bool
+	CMSBaseURL     string
+	AuthServiceURL string
+	SCSBaseURL     string
+	Subject        struct {
 		TLSCertCommonName string
 	}
 	TLSKeyFile               string
 	TLSCertFile              string
 	CertSANList              string
+	DisableSANAutoDetect     bool
 	SignQuoteResponse        bool
 	ResponseSigningKeyLength int
+	SigningKeyOverlap        time.Duration
+	ResultValidity           time.Duration
+	CustomClaimsFile         string
 	UsePSSPadding            bool
+	ResultSigningAlgorithm   string
+	ResultSigningKeys        []string
+	AllowDebugEnclaves       bool
+	PckPolicy                PckPolicyConfig
 	ReadTimeout              time.Duration
 	ReadHeaderTimeout        time.Duration
 	WriteTimeout             time.Duration
 	IdleTimeout              time.Duration
 	MaxHeaderBytes           int
+	MaxConcurrentRequests    int
+	MaxQueuedRequests        int
+	MaxQueueWait             time.Duration
+	TargetUtilization        float64
+	BatchWorkers             int
+
+	// ServiceUsername and ServicePasswordFile are the AAS service account SQVS gets the bearer tokens of its
+	// calls to SCS and CMS for. The tokens are cached and renewed TokenRenewBefore they expire.
+	ServiceUsername     string
+	ServicePasswordFile string
+	TokenRenewBefore    time.Duration
+
+	// WaitForDependencies delays startup until CMS, AAS and SCS are reachable, retrying with exponential
+	// backoff from DependencyRetryInterval up to DependencyMaxRetryInterval for at most DependencyWaitTimeout
+	WaitForDependencies        bool
+	DependencyWaitTimeout      time.Duration
+	DependencyRetryInterval    time.Duration
+	DependencyMaxRetryInterval time.Duration
+
+	EnableTcbDowngradeDetection bool
+	EnableVerificationHistory   bool
+	WebhookURL                  string
+	ResultEvents                ResultEventsConfig
+	KeyRelease                  KeyReleaseConfig
+	PayloadCapture              PayloadCaptureConfig
+
+	// EnableTestMode serves the canned verdicts of test quotes under /svs/test/v1/ in builds made with the
+	// sqvs_testmode tag, for relying-party integration tests. Production builds ignore it.
+	EnableTestMode bool
+
+	// RetainRawQuotes keeps the raw quote with each verification of the history, encrypted as RawQuotes
+	// configures, so it can be re-verified later under the current collateral
+	RetainRawQuotes bool
+	RawQuotes       RawQuoteConfig
+
+	// EnableResultRevocation keeps the revocations of signed results in the SQVS store and publishes them,
+	// signed, at /svs/v1/.well-known/revocations.json
+	EnableResultRevocation bool
+
+	// EnableCollateralHistory keeps every version of the TCB info and QE identity quotes are verified with in
+	// the SQVS store, so quotes can be re-evaluated later against the collateral current at a past time
+	EnableCollateralHistory bool
+
+	// TcbStatusVerdicts map TCB statuses to the verdict, allow, warn or deny, quotes of platforms with that
+	// status get, "Status=verdict" entries. Statuses not mapped are allowed.
+	TcbStatusVerdicts []string
+
+	// FipsMode restricts TLS, bearer token and signature algorithms to FIPS approved ones. The service must be
+	// built with a FIPS validated crypto module running in FIPS mode, otherwise it refuses to start.
+	FipsMode bool
+
+	// TLSPolicy sets the thresholds the TLS certificate and the trusted CA certificates are checked against at
+	// startup and restricts the cipher suites of the connections to the dependencies
+	TLSPolicy TLSPolicyConfig
+
+	// RequirePlatformEnrollment rejects the quotes of platforms whose FMSPC and PCE ID have not been enrolled
+	RequirePlatformEnrollment bool
+	// RequireRegisteredEnclaves rejects the quotes of enclaves whose MRENCLAVE and MRSIGNER have not been
+	// registered, and of registered enclaves not matching their ISVPRODID, ISVSVN and attributes
+	RequireRegisteredEnclaves bool
+
+	// RequireSignedRequests rejects the quote appraisal requests not signed by a relying party registered in
+	// the relying party key directory. Signed requests are verified whether or not signatures are required.
+	RequireSignedRequests bool
+	// RequestSignatureMaxAge is how far the iat of a request signature may be from the current time
+	RequestSignatureMaxAge time.Duration
+	// RequireSignedPolicies rejects the custom claims policy unless it is signed by a policy author registered
+	// in the policy author key directory. Signed policies are verified whether or not signatures are required.
+	RequireSignedPolicies bool
+
+	CorsAllowedOrigins []string
+	CorsAllowedMethods []string
+	CorsAllowedHeaders []string
+
+	IPAllowList []string
+	IPDenyList  []string
+
+	// TrustedProxies are the CIDR blocks or addresses of the load balancers in front of SQVS. The client address
+	// of requests they forward is taken from X-Forwarded-For, which is ignored from any other peer.
+	TrustedProxies []string
+	// ProxyProtocol requires the connections of the trusted proxies, of every peer when there are none, to start
+	// with a PROXY protocol v2 header carrying the client address
+	ProxyProtocol bool
+
+	// EgressAllowList restricts the outbound connections of SQVS to SCS, PCS, CMS, AAS, webhooks and any other
+	// destination to the host names, "*." domains, addresses and CIDR blocks it lists, when not empty
+	EgressAllowList []string
+	// DNS controls how the host names of the dependencies are resolved
+	DNS DNSConfig
+
+	// TLSSecondaryCertFile is a second TLS certificate chain whose key, TLSSecondaryKeyID in the key store, is of
+	// the other algorithm, RSA or ECDSA, than the TLS key. Clients are served the ECDSA chain when they support
+	// it and the RSA chain otherwise.
+	TLSSecondaryCertFile string
+	TLSSecondaryKeyID    string
+
+	// CollateralFetchTimeout bounds the retrieval of the TCB info and QE identity of a quote, CRLFetchTimeout
+	// that of its PCK CRLs, all attempts included. VerificationComputeTimeout bounds the time a quote
+	// verification spends outside of these fetches.
+	CollateralFetchTimeout     time.Duration
+	CRLFetchTimeout            time.Duration
+	VerificationComputeTimeout time.Duration
+
+	KeyStore    KeyStoreConfig
+	Outbound    OutboundConfig
+	TrustedTime TrustedTimeConfig
+	// ClockSkewToleranceSeconds is how far certificate validity periods and collateral issue and next update
+	// dates may be missed by the trusted time and still pass
+	ClockSkewToleranceSeconds int
+	// SGXRootKeyPins are the hex encoded SHA-256 digests of the public keys the Intel SGX root CA may have.
+	// When set, the trusted root CA the PCK, TCB info and QE identity chains are verified against must match one.
+	SGXRootKeyPins []string
+	// CrlURLOverrides replace the CRL distribution point of a PCK CA, processor=<url> or platform=<url>, for
+	// mirrored or offline environments. CRLs imported with the crl import command take precedence until expired.
+	CrlURLOverrides []string
+	// PckChainCacheTTL is how long a verified PCK certificate chain is reused for the quotes of the same
+	// platform without fetching its CRLs again, 0 disables the cache. Chains are verified again earlier once
+	// a newer CRL of their CA is seen or their CRLs are due for update.
+	PckChainCacheTTL time.Duration
+	// CollateralCacheTTL is how long the collateral served to relying parties verifying quotes locally is
+	// cached for, never past its next update.
+	CollateralCacheTTL time.Duration
+	// SessionTTL is how long a verification session is open for, its quote must be submitted before then
+	SessionTTL time.Duration
+	// PlatformIdentifiers is the redaction profile of the PPID and platform instance ID of the PCK certificates
+	// in the results of the clients that do not ask for them with the PlatformIdentityReader role. Events and
+	// logs carry the PPID pseudonymized unless the profile is full.
+	PlatformIdentifiers string
+	// Features turn the capabilities rolled out incrementally on or off by feature flag name, such as
+	// {batch: false}, flags left unset keep their default
+	Features map[string]bool
+
+	Database  DatabaseConfig
+	Retention RetentionConfig
+	Quota     QuotaConfig
+	Anomaly   AnomalyConfig
+	Expiry    ExpiryConfig
+
+	CollateralCheck   CollateralCheckConfig
+	CollateralFailure CollateralFailureConfig
+
+	VerifierEvidence VerifierEvidenceConfig
+}
+
+// PckPolicyConfig constrains the SGX extensions of the PCK certificates of the quotes. Quotes from platforms
+// whose FMSPC or SGX type is missing from a non empty allow-list are rejected.
+type PckPolicyConfig struct {
+	AllowedFmspcs   []string
+	AllowedSgxTypes []string
+}
+
+// ResultEventsConfig publishes the result of every quote verification to a message queue, a Kafka topic or a
+// NATS JetStream subject. Addresses are the Kafka brokers or the NATS server URLs, TLS connections are verified
+// against the trusted CAs when TLS is set. Results are encoded as json or cbor and sent in batches of up to
+// BatchSize every FlushInterval, at least once: batches are resent until the broker acknowledges them. Up to
+// QueueSize results are buffered while the broker is unreachable.
+type ResultEventsConfig struct {
+	Broker        string
+	Addresses     []string
+	Topic         string
+	TLS           bool
+	Encoding      string
+	BatchSize     int
+	FlushInterval time.Duration
+	QueueSize     int
+}
+
+// PayloadCaptureConfig enables the debug captures of request and response payloads holders of the
+// PayloadCapture role start through /svs/v1/admin/payload-capture, to reproduce the failures of a client.
+// Captures last at most MaxDuration and are appended to File, bodies truncated at MaxBodySize bytes.
+// RedactFields are the headers, query parameters and JSON fields redacted in addition to the tokens, request
+// signatures, raw quotes and released keys always redacted.
+type PayloadCaptureConfig struct {
+	Enabled      bool
+	File         string
+	MaxDuration  time.Duration
+	MaxBodySize  int
+	RedactFields []string
+}
+
+// TLSPolicyConfig checks the key size, signature algorithm and validity window of the TLS certificate chain of
+// SQVS and of the trusted CA certificates at startup. Mode enforce refuses to start when a certificate falls
+// short, warn, the default, logs it and off skips the checks. RSA and ECDSA keys must have at least
+// MinRSAKeySize and MinECDSAKeySize bits and certificates stay valid for at least MinValidity.
+// DisableLegacyCiphers restricts the TLS 1.2 connections to the dependencies to ECDHE AEAD cipher suites, with
+// no fallback to CBC or static RSA key exchange ones.
+type TLSPolicyConfig struct {
+	Mode                 string
+	MinRSAKeySize        int
+	MinECDSAKeySize      int
+	MinValidity          time.Duration
+	DisableLegacyCiphers bool
+}
+
+// KeyReleaseConfig releases keys to the enclaves whose quote is verified, for verify-then-release flows. Broker
+// is the key broker plugin, webhook posting the verified enclave identity to the key broker URL, a KBS or a KMIP
+// gateway, and waiting up to Timeout for the released key.
+type KeyReleaseConfig struct {
+	Broker  string
+	URL     string
+	Timeout time.Duration
+}
+
+// VerifierEvidenceConfig enables the evidence of the SGX enclave SQVS runs in. QuoteSource is helper, to run
+// the QuoteHelper program, or gramine, to use the attestation pseudo file system of Gramine. Evidence without
+// a client nonce is regenerated every RefreshInterval.
+type VerifierEvidenceConfig struct {
+	QuoteSource     string
+	QuoteHelper     string
+	RefreshInterval time.Duration
+}
+
+// DatabaseConfig selects the storage driver of the verification history and platform TCB statuses, memory,
+// sqlite for single node installs or postgres. File is the snapshot file of the memory driver or the database
+// file of the sqlite driver. The schema of the SQL databases is created when they are empty and otherwise
+// migrated with the migrate command.
+type DatabaseConfig struct {
+	Driver       string
+	File         string
+	Host         string
+	Port         int
+	Name         string
+	Username     string
+	PasswordFile string
+	SSLMode      string
+	SSLCert      string
+}
+
+// RetentionConfig limits the verification history kept by SQVS. Records older than RetentionDays or beyond
+// the newest MaxRecords are pruned every PruneInterval, after being exported to ExportDir or ExportS3URL when set.
+// ExportS3CredentialsFile holds the access key ID and the secret access key on separate lines.
+type RetentionConfig struct {
+	RetentionDays           int
+	MaxRecords              int
+	PruneInterval           time.Duration
+	ExportDir               string
+	ExportS3URL             string
+	ExportS3Region          string
+	ExportS3CredentialsFile string
+}
+
+// QuotaConfig counts the verification requests of every tenant per route, day and month in the SQVS store.
+// Tenants are identified by the TenantClaim of their token. Limits are "[route:]daily=N" or
+// "[route:]monthly=N" entries, a limit without route applying to all the requests of a tenant. Requests
+// beyond a limit are logged with the warn Enforcement and rejected with 429 with the reject Enforcement.
+type QuotaConfig struct {
+	Enabled     bool
+	TenantClaim string
+	Enforcement string
+	Limits      []string
+}
+
+// AnomalyConfig flags anomalous quote patterns within Window: FailedVerifications failed verifications from one
+// caller, DebugQuotes quotes of DEBUG enclaves or DistinctMeasurements distinct MRENCLAVEs verified by one tenant.
+// A zero threshold disables its heuristic. Anomalies are logged as security events and posted to WebhookURL, or
+// to the alerts webhook when not set.
+type AnomalyConfig struct {
+	Enabled              bool
+	Window               time.Duration
+	FailedVerifications  int
+	DebugQuotes          int
+	DistinctMeasurements int
+	WebhookURL           string
+}
+
+// ExpiryConfig warns, in the log, the sqvs_expiry_seconds metric and the alerts webhook, when the TLS and signing
+// certificates, the SGX root CA, the pinned TCB info or the imported PCK CRLs get within one of AlertThresholds
+// of their expiry, and when they expire. They are checked every CheckInterval, a zero interval disables the checks.
+type ExpiryConfig struct {
+	AlertThresholds []time.Duration
+	CheckInterval   time.Duration
+}
+
+// CollateralCheckConfig cross-checks the TCB info and QE identity fetched from SCS against a secondary source,
+// Intel PCS at https://api.trustedservices.intel.com/sgx/certification/v3, before they are used, to detect a
+// compromised or stale caching service. The SHA-384 digests of both must match: in enforce mode a mismatch or
+// an unreachable source fails the verification, in warn mode it is logged. The collateral of the secondary
+// source is cached for CacheTTL. Collateral is not checked when URL is empty.
+type CollateralCheckConfig struct {
+	URL      string
+	Mode     string
+	CacheTTL time.Duration
+}
+
+// Collateral failure modes, how verifications proceed when fresh collateral cannot be fetched
+const (
+	CollateralFailClosed = "fail-closed"
+	CollateralUseCached  = "use-cached"
+	CollateralFailOpen   = "fail-open"
+)
+
+// CollateralFailureConfig chooses how quotes are verified when fresh TCB info or QE identity cannot be fetched
+// from SCS. In fail-closed mode the verifications fail. In use-cached mode they use the last collateral SQVS
+// verified, up to GracePeriod past its next update. In fail-open mode they use it however old it is. Results
+// verified against cached collateral report the mode and a warning.
+type CollateralFailureConfig struct {
+	Mode        string
+	GracePeriod time.Duration
+}
+
+// Redaction profiles of the platform identifiers. Redacted results omit them, pseudonymized results carry the
+// hex encoded SHA-256 digests of the identifiers, which correlate the quotes of a platform without revealing
+// it, and full results carry them as they are.
+const (
+	PlatformIdentifiersRedacted      = "redacted"
+	PlatformIdentifiersPseudonymized = "pseudonymized"
+	PlatformIdentifiersFull          = "full"
+)
+
+// DNSConfig pins host names of dependencies to addresses and selects how the others are resolved.
+// HostOverrides are host=address entries, a host listed several times resolving to all its addresses, and take
+// precedence over DNS. Resolver is system, the resolver of the operating system, or go, the pure Go resolver
+// which alone can query Servers instead of those of /etc/resolv.conf. Resolved addresses are reused for
+// CacheTTL, 0 resolving the host names again for every connection.
+type DNSConfig struct {
+	HostOverrides []string
+	Resolver      string
+	Servers       []string
+	CacheTTL      time.Duration
+}
+
+// OutboundConfig controls retries and circuit breaking of the collateral requests made to SCS.
+// RetryBudgetRatio is the number of retries allowed per request made, averaged over recent requests.
+type OutboundConfig struct {
+	MaxAttempts             int
+	InitialBackoff          time.Duration
+	MaxBackoff              time.Duration
+	AttemptTimeout          time.Duration
+	RetryBudgetRatio        float64
+	BreakerFailureThreshold int
+	BreakerOpenDuration     time.Duration
+	RetryLogInterval        time.Duration
+}
+
+// TrustedTimeConfig selects the time certificate and collateral validity is checked against, the system
+// clock or a Roughtime server whose responses are signed with RoughtimePublicKey (base64 encoded Ed25519 key).
+// The Roughtime server is queried every RoughtimeRefresh. Offset is added to the time of either source.
+type TrustedTimeConfig struct {
+	Source             string
+	Offset             time.Duration
+	RoughtimeServer    string
+	RoughtimePublicKey string
+	RoughtimeRefresh   time.Duration
+}
+
+// RawQuoteConfig restricts the retention of raw quotes to the tenants of Tenants, all tenants when it is empty,
+// and selects the key they are encrypted at rest with: the base64 encoded AES-256 key of KeyFile or the Vault
+// transit key VaultTransitKey of the VaultTransitMount mount, reached with the Vault address and token of
+// the key store configuration.
+type RawQuoteConfig struct {
+	Tenants           []string
+	KeyFile           string
+	VaultTransitKey   string
+	VaultTransitMount string
+}
+
+// KeyStoreConfig selects the backend private keys are loaded from. Key IDs are file paths for the file
+// backend, secret paths for Vault KV, key names for Vault transit and object labels for PKCS#11.
+type KeyStoreConfig struct {
+	Type             string
+	SigningKeyID     string
+	TLSKeyID         string
+	VaultAddress     string
+	VaultMount       string
+	VaultTokenFile   string
+	PKCS11Module     string
+	PKCS11TokenLabel string
+	PKCS11PinFile    string
 }
 
 var global *Configuration
@@ -156,16 +561,74 @@ func (conf *Configuration) SaveConfiguration(taskName string, c setup.Context) e
 		} else if conf.CertSANList == "" {
 			conf.CertSANList = constants.DefaultSQVSTLSSan
 		}
+		conf.CertSANList, err = netfamily.NormalizeSANList(conf.CertSANList)
+		if err != nil {
+			return errors.Wrap(err, "config/config:SaveConfiguration() Invalid SAN_LIST")
+		}
+
+		sanAutoDetect, err := c.GetenvString("SAN_AUTO_DETECT", "Add the host name and addresses to the SAN list")
+		if err == nil && strings.TrimSpace(sanAutoDetect) != "" {
+			autoDetect, err := strconv.ParseBool(sanAutoDetect)
+			if err != nil {
+				return errors.Wrap(err, "config/config:SaveConfiguration() SAN_AUTO_DETECT must be true or false")
+			}
+			conf.DisableSANAutoDetect = !autoDetect
+		}
+		// the certificate download task reads SAN_LIST too, it must see the IPv6 literals unbracketed and the
+		// detected host names and addresses
+		if _, ok := os.LookupEnv("SAN_LIST"); ok {
+			os.Setenv("SAN_LIST", conf.TLSCertSANList())
+		}
 	}
 
 	return conf.Save()
 }
 
+// TLSCertSANList returns the SAN list the TLS certificate is requested with, CertSANList along with the host
+// name and the addresses of the host unless their detection is disabled. Only CertSANList is saved, so the SANs
+// are detected again, for the current addresses, whenever the certificate is downloaded.
+func (conf *Configuration) TLSCertSANList() string {
+	if conf.DisableSANAutoDetect {
+		return conf.CertSANList
+	}
+	sans, err := netfamily.HostSANs()
+	if err != nil {
+		log.WithError(err).Warn("config/config:TLSCertSANList() The host addresses could not be detected")
+	}
+	sanList, err := netfamily.MergeSANList(conf.CertSANList, sans)
+	if err != nil {
+		log.WithError(err).Warn("config/config:TLSCertSANList() Invalid SAN list, the host SANs are not added")
+		return conf.CertSANList
+	}
+	if sanList != conf.CertSANList {
+		log.Infof("config/config:TLSCertSANList() TLS certificate SANs with the host name and addresses: %s", sanList)
+	}
+	return sanList
+}
+
+// Save writes the configuration to its file and records the settings it changes in the configuration history,
+// the initial configuration excepted
 func (conf *Configuration) Save() error {
 	if conf.configFile == "" {
 		return ErrNoConfigFile
 	}
-	file, err := os.OpenFile(conf.configFile, os.O_RDWR, 0)
+	var previous *Configuration
+	if info, err := os.Stat(conf.configFile); err == nil && info.Size() > 0 {
+		previous = Load(conf.configFile)
+	}
+	err := conf.write()
+	if err != nil || previous == nil {
+		return err
+	}
+	err = RecordChanges(HistoryFile(conf.configFile), ChangeSourceSetup, "", Diff(previous, conf))
+	if err != nil {
+		log.WithError(err).Error("config/config:Save() Error recording configuration changes")
+	}
+	return nil
+}
+
+func (conf *Configuration) write() error {
+	file, err := os.OpenFile(conf.configFile, os.O_RDWR|os.O_TRUNC, 0)
 	if err != nil {
 		// we have an error
 		if os.IsNotExist(err) {
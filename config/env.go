@@ -0,0 +1,167 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package config
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// EnvPrefix namespaces the environment variables overriding the configuration when the service runs. They are
+// distinct from the SQVS_ variables read by setup, which are saved to config.yml.
+const EnvPrefix = "SVS_"
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	levelType    = reflect.TypeOf(logrus.Level(0))
+)
+
+// EnvOverride is a configuration field along with the environment variable overriding it
+type EnvOverride struct {
+	Field    string `json:"field"`
+	Variable string `json:"variable"`
+}
+
+// EnvVariables returns the environment variable of every configuration field, SVS_ followed by the path of the
+// field in upper snake case: SVS_PORT, SVS_QUOTA_TENANT_CLAIM, SVS_SUBJECT_TLS_CERT_COMMON_NAME
+func EnvVariables() []EnvOverride {
+	var fields []EnvOverride
+	walkFields(reflect.ValueOf(&Configuration{}).Elem(), "", "", func(field, variable string, v reflect.Value) error {
+		fields = append(fields, EnvOverride{Field: field, Variable: variable})
+		return nil
+	})
+	return fields
+}
+
+// ApplyEnv overrides the configuration fields whose environment variable is set, so the environment takes
+// precedence over config.yml, itself taking precedence over the defaults. Durations are Go durations such as
+// 30s, lists are comma separated and log levels are logrus level names. It returns the fields overridden.
+func (conf *Configuration) ApplyEnv(lookup func(string) (string, bool)) ([]EnvOverride, error) {
+	var overrides []EnvOverride
+	err := walkFields(reflect.ValueOf(conf).Elem(), "", "", func(field, variable string, v reflect.Value) error {
+		value, ok := lookup(variable)
+		if !ok {
+			return nil
+		}
+		err := setField(v, strings.TrimSpace(value))
+		if err != nil {
+			return errors.Wrapf(err, "config/env:ApplyEnv() Invalid value of %s", variable)
+		}
+		overrides = append(overrides, EnvOverride{Field: field, Variable: variable})
+		return nil
+	})
+	return overrides, err
+}
+
+// walkFields calls fn with the path and environment variable of every exported leaf field of v
+func walkFields(v reflect.Value, field, variable string, fn func(field, variable string, v reflect.Value) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		name := field + sf.Name
+		env := variable + envName(sf.Name)
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct && fv.Type() != durationType {
+			err := walkFields(fv, name+".", env+"_", fn)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		err := fn(name, EnvPrefix+env, fv)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// envName converts a Go field name to upper snake case, keeping acronyms together: SCSBaseURL is SCS_BASE_URL
+func envName(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+func setField(v reflect.Value, value string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case v.Type() == levelType:
+		level, err := logrus.ParseLevel(value)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(level))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return errors.Errorf("unsupported field type %s", v.Type())
+		}
+		items := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return errors.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"SVS_PORT":                         "12001",
+		"SVS_LOG_LEVEL":                    "debug",
+		"SVS_SCS_BASE_URL":                 "https://scs.com:9000/scs/sgx/certification/v1",
+		"SVS_IP_ALLOW_LIST":                "10.0.0.0/8, 192.168.1.0/24",
+		"SVS_QUOTA_ENABLED":                "true",
+		"SVS_OUTBOUND_ATTEMPT_TIMEOUT":     "5s",
+		"SVS_SUBJECT_TLS_CERT_COMMON_NAME": "SQVS TLS Certificate",
+		"SVS_OUTBOUND_RETRY_BUDGET_RATIO":  "0.2",
+		"SVS_CLOCK_SKEW_TOLERANCE_SECONDS": "30",
+		"SQVS_PORT":                        "1",
+	}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	conf := Configuration{Port: 12000, CMSBaseURL: "https://cms.com:8445/cms/v1"}
+	overrides, err := conf.ApplyEnv(lookup)
+	assert.NoError(t, err)
+	assert.Len(t, overrides, 9)
+	assert.Equal(t, 12001, conf.Port)
+	assert.Equal(t, logrus.DebugLevel, conf.LogLevel)
+	assert.Equal(t, "https://scs.com:9000/scs/sgx/certification/v1", conf.SCSBaseURL)
+	assert.Equal(t, "https://cms.com:8445/cms/v1", conf.CMSBaseURL)
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.0/24"}, conf.IPAllowList)
+	assert.True(t, conf.Quota.Enabled)
+	assert.Equal(t, 5*time.Second, conf.Outbound.AttemptTimeout)
+	assert.Equal(t, "SQVS TLS Certificate", conf.Subject.TLSCertCommonName)
+	assert.Equal(t, 0.2, conf.Outbound.RetryBudgetRatio)
+	assert.Equal(t, 30, conf.ClockSkewToleranceSeconds)
+
+	env["SVS_PORT"] = "high"
+	_, err = conf.ApplyEnv(lookup)
+	assert.Error(t, err)
+}
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "SCS_BASE_URL", envName("SCSBaseURL"))
+	assert.Equal(t, "IP_ALLOW_LIST", envName("IPAllowList"))
+	assert.Equal(t, "TLS_CERT_COMMON_NAME", envName("TLSCertCommonName"))
+	assert.Equal(t, "PKCS11_PIN_FILE", envName("PKCS11PinFile"))
+	assert.Equal(t, "SSL_MODE", envName("SSLMode"))
+}
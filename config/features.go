@@ -0,0 +1,150 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package config
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// EnabledFeatures returns the optional features the configuration enables, reported with the build provenance
+// so fleet inventory knows how every instance is deployed
+func (conf *Configuration) EnabledFeatures() []string {
+	features := []string{}
+	for _, feature := range []struct {
+		name    string
+		enabled bool
+	}{
+		{"token-auth", conf.IncludeToken},
+		{"fips", conf.FipsMode},
+		{"signed-responses", conf.SignQuoteResponse},
+		{"signed-requests", conf.RequireSignedRequests},
+		{"signed-policies", conf.RequireSignedPolicies},
+		{"debug-enclaves", conf.AllowDebugEnclaves},
+		{"platform-enrollment", conf.RequirePlatformEnrollment},
+		{"registered-enclaves", conf.RequireRegisteredEnclaves},
+		{"tcb-downgrade-detection", conf.EnableTcbDowngradeDetection},
+		{"verification-history", conf.EnableVerificationHistory},
+		{"raw-quotes", conf.RetainRawQuotes},
+		{"result-revocation", conf.EnableResultRevocation},
+		{"collateral-history", conf.EnableCollateralHistory},
+		{"collateral-check", conf.CollateralCheck.URL != ""},
+		{"key-release", conf.KeyRelease.Broker != ""},
+		{"payload-capture", conf.PayloadCapture.Enabled},
+		{"quotas", conf.Quota.Enabled},
+		{"anomaly-detection", conf.Anomaly.Enabled},
+	} {
+		if feature.enabled {
+			features = append(features, feature.name)
+		}
+	}
+	return features
+}
+
+// Feature flags gating the capabilities that are rolled out incrementally
+const (
+	FeatureBatch                = "batch"
+	FeatureVerificationSessions = "verification-sessions"
+)
+
+// FeatureFlag is a capability operators turn on or off with the Features of the configuration, enabled by
+// Default when the configuration does not set it. Experimental capabilities are off by default and may change
+// without notice.
+type FeatureFlag struct {
+	Name         string
+	Description  string
+	Default      bool
+	Experimental bool
+}
+
+// FeatureFlags are the known feature flags
+var FeatureFlags = []FeatureFlag{
+	{Name: FeatureBatch, Description: "Batch quote verification with /sgx_qv_verify_quotes", Default: true},
+	{Name: FeatureVerificationSessions, Description: "Verification sessions opened with /v2/sessions",
+		Experimental: true},
+}
+
+// LookupFeatureFlag returns the known feature flag named name
+func LookupFeatureFlag(name string) (FeatureFlag, bool) {
+	for _, flag := range FeatureFlags {
+		if flag.Name == name {
+			return flag, true
+		}
+	}
+	return FeatureFlag{}, false
+}
+
+// FeatureEnabled reports whether the feature flag name is enabled, by the configuration or else by default.
+// Unknown flags are never enabled.
+func (conf *Configuration) FeatureEnabled(name string) bool {
+	flag, ok := LookupFeatureFlag(name)
+	if !ok {
+		return false
+	}
+	if conf != nil {
+		if enabled, set := conf.Features[name]; set {
+			return enabled
+		}
+	}
+	return flag.Default
+}
+
+// FeatureFlagStates returns whether every known feature flag is enabled, by flag name
+func (conf *Configuration) FeatureFlagStates() map[string]bool {
+	states := make(map[string]bool, len(FeatureFlags))
+	for _, flag := range FeatureFlags {
+		states[flag.Name] = conf.FeatureEnabled(flag.Name)
+	}
+	return states
+}
+
+// UnknownFeatures returns the sorted names of the feature flags set by the configuration that this version
+// does not know, ignored so that a configuration can be shared with newer versions
+func (conf *Configuration) UnknownFeatures() []string {
+	unknown := []string{}
+	for name := range conf.Features {
+		if _, ok := LookupFeatureFlag(name); !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// ParseFeatures parses a comma separated list of <flag>=<true|false> feature flag settings, rejecting the
+// flags that are not known
+func ParseFeatures(list string) (map[string]bool, error) {
+	features := map[string]bool{}
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		name := strings.TrimSpace(parts[0])
+		if _, ok := LookupFeatureFlag(name); !ok {
+			names := make([]string, 0, len(FeatureFlags))
+			for _, flag := range FeatureFlags {
+				names = append(names, flag.Name)
+			}
+			return nil, errors.Errorf("config/features:ParseFeatures() Unknown feature flag %q, must be one of %s",
+				name, strings.Join(names, ", "))
+		}
+		enabled := true
+		if len(parts) == 2 {
+			var err error
+			enabled, err = strconv.ParseBool(strings.TrimSpace(parts[1]))
+			if err != nil {
+				return nil, errors.Errorf("config/features:ParseFeatures() Invalid setting %q of feature flag %s, "+
+					"must be true or false", parts[1], name)
+			}
+		}
+		features[name] = enabled
+	}
+	return features, nil
+}
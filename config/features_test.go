@@ -0,0 +1,32 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeatureFlags(t *testing.T) {
+	features, err := ParseFeatures("batch=false, verification-sessions")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{FeatureBatch: false, FeatureVerificationSessions: true}, features)
+
+	_, err = ParseFeatures("tdx=true")
+	assert.Error(t, err)
+	_, err = ParseFeatures("batch=maybe")
+	assert.Error(t, err)
+
+	conf := &Configuration{}
+	assert.True(t, conf.FeatureEnabled(FeatureBatch))
+	assert.False(t, conf.FeatureEnabled(FeatureVerificationSessions))
+
+	conf.Features = map[string]bool{FeatureBatch: false, "tdx": true}
+	assert.False(t, conf.FeatureEnabled(FeatureBatch))
+	assert.False(t, conf.FeatureEnabled("tdx"))
+	assert.Equal(t, []string{"tdx"}, conf.UnknownFeatures())
+	assert.Equal(t, map[string]bool{FeatureBatch: false, FeatureVerificationSessions: false}, conf.FeatureFlagStates())
+}
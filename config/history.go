@@ -0,0 +1,244 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	commLog "intel/isecl/lib/common/v4/log"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/logformat"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+var slog = commLog.GetSecurityLogger()
+
+// Sources of the configuration changes
+const (
+	// ChangeSourceSetup is a change made by a setup task from the environment
+	ChangeSourceSetup = "setup"
+	// ChangeSourceFile is a change made to config.yml while the service runs
+	ChangeSourceFile = "file"
+	// ChangeSourceAPI is a change made through the admin API, by the Author of the change
+	ChangeSourceAPI = "api"
+)
+
+// ConfigChange is a setting changed from Before to After, named by its path in config.yml. Unset settings
+// are empty. The configuration holds no secrets, only the files they are read from, so values are recorded
+// as they are.
+type ConfigChange struct {
+	Setting string `json:"setting"`
+	Before  string `json:"before"`
+	After   string `json:"after"`
+}
+
+// ConfigHistoryEntry records the settings changed at once, by a setup task, an edit of config.yml or an
+// administrator through the API
+type ConfigHistoryEntry struct {
+	Time    time.Time      `json:"time"`
+	Source  string         `json:"source"`
+	Author  string         `json:"author,omitempty"`
+	Changes []ConfigChange `json:"changes"`
+}
+
+// HistoryFile returns the file the changes of the configuration file are recorded in, next to it
+func HistoryFile(configFile string) string {
+	return path.Join(path.Dir(configFile), constants.ConfigHistoryFile)
+}
+
+// Diff returns the settings changed from before to after, sorted by setting
+func Diff(before, after *Configuration) []ConfigChange {
+	beforeSettings := map[string]string{}
+	afterSettings := map[string]string{}
+	if before != nil {
+		flattenSettings("", reflect.ValueOf(*before), beforeSettings)
+	}
+	if after != nil {
+		flattenSettings("", reflect.ValueOf(*after), afterSettings)
+	}
+
+	var changes []ConfigChange
+	for setting, value := range afterSettings {
+		if beforeSettings[setting] != value {
+			changes = append(changes, ConfigChange{Setting: setting, Before: beforeSettings[setting], After: value})
+		}
+	}
+	for setting, value := range beforeSettings {
+		if _, ok := afterSettings[setting]; !ok {
+			changes = append(changes, ConfigChange{Setting: setting, Before: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Setting < changes[j].Setting
+	})
+	return changes
+}
+
+// flattenSettings records the non-zero settings of v by their path in config.yml, whose keys are the
+// lowercased field names. Empty lists are unset, like the lists missing from config.yml.
+func flattenSettings(prefix string, v reflect.Value, settings map[string]string) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			flattenSettings(prefix, v.Elem(), settings)
+		}
+		return
+	case reflect.Slice, reflect.Map:
+		if v.Len() == 0 {
+			return
+		}
+	}
+	if v.IsZero() {
+		return
+	}
+	if stringer, ok := v.Interface().(fmt.Stringer); ok {
+		settings[prefix] = stringer.String()
+		return
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			flattenSettings(joinSetting(prefix, strings.ToLower(field.Name)), v.Field(i), settings)
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			flattenSettings(joinSetting(prefix, fmt.Sprint(key.Interface())), v.MapIndex(key), settings)
+		}
+	case reflect.Slice, reflect.Array:
+		values := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			values = append(values, fmt.Sprint(v.Index(i).Interface()))
+		}
+		settings[prefix] = strings.Join(values, ",")
+	default:
+		settings[prefix] = fmt.Sprint(v.Interface())
+	}
+}
+
+func joinSetting(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// RecordChanges appends the changes to the history file and emits a security log event for each of them.
+// Nothing is recorded when there is no change.
+func RecordChanges(file, source, author string, changes []ConfigChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	entry := ConfigHistoryEntry{Time: time.Now().UTC(), Source: source, Author: author, Changes: changes}
+	for _, change := range changes {
+		slog.WithFields(logrus.Fields{
+			logformat.EventField:   logformat.EventConfig,
+			logformat.OutcomeField: logformat.OutcomeSuccess,
+			"source":               source,
+			"author":               author,
+		}).Infof("config/history:RecordChanges() Setting %s changed from %q to %q", change.Setting, change.Before,
+			change.After)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "config/history:RecordChanges() Error encoding configuration changes")
+	}
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrap(err, "config/history:RecordChanges() Error opening configuration history")
+	}
+	_, err = f.Write(append(line, '\n'))
+	if err != nil {
+		f.Close()
+		return errors.Wrap(err, "config/history:RecordChanges() Error writing configuration history")
+	}
+	return errors.Wrap(f.Close(), "config/history:RecordChanges() Error writing configuration history")
+}
+
+var historyMu sync.Mutex
+
+// ReadHistory returns the configuration changes recorded in the history file, oldest first. A missing file
+// has no history.
+func ReadHistory(file string) ([]ConfigHistoryEntry, error) {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "config/history:ReadHistory() Error opening configuration history")
+	}
+	defer f.Close()
+
+	var entries []ConfigHistoryEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var entry ConfigHistoryEntry
+		err = json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			return nil, errors.Wrap(err, "config/history:ReadHistory() Invalid configuration history entry")
+		}
+		entries = append(entries, entry)
+	}
+	return entries, errors.Wrap(scanner.Err(), "config/history:ReadHistory() Error reading configuration history")
+}
+
+// ChangeDetector records the changes made to the configuration file while the service runs, the edits of
+// config.yml that setup has not already recorded
+type ChangeDetector struct {
+	mu   sync.Mutex
+	file string
+	last *Configuration
+}
+
+// NewChangeDetector detects the changes of the configuration file from current, the configuration in use
+func NewChangeDetector(configFile string, current *Configuration) *ChangeDetector {
+	return &ChangeDetector{file: configFile, last: current}
+}
+
+// Check records the changes of the configuration file since the last check
+func (d *ChangeDetector) Check() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, err := os.Stat(d.file); err != nil {
+		return errors.Wrap(err, "config/history:Check() Error reading configuration file")
+	}
+	current := Load(d.file)
+	changes := Diff(d.last, current)
+	d.last = current
+	if len(changes) == 0 {
+		return nil
+	}
+
+	history := HistoryFile(d.file)
+	entries, err := ReadHistory(history)
+	if err != nil {
+		return err
+	}
+	if n := len(entries); n > 0 && entries[n-1].Source == ChangeSourceSetup &&
+		reflect.DeepEqual(entries[n-1].Changes, changes) {
+		return nil
+	}
+	return RecordChanges(history, ChangeSourceFile, "", changes)
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	before := &Configuration{Port: 12000, SCSBaseURL: "https://scs.com:9000/scs/sgx/certification/v1"}
+	after := *before
+	after.Port = 12443
+	after.SCSBaseURL = ""
+	after.IPAllowList = []string{"10.0.0.0/8", "192.168.0.0/16"}
+	after.CollateralCacheTTL = 5 * time.Minute
+	after.Database.Driver = "sqlite"
+
+	assert.Empty(t, Diff(before, before))
+	assert.Equal(t, []ConfigChange{
+		{Setting: "collateralcachettl", After: "5m0s"},
+		{Setting: "database.driver", After: "sqlite"},
+		{Setting: "ipallowlist", After: "10.0.0.0/8,192.168.0.0/16"},
+		{Setting: "port", Before: "12000", After: "12443"},
+		{Setting: "scsbaseurl", Before: "https://scs.com:9000/scs/sgx/certification/v1"},
+	}, Diff(before, &after))
+}
+
+func TestSaveRecordsChanges(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqvs-config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := path.Join(dir, "config.yml")
+	assert.NoError(t, ioutil.WriteFile(file, nil, 0600))
+
+	// the initial configuration is not a change
+	c := Load(file)
+	c.Port = 12000
+	assert.NoError(t, c.Save())
+	entries, err := ReadHistory(HistoryFile(file))
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	c.Port = 12443
+	assert.NoError(t, c.Save())
+	entries, err = ReadHistory(HistoryFile(file))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, ChangeSourceSetup, entries[0].Source)
+	assert.Equal(t, []ConfigChange{{Setting: "port", Before: "12000", After: "12443"}}, entries[0].Changes)
+
+	// the detector does not record the changes setup already recorded again
+	d := NewChangeDetector(file, &Configuration{Port: 12000})
+	assert.NoError(t, d.Check())
+	c.LogMaxLength = 1500
+	assert.NoError(t, c.write())
+	assert.NoError(t, d.Check())
+	entries, err = ReadHistory(HistoryFile(file))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, ChangeSourceFile, entries[1].Source)
+	assert.Equal(t, []ConfigChange{{Setting: "logmaxlength", After: "1500"}}, entries[1].Changes)
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"intel/isecl/sqvs/v4/config"
+	"os"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// EffectiveConfig is the machine readable output of the config show command
+type EffectiveConfig struct {
+	Overrides     []config.EnvOverride  `json:"overrides"`
+	Configuration *config.Configuration `json:"configuration"`
+}
+
+// applyEnvOverrides overrides the configuration with the SVS_ environment variables
+func (a *App) applyEnvOverrides() ([]config.EnvOverride, error) {
+	overrides, err := a.configuration().ApplyEnv(os.LookupEnv)
+	if err != nil {
+		return nil, errors.Wrap(err, "app:applyEnvOverrides() Error overriding configuration from environment")
+	}
+	return overrides, nil
+}
+
+// configCommand runs the configuration commands, show prints config.yml or, with --effective, the
+// configuration the service runs with once the environment overrides are applied
+func (a *App) configCommand(args []string) error {
+	if len(args) == 0 || args[0] != "show" {
+		a.printUsage()
+		return errors.New("app:configCommand() Unsupported config command, must be show")
+	}
+
+	fs := flag.NewFlagSet("config show", flag.ContinueOnError)
+	var effective bool
+	fs.BoolVar(&effective, "effective", false, "apply the SVS_ environment variables")
+	err := fs.Parse(args[1:])
+	if err != nil {
+		return errors.Wrap(err, "app:configCommand() Invalid config show arguments")
+	}
+
+	result := EffectiveConfig{Overrides: []config.EnvOverride{}, Configuration: a.configuration()}
+	if effective {
+		result.Overrides, err = a.applyEnvOverrides()
+		if err != nil {
+			return err
+		}
+	}
+
+	if a.outputFormat == outputJSON {
+		return a.printJSON(result)
+	}
+	out, err := yaml.Marshal(result.Configuration)
+	if err != nil {
+		return errors.Wrap(err, "app:configCommand() Error marshalling configuration")
+	}
+	for _, override := range result.Overrides {
+		fmt.Fprintf(a.consoleWriter(), "# %s set by %s\n", override.Field, override.Variable)
+	}
+	fmt.Fprint(a.consoleWriter(), string(out))
+	return nil
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"intel/isecl/sqvs/v4/conformance"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/resource"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"intel/isecl/sqvs/v4/version"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// conformanceCommand runs the conformance commands
+func (a *App) conformanceCommand(args []string) error {
+	if len(args) == 0 || args[0] != "run" {
+		a.printUsage()
+		return usageError(errors.New("app:conformanceCommand() Unsupported conformance command, must be run"))
+	}
+
+	fs := flag.NewFlagSet("conformance run", flag.ContinueOnError)
+	var corpusDir, reportFile string
+	fs.StringVar(&corpusDir, "corpus", "", "directory of the test vector corpus and its manifest.json")
+	fs.StringVar(&reportFile, "report", "", "file the JSON report is written to")
+	err := fs.Parse(args[1:])
+	if err != nil {
+		return usageError(errors.Wrap(err, "app:conformanceCommand() Invalid conformance run arguments"))
+	}
+	if corpusDir == "" {
+		a.printUsage()
+		return usageError(errors.New("app:conformanceCommand() conformance run requires --corpus"))
+	}
+
+	corpus, err := conformance.Load(corpusDir)
+	if err != nil {
+		return err
+	}
+	report, err := a.runConformance(corpus)
+	if err != nil {
+		return err
+	}
+
+	if reportFile != "" {
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "app:conformanceCommand() Error marshalling conformance report")
+		}
+		err = ioutil.WriteFile(reportFile, out, 0644)
+		if err != nil {
+			return errors.Wrap(err, "app:conformanceCommand() Error writing conformance report")
+		}
+	}
+	if a.outputFormat == outputJSON {
+		err = a.printJSON(report)
+	} else {
+		w := a.consoleWriter()
+		for _, result := range report.Results {
+			if result.Passed {
+				fmt.Fprintf(w, "PASS  %s\n", result.Name)
+			} else {
+				fmt.Fprintf(w, "FAIL  %s: %s\n", result.Name, result.Mismatch)
+			}
+		}
+		fmt.Fprintf(w, "Corpus %s: %d test vectors, %d passed, %d failed\n", report.Corpus, report.Total,
+			report.Passed, report.Failed)
+	}
+	if err != nil {
+		return err
+	}
+	if report.Failed != 0 {
+		return errors.Errorf("app:conformanceCommand() %d of the %d test vectors do not conform", report.Failed,
+			report.Total)
+	}
+	return nil
+}
+
+// runConformance verifies the test vectors in process, their collateral served as SCS serves it and their
+// root CA trusted in place of the installed one. Collateral cross-checks, root key pins and the PCK chain
+// cache are disabled so the collateral of one vector never leaks into the verification of another.
+func (a *App) runConformance(corpus *conformance.Corpus) (conformance.Report, error) {
+	err := a.initInProcessVerification()
+	if err != nil {
+		return conformance.Report{}, err
+	}
+	server, err := conformance.NewCollateralServer()
+	if err != nil {
+		return conformance.Report{}, err
+	}
+	defer server.Close()
+
+	c := a.configuration()
+	c.SCSBaseURL = server.SCSBaseURL()
+	c.CrlURLOverrides = server.CrlURLOverrides()
+	c.CollateralCheck.URL = ""
+	c.SGXRootKeyPins = nil
+	c.PckChainCacheTTL = 0
+	for _, crl := range parser.ImportedPckCrls(constants.PckCrlDir) {
+		if time.Now().Before(crl.NextUpdate) {
+			fmt.Fprintf(a.consoleWriter(), "Warning: the imported %s CRL %s is used instead of the CRLs of the "+
+				"test vectors until %s\n", crl.CA, crl.File, crl.NextUpdate.Format(time.RFC3339))
+		}
+	}
+	defer resource.SetSGXRootCA(nil)
+
+	return conformance.Run(corpus, version.GetVersion(), func(vector conformance.Vector) conformance.Outcome {
+		rootCA, err := parseRootCA(vector.Files[conformance.RootCA])
+		if err != nil {
+			return conformance.Outcome{StatusCode: http.StatusInternalServerError, Message: err.Error()}
+		}
+		resource.SetSGXRootCA(rootCA)
+		server.Serve(vector)
+
+		resp, err := resource.SgxEcdsaQuoteVerify(context.Background(), resource.QuoteDataWithChallenge{QuoteData: resource.QuoteData{
+			QuoteBlob:      vector.QuoteBlob,
+			UserData:       vector.UserData,
+			EvaluationTime: vector.EvaluationTime,
+		}})
+		if err != nil {
+			statusCode, steps := resource.FailureOf(err)
+			return conformance.Outcome{StatusCode: statusCode, FailedStep: steps.FailedStep(), Message: err.Error()}
+		}
+		return conformance.Outcome{Verified: true, StatusCode: http.StatusOK, TcbStatus: resp.TcbLevel}
+	}), nil
+}
+
+// parseRootCA parses the PEM encoded root CA of a test vector
+func parseRootCA(content []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("app:parseRootCA() Test vector has no PEM encoded root CA")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "app:parseRootCA() Invalid test vector root CA")
+	}
+	return cert, nil
+}
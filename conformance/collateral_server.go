@@ -0,0 +1,85 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package conformance
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// CollateralServer serves the collateral of the test vector being verified as SCS does, so the verifier
+// fetches it unchanged. CRLs are served at the processor and platform CRL URL overrides.
+type CollateralServer struct {
+	listener net.Listener
+	server   *http.Server
+
+	mu    sync.RWMutex
+	files map[string][]byte
+}
+
+// NewCollateralServer starts serving collateral on a loopback address
+func NewCollateralServer() (*CollateralServer, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, errors.Wrap(err, "conformance/collateral_server:NewCollateralServer() Error listening")
+	}
+	s := &CollateralServer{listener: listener}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/scs/sgx/certification/v1/tcb", s.serve(TcbInfo, "SGX-TCB-Info-Issuer-Chain",
+		TcbInfoIssuerChain))
+	mux.HandleFunc("/scs/sgx/certification/v1/qe/identity", s.serve(QeIdentity, "Sgx-Qe-Identity-Issuer-Chain",
+		QeIdentityIssuerChain))
+	mux.HandleFunc("/crl/processor", s.serve(ProcessorCrl, "SGX-PCK-CRL-Issuer-Chain", CrlIssuerChain))
+	mux.HandleFunc("/crl/platform", s.serve(PlatformCrl, "SGX-PCK-CRL-Issuer-Chain", CrlIssuerChain))
+	s.server = &http.Server{Handler: mux}
+	go func() {
+		_ = s.server.Serve(listener)
+	}()
+	return s, nil
+}
+
+// SCSBaseURL is the SCS base URL the verifier fetches the TCB info and QE identity from
+func (s *CollateralServer) SCSBaseURL() string {
+	return "http://" + s.listener.Addr().String() + "/scs/sgx/certification/v1"
+}
+
+// CrlURLOverrides are the CRL URL overrides the verifier fetches the PCK CRLs from
+func (s *CollateralServer) CrlURLOverrides() []string {
+	base := "http://" + s.listener.Addr().String()
+	return []string{"processor=" + base + "/crl/processor", "platform=" + base + "/crl/platform"}
+}
+
+// Serve sets the test vector whose collateral is served
+func (s *CollateralServer) Serve(vector Vector) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files = vector.Files
+}
+
+// Close stops serving collateral
+func (s *CollateralServer) Close() error {
+	return s.server.Close()
+}
+
+func (s *CollateralServer) serve(collateral, issuerChainHeader, issuerChain string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.RLock()
+		content, ok := s.files[collateral]
+		chain := s.files[issuerChain]
+		s.mu.RUnlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if len(chain) != 0 {
+			w.Header().Set(issuerChainHeader, url.QueryEscape(string(chain)))
+		}
+		_, _ = w.Write(content)
+	}
+}
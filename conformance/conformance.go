@@ -0,0 +1,269 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package conformance
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ManifestFile describes the test vectors of a corpus, at its root
+const ManifestFile = "manifest.json"
+
+// Collateral are the files, relative to the corpus, of the collateral a quote is verified against. The TCB
+// info, QE identity and CRLs are served as SCS serves them, the issuer chains being PEM certificate chains.
+// Collateral left out of a test vector is taken from the defaults of the manifest, collateral missing from
+// both is not found.
+type Collateral struct {
+	RootCA                string `json:"rootCa,omitempty"`
+	TcbInfo               string `json:"tcbInfo,omitempty"`
+	TcbInfoIssuerChain    string `json:"tcbInfoIssuerChain,omitempty"`
+	QeIdentity            string `json:"qeIdentity,omitempty"`
+	QeIdentityIssuerChain string `json:"qeIdentityIssuerChain,omitempty"`
+	ProcessorCrl          string `json:"processorCrl,omitempty"`
+	PlatformCrl           string `json:"platformCrl,omitempty"`
+	CrlIssuerChain        string `json:"crlIssuerChain,omitempty"`
+}
+
+// Expectation is the reference outcome of a test vector. A rejected quote is expected to fail at FailedStep
+// when it is set, a verified one to have the TcbStatus when it is set.
+type Expectation struct {
+	Verified   bool   `json:"verified"`
+	FailedStep string `json:"failedStep,omitempty"`
+	TcbStatus  string `json:"tcbStatus,omitempty"`
+}
+
+// Case is a test vector of the manifest: the quote, binary or base64 encoded, its collateral, the time it is
+// evaluated at, RFC 3339, and the expected outcome
+type Case struct {
+	Name           string      `json:"name"`
+	Description    string      `json:"description,omitempty"`
+	Quote          string      `json:"quote"`
+	UserData       string      `json:"userData,omitempty"`
+	EvaluationTime string      `json:"evaluationTime,omitempty"`
+	Collateral     Collateral  `json:"collateral"`
+	Expect         Expectation `json:"expect"`
+}
+
+// Manifest lists the test vectors of a corpus, positive and negative, and their default collateral
+type Manifest struct {
+	Name           string     `json:"name"`
+	EvaluationTime string     `json:"evaluationTime,omitempty"`
+	Defaults       Collateral `json:"defaults"`
+	Cases          []Case     `json:"cases"`
+}
+
+// Vector is a test vector loaded from its corpus, with the base64 encoded quote and the content of the
+// collateral files by collateral name
+type Vector struct {
+	Case
+	QuoteBlob string
+	Files     map[string][]byte
+}
+
+// Corpus is a loaded corpus of test vectors
+type Corpus struct {
+	Name    string
+	Vectors []Vector
+}
+
+// Outcome is the outcome of the verification of a test vector
+type Outcome struct {
+	Verified   bool   `json:"verified"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	FailedStep string `json:"failedStep,omitempty"`
+	TcbStatus  string `json:"tcbStatus,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
+// Result compares the outcome of a test vector with the expected one
+type Result struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description,omitempty"`
+	Passed      bool          `json:"passed"`
+	Mismatch    string        `json:"mismatch,omitempty"`
+	Expected    Expectation   `json:"expected"`
+	Actual      Outcome       `json:"actual"`
+	Duration    time.Duration `json:"duration"`
+}
+
+// Report is the machine readable outcome of a conformance run
+type Report struct {
+	Corpus   string        `json:"corpus"`
+	Version  string        `json:"version"`
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+	Total    int           `json:"total"`
+	Passed   int           `json:"passed"`
+	Failed   int           `json:"failed"`
+	Results  []Result      `json:"results"`
+}
+
+// Load reads the manifest of the corpus in dir and the files of its test vectors
+func Load(dir string) (*Corpus, error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, errors.Wrap(err, "conformance/conformance:Load() Error reading corpus manifest")
+	}
+	var manifest Manifest
+	err = json.Unmarshal(content, &manifest)
+	if err != nil {
+		return nil, errors.Wrap(err, "conformance/conformance:Load() Invalid corpus manifest")
+	}
+	if len(manifest.Cases) == 0 {
+		return nil, errors.New("conformance/conformance:Load() No test vector found in " + dir)
+	}
+
+	corpus := &Corpus{Name: manifest.Name}
+	if corpus.Name == "" {
+		corpus.Name = filepath.Base(filepath.Clean(dir))
+	}
+	names := map[string]bool{}
+	for _, c := range manifest.Cases {
+		if c.Name == "" || c.Quote == "" || names[c.Name] {
+			return nil, errors.Errorf("conformance/conformance:Load() Test vector %q needs a unique name and a "+
+				"quote", c.Name)
+		}
+		names[c.Name] = true
+		if c.EvaluationTime == "" {
+			c.EvaluationTime = manifest.EvaluationTime
+		}
+		vector, err := loadVector(dir, c, manifest.Defaults)
+		if err != nil {
+			return nil, err
+		}
+		corpus.Vectors = append(corpus.Vectors, *vector)
+	}
+	return corpus, nil
+}
+
+func loadVector(dir string, c Case, defaults Collateral) (*Vector, error) {
+	quote, err := ioutil.ReadFile(filepath.Join(dir, c.Quote))
+	if err != nil {
+		return nil, errors.Wrapf(err, "conformance/conformance:loadVector() Error reading the quote of %s", c.Name)
+	}
+	vector := &Vector{Case: c, Files: map[string][]byte{}}
+	vector.QuoteBlob = strings.TrimSpace(string(quote))
+	if _, err := base64.StdEncoding.DecodeString(vector.QuoteBlob); err != nil {
+		vector.QuoteBlob = base64.StdEncoding.EncodeToString(quote)
+	}
+
+	for name, file := range c.Collateral.merge(defaults).files() {
+		if file == "" {
+			continue
+		}
+		content, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			return nil, errors.Wrapf(err, "conformance/conformance:loadVector() Error reading the %s of %s", name,
+				c.Name)
+		}
+		vector.Files[name] = content
+	}
+	return vector, nil
+}
+
+// Names of the collateral of a test vector
+const (
+	RootCA                = "rootCa"
+	TcbInfo               = "tcbInfo"
+	TcbInfoIssuerChain    = "tcbInfoIssuerChain"
+	QeIdentity            = "qeIdentity"
+	QeIdentityIssuerChain = "qeIdentityIssuerChain"
+	ProcessorCrl          = "processorCrl"
+	PlatformCrl           = "platformCrl"
+	CrlIssuerChain        = "crlIssuerChain"
+)
+
+func (c Collateral) files() map[string]string {
+	return map[string]string{
+		RootCA:                c.RootCA,
+		TcbInfo:               c.TcbInfo,
+		TcbInfoIssuerChain:    c.TcbInfoIssuerChain,
+		QeIdentity:            c.QeIdentity,
+		QeIdentityIssuerChain: c.QeIdentityIssuerChain,
+		ProcessorCrl:          c.ProcessorCrl,
+		PlatformCrl:           c.PlatformCrl,
+		CrlIssuerChain:        c.CrlIssuerChain,
+	}
+}
+
+// merge returns the collateral with the defaults of the files it leaves out
+func (c Collateral) merge(defaults Collateral) Collateral {
+	pick := func(file, defaultFile string) string {
+		if file != "" {
+			return file
+		}
+		return defaultFile
+	}
+	return Collateral{
+		RootCA:                pick(c.RootCA, defaults.RootCA),
+		TcbInfo:               pick(c.TcbInfo, defaults.TcbInfo),
+		TcbInfoIssuerChain:    pick(c.TcbInfoIssuerChain, defaults.TcbInfoIssuerChain),
+		QeIdentity:            pick(c.QeIdentity, defaults.QeIdentity),
+		QeIdentityIssuerChain: pick(c.QeIdentityIssuerChain, defaults.QeIdentityIssuerChain),
+		ProcessorCrl:          pick(c.ProcessorCrl, defaults.ProcessorCrl),
+		PlatformCrl:           pick(c.PlatformCrl, defaults.PlatformCrl),
+		CrlIssuerChain:        pick(c.CrlIssuerChain, defaults.CrlIssuerChain),
+	}
+}
+
+// Run verifies the test vectors of the corpus in turn and reports how their outcomes compare with the
+// expected ones
+func Run(corpus *Corpus, version string, verify func(Vector) Outcome) Report {
+	report := Report{Corpus: corpus.Name, Version: version, Started: time.Now().UTC()}
+	for _, vector := range corpus.Vectors {
+		started := time.Now()
+		outcome := verify(vector)
+		result := Result{
+			Name:        vector.Name,
+			Description: vector.Description,
+			Expected:    vector.Expect,
+			Actual:      outcome,
+			Duration:    time.Since(started),
+		}
+		result.Mismatch = vector.Expect.mismatch(outcome)
+		result.Passed = result.Mismatch == ""
+		if result.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Results = append(report.Results, result)
+	}
+	report.Total = len(report.Results)
+	report.Duration = time.Since(report.Started)
+	return report
+}
+
+// mismatch describes how the outcome differs from the expected one, an empty string when it does not
+func (e Expectation) mismatch(o Outcome) string {
+	switch {
+	case e.Verified && !o.Verified:
+		return fmt.Sprintf("expected the quote to be verified, rejected at %s: %s", stepOrNone(o.FailedStep),
+			o.Message)
+	case !e.Verified && o.Verified:
+		return "expected the quote to be rejected, verified with TCB status " + o.TcbStatus
+	case e.Verified && e.TcbStatus != "" && !strings.EqualFold(e.TcbStatus, o.TcbStatus):
+		return fmt.Sprintf("expected TCB status %s, got %s", e.TcbStatus, o.TcbStatus)
+	case !e.Verified && e.FailedStep != "" && e.FailedStep != o.FailedStep:
+		return fmt.Sprintf("expected the quote to be rejected at %s, rejected at %s: %s", e.FailedStep,
+			stepOrNone(o.FailedStep), o.Message)
+	}
+	return ""
+}
+
+func stepOrNone(step string) string {
+	if step == "" {
+		return "no step"
+	}
+	return step
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package conformance
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testManifest = `{
+  "name": "qvl",
+  "evaluationTime": "2021-06-01T00:00:00Z",
+  "defaults": {"rootCa": "root.pem", "tcbInfo": "tcb.json", "tcbInfoIssuerChain": "chain.pem"},
+  "cases": [
+    {"name": "up-to-date", "quote": "ok.dat", "expect": {"verified": true, "tcbStatus": "UpToDate"}},
+    {"name": "revoked", "quote": "ok.dat", "collateral": {"tcbInfo": "revoked.json"},
+     "expect": {"verified": false, "failedStep": "tcb_evaluation"}}
+  ]
+}`
+
+func writeCorpus(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "sqvs-conformance")
+	assert.NoError(t, err)
+	for name, content := range files {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	dir := writeCorpus(t, map[string]string{ManifestFile: testManifest, "ok.dat": "\x03\x00\x02\xff",
+		"root.pem": "root", "tcb.json": "tcb", "revoked.json": "revoked", "chain.pem": "chain"})
+	defer os.RemoveAll(dir)
+
+	corpus, err := Load(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "qvl", corpus.Name)
+	assert.Len(t, corpus.Vectors, 2)
+	assert.Equal(t, "AwAC/w==", corpus.Vectors[0].QuoteBlob)
+	assert.Equal(t, "2021-06-01T00:00:00Z", corpus.Vectors[0].EvaluationTime)
+	assert.Equal(t, "tcb", string(corpus.Vectors[0].Files[TcbInfo]))
+	// collateral of a vector overrides the defaults, the others are kept
+	assert.Equal(t, "revoked", string(corpus.Vectors[1].Files[TcbInfo]))
+	assert.Equal(t, "root", string(corpus.Vectors[1].Files[RootCA]))
+	_, ok := corpus.Vectors[1].Files[QeIdentity]
+	assert.False(t, ok)
+
+	missing := writeCorpus(t, map[string]string{ManifestFile: testManifest})
+	defer os.RemoveAll(missing)
+	_, err = Load(missing)
+	assert.Error(t, err)
+}
+
+func TestRun(t *testing.T) {
+	corpus := &Corpus{Name: "qvl", Vectors: []Vector{
+		{Case: Case{Name: "up-to-date", Expect: Expectation{Verified: true, TcbStatus: "UpToDate"}}},
+		{Case: Case{Name: "out-of-date", Expect: Expectation{Verified: true, TcbStatus: "OutOfDate"}}},
+		{Case: Case{Name: "revoked", Expect: Expectation{FailedStep: "tcb_evaluation"}}},
+		{Case: Case{Name: "bad-signature", Expect: Expectation{FailedStep: "quote_signature"}}},
+	}}
+	outcomes := map[string]Outcome{
+		"up-to-date":    {Verified: true, TcbStatus: "UpToDate"},
+		"out-of-date":   {Verified: true, TcbStatus: "UpToDate"},
+		"revoked":       {FailedStep: "tcb_evaluation"},
+		"bad-signature": {Verified: true, TcbStatus: "UpToDate"},
+	}
+
+	report := Run(corpus, "v4.1.0", func(vector Vector) Outcome {
+		return outcomes[vector.Name]
+	})
+	assert.Equal(t, 4, report.Total)
+	assert.Equal(t, 2, report.Passed)
+	assert.Equal(t, 2, report.Failed)
+	assert.True(t, report.Results[0].Passed)
+	assert.Equal(t, "expected TCB status OutOfDate, got UpToDate", report.Results[1].Mismatch)
+	assert.True(t, report.Results[2].Passed)
+	assert.Equal(t, "expected the quote to be rejected, verified with TCB status UpToDate",
+		report.Results[3].Mismatch)
+}
+
+func TestCollateralServer(t *testing.T) {
+	server, err := NewCollateralServer()
+	assert.NoError(t, err)
+	defer server.Close()
+	server.Serve(Vector{Files: map[string][]byte{TcbInfo: []byte(`{"tcbInfo":{}}`),
+		TcbInfoIssuerChain: []byte("-----BEGIN CERTIFICATE-----")}})
+
+	resp, err := http.Get(server.SCSBaseURL() + "/tcb?fmspc=00906ED50000")
+	assert.NoError(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `{"tcbInfo":{}}`, string(body))
+	chain, _ := url.QueryUnescape(resp.Header.Get("SGX-TCB-Info-Issuer-Chain"))
+	assert.Equal(t, "-----BEGIN CERTIFICATE-----", chain)
+
+	// collateral missing from the vector is not found
+	resp, err = http.Get(server.SCSBaseURL() + "/qe/identity")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Len(t, server.CrlURLOverrides(), 2)
+}
@@ -18,20 +18,31 @@ const (
 	SecLogFile                     = LogDir + "sqvs-security.log"
 	HTTPLogFile                    = LogDir + "http.log"
 	ConfigFile                     = "config.yml"
+	ConfigHistoryFile              = "config-history.log"
 	DefaultTLSCertFile             = ConfigDir + "tls-cert.pem"
 	DefaultTLSKeyFile              = ConfigDir + "tls.key"
 	TrustedJWTSigningCertsDir      = ConfigDir + "certs/trustedjwt/"
 	TrustedCAsStoreDir             = ConfigDir + "certs/trustedca/"
 	TrustedSGXRootCAFile           = ConfigDir + "certs/trustedSGXRootCA.pem"
+	PckCrlDir                      = ConfigDir + "crls/"
+	RelyingPartyKeysDir            = ConfigDir + "certs/relying-parties/"
+	PolicyAuthorKeysDir            = ConfigDir + "certs/policy-authors/"
 	ServiceRemoveCmd               = "systemctl disable sqvs"
 	ServiceName                    = "SQVS"
 	ExplicitServiceName            = "SGX Quote Verification Service"
 	QuoteVerifierGroupName         = "QuoteVerifier"
+	AdministratorGroupName         = "Administrator"
+	QuoteDiagnosticsGroupName      = "QuoteDiagnostics"
+	QuoteAuditorGroupName          = "QuoteAuditor"
+	PayloadCaptureGroupName        = "PayloadCapture"
+	CapacityReaderGroupName        = "CapacityReader"
+	CollateralReaderGroupName      = "CollateralReader"
+	PlatformIdentityGroupName      = "PlatformIdentityReader"
 	SQVSUserName                   = "sqvs"
 	DefaultHTTPSPort               = 12000
 	DefaultKeyAlgorithm            = "rsa"
 	DefaultKeyAlgorithmLength      = 3072
-	DefaultSQVSTLSSan              = "127.0.0.1,localhost"
+	DefaultSQVSTLSSan              = "127.0.0.1,::1,localhost"
 	DefaultSQVSTLSCn               = "SQVS TLS Certificate"
 	DefaultSQVSSigningCertCn       = "SQVS QVL Response Signing Certificate"
 	DefaultJwtValidateCacheKeyMins = 60
@@ -43,17 +54,97 @@ const (
 	DefaultIdleTimeout             = 1 * time.Second
 	DefaultMaxHeaderBytes          = 1 << 20
 	DefaultLogEntryMaxLength       = 300
-	SGXRootCACertSubjectStr        = "CN=Intel SGX Root CA,O=Intel Corporation,L=Santa Clara,ST=CA,C=US"
-	SGXInterCACertSubjectStr       = "CN=Intel SGX PCK Processor CA,O=Intel Corporation,L=Santa Clara,ST=CA,C=US|CN=Intel SGX PCK Platform CA,O=Intel Corporation,L=Santa Clara,ST=CA,C=US"
-	SGXCRLIssuerStr                = "C=US,ST=CA,L=Santa Clara,O=Intel Corporation,CN=Intel SGX PCK Processor CA|C=US,ST=CA,L=Santa Clara,O=Intel Corporation,CN=Intel SGX PCK Platform CA"
-	SGXPCKCertificateSubjectStr    = "CN=Intel SGX PCK Certificate,O=Intel Corporation,L=Santa Clara,ST=CA,C=US"
-	SGXTCBInfoSubjectStr           = "CN=Intel SGX TCB Signing,O=Intel Corporation,L=Santa Clara,ST=CA,C=US"
-	SGXQEInfoSubjectStr            = "CN=Intel SGX TCB Signing,O=Intel Corporation,L=Santa Clara,ST=CA,C=US"
-	MaxTcbLevels                   = 16
-	MaxTCBCompLevels               = 18
+	DefaultLogSampleRate           = 1
+	DefaultMaxConcurrentRequests   = 100
+	DefaultMaxQueuedRequests       = 200
+	DefaultMaxQueueWait            = 5 * time.Second
+	DefaultTargetUtilization       = 0.7
+	CapacityLoadAverageWindow      = time.Minute
+	DefaultWebhookTimeout          = 10 * time.Second
+	DefaultKeyReleaseTimeout       = 10 * time.Second
+	DefaultTLSMinRSAKeySize        = 3072
+	DefaultTLSMinECDSAKeySize      = 256
+	DefaultPayloadCaptureFile      = LogDir + "sqvs-payload-capture.log"
+	DefaultPayloadCaptureDuration  = 30 * time.Minute
+	DefaultPayloadCaptureBodySize  = 64 * 1024
+	DefaultBootstrapListenAddr     = "127.0.0.1:12001"
+	DefaultDBFile                  = ConfigDir + "sqvs-store.json"
+	DefaultSQLiteFile              = ConfigDir + "sqvs.db"
+	DefaultDBPort                  = 5432
+	DefaultDBSSLMode               = "verify-full"
+	DefaultCorsAllowedMethods      = "GET,POST"
+	DefaultCorsAllowedHeaders      = "Accept,Authorization,Content-Type"
+	TrustStoreReloadDelay          = 2 * time.Second
+	DefaultListLimit               = 100
+	MaxListLimit                   = 1000
+	DefaultQuotaTenantClaim        = "tenant"
+
+	DefaultAnomalyWindow               = 5 * time.Minute
+	DefaultAnomalyFailedVerifications  = 20
+	DefaultAnomalyDebugQuotes          = 50
+	DefaultAnomalyDistinctMeasurements = 10
+
+	DefaultExpiryAlertThresholds = "720h,168h,24h"
+	DefaultExpiryCheckInterval   = time.Hour
+
+	DefaultCollateralCheckMode     = "enforce"
+	DefaultCollateralCheckCacheTTL = 10 * time.Minute
+	CollateralCheckTimeout         = 10 * time.Second
+	MaxCollateralSize              = 1 << 20 // upper bound on the TCB info and QE identity of the secondary source
+	DefaultCollateralCacheTTL      = 10 * time.Minute
+
+	DefaultCollateralFailureMode = "fail-closed"
+	DefaultCollateralGracePeriod = 24 * time.Hour
+
+	DefaultPlatformIdentifiers = "redacted"
+
+	DefaultSessionTTL = 5 * time.Minute
+	SessionNonceSize  = 32
+
+	DefaultRequestSignatureMaxAge = 5 * time.Minute
+
+	DefaultOutboundMaxAttempts         = 3
+	DefaultOutboundInitialBackoff      = 200 * time.Millisecond
+	DefaultOutboundMaxBackoff          = 2 * time.Second
+	DefaultOutboundAttemptTimeout      = 10 * time.Second
+	DefaultOutboundRetryBudgetRatio    = 0.2
+	DefaultOutboundBreakerThreshold    = 5
+	DefaultOutboundBreakerOpenDuration = 30 * time.Second
+	DefaultOutboundRetryLogInterval    = time.Minute
+	DefaultCollateralFetchTimeout      = 30 * time.Second
+	DefaultCRLFetchTimeout             = 30 * time.Second
+	DefaultVerificationComputeTimeout  = 10 * time.Second
+	DefaultPckChainCacheTTL            = 5 * time.Minute
+	MaxVerifiedPckChains               = 10000
+	DefaultHistoryPruneInterval        = time.Hour
+	DefaultMemoryHistoryMaxRecords     = 100000
+	DefaultDependencyWaitTimeout       = 5 * time.Minute
+	DefaultTokenRenewBefore            = 5 * time.Minute
+	DefaultDependencyRetryInterval     = time.Second
+	DefaultDependencyMaxRetryInterval  = 30 * time.Second
+	DefaultVerifierEvidenceRefresh     = time.Hour
+	DefaultResultEventsBatchSize       = 100
+	DefaultResultEventsFlushInterval   = time.Second
+	DefaultResultEventsQueueSize       = 10000
+	DefaultTrustedTimeSource           = "system"
+	DefaultRoughtimeRefresh            = 10 * time.Minute
+	DefaultSigningKeyOverlap           = 24 * time.Hour
+	MaxVerifierEvidenceNonceLength     = 64
+	SGXRootCACertSubjectStr            = "CN=Intel SGX Root CA,O=Intel Corporation,L=Santa Clara,ST=CA,C=US"
+	SGXInterCACertSubjectStr           = "CN=Intel SGX PCK Processor CA,O=Intel Corporation,L=Santa Clara,ST=CA,C=US|CN=Intel SGX PCK Platform CA,O=Intel Corporation,L=Santa Clara,ST=CA,C=US"
+	SGXCRLIssuerStr                    = "C=US,ST=CA,L=Santa Clara,O=Intel Corporation,CN=Intel SGX PCK Processor CA|C=US,ST=CA,L=Santa Clara,O=Intel Corporation,CN=Intel SGX PCK Platform CA"
+	SGXPCKCertificateSubjectStr        = "CN=Intel SGX PCK Certificate,O=Intel Corporation,L=Santa Clara,ST=CA,C=US"
+	SGXPCKPlatformCACommonName         = "Intel SGX PCK Platform CA"
+	SGXTCBInfoSubjectStr               = "CN=Intel SGX TCB Signing,O=Intel Corporation,L=Santa Clara,ST=CA,C=US"
+	SGXQEInfoSubjectStr                = "CN=Intel SGX TCB Signing,O=Intel Corporation,L=Santa Clara,ST=CA,C=US"
+	MaxTcbLevels                       = 16
+	MaxTCBCompLevels                   = 18
 	// At a minimum, Quote Should contain header, ecdsa report, attestation public key, signature, cert data
 	MinQuoteSize        = 1020
 	MaxQuoteSize        = (30 * 1024)
+	MaxQuoteUploadSize  = (2 * MaxQuoteSize) // upper bound on multipart quote upload request bodies
+	MaxBatchQuotes      = 100                // upper bound on the number of quotes of a batch request
+	MaxEnclaveUpload    = (64 << 20)         // upper bound on enclave identity import request bodies
 	MinCertDataSize     = 500
 	MaxCertDataSize     = (4098 * 3)
 	MinCertsInCertChain = 3 // PCK Leaf/Intermediate/Root CA certificates expected in quote
@@ -61,4 +152,12 @@ const (
 	PCKCertType         = 5
 	PublicKeyLocation   = ConfigDir + "sqvs_signing_pub_key.pem"
 	PrivateKeyLocation  = ConfigDir + "sqvs_signing_priv_key.pem"
+	RetiredSigningCerts = ConfigDir + "certs/signing-retired/"
+	CustomClaimsFile    = ConfigDir + "custom-claims.yml"
+	MaintenanceFile     = ConfigDir + "maintenance.json"
+
+	// signed configuration bundles, verified against the operator public key and installed at startup
+	ConfigBundleFile          = ConfigDir + "config-bundle.tar.gz"
+	ConfigBundleSignatureFile = ConfigBundleFile + ".sig"
+	ConfigBundlePublicKeyFile = ConfigDir + "config-bundle-pub-key.pem"
 )
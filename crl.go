@@ -0,0 +1,100 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package main
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"io/ioutil"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// crl imports a PCK CRL, in DER, PEM or base64, for the service to use instead of fetching the CRL of its CA
+// until the CRL expires
+func (a *App) crl(args []string) error {
+	if len(args) == 0 || args[0] != "import" {
+		a.printUsage()
+		return errors.New("app:crl() Unsupported crl command, must be import")
+	}
+
+	fs := flag.NewFlagSet("crl import", flag.ContinueOnError)
+	var issuerChainFile string
+	fs.StringVar(&issuerChainFile, "issuer-chain", "", "PEM file with the issuer chain of the CRL")
+	err := fs.Parse(args[1:])
+	if err != nil {
+		return errors.Wrap(err, "app:crl() Invalid crl import arguments")
+	}
+	if fs.NArg() != 1 {
+		a.printUsage()
+		return errors.New("app:crl() crl import requires the CRL file")
+	}
+
+	data, err := ioutil.ReadFile(fs.Arg(0))
+	if err != nil {
+		return errors.Wrap(err, "app:crl() Error reading CRL")
+	}
+	var issuerChain []*x509.Certificate
+	var trustedRoot *x509.Certificate
+	if issuerChainFile != "" {
+		issuerChain, err = readCertificates(issuerChainFile)
+		if err != nil {
+			return errors.Wrap(err, "app:crl() Error reading CRL issuer chain")
+		}
+		roots, err := readCertificates(constants.TrustedSGXRootCAFile)
+		if err != nil {
+			return errors.Wrap(err, "app:crl() Error reading trusted SGX root CA")
+		}
+		trustedRoot = roots[0]
+	}
+
+	imported, err := parser.ImportPckCrl(data, constants.PckCrlDir, issuerChain, trustedRoot, time.Now())
+	if err != nil {
+		return errors.Wrap(err, "app:crl() Error importing CRL")
+	}
+
+	if a.outputFormat == outputJSON {
+		return a.printJSON(imported)
+	}
+	fmt.Fprintf(a.consoleWriter(), "Imported the PCK %s CA CRL to %s, %d revoked certificates, valid until %s\n",
+		imported.CA, imported.File, imported.Revoked, imported.NextUpdate.Format(time.RFC3339))
+	if issuerChainFile == "" {
+		fmt.Fprintln(a.consoleWriter(), "The CRL signature will be verified against the PCK certificate chain of the quotes")
+	}
+	return nil
+}
+
+// readCertificates reads the PEM encoded certificates of the file
+func readCertificates(file string) ([]*x509.Certificate, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM certificate found in " + file)
+	}
+	return certs, nil
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package dependencies
+
+import (
+	"context"
+	commLog "intel/isecl/lib/common/v4/log"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/netfamily"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var log = commLog.GetDefaultLogger()
+
+const dialTimeout = 5 * time.Second
+
+// Dependency is a service SQVS relies on, identified by its base URL
+type Dependency struct {
+	Name string
+	URL  string
+}
+
+// FromConfig returns the services SQVS relies on in the configuration: CMS, AAS when tokens are
+// required and SCS
+func FromConfig(c *config.Configuration) []Dependency {
+	var deps []Dependency
+	if c.CMSBaseURL != "" {
+		deps = append(deps, Dependency{Name: "CMS", URL: c.CMSBaseURL})
+	}
+	if c.IncludeToken && c.AuthServiceURL != "" {
+		deps = append(deps, Dependency{Name: "AAS", URL: c.AuthServiceURL})
+	}
+	if c.SCSBaseURL != "" {
+		deps = append(deps, Dependency{Name: "SCS", URL: c.SCSBaseURL})
+	}
+	return deps
+}
+
+// Check reports whether a TCP connection can be opened to the host of the dependency
+func (d Dependency) Check() error {
+	u, err := url.Parse(d.URL)
+	if err != nil {
+		return errors.Wrapf(err, "dependencies/dependencies:Check() Invalid %s URL", d.Name)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+	conn, err := netfamily.DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// WaitFor blocks until all dependencies are reachable, retrying with exponential backoff from initialBackoff up
+// to maxBackoff. It gives up once maxWait has elapsed, non-positive durations take their defaults.
+func WaitFor(deps []Dependency, maxWait, initialBackoff, maxBackoff time.Duration) error {
+	log.Trace("dependencies/dependencies:WaitFor() Entering")
+	defer log.Trace("dependencies/dependencies:WaitFor() Leaving")
+
+	if maxWait <= 0 {
+		maxWait = constants.DefaultDependencyWaitTimeout
+	}
+	if initialBackoff <= 0 {
+		initialBackoff = constants.DefaultDependencyRetryInterval
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = constants.DefaultDependencyMaxRetryInterval
+	}
+
+	deadline := time.Now().Add(maxWait)
+	backoff := initialBackoff
+	pending := deps
+	for {
+		var unreachable []Dependency
+		for _, dep := range pending {
+			if err := dep.Check(); err != nil {
+				log.WithError(err).Warnf("dependencies/dependencies:WaitFor() %s at %s is not reachable", dep.Name, dep.URL)
+				unreachable = append(unreachable, dep)
+				continue
+			}
+			log.Infof("dependencies/dependencies:WaitFor() %s at %s is reachable", dep.Name, dep.URL)
+		}
+		if len(unreachable) == 0 {
+			return nil
+		}
+		pending = unreachable
+
+		if time.Now().Add(backoff).After(deadline) {
+			names := make([]string, len(pending))
+			for i, dep := range pending {
+				names[i] = dep.Name
+			}
+			return errors.Errorf("dependencies/dependencies:WaitFor() Gave up waiting for %v after %v", names, maxWait)
+		}
+		log.Infof("dependencies/dependencies:WaitFor() Waiting %v for %d dependencies", backoff, len(pending))
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
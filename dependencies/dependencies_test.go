@@ -0,0 +1,29 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package dependencies
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitFor(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	reachable := Dependency{Name: "SCS", URL: "https://" + listener.Addr().String() + "/scs/sgx/"}
+	assert.NoError(t, WaitFor([]Dependency{reachable}, time.Second, 10*time.Millisecond, 10*time.Millisecond))
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	unreachable := Dependency{Name: "CMS", URL: "https://" + closed.Addr().String() + "/cms/v1/"}
+	closed.Close()
+	err = WaitFor([]Dependency{reachable, unreachable}, 50*time.Millisecond, 10*time.Millisecond, 20*time.Millisecond)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "CMS")
+}
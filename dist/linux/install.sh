@@ -54,45 +54,13 @@ if command -v $COMPONENT_NAME &>/dev/null; then
   exit 0
 fi
 
-echo "Setting up SGX Quote Verification Service Linux User..."
-id -u $SERVICE_USERNAME 2> /dev/null || useradd --shell /bin/false $SERVICE_USERNAME
-
-echo "Installing SGX Quote Verification Service..."
-
-PRODUCT_HOME=/opt/$COMPONENT_NAME
-BIN_PATH=$PRODUCT_HOME/bin
-LOG_PATH=/var/log/$COMPONENT_NAME/
-CONFIG_PATH=/etc/$COMPONENT_NAME/
-CERTS_PATH=$CONFIG_PATH/certs
-CERTDIR_TRUSTEDJWTCERTS=$CERTS_PATH/trustedjwt
-CERTDIR_TRUSTEDJWTCAS=$CERTS_PATH/trustedca
-
-for directory in $BIN_PATH $LOG_PATH $CONFIG_PATH $CERTS_PATH $CERTDIR_TRUSTEDJWTCERTS $CERTDIR_TRUSTEDJWTCAS; do
-  # mkdir -p will return 0 if directory exists or is a symlink to an existing directory or directory and parents can be created
-  mkdir -p $directory
-  if [ $? -ne 0 ]; then
-    echo "Cannot create directory: $directory"
-    exit 1
-  fi
-  chown -R $SERVICE_USERNAME:$SERVICE_USERNAME $directory
-  chmod 700 $directory
-done
-
-cp $COMPONENT_NAME $BIN_PATH/ && chown $SERVICE_USERNAME:$SERVICE_USERNAME $BIN_PATH/*
-chmod 700 $BIN_PATH/*
-ln -sfT $BIN_PATH/$COMPONENT_NAME /usr/bin/$COMPONENT_NAME
-
-# Create logging dir in /var/log
-mkdir -p $LOG_PATH && chown sqvs:sqvs $LOG_PATH
-chmod 740 $LOG_PATH
-
-# Install systemd script
-cp sqvs.service $PRODUCT_HOME && chown $SERVICE_USERNAME:$SERVICE_USERNAME $PRODUCT_HOME/sqvs.service && chown $SERVICE_USERNAME:$SERVICE_USERNAME $PRODUCT_HOME
-
-# Enable systemd service
-systemctl disable sqvs.service > /dev/null 2>&1
-systemctl enable $PRODUCT_HOME/sqvs.service
-systemctl daemon-reload
+# Create the user, directories, systemd unit and log rotation configuration with the binary itself,
+# so they always match what it expects
+./$COMPONENT_NAME install
+if [ $? -ne 0 ]; then
+  echo "Installation of $COMPONENT_NAME failed"
+  exit 1
+fi
 
 #Install log rotation
 auto_install() {
@@ -135,29 +103,6 @@ logRotate_install() {
 
 logRotate_install
 
-export LOG_ROTATION_PERIOD=${LOG_ROTATION_PERIOD:-weekly}
-export LOG_COMPRESS=${LOG_COMPRESS:-compress}
-export LOG_DELAYCOMPRESS=${LOG_DELAYCOMPRESS:-delaycompress}
-export LOG_COPYTRUNCATE=${LOG_COPYTRUNCATE:-copytruncate}
-export LOG_SIZE=${LOG_SIZE:-100M}
-export LOG_OLD=${LOG_OLD:-12}
-
-mkdir -p /etc/logrotate.d
-
-if [ ! -a /etc/logrotate.d/sqvs ]; then
- echo "/var/log/sqvs/*.log {
-    missingok
-    notifempty
-    rotate $LOG_OLD
-    maxsize $LOG_SIZE
-    nodateext
-    $LOG_ROTATION_PERIOD
-    $LOG_COMPRESS
-    $LOG_DELAYCOMPRESS
-    $LOG_COPYTRUNCATE
-}" > /etc/logrotate.d/sqvs
-fi
-
 # check if SQVS_NOSETUP is defined
 if [ "${SQVS_NOSETUP,,}" == "true" ]; then
     echo "SQVS_NOSETUP is true, skipping setup"
@@ -0,0 +1,216 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package egress
+
+import (
+	"context"
+	commLog "intel/isecl/lib/common/v4/log"
+	"intel/isecl/sqvs/v4/logformat"
+	"intel/isecl/sqvs/v4/resolver"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+var slog = commLog.GetSecurityLogger()
+
+// ErrBlocked is the cause of the errors returned for connections to destinations the allow-list does not allow
+var ErrBlocked = errors.New("egress/egress: Destination is not allowed by the egress allow-list")
+
+// AllowList lists the destinations SQVS may connect to: host names, matched exactly or, starting with "*.",
+// by suffix, and IP addresses or CIDR blocks matched against the addresses host names resolve to
+type AllowList struct {
+	hosts   []string
+	domains []string
+	nets    []*net.IPNet
+}
+
+var current atomic.Value
+
+// Parse builds the allow-list of the entries, nil when there are none and egress is not restricted
+func Parse(entries []string) (*AllowList, error) {
+	l := &AllowList{}
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			continue
+		case strings.Contains(entry, "/"):
+			_, ipNet, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, errors.Wrapf(err, "egress/egress:Parse() Invalid CIDR block %s", entry)
+			}
+			l.nets = append(l.nets, ipNet)
+		case net.ParseIP(strings.Trim(entry, "[]")) != nil:
+			ip := net.ParseIP(strings.Trim(entry, "[]"))
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			l.nets = append(l.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		case strings.HasPrefix(entry, "*."):
+			if !validHost(entry[2:]) {
+				return nil, errors.Errorf("egress/egress:Parse() Invalid domain %s", entry)
+			}
+			l.domains = append(l.domains, entry[1:])
+		default:
+			if !validHost(entry) {
+				return nil, errors.Errorf("egress/egress:Parse() Invalid host name %s", entry)
+			}
+			l.hosts = append(l.hosts, entry)
+		}
+	}
+	if len(l.hosts) == 0 && len(l.domains) == 0 && len(l.nets) == 0 {
+		return nil, nil
+	}
+	return l, nil
+}
+
+func validHost(host string) bool {
+	return host != "" && !strings.ContainsAny(host, "*[]:/ ")
+}
+
+// Set makes the allow-list the one outbound connections are checked against, nil lifting the restriction
+func Set(l *AllowList) {
+	current.Store(&l)
+}
+
+// Current returns the allow-list outbound connections are checked against, nil when egress is not restricted
+func Current() *AllowList {
+	l, _ := current.Load().(**AllowList)
+	if l == nil {
+		return nil
+	}
+	return *l
+}
+
+// AllowsHost tells whether the host name or IP address is allowed without resolving it
+func (l *AllowList) AllowsHost(host string) bool {
+	if l == nil {
+		return true
+	}
+	host = strings.TrimSuffix(strings.ToLower(strings.Trim(host, "[]")), ".")
+	if ip := net.ParseIP(host); ip != nil {
+		return l.AllowsIP(ip)
+	}
+	for _, h := range l.hosts {
+		if host == h {
+			return true
+		}
+	}
+	for _, domain := range l.domains {
+		if strings.HasSuffix(host, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsIP tells whether the IP address is in one of the CIDR blocks of the allow-list
+func (l *AllowList) AllowsIP(ip net.IP) bool {
+	if l == nil {
+		return true
+	}
+	for _, ipNet := range l.nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check checks a connection to the address, a host and port, against the current allow-list before it is
+// dialed. Connections to host names the allow-list does not name are checked once resolved by the returned
+// control function, to be set as the Control of the net.Dialer, so the address connected to is the one
+// checked. Blocked connections are security logged.
+func Check(network, address string) (func(network, address string, c syscall.RawConn) error, error) {
+	l := Current()
+	if l == nil {
+		return nil, nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	if l.AllowsHost(host) {
+		return nil, nil
+	}
+	if net.ParseIP(strings.Trim(host, "[]")) != nil || len(l.nets) == 0 {
+		return nil, blocked(network, address)
+	}
+	return func(network, resolved string, _ syscall.RawConn) error {
+		ip, _, err := net.SplitHostPort(resolved)
+		if err != nil {
+			ip = resolved
+		}
+		if !l.AllowsIP(net.ParseIP(ip)) {
+			return blocked(network, address+" ("+resolved+")")
+		}
+		return nil
+	}, nil
+}
+
+// Proxy returns the proxy of the request as http.ProxyFromEnvironment does once its destination is allowed.
+// Proxied destinations are only known by name, they must be allowed by name or IP address.
+func Proxy(req *http.Request) (*url.URL, error) {
+	proxy, err := http.ProxyFromEnvironment(req)
+	if err != nil || proxy == nil {
+		return proxy, err
+	}
+	if !Current().AllowsHost(req.URL.Hostname()) {
+		return nil, blocked("tcp", req.URL.Host)
+	}
+	return proxy, nil
+}
+
+// Resolver checks the destinations of clients that resolve host names themselves and take a resolver rather
+// than a dial function. Once the host or all the addresses it resolves to are allowed, LookupHost returns its
+// addresses when a resolver is configured, so that static host overrides apply, and none otherwise, the host
+// being dialed by name.
+type Resolver struct{}
+
+// LookupHost checks the host against the current allow-list
+func (Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	l := Current()
+	allowed := l.AllowsHost(host)
+	pinned := resolver.Current() != nil
+	if allowed && !pinned {
+		return nil, nil
+	}
+	if !allowed && (net.ParseIP(strings.Trim(host, "[]")) != nil || len(l.nets) == 0) {
+		return nil, blocked("tcp", host)
+	}
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	hosts := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		if !allowed && !l.AllowsIP(addr.IP) {
+			return nil, blocked("tcp", host+" ("+addr.IP.String()+")")
+		}
+		hosts = append(hosts, addr.IP.String())
+	}
+	if !pinned {
+		return nil, nil
+	}
+	return hosts, nil
+}
+
+func blocked(network, address string) error {
+	slog.WithFields(logrus.Fields{
+		logformat.EventField:   logformat.EventEgress,
+		logformat.OutcomeField: logformat.OutcomeFailure,
+		"destination":          address,
+		"network":              network,
+	}).Warnf("egress/egress: Blocked %s connection to %s, not allowed by the egress allow-list", network, address)
+	return errors.Wrapf(ErrBlocked, "egress/egress: Connection to %s blocked", address)
+}
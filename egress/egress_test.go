@@ -0,0 +1,69 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package egress
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowList(t *testing.T) {
+	l, err := Parse(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, l)
+	assert.True(t, l.AllowsHost("scs.example.com"))
+
+	l, err = Parse([]string{"SCS.example.com", "*.trustedservices.intel.com", "10.0.0.0/8", "2001:db8::1"})
+	assert.NoError(t, err)
+	assert.True(t, l.AllowsHost("scs.example.com"))
+	assert.True(t, l.AllowsHost("scs.example.com."))
+	assert.True(t, l.AllowsHost("api.trustedservices.intel.com"))
+	assert.False(t, l.AllowsHost("trustedservices.intel.com"))
+	assert.False(t, l.AllowsHost("cms.example.com"))
+	assert.True(t, l.AllowsHost("10.1.2.3"))
+	assert.True(t, l.AllowsHost("[2001:db8::1]"))
+	assert.False(t, l.AllowsHost("192.168.1.1"))
+
+	for _, entry := range []string{"10.0.0.0/33", "*.", "scs.example.com:443", "*.*.example.com"} {
+		_, err = Parse([]string{entry})
+		assert.Error(t, err, entry)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	defer Set(nil)
+	control, err := Check("tcp", "cms.example.com:8445")
+	assert.NoError(t, err)
+	assert.Nil(t, control)
+
+	l, err := Parse([]string{"scs.example.com", "127.0.0.0/8"})
+	assert.NoError(t, err)
+	Set(l)
+	control, err = Check("tcp", "scs.example.com:9000")
+	assert.NoError(t, err)
+	assert.Nil(t, control)
+	_, err = Check("tcp", "192.168.1.1:443")
+	assert.Equal(t, ErrBlocked, errors.Cause(err))
+
+	control, err = Check("tcp", "localhost:443")
+	assert.NoError(t, err)
+	assert.NoError(t, control("tcp", "127.0.0.1:443", nil))
+	assert.Error(t, control("tcp", "[::1]:443", nil))
+
+	l, err = Parse([]string{"scs.example.com"})
+	assert.NoError(t, err)
+	Set(l)
+	_, err = Check("tcp", "localhost:443")
+	assert.Equal(t, ErrBlocked, errors.Cause(err))
+	_, err = Resolver{}.LookupHost(context.Background(), "localhost")
+	assert.Equal(t, ErrBlocked, errors.Cause(err))
+	addrs, err := Resolver{}.LookupHost(context.Background(), "scs.example.com")
+	assert.NoError(t, err)
+	assert.Empty(t, addrs)
+
+}
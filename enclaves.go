@@ -0,0 +1,103 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/resource"
+	"intel/isecl/sqvs/v4/sigstruct"
+	"intel/isecl/sqvs/v4/types"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// enclaves registers the enclaves signed by SIGSTRUCT files or signed enclave binaries, and lists the
+// registered enclaves
+func (a *App) enclaves(args []string) error {
+	if len(args) == 0 || (args[0] != "import" && args[0] != "list") {
+		a.printUsage()
+		return errors.New("app:enclaves() Unsupported enclaves command, must be import or list")
+	}
+
+	var files []string
+	var description string
+	if args[0] == "import" {
+		fs := flag.NewFlagSet("enclaves import", flag.ContinueOnError)
+		fs.StringVar(&description, "description", "", "description of the enclaves, their file name by default")
+		err := fs.Parse(args[1:])
+		if err != nil {
+			return errors.Wrap(err, "app:enclaves() Invalid enclaves import arguments")
+		}
+		if fs.NArg() == 0 {
+			a.printUsage()
+			return errors.New("app:enclaves() enclaves import requires SIGSTRUCT or signed enclave files")
+		}
+		files = fs.Args()
+	}
+
+	// the memory driver keeps the store in memory and the service would overwrite the imported enclaves
+	conf := a.configuration().Database
+	if args[0] == "import" && (conf.Driver == "" || conf.Driver == repository.DriverMemory) {
+		if systemctl, err := exec.LookPath("systemctl"); err == nil {
+			if exec.Command(systemctl, "is-active", "--quiet", "sqvs").Run() == nil {
+				return errors.New("app:enclaves() sqvs must be stopped before importing enclaves, or they must " +
+					"be imported through /svs/v1/admin/enclaves")
+			}
+		}
+	}
+
+	// every file is parsed before any enclave is registered, so an invalid file registers none
+	now := time.Now().UTC()
+	enclaves := make(types.EnclaveIdentities, 0, len(files))
+	for _, file := range files {
+		s, err := sigstruct.ParseFile(file)
+		if err != nil {
+			return errors.Wrap(err, "app:enclaves() Error reading enclave identity")
+		}
+		fileDescription := description
+		if fileDescription == "" {
+			fileDescription = filepath.Base(file)
+		}
+		enclaves = append(enclaves, resource.NewEnclaveIdentity(s, fileDescription, now))
+	}
+
+	db, err := repository.Open(conf)
+	if err != nil {
+		return errors.Wrap(err, "app:enclaves() Error opening SQVS store")
+	}
+	defer db.Close()
+	repo := db.EnclaveIdentityRepository()
+
+	if args[0] == "list" {
+		enclaves, err = repo.RetrieveAll()
+		if err != nil {
+			return errors.Wrap(err, "app:enclaves() Error retrieving enclave identities")
+		}
+	}
+	for i := range files {
+		err = repo.Save(&enclaves[i])
+		if err != nil {
+			return errors.Wrap(err, "app:enclaves() Error saving enclave identity")
+		}
+	}
+
+	if a.outputFormat == outputJSON {
+		return a.printJSON(enclaves)
+	}
+	for _, enclave := range enclaves {
+		fmt.Fprintf(a.consoleWriter(), "MRENCLAVE %s MRSIGNER %s ISVPRODID %d ISVSVN %d attributes %s/%s %s\n",
+			enclave.MrEnclave, enclave.MrSigner, enclave.IsvProdID, enclave.IsvSvn, enclave.Attributes,
+			enclave.AttributesMask, enclave.Description)
+	}
+	if args[0] == "import" {
+		fmt.Fprintf(a.consoleWriter(), "Registered %d enclaves\n", len(enclaves))
+	}
+	return nil
+}
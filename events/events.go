@@ -0,0 +1,107 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	commLog "intel/isecl/lib/common/v4/log"
+	"intel/isecl/sqvs/v4/truststore"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var log = commLog.GetDefaultLogger()
+
+const (
+	TcbStatusDowngraded   = "tcb-status-downgraded"
+	VerificationResult    = "quote-verification-result"
+	AnomalousQuotePattern = "anomalous-quote-pattern"
+	ExpiryAlert           = "expiry-alert"
+)
+
+// Event is the payload delivered to event consumers
+type Event struct {
+	Type string      `json:"type"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data"`
+}
+
+func NewEvent(eventType string, data interface{}) Event {
+	return Event{
+		Type: eventType,
+		Time: time.Now().UTC(),
+		Data: data,
+	}
+}
+
+// Publisher delivers events to an external consumer
+type Publisher interface {
+	Publish(event Event) error
+}
+
+// NoopPublisher discards all events, it is used when no consumer is configured
+type NoopPublisher struct{}
+
+func (NoopPublisher) Publish(Event) error {
+	return nil
+}
+
+// WebhookPublisher posts each event as JSON to the configured URL
+type WebhookPublisher struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewWebhookPublisher(url, caCertsDir string, timeout time.Duration) (*WebhookPublisher, error) {
+	client, err := truststore.HTTPClient(caCertsDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "events/events:NewWebhookPublisher() Error in getting client object")
+	}
+	client.Timeout = timeout
+	return &WebhookPublisher{URL: url, Client: client}, nil
+}
+
+func (p *WebhookPublisher) Publish(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "events/events:Publish() Error marshalling event")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "events/events:Publish() Failed to create webhook request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "events/events:Publish() Failed to deliver event to webhook")
+	}
+	defer func() {
+		derr := resp.Body.Close()
+		if derr != nil {
+			log.WithError(derr).Error("Error closing webhook response")
+		}
+	}()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return errors.New(fmt.Sprintf("events/events:Publish() Invalid status code received from webhook: %d",
+			resp.StatusCode))
+	}
+	return nil
+}
+
+// PublishAsync delivers the event in the background so callers are not delayed by slow consumers
+func PublishAsync(p Publisher, event Event) {
+	go func() {
+		if err := p.Publish(event); err != nil {
+			log.WithError(err).Errorf("events/events:PublishAsync() Failed to publish %s event", event.Type)
+		}
+	}()
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package events
+
+import (
+	"context"
+	"crypto/tls"
+	"intel/isecl/sqvs/v4/egress"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+)
+
+// kafkaTimeout bounds the time spent writing a batch to the Kafka brokers
+const kafkaTimeout = 30 * time.Second
+
+// kafkaSender writes events to a Kafka topic, waiting for all in-sync replicas to acknowledge them
+type kafkaSender struct {
+	writer *kafka.Writer
+}
+
+func newKafkaSender(brokers []string, topic string, batchSize int, tlsConfig *tls.Config) *kafkaSender {
+	return &kafkaSender{
+		writer: kafka.NewWriter(kafka.WriterConfig{
+			Brokers: brokers,
+			Topic:   topic,
+			Dialer: &kafka.Dialer{
+				Timeout:  10 * time.Second,
+				TLS:      tlsConfig,
+				Resolver: egress.Resolver{},
+			},
+			// the events are already batched by the publisher, the writer must not hold them back
+			BatchSize:    batchSize,
+			BatchTimeout: 10 * time.Millisecond,
+			// a failed write is retried by the publisher
+			MaxAttempts:  1,
+			RequiredAcks: -1,
+		}),
+	}
+}
+
+func (s *kafkaSender) Send(messages [][]byte) error {
+	msgs := make([]kafka.Message, len(messages))
+	for i, message := range messages {
+		msgs[i] = kafka.Message{Value: message}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), kafkaTimeout)
+	defer cancel()
+	err := s.writer.WriteMessages(ctx, msgs...)
+	if err != nil {
+		return errors.Wrap(err, "events/kafka:Send() Error writing events to Kafka")
+	}
+	return nil
+}
+
+func (s *kafkaSender) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package events
+
+import (
+	"crypto/tls"
+	"intel/isecl/sqvs/v4/netfamily"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+// natsAckTimeout bounds the wait for JetStream to acknowledge a batch
+const natsAckTimeout = 30 * time.Second
+
+// natsSender publishes events to a NATS JetStream subject. Plain NATS subjects are not acknowledged,
+// a stream must capture the subject for events to be delivered at least once.
+type natsSender struct {
+	conn    *nats.Conn
+	js      nats.JetStreamContext
+	subject string
+}
+
+func newNATSSender(urls []string, subject string, tlsConfig *tls.Config) (*natsSender, error) {
+	options := []nats.Option{
+		nats.Name("SQVS"),
+		nats.MaxReconnects(-1),
+		nats.SetCustomDialer(netfamily.Dialer{}),
+	}
+	if tlsConfig != nil {
+		options = append(options, nats.Secure(tlsConfig))
+	}
+	conn, err := nats.Connect(strings.Join(urls, ","), options...)
+	if err != nil {
+		return nil, errors.Wrap(err, "events/nats:newNATSSender() Error connecting to NATS")
+	}
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "events/nats:newNATSSender() Error creating JetStream context")
+	}
+	return &natsSender{conn: conn, js: js, subject: subject}, nil
+}
+
+func (s *natsSender) Send(messages [][]byte) error {
+	futures := make([]nats.PubAckFuture, 0, len(messages))
+	for _, message := range messages {
+		future, err := s.js.PublishAsync(s.subject, message)
+		if err != nil {
+			return errors.Wrap(err, "events/nats:Send() Error publishing event")
+		}
+		futures = append(futures, future)
+	}
+
+	timeout := time.NewTimer(natsAckTimeout)
+	defer timeout.Stop()
+	for _, future := range futures {
+		select {
+		case <-future.Ok():
+		case err := <-future.Err():
+			return errors.Wrap(err, "events/nats:Send() Event was not acknowledged")
+		case <-timeout.C:
+			return errors.New("events/nats:Send() Timed out waiting for acknowledgements")
+		}
+	}
+	return nil
+}
+
+func (s *natsSender) Close() error {
+	s.conn.Close()
+	return nil
+}
@@ -0,0 +1,231 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package events
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/truststore"
+	"sync"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/pkg/errors"
+)
+
+const (
+	BrokerKafka = "kafka"
+	BrokerNATS  = "nats"
+
+	EncodingJSON = "json"
+	EncodingCBOR = "cbor"
+)
+
+const (
+	// sendRetryInterval and sendMaxRetryInterval bound the backoff between attempts to send a batch
+	sendRetryInterval    = time.Second
+	sendMaxRetryInterval = 30 * time.Second
+	// closeTimeout bounds the time spent delivering the buffered events on Close
+	closeTimeout = 10 * time.Second
+)
+
+// Sender delivers a batch of encoded events to a message queue, returning without error only once the
+// queue acknowledged all of them
+type Sender interface {
+	Send(messages [][]byte) error
+	Close() error
+}
+
+// QueuePublisher publishes events to a message queue in batches. Delivery is at least once, a batch that
+// could not be sent is resent until the queue acknowledges it, so consumers must tolerate duplicates.
+// Publish does not wait for the delivery, events are dropped when the buffer is full.
+type QueuePublisher struct {
+	sender        Sender
+	encode        func(Event) ([]byte, error)
+	batchSize     int
+	flushInterval time.Duration
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan []byte
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewQueuePublisher connects to the Kafka brokers or NATS servers of the configuration and starts
+// delivering the published events
+func NewQueuePublisher(conf config.ResultEventsConfig, caCertsDir string) (*QueuePublisher, error) {
+	log.Trace("events/queue:NewQueuePublisher() Entering")
+	defer log.Trace("events/queue:NewQueuePublisher() Leaving")
+
+	if len(conf.Addresses) == 0 || conf.Topic == "" {
+		return nil, errors.New("events/queue:NewQueuePublisher() Broker addresses and topic must be set")
+	}
+	encode, err := newEncoder(conf.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	batchSize := conf.BatchSize
+	if batchSize <= 0 {
+		batchSize = constants.DefaultResultEventsBatchSize
+	}
+
+	var tlsConfig *tls.Config
+	if conf.TLS {
+		tlsConfig, err = truststore.TLSConfig(caCertsDir)
+		if err != nil {
+			return nil, errors.Wrap(err, "events/queue:NewQueuePublisher() Error loading trusted CAs")
+		}
+	}
+
+	var sender Sender
+	switch conf.Broker {
+	case BrokerKafka:
+		sender = newKafkaSender(conf.Addresses, conf.Topic, batchSize, tlsConfig)
+	case BrokerNATS:
+		sender, err = newNATSSender(conf.Addresses, conf.Topic, tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Errorf("events/queue:NewQueuePublisher() Unsupported broker %s", conf.Broker)
+	}
+	return newQueuePublisher(sender, encode, batchSize, conf.FlushInterval, conf.QueueSize), nil
+}
+
+func newQueuePublisher(sender Sender, encode func(Event) ([]byte, error), batchSize int,
+	flushInterval time.Duration, queueSize int) *QueuePublisher {
+	if flushInterval <= 0 {
+		flushInterval = constants.DefaultResultEventsFlushInterval
+	}
+	if queueSize <= 0 {
+		queueSize = constants.DefaultResultEventsQueueSize
+	}
+	p := &QueuePublisher{
+		sender:        sender,
+		encode:        encode,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		queue:         make(chan []byte, queueSize),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+func newEncoder(encoding string) (func(Event) ([]byte, error), error) {
+	switch encoding {
+	case "", EncodingJSON:
+		return func(event Event) ([]byte, error) {
+			return json.Marshal(event)
+		}, nil
+	case EncodingCBOR:
+		// times are encoded as in the JSON events, CBOR falls back to the json field names
+		em, err := cbor.EncOptions{Time: cbor.TimeRFC3339Nano}.EncMode()
+		if err != nil {
+			return nil, errors.Wrap(err, "events/queue:newEncoder() Error creating CBOR encoder")
+		}
+		return func(event Event) ([]byte, error) {
+			return em.Marshal(event)
+		}, nil
+	}
+	return nil, errors.Errorf("events/queue:newEncoder() Unsupported encoding %s", encoding)
+}
+
+// Publish queues the event for delivery
+func (p *QueuePublisher) Publish(event Event) error {
+	message, err := p.encode(event)
+	if err != nil {
+		return errors.Wrap(err, "events/queue:Publish() Error encoding event")
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return errors.New("events/queue:Publish() Publisher is closed")
+	}
+	select {
+	case p.queue <- message:
+		return nil
+	default:
+		return errors.New("events/queue:Publish() Event buffer is full, dropping event")
+	}
+}
+
+// Close stops accepting events, delivers the buffered ones and closes the connection to the queue
+func (p *QueuePublisher) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.queue)
+	p.mu.Unlock()
+
+	select {
+	case <-p.done:
+	case <-time.After(closeTimeout):
+		close(p.stop)
+		<-p.done
+	}
+	return p.sender.Close()
+}
+
+func (p *QueuePublisher) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([][]byte, 0, p.batchSize)
+	for {
+		select {
+		case message, ok := <-p.queue:
+			if !ok {
+				p.send(batch)
+				return
+			}
+			batch = append(batch, message)
+			if len(batch) < p.batchSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+		if !p.send(batch) {
+			return
+		}
+		batch = make([][]byte, 0, p.batchSize)
+	}
+}
+
+// send delivers the batch, retrying with exponential backoff until it succeeds. It returns false when
+// the publisher was stopped before the batch could be delivered.
+func (p *QueuePublisher) send(batch [][]byte) bool {
+	if len(batch) == 0 {
+		return true
+	}
+	backoff := sendRetryInterval
+	for {
+		err := p.sender.Send(batch)
+		if err == nil {
+			return true
+		}
+		log.WithError(err).Warnf("events/queue:send() Error sending %d events, retrying in %s", len(batch), backoff)
+		select {
+		case <-p.stop:
+			log.Errorf("events/queue:send() Publisher closed, %d events were not delivered", len(batch))
+			return false
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > sendMaxRetryInterval {
+			backoff = sendMaxRetryInterval
+		}
+	}
+}
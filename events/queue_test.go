@@ -0,0 +1,96 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type testSender struct {
+	mu       sync.Mutex
+	failures int
+	batches  [][][]byte
+}
+
+func (s *testSender) Send(messages [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("broker unavailable")
+	}
+	s.batches = append(s.batches, messages)
+	return nil
+}
+
+func (s *testSender) Close() error {
+	return nil
+}
+
+func (s *testSender) batchSizes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sizes := make([]int, len(s.batches))
+	for i, batch := range s.batches {
+		sizes[i] = len(batch)
+	}
+	return sizes
+}
+
+func TestQueuePublisherBatches(t *testing.T) {
+	sender := &testSender{}
+	encode, err := newEncoder(EncodingJSON)
+	assert.NoError(t, err)
+	p := newQueuePublisher(sender, encode, 2, time.Hour, 10)
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, p.Publish(NewEvent(VerificationResult, i)))
+	}
+	assert.Eventually(t, func() bool { return len(sender.batchSizes()) == 2 }, time.Second, 10*time.Millisecond)
+
+	// the last, partial batch is delivered on close
+	assert.NoError(t, p.Close())
+	assert.Equal(t, []int{2, 2, 1}, sender.batchSizes())
+	assert.Error(t, p.Publish(NewEvent(VerificationResult, 5)))
+}
+
+func TestQueuePublisherRetriesFailedBatch(t *testing.T) {
+	sender := &testSender{failures: 1}
+	encode, err := newEncoder(EncodingJSON)
+	assert.NoError(t, err)
+	p := newQueuePublisher(sender, encode, 10, 10*time.Millisecond, 10)
+
+	assert.NoError(t, p.Publish(NewEvent(VerificationResult, "result")))
+	assert.Eventually(t, func() bool { return len(sender.batchSizes()) == 1 }, 3*time.Second, 10*time.Millisecond)
+	assert.NoError(t, p.Close())
+
+	var event Event
+	assert.NoError(t, json.Unmarshal(sender.batches[0][0], &event))
+	assert.Equal(t, VerificationResult, event.Type)
+	assert.Equal(t, "result", event.Data)
+}
+
+func TestCBOREncoding(t *testing.T) {
+	encode, err := newEncoder(EncodingCBOR)
+	assert.NoError(t, err)
+	message, err := encode(NewEvent(VerificationResult, map[string]string{"status": "verified"}))
+	assert.NoError(t, err)
+
+	var decoded map[string]interface{}
+	assert.NoError(t, cbor.Unmarshal(message, &decoded))
+	assert.Equal(t, VerificationResult, decoded["type"])
+	assert.IsType(t, "", decoded["time"])
+	assert.Equal(t, map[interface{}]interface{}{"status": "verified"}, decoded["data"])
+
+	_, err = newEncoder("xml")
+	assert.Error(t, err)
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package main
+
+import (
+	stderrors "errors"
+	"intel/isecl/sqvs/v4/server"
+)
+
+// Exit codes of the sqvs command, listed in its usage
+const (
+	ExitOK              = 0
+	ExitFailure         = 1
+	ExitConfigError     = 2
+	ExitDependencyError = 3
+	ExitSetupIncomplete = 4
+	ExitSetupFailed     = 5
+	ExitUsageError      = 64
+)
+
+// exitError is an error the command exits with a specific code for
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+func (e *exitError) Cause() error {
+	return e.err
+}
+
+// withExitCode marks err as the cause of the code the command exits with
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// serverExitCodes maps the kinds of the errors the service fails to start with to exit codes
+var serverExitCodes = map[server.Kind]int{
+	server.KindConfig:          ExitConfigError,
+	server.KindDependency:      ExitDependencyError,
+	server.KindSetupIncomplete: ExitSetupIncomplete,
+}
+
+func configError(err error) error {
+	return withExitCode(ExitConfigError, err)
+}
+
+func usageError(err error) error {
+	return withExitCode(ExitUsageError, err)
+}
+
+// exitCode returns the exit code of the outermost exitError wrapped by err, then of the kind of the server
+// error it wraps, ExitFailure when there is neither
+func exitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var exitErr *exitError
+	if stderrors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	if code, ok := serverExitCodes[server.KindOf(err)]; ok {
+		return code
+	}
+	return ExitFailure
+}
@@ -0,0 +1,202 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package expiry
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	commLog "intel/isecl/lib/common/v4/log"
+	"intel/isecl/sqvs/v4/events"
+	"intel/isecl/sqvs/v4/metrics"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var log = commLog.GetDefaultLogger()
+
+const (
+	KindTLSCertificate     = "tls-certificate"
+	KindSigningCertificate = "signing-certificate"
+	KindRootCA             = "sgx-root-ca"
+	KindTcbInfo            = "tcb-info"
+	KindCrl                = "crl"
+)
+
+var expirySeconds = metrics.NewGauge("sqvs_expiry_seconds",
+	"Seconds until a certificate or collateral expires, negative once expired", "kind", "name")
+
+// Item is a certificate or a collateral that expires
+type Item struct {
+	Kind   string
+	Name   string
+	Expiry time.Time
+}
+
+// Source lists the items to check
+type Source func() ([]Item, error)
+
+// Alert is raised when an item gets within a threshold of its expiry, or expires
+type Alert struct {
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Expiry    time.Time `json:"expiry"`
+	Remaining string    `json:"remaining"`
+	Threshold string    `json:"threshold,omitempty"`
+	Expired   bool      `json:"expired"`
+}
+
+// Checker raises an alert every time an item crosses one of the thresholds before its expiry and when it
+// expires. An item renewed, whose expiry changed, is alerted on again from the largest threshold.
+type Checker struct {
+	thresholds []time.Duration
+	sources    []Source
+	publisher  events.Publisher
+
+	mu      sync.Mutex
+	alerted map[string]alertLevel
+}
+
+type alertLevel struct {
+	expiry time.Time
+	level  int
+}
+
+func NewChecker(thresholds []time.Duration, publisher events.Publisher, sources ...Source) *Checker {
+	sorted := append([]time.Duration{}, thresholds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] > sorted[j] })
+	return &Checker{
+		thresholds: sorted,
+		sources:    sources,
+		publisher:  publisher,
+		alerted:    map[string]alertLevel{},
+	}
+}
+
+// Check updates the expiry metric of the items and returns the alerts raised at now, they are logged and
+// published
+func (c *Checker) Check(now time.Time) []Alert {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var alerts []Alert
+	seen := map[string]bool{}
+	for _, source := range c.sources {
+		items, err := source()
+		if err != nil {
+			log.WithError(err).Error("expiry/expiry:Check() Error listing the items to check")
+		}
+		for _, item := range items {
+			remaining := item.Expiry.Sub(now)
+			expirySeconds.Set(remaining.Seconds(), item.Kind, item.Name)
+
+			key := item.Kind + "\x00" + item.Name
+			seen[key] = true
+			level := c.level(remaining)
+			previous, ok := c.alerted[key]
+			if ok && previous.expiry.Equal(item.Expiry) && level <= previous.level {
+				continue
+			}
+			c.alerted[key] = alertLevel{expiry: item.Expiry, level: level}
+			if level == 0 {
+				continue
+			}
+			alert := Alert{
+				Kind:      item.Kind,
+				Name:      item.Name,
+				Expiry:    item.Expiry.UTC(),
+				Remaining: remaining.Round(time.Second).String(),
+				Expired:   remaining <= 0,
+			}
+			if alert.Expired {
+				log.Errorf("expiry/expiry:Check() The %s %s expired on %s", item.Kind, item.Name,
+					alert.Expiry.Format(time.RFC3339))
+			} else {
+				alert.Threshold = c.thresholds[level-1].String()
+				log.Warnf("expiry/expiry:Check() The %s %s expires on %s, in %s", item.Kind, item.Name,
+					alert.Expiry.Format(time.RFC3339), alert.Remaining)
+			}
+			events.PublishAsync(c.publisher, events.NewEvent(events.ExpiryAlert, alert))
+			alerts = append(alerts, alert)
+		}
+	}
+	for key := range c.alerted {
+		if !seen[key] {
+			delete(c.alerted, key)
+		}
+	}
+	return alerts
+}
+
+// level is 0 when the remaining time is above every threshold, the number of thresholds crossed otherwise and
+// one more once expired
+func (c *Checker) level(remaining time.Duration) int {
+	if remaining <= 0 {
+		return len(c.thresholds) + 1
+	}
+	level := 0
+	for i, threshold := range c.thresholds {
+		if remaining <= threshold {
+			level = i + 1
+		}
+	}
+	return level
+}
+
+// Run checks the items every interval until stop is closed
+func (c *Checker) Run(interval time.Duration, stop <-chan struct{}) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		c.Check(time.Now())
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// CertificateFile lists the certificates of the PEM file, no certificate is listed when the file does not exist
+func CertificateFile(kind, file string) Source {
+	return func() ([]Item, error) {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, nil
+			}
+			return nil, errors.Wrapf(err, "expiry/expiry:CertificateFile() Error reading %s", file)
+		}
+		var items []Item
+		for {
+			var block *pem.Block
+			block, data = pem.Decode(data)
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return items, errors.Wrapf(err, "expiry/expiry:CertificateFile() Error parsing certificate of %s", file)
+			}
+			items = append(items, Item{
+				Kind:   kind,
+				Name:   filepath.Base(file) + ":" + cert.Subject.CommonName,
+				Expiry: cert.NotAfter,
+			})
+		}
+		return items, nil
+	}
+}
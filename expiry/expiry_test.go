@@ -0,0 +1,41 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package expiry
+
+import (
+	"intel/isecl/sqvs/v4/events"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker(t *testing.T) {
+	now := time.Date(2021, 6, 15, 0, 0, 0, 0, time.UTC)
+	item := Item{Kind: KindCrl, Name: "processor", Expiry: now.Add(10 * 24 * time.Hour)}
+	checker := NewChecker([]time.Duration{24 * time.Hour, 720 * time.Hour, 168 * time.Hour}, events.NoopPublisher{},
+		func() ([]Item, error) { return []Item{item}, nil })
+
+	alerts := checker.Check(now)
+	assert.Len(t, alerts, 1)
+	assert.Equal(t, "720h0m0s", alerts[0].Threshold)
+	assert.Empty(t, checker.Check(now.Add(time.Hour)))
+
+	alerts = checker.Check(now.Add(4 * 24 * time.Hour))
+	assert.Len(t, alerts, 1)
+	assert.Equal(t, "168h0m0s", alerts[0].Threshold)
+
+	alerts = checker.Check(now.Add(11 * 24 * time.Hour))
+	assert.Len(t, alerts, 1)
+	assert.True(t, alerts[0].Expired)
+	assert.Empty(t, checker.Check(now.Add(12*24*time.Hour)))
+
+	// Renewed, alerted on again once within a threshold
+	item.Expiry = now.Add(100 * 24 * time.Hour)
+	assert.Empty(t, checker.Check(now.Add(12*24*time.Hour)))
+	alerts = checker.Check(now.Add(75 * 24 * time.Hour))
+	assert.Len(t, alerts, 1)
+	assert.Equal(t, "720h0m0s", alerts[0].Threshold)
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package fips
+
+import (
+	"crypto/tls"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/trustedtime"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ModeHeader is the response header reporting whether the service runs in FIPS mode
+const ModeHeader = "X-SQVS-FIPS-Mode"
+
+// CipherSuites are the FIPS approved TLS 1.2 cipher suites, the TLS 1.3 suites are restricted by the validated
+// crypto module itself
+var CipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+}
+
+// CurvePreferences are the FIPS approved key exchange curves, X25519 is not
+var CurvePreferences = []tls.CurveID{tls.CurveP384, tls.CurveP256}
+
+// JWTAlgorithms are the FIPS approved algorithms bearer tokens may be signed with
+var JWTAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// IsApprovedJWTAlgorithm reports whether alg is a FIPS approved JWT signature algorithm
+func IsApprovedJWTAlgorithm(alg string) bool {
+	for _, approved := range JWTAlgorithms {
+		if alg == approved {
+			return true
+		}
+	}
+	return false
+}
+
+// CryptoModule returns the name of the crypto module the service is built with and whether it runs in its
+// FIPS validated mode
+func CryptoModule() (string, bool) {
+	return validatedModule()
+}
+
+// Validate checks that the service can run in FIPS mode: it must be built with a FIPS validated crypto module
+// running in FIPS mode and must not be configured to use algorithms that are not approved
+func Validate(conf *config.Configuration) error {
+	var problems []string
+	if module, enabled := CryptoModule(); !enabled {
+		if module == "" {
+			problems = append(problems, "the service is not built with a FIPS validated crypto module, "+
+				"build it with GOEXPERIMENT=boringcrypto or run it with GODEBUG=fips140=on")
+		} else {
+			problems = append(problems, "the "+module+" is not running in FIPS mode")
+		}
+	}
+	if conf.TrustedTime.Source == trustedtime.SourceRoughtime {
+		problems = append(problems, "the roughtime trusted time source relies on Ed25519 signatures")
+	}
+	for _, alg := range resultSigningAlgorithms(conf) {
+		if !IsApprovedJWTAlgorithm(alg) {
+			problems = append(problems, "results are signed with "+alg+" which is not approved")
+		}
+	}
+	if len(problems) > 0 {
+		return errors.Errorf("fips/fips:Validate() FIPS mode cannot be enabled, %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// resultSigningAlgorithms returns the algorithms signed results are configured to be signed with
+func resultSigningAlgorithms(conf *config.Configuration) []string {
+	var algorithms []string
+	if conf.ResultSigningAlgorithm != "" {
+		algorithms = append(algorithms, conf.ResultSigningAlgorithm)
+	}
+	for _, entry := range conf.ResultSigningKeys {
+		algorithms = append(algorithms, strings.SplitN(strings.TrimSpace(entry), "=", 2)[0])
+	}
+	return algorithms
+}
+
+// Middleware annotates the responses with the FIPS mode in effect
+func Middleware(enabled bool) func(http.Handler) http.Handler {
+	mode := "disabled"
+	if enabled {
+		mode = "enabled"
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(ModeHeader, mode)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,43 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package fips
+
+import (
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/trustedtime"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsApprovedJWTAlgorithm(t *testing.T) {
+	assert.True(t, IsApprovedJWTAlgorithm("RS384"))
+	assert.True(t, IsApprovedJWTAlgorithm("ES256"))
+	for _, alg := range []string{"HS256", "EdDSA", "none", ""} {
+		assert.False(t, IsApprovedJWTAlgorithm(alg), alg)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	conf := &config.Configuration{}
+	conf.TrustedTime.Source = trustedtime.SourceRoughtime
+	err := Validate(conf)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "roughtime")
+
+	if _, enabled := CryptoModule(); enabled {
+		conf.TrustedTime.Source = ""
+		assert.NoError(t, Validate(conf))
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	handler := Middleware(true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/svs/v1/version", nil))
+	assert.Equal(t, "enabled", recorder.Header().Get(ModeHeader))
+}
@@ -0,0 +1,14 @@
+//go:build goexperiment.boringcrypto
+// +build goexperiment.boringcrypto
+
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package fips
+
+import "crypto/boring"
+
+func validatedModule() (string, bool) {
+	return "BoringCrypto module", boring.Enabled()
+}
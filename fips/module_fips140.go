@@ -0,0 +1,14 @@
+//go:build go1.24 && !goexperiment.boringcrypto
+// +build go1.24,!goexperiment.boringcrypto
+
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package fips
+
+import "crypto/fips140"
+
+func validatedModule() (string, bool) {
+	return "Go Cryptographic Module", fips140.Enabled()
+}
@@ -0,0 +1,12 @@
+//go:build !go1.24 && !goexperiment.boringcrypto
+// +build !go1.24,!goexperiment.boringcrypto
+
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package fips
+
+func validatedModule() (string, bool) {
+	return "", false
+}
@@ -1,11 +1,19 @@
 module intel/isecl/sqvs/v4
 
 require (
+	github.com/fsnotify/fsnotify v1.4.9
+	github.com/fxamacker/cbor/v2 v2.3.0
 	github.com/gorilla/handlers v1.4.2
 	github.com/gorilla/mux v1.7.4
+	github.com/lib/pq v1.10.0
+	github.com/mattn/go-sqlite3 v1.14.6
+	github.com/miekg/pkcs11 v1.1.1
+	github.com/nats-io/nats.go v1.11.0
 	github.com/pkg/errors v0.9.1
+	github.com/segmentio/kafka-go v0.3.5
 	github.com/sirupsen/logrus v1.7.0
 	github.com/stretchr/testify v1.6.1
+	golang.org/x/sys v0.20.0
 	gopkg.in/restruct.v1 v1.0.0-20190323193435-3c2afb705f3c
 	gopkg.in/yaml.v2 v2.4.0
 	intel/isecl/lib/clients/v4 v4.2.0
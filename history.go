@@ -0,0 +1,80 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/retention"
+	"os/exec"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// PruneResult is the machine readable output of the history prune command
+type PruneResult struct {
+	Before  string `json:"before"`
+	Deleted int    `json:"deleted"`
+}
+
+// history runs the verification history maintenance commands
+func (a *App) history(args []string) error {
+	if len(args) == 0 || args[0] != "prune" {
+		a.printUsage()
+		return errors.New("app:history() Unsupported history command, must be prune")
+	}
+
+	fs := flag.NewFlagSet("history prune", flag.ContinueOnError)
+	var beforeArg string
+	fs.StringVar(&beforeArg, "before", "", "date, YYYY-MM-DD or RFC 3339, before which records are pruned")
+	err := fs.Parse(args[1:])
+	if err != nil {
+		return errors.Wrap(err, "app:history() Invalid history prune arguments")
+	}
+	before, err := parseDate(beforeArg)
+	if err != nil {
+		return errors.Wrap(err, "app:history() --before must be a date in YYYY-MM-DD or RFC 3339 format")
+	}
+
+	// the memory driver keeps the store in memory and the service would overwrite the pruned snapshot
+	conf := a.configuration().Database
+	if conf.Driver == "" || conf.Driver == repository.DriverMemory {
+		if systemctl, err := exec.LookPath("systemctl"); err == nil {
+			if exec.Command(systemctl, "is-active", "--quiet", "sqvs").Run() == nil {
+				return errors.New("app:history() sqvs must be stopped before pruning the verification history")
+			}
+		}
+	}
+
+	db, err := repository.Open(conf)
+	if err != nil {
+		return errors.Wrap(err, "app:history() Error opening SQVS store")
+	}
+	defer db.Close()
+
+	pruner, err := retention.NewPruner(a.configuration().Retention, db.VerificationRepository())
+	if err != nil {
+		return errors.Wrap(err, "app:history() Error initializing verification history retention")
+	}
+	deleted, err := pruner.Prune(before, 0)
+	if err != nil {
+		return errors.Wrap(err, "app:history() Error pruning verification history")
+	}
+
+	if a.outputFormat == outputJSON {
+		return a.printJSON(PruneResult{Before: before.Format(time.RFC3339), Deleted: deleted})
+	}
+	fmt.Fprintf(a.consoleWriter(), "Pruned %d verification records created before %s\n", deleted, before.Format(time.RFC3339))
+	return nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
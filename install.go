@@ -0,0 +1,268 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"intel/isecl/sqvs/v4/constants"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	serviceUnitFile   = "sqvs.service"
+	logRotateConfFile = "/etc/logrotate.d/sqvs"
+)
+
+// serviceUnitTemplate is the systemd unit of the service, the binary expects to run as the service user with
+// its run directory created by systemd
+var serviceUnitTemplate = template.Must(template.New(serviceUnitFile).Parse(`[Unit]
+Description=SGX Verification Service
+
+[Service]
+Type=notify
+NotifyAccess=all
+User={{.User}}
+Group={{.User}}
+ExecStart={{.ExecLinkPath}} run
+ExecReload=/bin/kill -s HUP $MAINPID
+# sqvs upgrade, systemctl kill -s USR2 --kill-who=main sqvs, hands the service over to an upgraded binary without dropping requests
+TimeoutStartSec=0
+Restart=on-failure
+PermissionsStartOnly=true
+RuntimeDirectory=sqvs
+RuntimeDirectoryMode=0775
+
+[Install]
+WantedBy=multi-user.target
+`))
+
+// logRotateTemplate rotates the logs of the service, the options are read from the environment variables
+// the installer has always honored
+var logRotateTemplate = template.Must(template.New("logrotate").Parse(`{{.LogDir}}*.log {
+    missingok
+    notifempty
+    rotate {{.Old}}
+    maxsize {{.Size}}
+    nodateext
+    {{.Period}}
+    {{.Compress}}
+    {{.DelayCompress}}
+    {{.CopyTruncate}}
+}
+`))
+
+// installDir is a directory of the service, owned by the service user, and its permissions
+type installDir struct {
+	path string
+	mode os.FileMode
+}
+
+// install installs the binary and creates the service user, the directories of the service, its systemd
+// unit and its log rotation configuration. Existing unit and log rotation files are kept unless --force is
+// given, so the installation can be repeated safely.
+func (a *App) install(args []string) error {
+	fs := flag.NewFlagSet("install", flag.ContinueOnError)
+	force := fs.Bool("force", false, "overwrite the systemd unit and log rotation configuration")
+	noSystemd := fs.Bool("no-systemd", false, "do not install and enable the systemd unit")
+	err := fs.Parse(args)
+	if err != nil {
+		return errors.Wrap(err, "app:install() Invalid install arguments")
+	}
+	if os.Geteuid() != 0 {
+		return errors.New("app:install() sqvs install must be run as root")
+	}
+	w := a.consoleWriter()
+
+	fmt.Fprintln(w, "Setting up SGX Quote Verification Service Linux User...")
+	uid, gid, err := ensureServiceUser(constants.SQVSUserName)
+	if err != nil {
+		return err
+	}
+
+	binDir := filepath.Join(a.homeDir(), "bin")
+	for _, dir := range []installDir{
+		{a.homeDir(), 0700},
+		{binDir, 0700},
+		{a.logDir(), 0740},
+		{a.configDir(), 0700},
+		{filepath.Join(a.configDir(), "certs"), 0700},
+		{constants.TrustedJWTSigningCertsDir, 0700},
+		{constants.TrustedCAsStoreDir, 0700},
+	} {
+		err = os.MkdirAll(dir.path, dir.mode)
+		if err == nil {
+			err = os.Chmod(dir.path, dir.mode)
+		}
+		if err == nil {
+			err = os.Chown(dir.path, uid, gid)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "app:install() Error creating directory %s", dir.path)
+		}
+	}
+
+	fmt.Fprintln(w, "Installing SGX Quote Verification Service...")
+	binPath := filepath.Join(binDir, "sqvs")
+	err = installBinary(a.executablePath(), binPath, uid, gid)
+	if err != nil {
+		return err
+	}
+	if target, err := os.Readlink(a.execLinkPath()); err != nil || target != binPath {
+		_ = os.Remove(a.execLinkPath())
+		err = os.Symlink(binPath, a.execLinkPath())
+		if err != nil {
+			return errors.Wrap(err, "app:install() Error linking sqvs executable")
+		}
+	}
+
+	if !*noSystemd {
+		unitPath := filepath.Join(a.homeDir(), serviceUnitFile)
+		err = writeTemplate(unitPath, serviceUnitTemplate, map[string]string{"User": constants.SQVSUserName,
+			"ExecLinkPath": a.execLinkPath()}, 0644, *force)
+		if err != nil {
+			return err
+		}
+		err = os.Chown(unitPath, uid, gid)
+		if err != nil {
+			return errors.Wrap(err, "app:install() Error changing ownership of systemd unit")
+		}
+		err = enableService(unitPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = writeTemplate(logRotateConfFile, logRotateTemplate, map[string]string{
+		"LogDir":        a.logDir(),
+		"Old":           getenvDefault("LOG_OLD", "12"),
+		"Size":          getenvDefault("LOG_SIZE", "100M"),
+		"Period":        getenvDefault("LOG_ROTATION_PERIOD", "weekly"),
+		"Compress":      getenvDefault("LOG_COMPRESS", "compress"),
+		"DelayCompress": getenvDefault("LOG_DELAYCOMPRESS", "delaycompress"),
+		"CopyTruncate":  getenvDefault("LOG_COPYTRUNCATE", "copytruncate"),
+	}, 0644, *force)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "SGX Quote Verification Service installed, run \"sqvs setup all\" to configure it")
+	return nil
+}
+
+// ensureServiceUser creates the system user the service runs as, unless it exists, and returns its IDs
+func ensureServiceUser(name string) (int, int, error) {
+	serviceUser, err := user.Lookup(name)
+	if err != nil {
+		out, err := exec.Command("useradd", "--system", "--shell", "/bin/false", name).CombinedOutput()
+		if err != nil {
+			return 0, 0, errors.Wrapf(err, "app:ensureServiceUser() Error creating user %s: %s", name, out)
+		}
+		serviceUser, err = user.Lookup(name)
+		if err != nil {
+			return 0, 0, errors.Wrapf(err, "app:ensureServiceUser() Error looking up user %s", name)
+		}
+	}
+	uid, err := strconv.Atoi(serviceUser.Uid)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "app:ensureServiceUser() Could not parse %s user uid '%s'", name, serviceUser.Uid)
+	}
+	gid, err := strconv.Atoi(serviceUser.Gid)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "app:ensureServiceUser() Could not parse %s user gid '%s'", name, serviceUser.Gid)
+	}
+	return uid, gid, nil
+}
+
+// installBinary copies the running executable to the bin directory of the service, unless it runs from there
+func installBinary(src, dst string, uid, gid int) error {
+	if resolved, err := filepath.EvalSymlinks(src); err == nil {
+		src = resolved
+	}
+	if src == dst {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return errors.Wrap(err, "app:installBinary() Error opening sqvs executable")
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0700)
+	if err != nil {
+		return errors.Wrap(err, "app:installBinary() Error creating sqvs executable")
+	}
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chown(tmp, uid, gid)
+	}
+	if err == nil {
+		err = os.Rename(tmp, dst)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return errors.Wrap(err, "app:installBinary() Error installing sqvs executable")
+	}
+	return nil
+}
+
+// writeTemplate renders the template to the file, an existing file is only replaced when force is set
+func writeTemplate(path string, tmpl *template.Template, data interface{}, mode os.FileMode, force bool) error {
+	if _, err := os.Stat(path); err == nil && !force {
+		log.Infof("app:writeTemplate() Keeping existing %s", path)
+		return nil
+	}
+	var b bytes.Buffer
+	err := tmpl.Execute(&b, data)
+	if err != nil {
+		return errors.Wrapf(err, "app:writeTemplate() Error rendering %s", path)
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err == nil {
+		err = ioutil.WriteFile(path, b.Bytes(), mode)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "app:writeTemplate() Error writing %s", path)
+	}
+	return nil
+}
+
+// enableService enables the systemd unit, replacing a unit of the same name enabled earlier
+func enableService(unitPath string) error {
+	systemctl, err := exec.LookPath("systemctl")
+	if err != nil {
+		return errors.Wrap(err, "app:enableService() Could not locate systemctl to enable application service")
+	}
+	_ = exec.Command(systemctl, "disable", serviceUnitFile).Run()
+	for _, args := range [][]string{{"enable", unitPath}, {"daemon-reload"}} {
+		out, err := exec.Command(systemctl, args...).CombinedOutput()
+		if err != nil {
+			return errors.Wrapf(err, "app:enableService() systemctl %s failed: %s", args[0], out)
+		}
+	}
+	return nil
+}
+
+func getenvDefault(name, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return defaultValue
+}
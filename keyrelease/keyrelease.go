@@ -0,0 +1,105 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package keyrelease
+
+import (
+	"context"
+	"encoding/json"
+	"intel/isecl/sqvs/v4/config"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// BrokerWebhook posts the key release requests to a key broker over HTTPS
+const BrokerWebhook = "webhook"
+
+// ErrDenied is returned when the key broker refuses to release the key to the enclave
+var ErrDenied = errors.New("keyrelease: key release denied by the key broker")
+
+// Identity is the verified identity of the enclave a key is released to
+type Identity struct {
+	MrEnclave   string `json:"mrEnclave"`
+	MrSigner    string `json:"mrSigner"`
+	IsvProdID   string `json:"isvProdId"`
+	IsvSvn      string `json:"isvSvn"`
+	TcbStatus   string `json:"tcbStatus"`
+	Debug       bool   `json:"debug"`
+	Fmspc       string `json:"fmspc,omitempty"`
+	QuoteSHA256 string `json:"quoteSha256,omitempty"`
+}
+
+// Request asks the key broker to release the key KeyID to the verified enclave. UserData is the data the
+// enclave bound to its quote through the report data, typically the public key the released key is wrapped
+// with, and is only set when its hash matched the report data.
+type Request struct {
+	KeyID    string   `json:"keyId"`
+	UserData string   `json:"userData,omitempty"`
+	Identity Identity `json:"identity"`
+}
+
+// Release is the key released by the broker, returned to the enclave as is. WrappedKey is the key wrapped
+// for the enclave, Data any other material of the broker.
+type Release struct {
+	KeyID      string          `json:"keyId"`
+	WrappedKey string          `json:"wrappedKey,omitempty"`
+	Data       json.RawMessage `json:"data,omitempty"`
+}
+
+// Broker releases keys to the enclaves SQVS verified the quote of
+type Broker interface {
+	Release(ctx context.Context, req Request) (*Release, error)
+}
+
+// Factory creates the broker of the configuration, the TLS connections to the broker being verified against
+// the CA certificates of caCertsDir
+type Factory func(conf config.KeyReleaseConfig, caCertsDir string) (Broker, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]Factory)
+)
+
+func init() {
+	RegisterBroker(BrokerWebhook, newWebhookBroker)
+}
+
+// RegisterBroker makes a key broker plugin available by name, plugins register themselves when their package
+// is imported
+func RegisterBroker(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if _, ok := factories[name]; ok {
+		panic("keyrelease: RegisterBroker called twice for broker " + name)
+	}
+	factories[name] = factory
+}
+
+// Brokers returns the names of the registered key brokers
+func Brokers() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open creates the key broker of the configuration, it returns nil when key release is disabled
+func Open(conf config.KeyReleaseConfig, caCertsDir string) (Broker, error) {
+	if conf.Broker == "" {
+		return nil, nil
+	}
+	factoriesMu.RLock()
+	factory, ok := factories[conf.Broker]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, errors.Errorf("keyrelease/keyrelease:Open() Unknown key broker %s", conf.Broker)
+	}
+	return factory(conf, caCertsDir)
+}
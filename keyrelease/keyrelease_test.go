@@ -0,0 +1,58 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package keyrelease
+
+import (
+	"context"
+	"encoding/json"
+	"intel/isecl/sqvs/v4/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookBroker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		switch req.Identity.MrEnclave {
+		case "trusted":
+			_, _ = w.Write([]byte(`{"wrappedKey":"AAEC","data":{"kid":"` + req.KeyID + `"}}`))
+		case "untrusted":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	broker := &WebhookBroker{URL: server.URL, Client: server.Client()}
+
+	release, err := broker.Release(context.Background(), Request{KeyID: "k1", Identity: Identity{MrEnclave: "trusted"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "k1", release.KeyID)
+	assert.Equal(t, "AAEC", release.WrappedKey)
+	assert.JSONEq(t, `{"kid":"k1"}`, string(release.Data))
+
+	_, err = broker.Release(context.Background(), Request{KeyID: "k1", Identity: Identity{MrEnclave: "untrusted"}})
+	assert.Equal(t, ErrDenied, err)
+
+	_, err = broker.Release(context.Background(), Request{KeyID: "k1"})
+	assert.Error(t, err)
+	assert.NotEqual(t, ErrDenied, err)
+}
+
+func TestOpen(t *testing.T) {
+	broker, err := Open(config.KeyReleaseConfig{}, "")
+	assert.NoError(t, err)
+	assert.Nil(t, broker)
+
+	_, err = Open(config.KeyReleaseConfig{Broker: "kmip"}, "")
+	assert.Error(t, err)
+	_, err = Open(config.KeyReleaseConfig{Broker: BrokerWebhook}, "")
+	assert.Error(t, err)
+	assert.Equal(t, []string{BrokerWebhook}, Brokers())
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package keyrelease
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	commLog "intel/isecl/lib/common/v4/log"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/truststore"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+var log = commLog.GetDefaultLogger()
+
+// maxReleaseSize bounds the response of the key broker
+const maxReleaseSize = 1 << 20
+
+// WebhookBroker posts every key release request as JSON to the key broker URL, a KBS or a KMIP gateway. The
+// broker answers 2xx with the Release, 403 when it refuses to release the key to the enclave.
+type WebhookBroker struct {
+	URL    string
+	Client *http.Client
+}
+
+func newWebhookBroker(conf config.KeyReleaseConfig, caCertsDir string) (Broker, error) {
+	if conf.URL == "" {
+		return nil, errors.New("keyrelease/webhook:newWebhookBroker() Key broker URL must be set")
+	}
+	client, err := truststore.HTTPClient(caCertsDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "keyrelease/webhook:newWebhookBroker() Error in getting client object")
+	}
+	client.Timeout = conf.Timeout
+	if client.Timeout <= 0 {
+		client.Timeout = constants.DefaultKeyReleaseTimeout
+	}
+	return &WebhookBroker{URL: conf.URL, Client: client}, nil
+}
+
+func (b *WebhookBroker) Release(ctx context.Context, req Request) (*Release, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "keyrelease/webhook:Release() Error marshalling key release request")
+	}
+	httpReq, err := http.NewRequest(http.MethodPost, b.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "keyrelease/webhook:Release() Failed to create key release request")
+	}
+	httpReq = httpReq.WithContext(ctx)
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+
+	resp, err := b.Client.Do(httpReq)
+	if err != nil {
+		return nil, errors.Wrap(err, "keyrelease/webhook:Release() Failed to reach the key broker")
+	}
+	defer func() {
+		derr := resp.Body.Close()
+		if derr != nil {
+			log.WithError(derr).Error("Error closing key broker response")
+		}
+	}()
+
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxReleaseSize))
+	if err != nil {
+		return nil, errors.Wrap(err, "keyrelease/webhook:Release() Error reading key broker response")
+	}
+	if resp.StatusCode == http.StatusForbidden {
+		return nil, ErrDenied
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, errors.Errorf("keyrelease/webhook:Release() Invalid status code received from key broker: %d",
+			resp.StatusCode)
+	}
+	var release Release
+	err = json.Unmarshal(content, &release)
+	if err != nil {
+		return nil, errors.Wrap(err, "keyrelease/webhook:Release() Invalid key broker response")
+	}
+	if release.KeyID == "" {
+		release.KeyID = req.KeyID
+	}
+	return &release, nil
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package keystore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"intel/isecl/sqvs/v4/config"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// aesGCMPrefix marks the ciphertexts of the operator key, Vault transit ciphertexts starting with "vault:"
+	aesGCMPrefix    = "aes256gcm:"
+	vaultPrefix     = "vault:"
+	aesGCMKeyLength = 32
+
+	defaultTransitMount = "transit"
+)
+
+// Encrypter encrypts data at rest. The associated data binds a ciphertext to the record it is stored with,
+// decryption failing for any other record.
+type Encrypter interface {
+	Encrypt(plaintext, associatedData []byte) (string, error)
+	Decrypt(ciphertext string, associatedData []byte) ([]byte, error)
+}
+
+// NewEncrypter returns the encrypter of the raw quotes retained with the verification history, AES-256-GCM
+// with the operator key of KeyFile or the Vault transit key VaultTransitKey
+func NewEncrypter(conf config.RawQuoteConfig, keyStore config.KeyStoreConfig, caCertsDir string) (Encrypter, error) {
+	switch {
+	case conf.KeyFile != "" && conf.VaultTransitKey != "":
+		return nil, errors.New("keystore/encryption:NewEncrypter() Only one of the key file and the Vault transit " +
+			"key must be configured")
+	case conf.KeyFile != "":
+		return NewAESEncrypter(conf.KeyFile)
+	case conf.VaultTransitKey != "":
+		token, err := readSecretFile(keyStore.VaultTokenFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "keystore/encryption:NewEncrypter() Error reading Vault token")
+		}
+		mount := conf.VaultTransitMount
+		if mount == "" {
+			mount = defaultTransitMount
+		}
+		store, err := NewVaultKeyStore(true, keyStore.VaultAddress, mount, token, caCertsDir)
+		if err != nil {
+			return nil, err
+		}
+		return &transitEncrypter{store: store, name: conf.VaultTransitKey}, nil
+	}
+	return nil, errors.New("keystore/encryption:NewEncrypter() No encryption key is configured")
+}
+
+// AESEncrypter encrypts with AES-256-GCM under an operator provided key
+type AESEncrypter struct {
+	aead cipher.AEAD
+}
+
+// NewAESEncrypter loads the base64 encoded 256-bit key of the file
+func NewAESEncrypter(keyFile string) (*AESEncrypter, error) {
+	encoded, err := readSecretFile(keyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "keystore/encryption:NewAESEncrypter() Error reading encryption key")
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != aesGCMKeyLength {
+		return nil, errors.New("keystore/encryption:NewAESEncrypter() Encryption key must be 32 base64 encoded bytes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "keystore/encryption:NewAESEncrypter() Error creating cipher")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "keystore/encryption:NewAESEncrypter() Error creating cipher")
+	}
+	return &AESEncrypter{aead: aead}, nil
+}
+
+func (e *AESEncrypter) Encrypt(plaintext, associatedData []byte) (string, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", errors.Wrap(err, "keystore/encryption:Encrypt() Error generating nonce")
+	}
+	sealed := e.aead.Seal(nonce, nonce, plaintext, associatedData)
+	return aesGCMPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (e *AESEncrypter) Decrypt(ciphertext string, associatedData []byte) ([]byte, error) {
+	if !strings.HasPrefix(ciphertext, aesGCMPrefix) {
+		return nil, errors.New("keystore/encryption:Decrypt() Not encrypted with the operator key")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(ciphertext, aesGCMPrefix))
+	if err != nil || len(sealed) < e.aead.NonceSize() {
+		return nil, errors.New("keystore/encryption:Decrypt() Invalid ciphertext")
+	}
+	nonceSize := e.aead.NonceSize()
+	plaintext, err := e.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], associatedData)
+	if err != nil {
+		return nil, errors.Wrap(err, "keystore/encryption:Decrypt() Error decrypting")
+	}
+	return plaintext, nil
+}
+
+// transitEncrypter encrypts with a Vault transit key, the key never leaving Vault. Vault only binds
+// ciphertexts to associated data with some key types, so the associated data is sealed along with the
+// plaintext, prefixed with its length, and checked on decryption.
+type transitEncrypter struct {
+	store *VaultKeyStore
+	name  string
+}
+
+func (t *transitEncrypter) Encrypt(plaintext, associatedData []byte) (string, error) {
+	var resp struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	bound := make([]byte, 4, 4+len(associatedData)+len(plaintext))
+	binary.BigEndian.PutUint32(bound, uint32(len(associatedData)))
+	bound = append(append(bound, associatedData...), plaintext...)
+	req := map[string]string{"plaintext": base64.StdEncoding.EncodeToString(bound)}
+	err := t.store.do(http.MethodPost, "/v1/"+t.store.Mount+"/encrypt/"+t.name, req, &resp)
+	if err != nil {
+		return "", errors.Wrap(err, "keystore/encryption:Encrypt() Error encrypting with Vault transit key")
+	}
+	if !strings.HasPrefix(resp.Data.Ciphertext, vaultPrefix) {
+		return "", errors.New("keystore/encryption:Encrypt() Invalid ciphertext received from Vault")
+	}
+	return resp.Data.Ciphertext, nil
+}
+
+func (t *transitEncrypter) Decrypt(ciphertext string, associatedData []byte) ([]byte, error) {
+	if !strings.HasPrefix(ciphertext, vaultPrefix) {
+		return nil, errors.New("keystore/encryption:Decrypt() Not encrypted with the Vault transit key")
+	}
+	var resp struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	err := t.store.do(http.MethodPost, "/v1/"+t.store.Mount+"/decrypt/"+t.name, map[string]string{
+		"ciphertext": ciphertext}, &resp)
+	if err != nil {
+		return nil, errors.Wrap(err, "keystore/encryption:Decrypt() Error decrypting with Vault transit key")
+	}
+	bound, err := base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+	if err != nil || len(bound) < 4 {
+		return nil, errors.New("keystore/encryption:Decrypt() Invalid plaintext received from Vault")
+	}
+	length := uint64(binary.BigEndian.Uint32(bound))
+	if uint64(len(bound)-4) < length || !bytes.Equal(bound[4:4+length], associatedData) {
+		return nil, errors.New("keystore/encryption:Decrypt() Ciphertext is not bound to the associated data")
+	}
+	return bound[4+length:], nil
+}
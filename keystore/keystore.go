@@ -0,0 +1,120 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package keystore
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	commLog "intel/isecl/lib/common/v4/log"
+	"intel/isecl/sqvs/v4/config"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var log = commLog.GetDefaultLogger()
+
+const (
+	TypeFile         = "file"
+	TypeVaultKV      = "vault-kv"
+	TypeVaultTransit = "vault-transit"
+	TypePKCS11       = "pkcs11"
+)
+
+// KeyStore loads the private keys SQVS uses for TLS and for signing quote verification responses
+type KeyStore interface {
+	// Signer returns a signer backed by the private key identified by keyID
+	Signer(keyID string) (crypto.Signer, error)
+	Close() error
+}
+
+// IsValidType reports whether keyStoreType names a supported key store backend
+func IsValidType(keyStoreType string) bool {
+	switch keyStoreType {
+	case TypeFile, TypeVaultKV, TypeVaultTransit, TypePKCS11:
+		return true
+	}
+	return false
+}
+
+// New creates the key store backend selected in the configuration, defaulting to keys stored on disk
+func New(conf config.KeyStoreConfig, caCertsDir string) (KeyStore, error) {
+	switch conf.Type {
+	case "", TypeFile:
+		return FileKeyStore{}, nil
+	case TypeVaultKV, TypeVaultTransit:
+		token, err := readSecretFile(conf.VaultTokenFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "keystore/keystore:New() Error reading Vault token")
+		}
+		return NewVaultKeyStore(conf.Type == TypeVaultTransit, conf.VaultAddress, conf.VaultMount, token, caCertsDir)
+	case TypePKCS11:
+		pin, err := readSecretFile(conf.PKCS11PinFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "keystore/keystore:New() Error reading PKCS#11 PIN")
+		}
+		return NewPKCS11KeyStore(conf.PKCS11Module, conf.PKCS11TokenLabel, pin)
+	}
+	return nil, errors.Errorf("keystore/keystore:New() Unsupported key store type %s", conf.Type)
+}
+
+// FileKeyStore loads PEM encoded private keys from the local file system
+type FileKeyStore struct{}
+
+func (FileKeyStore) Signer(keyFile string) (crypto.Signer, error) {
+	log.Trace("keystore/keystore:Signer() Entering")
+	defer log.Trace("keystore/keystore:Signer() Leaving")
+
+	keyPem, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "keystore/keystore:Signer() Error reading private key from file")
+	}
+	return parsePrivateKeyPEM(keyPem)
+}
+
+func (FileKeyStore) Close() error {
+	return nil
+}
+
+// parsePrivateKeyPEM parses a PKCS#8, PKCS#1 or SEC 1 encoded private key
+func parsePrivateKeyPEM(keyPem []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(keyPem)
+	if block == nil {
+		return nil, errors.New("keystore/keystore:parsePrivateKeyPEM() Failed to decode private key PEM")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "keystore/keystore:parsePrivateKeyPEM() Cannot parse private key")
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("keystore/keystore:parsePrivateKeyPEM() Unsupported private key type")
+	}
+	return signer, nil
+}
+
+func readSecretFile(secretFile string) (string, error) {
+	if secretFile == "" {
+		return "", errors.New("secret file is not configured")
+	}
+	secret, err := ioutil.ReadFile(secretFile)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(secret)), nil
+}
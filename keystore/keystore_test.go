@@ -0,0 +1,137 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package keystore
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testKeyPem(t *testing.T) (*rsa.PrivateKey, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	return key, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestFileKeyStoreSigner(t *testing.T) {
+	key, keyPem := testKeyPem(t)
+	dir, err := ioutil.TempDir("", "keystore")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "key.pem")
+	assert.NoError(t, ioutil.WriteFile(keyFile, keyPem, 0600))
+
+	signer, err := FileKeyStore{}.Signer(keyFile)
+	assert.NoError(t, err)
+	assert.Equal(t, &key.PublicKey, signer.Public())
+
+	_, err = FileKeyStore{}.Signer(filepath.Join(dir, "missing.pem"))
+	assert.Error(t, err)
+}
+
+func TestVaultKVKeyStoreSigner(t *testing.T) {
+	key, keyPem := testKeyPem(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" || r.URL.Path != "/v1/secret/data/sqvs/signing" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"data": map[string]string{vaultKVKeyField: string(keyPem)},
+			},
+		})
+	}))
+	defer server.Close()
+
+	ks := &VaultKeyStore{Address: server.URL, Mount: "secret", Client: server.Client(), token: "token"}
+	signer, err := ks.Signer("sqvs/signing")
+	assert.NoError(t, err)
+	assert.Equal(t, &key.PublicKey, signer.Public())
+
+	digest := sha512.Sum384([]byte("response"))
+	signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA384)
+	assert.NoError(t, err)
+	assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA384, digest[:], signature))
+
+	_, err = ks.Signer("sqvs/other")
+	assert.Error(t, err)
+}
+
+func TestAESEncrypter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqvs-keystore")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "raw-quote.key")
+	assert.NoError(t, ioutil.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(make([]byte, 32))), 0600))
+
+	encrypter, err := NewAESEncrypter(keyFile)
+	assert.NoError(t, err)
+	ciphertext, err := encrypter.Encrypt([]byte("quote"), []byte("record-1"))
+	assert.NoError(t, err)
+	plaintext, err := encrypter.Decrypt(ciphertext, []byte("record-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "quote", string(plaintext))
+
+	// ciphertexts are bound to their record
+	_, err = encrypter.Decrypt(ciphertext, []byte("record-2"))
+	assert.Error(t, err)
+
+	assert.NoError(t, ioutil.WriteFile(keyFile, []byte("c2hvcnQ="), 0600))
+	_, err = NewAESEncrypter(keyFile)
+	assert.Error(t, err)
+}
+
+func TestTransitEncrypter(t *testing.T) {
+	// the fake transit engine "encrypts" by encoding the plaintext again
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/raw-quotes":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]string{"ciphertext": vaultPrefix + "v1:" + req["plaintext"]},
+			})
+		case "/v1/transit/decrypt/raw-quotes":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]string{"plaintext": strings.TrimPrefix(req["ciphertext"], vaultPrefix+"v1:")},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	encrypter := &transitEncrypter{store: &VaultKeyStore{Address: server.URL, Mount: "transit",
+		Client: server.Client(), token: "token"}, name: "raw-quotes"}
+	ciphertext, err := encrypter.Encrypt([]byte("quote"), []byte("record-1"))
+	assert.NoError(t, err)
+	plaintext, err := encrypter.Decrypt(ciphertext, []byte("record-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "quote", string(plaintext))
+
+	// a ciphertext moved to another record does not decrypt
+	_, err = encrypter.Decrypt(ciphertext, []byte("record-2"))
+	assert.Error(t, err)
+	_, err = encrypter.Decrypt(ciphertext, []byte("record-10"))
+	assert.Error(t, err)
+}
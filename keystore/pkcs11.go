@@ -0,0 +1,196 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package keystore
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"io"
+	"math/big"
+	"sync"
+
+	"github.com/miekg/pkcs11"
+	"github.com/pkg/errors"
+)
+
+// digestInfoPrefixes are the DER encoded DigestInfo headers prepended to digests for CKM_RSA_PKCS signatures
+var digestInfoPrefixes = map[crypto.Hash][]byte{
+	crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+	crypto.SHA384: {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+	crypto.SHA512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+}
+
+// pssMechanisms maps hash functions to the PKCS#11 hash and MGF1 mechanisms used for RSA-PSS
+var pssMechanisms = map[crypto.Hash][2]uint{
+	crypto.SHA256: {pkcs11.CKM_SHA256, pkcs11.CKG_MGF1_SHA256},
+	crypto.SHA384: {pkcs11.CKM_SHA384, pkcs11.CKG_MGF1_SHA384},
+	crypto.SHA512: {pkcs11.CKM_SHA512, pkcs11.CKG_MGF1_SHA512},
+}
+
+// PKCS11KeyStore uses RSA keys held in a PKCS#11 token, keys are looked up by their CKA_LABEL.
+// A single logged in session is shared and serialized between signers.
+type PKCS11KeyStore struct {
+	mu      sync.Mutex
+	ctx     *pkcs11.Ctx
+	session pkcs11.SessionHandle
+}
+
+func NewPKCS11KeyStore(module, tokenLabel, pin string) (*PKCS11KeyStore, error) {
+	ctx := pkcs11.New(module)
+	if ctx == nil {
+		return nil, errors.Errorf("keystore/pkcs11:NewPKCS11KeyStore() Failed to load PKCS#11 module %s", module)
+	}
+	err := ctx.Initialize()
+	if err != nil {
+		ctx.Destroy()
+		return nil, errors.Wrap(err, "keystore/pkcs11:NewPKCS11KeyStore() Failed to initialize PKCS#11 module")
+	}
+
+	session, err := openSession(ctx, tokenLabel, pin)
+	if err != nil {
+		_ = ctx.Finalize()
+		ctx.Destroy()
+		return nil, err
+	}
+	return &PKCS11KeyStore{ctx: ctx, session: session}, nil
+}
+
+func openSession(ctx *pkcs11.Ctx, tokenLabel, pin string) (pkcs11.SessionHandle, error) {
+	slots, err := ctx.GetSlotList(true)
+	if err != nil {
+		return 0, errors.Wrap(err, "keystore/pkcs11:openSession() Failed to list PKCS#11 slots")
+	}
+	for _, slot := range slots {
+		info, err := ctx.GetTokenInfo(slot)
+		if err != nil || info.Label != tokenLabel {
+			continue
+		}
+		session, err := ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION)
+		if err != nil {
+			return 0, errors.Wrap(err, "keystore/pkcs11:openSession() Failed to open PKCS#11 session")
+		}
+		err = ctx.Login(session, pkcs11.CKU_USER, pin)
+		if err != nil {
+			_ = ctx.CloseSession(session)
+			return 0, errors.Wrap(err, "keystore/pkcs11:openSession() Failed to log in to PKCS#11 token")
+		}
+		return session, nil
+	}
+	return 0, errors.Errorf("keystore/pkcs11:openSession() PKCS#11 token %s not found", tokenLabel)
+}
+
+func (p *PKCS11KeyStore) Signer(label string) (crypto.Signer, error) {
+	log.Trace("keystore/pkcs11:Signer() Entering")
+	defer log.Trace("keystore/pkcs11:Signer() Leaving")
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	privKey, err := p.findObject(pkcs11.CKO_PRIVATE_KEY, label)
+	if err != nil {
+		return nil, err
+	}
+	pubKey, err := p.findObject(pkcs11.CKO_PUBLIC_KEY, label)
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := p.ctx.GetAttributeValue(p.session, pubKey, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_MODULUS, nil),
+		pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, nil),
+	})
+	if err != nil || len(attrs) != 2 {
+		return nil, errors.Errorf("keystore/pkcs11:Signer() Key %s is not an RSA key", label)
+	}
+
+	pub := &rsa.PublicKey{
+		N: new(big.Int).SetBytes(attrs[0].Value),
+		E: int(new(big.Int).SetBytes(attrs[1].Value).Int64()),
+	}
+	return &pkcs11Signer{store: p, key: privKey, pub: pub}, nil
+}
+
+func (p *PKCS11KeyStore) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_ = p.ctx.Logout(p.session)
+	_ = p.ctx.CloseSession(p.session)
+	err := p.ctx.Finalize()
+	p.ctx.Destroy()
+	return err
+}
+
+func (p *PKCS11KeyStore) findObject(class uint, label string) (pkcs11.ObjectHandle, error) {
+	err := p.ctx.FindObjectsInit(p.session, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "keystore/pkcs11:findObject() Failed to search PKCS#11 objects")
+	}
+	objects, _, err := p.ctx.FindObjects(p.session, 1)
+	ferr := p.ctx.FindObjectsFinal(p.session)
+	if err != nil {
+		return 0, errors.Wrap(err, "keystore/pkcs11:findObject() Failed to search PKCS#11 objects")
+	}
+	if ferr != nil {
+		return 0, errors.Wrap(ferr, "keystore/pkcs11:findObject() Failed to search PKCS#11 objects")
+	}
+	if len(objects) == 0 {
+		return 0, errors.Errorf("keystore/pkcs11:findObject() PKCS#11 key %s not found", label)
+	}
+	return objects[0], nil
+}
+
+// pkcs11Signer signs digests with an RSA private key held in a PKCS#11 token
+type pkcs11Signer struct {
+	store *PKCS11KeyStore
+	key   pkcs11.ObjectHandle
+	pub   *rsa.PublicKey
+}
+
+func (s *pkcs11Signer) Public() crypto.PublicKey {
+	return s.pub
+}
+
+func (s *pkcs11Signer) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	var mechanism *pkcs11.Mechanism
+	var input []byte
+	if pssOpts, ok := opts.(*rsa.PSSOptions); ok {
+		mechs, ok := pssMechanisms[opts.HashFunc()]
+		if !ok {
+			return nil, errors.Errorf("keystore/pkcs11:Sign() Unsupported hash function %v", opts.HashFunc())
+		}
+		saltLength := pssOpts.SaltLength
+		switch saltLength {
+		case rsa.PSSSaltLengthAuto:
+			saltLength = (s.pub.N.BitLen()-1+7)/8 - 2 - opts.HashFunc().Size()
+		case rsa.PSSSaltLengthEqualsHash:
+			saltLength = opts.HashFunc().Size()
+		}
+		mechanism = pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS_PSS, pkcs11.NewPSSParams(mechs[0], mechs[1], uint(saltLength)))
+		input = digest
+	} else {
+		prefix, ok := digestInfoPrefixes[opts.HashFunc()]
+		if !ok {
+			return nil, errors.Errorf("keystore/pkcs11:Sign() Unsupported hash function %v", opts.HashFunc())
+		}
+		mechanism = pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS, nil)
+		input = append(append([]byte{}, prefix...), digest...)
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	err := s.store.ctx.SignInit(s.store.session, []*pkcs11.Mechanism{mechanism}, s.key)
+	if err != nil {
+		return nil, errors.Wrap(err, "keystore/pkcs11:Sign() Failed to initialize PKCS#11 signing")
+	}
+	signature, err := s.store.ctx.Sign(s.store.session, input)
+	if err != nil {
+		return nil, errors.Wrap(err, "keystore/pkcs11:Sign() Failed to sign with PKCS#11 key")
+	}
+	return signature, nil
+}
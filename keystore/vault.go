@@ -0,0 +1,222 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package keystore
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"intel/isecl/sqvs/v4/truststore"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// vaultKVKeyField is the field of a Vault KV secret holding the PEM encoded private key
+const vaultKVKeyField = "private_key"
+
+// VaultKeyStore loads keys from HashiCorp Vault. With the KV v2 secrets engine, the PEM encoded private
+// key is read from the secret at the key ID path. With the transit secrets engine, the private key never
+// leaves Vault and signing is delegated to the named transit key.
+type VaultKeyStore struct {
+	Transit bool
+	Address string
+	Mount   string
+	Client  *http.Client
+
+	token   string
+	signers sync.Map
+}
+
+func NewVaultKeyStore(transit bool, address, mount, token, caCertsDir string) (*VaultKeyStore, error) {
+	if address == "" || mount == "" {
+		return nil, errors.New("keystore/vault:NewVaultKeyStore() Vault address and mount must be configured")
+	}
+	client, err := truststore.HTTPClient(caCertsDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "keystore/vault:NewVaultKeyStore() Error in getting client object")
+	}
+	return &VaultKeyStore{
+		Transit: transit,
+		Address: strings.TrimSuffix(address, "/"),
+		Mount:   strings.Trim(mount, "/"),
+		Client:  client,
+		token:   token,
+	}, nil
+}
+
+func (v *VaultKeyStore) Signer(keyID string) (crypto.Signer, error) {
+	log.Trace("keystore/vault:Signer() Entering")
+	defer log.Trace("keystore/vault:Signer() Leaving")
+
+	if signer, ok := v.signers.Load(keyID); ok {
+		return signer.(crypto.Signer), nil
+	}
+
+	var signer crypto.Signer
+	var err error
+	if v.Transit {
+		signer, err = v.transitSigner(keyID)
+	} else {
+		signer, err = v.kvSigner(keyID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	v.signers.Store(keyID, signer)
+	return signer, nil
+}
+
+func (v *VaultKeyStore) Close() error {
+	return nil
+}
+
+func (v *VaultKeyStore) kvSigner(keyPath string) (crypto.Signer, error) {
+	var secret struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	err := v.do(http.MethodGet, "/v1/"+v.Mount+"/data/"+strings.Trim(keyPath, "/"), nil, &secret)
+	if err != nil {
+		return nil, errors.Wrap(err, "keystore/vault:kvSigner() Error reading key from Vault")
+	}
+	keyPem, ok := secret.Data.Data[vaultKVKeyField]
+	if !ok {
+		return nil, errors.Errorf("keystore/vault:kvSigner() Secret %s does not have a %s field", keyPath, vaultKVKeyField)
+	}
+	return parsePrivateKeyPEM([]byte(keyPem))
+}
+
+func (v *VaultKeyStore) transitSigner(keyName string) (crypto.Signer, error) {
+	var key struct {
+		Data struct {
+			LatestVersion int `json:"latest_version"`
+			Keys          map[string]struct {
+				PublicKey string `json:"public_key"`
+			} `json:"keys"`
+		} `json:"data"`
+	}
+	err := v.do(http.MethodGet, "/v1/"+v.Mount+"/keys/"+keyName, nil, &key)
+	if err != nil {
+		return nil, errors.Wrap(err, "keystore/vault:transitSigner() Error reading transit key from Vault")
+	}
+
+	latest, ok := key.Data.Keys[strconv.Itoa(key.Data.LatestVersion)]
+	if !ok {
+		return nil, errors.Errorf("keystore/vault:transitSigner() Transit key %s has no public key", keyName)
+	}
+	block, _ := pem.Decode([]byte(latest.PublicKey))
+	if block == nil {
+		return nil, errors.Errorf("keystore/vault:transitSigner() Transit key %s is not an asymmetric key", keyName)
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "keystore/vault:transitSigner() Cannot parse transit public key")
+	}
+	return &transitSigner{store: v, name: keyName, pub: pub}, nil
+}
+
+// do sends an authenticated request to Vault and decodes the JSON response into out
+func (v *VaultKeyStore) do(method, path string, body interface{}, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, v.Address+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		derr := resp.Body.Close()
+		if derr != nil {
+			log.WithError(derr).Error("Error closing Vault response")
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status code %d received from Vault", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// transitSigner signs digests with a Vault transit key
+type transitSigner struct {
+	store *VaultKeyStore
+	name  string
+	pub   crypto.PublicKey
+}
+
+func (t *transitSigner) Public() crypto.PublicKey {
+	return t.pub
+}
+
+func (t *transitSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	var hashAlgorithm string
+	switch opts.HashFunc() {
+	case crypto.SHA256:
+		hashAlgorithm = "sha2-256"
+	case crypto.SHA384:
+		hashAlgorithm = "sha2-384"
+	case crypto.SHA512:
+		hashAlgorithm = "sha2-512"
+	default:
+		return nil, errors.Errorf("keystore/vault:Sign() Unsupported hash function %v", opts.HashFunc())
+	}
+
+	req := map[string]interface{}{
+		"input":                base64.StdEncoding.EncodeToString(digest),
+		"prehashed":            true,
+		"marshaling_algorithm": "asn1",
+	}
+	if _, ok := t.pub.(*rsa.PublicKey); ok {
+		req["signature_algorithm"] = "pkcs1v15"
+		if pssOpts, ok := opts.(*rsa.PSSOptions); ok {
+			req["signature_algorithm"] = "pss"
+			if pssOpts.SaltLength == rsa.PSSSaltLengthEqualsHash {
+				req["salt_length"] = "hash"
+			}
+		}
+	}
+
+	var resp struct {
+		Data struct {
+			Signature string `json:"signature"`
+		} `json:"data"`
+	}
+	err := t.store.do(http.MethodPost, "/v1/"+t.store.Mount+"/sign/"+t.name+"/"+hashAlgorithm, req, &resp)
+	if err != nil {
+		return nil, errors.Wrap(err, "keystore/vault:Sign() Error signing with Vault transit key")
+	}
+
+	// Vault prefixes signatures with "vault:v<key version>:"
+	parts := strings.SplitN(resp.Data.Signature, ":", 3)
+	if len(parts) != 3 {
+		return nil, errors.New("keystore/vault:Sign() Invalid signature format received from Vault")
+	}
+	return base64.StdEncoding.DecodeString(parts[2])
+}
@@ -0,0 +1,423 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package logformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	FormatText = "text"
+	FormatCEF  = "cef"
+	FormatLEEF = "leef"
+	FormatJSON = "json"
+
+	// EventField is the log entry field naming the kind of security event, EventGeneric when missing
+	EventField = "event"
+	// OutcomeField is the log entry field with the outcome of the event, OutcomeSuccess or OutcomeFailure
+	OutcomeField = "outcome"
+
+	EventGeneric      = "log"
+	EventVerification = "verification"
+	EventAuth         = "auth"
+	EventAnomaly      = "anomaly"
+	EventEgress       = "egress"
+	EventPolicy       = "policy"
+	EventAdmin        = "admin"
+	EventConfig       = "config"
+
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+
+	vendor  = "Intel"
+	product = "SQVS"
+)
+
+// Formats lists the supported log formats
+var Formats = []string{FormatText, FormatJSON, FormatCEF, FormatLEEF}
+
+// Events lists the categories of security events
+var Events = []string{EventGeneric, EventVerification, EventAuth, EventAnomaly, EventEgress, EventPolicy, EventAdmin,
+	EventConfig}
+
+// New returns the formatter of the log format, text records being formatted by text
+func New(format string, text logrus.Formatter, version string) (logrus.Formatter, error) {
+	switch format {
+	case "", FormatText:
+		return text, nil
+	case FormatCEF:
+		return &CEFFormatter{Version: version}, nil
+	case FormatLEEF:
+		return &LEEFFormatter{Version: version}, nil
+	case FormatJSON:
+		return &JSONFormatter{Version: version}, nil
+	}
+	return nil, errors.Errorf("logformat/logformat:New() Unsupported log format %s, must be one of %s", format,
+		strings.Join(Formats, ", "))
+}
+
+// CEFFormatter formats log entries as ArcSight Common Event Format records
+type CEFFormatter struct {
+	Version string
+}
+
+func (f *CEFFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	var b bytes.Buffer
+	event := eventName(e)
+	fmt.Fprintf(&b, "CEF:0|%s|%s|%s|%s|%s|%d|", cefHeader(vendor), cefHeader(product), cefHeader(f.Version),
+		cefHeader(event), cefHeader(firstLine(e.Message)), severity(e.Level))
+
+	ext := []string{
+		"rt=" + fmt.Sprint(e.Time.UnixNano()/int64(1e6)),
+		"cat=" + cefValue(event),
+		"msg=" + cefValue(e.Message),
+	}
+	if outcome, ok := e.Data[OutcomeField]; ok {
+		ext = append(ext, "outcome="+cefValue(fmt.Sprint(outcome)))
+	}
+	for i, key := range dataKeys(e) {
+		// custom strings carry the remaining fields, cs1 to cs6 being the only ones CEF defines
+		if i == 6 {
+			break
+		}
+		ext = append(ext, fmt.Sprintf("cs%dLabel=%s cs%d=%s", i+1, cefValue(key), i+1, cefValue(fmt.Sprint(e.Data[key]))))
+	}
+	b.WriteString(strings.Join(ext, " "))
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+// LEEFFormatter formats log entries as QRadar Log Event Extended Format 2.0 records, attributes being
+// separated by tabs
+type LEEFFormatter struct {
+	Version string
+}
+
+func (f *LEEFFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	var b bytes.Buffer
+	event := eventName(e)
+	fmt.Fprintf(&b, "LEEF:2.0|%s|%s|%s|%s|\t|", leefValue(vendor), leefValue(product), leefValue(f.Version),
+		leefValue(event))
+
+	attrs := []string{
+		"devTime=" + e.Time.UTC().Format("Jan 02 2006 15:04:05.000 UTC"),
+		"devTimeFormat=MMM dd yyyy HH:mm:ss.SSS z",
+		fmt.Sprintf("sev=%d", severity(e.Level)),
+		"cat=" + leefValue(event),
+		"msg=" + leefValue(e.Message),
+	}
+	if outcome, ok := e.Data[OutcomeField]; ok {
+		attrs = append(attrs, "outcome="+leefValue(fmt.Sprint(outcome)))
+	}
+	for _, key := range dataKeys(e) {
+		attrs = append(attrs, leefValue(key)+"="+leefValue(fmt.Sprint(e.Data[key])))
+	}
+	b.WriteString(strings.Join(attrs, "\t"))
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+// JSONFormatter formats log entries as single line JSON objects for log pipelines. The names of the top
+// level fields are stable, the fields of the entry other than its event and outcome are nested under
+// "fields" so they never clash with them.
+type JSONFormatter struct {
+	Version string
+}
+
+type jsonRecord struct {
+	Time    string                 `json:"time"`
+	Level   string                 `json:"level"`
+	Service string                 `json:"service"`
+	Version string                 `json:"version"`
+	Event   string                 `json:"event"`
+	Outcome string                 `json:"outcome,omitempty"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+func (f *JSONFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	record := jsonRecord{
+		Time:    e.Time.UTC().Format(time.RFC3339Nano),
+		Level:   e.Level.String(),
+		Service: product,
+		Version: f.Version,
+		Event:   eventName(e),
+		Message: e.Message,
+	}
+	if outcome, ok := e.Data[OutcomeField]; ok {
+		record.Outcome = fmt.Sprint(outcome)
+	}
+	if keys := dataKeys(e); len(keys) > 0 {
+		record.Fields = make(map[string]interface{}, len(keys))
+		for _, key := range keys {
+			record.Fields[key] = jsonValue(e.Data[key])
+		}
+	}
+	b, err := json.Marshal(record)
+	if err != nil {
+		return nil, errors.Wrap(err, "logformat/logformat:Format() Error marshalling log record")
+	}
+	return append(b, '\n'), nil
+}
+
+// jsonValue returns the value of a field as it is marshalled, errors and values JSON cannot represent as
+// their text
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	if _, err := json.Marshal(value); err != nil {
+		return fmt.Sprint(value)
+	}
+	return value
+}
+
+// Sink is an output the records of a logger are written to in its own format, only those its filter allows
+// when it has one
+type Sink struct {
+	Writer    io.Writer
+	Formatter logrus.Formatter
+	Filter    *Filter
+}
+
+// Filter selects the records of a sink: those of the event categories of Events, of every category when
+// empty, with the Outcome, any outcome when empty, logged at Level or a more severe level
+type Filter struct {
+	Events  []string
+	Outcome string
+	Level   logrus.Level
+}
+
+// Allows tells whether the filter selects the entry
+func (f *Filter) Allows(e *logrus.Entry) bool {
+	if f == nil {
+		return true
+	}
+	if e.Level > f.Level {
+		return false
+	}
+	if f.Outcome != "" {
+		if outcome, _ := e.Data[OutcomeField].(string); outcome != f.Outcome {
+			return false
+		}
+	}
+	if len(f.Events) == 0 {
+		return true
+	}
+	event := eventName(e)
+	for _, allowed := range f.Events {
+		if event == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// SinkSpec is a sink of the security log of the configuration, written as semicolon separated options:
+// file=<file>;events=<event>|<event>;outcome=success|failure;level=<level>;format=<format>. Only the file is
+// required, the sink receiving every event at info level in text otherwise.
+type SinkSpec struct {
+	File   string
+	Format string
+	Filter Filter
+}
+
+// ParseSinkSpec parses a security log sink of the configuration
+func ParseSinkSpec(spec string) (*SinkSpec, error) {
+	sink := &SinkSpec{Format: FormatText, Filter: Filter{Level: logrus.InfoLevel}}
+	for _, option := range strings.Split(spec, ";") {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			continue
+		}
+		parts := strings.SplitN(option, "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("logformat/logformat:ParseSinkSpec() Invalid sink option %s, must be "+
+				"<name>=<value>", option)
+		}
+		name, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		switch name {
+		case "file":
+			sink.File = value
+		case "format":
+			if _, err := New(value, nil, ""); err != nil {
+				return nil, err
+			}
+			sink.Format = value
+		case "level":
+			level, err := logrus.ParseLevel(value)
+			if err != nil {
+				return nil, errors.Wrap(err, "logformat/logformat:ParseSinkSpec() Invalid sink level")
+			}
+			sink.Filter.Level = level
+		case "outcome":
+			if value != OutcomeSuccess && value != OutcomeFailure {
+				return nil, errors.Errorf("logformat/logformat:ParseSinkSpec() Unknown outcome %s, must be %s or %s",
+					value, OutcomeSuccess, OutcomeFailure)
+			}
+			sink.Filter.Outcome = value
+		case "events":
+			for _, event := range strings.Split(value, "|") {
+				if event = strings.TrimSpace(event); event == "" {
+					continue
+				}
+				if !knownEvent(event) {
+					return nil, errors.Errorf("logformat/logformat:ParseSinkSpec() Unknown security event %s, must "+
+						"be one of %s", event, strings.Join(Events, ", "))
+				}
+				sink.Filter.Events = append(sink.Filter.Events, event)
+			}
+		default:
+			return nil, errors.Errorf("logformat/logformat:ParseSinkSpec() Unknown sink option %s, must be file, "+
+				"events, outcome, level or format", name)
+		}
+	}
+	if sink.File == "" {
+		return nil, errors.Errorf("logformat/logformat:ParseSinkSpec() Sink %s has no file", spec)
+	}
+	return sink, nil
+}
+
+func knownEvent(event string) bool {
+	for _, e := range Events {
+		if event == e {
+			return true
+		}
+	}
+	return false
+}
+
+// Tee writes every log entry to each of its sinks whose filter allows it, formatted by the formatter of the
+// sink. The logger it is the formatter of must discard its output, Format writing the records itself and
+// returning nothing.
+type Tee struct {
+	Sinks []Sink
+}
+
+func (t *Tee) Format(e *logrus.Entry) ([]byte, error) {
+	for _, sink := range t.Sinks {
+		if !sink.Filter.Allows(e) {
+			continue
+		}
+		record, err := sink.Formatter.Format(e)
+		if err != nil {
+			return nil, err
+		}
+		_, err = sink.Writer.Write(record)
+		if err != nil {
+			return nil, errors.Wrap(err, "logformat/logformat:Format() Error writing log record")
+		}
+	}
+	return nil, nil
+}
+
+// Output returns the formatter and the writer of a logger writing to the sinks. When the sinks share
+// their formatter and none filters its records they are formatted once and written to all of them,
+// otherwise a Tee formats them for each sink and the logger discards its own output.
+func Output(sinks []Sink) (logrus.Formatter, io.Writer) {
+	writers := make([]io.Writer, 0, len(sinks))
+	for _, sink := range sinks {
+		if sink.Formatter != sinks[0].Formatter || sink.Filter != nil {
+			return &Tee{Sinks: sinks}, ioutil.Discard
+		}
+		writers = append(writers, sink.Writer)
+	}
+	if len(writers) == 1 {
+		return sinks[0].Formatter, writers[0]
+	}
+	return sinks[0].Formatter, io.MultiWriter(writers...)
+}
+
+// Fields adds its fields to every entry before formatting it with Formatter, fields of the entry taking
+// precedence
+type Fields struct {
+	Formatter logrus.Formatter
+	Fields    logrus.Fields
+}
+
+func (f *Fields) Format(e *logrus.Entry) ([]byte, error) {
+	data := make(logrus.Fields, len(e.Data)+len(f.Fields))
+	for key, value := range f.Fields {
+		data[key] = value
+	}
+	for key, value := range e.Data {
+		data[key] = value
+	}
+	entry := *e
+	entry.Data = data
+	return f.Formatter.Format(&entry)
+}
+
+func eventName(e *logrus.Entry) string {
+	if event, ok := e.Data[EventField].(string); ok && event != "" {
+		return event
+	}
+	return EventGeneric
+}
+
+// dataKeys returns the sorted names of the fields of the entry, other than the event and its outcome
+func dataKeys(e *logrus.Entry) []string {
+	keys := make([]string, 0, len(e.Data))
+	for key := range e.Data {
+		if key != EventField && key != OutcomeField {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// severity maps the level of the entry to the 0 to 10 severity scale of CEF and LEEF
+func severity(level logrus.Level) int {
+	switch level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return 10
+	case logrus.ErrorLevel:
+		return 7
+	case logrus.WarnLevel:
+		return 5
+	case logrus.InfoLevel:
+		return 3
+	}
+	return 1
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexAny(s, "\r\n"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+var (
+	cefHeaderEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ")
+	cefValueEscaper  = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r", `\r`, "\n", `\n`)
+	leefValueEscaper = strings.NewReplacer("\t", " ", "\r", " ", "\n", " ", "|", " ")
+)
+
+func cefHeader(s string) string {
+	return cefHeaderEscaper.Replace(s)
+}
+
+func cefValue(s string) string {
+	return cefValueEscaper.Replace(s)
+}
+
+func leefValue(s string) string {
+	return leefValueEscaper.Replace(s)
+}
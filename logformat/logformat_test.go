@@ -0,0 +1,150 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package logformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func testEntry() *logrus.Entry {
+	entry := logrus.NewEntry(logrus.New()).WithFields(logrus.Fields{
+		EventField:   EventAuth,
+		OutcomeField: OutcomeFailure,
+		"role":       "QuoteVerifier",
+	})
+	entry.Time = time.Date(2021, 6, 15, 10, 0, 0, 0, time.UTC)
+	entry.Level = logrus.WarnLevel
+	entry.Message = "access denied|role=x"
+	return entry
+}
+
+func TestCEFFormatter(t *testing.T) {
+	record, err := (&CEFFormatter{Version: "4.1"}).Format(testEntry())
+	assert.NoError(t, err)
+	assert.Equal(t, `CEF:0|Intel|SQVS|4.1|auth|access denied\|role=x|5|rt=1623751200000 cat=auth `+
+		`msg=access denied|role\=x outcome=failure cs1Label=role cs1=QuoteVerifier`+"\n", string(record))
+}
+
+func TestLEEFFormatter(t *testing.T) {
+	record, err := (&LEEFFormatter{Version: "4.1"}).Format(testEntry())
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(record), "LEEF:2.0|Intel|SQVS|4.1|auth|\t|devTime=Jun 15 2021 10:00:00.000 UTC\t"))
+	assert.Contains(t, string(record), "\tsev=5\tcat=auth\tmsg=access denied role=x\toutcome=failure\trole=QuoteVerifier\n")
+}
+
+func TestJSONFormatter(t *testing.T) {
+	entry := testEntry().WithError(errors.New("no role")).WithField("status", 401)
+	entry.Time, entry.Level, entry.Message = time.Date(2021, 6, 15, 10, 0, 0, 0, time.UTC), logrus.WarnLevel,
+		"access denied"
+	record, err := (&JSONFormatter{Version: "4.1"}).Format(entry)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(string(record), "}\n"))
+
+	var fields map[string]interface{}
+	assert.NoError(t, json.Unmarshal(record, &fields))
+	assert.Equal(t, map[string]interface{}{
+		"time": "2021-06-15T10:00:00Z", "level": "warning", "service": "SQVS", "version": "4.1", "event": "auth",
+		"outcome": "failure", "message": "access denied",
+		"fields": map[string]interface{}{"role": "QuoteVerifier", "error": "no role", "status": float64(401)},
+	}, fields)
+}
+
+func TestOutput(t *testing.T) {
+	text := &logrus.TextFormatter{}
+	var console, file bytes.Buffer
+	formatter, writer := Output([]Sink{{Writer: &console, Formatter: text}})
+	assert.Equal(t, text, formatter)
+	assert.Equal(t, &console, writer)
+
+	formatter, _ = Output([]Sink{{Writer: &console, Formatter: text}, {Writer: &file, Formatter: text}})
+	assert.Equal(t, text, formatter)
+
+	formatter, writer = Output([]Sink{{Writer: &console, Formatter: text}, {Writer: &file, Formatter: &JSONFormatter{}}})
+	assert.IsType(t, &Tee{}, formatter)
+	assert.Equal(t, ioutil.Discard, writer)
+}
+
+func TestTee(t *testing.T) {
+	var text, cef bytes.Buffer
+	tee := &Tee{Sinks: []Sink{
+		{Writer: &text, Formatter: &logrus.TextFormatter{DisableTimestamp: true}},
+		{Writer: &cef, Formatter: &CEFFormatter{}},
+	}}
+	record, err := tee.Format(testEntry())
+	assert.NoError(t, err)
+	assert.Empty(t, record)
+	assert.Contains(t, text.String(), "level=warning")
+	assert.True(t, strings.HasPrefix(cef.String(), "CEF:0|"))
+
+	_, err = New("syslog", nil, "")
+	assert.Error(t, err)
+}
+
+func TestFilter(t *testing.T) {
+	var all, denials bytes.Buffer
+	text := &logrus.TextFormatter{DisableTimestamp: true}
+	formatter, _ := Output([]Sink{
+		{Writer: &all, Formatter: text, Filter: &Filter{Level: logrus.InfoLevel}},
+		{Writer: &denials, Formatter: text, Filter: &Filter{Events: []string{EventVerification, EventAdmin},
+			Outcome: OutcomeFailure, Level: logrus.DebugLevel}},
+	})
+	entry := func(level logrus.Level, event, outcome string) *logrus.Entry {
+		return &logrus.Entry{Logger: logrus.New(), Level: level, Message: event + " " + outcome,
+			Data: logrus.Fields{EventField: event, OutcomeField: outcome}}
+	}
+	for _, e := range []*logrus.Entry{
+		entry(logrus.InfoLevel, EventVerification, OutcomeFailure),
+		entry(logrus.DebugLevel, EventAdmin, OutcomeFailure),
+		entry(logrus.InfoLevel, EventVerification, OutcomeSuccess),
+		entry(logrus.WarnLevel, EventAuth, OutcomeFailure),
+	} {
+		_, err := formatter.Format(e)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 3, strings.Count(all.String(), "\n"))
+	assert.NotContains(t, all.String(), "admin")
+	assert.Equal(t, 2, strings.Count(denials.String(), "\n"))
+	assert.Contains(t, denials.String(), "level=debug")
+	assert.NotContains(t, denials.String(), "success")
+
+}
+
+func TestParseSinkSpec(t *testing.T) {
+	sink, err := ParseSinkSpec("file=soc.log; events=verification|admin; outcome=failure; level=trace; format=cef")
+	assert.NoError(t, err)
+	assert.Equal(t, &SinkSpec{File: "soc.log", Format: FormatCEF, Filter: Filter{
+		Events: []string{EventVerification, EventAdmin}, Outcome: OutcomeFailure, Level: logrus.TraceLevel}}, sink)
+
+	sink, err = ParseSinkSpec("file=/var/log/sqvs/auth.log")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatText, sink.Format)
+	assert.Equal(t, logrus.InfoLevel, sink.Filter.Level)
+	assert.Empty(t, sink.Filter.Events)
+
+	for _, invalid := range []string{"events=admin", "file=a.log;events=audit", "file=a.log;outcome=denied",
+		"file=a.log;level=loud", "file=a.log;format=syslog", "file=a.log;color=red", "file"} {
+		_, err = ParseSinkSpec(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestFields(t *testing.T) {
+	formatter := &Fields{Formatter: &LEEFFormatter{}, Fields: logrus.Fields{"configBundle": "7", "role": "default"}}
+	e := testEntry()
+	record, err := formatter.Format(e)
+	assert.NoError(t, err)
+	assert.Contains(t, string(record), "configBundle=7")
+	assert.Contains(t, string(record), "role=QuoteVerifier")
+	assert.NotContains(t, e.Data, "configBundle")
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package logformat
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SampleRateField is the field of a sampled record telling how many records of its kind it stands for
+const SampleRateField = "sampleRate"
+
+// SampledEvents are the high-volume events whose successful records are sampled
+var SampledEvents = []string{EventVerification, EventAuth}
+
+// Sampler keeps 1 in Every of the successful records of the SampledEvents logged at info level or below, and
+// every failure, formatting those it keeps with Formatter. Every record is kept when Every is 0 or 1.
+type Sampler struct {
+	seen      uint64 // first for its 64-bit alignment
+	Formatter logrus.Formatter
+	Every     uint64
+}
+
+func (s *Sampler) Format(e *logrus.Entry) ([]byte, error) {
+	if s.Every <= 1 || !sampled(e) {
+		return s.Formatter.Format(e)
+	}
+	if (atomic.AddUint64(&s.seen, 1)-1)%s.Every != 0 {
+		return nil, nil
+	}
+	data := make(logrus.Fields, len(e.Data)+1)
+	for key, value := range e.Data {
+		data[key] = value
+	}
+	data[SampleRateField] = s.Every
+	entry := *e
+	entry.Data = data
+	return s.Formatter.Format(&entry)
+}
+
+// sampled tells whether the entry is the successful record of a sampled event
+func sampled(e *logrus.Entry) bool {
+	if e.Level < logrus.InfoLevel {
+		return false
+	}
+	if outcome, _ := e.Data[OutcomeField].(string); outcome != OutcomeSuccess {
+		return false
+	}
+	event := eventName(e)
+	for _, sampled := range SampledEvents {
+		if event == sampled {
+			return true
+		}
+	}
+	return false
+}
+
+// AccessLogSampler writes 1 in Every of the combined access log records of the requests answered with a
+// status below 400 to Writer, and every other record, those it cannot read the status of included. Every
+// record is written when Every is 0 or 1.
+type AccessLogSampler struct {
+	seen   uint64 // first for its 64-bit alignment
+	Writer io.Writer
+	Every  uint64
+}
+
+// Write expects a single record, as the logging handlers write them
+func (s *AccessLogSampler) Write(record []byte) (int, error) {
+	if status := accessLogStatus(record); s.Every > 1 && status > 0 && status < 400 {
+		if (atomic.AddUint64(&s.seen, 1)-1)%s.Every != 0 {
+			return len(record), nil
+		}
+	}
+	return s.Writer.Write(record)
+}
+
+// accessLogStatus returns the status of a common or combined log format record, 0 when it has none. The
+// status follows the quoted request line, in which quotes are escaped.
+func accessLogStatus(record []byte) int {
+	start := bytes.Index(record, []byte("] \""))
+	if start < 0 {
+		return 0
+	}
+	for i := start + 3; i < len(record); i++ {
+		switch record[i] {
+		case '\\':
+			i++
+		case '"':
+			rest := bytes.TrimLeft(record[i+1:], " ")
+			if end := bytes.IndexByte(rest, ' '); end >= 0 {
+				rest = rest[:end]
+			}
+			status, _ := strconv.Atoi(string(rest))
+			return status
+		}
+	}
+	return 0
+}
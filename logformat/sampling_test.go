@@ -0,0 +1,53 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package logformat
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampler(t *testing.T) {
+	sampler := &Sampler{Formatter: &JSONFormatter{}, Every: 3}
+	success := testEntry().WithField(OutcomeField, OutcomeSuccess)
+	success.Level = logrus.InfoLevel
+
+	var kept int
+	for i := 0; i < 9; i++ {
+		record, err := sampler.Format(success)
+		assert.NoError(t, err)
+		if len(record) > 0 {
+			kept++
+			assert.Contains(t, string(record), `"sampleRate":3`)
+		}
+	}
+	assert.Equal(t, 3, kept)
+
+	// failures and records of other events are all kept
+	for _, entry := range []*logrus.Entry{testEntry(), success.WithField(EventField, EventAdmin)} {
+		record, err := sampler.Format(entry)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, record)
+	}
+}
+
+func TestAccessLogSampler(t *testing.T) {
+	var out bytes.Buffer
+	sampler := &AccessLogSampler{Writer: &out, Every: 2}
+	ok := `10.0.0.1 - - [15/Jun/2021:10:00:00 +0000] "POST /svs/v1/sgx_qv_verify_quote HTTP/1.1" 200 512 "" "curl"` + "\n"
+	failed := `10.0.0.1 - - [15/Jun/2021:10:00:00 +0000] "GET /svs/v1/a\" 200 b HTTP/1.1" 401 0 "" "curl"` + "\n"
+
+	for i := 0; i < 4; i++ {
+		n, err := sampler.Write([]byte(ok))
+		assert.NoError(t, err)
+		assert.Equal(t, len(ok), n)
+	}
+	_, _ = sampler.Write([]byte(failed))
+	_, _ = sampler.Write([]byte("malformed\n"))
+	assert.Equal(t, ok+ok+failed+"malformed\n", out.String())
+}
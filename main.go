@@ -106,7 +106,7 @@ func main() {
 	if err != nil {
 		fmt.Println("Application returned with error: ", err.Error())
 		closeLogFiles(l, h, s)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
@@ -0,0 +1,69 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/resource"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maintenance turns the maintenance mode of the service on or off through the maintenance file, which the
+// running service watches
+func (a *App) maintenance(args []string) error {
+	if len(args) == 0 {
+		a.printUsage()
+		return errors.New("app:maintenance() Maintenance command must be on, off or status")
+	}
+
+	var state resource.MaintenanceState
+	switch args[0] {
+	case "on":
+		fs := flag.NewFlagSet("maintenance on", flag.ContinueOnError)
+		fs.StringVar(&state.Message, "message", "", "message returned to the rejected clients")
+		err := fs.Parse(args[1:])
+		if err != nil {
+			return errors.Wrap(err, "app:maintenance() Invalid maintenance arguments")
+		}
+		now := time.Now().UTC()
+		state.Enabled = true
+		state.Since = &now
+		err = resource.WriteMaintenanceState(constants.MaintenanceFile, state)
+		if err != nil {
+			return errors.Wrap(err, "app:maintenance() Error turning maintenance mode on")
+		}
+	case "off":
+		err := resource.WriteMaintenanceState(constants.MaintenanceFile, state)
+		if err != nil {
+			return errors.Wrap(err, "app:maintenance() Error turning maintenance mode off")
+		}
+	case "status":
+		var err error
+		state, err = resource.ReadMaintenanceState(constants.MaintenanceFile)
+		if err != nil {
+			return errors.Wrap(err, "app:maintenance() Error reading maintenance mode")
+		}
+	default:
+		a.printUsage()
+		return errors.Errorf("app:maintenance() Unsupported maintenance command %s, must be on, off or status", args[0])
+	}
+
+	if a.outputFormat == outputJSON {
+		return a.printJSON(state)
+	}
+	if !state.Enabled {
+		fmt.Fprintln(a.consoleWriter(), "Maintenance mode is off")
+		return nil
+	}
+	fmt.Fprintf(a.consoleWriter(), "Maintenance mode is on since %s\n", state.Since.Format(time.RFC3339))
+	if state.Message != "" {
+		fmt.Fprintf(a.consoleWriter(), "Message: %s\n", state.Message)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	typeCounter = "counter"
+	typeGauge   = "gauge"
+)
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]*family)
+)
+
+// family holds the samples of a metric, one per combination of label values
+type family struct {
+	name       string
+	help       string
+	metricType string
+	labelNames []string
+
+	mu      sync.Mutex
+	samples map[string]*sample
+}
+
+type sample struct {
+	labelValues []string
+	value       float64
+}
+
+// Counter is a metric whose value only increases
+type Counter struct {
+	f *family
+}
+
+// Gauge is a metric whose value can be set to any value
+type Gauge struct {
+	f *family
+}
+
+// NewCounter registers a counter with the given label names, registering an existing name returns the same counter
+func NewCounter(name, help string, labelNames ...string) *Counter {
+	return &Counter{f: register(name, help, typeCounter, labelNames)}
+}
+
+// NewGauge registers a gauge with the given label names, registering an existing name returns the same gauge
+func NewGauge(name, help string, labelNames ...string) *Gauge {
+	return &Gauge{f: register(name, help, typeGauge, labelNames)}
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.f.update(labelValues, func(v float64) float64 { return v + 1 })
+}
+
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		return
+	}
+	c.f.update(labelValues, func(v float64) float64 { return v + delta })
+}
+
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	g.f.update(labelValues, func(float64) float64 { return value })
+}
+
+func register(name, help, metricType string, labelNames []string) *family {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if f, ok := registry[name]; ok {
+		return f
+	}
+	f := &family{
+		name:       name,
+		help:       help,
+		metricType: metricType,
+		labelNames: labelNames,
+		samples:    make(map[string]*sample),
+	}
+	registry[name] = f
+	return f
+}
+
+func (f *family) update(labelValues []string, fn func(float64) float64) {
+	if len(labelValues) != len(f.labelNames) {
+		return
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.samples[key]
+	if !ok {
+		s = &sample{labelValues: append([]string{}, labelValues...)}
+		f.samples[key] = s
+	}
+	s.value = fn(s.value)
+}
+
+func (f *family) write(w io.Writer) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys := make([]string, 0, len(f.samples))
+	for key := range f.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.metricType)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		s := f.samples[key]
+		labels := make([]string, len(f.labelNames))
+		for i, name := range f.labelNames {
+			labels[i] = name + "=" + strconv.Quote(s.labelValues[i])
+		}
+		series := f.name
+		if len(labels) > 0 {
+			series += "{" + strings.Join(labels, ",") + "}"
+		}
+		_, err = fmt.Fprintf(w, "%s %s\n", series, strconv.FormatFloat(s.value, 'g', -1, 64))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write writes all registered metrics in the Prometheus text exposition format
+func Write(w io.Writer) error {
+	registryMu.Lock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	registryMu.Unlock()
+	sort.Strings(names)
+
+	for _, name := range names {
+		registryMu.Lock()
+		f := registry[name]
+		registryMu.Unlock()
+		if err := f.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler serves the registered metrics to Prometheus scrapers
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		_ = Write(w)
+	})
+}
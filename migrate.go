@@ -0,0 +1,102 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"intel/isecl/sqvs/v4/repository"
+
+	"github.com/pkg/errors"
+)
+
+// MigrationResult is the machine readable output of the migrate command
+type MigrationResult struct {
+	FromVersion int                    `json:"fromVersion"`
+	Version     int                    `json:"version"`
+	Migrations  []repository.Migration `json:"migrations"`
+}
+
+// migrate upgrades, downgrades or shows the schema version of the SQVS database. The server refuses to
+// start against a database whose schema is at another version than the one it works with.
+func (a *App) migrate(args []string) error {
+	if len(args) == 0 || (args[0] != "up" && args[0] != "down" && args[0] != "status") {
+		a.printUsage()
+		return errors.New("app:migrate() Unsupported migrate command, must be up, down or status")
+	}
+
+	fs := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	var to int
+	fs.IntVar(&to, "to", -1, "schema version to migrate to, the latest version for up and the previous one for down")
+	err := fs.Parse(args[1:])
+	if err != nil {
+		return errors.Wrap(err, "app:migrate() Invalid migrate arguments")
+	}
+
+	db, err := repository.OpenMigrator(a.configuration().Database)
+	if err != nil {
+		return errors.Wrap(err, "app:migrate() Error opening SQVS store")
+	}
+	defer db.Close()
+
+	from, err := db.SchemaVersion()
+	if err != nil {
+		return errors.Wrap(err, "app:migrate() Error reading schema version")
+	}
+	migrations, err := db.Migrations()
+	if err != nil {
+		return errors.Wrap(err, "app:migrate() Error reading migrations")
+	}
+
+	version := from
+	switch args[0] {
+	case "up":
+		if to < 0 {
+			to = len(migrations)
+		}
+		if to < from {
+			return errors.Errorf("app:migrate() Schema version %d is older than the current version %d, use migrate down",
+				to, from)
+		}
+	case "down":
+		if to < 0 {
+			to = from - 1
+		}
+		if to < 0 || to > from {
+			return errors.Errorf("app:migrate() Cannot migrate schema version %d down to version %d", from, to)
+		}
+	}
+	if args[0] != "status" && to != from {
+		err = db.Migrate(to)
+		if err != nil {
+			slog.Errorf("app:migrate() Failed to migrate SQVS database schema from version %d to %d", from, to)
+			return errors.Wrap(err, "app:migrate() Error migrating schema")
+		}
+		slog.Infof("app:migrate() Migrated SQVS database schema from version %d to %d", from, to)
+		version = to
+		migrations, err = db.Migrations()
+		if err != nil {
+			return errors.Wrap(err, "app:migrate() Error reading migrations")
+		}
+	}
+
+	if a.outputFormat == outputJSON {
+		return a.printJSON(MigrationResult{FromVersion: from, Version: version, Migrations: migrations})
+	}
+	w := a.consoleWriter()
+	if version != from {
+		fmt.Fprintf(w, "Migrated the database schema from version %d to %d\n", from, version)
+	} else {
+		fmt.Fprintf(w, "Database schema version %d, latest version %d\n", version, len(migrations))
+	}
+	for _, m := range migrations {
+		state := "pending"
+		if m.Applied {
+			state = "applied"
+		}
+		fmt.Fprintf(w, "%4d  %-8s %s\n", m.Version, state, m.Description)
+	}
+	return nil
+}
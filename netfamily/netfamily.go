@@ -0,0 +1,192 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package netfamily
+
+import (
+	"context"
+	"intel/isecl/sqvs/v4/egress"
+	"intel/isecl/sqvs/v4/resolver"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Address families SQVS listens on and connects to its dependencies over. The empty family is dual-stack,
+// IPv4 and IPv6 force one family and PreferIPv4 and PreferIPv6 try the addresses of one family first.
+const (
+	DualStack  = ""
+	IPv4       = "ipv4"
+	IPv6       = "ipv6"
+	PreferIPv4 = "prefer-ipv4"
+	PreferIPv6 = "prefer-ipv6"
+)
+
+// Families lists the supported address families
+var Families = []string{IPv4, IPv6, PreferIPv4, PreferIPv6}
+
+const dialTimeout = 30 * time.Second
+
+var defaultFamily atomic.Value
+
+func init() {
+	defaultFamily.Store(DualStack)
+}
+
+// Validate returns an error if the family is not supported
+func Validate(family string) error {
+	if family == DualStack {
+		return nil
+	}
+	for _, f := range Families {
+		if family == f {
+			return nil
+		}
+	}
+	return errors.Errorf("netfamily/netfamily:Validate() Unsupported address family %s, must be one of %s",
+		family, strings.Join(Families, ", "))
+}
+
+// SetDefault sets the address family outbound connections are made over
+func SetDefault(family string) {
+	defaultFamily.Store(family)
+}
+
+// Default returns the address family outbound connections are made over
+func Default() string {
+	return defaultFamily.Load().(string)
+}
+
+// ListenNetwork returns the network to listen on for the family, only a forced family restricts the listener
+func ListenNetwork(family string) string {
+	switch family {
+	case IPv4:
+		return "tcp4"
+	case IPv6:
+		return "tcp6"
+	}
+	return "tcp"
+}
+
+// ListenAddress joins the host, an IPv4 or IPv6 address or a host name, optionally bracketed, with the port.
+// An empty host listens on all the addresses of the family.
+func ListenAddress(host string, port int) string {
+	return net.JoinHostPort(StripBrackets(host), strconv.Itoa(port))
+}
+
+// StripBrackets removes the brackets around an IPv6 literal, [2001:db8::1] is 2001:db8::1
+func StripBrackets(host string) string {
+	host = strings.TrimSpace(host)
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		return host[1 : len(host)-1]
+	}
+	return host
+}
+
+// NormalizeSANList validates the comma separated subject alternative names of a TLS certificate, IP
+// addresses and DNS names, removes the brackets of IPv6 literals and drops the duplicates
+func NormalizeSANList(sanList string) (string, error) {
+	var sans []string
+	seen := map[string]bool{}
+	for _, san := range strings.Split(sanList, ",") {
+		san, err := normalizeSAN(san)
+		if err != nil {
+			return "", errors.Wrap(err, "netfamily/netfamily:NormalizeSANList() Invalid SAN list")
+		}
+		if san == "" || seen[strings.ToLower(san)] {
+			continue
+		}
+		seen[strings.ToLower(san)] = true
+		sans = append(sans, san)
+	}
+	return strings.Join(sans, ","), nil
+}
+
+// Dialer connects over the default address family, for clients taking a dialer rather than a dial function
+type Dialer struct{}
+
+// Dial connects to the address over the default address family
+func (Dialer) Dial(network, address string) (net.Conn, error) {
+	return DialContext(context.Background(), network, address)
+}
+
+// DialContext connects to the address over the default address family, once the egress allow-list allows it
+func DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return dial(ctx, Default(), network, address)
+}
+
+func dial(ctx context.Context, family, network, address string) (net.Conn, error) {
+	control, err := egress.Check(network, address)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: dialTimeout, KeepAlive: 30 * time.Second, Control: control}
+	if network == "tcp" {
+		switch family {
+		case IPv4:
+			network = "tcp4"
+		case IPv6:
+			network = "tcp6"
+		case PreferIPv4, PreferIPv6:
+			return dialResolved(ctx, dialer, network, address, family)
+		}
+	}
+	if resolver.Current() != nil {
+		return dialResolved(ctx, dialer, network, address, family)
+	}
+	return dialer.DialContext(ctx, network, address)
+}
+
+// dialResolved resolves the host with the current resolver, honoring its static overrides, and tries its
+// addresses of the network in turn, those of the preferred family first for the prefer families
+func dialResolved(ctx context.Context, dialer *net.Dialer, network, address, family string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return dialer.DialContext(ctx, network, address)
+	}
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	switch family {
+	case PreferIPv4, PreferIPv6:
+		addrs = orderAddrs(addrs, family == PreferIPv6)
+	}
+	var lastErr error
+	for _, addr := range addrs {
+		if (strings.HasSuffix(network, "4") && addr.IP.To4() == nil) ||
+			(strings.HasSuffix(network, "6") && addr.IP.To4() != nil) {
+			continue
+		}
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr.IP.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = errors.Errorf("netfamily/netfamily:dialResolved() No %s address found for %s", network, host)
+	}
+	return nil, lastErr
+}
+
+// orderAddrs returns the addresses of the preferred family first, keeping the resolver order otherwise
+func orderAddrs(addrs []net.IPAddr, preferIPv6 bool) []net.IPAddr {
+	var preferred, others []net.IPAddr
+	for _, addr := range addrs {
+		if (addr.IP.To4() == nil) == preferIPv6 {
+			preferred = append(preferred, addr)
+		} else {
+			others = append(others, addr)
+		}
+	}
+	return append(preferred, others...)
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package netfamily
+
+import (
+	"context"
+	"intel/isecl/sqvs/v4/resolver"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeSANList(t *testing.T) {
+	sans, err := NormalizeSANList("127.0.0.1, [2001:db8::1],::1,sqvs.example.com,")
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1,2001:db8::1,::1,sqvs.example.com", sans)
+
+	_, err = NormalizeSANList("[2001:db8::1")
+	assert.Error(t, err)
+	_, err = NormalizeSANList("sqvs.example.com:12000")
+	assert.Error(t, err)
+}
+
+func TestListenAddress(t *testing.T) {
+	assert.Equal(t, ":12000", ListenAddress("", 12000))
+	assert.Equal(t, "[2001:db8::1]:12000", ListenAddress("[2001:db8::1]", 12000))
+	assert.Equal(t, "10.0.0.1:12000", ListenAddress("10.0.0.1", 12000))
+	assert.Equal(t, "tcp6", ListenNetwork(IPv6))
+	assert.Equal(t, "tcp", ListenNetwork(PreferIPv6))
+
+	assert.NoError(t, Validate(DualStack))
+	assert.NoError(t, Validate(PreferIPv4))
+	assert.Error(t, Validate("ipv5"))
+}
+
+func TestOrderAddrs(t *testing.T) {
+	v4 := net.IPAddr{IP: net.ParseIP("10.0.0.1")}
+	v6 := net.IPAddr{IP: net.ParseIP("2001:db8::1")}
+	assert.Equal(t, []net.IPAddr{v6, v4}, orderAddrs([]net.IPAddr{v4, v6}, true))
+	assert.Equal(t, []net.IPAddr{v4, v6}, orderAddrs([]net.IPAddr{v6, v4}, false))
+}
+
+func TestDialHostOverride(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			conn.Close()
+		}
+	}()
+	r, err := resolver.New([]string{"scs.sqvs.invalid=127.0.0.1"}, "", nil, 0)
+	assert.NoError(t, err)
+	resolver.Set(r)
+	defer resolver.Set(nil)
+
+	// the pinned host is dialed at its override address without being looked up
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	conn, err := DialContext(context.Background(), "tcp", net.JoinHostPort("scs.sqvs.invalid", port))
+	assert.NoError(t, err)
+	if conn != nil {
+		assert.Equal(t, listener.Addr().String(), conn.RemoteAddr().String())
+		conn.Close()
+	}
+	_, err = dial(context.Background(), IPv6, "tcp", net.JoinHostPort("scs.sqvs.invalid", port))
+	assert.Error(t, err)
+}
+
+func TestMergeSANList(t *testing.T) {
+	sans, err := MergeSANList("127.0.0.1,::1,localhost", []string{"sqvs-node1", "10.0.0.5", "2001:db8::5", "LOCALHOST"})
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1,::1,localhost,sqvs-node1,10.0.0.5,2001:db8::5", sans)
+
+	sans, err = MergeSANList("*.sqvs.example.com,sqvs.example.com.", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "*.sqvs.example.com,sqvs.example.com", sans)
+
+	for _, invalid := range []string{"sqvs_node", "-sqvs.example.com", "sqvs.*.example.com", "sqvs..example.com"} {
+		_, err = MergeSANList("localhost", []string{invalid})
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestHostSANs(t *testing.T) {
+	sans, err := HostSANs()
+	assert.NoError(t, err)
+	for _, san := range sans {
+		if ip := net.ParseIP(san); ip != nil {
+			assert.False(t, ip.IsLoopback() || ip.IsLinkLocalUnicast(), san)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package netfamily
+
+import (
+	"net"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// dnsNameRegex matches the DNS names of TLS certificates, host names whose first label may be a wildcard
+var dnsNameRegex = regexp.MustCompile(`^(\*|[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+
+const maxDNSNameLength = 253
+
+// normalizeSAN validates a subject alternative name, an IP address, bracketed or not for IPv6, or a DNS name
+func normalizeSAN(san string) (string, error) {
+	san = StripBrackets(san)
+	if san == "" {
+		return "", nil
+	}
+	if ip := net.ParseIP(san); ip != nil {
+		return ip.String(), nil
+	}
+	san = strings.TrimSuffix(san, ".")
+	if len(san) > maxDNSNameLength || !dnsNameRegex.MatchString(san) {
+		return "", errors.Errorf("invalid subject alternative name %s, must be an IP address or a DNS name", san)
+	}
+	return san, nil
+}
+
+// HostSANs returns the subject alternative names clients may reach the host by, its host name and the addresses
+// of its interfaces. Loopback addresses, which the default SAN list holds, and link-local addresses, which only
+// the clients of the same link reach, are left out, and so is a host name that is not a valid DNS name.
+func HostSANs() ([]string, error) {
+	var sans []string
+	hostname, err := os.Hostname()
+	if err == nil {
+		if san, err := normalizeSAN(hostname); err == nil && san != "" {
+			sans = append(sans, san)
+		}
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return sans, errors.Wrap(err, "netfamily/san:HostSANs() Error listing the interface addresses")
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		ip := ipNet.IP
+		if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsMulticast() || ip.IsUnspecified() {
+			continue
+		}
+		sans = append(sans, ip.String())
+	}
+	return sans, nil
+}
+
+// MergeSANList adds sans to the comma separated SAN list, validating and deduplicating every entry
+func MergeSANList(sanList string, sans []string) (string, error) {
+	if len(sans) > 0 {
+		sanList = strings.Join(append([]string{sanList}, sans...), ",")
+	}
+	return NormalizeSANList(sanList)
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package proxyproto
+
+import (
+	"bytes"
+	"encoding/binary"
+	commLog "intel/isecl/lib/common/v4/log"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var log = commLog.GetDefaultLogger()
+
+// DefaultHeaderTimeout bounds the time a proxy is given to send the PROXY protocol header
+const DefaultHeaderTimeout = 5 * time.Second
+
+// signature starts every PROXY protocol v2 header
+var signature = []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+
+const (
+	headerLen = 16
+
+	commandLocal = 0x20
+	commandProxy = 0x21
+
+	familyTCP4 = 0x11
+	familyTCP6 = 0x21
+)
+
+// Listener accepts the connections of load balancers speaking PROXY protocol v2. Connections from the peers
+// Trusted accepts, every peer when nil, must start with the header, their remote address being the one of the
+// client it carries. Headers of LOCAL connections, the health checks of the proxy, keep the address of the peer.
+type Listener struct {
+	net.Listener
+	Trusted       func(net.IP) bool
+	HeaderTimeout time.Duration
+}
+
+// NewListener returns the PROXY protocol listener of l
+func NewListener(l net.Listener, trusted func(net.IP) bool) *Listener {
+	return &Listener{Listener: l, Trusted: trusted, HeaderTimeout: DefaultHeaderTimeout}
+}
+
+func (l *Listener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if l.Trusted != nil {
+		if addr, ok := c.RemoteAddr().(*net.TCPAddr); !ok || !l.Trusted(addr.IP) {
+			return c, nil
+		}
+	}
+	// the header is read by the goroutine serving the connection, not to block accepting others
+	return &conn{Conn: c, timeout: l.HeaderTimeout}, nil
+}
+
+type conn struct {
+	net.Conn
+	timeout time.Duration
+	once    sync.Once
+	remote  net.Addr
+	err     error
+}
+
+func (c *conn) readHeader() {
+	c.once.Do(func() {
+		c.remote = c.Conn.RemoteAddr()
+		if c.timeout > 0 {
+			_ = c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+			defer func() {
+				_ = c.Conn.SetReadDeadline(time.Time{})
+			}()
+		}
+		var remote net.Addr
+		remote, c.err = ReadHeader(c.Conn)
+		if c.err != nil {
+			log.WithError(c.err).Warnf("proxyproto/proxyproto:readHeader() Invalid PROXY protocol header from %s",
+				c.remote)
+			_ = c.Conn.Close()
+			return
+		}
+		if remote != nil {
+			c.remote = remote
+		}
+	})
+}
+
+func (c *conn) Read(b []byte) (int, error) {
+	c.readHeader()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.Conn.Read(b)
+}
+
+func (c *conn) RemoteAddr() net.Addr {
+	c.readHeader()
+	return c.remote
+}
+
+// ReadHeader reads a PROXY protocol v2 header and returns the address of the client it carries, nil for LOCAL
+// headers and address families other than TCP over IPv4 or IPv6
+func ReadHeader(r io.Reader) (net.Addr, error) {
+	header := make([]byte, headerLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, errors.Wrap(err, "proxyproto/proxyproto:ReadHeader() Error reading header")
+	}
+	if !bytes.Equal(header[:len(signature)], signature) {
+		return nil, errors.New("proxyproto/proxyproto:ReadHeader() Not a PROXY protocol v2 header")
+	}
+	command, family := header[12], header[13]
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, errors.Wrap(err, "proxyproto/proxyproto:ReadHeader() Error reading addresses")
+	}
+
+	switch command {
+	case commandLocal:
+		return nil, nil
+	case commandProxy:
+	default:
+		return nil, errors.Errorf("proxyproto/proxyproto:ReadHeader() Unsupported version or command %#x", command)
+	}
+	var ipLen int
+	switch family {
+	case familyTCP4:
+		ipLen = net.IPv4len
+	case familyTCP6:
+		ipLen = net.IPv6len
+	default:
+		return nil, nil
+	}
+	// source and destination addresses followed by source and destination ports, then TLVs
+	if len(payload) < 2*ipLen+4 {
+		return nil, errors.New("proxyproto/proxyproto:ReadHeader() Truncated addresses")
+	}
+	return &net.TCPAddr{
+		IP:   net.IP(append([]byte{}, payload[:ipLen]...)),
+		Port: int(binary.BigEndian.Uint16(payload[2*ipLen:])),
+	}, nil
+}
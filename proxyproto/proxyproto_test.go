@@ -0,0 +1,69 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package proxyproto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func header(command, family byte, addresses []byte) []byte {
+	b := append(append([]byte{}, signature...), command, family, 0, 0)
+	binary.BigEndian.PutUint16(b[14:], uint16(len(addresses)))
+	return append(b, addresses...)
+}
+
+func TestReadHeader(t *testing.T) {
+	tcp4 := []byte{192, 0, 2, 10, 10, 0, 0, 1, 0x1F, 0x90, 0x01, 0xBB}
+	addr, err := ReadHeader(bytes.NewReader(header(commandProxy, familyTCP4, tcp4)))
+	assert.NoError(t, err)
+	assert.Equal(t, "192.0.2.10:8080", addr.String())
+
+	tcp6 := make([]byte, 36)
+	copy(tcp6, net.ParseIP("2001:db8::7"))
+	binary.BigEndian.PutUint16(tcp6[32:], 443)
+	addr, err = ReadHeader(bytes.NewReader(header(commandProxy, familyTCP6, tcp6)))
+	assert.NoError(t, err)
+	assert.Equal(t, "[2001:db8::7]:443", addr.String())
+
+	addr, err = ReadHeader(bytes.NewReader(header(commandLocal, 0, nil)))
+	assert.NoError(t, err)
+	assert.Nil(t, addr)
+
+	_, err = ReadHeader(bytes.NewReader([]byte("GET / HTTP/1.1\r\nHost: sqvs\r\n\r\n")))
+	assert.Error(t, err)
+	_, err = ReadHeader(bytes.NewReader(header(commandProxy, familyTCP4, tcp4[:6])))
+	assert.Error(t, err)
+}
+
+func TestListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+	pl := NewListener(l, nil)
+
+	go func() {
+		c, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		_, _ = c.Write(header(commandProxy, familyTCP4, []byte{198, 51, 100, 4, 127, 0, 0, 1, 0x30, 0x39, 0x01, 0xBB}))
+		_, _ = c.Write([]byte("hello"))
+	}()
+
+	c, err := pl.Accept()
+	assert.NoError(t, err)
+	defer c.Close()
+	assert.Equal(t, "198.51.100.4:12345", c.RemoteAddr().String())
+	data, err := ioutil.ReadAll(c)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+}
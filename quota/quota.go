@@ -0,0 +1,77 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package quota
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	PeriodDaily   = "daily"
+	PeriodMonthly = "monthly"
+
+	EnforcementWarn   = "warn"
+	EnforcementReject = "reject"
+
+	// AllRoutes is the route of the counters of all the requests of a tenant
+	AllRoutes = "*"
+)
+
+// Limit is the maximum number of requests of a tenant to Route, or to all routes, over a day or a month
+type Limit struct {
+	Route  string `json:"route"`
+	Period string `json:"period"`
+	Max    int64  `json:"max"`
+}
+
+// ParseLimits parses "[route:]daily=N" and "[route:]monthly=N" limits
+func ParseLimits(entries []string) ([]Limit, error) {
+	var limits []Limit
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		limit := Limit{Route: AllRoutes}
+		spec := entry
+		if i := strings.LastIndex(entry, ":"); i >= 0 {
+			limit.Route, spec = entry[:i], entry[i+1:]
+		}
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 || (parts[0] != PeriodDaily && parts[0] != PeriodMonthly) || limit.Route == "" {
+			return nil, errors.Errorf("quota/quota:ParseLimits() Invalid quota limit %s, must be "+
+				"[route:]daily=N or [route:]monthly=N", entry)
+		}
+		max, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || max < 0 {
+			return nil, errors.Errorf("quota/quota:ParseLimits() Invalid maximum number of requests in quota limit %s", entry)
+		}
+		limit.Period = parts[0]
+		limit.Max = max
+		limits = append(limits, limit)
+	}
+	return limits, nil
+}
+
+// PeriodKey returns the key of the day (YYYY-MM-DD) or month (YYYY-MM) of t the usage is counted in
+func PeriodKey(period string, t time.Time) string {
+	if period == PeriodDaily {
+		return t.UTC().Format("2006-01-02")
+	}
+	return t.UTC().Format("2006-01")
+}
+
+// PeriodEnd returns the end of the day or month of t, in UTC, when the usage counted in it is reset
+func PeriodEnd(period string, t time.Time) time.Time {
+	t = t.UTC()
+	if period == PeriodDaily {
+		return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+}
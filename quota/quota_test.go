@@ -0,0 +1,32 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package quota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits([]string{"daily=100", " /v1/sgx_qv_verify_quote:monthly=1000", ""})
+	assert.NoError(t, err)
+	assert.Equal(t, []Limit{
+		{Route: AllRoutes, Period: PeriodDaily, Max: 100},
+		{Route: "/v1/sgx_qv_verify_quote", Period: PeriodMonthly, Max: 1000},
+	}, limits)
+
+	for _, entry := range []string{"weekly=1", "daily", "daily=-1", ":daily=1", "monthly=many"} {
+		_, err = ParseLimits([]string{entry})
+		assert.Error(t, err, entry)
+	}
+}
+
+func TestPeriodKey(t *testing.T) {
+	now := time.Date(2021, 3, 31, 23, 30, 0, 0, time.FixedZone("", -3600))
+	assert.Equal(t, "2021-04-01", PeriodKey(PeriodDaily, now))
+	assert.Equal(t, "2021-04", PeriodKey(PeriodMonthly, now))
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package quoteprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	commLog "intel/isecl/lib/common/v4/log"
+	"intel/isecl/sqvs/v4/config"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var log = commLog.GetDefaultLogger()
+
+const (
+	TypeHelper  = "helper"
+	TypeGramine = "gramine"
+
+	// ReportDataSize is the size of the report data bound in an SGX quote
+	ReportDataSize = 64
+
+	// GramineAttestationDir is the pseudo file system through which Gramine exposes quote generation
+	GramineAttestationDir = "/dev/attestation"
+
+	helperTimeout = 30 * time.Second
+)
+
+// Provider obtains SGX quotes of the enclave SQVS runs in, binding the given report data
+type Provider interface {
+	Quote(reportData []byte) ([]byte, error)
+}
+
+// New returns the quote provider of the configured quote source, or nil when the verifier evidence is disabled
+func New(conf config.VerifierEvidenceConfig) (Provider, error) {
+	switch conf.QuoteSource {
+	case "":
+		return nil, nil
+	case TypeHelper:
+		if conf.QuoteHelper == "" {
+			return nil, errors.New("quoteprovider/quoteprovider:New() Quote helper must be configured")
+		}
+		return &HelperProvider{Path: conf.QuoteHelper, Timeout: helperTimeout}, nil
+	case TypeGramine:
+		return &GramineProvider{Dir: GramineAttestationDir}, nil
+	}
+	return nil, errors.Errorf("quoteprovider/quoteprovider:New() Unsupported quote source %s", conf.QuoteSource)
+}
+
+// HelperProvider runs a helper program, usually talking to a helper enclave through AESM, with the hex encoded
+// report data as its only argument. The helper prints the base64 encoded quote to its standard output.
+type HelperProvider struct {
+	Path    string
+	Timeout time.Duration
+}
+
+func (p *HelperProvider) Quote(reportData []byte) ([]byte, error) {
+	log.Trace("quoteprovider/quoteprovider:Quote() Entering")
+	defer log.Trace("quoteprovider/quoteprovider:Quote() Leaving")
+
+	if len(reportData) != ReportDataSize {
+		return nil, errors.Errorf("quoteprovider/quoteprovider:Quote() Report data must be %d bytes", ReportDataSize)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), p.Timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Path, hex.EncodeToString(reportData))
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		return nil, errors.Wrapf(err, "quoteprovider/quoteprovider:Quote() Quote helper failed: %s", strings.TrimSpace(stderr.String()))
+	}
+	quote, err := base64.StdEncoding.DecodeString(strings.TrimSpace(stdout.String()))
+	if err != nil {
+		return nil, errors.Wrap(err, "quoteprovider/quoteprovider:Quote() Quote helper returned an invalid quote")
+	}
+	return quote, nil
+}
+
+// GramineProvider obtains quotes through the attestation pseudo file system of Gramine, writing the report data
+// to user_report_data and reading the quote back from quote
+type GramineProvider struct {
+	Dir string
+
+	// mu serializes quote generation, the report data file is shared by all callers
+	mu sync.Mutex
+}
+
+func (p *GramineProvider) Quote(reportData []byte) ([]byte, error) {
+	log.Trace("quoteprovider/quoteprovider:Quote() Entering")
+	defer log.Trace("quoteprovider/quoteprovider:Quote() Leaving")
+
+	if len(reportData) != ReportDataSize {
+		return nil, errors.Errorf("quoteprovider/quoteprovider:Quote() Report data must be %d bytes", ReportDataSize)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	err := ioutil.WriteFile(filepath.Join(p.Dir, "user_report_data"), reportData, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "quoteprovider/quoteprovider:Quote() Error writing report data")
+	}
+	quote, err := ioutil.ReadFile(filepath.Join(p.Dir, "quote"))
+	if err != nil {
+		return nil, errors.Wrap(err, "quoteprovider/quoteprovider:Quote() Error reading quote")
+	}
+	return quote, nil
+}
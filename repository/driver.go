@@ -0,0 +1,64 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package repository
+
+import (
+	"intel/isecl/sqvs/v4/config"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DriverMemory   = "memory"
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// Driver opens an SQVS database from the configuration, migrating its schema when needed
+type Driver func(conf config.DatabaseConfig) (SQVSDatabase, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]Driver)
+)
+
+// RegisterDriver makes a storage driver available by name, drivers register themselves when their package is imported
+func RegisterDriver(name string, driver Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if _, ok := drivers[name]; ok {
+		panic("repository: RegisterDriver called twice for driver " + name)
+	}
+	drivers[name] = driver
+}
+
+// Drivers returns the names of the registered storage drivers
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open opens the database of the configured storage driver, the in-memory driver being the default
+func Open(conf config.DatabaseConfig) (SQVSDatabase, error) {
+	name := conf.Driver
+	if name == "" {
+		name = DriverMemory
+	}
+	driversMu.RLock()
+	driver, ok := drivers[name]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, errors.Errorf("repository/driver:Open() Unknown storage driver %s", name)
+	}
+	return driver(conf)
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package memory
+
+import (
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/types"
+	"time"
+)
+
+type collateralSnapshotRepository struct {
+	db *MemoryDatabase
+}
+
+func (r *collateralSnapshotRepository) Save(snapshot *types.CollateralSnapshot) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, s := range r.db.data.CollateralSnapshots {
+		if s.Collateral == snapshot.Collateral && s.Fmspc == snapshot.Fmspc && s.IssueDate.Equal(snapshot.IssueDate) {
+			return nil
+		}
+	}
+	r.db.data.CollateralSnapshots = append(r.db.data.CollateralSnapshots, *snapshot)
+	return r.db.persist()
+}
+
+func (r *collateralSnapshotRepository) RetrieveAt(collateral, fmspc string, at time.Time) (*types.CollateralSnapshot, error) {
+	return r.latest(func(s *types.CollateralSnapshot) bool {
+		return s.Collateral == collateral && s.Fmspc == fmspc && !s.IssueDate.After(at)
+	})
+}
+
+func (r *collateralSnapshotRepository) RetrieveVersion(collateral, fmspc string,
+	tcbEvaluationDataNumber uint) (*types.CollateralSnapshot, error) {
+	return r.latest(func(s *types.CollateralSnapshot) bool {
+		return s.Collateral == collateral && s.Fmspc == fmspc && s.TcbEvaluationDataNumber == tcbEvaluationDataNumber
+	})
+}
+
+// latest returns the snapshot matched with the latest issue date
+func (r *collateralSnapshotRepository) latest(match func(*types.CollateralSnapshot) bool) (*types.CollateralSnapshot, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var latest *types.CollateralSnapshot
+	for i := range r.db.data.CollateralSnapshots {
+		s := &r.db.data.CollateralSnapshots[i]
+		if match(s) && (latest == nil || s.IssueDate.After(latest.IssueDate)) {
+			latest = s
+		}
+	}
+	if latest == nil {
+		return nil, repository.ErrRecordNotFound
+	}
+	snapshot := *latest
+	return &snapshot, nil
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package memory
+
+import (
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/types"
+	"sort"
+)
+
+type enclaveIdentityRepository struct {
+	db *MemoryDatabase
+}
+
+func enclaveIdentityKey(mrEnclave, mrSigner string) string {
+	return mrEnclave + "\x00" + mrSigner
+}
+
+func (r *enclaveIdentityRepository) Retrieve(mrEnclave, mrSigner string) (*types.EnclaveIdentity, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	enclave, ok := r.db.data.EnclaveIdentities[enclaveIdentityKey(mrEnclave, mrSigner)]
+	if !ok {
+		return nil, repository.ErrRecordNotFound
+	}
+	return &enclave, nil
+}
+
+func (r *enclaveIdentityRepository) RetrieveAll() (types.EnclaveIdentities, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	enclaves := make(types.EnclaveIdentities, 0, len(r.db.data.EnclaveIdentities))
+	for _, enclave := range r.db.data.EnclaveIdentities {
+		enclaves = append(enclaves, enclave)
+	}
+	sort.Slice(enclaves, func(i, j int) bool {
+		if enclaves[i].MrEnclave != enclaves[j].MrEnclave {
+			return enclaves[i].MrEnclave < enclaves[j].MrEnclave
+		}
+		return enclaves[i].MrSigner < enclaves[j].MrSigner
+	})
+	return enclaves, nil
+}
+
+func (r *enclaveIdentityRepository) Search(criteria repository.ListCriteria) (types.EnclaveIdentities, int, error) {
+	records, err := r.RetrieveAll()
+	if err != nil {
+		return nil, 0, err
+	}
+	selected, total := selectRecords(len(records), criteria,
+		func(i int, name string) string {
+			return enclaveIdentityField(&records[i], name)
+		},
+		func(i, j int, name string) bool {
+			if name == "registeredTime" {
+				return records[i].RegisteredTime.Before(records[j].RegisteredTime)
+			}
+			return enclaveIdentityField(&records[i], name) < enclaveIdentityField(&records[j], name)
+		})
+
+	enclaves := make(types.EnclaveIdentities, 0, len(selected))
+	for _, i := range selected {
+		enclaves = append(enclaves, records[i])
+	}
+	return enclaves, total, nil
+}
+
+// enclaveIdentityField returns the value of an enclave identity field by its JSON name
+func enclaveIdentityField(e *types.EnclaveIdentity, name string) string {
+	switch name {
+	case "mrEnclave":
+		return e.MrEnclave
+	case "mrSigner":
+		return e.MrSigner
+	}
+	return ""
+}
+
+func (r *enclaveIdentityRepository) Save(enclave *types.EnclaveIdentity) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if r.db.data.EnclaveIdentities == nil {
+		r.db.data.EnclaveIdentities = make(map[string]types.EnclaveIdentity)
+	}
+	r.db.data.EnclaveIdentities[enclaveIdentityKey(enclave.MrEnclave, enclave.MrSigner)] = *enclave
+	return r.db.persist()
+}
+
+func (r *enclaveIdentityRepository) Delete(mrEnclave, mrSigner string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	key := enclaveIdentityKey(mrEnclave, mrSigner)
+	if _, ok := r.db.data.EnclaveIdentities[key]; !ok {
+		return repository.ErrRecordNotFound
+	}
+	delete(r.db.data.EnclaveIdentities, key)
+	return r.db.persist()
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package memory
+
+import (
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/types"
+	"sort"
+)
+
+type enrolledPlatformRepository struct {
+	db *MemoryDatabase
+}
+
+func enrolledPlatformKey(fmspc, pceID string) string {
+	return fmspc + "\x00" + pceID
+}
+
+func (r *enrolledPlatformRepository) Retrieve(fmspc, pceID string) (*types.EnrolledPlatform, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	platform, ok := r.db.data.EnrolledPlatforms[enrolledPlatformKey(fmspc, pceID)]
+	if !ok {
+		return nil, repository.ErrRecordNotFound
+	}
+	return &platform, nil
+}
+
+func (r *enrolledPlatformRepository) RetrieveAll() (types.EnrolledPlatforms, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	platforms := make(types.EnrolledPlatforms, 0, len(r.db.data.EnrolledPlatforms))
+	for _, platform := range r.db.data.EnrolledPlatforms {
+		platforms = append(platforms, platform)
+	}
+	sort.Slice(platforms, func(i, j int) bool {
+		if platforms[i].Fmspc != platforms[j].Fmspc {
+			return platforms[i].Fmspc < platforms[j].Fmspc
+		}
+		return platforms[i].PceID < platforms[j].PceID
+	})
+	return platforms, nil
+}
+
+func (r *enrolledPlatformRepository) Search(criteria repository.ListCriteria) (types.EnrolledPlatforms, int, error) {
+	records, err := r.RetrieveAll()
+	if err != nil {
+		return nil, 0, err
+	}
+	selected, total := selectRecords(len(records), criteria,
+		func(i int, name string) string {
+			return enrolledPlatformField(&records[i], name)
+		},
+		func(i, j int, name string) bool {
+			if name == "enrolledTime" {
+				return records[i].EnrolledTime.Before(records[j].EnrolledTime)
+			}
+			return enrolledPlatformField(&records[i], name) < enrolledPlatformField(&records[j], name)
+		})
+
+	platforms := make(types.EnrolledPlatforms, 0, len(selected))
+	for _, i := range selected {
+		platforms = append(platforms, records[i])
+	}
+	return platforms, total, nil
+}
+
+// enrolledPlatformField returns the value of an enrolled platform field by its JSON name
+func enrolledPlatformField(p *types.EnrolledPlatform, name string) string {
+	switch name {
+	case "fmspc":
+		return p.Fmspc
+	case "pceId":
+		return p.PceID
+	}
+	return ""
+}
+
+func (r *enrolledPlatformRepository) Save(platform *types.EnrolledPlatform) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if r.db.data.EnrolledPlatforms == nil {
+		r.db.data.EnrolledPlatforms = make(map[string]types.EnrolledPlatform)
+	}
+	r.db.data.EnrolledPlatforms[enrolledPlatformKey(platform.Fmspc, platform.PceID)] = *platform
+	return r.db.persist()
+}
+
+func (r *enrolledPlatformRepository) Delete(fmspc, pceID string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	key := enrolledPlatformKey(fmspc, pceID)
+	if _, ok := r.db.data.EnrolledPlatforms[key]; !ok {
+		return repository.ErrRecordNotFound
+	}
+	delete(r.db.data.EnrolledPlatforms, key)
+	return r.db.persist()
+}
@@ -0,0 +1,50 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package memory
+
+import (
+	"intel/isecl/sqvs/v4/repository"
+	"sort"
+)
+
+// selectRecords applies the list criteria to a collection of n records. field returns the value of the named
+// field of record i and less orders records i and j by the named field. It returns the indexes of the
+// records in the requested page and the total number of records matching the filters.
+func selectRecords(n int, criteria repository.ListCriteria, field func(i int, name string) string,
+	less func(i, j int, name string) bool) ([]int, int) {
+
+	matches := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		matched := true
+		for name, value := range criteria.Filters {
+			if field(i, name) != value {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			matches = append(matches, i)
+		}
+	}
+
+	if criteria.SortBy != "" {
+		sort.SliceStable(matches, func(a, b int) bool {
+			if criteria.SortDescending {
+				return less(matches[b], matches[a], criteria.SortBy)
+			}
+			return less(matches[a], matches[b], criteria.SortBy)
+		})
+	}
+
+	total := len(matches)
+	if criteria.Offset >= total {
+		return []int{}, total
+	}
+	matches = matches[criteria.Offset:]
+	if criteria.Limit > 0 && criteria.Limit < len(matches) {
+		matches = matches[:criteria.Limit]
+	}
+	return matches, total
+}
@@ -0,0 +1,316 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package memory
+
+import (
+	"bufio"
+	"encoding/json"
+	commLog "intel/isecl/lib/common/v4/log"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/types"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var log = commLog.GetDefaultLogger()
+
+// journalCompactRecords is the least number of journaled records the snapshot is written again at
+const journalCompactRecords = 1024
+
+// MemoryDatabase keeps all records in memory and, when a snapshot file is configured,
+// writes them to that file after every change so they survive service restarts. The verifications,
+// platform TCB statuses, usage counters and token uses are appended to a journal instead, which is folded
+// into the snapshot once it holds as many records as the snapshot.
+type MemoryDatabase struct {
+	mu           sync.RWMutex
+	snapshotFile string
+	data         snapshot
+	// verificationIndex maps the ID of a verification to its position in data.Verifications
+	verificationIndex map[string]int
+	journal           *os.File
+	journaled         int
+	// sequence is the sequence number of the last journaled record
+	sequence uint64
+}
+
+func init() {
+	repository.RegisterDriver(repository.DriverMemory, func(conf config.DatabaseConfig) (repository.SQVSDatabase, error) {
+		snapshotFile := conf.File
+		if snapshotFile == "" {
+			snapshotFile = constants.DefaultDBFile
+		}
+		return New(snapshotFile)
+	})
+}
+
+type snapshot struct {
+	PlatformTcbStatuses map[string]types.PlatformTcbStatus `json:"platformTcbStatuses"`
+	Verifications       types.Verifications                `json:"verifications"`
+	Usages              map[string]types.Usage             `json:"usages,omitempty"`
+	EnrolledPlatforms   map[string]types.EnrolledPlatform  `json:"enrolledPlatforms,omitempty"`
+	Revocations         types.Revocations                  `json:"revocations,omitempty"`
+	CollateralSnapshots types.CollateralSnapshots          `json:"collateralSnapshots,omitempty"`
+	EnclaveIdentities   map[string]types.EnclaveIdentity   `json:"enclaveIdentities,omitempty"`
+	UsedTokens          map[string]types.UsedToken         `json:"usedTokens,omitempty"`
+	// JournalSequence is the sequence number of the last journaled record the snapshot holds
+	JournalSequence uint64 `json:"journalSequence,omitempty"`
+}
+
+// journalRecord is a line of the journal, holding one of the records. Journals written before records
+// were numbered hold bare verifications.
+type journalRecord struct {
+	Sequence          uint64                   `json:"sequence"`
+	Verification      *types.Verification      `json:"verification,omitempty"`
+	PlatformTcbStatus *types.PlatformTcbStatus `json:"platformTcbStatus,omitempty"`
+	Usages            []types.Usage            `json:"usages,omitempty"`
+	UsedToken         *types.UsedToken         `json:"usedToken,omitempty"`
+}
+
+func New(snapshotFile string) (*MemoryDatabase, error) {
+	db := &MemoryDatabase{
+		snapshotFile: snapshotFile,
+		data: snapshot{
+			PlatformTcbStatuses: make(map[string]types.PlatformTcbStatus),
+		},
+		verificationIndex: make(map[string]int),
+	}
+	if snapshotFile == "" {
+		return db, nil
+	}
+
+	content, err := ioutil.ReadFile(snapshotFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "repository/memory:New() Error reading snapshot file")
+	}
+	if err == nil {
+		err = json.Unmarshal(content, &db.data)
+		if err != nil {
+			return nil, errors.Wrap(err, "repository/memory:New() Error decoding snapshot file")
+		}
+	}
+	if db.data.PlatformTcbStatuses == nil {
+		db.data.PlatformTcbStatuses = make(map[string]types.PlatformTcbStatus)
+	}
+	db.indexVerifications()
+	if err = db.replayJournal(); err != nil {
+		return nil, err
+	}
+	// the service starts with an empty journal, which a verification it stopped in the middle of journaling
+	// would corrupt
+	if _, err = os.Stat(db.journalFile()); err == nil {
+		if err = db.persist(); err != nil {
+			return nil, errors.Wrap(err, "repository/memory:New() Error writing snapshot file")
+		}
+	}
+	return db, nil
+}
+
+// journalFile is the file the verifications created since the snapshot was written are appended to
+func (db *MemoryDatabase) journalFile() string {
+	return db.snapshotFile + ".journal"
+}
+
+// replayJournal applies the journaled records missing from the snapshot. A record the service stopped in
+// the middle of journaling is dropped.
+func (db *MemoryDatabase) replayJournal() error {
+	db.sequence = db.data.JournalSequence
+	journal, err := os.Open(db.journalFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrap(err, "repository/memory:replayJournal() Error reading journal file")
+	}
+	defer journal.Close()
+
+	scanner := bufio.NewScanner(journal)
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		var record journalRecord
+		if err = json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			log.WithError(err).Warn("repository/memory:replayJournal() Dropping a truncated journal record")
+			break
+		}
+		if record.Sequence == 0 && record.Verification == nil && record.PlatformTcbStatus == nil &&
+			record.Usages == nil && record.UsedToken == nil {
+			var verification types.Verification
+			if err = json.Unmarshal(scanner.Bytes(), &verification); err != nil {
+				log.WithError(err).Warn("repository/memory:replayJournal() Dropping a truncated journal record")
+				break
+			}
+			record.Verification = &verification
+		}
+		// the records of a journal the snapshot was written after are already in the snapshot
+		if record.Sequence != 0 && record.Sequence <= db.data.JournalSequence {
+			continue
+		}
+		if record.Sequence > db.sequence {
+			db.sequence = record.Sequence
+		}
+		db.apply(&record)
+		db.journaled++
+	}
+	if err = scanner.Err(); err != nil {
+		return errors.Wrap(err, "repository/memory:replayJournal() Error reading journal file")
+	}
+	return nil
+}
+
+// apply adds the record of the journal to the contents, callers must hold db.mu
+func (db *MemoryDatabase) apply(record *journalRecord) {
+	if verification := record.Verification; verification != nil {
+		if _, ok := db.verificationIndex[verification.ID]; !ok {
+			db.verificationIndex[verification.ID] = len(db.data.Verifications)
+			db.data.Verifications = append(db.data.Verifications, *verification)
+		}
+	}
+	if status := record.PlatformTcbStatus; status != nil {
+		db.data.PlatformTcbStatuses[status.PlatformID] = *status
+	}
+	if len(record.Usages) > 0 && db.data.Usages == nil {
+		db.data.Usages = make(map[string]types.Usage)
+	}
+	for _, usage := range record.Usages {
+		db.data.Usages[usageKey(usage.Tenant, usage.Route, usage.Period)] = usage
+	}
+	if used := record.UsedToken; used != nil {
+		if db.data.UsedTokens == nil {
+			db.data.UsedTokens = make(map[string]types.UsedToken)
+		}
+		db.data.UsedTokens[used.ID] = *used
+	}
+}
+
+// indexVerifications indexes the verifications by ID, callers must hold db.mu
+func (db *MemoryDatabase) indexVerifications() {
+	db.verificationIndex = make(map[string]int, len(db.data.Verifications))
+	for i, verification := range db.data.Verifications {
+		db.verificationIndex[verification.ID] = i
+	}
+}
+
+// records returns the number of records the journal can hold changes of, callers must hold db.mu
+func (db *MemoryDatabase) records() int {
+	return len(db.data.Verifications) + len(db.data.PlatformTcbStatuses) + len(db.data.Usages) +
+		len(db.data.UsedTokens)
+}
+
+// appendJournal appends the record, already applied to the contents, to the journal or writes the snapshot
+// once the journal holds as many records as the snapshot. Callers must hold db.mu.
+func (db *MemoryDatabase) appendJournal(record journalRecord) error {
+	if db.snapshotFile == "" {
+		return nil
+	}
+	db.sequence++
+	if db.journaled >= journalCompactRecords && db.journaled >= db.records()-db.journaled {
+		return db.persist()
+	}
+	record.Sequence = db.sequence
+	line, err := json.Marshal(record)
+	if err != nil {
+		return errors.Wrap(err, "repository/memory:appendJournal() Error encoding journal record")
+	}
+	if db.journal == nil {
+		db.journal, err = os.OpenFile(db.journalFile(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			return errors.Wrap(err, "repository/memory:appendJournal() Error opening journal file")
+		}
+	}
+	if _, err = db.journal.Write(append(line, '\n')); err != nil {
+		return errors.Wrap(err, "repository/memory:appendJournal() Error writing journal file")
+	}
+	db.journaled++
+	return nil
+}
+
+func (db *MemoryDatabase) PlatformTcbStatusRepository() repository.PlatformTcbStatusRepository {
+	return &platformTcbStatusRepository{db: db}
+}
+
+func (db *MemoryDatabase) VerificationRepository() repository.VerificationRepository {
+	return &verificationRepository{db: db}
+}
+
+func (db *MemoryDatabase) UsageRepository() repository.UsageRepository {
+	return &usageRepository{db: db}
+}
+
+func (db *MemoryDatabase) EnrolledPlatformRepository() repository.EnrolledPlatformRepository {
+	return &enrolledPlatformRepository{db: db}
+}
+
+func (db *MemoryDatabase) RevocationRepository() repository.RevocationRepository {
+	return &revocationRepository{db: db}
+}
+
+func (db *MemoryDatabase) CollateralSnapshotRepository() repository.CollateralSnapshotRepository {
+	return &collateralSnapshotRepository{db: db}
+}
+
+func (db *MemoryDatabase) EnclaveIdentityRepository() repository.EnclaveIdentityRepository {
+	return &enclaveIdentityRepository{db: db}
+}
+
+func (db *MemoryDatabase) UsedTokenRepository() repository.UsedTokenRepository {
+	return &usedTokenRepository{db: db}
+}
+
+func (db *MemoryDatabase) Close() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if err := db.persist(); err != nil {
+		log.WithError(err).Error("repository/memory:Close() Error writing snapshot file")
+	}
+	if db.journal != nil {
+		db.journal.Close()
+		db.journal = nil
+	}
+}
+
+// persist writes the current contents to the snapshot file and empties the journal, callers must hold db.mu
+func (db *MemoryDatabase) persist() error {
+	if db.snapshotFile == "" {
+		return nil
+	}
+	// the uses of expired tokens are dropped whenever the snapshot is written
+	now := time.Now()
+	for id, used := range db.data.UsedTokens {
+		if now.After(used.ExpiresTime) {
+			delete(db.data.UsedTokens, id)
+		}
+	}
+	db.data.JournalSequence = db.sequence
+	content, err := json.Marshal(db.data)
+	if err != nil {
+		return errors.Wrap(err, "repository/memory:persist() Error encoding snapshot")
+	}
+	tmpFile := db.snapshotFile + ".tmp"
+	err = ioutil.WriteFile(tmpFile, content, 0600)
+	if err != nil {
+		return errors.Wrap(err, "repository/memory:persist() Error writing snapshot")
+	}
+	if err = os.Rename(tmpFile, db.snapshotFile); err != nil {
+		return err
+	}
+	// the snapshot holds the journaled records
+	if db.journaled > 0 || db.journal == nil {
+		if db.journal != nil {
+			err = db.journal.Truncate(0)
+		} else {
+			err = os.Truncate(db.journalFile(), 0)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "repository/memory:persist() Error truncating journal")
+		}
+		db.journaled = 0
+	}
+	return nil
+}
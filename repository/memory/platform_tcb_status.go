@@ -0,0 +1,60 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package memory
+
+import (
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/types"
+	"sort"
+)
+
+type platformTcbStatusRepository struct {
+	db *MemoryDatabase
+}
+
+func (r *platformTcbStatusRepository) Retrieve(platformID string) (*types.PlatformTcbStatus, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	status, ok := r.db.data.PlatformTcbStatuses[platformID]
+	if !ok {
+		return nil, repository.ErrRecordNotFound
+	}
+	return &status, nil
+}
+
+func (r *platformTcbStatusRepository) RetrieveAll() (types.PlatformTcbStatuses, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	statuses := make(types.PlatformTcbStatuses, 0, len(r.db.data.PlatformTcbStatuses))
+	for _, status := range r.db.data.PlatformTcbStatuses {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].PlatformID < statuses[j].PlatformID
+	})
+	return statuses, nil
+}
+
+func (r *platformTcbStatusRepository) Save(status *types.PlatformTcbStatus) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.data.PlatformTcbStatuses[status.PlatformID] = *status
+	return r.db.appendJournal(journalRecord{PlatformTcbStatus: status})
+}
+
+func (r *platformTcbStatusRepository) Swap(status *types.PlatformTcbStatus) (*types.PlatformTcbStatus, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var previous *types.PlatformTcbStatus
+	if replaced, ok := r.db.data.PlatformTcbStatuses[status.PlatformID]; ok {
+		previous = &replaced
+	}
+	r.db.data.PlatformTcbStatuses[status.PlatformID] = *status
+	return previous, r.db.appendJournal(journalRecord{PlatformTcbStatus: status})
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package memory
+
+import (
+	"intel/isecl/sqvs/v4/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlatformTcbStatusJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqvs-store")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	snapshotFile := filepath.Join(dir, "sqvs-store.json")
+
+	db, err := New(snapshotFile)
+	assert.NoError(t, err)
+	statuses := db.PlatformTcbStatusRepository()
+	assert.NoError(t, statuses.Save(&types.PlatformTcbStatus{PlatformID: "p1", TcbStatus: "UpToDate"}))
+	previous, err := statuses.Swap(&types.PlatformTcbStatus{PlatformID: "p1", TcbStatus: "OutOfDate"})
+	assert.NoError(t, err)
+	assert.Equal(t, "UpToDate", previous.TcbStatus)
+	// the statuses are journaled, the snapshot is not written
+	_, err = os.Stat(snapshotFile)
+	assert.True(t, os.IsNotExist(err))
+	journal, err := ioutil.ReadFile(snapshotFile + ".journal")
+	assert.NoError(t, err)
+
+	db, err = New(snapshotFile)
+	assert.NoError(t, err)
+	status, err := db.PlatformTcbStatusRepository().Retrieve("p1")
+	assert.NoError(t, err)
+	assert.Equal(t, "OutOfDate", status.TcbStatus)
+	assert.NoError(t, db.PlatformTcbStatusRepository().Save(&types.PlatformTcbStatus{PlatformID: "p1",
+		TcbStatus: "UpToDate"}))
+	db.Close()
+
+	// the records of a journal the service stopped before truncating are in the snapshot already, they must
+	// not replace the newer statuses
+	assert.NoError(t, ioutil.WriteFile(snapshotFile+".journal", journal, 0600))
+	db, err = New(snapshotFile)
+	assert.NoError(t, err)
+	status, err = db.PlatformTcbStatusRepository().Retrieve("p1")
+	assert.NoError(t, err)
+	assert.Equal(t, "UpToDate", status.TcbStatus)
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package memory
+
+import (
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/types"
+)
+
+type revocationRepository struct {
+	db *MemoryDatabase
+}
+
+func (r *revocationRepository) Create(revocation *types.Revocation) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.data.Revocations = append(r.db.data.Revocations, *revocation)
+	return r.db.persist()
+}
+
+func (r *revocationRepository) RetrieveAll() (types.Revocations, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return append(types.Revocations{}, r.db.data.Revocations...), nil
+}
+
+func (r *revocationRepository) Search(criteria repository.ListCriteria) (types.Revocations, int, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	records := r.db.data.Revocations
+	selected, total := selectRecords(len(records), criteria,
+		func(i int, name string) string {
+			if name == "keyId" {
+				return records[i].KeyID
+			}
+			return ""
+		},
+		func(i, j int, name string) bool {
+			if name == "revokedTime" {
+				return records[i].RevokedTime.Before(records[j].RevokedTime)
+			}
+			return false
+		})
+
+	revocations := make(types.Revocations, 0, len(selected))
+	for _, i := range selected {
+		revocations = append(revocations, records[i])
+	}
+	return revocations, total, nil
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package memory
+
+import (
+	"intel/isecl/sqvs/v4/types"
+	"sort"
+)
+
+type usageRepository struct {
+	db *MemoryDatabase
+}
+
+func usageKey(tenant, route, period string) string {
+	return tenant + "\x00" + route + "\x00" + period
+}
+
+func (r *usageRepository) Add(tenant, route string, periods []string, delta int64) ([]int64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if r.db.data.Usages == nil {
+		r.db.data.Usages = make(map[string]types.Usage)
+	}
+	counts := make([]int64, len(periods))
+	// the updated counters are journaled rather than the delta, replaying them again leaves them unchanged
+	updated := make([]types.Usage, len(periods))
+	for i, period := range periods {
+		key := usageKey(tenant, route, period)
+		usage, ok := r.db.data.Usages[key]
+		if !ok {
+			usage = types.Usage{Tenant: tenant, Route: route, Period: period}
+		}
+		usage.Count += delta
+		r.db.data.Usages[key] = usage
+		counts[i] = usage.Count
+		updated[i] = usage
+	}
+	return counts, r.db.appendJournal(journalRecord{Usages: updated})
+}
+
+func (r *usageRepository) Search(tenant, period string) (types.Usages, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	usages := types.Usages{}
+	for _, usage := range r.db.data.Usages {
+		if usage.Period == period && (tenant == "" || usage.Tenant == tenant) {
+			usages = append(usages, usage)
+		}
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].Tenant != usages[j].Tenant {
+			return usages[i].Tenant < usages[j].Tenant
+		}
+		return usages[i].Route < usages[j].Route
+	})
+	return usages, nil
+}
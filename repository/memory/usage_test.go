@@ -0,0 +1,44 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package memory
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsageJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqvs-store")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	snapshotFile := filepath.Join(dir, "sqvs-store.json")
+
+	db, err := New(snapshotFile)
+	assert.NoError(t, err)
+	usages := db.UsageRepository()
+	periods := []string{"2021-06-01", "2021-06"}
+	for i := 0; i < 3; i++ {
+		_, err = usages.Add("tenant-a", "/quote", periods, 1)
+		assert.NoError(t, err)
+	}
+	counts, err := usages.Add("tenant-a", "/quote", periods, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 2}, counts)
+	// the counters are journaled, the snapshot is not written
+	_, err = os.Stat(snapshotFile)
+	assert.True(t, os.IsNotExist(err))
+
+	db, err = New(snapshotFile)
+	assert.NoError(t, err)
+	monthly, err := db.UsageRepository().Search("tenant-a", "2021-06")
+	assert.NoError(t, err)
+	if assert.Len(t, monthly, 1) {
+		assert.Equal(t, int64(2), monthly[0].Count)
+	}
+}
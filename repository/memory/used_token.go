@@ -0,0 +1,30 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package memory
+
+import (
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/types"
+	"time"
+)
+
+type usedTokenRepository struct {
+	db *MemoryDatabase
+}
+
+func (r *usedTokenRepository) Use(id string, expires time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if used, ok := r.db.data.UsedTokens[id]; ok && time.Now().Before(used.ExpiresTime) {
+		return repository.ErrTokenUsed
+	}
+	if r.db.data.UsedTokens == nil {
+		r.db.data.UsedTokens = make(map[string]types.UsedToken)
+	}
+	used := types.UsedToken{ID: id, ExpiresTime: expires}
+	r.db.data.UsedTokens[id] = used
+	return r.db.appendJournal(journalRecord{UsedToken: &used})
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package memory
+
+import (
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/types"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type verificationRepository struct {
+	db *MemoryDatabase
+}
+
+func (r *verificationRepository) Create(verification *types.Verification) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.verificationIndex[verification.ID] = len(r.db.data.Verifications)
+	r.db.data.Verifications = append(r.db.data.Verifications, *verification)
+	return r.db.appendJournal(journalRecord{Verification: verification})
+}
+
+func (r *verificationRepository) Retrieve(id string) (*types.Verification, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	i, ok := r.db.verificationIndex[id]
+	if !ok {
+		return nil, repository.ErrRecordNotFound
+	}
+	verification := r.db.data.Verifications[i]
+	return &verification, nil
+}
+
+func (r *verificationRepository) Search(criteria repository.ListCriteria) (types.Verifications, int, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	records := r.db.data.Verifications
+	selected, total := selectRecords(len(records), criteria,
+		func(i int, name string) string {
+			return verificationField(&records[i], name)
+		},
+		func(i, j int, name string) bool {
+			if name == "createdTime" {
+				return records[i].CreatedTime.Before(records[j].CreatedTime)
+			}
+			return verificationField(&records[i], name) < verificationField(&records[j], name)
+		})
+
+	verifications := make(types.Verifications, 0, len(selected))
+	for _, i := range selected {
+		verifications = append(verifications, records[i])
+	}
+	return verifications, total, nil
+}
+
+func (r *verificationRepository) Expired(before time.Time, maxRecords int) (types.Verifications, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	records := append(types.Verifications{}, r.db.data.Verifications...)
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].CreatedTime.Before(records[j].CreatedTime)
+	})
+	excess := 0
+	if maxRecords > 0 && len(records) > maxRecords {
+		excess = len(records) - maxRecords
+	}
+
+	var expired types.Verifications
+	for i, verification := range records {
+		if i < excess || verification.CreatedTime.Before(before) {
+			expired = append(expired, verification)
+		}
+	}
+	return expired, nil
+}
+
+func (r *verificationRepository) Delete(ids []string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	deleted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		deleted[id] = true
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	kept := make(types.Verifications, 0, len(r.db.data.Verifications))
+	for _, verification := range r.db.data.Verifications {
+		if !deleted[verification.ID] {
+			kept = append(kept, verification)
+		}
+	}
+	removed := len(r.db.data.Verifications) - len(kept)
+	r.db.data.Verifications = kept
+	r.db.indexVerifications()
+	return removed, r.db.persist()
+}
+
+// verificationField returns the value of a verification field by its JSON name
+func verificationField(v *types.Verification, name string) string {
+	switch name {
+	case "id":
+		return v.ID
+	case "status":
+		return v.Status
+	case "enclaveIssuer":
+		return v.EnclaveIssuer
+	case "enclaveMeasurement":
+		return v.EnclaveMeasurement
+	case "enclaveIssuerProdId":
+		return v.EnclaveIssuerProdID
+	case "isvSvn":
+		return v.IsvSvn
+	case "tcbLevel":
+		return v.TcbLevel
+	case "enclaveDebugMode":
+		return strconv.FormatBool(v.EnclaveDebugMode)
+	case "reverifiedFrom":
+		return v.ReverifiedFrom
+	case "relyingParty":
+		return v.RelyingParty
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package memory
+
+import (
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerificationJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqvs-store")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	snapshotFile := filepath.Join(dir, "sqvs-store.json")
+
+	db, err := New(snapshotFile)
+	assert.NoError(t, err)
+	repo := db.VerificationRepository()
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, repo.Create(&types.Verification{ID: strconv.Itoa(i), Status: "OK"}))
+	}
+	// the verifications are journaled, the snapshot is not written
+	_, err = os.Stat(snapshotFile)
+	assert.True(t, os.IsNotExist(err))
+
+	// a crash in the middle of journaling a verification drops it
+	journal, err := os.OpenFile(snapshotFile+".journal", os.O_WRONLY|os.O_APPEND, 0600)
+	assert.NoError(t, err)
+	_, err = journal.WriteString(`{"id":"3","sta`)
+	assert.NoError(t, err)
+	journal.Close()
+
+	db, err = New(snapshotFile)
+	assert.NoError(t, err)
+	repo = db.VerificationRepository()
+	verification, err := repo.Retrieve("2")
+	assert.NoError(t, err)
+	assert.Equal(t, "OK", verification.Status)
+	_, err = repo.Retrieve("3")
+	assert.Equal(t, repository.ErrRecordNotFound, err)
+
+	deleted, err := repo.Delete([]string{"0"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	content, err := ioutil.ReadFile(snapshotFile + ".journal")
+	assert.NoError(t, err)
+	assert.Empty(t, content)
+	db.Close()
+
+	db, err = New(snapshotFile)
+	assert.NoError(t, err)
+	_, err = db.VerificationRepository().Retrieve("0")
+	assert.Equal(t, repository.ErrRecordNotFound, err)
+	verification, err = db.VerificationRepository().Retrieve("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", verification.ID)
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package repository
+
+import (
+	"intel/isecl/sqvs/v4/config"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrSchemaVersion is returned when opening a database whose schema is older or newer than the version
+// SQVS works with, the database must then be migrated with the migrate command
+var ErrSchemaVersion = errors.New("database schema version mismatch")
+
+// Migration is a version of the schema of the SQVS database and whether the database is migrated to it
+type Migration struct {
+	Version     int    `json:"version"`
+	Description string `json:"description"`
+	Applied     bool   `json:"applied"`
+}
+
+// Migrator upgrades and downgrades the schema of an SQVS database
+type Migrator interface {
+	SchemaVersion() (int, error)
+	Migrations() ([]Migration, error)
+	// Migrate applies or reverts the migrations, in a single transaction, until the schema is at the version
+	Migrate(version int) error
+	Close()
+}
+
+// MigratorDriver opens an SQVS database for migration, whatever the version of its schema
+type MigratorDriver func(conf config.DatabaseConfig) (Migrator, error)
+
+var (
+	migratorsMu sync.RWMutex
+	migrators   = make(map[string]MigratorDriver)
+)
+
+// RegisterMigrator makes the migration of the databases of a storage driver available, the drivers of
+// databases with a schema register it along with the driver
+func RegisterMigrator(name string, driver MigratorDriver) {
+	migratorsMu.Lock()
+	defer migratorsMu.Unlock()
+	if _, ok := migrators[name]; ok {
+		panic("repository: RegisterMigrator called twice for driver " + name)
+	}
+	migrators[name] = driver
+}
+
+// OpenMigrator opens the database of the configured storage driver for migration
+func OpenMigrator(conf config.DatabaseConfig) (Migrator, error) {
+	name := conf.Driver
+	if name == "" {
+		name = DriverMemory
+	}
+	migratorsMu.RLock()
+	driver, ok := migrators[name]
+	migratorsMu.RUnlock()
+	if !ok {
+		return nil, errors.Errorf("repository/migrator:OpenMigrator() The %s storage driver has no schema to migrate", name)
+	}
+	return driver(conf)
+}
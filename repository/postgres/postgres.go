@@ -0,0 +1,109 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package postgres
+
+import (
+	"database/sql"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/repository/sqldb"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	// registers the postgres database/sql driver
+	_ "github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	repository.RegisterDriver(repository.DriverPostgres, Open)
+	repository.RegisterMigrator(repository.DriverPostgres, OpenMigrator)
+}
+
+// Open connects to the configured Postgres database, the production storage driver
+func Open(conf config.DatabaseConfig) (repository.SQVSDatabase, error) {
+	db, err := connect(conf)
+	if err != nil {
+		return nil, err
+	}
+	d, err := sqldb.New(db, sqldb.DialectPostgres)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// OpenMigrator connects to the configured Postgres database to migrate its schema
+func OpenMigrator(conf config.DatabaseConfig) (repository.Migrator, error) {
+	db, err := connect(conf)
+	if err != nil {
+		return nil, err
+	}
+	d, err := sqldb.NewMigrator(db, sqldb.DialectPostgres)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+func connect(conf config.DatabaseConfig) (*sql.DB, error) {
+	dsn, err := dataSourceName(conf)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository/postgres:connect() Error opening database")
+	}
+	err = db.Ping()
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.Wrap(err, "repository/postgres:connect() Error connecting to database")
+	}
+	return db, nil
+}
+
+// dataSourceName builds the libpq connection string of the configuration, reading the password from its file
+func dataSourceName(conf config.DatabaseConfig) (string, error) {
+	if conf.Host == "" || conf.Name == "" || conf.Username == "" {
+		return "", errors.New("repository/postgres:dataSourceName() Database host, name and username must be configured")
+	}
+	port := conf.Port
+	if port == 0 {
+		port = constants.DefaultDBPort
+	}
+	sslMode := conf.SSLMode
+	if sslMode == "" {
+		sslMode = constants.DefaultDBSSLMode
+	}
+
+	params := []string{
+		"host=" + quote(conf.Host),
+		"port=" + strconv.Itoa(port),
+		"dbname=" + quote(conf.Name),
+		"user=" + quote(conf.Username),
+		"sslmode=" + quote(sslMode),
+	}
+	if conf.SSLCert != "" {
+		params = append(params, "sslrootcert="+quote(conf.SSLCert))
+	}
+	if conf.PasswordFile != "" {
+		password, err := ioutil.ReadFile(conf.PasswordFile)
+		if err != nil {
+			return "", errors.Wrap(err, "repository/postgres:dataSourceName() Error reading database password")
+		}
+		params = append(params, "password="+quote(strings.TrimSpace(string(password))))
+	}
+	return strings.Join(params, " "), nil
+}
+
+// quote quotes a connection string value, escaping backslashes and single quotes
+func quote(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
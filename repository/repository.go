@@ -0,0 +1,113 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package repository
+
+import (
+	"intel/isecl/sqvs/v4/types"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrRecordNotFound is returned by repositories when the requested record does not exist
+var ErrRecordNotFound = errors.New("record not found")
+
+// ErrTokenUsed is returned when recording the use of a single-use token that was used already
+var ErrTokenUsed = errors.New("token already used")
+
+// ListCriteria selects a page of a collection. Filters are matched exactly against the named fields,
+// results are ordered by SortBy and a non-positive Limit returns all remaining records.
+type ListCriteria struct {
+	Filters        map[string]string
+	SortBy         string
+	SortDescending bool
+	Offset         int
+	Limit          int
+}
+
+type SQVSDatabase interface {
+	PlatformTcbStatusRepository() PlatformTcbStatusRepository
+	VerificationRepository() VerificationRepository
+	UsageRepository() UsageRepository
+	EnrolledPlatformRepository() EnrolledPlatformRepository
+	RevocationRepository() RevocationRepository
+	CollateralSnapshotRepository() CollateralSnapshotRepository
+	EnclaveIdentityRepository() EnclaveIdentityRepository
+	UsedTokenRepository() UsedTokenRepository
+	Close()
+}
+
+type PlatformTcbStatusRepository interface {
+	Retrieve(platformID string) (*types.PlatformTcbStatus, error)
+	RetrieveAll() (types.PlatformTcbStatuses, error)
+	Save(status *types.PlatformTcbStatus) error
+	// Swap saves the status of a platform and returns the one it replaced, nil when there was none, as one
+	// atomic operation
+	Swap(status *types.PlatformTcbStatus) (*types.PlatformTcbStatus, error)
+}
+
+type VerificationRepository interface {
+	Create(verification *types.Verification) error
+	Retrieve(id string) (*types.Verification, error)
+	// Search returns the page of verifications selected by criteria along with the total number of matches
+	Search(criteria ListCriteria) (types.Verifications, int, error)
+	// Expired returns, oldest first, the verifications created before the given time and, when maxRecords
+	// is positive, those beyond the newest maxRecords
+	Expired(before time.Time, maxRecords int) (types.Verifications, error)
+	// Delete removes the verifications with the given IDs and returns the number removed
+	Delete(ids []string) (int, error)
+}
+
+type UsageRepository interface {
+	// Add adds delta to the usage counters of the tenant and route for every period and returns the updated
+	// counts, in the order of the periods
+	Add(tenant, route string, periods []string, delta int64) ([]int64, error)
+	// Search returns the usage counters of the tenant for the period, of every tenant when tenant is empty
+	Search(tenant, period string) (types.Usages, error)
+}
+
+type EnrolledPlatformRepository interface {
+	Retrieve(fmspc, pceID string) (*types.EnrolledPlatform, error)
+	RetrieveAll() (types.EnrolledPlatforms, error)
+	// Search returns the page of platforms selected by criteria along with the total number of matches
+	Search(criteria ListCriteria) (types.EnrolledPlatforms, int, error)
+	// Save creates the platform or replaces the platform with the same FMSPC and PCE ID
+	Save(platform *types.EnrolledPlatform) error
+	Delete(fmspc, pceID string) error
+}
+
+type RevocationRepository interface {
+	Create(revocation *types.Revocation) error
+	// RetrieveAll returns the revocations, oldest first
+	RetrieveAll() (types.Revocations, error)
+	// Search returns the page of revocations selected by criteria along with the total number of matches
+	Search(criteria ListCriteria) (types.Revocations, int, error)
+}
+
+type CollateralSnapshotRepository interface {
+	// Save records the snapshot unless the collateral issued at the same date for the FMSPC is recorded already
+	Save(snapshot *types.CollateralSnapshot) error
+	// RetrieveAt returns the latest snapshot of the collateral for the FMSPC issued at or before the given time
+	RetrieveAt(collateral, fmspc string, at time.Time) (*types.CollateralSnapshot, error)
+	// RetrieveVersion returns the latest snapshot of the collateral for the FMSPC with the TCB evaluation
+	// data number
+	RetrieveVersion(collateral, fmspc string, tcbEvaluationDataNumber uint) (*types.CollateralSnapshot, error)
+}
+
+type EnclaveIdentityRepository interface {
+	Retrieve(mrEnclave, mrSigner string) (*types.EnclaveIdentity, error)
+	RetrieveAll() (types.EnclaveIdentities, error)
+	// Search returns the page of enclaves selected by criteria along with the total number of matches
+	Search(criteria ListCriteria) (types.EnclaveIdentities, int, error)
+	// Save registers the enclave or replaces the enclave with the same MRENCLAVE and MRSIGNER
+	Save(enclave *types.EnclaveIdentity) error
+	Delete(mrEnclave, mrSigner string) error
+}
+
+type UsedTokenRepository interface {
+	// Use records the use of the single-use token with the ID until it expires, failing with ErrTokenUsed
+	// when it was used already. The uses of expired tokens are forgotten.
+	Use(id string, expires time.Time) error
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package sqldb
+
+import (
+	"database/sql"
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/types"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type collateralSnapshotRepository struct {
+	d *Database
+}
+
+const collateralSnapshotColumns = `id, collateral, fmspc, tcb_evaluation_data_number, issue_date, next_update,
+	content, issuer_chain, recorded_time`
+
+func (r *collateralSnapshotRepository) Save(snapshot *types.CollateralSnapshot) error {
+	_, err := r.d.exec(`INSERT INTO collateral_snapshots (`+collateralSnapshotColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (collateral, fmspc, issue_date) DO NOTHING`,
+		snapshot.ID, snapshot.Collateral, snapshot.Fmspc, snapshot.TcbEvaluationDataNumber, snapshot.IssueDate.UTC(),
+		snapshot.NextUpdate.UTC(), snapshot.Content, snapshot.IssuerChain, snapshot.RecordedTime.UTC())
+	if err != nil {
+		return errors.Wrap(err, "repository/sqldb:Save() Error saving collateral snapshot")
+	}
+	return nil
+}
+
+func (r *collateralSnapshotRepository) RetrieveAt(collateral, fmspc string, at time.Time) (*types.CollateralSnapshot, error) {
+	return r.retrieve(`collateral = ? AND fmspc = ? AND issue_date <= ?`, collateral, fmspc, at.UTC())
+}
+
+func (r *collateralSnapshotRepository) RetrieveVersion(collateral, fmspc string,
+	tcbEvaluationDataNumber uint) (*types.CollateralSnapshot, error) {
+	return r.retrieve(`collateral = ? AND fmspc = ? AND tcb_evaluation_data_number = ?`, collateral, fmspc,
+		tcbEvaluationDataNumber)
+}
+
+// retrieve returns the snapshot selected by the condition with the latest issue date
+func (r *collateralSnapshotRepository) retrieve(condition string, args ...interface{}) (*types.CollateralSnapshot, error) {
+	var snapshot types.CollateralSnapshot
+	err := r.d.queryRow(`SELECT `+collateralSnapshotColumns+` FROM collateral_snapshots WHERE `+condition+`
+		ORDER BY issue_date DESC LIMIT 1`, args...).Scan(&snapshot.ID, &snapshot.Collateral, &snapshot.Fmspc,
+		&snapshot.TcbEvaluationDataNumber, &snapshot.IssueDate, &snapshot.NextUpdate, &snapshot.Content,
+		&snapshot.IssuerChain, &snapshot.RecordedTime)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrRecordNotFound
+	} else if err != nil {
+		return nil, errors.Wrap(err, "repository/sqldb:retrieve() Error reading collateral snapshot")
+	}
+	snapshot.IssueDate = snapshot.IssueDate.UTC()
+	snapshot.NextUpdate = snapshot.NextUpdate.UTC()
+	snapshot.RecordedTime = snapshot.RecordedTime.UTC()
+	return &snapshot, nil
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package sqldb
+
+import (
+	"database/sql"
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/types"
+
+	"github.com/pkg/errors"
+)
+
+type enclaveIdentityRepository struct {
+	d *Database
+}
+
+const enclaveIdentityColumns = `mr_enclave, mr_signer, isv_prod_id, isv_svn, attributes, attributes_mask,
+	description, registered_time`
+
+// enclaveIdentityColumnNames maps the JSON names of enclave identity fields to their columns
+var enclaveIdentityColumnNames = map[string]string{
+	"mrEnclave":      "mr_enclave",
+	"mrSigner":       "mr_signer",
+	"registeredTime": "registered_time",
+}
+
+func scanEnclaveIdentity(row rowScanner) (*types.EnclaveIdentity, error) {
+	var enclave types.EnclaveIdentity
+	err := row.Scan(&enclave.MrEnclave, &enclave.MrSigner, &enclave.IsvProdID, &enclave.IsvSvn, &enclave.Attributes,
+		&enclave.AttributesMask, &enclave.Description, &enclave.RegisteredTime)
+	if err != nil {
+		return nil, err
+	}
+	enclave.RegisteredTime = enclave.RegisteredTime.UTC()
+	return &enclave, nil
+}
+
+func (r *enclaveIdentityRepository) Retrieve(mrEnclave, mrSigner string) (*types.EnclaveIdentity, error) {
+	enclave, err := scanEnclaveIdentity(r.d.queryRow(`SELECT `+enclaveIdentityColumns+` FROM enclave_identities
+		WHERE mr_enclave = ? AND mr_signer = ?`, mrEnclave, mrSigner))
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrRecordNotFound
+	} else if err != nil {
+		return nil, errors.Wrap(err, "repository/sqldb:Retrieve() Error reading enclave identity")
+	}
+	return enclave, nil
+}
+
+func (r *enclaveIdentityRepository) RetrieveAll() (types.EnclaveIdentities, error) {
+	rows, err := r.d.query(`SELECT ` + enclaveIdentityColumns + ` FROM enclave_identities
+		ORDER BY mr_enclave, mr_signer`)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository/sqldb:RetrieveAll() Error reading enclave identities")
+	}
+	defer func() {
+		derr := rows.Close()
+		if derr != nil {
+			log.WithError(derr).Error("Error closing rows")
+		}
+	}()
+
+	return scanEnclaveIdentities(rows)
+}
+
+func (r *enclaveIdentityRepository) Search(criteria repository.ListCriteria) (types.EnclaveIdentities, int, error) {
+	rows, total, err := r.d.search("enclave_identities", enclaveIdentityColumns, enclaveIdentityColumnNames,
+		"mr_enclave, mr_signer", criteria)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		derr := rows.Close()
+		if derr != nil {
+			log.WithError(derr).Error("Error closing rows")
+		}
+	}()
+	enclaves, err := scanEnclaveIdentities(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return enclaves, total, nil
+}
+
+func scanEnclaveIdentities(rows *sql.Rows) (types.EnclaveIdentities, error) {
+	enclaves := types.EnclaveIdentities{}
+	for rows.Next() {
+		enclave, err := scanEnclaveIdentity(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "repository/sqldb:scanEnclaveIdentities() Error reading enclave identity")
+		}
+		enclaves = append(enclaves, *enclave)
+	}
+	return enclaves, rows.Err()
+}
+
+func (r *enclaveIdentityRepository) Save(enclave *types.EnclaveIdentity) error {
+	_, err := r.d.exec(`INSERT INTO enclave_identities (`+enclaveIdentityColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (mr_enclave, mr_signer) DO UPDATE SET isv_prod_id = excluded.isv_prod_id,
+		isv_svn = excluded.isv_svn, attributes = excluded.attributes, attributes_mask = excluded.attributes_mask,
+		description = excluded.description, registered_time = excluded.registered_time`,
+		enclave.MrEnclave, enclave.MrSigner, enclave.IsvProdID, enclave.IsvSvn, enclave.Attributes,
+		enclave.AttributesMask, enclave.Description, enclave.RegisteredTime.UTC())
+	if err != nil {
+		return errors.Wrap(err, "repository/sqldb:Save() Error saving enclave identity")
+	}
+	return nil
+}
+
+func (r *enclaveIdentityRepository) Delete(mrEnclave, mrSigner string) error {
+	result, err := r.d.exec(`DELETE FROM enclave_identities WHERE mr_enclave = ? AND mr_signer = ?`, mrEnclave,
+		mrSigner)
+	if err != nil {
+		return errors.Wrap(err, "repository/sqldb:Delete() Error deleting enclave identity")
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "repository/sqldb:Delete() Error reading number of deleted enclave identities")
+	}
+	if deleted == 0 {
+		return repository.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package sqldb
+
+import (
+	"database/sql"
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/types"
+
+	"github.com/pkg/errors"
+)
+
+type enrolledPlatformRepository struct {
+	d *Database
+}
+
+const enrolledPlatformColumns = `fmspc, pce_id, description, enrolled_time, tcb_info, tcb_info_issuer_chain,
+	tcb_info_next_update, pinned_time`
+
+// enrolledPlatformColumnNames maps the JSON names of enrolled platform fields to their columns
+var enrolledPlatformColumnNames = map[string]string{
+	"fmspc":        "fmspc",
+	"pceId":        "pce_id",
+	"enrolledTime": "enrolled_time",
+}
+
+func scanEnrolledPlatform(row rowScanner) (*types.EnrolledPlatform, error) {
+	var platform types.EnrolledPlatform
+	err := row.Scan(&platform.Fmspc, &platform.PceID, &platform.Description, &platform.EnrolledTime,
+		&platform.TcbInfo, &platform.TcbInfoIssuerChain, &platform.TcbInfoNextUpdate, &platform.PinnedTime)
+	if err != nil {
+		return nil, err
+	}
+	platform.EnrolledTime = platform.EnrolledTime.UTC()
+	platform.TcbInfoNextUpdate = platform.TcbInfoNextUpdate.UTC()
+	platform.PinnedTime = platform.PinnedTime.UTC()
+	return &platform, nil
+}
+
+func (r *enrolledPlatformRepository) Retrieve(fmspc, pceID string) (*types.EnrolledPlatform, error) {
+	platform, err := scanEnrolledPlatform(r.d.queryRow(`SELECT `+enrolledPlatformColumns+` FROM enrolled_platforms
+		WHERE fmspc = ? AND pce_id = ?`, fmspc, pceID))
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrRecordNotFound
+	} else if err != nil {
+		return nil, errors.Wrap(err, "repository/sqldb:Retrieve() Error reading enrolled platform")
+	}
+	return platform, nil
+}
+
+func (r *enrolledPlatformRepository) RetrieveAll() (types.EnrolledPlatforms, error) {
+	rows, err := r.d.query(`SELECT ` + enrolledPlatformColumns + ` FROM enrolled_platforms ORDER BY fmspc, pce_id`)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository/sqldb:RetrieveAll() Error reading enrolled platforms")
+	}
+	defer func() {
+		derr := rows.Close()
+		if derr != nil {
+			log.WithError(derr).Error("Error closing rows")
+		}
+	}()
+
+	return scanEnrolledPlatforms(rows)
+}
+
+func (r *enrolledPlatformRepository) Search(criteria repository.ListCriteria) (types.EnrolledPlatforms, int, error) {
+	rows, total, err := r.d.search("enrolled_platforms", enrolledPlatformColumns, enrolledPlatformColumnNames,
+		"fmspc, pce_id", criteria)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		derr := rows.Close()
+		if derr != nil {
+			log.WithError(derr).Error("Error closing rows")
+		}
+	}()
+	platforms, err := scanEnrolledPlatforms(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return platforms, total, nil
+}
+
+func scanEnrolledPlatforms(rows *sql.Rows) (types.EnrolledPlatforms, error) {
+	platforms := types.EnrolledPlatforms{}
+	for rows.Next() {
+		platform, err := scanEnrolledPlatform(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "repository/sqldb:scanEnrolledPlatforms() Error reading enrolled platform")
+		}
+		platforms = append(platforms, *platform)
+	}
+	return platforms, rows.Err()
+}
+
+func (r *enrolledPlatformRepository) Save(platform *types.EnrolledPlatform) error {
+	_, err := r.d.exec(`INSERT INTO enrolled_platforms (`+enrolledPlatformColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (fmspc, pce_id) DO UPDATE SET description = excluded.description,
+		enrolled_time = excluded.enrolled_time, tcb_info = excluded.tcb_info,
+		tcb_info_issuer_chain = excluded.tcb_info_issuer_chain, tcb_info_next_update = excluded.tcb_info_next_update,
+		pinned_time = excluded.pinned_time`,
+		platform.Fmspc, platform.PceID, platform.Description, platform.EnrolledTime.UTC(), platform.TcbInfo,
+		platform.TcbInfoIssuerChain, platform.TcbInfoNextUpdate.UTC(), platform.PinnedTime.UTC())
+	if err != nil {
+		return errors.Wrap(err, "repository/sqldb:Save() Error saving enrolled platform")
+	}
+	return nil
+}
+
+func (r *enrolledPlatformRepository) Delete(fmspc, pceID string) error {
+	result, err := r.d.exec(`DELETE FROM enrolled_platforms WHERE fmspc = ? AND pce_id = ?`, fmspc, pceID)
+	if err != nil {
+		return errors.Wrap(err, "repository/sqldb:Delete() Error deleting enrolled platform")
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "repository/sqldb:Delete() Error reading number of deleted platforms")
+	}
+	if deleted == 0 {
+		return repository.ErrRecordNotFound
+	}
+	return nil
+}
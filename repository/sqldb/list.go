@@ -0,0 +1,65 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package sqldb
+
+import (
+	"database/sql"
+	"intel/isecl/sqvs/v4/repository"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// search selects the columns of the rows of the table in the page of the list criteria and counts the rows
+// matching its filters. columnNames maps the JSON names of the fields to their columns, ties are broken by the
+// order columns so paging is stable.
+func (d *Database) search(table, columns string, columnNames map[string]string, order string,
+	criteria repository.ListCriteria) (*sql.Rows, int, error) {
+
+	names := make([]string, 0, len(criteria.Filters))
+	for name := range criteria.Filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var conditions []string
+	var args []interface{}
+	for _, name := range names {
+		column, ok := columnNames[name]
+		if !ok {
+			return nil, 0, errors.Errorf("repository/sqldb:search() Unknown %s field %s", table, name)
+		}
+		conditions = append(conditions, column+" = ?")
+		args = append(args, criteria.Filters[name])
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	err := d.queryRow(`SELECT COUNT(*) FROM `+table+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "repository/sqldb:search() Error counting %s", table)
+	}
+
+	if criteria.SortBy != "" {
+		column, ok := columnNames[criteria.SortBy]
+		if !ok {
+			return nil, 0, errors.Errorf("repository/sqldb:search() Unknown %s field %s", table, criteria.SortBy)
+		}
+		if criteria.SortDescending {
+			column += " DESC"
+		}
+		order = column + ", " + order
+	}
+	rows, err := d.query(`SELECT `+columns+` FROM `+table+where+` ORDER BY `+order+
+		d.dialect.limit(criteria.Limit, criteria.Offset), args...)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "repository/sqldb:search() Error searching %s", table)
+	}
+	return rows, total, nil
+}
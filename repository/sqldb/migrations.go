@@ -0,0 +1,342 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package sqldb
+
+import (
+	"database/sql"
+	"intel/isecl/sqvs/v4/repository"
+
+	"github.com/pkg/errors"
+)
+
+// migration brings the schema from the previous version to the next with up, and back with down. SQLite
+// cannot drop columns, migrations altering tables revert them with sqliteDown instead.
+type migration struct {
+	description string
+	up          []string
+	down        []string
+	sqliteDown  []string
+}
+
+// migrations upgrade the schema one version at a time, migration i bringing it to version i+1.
+// Released migrations must never change, schema changes are made by appending a migration.
+var migrations = []migration{
+	{
+		description: "Verification history and platform TCB statuses",
+		up: []string{
+			`CREATE TABLE verifications (
+			id VARCHAR(36) PRIMARY KEY,
+			created_time TIMESTAMP NOT NULL,
+			status VARCHAR(16) NOT NULL,
+			message TEXT NOT NULL,
+			enclave_issuer VARCHAR(64) NOT NULL,
+			enclave_measurement VARCHAR(64) NOT NULL,
+			enclave_issuer_prod_id VARCHAR(8) NOT NULL,
+			isv_svn VARCHAR(8) NOT NULL,
+			tcb_level VARCHAR(32) NOT NULL,
+			enclave_debug_mode BOOLEAN NOT NULL
+		)`,
+			`CREATE INDEX verifications_created_time ON verifications (created_time)`,
+			`CREATE TABLE platform_tcb_statuses (
+			platform_id VARCHAR(64) PRIMARY KEY,
+			fmspc VARCHAR(12) NOT NULL,
+			tcb_status VARCHAR(32) NOT NULL,
+			updated_time TIMESTAMP NOT NULL
+		)`,
+		},
+		down: []string{
+			`DROP TABLE platform_tcb_statuses`,
+			`DROP TABLE verifications`,
+		},
+	},
+	{
+		description: "Tenant usage",
+		up: []string{
+			`CREATE TABLE usages (
+			tenant VARCHAR(128) NOT NULL,
+			route VARCHAR(128) NOT NULL,
+			period VARCHAR(10) NOT NULL,
+			count BIGINT NOT NULL,
+			PRIMARY KEY (tenant, route, period)
+		)`,
+			`CREATE INDEX usages_period ON usages (period)`,
+		},
+		down: []string{`DROP TABLE usages`},
+	},
+	{
+		description: "Enrolled platforms",
+		up: []string{
+			`CREATE TABLE enrolled_platforms (
+			fmspc VARCHAR(12) NOT NULL,
+			pce_id VARCHAR(4) NOT NULL,
+			description TEXT NOT NULL,
+			enrolled_time TIMESTAMP NOT NULL,
+			tcb_info TEXT NOT NULL,
+			tcb_info_issuer_chain TEXT NOT NULL,
+			tcb_info_next_update TIMESTAMP NOT NULL,
+			pinned_time TIMESTAMP NOT NULL,
+			PRIMARY KEY (fmspc, pce_id)
+		)`,
+		},
+		down: []string{`DROP TABLE enrolled_platforms`},
+	},
+	{
+		description: "Verification result revocations",
+		up: []string{
+			`CREATE TABLE revocations (
+			id VARCHAR(36) PRIMARY KEY,
+			reason TEXT NOT NULL,
+			revoked_time TIMESTAMP NOT NULL,
+			issued_after TIMESTAMP,
+			issued_before TIMESTAMP,
+			key_id VARCHAR(128) NOT NULL,
+			quote_hashes TEXT NOT NULL
+		)`,
+		},
+		down: []string{`DROP TABLE revocations`},
+	},
+	{
+		description: "Collateral history",
+		up: []string{
+			`CREATE TABLE collateral_snapshots (
+			id VARCHAR(36) PRIMARY KEY,
+			collateral VARCHAR(16) NOT NULL,
+			fmspc VARCHAR(12) NOT NULL,
+			tcb_evaluation_data_number INTEGER NOT NULL,
+			issue_date TIMESTAMP NOT NULL,
+			next_update TIMESTAMP NOT NULL,
+			content TEXT NOT NULL,
+			issuer_chain TEXT NOT NULL,
+			recorded_time TIMESTAMP NOT NULL,
+			UNIQUE (collateral, fmspc, issue_date)
+		)`,
+		},
+		down: []string{`DROP TABLE collateral_snapshots`},
+	},
+	{
+		description: "Re-verifications and retained raw quotes",
+		up: []string{
+			`ALTER TABLE verifications ADD COLUMN reverified_from VARCHAR(36) NOT NULL DEFAULT ''`,
+			`ALTER TABLE verifications ADD COLUMN quote TEXT NOT NULL DEFAULT ''`,
+		},
+		down: []string{
+			`ALTER TABLE verifications DROP COLUMN quote`,
+			`ALTER TABLE verifications DROP COLUMN reverified_from`,
+		},
+		sqliteDown: []string{
+			`CREATE TABLE verifications_v5 (
+			id VARCHAR(36) PRIMARY KEY,
+			created_time TIMESTAMP NOT NULL,
+			status VARCHAR(16) NOT NULL,
+			message TEXT NOT NULL,
+			enclave_issuer VARCHAR(64) NOT NULL,
+			enclave_measurement VARCHAR(64) NOT NULL,
+			enclave_issuer_prod_id VARCHAR(8) NOT NULL,
+			isv_svn VARCHAR(8) NOT NULL,
+			tcb_level VARCHAR(32) NOT NULL,
+			enclave_debug_mode BOOLEAN NOT NULL
+		)`,
+			`INSERT INTO verifications_v5 SELECT id, created_time, status, message, enclave_issuer, enclave_measurement,
+			enclave_issuer_prod_id, isv_svn, tcb_level, enclave_debug_mode FROM verifications`,
+			`DROP TABLE verifications`,
+			`ALTER TABLE verifications_v5 RENAME TO verifications`,
+			`CREATE INDEX verifications_created_time ON verifications (created_time)`,
+		},
+	},
+	{
+		description: "Relying party request signatures",
+		up: []string{
+			`ALTER TABLE verifications ADD COLUMN relying_party VARCHAR(128) NOT NULL DEFAULT ''`,
+			`ALTER TABLE verifications ADD COLUMN request_signature TEXT NOT NULL DEFAULT ''`,
+		},
+		down: []string{
+			`ALTER TABLE verifications DROP COLUMN request_signature`,
+			`ALTER TABLE verifications DROP COLUMN relying_party`,
+		},
+		sqliteDown: []string{
+			`CREATE TABLE verifications_v6 (
+			id VARCHAR(36) PRIMARY KEY,
+			created_time TIMESTAMP NOT NULL,
+			status VARCHAR(16) NOT NULL,
+			message TEXT NOT NULL,
+			enclave_issuer VARCHAR(64) NOT NULL,
+			enclave_measurement VARCHAR(64) NOT NULL,
+			enclave_issuer_prod_id VARCHAR(8) NOT NULL,
+			isv_svn VARCHAR(8) NOT NULL,
+			tcb_level VARCHAR(32) NOT NULL,
+			enclave_debug_mode BOOLEAN NOT NULL,
+			reverified_from VARCHAR(36) NOT NULL DEFAULT '',
+			quote TEXT NOT NULL DEFAULT ''
+		)`,
+			`INSERT INTO verifications_v6 SELECT id, created_time, status, message, enclave_issuer, enclave_measurement,
+			enclave_issuer_prod_id, isv_svn, tcb_level, enclave_debug_mode, reverified_from, quote FROM verifications`,
+			`DROP TABLE verifications`,
+			`ALTER TABLE verifications_v6 RENAME TO verifications`,
+			`CREATE INDEX verifications_created_time ON verifications (created_time)`,
+		},
+	},
+	{
+		description: "Enclave identities",
+		up: []string{
+			`CREATE TABLE enclave_identities (
+			mr_enclave VARCHAR(64) NOT NULL,
+			mr_signer VARCHAR(64) NOT NULL,
+			isv_prod_id INTEGER NOT NULL,
+			isv_svn INTEGER NOT NULL,
+			attributes VARCHAR(32) NOT NULL,
+			attributes_mask VARCHAR(32) NOT NULL,
+			description TEXT NOT NULL,
+			registered_time TIMESTAMP NOT NULL,
+			PRIMARY KEY (mr_enclave, mr_signer)
+		)`,
+		},
+		down: []string{`DROP TABLE enclave_identities`},
+	},
+	{
+		description: "Used single-use tokens",
+		up: []string{
+			`CREATE TABLE used_tokens (
+			id VARCHAR(256) PRIMARY KEY,
+			expires_time TIMESTAMP NOT NULL
+		)`,
+			`CREATE INDEX used_tokens_expires_time ON used_tokens (expires_time)`,
+		},
+		down: []string{`DROP TABLE used_tokens`},
+	},
+}
+
+// LatestSchemaVersion is the schema version of the databases SQVS works with
+func LatestSchemaVersion() int {
+	return len(migrations)
+}
+
+func (d *Database) createVersionTable() error {
+	_, err := d.exec(`CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)`)
+	if err != nil {
+		return errors.Wrap(err, "repository/sqldb:createVersionTable() Error creating schema version table")
+	}
+	return nil
+}
+
+// checkSchema creates the schema of an empty database and fails with repository.ErrSchemaVersion when the
+// schema of the database is older or newer than LatestSchemaVersion, the schema of databases in use being
+// only migrated with the migrate command
+func (d *Database) checkSchema() error {
+	version, err := d.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version == 0 {
+		return d.Migrate(LatestSchemaVersion())
+	}
+	if version < LatestSchemaVersion() {
+		return errors.Wrapf(repository.ErrSchemaVersion, "Database schema version %d is older than version %d, "+
+			"run sqvs migrate up", version, LatestSchemaVersion())
+	}
+	if version > LatestSchemaVersion() {
+		return errors.Wrapf(repository.ErrSchemaVersion, "Database schema version %d is newer than version %d, "+
+			"run sqvs migrate down with the sqvs release that migrated it", version, LatestSchemaVersion())
+	}
+	return nil
+}
+
+// SchemaVersion returns the version of the schema of the database, 0 when it has none
+func (d *Database) SchemaVersion() (int, error) {
+	var version int
+	err := d.queryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version)
+	if err != nil {
+		return 0, errors.Wrap(err, "repository/sqldb:SchemaVersion() Error reading schema version")
+	}
+	return version, nil
+}
+
+// Migrations returns the migrations known to SQVS and whether they are applied to the database
+func (d *Database) Migrations() ([]repository.Migration, error) {
+	version, err := d.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	status := make([]repository.Migration, len(migrations))
+	for i, m := range migrations {
+		status[i] = repository.Migration{Version: i + 1, Description: m.description, Applied: i < version}
+	}
+	return status, nil
+}
+
+// Migrate applies or reverts the migrations until the schema is at the version, in a single transaction so
+// concurrent SQVS instances migrate the database only once and a failed migration leaves it untouched
+func (d *Database) Migrate(version int) error {
+	log.Trace("repository/sqldb:Migrate() Entering")
+	defer log.Trace("repository/sqldb:Migrate() Leaving")
+
+	if version < 0 || version > LatestSchemaVersion() {
+		return errors.Errorf("repository/sqldb:Migrate() Unknown schema version %d, versions go from 0 to %d",
+			version, LatestSchemaVersion())
+	}
+	tx, err := d.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "repository/sqldb:Migrate() Error starting transaction")
+	}
+	err = d.applyMigrations(tx, version)
+	if err != nil {
+		if rerr := tx.Rollback(); rerr != nil {
+			log.WithError(rerr).Error("repository/sqldb:Migrate() Error rolling back transaction")
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+func (d *Database) applyMigrations(tx *sql.Tx, target int) error {
+	if d.dialect == DialectPostgres {
+		_, err := tx.Exec(`LOCK TABLE schema_version IN EXCLUSIVE MODE`)
+		if err != nil {
+			return errors.Wrap(err, "repository/sqldb:applyMigrations() Error locking schema version table")
+		}
+	}
+
+	var version int
+	err := tx.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version)
+	if err != nil {
+		return errors.Wrap(err, "repository/sqldb:applyMigrations() Error reading schema version")
+	}
+	if version > len(migrations) {
+		return errors.Errorf("repository/sqldb:applyMigrations() Database schema version %d is newer than supported version %d",
+			version, len(migrations))
+	}
+
+	for ; version < target; version++ {
+		for _, statement := range migrations[version].up {
+			_, err = tx.Exec(statement)
+			if err != nil {
+				return errors.Wrapf(err, "repository/sqldb:applyMigrations() Error migrating schema to version %d", version+1)
+			}
+		}
+		_, err = tx.Exec(d.dialect.rebind(`INSERT INTO schema_version (version) VALUES (?)`), version+1)
+		if err != nil {
+			return errors.Wrap(err, "repository/sqldb:applyMigrations() Error recording schema version")
+		}
+		log.Infof("repository/sqldb:applyMigrations() Migrated database schema to version %d", version+1)
+	}
+
+	for ; version > target; version-- {
+		statements := migrations[version-1].down
+		if d.dialect == DialectSQLite && migrations[version-1].sqliteDown != nil {
+			statements = migrations[version-1].sqliteDown
+		}
+		for _, statement := range statements {
+			_, err = tx.Exec(statement)
+			if err != nil {
+				return errors.Wrapf(err, "repository/sqldb:applyMigrations() Error reverting schema version %d", version)
+			}
+		}
+		_, err = tx.Exec(d.dialect.rebind(`DELETE FROM schema_version WHERE version >= ?`), version)
+		if err != nil {
+			return errors.Wrap(err, "repository/sqldb:applyMigrations() Error recording schema version")
+		}
+		log.Infof("repository/sqldb:applyMigrations() Reverted database schema to version %d", version-1)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package sqldb
+
+import (
+	"database/sql"
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/types"
+
+	"github.com/pkg/errors"
+)
+
+type platformTcbStatusRepository struct {
+	d *Database
+}
+
+func (r *platformTcbStatusRepository) Retrieve(platformID string) (*types.PlatformTcbStatus, error) {
+	var status types.PlatformTcbStatus
+	err := r.d.queryRow(`SELECT platform_id, fmspc, tcb_status, updated_time FROM platform_tcb_statuses
+		WHERE platform_id = ?`, platformID).Scan(&status.PlatformID, &status.Fmspc, &status.TcbStatus, &status.UpdatedTime)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrRecordNotFound
+	} else if err != nil {
+		return nil, errors.Wrap(err, "repository/sqldb:Retrieve() Error reading platform TCB status")
+	}
+	status.UpdatedTime = status.UpdatedTime.UTC()
+	return &status, nil
+}
+
+func (r *platformTcbStatusRepository) RetrieveAll() (types.PlatformTcbStatuses, error) {
+	rows, err := r.d.query(`SELECT platform_id, fmspc, tcb_status, updated_time FROM platform_tcb_statuses
+		ORDER BY platform_id`)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository/sqldb:RetrieveAll() Error reading platform TCB statuses")
+	}
+	defer func() {
+		derr := rows.Close()
+		if derr != nil {
+			log.WithError(derr).Error("Error closing rows")
+		}
+	}()
+
+	statuses := types.PlatformTcbStatuses{}
+	for rows.Next() {
+		var status types.PlatformTcbStatus
+		err = rows.Scan(&status.PlatformID, &status.Fmspc, &status.TcbStatus, &status.UpdatedTime)
+		if err != nil {
+			return nil, errors.Wrap(err, "repository/sqldb:RetrieveAll() Error reading platform TCB status")
+		}
+		status.UpdatedTime = status.UpdatedTime.UTC()
+		statuses = append(statuses, status)
+	}
+	return statuses, rows.Err()
+}
+
+func (r *platformTcbStatusRepository) Save(status *types.PlatformTcbStatus) error {
+	_, err := r.d.exec(`INSERT INTO platform_tcb_statuses (platform_id, fmspc, tcb_status, updated_time)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (platform_id) DO UPDATE SET fmspc = excluded.fmspc, tcb_status = excluded.tcb_status,
+		updated_time = excluded.updated_time`,
+		status.PlatformID, status.Fmspc, status.TcbStatus, status.UpdatedTime.UTC())
+	if err != nil {
+		return errors.Wrap(err, "repository/sqldb:Save() Error saving platform TCB status")
+	}
+	return nil
+}
+
+func (r *platformTcbStatusRepository) Swap(status *types.PlatformTcbStatus) (*types.PlatformTcbStatus, error) {
+	tx, err := r.d.db.Begin()
+	if err != nil {
+		return nil, errors.Wrap(err, "repository/sqldb:Swap() Error starting transaction")
+	}
+	previous, err := r.swap(tx, status)
+	if err != nil {
+		if rerr := tx.Rollback(); rerr != nil {
+			log.WithError(rerr).Error("repository/sqldb:Swap() Error rolling back transaction")
+		}
+		return nil, err
+	}
+	return previous, errors.Wrap(tx.Commit(), "repository/sqldb:Swap() Error committing platform TCB status")
+}
+
+func (r *platformTcbStatusRepository) swap(tx *sql.Tx, status *types.PlatformTcbStatus) (*types.PlatformTcbStatus, error) {
+	// the first status of a platform replaces none, the insert takes the write lock of SQLite until the
+	// transaction ends
+	result, err := tx.Exec(r.d.dialect.rebind(`INSERT INTO platform_tcb_statuses (platform_id, fmspc, tcb_status,
+		updated_time) VALUES (?, ?, ?, ?) ON CONFLICT (platform_id) DO NOTHING`),
+		status.PlatformID, status.Fmspc, status.TcbStatus, status.UpdatedTime.UTC())
+	if err != nil {
+		return nil, errors.Wrap(err, "repository/sqldb:swap() Error saving platform TCB status")
+	}
+	if inserted, err := result.RowsAffected(); err == nil && inserted == 1 {
+		return nil, nil
+	}
+
+	query := `SELECT platform_id, fmspc, tcb_status, updated_time FROM platform_tcb_statuses WHERE platform_id = ?`
+	if r.d.dialect == DialectPostgres {
+		query += ` FOR UPDATE`
+	}
+	var previous types.PlatformTcbStatus
+	err = tx.QueryRow(r.d.dialect.rebind(query), status.PlatformID).Scan(&previous.PlatformID, &previous.Fmspc,
+		&previous.TcbStatus, &previous.UpdatedTime)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository/sqldb:swap() Error reading platform TCB status")
+	}
+	previous.UpdatedTime = previous.UpdatedTime.UTC()
+	_, err = tx.Exec(r.d.dialect.rebind(`UPDATE platform_tcb_statuses SET fmspc = ?, tcb_status = ?, updated_time = ?
+		WHERE platform_id = ?`), status.Fmspc, status.TcbStatus, status.UpdatedTime.UTC(), status.PlatformID)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository/sqldb:swap() Error saving platform TCB status")
+	}
+	return &previous, nil
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package sqldb
+
+import (
+	"database/sql"
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/types"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type revocationRepository struct {
+	d *Database
+}
+
+const revocationColumns = `id, reason, revoked_time, issued_after, issued_before, key_id, quote_hashes`
+
+// revocationColumnNames maps the JSON names of revocation fields to their columns
+var revocationColumnNames = map[string]string{
+	"keyId":       "key_id",
+	"revokedTime": "revoked_time",
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+func timeOf(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	utc := t.Time.UTC()
+	return &utc
+}
+
+func (r *revocationRepository) Create(revocation *types.Revocation) error {
+	_, err := r.d.exec(`INSERT INTO revocations (id, reason, revoked_time, issued_after, issued_before, key_id,
+		quote_hashes) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		revocation.ID, revocation.Reason, revocation.RevokedTime.UTC(), nullTime(revocation.IssuedAfter),
+		nullTime(revocation.IssuedBefore), revocation.KeyID, strings.Join(revocation.QuoteHashes, ","))
+	if err != nil {
+		return errors.Wrap(err, "repository/sqldb:Create() Error saving revocation")
+	}
+	return nil
+}
+
+func (r *revocationRepository) RetrieveAll() (types.Revocations, error) {
+	rows, err := r.d.query(`SELECT ` + revocationColumns + ` FROM revocations ORDER BY revoked_time, id`)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository/sqldb:RetrieveAll() Error reading revocations")
+	}
+	defer func() {
+		derr := rows.Close()
+		if derr != nil {
+			log.WithError(derr).Error("Error closing rows")
+		}
+	}()
+	return scanRevocations(rows)
+}
+
+func (r *revocationRepository) Search(criteria repository.ListCriteria) (types.Revocations, int, error) {
+	rows, total, err := r.d.search("revocations", revocationColumns, revocationColumnNames, "revoked_time, id",
+		criteria)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		derr := rows.Close()
+		if derr != nil {
+			log.WithError(derr).Error("Error closing rows")
+		}
+	}()
+	revocations, err := scanRevocations(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return revocations, total, nil
+}
+
+func scanRevocations(rows *sql.Rows) (types.Revocations, error) {
+	revocations := types.Revocations{}
+	for rows.Next() {
+		var revocation types.Revocation
+		var issuedAfter, issuedBefore sql.NullTime
+		var quoteHashes string
+		err := rows.Scan(&revocation.ID, &revocation.Reason, &revocation.RevokedTime, &issuedAfter, &issuedBefore,
+			&revocation.KeyID, &quoteHashes)
+		if err != nil {
+			return nil, errors.Wrap(err, "repository/sqldb:scanRevocations() Error reading revocation")
+		}
+		revocation.RevokedTime = revocation.RevokedTime.UTC()
+		revocation.IssuedAfter, revocation.IssuedBefore = timeOf(issuedAfter), timeOf(issuedBefore)
+		if quoteHashes != "" {
+			revocation.QuoteHashes = strings.Split(quoteHashes, ",")
+		}
+		revocations = append(revocations, revocation)
+	}
+	return revocations, rows.Err()
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package sqldb
+
+import (
+	"database/sql"
+	"fmt"
+	commLog "intel/isecl/lib/common/v4/log"
+	"intel/isecl/sqvs/v4/repository"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var log = commLog.GetDefaultLogger()
+
+// Dialect holds the differences between the SQL databases supported by the storage drivers
+type Dialect int
+
+const (
+	DialectSQLite Dialect = iota
+	DialectPostgres
+)
+
+// rebind replaces the ? bind parameters of a query with the parameters of the dialect
+func (d Dialect) rebind(query string) string {
+	if d != DialectPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// limit returns the LIMIT and OFFSET clause of a page, a non-positive limit selecting all remaining rows
+func (d Dialect) limit(limit, offset int) string {
+	switch {
+	case limit > 0:
+		return fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
+	case offset == 0:
+		return ""
+	case d == DialectSQLite:
+		return fmt.Sprintf(" LIMIT -1 OFFSET %d", offset)
+	}
+	return fmt.Sprintf(" OFFSET %d", offset)
+}
+
+// Database stores SQVS records in an SQL database through database/sql
+type Database struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+// New wraps an open database, creating its schema when it is empty. Databases whose schema is at another
+// version than LatestSchemaVersion are refused, they are migrated with NewMigrator.
+func New(db *sql.DB, dialect Dialect) (*Database, error) {
+	d := &Database{db: db, dialect: dialect}
+	err := d.createVersionTable()
+	if err != nil {
+		return nil, err
+	}
+	err = d.checkSchema()
+	if err != nil {
+		return nil, errors.Wrap(err, "repository/sqldb:New() Error checking database schema")
+	}
+	return d, nil
+}
+
+// NewMigrator wraps an open database whatever the version of its schema, to migrate it
+func NewMigrator(db *sql.DB, dialect Dialect) (*Database, error) {
+	d := &Database{db: db, dialect: dialect}
+	err := d.createVersionTable()
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Database) PlatformTcbStatusRepository() repository.PlatformTcbStatusRepository {
+	return &platformTcbStatusRepository{d: d}
+}
+
+func (d *Database) VerificationRepository() repository.VerificationRepository {
+	return &verificationRepository{d: d}
+}
+
+func (d *Database) UsageRepository() repository.UsageRepository {
+	return &usageRepository{d: d}
+}
+
+func (d *Database) EnrolledPlatformRepository() repository.EnrolledPlatformRepository {
+	return &enrolledPlatformRepository{d: d}
+}
+
+func (d *Database) RevocationRepository() repository.RevocationRepository {
+	return &revocationRepository{d: d}
+}
+
+func (d *Database) CollateralSnapshotRepository() repository.CollateralSnapshotRepository {
+	return &collateralSnapshotRepository{d: d}
+}
+
+func (d *Database) EnclaveIdentityRepository() repository.EnclaveIdentityRepository {
+	return &enclaveIdentityRepository{d: d}
+}
+
+func (d *Database) UsedTokenRepository() repository.UsedTokenRepository {
+	return &usedTokenRepository{d: d}
+}
+
+func (d *Database) Close() {
+	if err := d.db.Close(); err != nil {
+		log.WithError(err).Error("repository/sqldb:Close() Error closing database")
+	}
+}
+
+func (d *Database) exec(query string, args ...interface{}) (sql.Result, error) {
+	return d.db.Exec(d.dialect.rebind(query), args...)
+}
+
+func (d *Database) query(query string, args ...interface{}) (*sql.Rows, error) {
+	return d.db.Query(d.dialect.rebind(query), args...)
+}
+
+func (d *Database) queryRow(query string, args ...interface{}) *sql.Row {
+	return d.db.QueryRow(d.dialect.rebind(query), args...)
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package sqldb
+
+import (
+	"database/sql"
+	"intel/isecl/sqvs/v4/types"
+
+	"github.com/pkg/errors"
+)
+
+type usageRepository struct {
+	d *Database
+}
+
+func (r *usageRepository) Add(tenant, route string, periods []string, delta int64) ([]int64, error) {
+	tx, err := r.d.db.Begin()
+	if err != nil {
+		return nil, errors.Wrap(err, "repository/sqldb:Add() Error starting transaction")
+	}
+	counts, err := r.add(tx, tenant, route, periods, delta)
+	if err != nil {
+		if rerr := tx.Rollback(); rerr != nil {
+			log.WithError(rerr).Error("repository/sqldb:Add() Error rolling back transaction")
+		}
+		return nil, err
+	}
+	return counts, errors.Wrap(tx.Commit(), "repository/sqldb:Add() Error committing usage")
+}
+
+func (r *usageRepository) add(tx *sql.Tx, tenant, route string, periods []string, delta int64) ([]int64, error) {
+	counts := make([]int64, len(periods))
+	for i, period := range periods {
+		// the upsert locks the row until the transaction ends, the count read back is the updated one
+		_, err := tx.Exec(r.d.dialect.rebind(`INSERT INTO usages (tenant, route, period, count) VALUES (?, ?, ?, ?)
+			ON CONFLICT (tenant, route, period) DO UPDATE SET count = usages.count + excluded.count`),
+			tenant, route, period, delta)
+		if err != nil {
+			return nil, errors.Wrap(err, "repository/sqldb:add() Error updating usage")
+		}
+		err = tx.QueryRow(r.d.dialect.rebind(`SELECT count FROM usages WHERE tenant = ? AND route = ? AND period = ?`),
+			tenant, route, period).Scan(&counts[i])
+		if err != nil {
+			return nil, errors.Wrap(err, "repository/sqldb:add() Error reading usage")
+		}
+	}
+	return counts, nil
+}
+
+func (r *usageRepository) Search(tenant, period string) (types.Usages, error) {
+	query := `SELECT tenant, route, period, count FROM usages WHERE period = ?`
+	args := []interface{}{period}
+	if tenant != "" {
+		query += ` AND tenant = ?`
+		args = append(args, tenant)
+	}
+	rows, err := r.d.query(query+` ORDER BY tenant, route`, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository/sqldb:Search() Error reading usages")
+	}
+	defer func() {
+		derr := rows.Close()
+		if derr != nil {
+			log.WithError(derr).Error("Error closing rows")
+		}
+	}()
+
+	usages := types.Usages{}
+	for rows.Next() {
+		var usage types.Usage
+		err = rows.Scan(&usage.Tenant, &usage.Route, &usage.Period, &usage.Count)
+		if err != nil {
+			return nil, errors.Wrap(err, "repository/sqldb:Search() Error reading usage")
+		}
+		usages = append(usages, usage)
+	}
+	return usages, rows.Err()
+}
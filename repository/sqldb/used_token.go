@@ -0,0 +1,37 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package sqldb
+
+import (
+	"intel/isecl/sqvs/v4/repository"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type usedTokenRepository struct {
+	d *Database
+}
+
+func (r *usedTokenRepository) Use(id string, expires time.Time) error {
+	_, err := r.d.exec(`DELETE FROM used_tokens WHERE expires_time < ?`, time.Now().UTC())
+	if err != nil {
+		return errors.Wrap(err, "repository/sqldb:Use() Error deleting expired tokens")
+	}
+	// the replicas sharing the database race on the primary key, a single one records the use
+	result, err := r.d.exec(`INSERT INTO used_tokens (id, expires_time) VALUES (?, ?) ON CONFLICT (id) DO NOTHING`,
+		id, expires.UTC())
+	if err != nil {
+		return errors.Wrap(err, "repository/sqldb:Use() Error recording token use")
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "repository/sqldb:Use() Error recording token use")
+	}
+	if inserted == 0 {
+		return repository.ErrTokenUsed
+	}
+	return nil
+}
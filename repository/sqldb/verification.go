@@ -0,0 +1,203 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package sqldb
+
+import (
+	"database/sql"
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/types"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const verificationColumns = `id, created_time, status, message, enclave_issuer, enclave_measurement,
+	enclave_issuer_prod_id, isv_svn, tcb_level, enclave_debug_mode, reverified_from, quote,
+	relying_party, request_signature`
+
+// verificationColumnNames maps the JSON names of verification fields to their columns
+var verificationColumnNames = map[string]string{
+	"id":                  "id",
+	"createdTime":         "created_time",
+	"status":              "status",
+	"enclaveIssuer":       "enclave_issuer",
+	"enclaveMeasurement":  "enclave_measurement",
+	"enclaveIssuerProdId": "enclave_issuer_prod_id",
+	"isvSvn":              "isv_svn",
+	"tcbLevel":            "tcb_level",
+	"enclaveDebugMode":    "enclave_debug_mode",
+	"reverifiedFrom":      "reverified_from",
+	"relyingParty":        "relying_party",
+}
+
+type verificationRepository struct {
+	d *Database
+}
+
+func (r *verificationRepository) Create(v *types.Verification) error {
+	_, err := r.d.exec(`INSERT INTO verifications (`+verificationColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		v.ID, v.CreatedTime.UTC(), v.Status, v.Message, v.EnclaveIssuer, v.EnclaveMeasurement, v.EnclaveIssuerProdID,
+		v.IsvSvn, v.TcbLevel, v.EnclaveDebugMode, v.ReverifiedFrom, v.Quote, v.RelyingParty,
+		v.RequestSignature)
+	if err != nil {
+		return errors.Wrap(err, "repository/sqldb:Create() Error inserting verification")
+	}
+	return nil
+}
+
+func (r *verificationRepository) Retrieve(id string) (*types.Verification, error) {
+	row := r.d.queryRow(`SELECT `+verificationColumns+` FROM verifications WHERE id = ?`, id)
+	verification, err := scanVerification(row)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrRecordNotFound
+	} else if err != nil {
+		return nil, errors.Wrap(err, "repository/sqldb:Retrieve() Error reading verification")
+	}
+	return verification, nil
+}
+
+func (r *verificationRepository) Search(criteria repository.ListCriteria) (types.Verifications, int, error) {
+	names := make([]string, 0, len(criteria.Filters))
+	for name := range criteria.Filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var conditions []string
+	var args []interface{}
+	for _, name := range names {
+		column, ok := verificationColumnNames[name]
+		if !ok {
+			return nil, 0, errors.Errorf("repository/sqldb:Search() Unknown verification field %s", name)
+		}
+		conditions = append(conditions, column+" = ?")
+		if column == "enclave_debug_mode" {
+			value, err := strconv.ParseBool(criteria.Filters[name])
+			if err != nil {
+				return types.Verifications{}, 0, nil
+			}
+			args = append(args, value)
+		} else {
+			args = append(args, criteria.Filters[name])
+		}
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	err := r.d.queryRow(`SELECT COUNT(*) FROM verifications`+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "repository/sqldb:Search() Error counting verifications")
+	}
+
+	// ties are broken by insertion time so paging is stable, as with the in-memory store
+	order := " ORDER BY created_time, id"
+	if criteria.SortBy != "" {
+		column, ok := verificationColumnNames[criteria.SortBy]
+		if !ok {
+			return nil, 0, errors.Errorf("repository/sqldb:Search() Unknown verification field %s", criteria.SortBy)
+		}
+		if criteria.SortDescending {
+			column += " DESC"
+		}
+		order = " ORDER BY " + column + ", created_time, id"
+	}
+
+	rows, err := r.d.query(`SELECT `+verificationColumns+` FROM verifications`+where+order+
+		r.d.dialect.limit(criteria.Limit, criteria.Offset), args...)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "repository/sqldb:Search() Error searching verifications")
+	}
+	verifications, err := scanVerifications(rows)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "repository/sqldb:Search() Error reading verifications")
+	}
+	return verifications, total, nil
+}
+
+func (r *verificationRepository) Expired(before time.Time, maxRecords int) (types.Verifications, error) {
+	query := `SELECT ` + verificationColumns + ` FROM verifications WHERE created_time < ?`
+	args := []interface{}{before.UTC()}
+	if maxRecords > 0 {
+		query += ` OR id NOT IN (SELECT id FROM verifications ORDER BY created_time DESC, id DESC LIMIT ?)`
+		args = append(args, maxRecords)
+	}
+	rows, err := r.d.query(query+` ORDER BY created_time, id`, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository/sqldb:Expired() Error selecting expired verifications")
+	}
+	verifications, err := scanVerifications(rows)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository/sqldb:Expired() Error reading verifications")
+	}
+	return verifications, nil
+}
+
+func (r *verificationRepository) Delete(ids []string) (int, error) {
+	deleted := 0
+	// delete in batches to stay below the bind parameter limits of the databases
+	const batchSize = 500
+	for start := 0; start < len(ids); start += batchSize {
+		end := start + batchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batch := ids[start:end]
+		args := make([]interface{}, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(batch)), ", ")
+		result, err := r.d.exec(`DELETE FROM verifications WHERE id IN (`+placeholders+`)`, args...)
+		if err != nil {
+			return deleted, errors.Wrap(err, "repository/sqldb:Delete() Error deleting verifications")
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return deleted, errors.Wrap(err, "repository/sqldb:Delete() Error reading deleted verification count")
+		}
+		deleted += int(n)
+	}
+	return deleted, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanVerification(row rowScanner) (*types.Verification, error) {
+	var v types.Verification
+	err := row.Scan(&v.ID, &v.CreatedTime, &v.Status, &v.Message, &v.EnclaveIssuer, &v.EnclaveMeasurement,
+		&v.EnclaveIssuerProdID, &v.IsvSvn, &v.TcbLevel, &v.EnclaveDebugMode, &v.ReverifiedFrom, &v.Quote,
+		&v.RelyingParty, &v.RequestSignature)
+	if err != nil {
+		return nil, err
+	}
+	v.CreatedTime = v.CreatedTime.UTC()
+	return &v, nil
+}
+
+func scanVerifications(rows *sql.Rows) (types.Verifications, error) {
+	defer func() {
+		derr := rows.Close()
+		if derr != nil {
+			log.WithError(derr).Error("Error closing rows")
+		}
+	}()
+	verifications := types.Verifications{}
+	for rows.Next() {
+		verification, err := scanVerification(rows)
+		if err != nil {
+			return nil, err
+		}
+		verifications = append(verifications, *verification)
+	}
+	return verifications, rows.Err()
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package sqlite
+
+import (
+	"database/sql"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/repository/sqldb"
+
+	// registers the sqlite3 database/sql driver
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	repository.RegisterDriver(repository.DriverSQLite, Open)
+	repository.RegisterMigrator(repository.DriverSQLite, OpenMigrator)
+}
+
+// Open opens the configured SQLite database file, the storage driver of single node edge installs
+func Open(conf config.DatabaseConfig) (repository.SQVSDatabase, error) {
+	file := conf.File
+	if file == "" {
+		file = constants.DefaultSQLiteFile
+	}
+	return OpenFile(file)
+}
+
+// OpenFile opens an SQLite database file, creating it when it does not exist
+func OpenFile(file string) (*sqldb.Database, error) {
+	return openFile(file, sqldb.New)
+}
+
+// OpenMigrator opens the configured SQLite database file to migrate its schema
+func OpenMigrator(conf config.DatabaseConfig) (repository.Migrator, error) {
+	file := conf.File
+	if file == "" {
+		file = constants.DefaultSQLiteFile
+	}
+	return openFile(file, sqldb.NewMigrator)
+}
+
+func openFile(file string, wrap func(*sql.DB, sqldb.Dialect) (*sqldb.Database, error)) (*sqldb.Database, error) {
+	db, err := sql.Open("sqlite3", "file:"+file+"?_busy_timeout=5000&_foreign_keys=on")
+	if err != nil {
+		return nil, errors.Wrap(err, "repository/sqlite:openFile() Error opening database")
+	}
+	// SQLite allows a single writer, serialize access instead of failing on a locked database
+	db.SetMaxOpenConns(1)
+	d, err := wrap(db, sqldb.DialectSQLite)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return d, nil
+}
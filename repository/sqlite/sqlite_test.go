@@ -0,0 +1,258 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package sqlite
+
+import (
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/repository/sqldb"
+	"intel/isecl/sqvs/v4/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteRepositories(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqvs-sqlite")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "sqvs.db")
+
+	db, err := OpenFile(file)
+	assert.NoError(t, err)
+	repo := db.VerificationRepository()
+	now := time.Now().UTC().Truncate(time.Second)
+	for i := 0; i < 5; i++ {
+		status := types.VerificationStatusVerified
+		if i%2 == 1 {
+			status = types.VerificationStatusFailed
+		}
+		assert.NoError(t, repo.Create(&types.Verification{
+			ID:          strconv.Itoa(i),
+			CreatedTime: now.Add(time.Duration(i) * time.Hour),
+			Status:      status,
+		}))
+	}
+
+	verifications, total, err := repo.Search(repository.ListCriteria{
+		Filters:        map[string]string{"status": types.VerificationStatusVerified},
+		SortBy:         "createdTime",
+		SortDescending: true,
+		Offset:         1,
+		Limit:          1,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+	if assert.Len(t, verifications, 1) {
+		assert.Equal(t, "2", verifications[0].ID)
+		assert.True(t, verifications[0].CreatedTime.Equal(now.Add(2*time.Hour)))
+	}
+
+	expired, err := repo.Expired(now.Add(time.Hour), 3)
+	assert.NoError(t, err)
+	assert.Len(t, expired, 2)
+	deleted, err := repo.Delete([]string{expired[0].ID, expired[1].ID})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	_, err = repo.Retrieve("0")
+	assert.Equal(t, repository.ErrRecordNotFound, err)
+
+	statuses := db.PlatformTcbStatusRepository()
+	assert.NoError(t, statuses.Save(&types.PlatformTcbStatus{PlatformID: "p1", Fmspc: "00906ea10000", TcbStatus: "UpToDate", UpdatedTime: now}))
+	previous, err := statuses.Swap(&types.PlatformTcbStatus{PlatformID: "p1", Fmspc: "00906ea10000", TcbStatus: "OutOfDate", UpdatedTime: now})
+	assert.NoError(t, err)
+	assert.Equal(t, "UpToDate", previous.TcbStatus)
+	previous, err = statuses.Swap(&types.PlatformTcbStatus{PlatformID: "p2", Fmspc: "00906ea10000", TcbStatus: "UpToDate", UpdatedTime: now})
+	assert.NoError(t, err)
+	assert.Nil(t, previous)
+	db.Close()
+
+	// reopening must not migrate the schema again
+	db, err = OpenFile(file)
+	assert.NoError(t, err)
+	defer db.Close()
+	status, err := db.PlatformTcbStatusRepository().Retrieve("p1")
+	assert.NoError(t, err)
+	assert.Equal(t, "OutOfDate", status.TcbStatus)
+	_, total, err = db.VerificationRepository().Search(repository.ListCriteria{})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+
+	usages := db.UsageRepository()
+	_, err = usages.Add("team-a", "/svs/v1/sgx_qv_verify_quote", []string{"2021-07-14", "2021-07"}, 1)
+	assert.NoError(t, err)
+	counts, err := usages.Add("team-a", "/svs/v1/sgx_qv_verify_quote", []string{"2021-07-14", "2021-07"}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 2}, counts)
+	counts, err = usages.Add("team-a", "/svs/v1/sgx_qv_verify_quote", []string{"2021-07"}, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1}, counts)
+	_, err = usages.Add("team-b", "/svs/v1/sgx_qv_verify_quote", []string{"2021-07"}, 1)
+	assert.NoError(t, err)
+	monthly, err := usages.Search("", "2021-07")
+	assert.NoError(t, err)
+	assert.Len(t, monthly, 2)
+	monthly, err = usages.Search("team-a", "2021-07")
+	assert.NoError(t, err)
+	if assert.Len(t, monthly, 1) {
+		assert.Equal(t, int64(1), monthly[0].Count)
+	}
+
+	platforms := db.EnrolledPlatformRepository()
+	assert.NoError(t, platforms.Save(&types.EnrolledPlatform{Fmspc: "00906ea10000", PceID: "0000", EnrolledTime: now}))
+	assert.NoError(t, platforms.Save(&types.EnrolledPlatform{Fmspc: "00906ea10000", PceID: "0000", EnrolledTime: now,
+		TcbInfo: "{}", TcbInfoNextUpdate: now.Add(time.Hour), PinnedTime: now}))
+	platform, err := platforms.Retrieve("00906ea10000", "0000")
+	assert.NoError(t, err)
+	assert.Equal(t, "{}", platform.TcbInfo)
+	assert.True(t, now.Add(time.Hour).Equal(platform.TcbInfoNextUpdate))
+	all, err := platforms.RetrieveAll()
+	assert.NoError(t, err)
+	assert.Len(t, all, 1)
+	assert.NoError(t, platforms.Save(&types.EnrolledPlatform{Fmspc: "00606a000000", PceID: "0000", EnrolledTime: now}))
+	all, total, err = platforms.Search(repository.ListCriteria{Filters: map[string]string{"pceId": "0000"},
+		SortBy: "fmspc", SortDescending: true, Limit: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	if assert.Len(t, all, 1) {
+		assert.Equal(t, "00906ea10000", all[0].Fmspc)
+	}
+	assert.NoError(t, platforms.Delete("00606a000000", "0000"))
+	assert.NoError(t, platforms.Delete("00906ea10000", "0000"))
+	assert.Equal(t, repository.ErrRecordNotFound, platforms.Delete("00906ea10000", "0000"))
+
+	enclaves := db.EnclaveIdentityRepository()
+	mrEnclave := strings.Repeat("ab", 32)
+	mrSigner := strings.Repeat("cd", 32)
+	assert.NoError(t, enclaves.Save(&types.EnclaveIdentity{MrEnclave: mrEnclave, MrSigner: mrSigner, IsvSvn: 1,
+		RegisteredTime: now}))
+	assert.NoError(t, enclaves.Save(&types.EnclaveIdentity{MrEnclave: mrEnclave, MrSigner: mrSigner, IsvProdID: 2,
+		IsvSvn: 3, Attributes: "0700", AttributesMask: "ff00", RegisteredTime: now}))
+	enclave, err := enclaves.Retrieve(mrEnclave, mrSigner)
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(3), enclave.IsvSvn)
+	assert.Equal(t, "ff00", enclave.AttributesMask)
+	registered, err := enclaves.RetrieveAll()
+	assert.NoError(t, err)
+	assert.Len(t, registered, 1)
+	registered, total, err = enclaves.Search(repository.ListCriteria{Filters: map[string]string{"mrSigner": mrSigner},
+		SortBy: "registeredTime", Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Len(t, registered, 1)
+	assert.NoError(t, enclaves.Delete(mrEnclave, mrSigner))
+	_, err = enclaves.Retrieve(mrEnclave, mrSigner)
+	assert.Equal(t, repository.ErrRecordNotFound, err)
+
+	revocations := db.RevocationRepository()
+	issuedBefore := now.Add(-time.Hour)
+	assert.NoError(t, revocations.Create(&types.Revocation{ID: "r1", Reason: "signing key compromised",
+		RevokedTime: now, IssuedBefore: &issuedBefore, KeyID: "key-1"}))
+	assert.NoError(t, revocations.Create(&types.Revocation{ID: "r2", Reason: "bad collateral",
+		RevokedTime: now.Add(time.Second), QuoteHashes: []string{"aa", "bb"}}))
+	revoked, err := revocations.RetrieveAll()
+	assert.NoError(t, err)
+	if assert.Len(t, revoked, 2) {
+		assert.Nil(t, revoked[0].IssuedAfter)
+		assert.True(t, issuedBefore.Equal(*revoked[0].IssuedBefore))
+		assert.Equal(t, []string{"aa", "bb"}, revoked[1].QuoteHashes)
+	}
+	revoked, total, err = revocations.Search(repository.ListCriteria{SortBy: "revokedTime", SortDescending: true,
+		Limit: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	if assert.Len(t, revoked, 1) {
+		assert.Equal(t, "r2", revoked[0].ID)
+	}
+	revoked, total, err = revocations.Search(repository.ListCriteria{Filters: map[string]string{"keyId": "key-1"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Len(t, revoked, 1)
+
+	snapshots := db.CollateralSnapshotRepository()
+	for i, issued := range []time.Time{now.Add(-48 * time.Hour), now.Add(-24 * time.Hour)} {
+		snapshot := &types.CollateralSnapshot{ID: "s" + strconv.Itoa(i), Collateral: "tcb_info", Fmspc: "00906ea10000",
+			TcbEvaluationDataNumber: uint(10 + i), IssueDate: issued, NextUpdate: issued.Add(30 * 24 * time.Hour),
+			Content: "{}", RecordedTime: now}
+		assert.NoError(t, snapshots.Save(snapshot))
+		// the same collateral is recorded once
+		assert.NoError(t, snapshots.Save(snapshot))
+	}
+	snapshot, err := snapshots.RetrieveAt("tcb_info", "00906ea10000", now.Add(-30*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, uint(10), snapshot.TcbEvaluationDataNumber)
+	snapshot, err = snapshots.RetrieveAt("tcb_info", "00906ea10000", now)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(11), snapshot.TcbEvaluationDataNumber)
+	assert.True(t, now.Add(-24*time.Hour).Equal(snapshot.IssueDate))
+	_, err = snapshots.RetrieveAt("tcb_info", "00906ea10000", now.Add(-72*time.Hour))
+	assert.Equal(t, repository.ErrRecordNotFound, err)
+	snapshot, err = snapshots.RetrieveVersion("tcb_info", "00906ea10000", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, "s0", snapshot.ID)
+	_, err = snapshots.RetrieveVersion("qe_identity", "", 10)
+	assert.Equal(t, repository.ErrRecordNotFound, err)
+
+	usedTokens := db.UsedTokenRepository()
+	assert.NoError(t, usedTokens.Use("t1", time.Now().Add(time.Hour)))
+	assert.Equal(t, repository.ErrTokenUsed, usedTokens.Use("t1", time.Now().Add(time.Hour)))
+	// the uses of expired tokens are forgotten
+	assert.NoError(t, usedTokens.Use("t2", time.Now().Add(-time.Second)))
+	assert.NoError(t, usedTokens.Use("t2", time.Now().Add(time.Hour)))
+}
+
+func TestSQLiteMigrations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqvs-sqlite")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "sqvs.db")
+
+	// the schema of an empty database is created
+	db, err := OpenFile(file)
+	assert.NoError(t, err)
+	assert.NoError(t, db.VerificationRepository().Create(&types.Verification{ID: "1", CreatedTime: time.Now(),
+		Status: types.VerificationStatusVerified, ReverifiedFrom: "0", RelyingParty: "rp1"}))
+	latest, err := db.SchemaVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, sqldb.LatestSchemaVersion(), latest)
+	db.Close()
+
+	migrator, err := OpenMigrator(config.DatabaseConfig{File: file})
+	assert.NoError(t, err)
+	// reverting the relying party request signatures drops the columns added to the verifications
+	assert.NoError(t, migrator.Migrate(latest-3))
+	migrations, err := migrator.Migrations()
+	assert.NoError(t, err)
+	assert.True(t, migrations[latest-4].Applied)
+	assert.False(t, migrations[latest-3].Applied)
+	assert.False(t, migrations[latest-2].Applied)
+	assert.False(t, migrations[latest-1].Applied)
+	migrator.Close()
+
+	// the server refuses databases at another schema version
+	_, err = OpenFile(file)
+	assert.Equal(t, repository.ErrSchemaVersion, errors.Cause(err))
+
+	migrator, err = OpenMigrator(config.DatabaseConfig{File: file})
+	assert.NoError(t, err)
+	assert.NoError(t, migrator.Migrate(latest))
+	assert.Error(t, migrator.Migrate(latest+1))
+	migrator.Close()
+
+	db, err = OpenFile(file)
+	assert.NoError(t, err)
+	defer db.Close()
+	verification, err := db.VerificationRepository().Retrieve("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "0", verification.ReverifiedFrom)
+	assert.Empty(t, verification.RelyingParty)
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resilience
+
+import (
+	"sync"
+	"time"
+)
+
+// Circuit breaker states, the values are exported as the breaker state metric
+const (
+	StateClosed   = 0
+	StateHalfOpen = 1
+	StateOpen     = 2
+)
+
+// breaker stops calls to a host, or to a scope of a host, after consecutive failures. Once openDuration has
+// passed a single probe call is let through, its outcome closes the breaker again or keeps it open.
+type breaker struct {
+	host         string
+	threshold    int
+	openDuration time.Duration
+
+	mu       sync.Mutex
+	state    int
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newBreaker(host string, threshold int, openDuration time.Duration) *breaker {
+	b := &breaker{host: host, threshold: threshold, openDuration: openDuration}
+	breakerState.Set(StateClosed, host)
+	return b
+}
+
+// allow reports whether a call may be made to the host
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if time.Since(b.openedAt) < b.openDuration {
+			return false
+		}
+		b.setState(StateHalfOpen)
+		b.probing = true
+		return true
+	case StateHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// record updates the breaker with the outcome of a call
+func (b *breaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if success {
+		b.failures = 0
+		if b.state != StateClosed {
+			log.Infof("resilience/breaker:record() Circuit breaker for %s closed", b.host)
+			b.setState(StateClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == StateHalfOpen || (b.threshold > 0 && b.failures >= b.threshold) {
+		if b.state != StateOpen {
+			log.Warnf("resilience/breaker:record() Circuit breaker for %s opened after %d consecutive failures",
+				b.host, b.failures)
+		}
+		b.openedAt = time.Now()
+		b.setState(StateOpen)
+	}
+}
+
+func (b *breaker) currentState() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// setState changes the breaker state, callers must hold b.mu
+func (b *breaker) setState(state int) {
+	b.state = state
+	breakerState.Set(float64(state), b.host)
+}
+
+// retryBudget caps retries to a ratio of the calls made. Every call deposits ratio tokens, up to
+// maxTokens, and every retry spends one token, so an outage cannot multiply the load on a host.
+type retryBudget struct {
+	ratio     float64
+	maxTokens float64
+
+	mu     sync.Mutex
+	tokens float64
+}
+
+func newRetryBudget(ratio float64) *retryBudget {
+	maxTokens := 10.0
+	return &retryBudget{ratio: ratio, maxTokens: maxTokens, tokens: maxTokens}
+}
+
+func (r *retryBudget) deposit() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens += r.ratio
+	if r.tokens > r.maxTokens {
+		r.tokens = r.maxTokens
+	}
+}
+
+func (r *retryBudget) withdraw() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tokens < 1 {
+		return false
+	}
+	r.tokens--
+	return true
+}
@@ -0,0 +1,275 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resilience
+
+import (
+	"context"
+	commLog "intel/isecl/lib/common/v4/log"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/metrics"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var log = commLog.GetDefaultLogger()
+
+var (
+	requestsTotal = metrics.NewCounter("sqvs_outbound_requests_total",
+		"Outbound requests by host and outcome (success, failure, canceled by the caller or rejected by an open "+
+			"circuit breaker)", "host", "outcome")
+	retriesTotal = metrics.NewCounter("sqvs_outbound_retries_total", "Outbound request retries by host", "host")
+	breakerState = metrics.NewGauge("sqvs_outbound_circuit_breaker_state",
+		"Outbound circuit breaker state by host, or host/scope for the breakers of scoped requests, 0 closed, "+
+			"1 half-open, 2 open", "host")
+)
+
+// ErrCircuitOpen is returned without contacting the host while its circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+var (
+	defaultMu     sync.RWMutex
+	defaultPolicy = NewPolicy(config.OutboundConfig{})
+)
+
+// Default returns the policy shared by all collateral fetches
+func Default() *Policy {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultPolicy
+}
+
+// SetDefault replaces the policy shared by all collateral fetches
+func SetDefault(p *Policy) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultPolicy = p
+}
+
+// Policy holds the retry settings, the retry budget and the per host circuit breakers shared by the
+// clients created from it
+type Policy struct {
+	conf     config.OutboundConfig
+	budget   *retryBudget
+	retryLog *retryLog
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+	stats    map[string]*hostStats
+}
+
+// NewPolicy creates a policy from the configuration, unset values take their defaults
+func NewPolicy(conf config.OutboundConfig) *Policy {
+	if conf.MaxAttempts <= 0 {
+		conf.MaxAttempts = constants.DefaultOutboundMaxAttempts
+	}
+	if conf.InitialBackoff <= 0 {
+		conf.InitialBackoff = constants.DefaultOutboundInitialBackoff
+	}
+	if conf.MaxBackoff <= 0 {
+		conf.MaxBackoff = constants.DefaultOutboundMaxBackoff
+	}
+	if conf.AttemptTimeout <= 0 {
+		conf.AttemptTimeout = constants.DefaultOutboundAttemptTimeout
+	}
+	if conf.RetryBudgetRatio <= 0 {
+		conf.RetryBudgetRatio = constants.DefaultOutboundRetryBudgetRatio
+	}
+	if conf.BreakerFailureThreshold <= 0 {
+		conf.BreakerFailureThreshold = constants.DefaultOutboundBreakerThreshold
+	}
+	if conf.BreakerOpenDuration <= 0 {
+		conf.BreakerOpenDuration = constants.DefaultOutboundBreakerOpenDuration
+	}
+	if conf.RetryLogInterval <= 0 {
+		conf.RetryLogInterval = constants.DefaultOutboundRetryLogInterval
+	}
+	return &Policy{
+		conf:     conf,
+		budget:   newRetryBudget(conf.RetryBudgetRatio),
+		retryLog: newRetryLog(conf.RetryLogInterval),
+		breakers: make(map[string]*breaker),
+		stats:    make(map[string]*hostStats),
+	}
+}
+
+type scopeKey struct{}
+
+// WithScope scopes the requests made with ctx to a circuit breaker of their own for each host, named by scope,
+// such as fmspc=<FMSPC> for the collateral of a platform family, so the failures of the requests of one scope
+// do not stop those of the others. Scoped requests do not count toward the breaker of the host.
+func WithScope(ctx context.Context, scope string) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// breakerName returns the name of the breaker of the requests to host made with ctx, host/scope for scoped
+// requests
+func breakerName(ctx context.Context, host string) string {
+	if scope, _ := ctx.Value(scopeKey{}).(string); scope != "" {
+		return host + "/" + scope
+	}
+	return host
+}
+
+// OpenScopes returns the sorted scopes of host whose circuit breakers are open or half-open
+func (p *Policy) OpenScopes(host string) []string {
+	p.mu.Lock()
+	breakers := make(map[string]*breaker)
+	for name, b := range p.breakers {
+		if strings.HasPrefix(name, host+"/") {
+			breakers[strings.TrimPrefix(name, host+"/")] = b
+		}
+	}
+	p.mu.Unlock()
+
+	var scopes []string
+	for scope, b := range breakers {
+		if b.currentState() != StateClosed {
+			scopes = append(scopes, scope)
+		}
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// Client wraps an HTTP client so its requests follow the policy
+func (p *Policy) Client(client *http.Client) *Client {
+	return &Client{policy: p, client: client}
+}
+
+// BreakerState returns the circuit breaker state of a host
+func (p *Policy) BreakerState(host string) int {
+	return p.breaker(host).currentState()
+}
+
+func (p *Policy) breaker(host string) *breaker {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b, ok := p.breakers[host]
+	if !ok {
+		b = newBreaker(host, p.conf.BreakerFailureThreshold, p.conf.BreakerOpenDuration)
+		p.breakers[host] = b
+	}
+	return b
+}
+
+// backoff returns the delay before the given retry, using exponential backoff with full jitter
+func (p *Policy) backoff(retry int) time.Duration {
+	delay := p.conf.InitialBackoff << uint(retry-1)
+	if delay <= 0 || delay > p.conf.MaxBackoff {
+		delay = p.conf.MaxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// Client sends requests with a per attempt timeout, retrying idempotent requests that fail with
+// a network error, a 5xx or a 429 response while the retry budget allows
+type Client struct {
+	policy *Policy
+	client *http.Client
+}
+
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	p := c.policy
+	host := req.URL.Host
+	b := p.breaker(breakerName(req.Context(), host))
+	if !b.allow() {
+		requestsTotal.Inc(host, "rejected")
+		return nil, errors.Wrapf(ErrCircuitOpen, "resilience/resilience:Do() Not contacting %s", b.host)
+	}
+	p.budget.deposit()
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.attempt(req)
+		if err != nil && req.Context().Err() == context.Canceled {
+			// the caller gave up on the request, which tells nothing of the health of the host
+			requestsTotal.Inc(host, "canceled")
+			return nil, err
+		}
+		if !shouldRetry(req, resp, err) {
+			success := err == nil && resp.StatusCode < http.StatusInternalServerError
+			b.record(success)
+			if success {
+				requestsTotal.Inc(host, "success")
+				p.retryLog.success(host)
+			} else {
+				requestsTotal.Inc(host, "failure")
+			}
+			return resp, err
+		}
+		b.record(false)
+
+		if attempt >= p.conf.MaxAttempts || req.Context().Err() != nil || !p.budget.withdraw() || !b.allow() {
+			requestsTotal.Inc(host, "failure")
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		delay := p.backoff(attempt)
+		p.retryLog.retry(host, delay, err)
+		retriesTotal.Inc(host)
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			if req.Context().Err() == context.Canceled {
+				requestsTotal.Inc(host, "canceled")
+			} else {
+				requestsTotal.Inc(host, "failure")
+			}
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// attempt sends a single request bounded by the attempt timeout, which is released when the response body is closed
+func (c *Client) attempt(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), c.policy.conf.AttemptTimeout)
+	start := time.Now()
+	resp, err := c.client.Do(req.Clone(ctx))
+	stats := c.policy.hostStats(req.URL.Host)
+	if err != nil {
+		stats.record(time.Since(start), err)
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		stats.record(time.Since(start), errors.Errorf("%s responded %s", req.URL.Host, resp.Status))
+	} else {
+		stats.record(time.Since(start), nil)
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resilience
+
+import (
+	"context"
+	"errors"
+	"intel/isecl/sqvs/v4/config"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testPolicy() *Policy {
+	return NewPolicy(config.OutboundConfig{
+		MaxAttempts:             3,
+		InitialBackoff:          time.Millisecond,
+		MaxBackoff:              time.Millisecond,
+		RetryBudgetRatio:        10,
+		BreakerFailureThreshold: 2,
+		BreakerOpenDuration:     time.Hour,
+	})
+}
+
+func TestRetryOnServerError(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	assert.NoError(t, err)
+	resp, err := testPolicy().Client(server.Client()).Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, calls)
+}
+
+func TestCircuitBreakerOpens(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	policy := testPolicy()
+	client := policy.Client(server.Client())
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	assert.NoError(t, err)
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 2, calls)
+
+	u, _ := url.Parse(server.URL)
+	assert.Equal(t, StateOpen, policy.BreakerState(u.Host))
+	_, err = client.Do(req)
+	assert.Error(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestScopedCircuitBreaker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fmspc") == "00906ea10000" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	policy := testPolicy()
+	client := policy.Client(server.Client())
+	get := func(fmspc string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(WithScope(context.Background(), "fmspc="+fmspc), http.MethodGet,
+			server.URL+"/tcb?fmspc="+fmspc, nil)
+		assert.NoError(t, err)
+		return client.Do(req)
+	}
+	resp, err := get("00906ea10000")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	_, err = get("00906ea10000")
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+
+	// the other platform families and the unscoped requests are still served
+	resp, err = get("00606a000000")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	u, _ := url.Parse(server.URL)
+	assert.Equal(t, StateClosed, policy.BreakerState(u.Host))
+	assert.Equal(t, []string{"fmspc=00906ea10000"}, policy.HostStatus(u.Host).OpenScopes)
+}
+
+func TestCanceledRequestKeepsBreakerClosed(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	policy := testPolicy()
+	client := policy.Client(server.Client())
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		assert.NoError(t, err)
+		time.AfterFunc(5*time.Millisecond, cancel)
+		_, err = client.Do(req)
+		assert.Error(t, err)
+	}
+	u, _ := url.Parse(server.URL)
+	assert.Equal(t, StateClosed, policy.BreakerState(u.Host))
+}
+
+func TestHostStatus(t *testing.T) {
+	fail := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	policy := testPolicy()
+	client := policy.Client(server.Client())
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	assert.NoError(t, err)
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	u, _ := url.Parse(server.URL)
+	status := policy.HostStatus(u.Host)
+	assert.Equal(t, "open", status.BreakerState)
+	assert.Equal(t, int64(2), status.Failures)
+	assert.Nil(t, status.LastSuccess)
+	assert.NotNil(t, status.LastFailure)
+	assert.Contains(t, status.LastError, "502")
+
+	policy = testPolicy()
+	fail = false
+	resp, err = policy.Client(server.Client()).Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	status = policy.HostStatus(u.Host)
+	assert.Equal(t, "closed", status.BreakerState)
+	assert.Equal(t, int64(1), status.Requests)
+	assert.NotNil(t, status.LastSuccess)
+	assert.True(t, status.LatencyP99 >= status.LatencyP50)
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resilience
+
+import (
+	"sync"
+	"time"
+)
+
+// retryLog logs the first retry to a host in every interval, counting the retries it does not log. The
+// count is summarized in the next retry logged or once a request to the host succeeds, so a dependency
+// failing at a high request rate logs one line per interval instead of one per retry.
+type retryLog struct {
+	interval time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*retryLogState
+}
+
+type retryLogState struct {
+	logged     time.Time
+	suppressed int
+}
+
+func newRetryLog(interval time.Duration) *retryLog {
+	return &retryLog{interval: interval, hosts: make(map[string]*retryLogState)}
+}
+
+// retry logs a retry to the host unless one was logged less than interval ago
+func (l *retryLog) retry(host string, delay time.Duration, err error) {
+	now := time.Now()
+	l.mu.Lock()
+	state, ok := l.hosts[host]
+	if !ok {
+		state = &retryLogState{}
+		l.hosts[host] = state
+	}
+	if now.Sub(state.logged) < l.interval {
+		state.suppressed++
+		l.mu.Unlock()
+		return
+	}
+	suppressed := state.suppressed
+	state.logged = now
+	state.suppressed = 0
+	l.mu.Unlock()
+
+	if suppressed > 0 {
+		log.WithError(err).Warnf("resilience/resilience:Do() Request to %s failed, retrying in %v, %d more retries "+
+			"in the last %v", host, delay, suppressed, l.interval)
+		return
+	}
+	log.WithError(err).Warnf("resilience/resilience:Do() Request to %s failed, retrying in %v", host, delay)
+}
+
+// success summarizes the retries to the host not logged yet once a request to it succeeds
+func (l *retryLog) success(host string) {
+	l.mu.Lock()
+	state, ok := l.hosts[host]
+	if !ok {
+		l.mu.Unlock()
+		return
+	}
+	suppressed := state.suppressed
+	delete(l.hosts, host)
+	l.mu.Unlock()
+
+	if suppressed > 0 {
+		log.Infof("resilience/resilience:Do() Request to %s succeeded after %d more retries since the last one "+
+			"logged", host, suppressed)
+	}
+}
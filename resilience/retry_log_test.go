@@ -0,0 +1,31 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resilience
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryLog(t *testing.T) {
+	l := newRetryLog(time.Hour)
+	err := errors.New("connection refused")
+	l.retry("scs.com:9000", time.Second, err)
+	l.retry("scs.com:9000", time.Second, err)
+	l.retry("scs.com:9000", time.Second, err)
+	l.retry("pccs.com:8081", time.Second, err)
+	assert.Equal(t, 2, l.hosts["scs.com:9000"].suppressed)
+	assert.Equal(t, 0, l.hosts["pccs.com:8081"].suppressed)
+
+	// a success summarizes and forgets the retries of the host
+	l.success("scs.com:9000")
+	_, ok := l.hosts["scs.com:9000"]
+	assert.False(t, ok)
+	l.retry("scs.com:9000", time.Second, err)
+	assert.Equal(t, 0, l.hosts["scs.com:9000"].suppressed)
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resilience
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencySamples is the number of most recent request latencies percentiles are computed over
+const latencySamples = 256
+
+// HostStatus summarizes the requests made to a host and the state of its circuit breaker. Latencies are the
+// percentiles of the most recent attempts, in milliseconds.
+type HostStatus struct {
+	Host         string     `json:"host"`
+	BreakerState string     `json:"breakerState"`
+	Requests     int64      `json:"requests"`
+	Failures     int64      `json:"failures"`
+	LastSuccess  *time.Time `json:"lastSuccess,omitempty"`
+	LastFailure  *time.Time `json:"lastFailure,omitempty"`
+	LastError    string     `json:"lastError,omitempty"`
+	LatencyP50   float64    `json:"latencyP50Ms"`
+	LatencyP90   float64    `json:"latencyP90Ms"`
+	LatencyP99   float64    `json:"latencyP99Ms"`
+	// OpenScopes are the scopes of the requests to the host whose circuit breakers are open or half-open
+	OpenScopes []string `json:"openScopes,omitempty"`
+}
+
+// StateName returns the name of a circuit breaker state
+func StateName(state int) string {
+	switch state {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// hostStats records the outcome and latency of the attempts made to a host
+type hostStats struct {
+	mu          sync.Mutex
+	requests    int64
+	failures    int64
+	lastSuccess time.Time
+	lastFailure time.Time
+	lastError   string
+	latencies   []time.Duration
+	next        int
+}
+
+func (s *hostStats) record(latency time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	now := time.Now().UTC()
+	if err == nil {
+		s.lastSuccess = now
+	} else {
+		s.failures++
+		s.lastFailure = now
+		s.lastError = err.Error()
+	}
+	if len(s.latencies) < latencySamples {
+		s.latencies = append(s.latencies, latency)
+	} else {
+		s.latencies[s.next] = latency
+	}
+	s.next = (s.next + 1) % latencySamples
+}
+
+func (s *hostStats) status(host string, state int) HostStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := HostStatus{
+		Host:         host,
+		BreakerState: StateName(state),
+		Requests:     s.requests,
+		Failures:     s.failures,
+		LastError:    s.lastError,
+	}
+	if !s.lastSuccess.IsZero() {
+		lastSuccess := s.lastSuccess
+		status.LastSuccess = &lastSuccess
+	}
+	if !s.lastFailure.IsZero() {
+		lastFailure := s.lastFailure
+		status.LastFailure = &lastFailure
+	}
+	if len(s.latencies) > 0 {
+		sorted := append([]time.Duration(nil), s.latencies...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		status.LatencyP50 = percentile(sorted, 50)
+		status.LatencyP90 = percentile(sorted, 90)
+		status.LatencyP99 = percentile(sorted, 99)
+	}
+	return status
+}
+
+// percentile returns the nearest rank percentile of sorted latencies in milliseconds
+func percentile(sorted []time.Duration, p int) float64 {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return float64(sorted[rank-1]) / float64(time.Millisecond)
+}
+
+// HostStatus returns the status of the requests made to a host through the clients of the policy
+func (p *Policy) HostStatus(host string) HostStatus {
+	status := p.hostStats(host).status(host, p.BreakerState(host))
+	status.OpenScopes = p.OpenScopes(host)
+	return status
+}
+
+func (p *Policy) hostStats(host string) *hostStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s, ok := p.stats[host]
+	if !ok {
+		s = &hostStats{}
+		p.stats[host] = s
+	}
+	return s
+}
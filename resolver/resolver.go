@@ -0,0 +1,215 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resolver
+
+import (
+	"context"
+	commLog "intel/isecl/lib/common/v4/log"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var log = commLog.GetDefaultLogger()
+
+// Resolvers the host names of the dependencies are looked up with, the one of the operating system or the
+// pure Go one
+const (
+	System = "system"
+	Go     = "go"
+)
+
+const dnsPort = "53"
+
+var current atomic.Value
+
+// Resolver looks up the host names of the dependencies SQVS connects to. Hosts pinned by a static override
+// resolve to their addresses without any lookup, the others are looked up with the system or Go resolver,
+// querying the configured servers, and their addresses are reused until the cache TTL elapses.
+type Resolver struct {
+	overrides map[string][]net.IPAddr
+	resolver  *net.Resolver
+	ttl       time.Duration
+	now       func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedAddrs
+}
+
+type cachedAddrs struct {
+	addrs   []net.IPAddr
+	expires time.Time
+}
+
+// New returns the resolver pinning the hosts of the host=address overrides and looking the others up with the
+// system or go resolver, querying the DNS servers with the go one, caching their addresses for cacheTTL. It
+// returns nil when host names are left to be resolved by the dialer for every connection.
+func New(hostOverrides []string, resolverName string, servers []string, cacheTTL time.Duration) (*Resolver, error) {
+	overrides, err := ParseOverrides(hostOverrides)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.ToLower(strings.TrimSpace(resolverName))
+	if name != "" && name != System && name != Go {
+		return nil, errors.Errorf("resolver/resolver:New() Unsupported resolver %s, must be %s or %s", resolverName,
+			System, Go)
+	}
+	if len(servers) != 0 && name != Go {
+		return nil, errors.New("resolver/resolver:New() DNS servers can only be queried with the go resolver")
+	}
+	if cacheTTL < 0 {
+		return nil, errors.New("resolver/resolver:New() DNS cache TTL must not be negative")
+	}
+	if len(overrides) == 0 && name != Go && cacheTTL == 0 {
+		return nil, nil
+	}
+
+	r := &Resolver{
+		overrides: overrides,
+		resolver:  net.DefaultResolver,
+		ttl:       cacheTTL,
+		now:       time.Now,
+		cache:     map[string]cachedAddrs{},
+	}
+	if name == Go {
+		r.resolver = &net.Resolver{PreferGo: true}
+	}
+	if len(servers) != 0 {
+		addrs, err := parseServers(servers)
+		if err != nil {
+			return nil, err
+		}
+		r.resolver.Dial = serverDialer(addrs)
+	}
+	return r, nil
+}
+
+// ParseOverrides parses the host=address static overrides, a host listed several times resolving to all its
+// addresses in order
+func ParseOverrides(entries []string) (map[string][]net.IPAddr, error) {
+	overrides := map[string][]net.IPAddr{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("resolver/resolver:ParseOverrides() Invalid host override %s, must be "+
+				"host=address", entry)
+		}
+		host := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(parts[0]), "."))
+		ip := net.ParseIP(strings.Trim(strings.TrimSpace(parts[1]), "[]"))
+		if host == "" || net.ParseIP(host) != nil || strings.ContainsAny(host, "[]:/ *") || ip == nil {
+			return nil, errors.Errorf("resolver/resolver:ParseOverrides() Invalid host override %s, must be "+
+				"host=address", entry)
+		}
+		overrides[host] = append(overrides[host], net.IPAddr{IP: ip})
+	}
+	return overrides, nil
+}
+
+func parseServers(entries []string) ([]string, error) {
+	var servers []string
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		host, port, err := net.SplitHostPort(entry)
+		if err != nil {
+			host, port = strings.Trim(entry, "[]"), dnsPort
+		}
+		if net.ParseIP(host) == nil {
+			return nil, errors.Errorf("resolver/resolver:parseServers() Invalid DNS server %s, must be an address "+
+				"with an optional port", entry)
+		}
+		servers = append(servers, net.JoinHostPort(host, port))
+	}
+	return servers, nil
+}
+
+// serverDialer returns the dial function of the Go resolver querying the servers in turn instead of those of
+// /etc/resolv.conf
+func serverDialer(servers []string) func(ctx context.Context, network, address string) (net.Conn, error) {
+	var next uint32
+	return func(ctx context.Context, network, _ string) (net.Conn, error) {
+		var dialer net.Dialer
+		var lastErr error
+		start := atomic.AddUint32(&next, 1)
+		for i := range servers {
+			conn, err := dialer.DialContext(ctx, network, servers[(int(start)+i)%len(servers)])
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
+
+// Set sets the resolver outbound connections look host names up with, the dialer resolving them when nil
+func Set(r *Resolver) {
+	current.Store(r)
+}
+
+// Current returns the resolver outbound connections look host names up with, nil when the dialer resolves them
+func Current() *Resolver {
+	r, _ := current.Load().(*Resolver)
+	return r
+}
+
+// LookupIPAddr returns the addresses of the host, its static override or the addresses looked up, cached for
+// the TTL of the resolver
+func (r *Resolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	name := strings.ToLower(strings.TrimSuffix(host, "."))
+	if addrs, ok := r.overrides[name]; ok {
+		return addrs, nil
+	}
+	if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil {
+		return []net.IPAddr{{IP: ip}}, nil
+	}
+	now := r.now()
+	if r.ttl > 0 {
+		r.mu.Lock()
+		cached, ok := r.cache[name]
+		r.mu.Unlock()
+		if ok && now.Before(cached.expires) {
+			return cached.addrs, nil
+		}
+	}
+
+	addrs, err := r.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, errors.Wrapf(err, "resolver/resolver:LookupIPAddr() Error looking up %s", host)
+	}
+	if r.ttl > 0 {
+		r.mu.Lock()
+		r.cache[name] = cachedAddrs{addrs: addrs, expires: now.Add(r.ttl)}
+		r.mu.Unlock()
+		log.Debugf("resolver/resolver:LookupIPAddr() Resolved %s to %v for %v", host, addrs, r.ttl)
+	}
+	return addrs, nil
+}
+
+// Flush drops the cached addresses, the hosts being looked up again on their next connection
+func (r *Resolver) Flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache = map[string]cachedAddrs{}
+}
+
+// LookupIPAddr returns the addresses of the host with the current resolver, with the default resolver of the
+// net package when none is set
+func LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if r := Current(); r != nil {
+		return r.LookupIPAddr(ctx, host)
+	}
+	return net.DefaultResolver.LookupIPAddr(ctx, host)
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resolver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOverrides(t *testing.T) {
+	overrides, err := ParseOverrides([]string{"scs.example.com=10.0.0.5", " SCS.example.com.=[2001:db8::5]",
+		"cms.example.com=10.0.0.6", ""})
+	assert.NoError(t, err)
+	assert.Len(t, overrides, 2)
+	assert.Equal(t, []net.IPAddr{{IP: net.ParseIP("10.0.0.5")}, {IP: net.ParseIP("2001:db8::5")}},
+		overrides["scs.example.com"])
+
+	for _, invalid := range []string{"scs.example.com", "scs.example.com=scs2", "=10.0.0.5", "10.0.0.1=10.0.0.5",
+		"*.example.com=10.0.0.5"} {
+		_, err = ParseOverrides([]string{invalid})
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestNew(t *testing.T) {
+	r, err := New(nil, "", nil, 0)
+	assert.NoError(t, err)
+	assert.Nil(t, r)
+
+	_, err = New(nil, "bind", nil, 0)
+	assert.Error(t, err)
+	_, err = New(nil, System, []string{"10.0.0.53"}, 0)
+	assert.Error(t, err)
+	_, err = New(nil, Go, []string{"dns.example.com"}, 0)
+	assert.Error(t, err)
+	_, err = New(nil, "", nil, -time.Second)
+	assert.Error(t, err)
+
+	r, err = New(nil, Go, []string{"10.0.0.53", "[2001:db8::53]:5353"}, time.Minute)
+	assert.NoError(t, err)
+	assert.NotNil(t, r)
+}
+
+func TestLookupIPAddr(t *testing.T) {
+	r, err := New([]string{"scs.example.com=127.0.0.1"}, "", nil, time.Minute)
+	assert.NoError(t, err)
+
+	// overrides and literals are never looked up
+	addrs, err := r.LookupIPAddr(context.Background(), "SCS.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}}, addrs)
+	addrs, err = r.LookupIPAddr(context.Background(), "::1")
+	assert.NoError(t, err)
+	assert.Equal(t, []net.IPAddr{{IP: net.ParseIP("::1")}}, addrs)
+
+	// looked up addresses are reused until the cache TTL elapses
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	r.cache["cms.example.com"] = cachedAddrs{addrs: []net.IPAddr{{IP: net.ParseIP("10.0.0.6")}},
+		expires: now.Add(time.Second)}
+	addrs, err = r.LookupIPAddr(context.Background(), "cms.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []net.IPAddr{{IP: net.ParseIP("10.0.0.6")}}, addrs)
+	r.Flush()
+	assert.Empty(t, r.cache)
+}
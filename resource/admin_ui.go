@@ -0,0 +1,380 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/json"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/dependencies"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"intel/isecl/sqvs/v4/types"
+	"intel/isecl/sqvs/v4/version"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// recentVerificationsKept is the number of verifications listed in the admin overview
+const recentVerificationsKept = 20
+
+var startedTime = time.Now().UTC()
+
+// VerificationStatistics counts the quote verifications made since the service started, by status, TCB level
+// and failed verification step, along with the most recent verifications, newest first
+type VerificationStatistics struct {
+	Since       time.Time            `json:"since"`
+	Total       int64                `json:"total"`
+	Verified    int64                `json:"verified"`
+	Failed      int64                `json:"failed"`
+	TcbLevels   map[string]int64     `json:"tcbLevels"`
+	FailedSteps map[string]int64     `json:"failedSteps"`
+	Recent      []types.Verification `json:"recent"`
+}
+
+type verificationStatsCounter struct {
+	mu    sync.Mutex
+	stats VerificationStatistics
+}
+
+var verificationStats = newVerificationStatsCounter()
+
+func newVerificationStatsCounter() *verificationStatsCounter {
+	return &verificationStatsCounter{stats: VerificationStatistics{
+		Since:       startedTime,
+		TcbLevels:   map[string]int64{},
+		FailedSteps: map[string]int64{},
+	}}
+}
+
+func (c *verificationStatsCounter) record(verification types.Verification, failedStep string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Total++
+	if verification.Status == types.VerificationStatusFailed {
+		c.stats.Failed++
+		if failedStep != "" {
+			c.stats.FailedSteps[failedStep]++
+		}
+	} else {
+		c.stats.Verified++
+	}
+	if verification.TcbLevel != "" {
+		c.stats.TcbLevels[verification.TcbLevel]++
+	}
+	recent := append([]types.Verification{verification}, c.stats.Recent...)
+	if len(recent) > recentVerificationsKept {
+		recent = recent[:recentVerificationsKept]
+	}
+	c.stats.Recent = recent
+}
+
+func (c *verificationStatsCounter) snapshot() VerificationStatistics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.TcbLevels = make(map[string]int64, len(c.stats.TcbLevels))
+	for level, count := range c.stats.TcbLevels {
+		stats.TcbLevels[level] = count
+	}
+	stats.FailedSteps = make(map[string]int64, len(c.stats.FailedSteps))
+	for step, count := range c.stats.FailedSteps {
+		stats.FailedSteps[step] = count
+	}
+	stats.Recent = append([]types.Verification{}, c.stats.Recent...)
+	return stats
+}
+
+// AdminPolicies are the policies quotes are verified against
+type AdminPolicies struct {
+	AllowDebugEnclaves        bool     `json:"allowDebugEnclaves"`
+	RequirePlatformEnrollment bool     `json:"requirePlatformEnrollment"`
+	RequireRegisteredEnclaves bool     `json:"requireRegisteredEnclaves"`
+	AllowedFmspcs             []string `json:"allowedFmspcs"`
+	AllowedSgxTypes           []string `json:"allowedSgxTypes"`
+	TcbStatusVerdicts         []string `json:"tcbStatusVerdicts"`
+	IPAllowList               []string `json:"ipAllowList"`
+	IPDenyList                []string `json:"ipDenyList"`
+	// CustomClaimsSignature attributes the custom claims policy to its author, nil when it is not signed
+	CustomClaimsSignature *PolicySignature `json:"customClaimsSignature,omitempty"`
+}
+
+// CollateralCache is the collateral SQVS keeps instead of fetching it, the imported PCK CRLs and the TCB info
+// pinned for the enrolled platforms
+type CollateralCache struct {
+	ImportedPckCrls        []parser.ImportedCrl `json:"importedPckCrls"`
+	EnrolledPlatforms      []PlatformEnrollment `json:"enrolledPlatforms"`
+	EnrolledPlatformsError string               `json:"enrolledPlatformsError,omitempty"`
+}
+
+// AdminOverview is the operator view of the service shown by the admin UI
+type AdminOverview struct {
+	Version       version.VersionInfo    `json:"version"`
+	StartedTime   time.Time              `json:"startedTime"`
+	Uptime        string                 `json:"uptime"`
+	Maintenance   MaintenanceState       `json:"maintenance"`
+	Dependencies  []DependencyStatus     `json:"dependencies"`
+	Verifications VerificationStatistics `json:"verifications"`
+	Policies      AdminPolicies          `json:"policies"`
+	Collateral    CollateralCache        `json:"collateral"`
+}
+
+func AdminUICB(router *mux.Router) {
+	router.Handle("/admin/overview", getAdminOverview()).Methods("GET")
+}
+
+// SetAdminUIRoutes serves the admin UI page. The page holds no data, it asks for an administrator token and
+// gets the overview from the authenticated /svs/v1/admin/overview endpoint.
+func SetAdminUIRoutes(router *mux.Router) {
+	router.Handle("/admin/ui", getAdminUI()).Methods("GET")
+}
+
+func getAdminOverview() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/admin_ui:getAdminOverview() Entering")
+		defer log.Trace("resource/admin_ui:getAdminOverview() Leaving")
+
+		err := authorizeAdministrator(r)
+		if err != nil {
+			return err
+		}
+		conf := config.Global()
+		now := time.Now().UTC()
+		overview := AdminOverview{
+			Version:       BuildProvenance(conf),
+			StartedTime:   startedTime,
+			Uptime:        now.Sub(startedTime).Round(time.Second).String(),
+			Maintenance:   maintenance.State(),
+			Dependencies:  dependencyStatuses(dependencies.FromConfig(conf)),
+			Verifications: verificationStats.snapshot(),
+			Policies: AdminPolicies{
+				AllowDebugEnclaves:        conf.AllowDebugEnclaves,
+				RequirePlatformEnrollment: conf.RequirePlatformEnrollment,
+				RequireRegisteredEnclaves: conf.RequireRegisteredEnclaves,
+				AllowedFmspcs:             conf.PckPolicy.AllowedFmspcs,
+				AllowedSgxTypes:           conf.PckPolicy.AllowedSgxTypes,
+				TcbStatusVerdicts:         conf.TcbStatusVerdicts,
+				IPAllowList:               conf.IPAllowList,
+				IPDenyList:                conf.IPDenyList,
+				CustomClaimsSignature:     customClaimsSignature,
+			},
+			Collateral: CollateralCache{ImportedPckCrls: parser.ImportedPckCrls(constants.PckCrlDir)},
+		}
+		if sqvsDB != nil {
+			platforms, err := sqvsDB.EnrolledPlatformRepository().RetrieveAll()
+			if err != nil {
+				log.WithError(err).Error("resource/admin_ui:getAdminOverview() Error retrieving enrolled platforms")
+				overview.Collateral.EnrolledPlatformsError = "Error retrieving enrolled platforms"
+			}
+			for i := range platforms {
+				overview.Collateral.EnrolledPlatforms = append(overview.Collateral.EnrolledPlatforms,
+					newPlatformEnrollment(&platforms[i]))
+			}
+		}
+
+		// the overview is unchanged while only its uptime changes, the uptime of a revalidated overview is
+		// derived from its started time
+		uptime := overview.Uptime
+		overview.Uptime = ""
+		content, err := json.Marshal(overview)
+		if err != nil {
+			return &resourceError{Message: "Error marshalling admin overview in JSON",
+				StatusCode: http.StatusInternalServerError}
+		}
+		if notModified(w, r, weakEntityTag(content)) {
+			return nil
+		}
+		overview.Uptime = uptime
+		body, err := json.Marshal(overview)
+		if err != nil {
+			return &resourceError{Message: "Error marshalling admin overview in JSON",
+				StatusCode: http.StatusInternalServerError}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(body)
+		if err != nil {
+			return &resourceError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+		return nil
+	}
+}
+
+func getAdminUI() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; script-src 'unsafe-inline'; "+
+			"style-src 'unsafe-inline'; connect-src 'self'; frame-ancestors 'none'")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		_, err := w.Write([]byte(adminUIPage))
+		if err != nil {
+			log.WithError(err).Error("resource/admin_ui:getAdminUI() Could not write admin UI page to response")
+		}
+	}
+}
+
+// adminUIPage renders the admin overview, the values are inserted as text so they are never interpreted as HTML
+const adminUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>SQVS administration</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h2 { border-bottom: 1px solid #ccc; padding-bottom: 4px; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f2f2f2; }
+.bad { color: #b00020; }
+.good { color: #1b5e20; }
+#error { color: #b00020; }
+</style>
+</head>
+<body>
+<h1>SGX Quote Verification Service</h1>
+<form id="login">
+<label>Administrator token <input id="token" type="password" size="60" autocomplete="off"></label>
+<button type="submit">Show</button>
+<button type="button" id="refresh">Refresh</button>
+</form>
+<p id="error"></p>
+<div id="overview"></div>
+<script>
+(function () {
+  var overviewURL = "/svs/v1/admin/overview";
+  var root = document.getElementById("overview");
+  var errorText = document.getElementById("error");
+
+  function el(tag, text, cls) {
+    var e = document.createElement(tag);
+    if (text !== undefined && text !== null) { e.textContent = String(text); }
+    if (cls) { e.className = cls; }
+    return e;
+  }
+  function section(title) {
+    root.appendChild(el("h2", title));
+  }
+  function table(headers, rows) {
+    var t = el("table"), tr = el("tr");
+    headers.forEach(function (h) { tr.appendChild(el("th", h)); });
+    t.appendChild(tr);
+    rows.forEach(function (row) {
+      var r = el("tr");
+      row.forEach(function (cell) {
+        r.appendChild(cell instanceof Node ? wrap(cell) : el("td", cell));
+      });
+      t.appendChild(r);
+    });
+    if (rows.length === 0) {
+      var empty = el("tr"), td = el("td", "none");
+      td.colSpan = headers.length;
+      empty.appendChild(td);
+      t.appendChild(empty);
+    }
+    root.appendChild(t);
+  }
+  function wrap(node) {
+    var td = el("td");
+    td.appendChild(node);
+    return td;
+  }
+  function flag(ok, yes, no) {
+    return el("span", ok ? yes : no, ok ? "good" : "bad");
+  }
+  function list(values) {
+    return values && values.length ? values.join(", ") : "any";
+  }
+  function counts(obj) {
+    return Object.keys(obj || {}).sort().map(function (k) { return [k, obj[k]]; });
+  }
+
+  function render(o) {
+    root.textContent = "";
+    section("Health");
+    table(["Version", "Build date", "Started", "Uptime", "Maintenance", "In flight"], [[
+      o.version.version + "-" + o.version.gitHash, o.version.buildDate, o.startedTime, o.uptime,
+      flag(!o.maintenance.enabled, "off", "on" + (o.maintenance.message ? ": " + o.maintenance.message : "")),
+      o.maintenance.inFlight
+    ]]);
+
+    section("Dependencies");
+    table(["Name", "URL", "Reachable", "Error"], (o.dependencies || []).map(function (d) {
+      return [d.name, d.url, flag(d.reachable, "yes", "no"), d.reachabilityError || ""];
+    }));
+
+    section("Verifications");
+    var v = o.verifications;
+    table(["Since", "Total", "Verified", "Failed"], [[v.since, v.total, v.verified, v.failed]]);
+    table(["TCB level", "Verifications"], counts(v.tcbLevels));
+    table(["Failed step", "Verifications"], counts(v.failedSteps));
+    table(["Time", "Status", "Enclave measurement", "TCB level", "Message"], (v.recent || []).map(function (r) {
+      return [r.createdTime, flag(r.status !== "failed", r.status, r.status), r.enclaveMeasurement || "",
+        r.tcbLevel || "", r.message];
+    }));
+
+    section("Policies");
+    var p = o.policies;
+    table(["Policy", "Value"], [
+      ["Debug enclaves", p.allowDebugEnclaves ? "allowed" : "rejected"],
+      ["Platform enrollment", p.requirePlatformEnrollment ? "required" : "not required"],
+      ["Enclave registration", p.requireRegisteredEnclaves ? "required" : "not required"],
+      ["Allowed FMSPCs", list(p.allowedFmspcs)],
+      ["Allowed SGX types", list(p.allowedSgxTypes)],
+      ["TCB status verdicts", p.tcbStatusVerdicts && p.tcbStatusVerdicts.length ? p.tcbStatusVerdicts.join(", ") : "all allowed"],
+      ["IP allow-list", list(p.ipAllowList)],
+      ["IP deny-list", p.ipDenyList && p.ipDenyList.length ? p.ipDenyList.join(", ") : "none"],
+      ["Custom claims author", p.customClaimsSignature ? p.customClaimsSignature.author : "unsigned"]
+    ]);
+
+    section("Collateral cache");
+    var now = new Date();
+    table(["PCK CA", "CRL number", "This update", "Next update", "Revoked"], (o.collateral.importedPckCrls || []).map(function (c) {
+      return [c.ca, c.number === undefined ? "" : c.number, c.thisUpdate,
+        flag(new Date(c.nextUpdate) > now, c.nextUpdate, c.nextUpdate + " (expired)"), c.revoked];
+    }));
+    if (o.collateral.enrolledPlatformsError) {
+      root.appendChild(el("p", o.collateral.enrolledPlatformsError, "bad"));
+    }
+    table(["FMSPC", "PCE ID", "Description", "Enrolled", "TCB info pinned", "TCB info next update"],
+      (o.collateral.enrolledPlatforms || []).map(function (e) {
+        return [e.fmspc, e.pceId, e.description, e.enrolledTime, e.collateral ? e.collateral.pinnedTime : "not pinned",
+          e.collateral ? e.collateral.tcbInfoNextUpdate : ""];
+      }));
+  }
+
+  function load() {
+    var token = sessionStorage.getItem("sqvsAdminToken");
+    var headers = { "Accept": "application/json" };
+    if (token) { headers["Authorization"] = "Bearer " + token; }
+    errorText.textContent = "";
+    fetch(overviewURL, { headers: headers, credentials: "omit" }).then(function (resp) {
+      if (!resp.ok) {
+        throw new Error("Overview request failed with status " + resp.status);
+      }
+      return resp.json();
+    }).then(render).catch(function (err) {
+      errorText.textContent = err.message;
+    });
+  }
+
+  document.getElementById("login").addEventListener("submit", function (e) {
+    e.preventDefault();
+    var token = document.getElementById("token").value.trim();
+    if (token) { sessionStorage.setItem("sqvsAdminToken", token); }
+    document.getElementById("token").value = "";
+    load();
+  });
+  document.getElementById("refresh").addEventListener("click", load);
+  load();
+})();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,59 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/json"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/types"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerificationStatsCounter(t *testing.T) {
+	counter := newVerificationStatsCounter()
+	for i := 0; i < recentVerificationsKept+5; i++ {
+		counter.record(types.Verification{ID: "verified", Status: types.VerificationStatusVerified,
+			TcbLevel: "UpToDate"}, "")
+	}
+	counter.record(types.Verification{ID: "failed", Status: types.VerificationStatusFailed}, "pck_chain")
+
+	stats := counter.snapshot()
+	assert.Equal(t, int64(recentVerificationsKept+6), stats.Total)
+	assert.Equal(t, int64(1), stats.Failed)
+	assert.Equal(t, map[string]int64{"UpToDate": int64(recentVerificationsKept + 5)}, stats.TcbLevels)
+	assert.Equal(t, map[string]int64{"pck_chain": 1}, stats.FailedSteps)
+	assert.Len(t, stats.Recent, recentVerificationsKept)
+	assert.Equal(t, "failed", stats.Recent[0].ID)
+}
+
+func TestGetAdminOverview(t *testing.T) {
+	conf := config.Global()
+	conf.IncludeToken = false
+	conf.TcbStatusVerdicts = []string{"OutOfDate=warn"}
+	defer func() { conf.TcbStatusVerdicts = nil }()
+
+	router := mux.NewRouter()
+	AdminUICB(router.PathPrefix("/svs/v1/").Subrouter())
+	SetAdminUIRoutes(router.PathPrefix("/svs/v{version:[1-2]}/").Subrouter())
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/svs/v1/admin/overview", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var overview AdminOverview
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &overview))
+	assert.Equal(t, []string{"OutOfDate=warn"}, overview.Policies.TcbStatusVerdicts)
+	assert.Equal(t, startedTime, overview.StartedTime)
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/svs/v1/admin/ui", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/html; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), "/svs/v1/admin/overview")
+}
@@ -0,0 +1,172 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"intel/isecl/sqvs/v4/constants"
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// OverloadError is the problem details returned when a request is shed
+type OverloadError struct {
+	Problem
+}
+
+// AdmissionController bounds the number of verification requests processed concurrently. Requests beyond
+// the limit wait in a bounded queue for at most maxQueueWait, after which they are rejected with 503.
+type AdmissionController struct {
+	slots        chan struct{}
+	queued       int64
+	maxQueued    int64
+	maxQueueWait time.Duration
+	load         loadAverage
+}
+
+// UnboundedQueue is the maxQueued of the admission controllers queueing any number of requests
+const UnboundedQueue = -1
+
+// NewAdmissionController returns a controller admitting up to maxConcurrent requests at a time,
+// or nil when maxConcurrent is not positive. A maxQueued of 0 rejects the requests beyond the limit at
+// once, UnboundedQueue leaves the queue unbounded.
+func NewAdmissionController(maxConcurrent, maxQueued int, maxQueueWait time.Duration) *AdmissionController {
+	if maxConcurrent <= 0 {
+		return nil
+	}
+	if maxQueued <= UnboundedQueue {
+		maxQueued = math.MaxInt32
+	}
+	return &AdmissionController{
+		slots:        make(chan struct{}, maxConcurrent),
+		maxQueued:    int64(maxQueued),
+		maxQueueWait: maxQueueWait,
+		load:         loadAverage{window: constants.CapacityLoadAverageWindow},
+	}
+}
+
+// Middleware returns the admission control middleware, it passes all requests through for a nil controller
+func (ac *AdmissionController) Middleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if ac == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ac.load.add(1, time.Now())
+			defer func() { ac.load.add(-1, time.Now()) }()
+			if !ac.admit(r) {
+				slog.Warnf("resource/admission:Middleware() Shedding request %s %s from %s, service overloaded",
+					r.Method, r.URL.Path, r.RemoteAddr)
+				writeOverloadError(w, r, ac.maxQueueWait)
+				return
+			}
+			defer func() { <-ac.slots }()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AdmissionLoad is the load of the admission controller. AverageLoad is the moving average of the number of
+// requests in flight or queued, over about a minute.
+type AdmissionLoad struct {
+	MaxConcurrent int     `json:"maxConcurrent"`
+	InFlight      int     `json:"inFlight"`
+	MaxQueued     *int    `json:"maxQueued,omitempty"`
+	Queued        int     `json:"queued"`
+	AverageLoad   float64 `json:"averageLoad"`
+}
+
+// Load returns the current load of the controller, MaxQueued is left out when the queue is unbounded
+func (ac *AdmissionController) Load() AdmissionLoad {
+	load := AdmissionLoad{
+		MaxConcurrent: cap(ac.slots),
+		InFlight:      len(ac.slots),
+		Queued:        int(atomic.LoadInt64(&ac.queued)),
+		AverageLoad:   ac.load.value(time.Now()),
+	}
+	if ac.maxQueued < math.MaxInt32 {
+		maxQueued := int(ac.maxQueued)
+		load.MaxQueued = &maxQueued
+	}
+	return load
+}
+
+// admit waits for a free slot and reports whether the request may proceed
+func (ac *AdmissionController) admit(r *http.Request) bool {
+	select {
+	case ac.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	if atomic.AddInt64(&ac.queued, 1) > ac.maxQueued {
+		atomic.AddInt64(&ac.queued, -1)
+		return false
+	}
+	defer atomic.AddInt64(&ac.queued, -1)
+
+	timer := time.NewTimer(ac.maxQueueWait)
+	defer timer.Stop()
+	select {
+	case ac.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// loadAverage is the exponentially weighted moving average of the number of requests in flight or queued,
+// the load being weighted by the time it lasted
+type loadAverage struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	current int64
+	average float64
+	updated time.Time
+}
+
+func (l *loadAverage) add(delta int64, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.decay(now)
+	l.current += delta
+}
+
+func (l *loadAverage) value(now time.Time) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.decay(now)
+	return l.average
+}
+
+// decay moves the average towards the load held since the last change
+func (l *loadAverage) decay(now time.Time) {
+	elapsed := now.Sub(l.updated)
+	if l.updated.IsZero() || elapsed > 0 {
+		l.updated = now
+	}
+	if elapsed <= 0 {
+		return
+	}
+	current := float64(l.current)
+	l.average = current + (l.average-current)*math.Exp(-float64(elapsed)/float64(l.window))
+}
+
+func writeOverloadError(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	retrySeconds := int(math.Ceil(retryAfter.Seconds()))
+	if retrySeconds < 1 {
+		retrySeconds = 1
+	}
+	problem := newCategoryProblem(ProblemOverloaded, http.StatusServiceUnavailable, "Service is overloaded, retry later")
+	problem.RetryAfter = retrySeconds
+	writeProblem(w, r, &OverloadError{Problem: *problem})
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdmissionControllerShedsLoad(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	ac := NewAdmissionController(1, 1, 50*time.Millisecond)
+	handler := ac.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	done := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/svs/v1/sgx_qv_verify_quote", nil))
+		done <- rec.Code
+	}()
+	<-started
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/svs/v1/sgx_qv_verify_quote", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+	var overloadErr OverloadError
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &overloadErr))
+	assert.Equal(t, ProblemContentType, rec.Header().Get("Content-Type"))
+	assert.Equal(t, ProblemType(ProblemOverloaded), overloadErr.Type)
+	assert.Equal(t, http.StatusServiceUnavailable, overloadErr.Status)
+	assert.Equal(t, 1, overloadErr.RetryAfter)
+	assert.True(t, overloadErr.Retryable)
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-done)
+}
+
+func TestAdmissionControllerWithoutQueue(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	ac := NewAdmissionController(1, 0, time.Minute)
+	handler := ac.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	done := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/svs/v1/sgx_qv_verify_quote", nil))
+		done <- rec.Code
+	}()
+	<-started
+
+	// the request beyond the limit is rejected at once rather than after the queue wait
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/svs/v1/sgx_qv_verify_quote", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	maxQueued := 0
+	assert.Equal(t, &maxQueued, ac.Load().MaxQueued)
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-done)
+}
+
+func TestAdmissionControllerUnboundedQueue(t *testing.T) {
+	release := make(chan struct{})
+	ac := NewAdmissionController(1, UnboundedQueue, time.Minute)
+	handler := ac.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	const requests = 5
+	done := make(chan int, requests)
+	for i := 0; i < requests; i++ {
+		go func() {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/svs/v1/sgx_qv_verify_quote", nil))
+			done <- rec.Code
+		}()
+	}
+	// all the requests beyond the limit wait in the queue
+	assert.Eventually(t, func() bool { return ac.Load().Queued == requests-1 }, time.Second, time.Millisecond)
+	assert.Nil(t, ac.Load().MaxQueued)
+
+	close(release)
+	for i := 0; i < requests; i++ {
+		assert.Equal(t, http.StatusOK, <-done)
+	}
+}
+
+func TestAdmissionControllerDisabled(t *testing.T) {
+	ac := NewAdmissionController(0, 0, time.Second)
+	assert.Nil(t, ac)
+
+	rec := httptest.NewRecorder()
+	ac.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/svs/v1/sgx_qv_verify_quote", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestLoadAverage(t *testing.T) {
+	start := time.Now()
+	l := loadAverage{window: time.Minute}
+	l.add(4, start)
+	assert.Equal(t, 0.0, l.value(start))
+	// a load held for one window covers 1-1/e of the way to it
+	assert.InDelta(t, 4*(1-math.Exp(-1)), l.value(start.Add(time.Minute)), 1e-9)
+	l.add(-4, start.Add(time.Minute))
+	assert.InDelta(t, 4*(1-math.Exp(-1))*math.Exp(-1), l.value(start.Add(2*time.Minute)), 1e-9)
+	// going back in time leaves the average unchanged
+	assert.InDelta(t, 4*(1-math.Exp(-1))*math.Exp(-1), l.value(start), 1e-9)
+}
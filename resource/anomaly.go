@@ -0,0 +1,80 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	commLogMsg "intel/isecl/lib/common/v4/log/message"
+	"intel/isecl/sqvs/v4/anomaly"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/events"
+	"intel/isecl/sqvs/v4/logformat"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var anomalies *anomaly.Detector
+var anomalyPublisher events.Publisher = events.NoopPublisher{}
+
+// SetAnomalyDetector sets the detector of anomalous quote patterns and the publisher they are posted to,
+// anomalies are not detected when d is nil
+func SetAnomalyDetector(d *anomaly.Detector, p events.Publisher) {
+	anomalies = d
+	anomalyPublisher = p
+}
+
+// verificationCaller identifies the client that requested a verification, by its address, the tenant claim
+// of its token and the relying party that signed the request, if any
+type verificationCaller struct {
+	Address string
+	Tenant  string
+	signedRequest
+}
+
+func callerOf(r *http.Request) verificationCaller {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	tenantClaim := constants.DefaultQuotaTenantClaim
+	if conf := config.Global(); conf != nil && conf.Quota.TenantClaim != "" {
+		tenantClaim = conf.Quota.TenantClaim
+	}
+	tenant, ok := tokenClaim(r, tenantClaim).(string)
+	if !ok || tenant == "" {
+		tenant = DefaultTenant
+	}
+	return verificationCaller{Address: host, Tenant: tenant, signedRequest: signedRequestOf(r)}
+}
+
+// observeVerification feeds the outcome of a verification to the anomaly detector, the anomalies it flags
+// are logged as security events and published for SOC triage
+func observeVerification(caller verificationCaller, resp SGXResponse, verifyErr error) {
+	if anomalies == nil {
+		return
+	}
+	flagged := anomalies.Observe(anomaly.Observation{
+		Time:        time.Now().UTC(),
+		Caller:      caller.Address,
+		Tenant:      caller.Tenant,
+		Measurement: resp.EnclaveMeasurement,
+		Failed:      verifyErr != nil,
+		Debug:       resp.EnclaveDebugMode,
+	})
+	for _, a := range flagged {
+		slog.WithFields(logrus.Fields{
+			logformat.EventField:   logformat.EventAnomaly,
+			logformat.OutcomeField: logformat.OutcomeFailure,
+			"anomaly":              a.Type,
+			"subject":              a.Subject,
+			"count":                a.Count,
+		}).Warnf("resource/anomaly:observeVerification() %s: Anomalous quote pattern %s, %d events within %s "+
+			"(threshold %d) from %q", commLogMsg.UnauthorizedAccess, a.Type, a.Count, a.Window, a.Threshold, a.Subject)
+		events.PublishAsync(anomalyPublisher, events.NewEvent(events.AnomalousQuotePattern, a))
+	}
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/base64"
+	"errors"
+	"intel/isecl/sqvs/v4/anomaly"
+	"intel/isecl/sqvs/v4/events"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallerOf(t *testing.T) {
+	req := httptest.NewRequest("POST", "/svs/v1/sgx_qv_verify_quote", nil)
+	req.RemoteAddr = "10.1.2.3:41000"
+	assert.Equal(t, verificationCaller{Address: "10.1.2.3", Tenant: DefaultTenant}, callerOf(req))
+
+	req.Header.Set("Authorization", "Bearer e30."+base64.RawURLEncoding.EncodeToString([]byte(`{"tenant":"acme"}`))+".sig")
+	assert.Equal(t, "acme", callerOf(req).Tenant)
+}
+
+func TestObserveVerification(t *testing.T) {
+	publisher := &testPublisher{}
+	SetAnomalyDetector(anomaly.NewDetector(anomaly.Thresholds{Window: time.Minute, FailedVerifications: 2}), publisher)
+	defer SetAnomalyDetector(nil, events.NoopPublisher{})
+
+	caller := verificationCaller{Address: "10.1.2.3", Tenant: DefaultTenant}
+	observeVerification(caller, SGXResponse{}, nil)
+	observeVerification(caller, SGXResponse{}, errors.New("invalid quote"))
+	observeVerification(verificationCaller{Address: "10.1.2.4"}, SGXResponse{}, errors.New("invalid quote"))
+	observeVerification(caller, SGXResponse{}, errors.New("invalid quote"))
+
+	assert.Eventually(t, func() bool { return publisher.count() == 1 }, time.Second, 10*time.Millisecond)
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+	assert.Equal(t, events.AnomalousQuotePattern, publisher.events[0].Type)
+	assert.Equal(t, "10.1.2.3", publisher.events[0].Data.(anomaly.Anomaly).Subject)
+}
@@ -0,0 +1,230 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	commLogMsg "intel/isecl/lib/common/v4/log/message"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/handlers"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// EvidenceTypeSGXQuote is the evidence type of SGX ECDSA quotes, the evidence is the base64 encoded quote
+	EvidenceTypeSGXQuote = "application/vnd.intel.sgx.quote"
+	// EvidenceTypeSGXReport is the evidence type of SGX reports targeting a verifier enclave, the evidence is
+	// the base64 encoded report and the parameters carry the quote of the verifier enclave
+	EvidenceTypeSGXReport = "application/vnd.intel.sgx.report"
+)
+
+// Evidence is the self-describing envelope of the evidence sent to the attest endpoint. Type is the media
+// type of the evidence, selecting the handler verifying it. Parameters are specific to the evidence type.
+type Evidence struct {
+	Type       string          `json:"type"`
+	Evidence   string          `json:"evidence"`
+	UserData   string          `json:"userData,omitempty"`
+	Parameters json.RawMessage `json:"parameters,omitempty"`
+
+	// caller is the client that sent the evidence
+	caller verificationCaller
+	// ctx is the context of the request the evidence was sent in
+	ctx context.Context
+}
+
+// Context returns the context of the request the evidence was sent in, canceled when its client disconnects.
+// Handlers pass it to the verifications and fetches they make.
+func (e Evidence) Context() context.Context {
+	if e.ctx == nil {
+		return context.Background()
+	}
+	return e.ctx
+}
+
+// AttestationResult is the result of the verification of the evidence by its handler
+type AttestationResult struct {
+	Type   string      `json:"type"`
+	Result interface{} `json:"result"`
+}
+
+// EvidenceHandler verifies evidence of the type it is registered for, it returns the verification result
+// or a resourceError
+type EvidenceHandler func(evidence Evidence) (interface{}, error)
+
+var (
+	evidenceHandlersMu sync.RWMutex
+	evidenceHandlers   = map[string]EvidenceHandler{}
+)
+
+// RegisterEvidenceHandler registers the handler of an evidence type, replacing the handler registered for
+// the type before
+func RegisterEvidenceHandler(evidenceType string, handler EvidenceHandler) {
+	evidenceHandlersMu.Lock()
+	defer evidenceHandlersMu.Unlock()
+	evidenceHandlers[strings.ToLower(evidenceType)] = handler
+}
+
+// EvidenceTypes returns the evidence types handlers are registered for
+func EvidenceTypes() []string {
+	evidenceHandlersMu.RLock()
+	defer evidenceHandlersMu.RUnlock()
+	types := make([]string, 0, len(evidenceHandlers))
+	for evidenceType := range evidenceHandlers {
+		types = append(types, evidenceType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func evidenceHandler(evidenceType string) EvidenceHandler {
+	evidenceHandlersMu.RLock()
+	defer evidenceHandlersMu.RUnlock()
+	return evidenceHandlers[strings.ToLower(evidenceType)]
+}
+
+func init() {
+	RegisterEvidenceHandler(EvidenceTypeSGXQuote, verifySGXQuoteEvidence)
+	RegisterEvidenceHandler(EvidenceTypeSGXReport, verifySGXReportEvidence)
+}
+
+func AttestCB(router *mux.Router) {
+	router.Handle("/attest", handlers.ContentTypeHandler(attest(), contentTypeJSON)).Methods("POST")
+}
+
+func attest() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/attest:attest() Entering")
+		defer log.Trace("resource/attest:attest() Leaving")
+
+		conf := config.Global()
+		if conf == nil {
+			return &resourceError{Message: "Could not read config", StatusCode: http.StatusInternalServerError}
+		}
+		if conf.IncludeToken {
+			err := AuthorizeEndpoint(r, constants.QuoteVerifierGroupName, true)
+			if err != nil {
+				slog.WithError(err).Error("resource/attest: attest() Authorization Error")
+				return err
+			}
+		}
+
+		var evidence Evidence
+		r.Body = http.MaxBytesReader(w, r.Body, constants.MaxQuoteUploadSize+constants.MaxQuoteSize)
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		err := dec.Decode(&evidence)
+		if err != nil || evidence.Evidence == "" {
+			slog.WithError(err).Errorf("resource/attest: attest() %s: Failed to decode request body",
+				commLogMsg.InvalidInputBadEncoding)
+			return &resourceError{Message: "Invalid JSON input provided", StatusCode: http.StatusBadRequest}
+		}
+
+		handler := evidenceHandler(evidence.Type)
+		if handler == nil {
+			slog.Errorf("resource/attest: attest() %s: Unsupported evidence type %q", commLogMsg.InvalidInputBadParam,
+				evidence.Type)
+			return &resourceError{Message: "Unsupported evidence type, must be one of " +
+				strings.Join(EvidenceTypes(), ", "), StatusCode: http.StatusUnsupportedMediaType}
+		}
+		evidence.caller = callerOf(r)
+		evidence.ctx = r.Context()
+		result, err := handler(evidence)
+		if err != nil {
+			return err
+		}
+
+		body, err := json.Marshal(AttestationResult{Type: strings.ToLower(evidence.Type), Result: result})
+		if err != nil {
+			log.WithError(err).Error("Error marshalling attestation result in JSON")
+			return &resourceError{Message: "Error marshalling attestation result in JSON",
+				StatusCode: http.StatusInternalServerError}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(body)
+		if err != nil {
+			return &resourceError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+		return nil
+	}
+}
+
+// decodeEvidenceParameters decodes the type specific parameters of the evidence, rejecting unknown fields
+func decodeEvidenceParameters(evidence Evidence, params interface{}) error {
+	if len(evidence.Parameters) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(evidence.Parameters))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(params)
+	if err != nil {
+		slog.WithError(err).Errorf("resource/attest:decodeEvidenceParameters() %s: Invalid %s parameters",
+			commLogMsg.InvalidInputBadEncoding, evidence.Type)
+		return &resourceError{Message: "Invalid evidence parameters provided", StatusCode: http.StatusBadRequest}
+	}
+	return nil
+}
+
+// sgxQuoteParameters are the parameters of SGX quote evidence
+type sgxQuoteParameters struct {
+	Constraints       *QuoteConstraints  `json:"constraints,omitempty"`
+	ReportDataBinding *ReportDataBinding `json:"reportDataBinding,omitempty"`
+	EvaluationTime    string             `json:"evaluationTime,omitempty"`
+}
+
+func verifySGXQuoteEvidence(evidence Evidence) (interface{}, error) {
+	var params sgxQuoteParameters
+	err := decodeEvidenceParameters(evidence, &params)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := SgxEcdsaQuoteVerify(evidence.Context(), QuoteDataWithChallenge{QuoteData: QuoteData{
+		QuoteBlob:         evidence.Evidence,
+		UserData:          evidence.UserData,
+		Constraints:       params.Constraints,
+		ReportDataBinding: params.ReportDataBinding,
+		EvaluationTime:    params.EvaluationTime,
+	}})
+	recordVerification(evidence.caller, evidence.Evidence, resp, err)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// sgxReportParameters are the parameters of SGX report evidence
+type sgxReportParameters struct {
+	Quote             string `json:"quote"`
+	VerifierMrSigner  string `json:"verifierMrSigner,omitempty"`
+	VerifierMrEnclave string `json:"verifierMrEnclave,omitempty"`
+}
+
+func verifySGXReportEvidence(evidence Evidence) (interface{}, error) {
+	var params sgxReportParameters
+	err := decodeEvidenceParameters(evidence, &params)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := SgxChainedReportVerify(evidence.Context(), ChainedReportData{
+		Report:            evidence.Evidence,
+		Quote:             params.Quote,
+		UserData:          evidence.UserData,
+		VerifierMrSigner:  params.VerifierMrSigner,
+		VerifierMrEnclave: params.VerifierMrEnclave,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"bytes"
+	"encoding/json"
+	"intel/isecl/sqvs/v4/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttestEvidenceHandlers(t *testing.T) {
+	config.Global().IncludeToken = false
+	router := mux.NewRouter()
+	AttestCB(router)
+
+	const testEvidenceType = "application/vnd.test.evidence"
+	RegisterEvidenceHandler(testEvidenceType, func(evidence Evidence) (interface{}, error) {
+		var params struct {
+			Expected string `json:"expected"`
+		}
+		err := decodeEvidenceParameters(evidence, &params)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]bool{"passed": evidence.Evidence == params.Expected}, nil
+	})
+	assert.Contains(t, EvidenceTypes(), EvidenceTypeSGXQuote)
+	assert.Contains(t, EvidenceTypes(), testEvidenceType)
+
+	post := func(evidence Evidence) *httptest.ResponseRecorder {
+		body, err := json.Marshal(evidence)
+		assert.NoError(t, err)
+		req := httptest.NewRequest("POST", "/attest", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := post(Evidence{Type: "Application/Vnd.Test.Evidence", Evidence: "abcd",
+		Parameters: json.RawMessage(`{"expected":"abcd"}`)})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"type":"application/vnd.test.evidence","result":{"passed":true}}`, recorder.Body.String())
+
+	recorder = post(Evidence{Type: testEvidenceType, Evidence: "abcd", Parameters: json.RawMessage(`{"unknown":1}`)})
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = post(Evidence{Type: "application/vnd.amd.sev-snp.report", Evidence: "abcd"})
+	assert.Equal(t, http.StatusUnsupportedMediaType, recorder.Code)
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/json"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+var admission *AdmissionController
+
+// SetAdmissionController sets the admission controller whose load the capacity endpoint reports, the
+// endpoint is disabled when it is nil
+func SetAdmissionController(ac *AdmissionController) {
+	admission = ac
+}
+
+// CapacityReport is the verification load of the replica and the replica count suggested for it.
+// Utilization is the share of MaxConcurrent currently in flight or queued, AverageUtilization its moving
+// average over about a minute. SuggestedReplicas is the number of replicas bringing AverageUtilization to
+// TargetUtilization, given the Replicas currently serving the load.
+type CapacityReport struct {
+	AdmissionLoad
+	Utilization        float64 `json:"utilization"`
+	AverageUtilization float64 `json:"averageUtilization"`
+	TargetUtilization  float64 `json:"targetUtilization"`
+	Replicas           int     `json:"replicas"`
+	SuggestedReplicas  int     `json:"suggestedReplicas"`
+}
+
+func CapacityCB(router *mux.Router) {
+	router.Handle("/admin/capacity", getCapacity()).Methods("GET")
+}
+
+func getCapacity() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/capacity:getCapacity() Entering")
+		defer log.Trace("resource/capacity:getCapacity() Leaving")
+
+		conf := config.Global()
+		if conf == nil {
+			return &resourceError{Message: "Could not read config", StatusCode: http.StatusInternalServerError}
+		}
+		if conf.IncludeToken {
+			err := AuthorizeEndpoint(r, constants.CapacityReaderGroupName, true)
+			if err != nil {
+				return err
+			}
+		}
+		if admission == nil {
+			return &resourceError{Message: "Admission control is not enabled", StatusCode: http.StatusNotFound}
+		}
+
+		replicas := 1
+		if value := r.URL.Query().Get("replicas"); value != "" {
+			var err error
+			replicas, err = strconv.Atoi(value)
+			if err != nil || replicas < 1 {
+				return &resourceError{Message: "Invalid replicas query parameter", StatusCode: http.StatusBadRequest}
+			}
+		}
+
+		target := conf.TargetUtilization
+		if target <= 0 || target > 1 {
+			target = constants.DefaultTargetUtilization
+		}
+		body, err := json.Marshal(newCapacityReport(admission.Load(), target, replicas))
+		if err != nil {
+			return &resourceError{Message: "Error marshalling capacity report in JSON",
+				StatusCode: http.StatusInternalServerError}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(body)
+		if err != nil {
+			return &resourceError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+		return nil
+	}
+}
+
+func newCapacityReport(load AdmissionLoad, target float64, replicas int) CapacityReport {
+	report := CapacityReport{
+		AdmissionLoad:     load,
+		TargetUtilization: target,
+		Replicas:          replicas,
+		SuggestedReplicas: replicas,
+	}
+	if load.MaxConcurrent <= 0 {
+		return report
+	}
+	report.Utilization = float64(load.InFlight+load.Queued) / float64(load.MaxConcurrent)
+	report.AverageUtilization = load.AverageLoad / float64(load.MaxConcurrent)
+	// the replicas share the load evenly, so the same load spread over n replicas has replicas/n times the
+	// utilization of this one
+	suggested := int(math.Ceil(float64(replicas)*report.AverageUtilization/target - 1e-9))
+	if suggested < 1 {
+		suggested = 1
+	}
+	report.SuggestedReplicas = suggested
+	return report
+}
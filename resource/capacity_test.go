@@ -0,0 +1,27 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCapacityReport(t *testing.T) {
+	load := AdmissionLoad{MaxConcurrent: 100, InFlight: 100, Queued: 40, AverageLoad: 84}
+	report := newCapacityReport(load, 0.7, 3)
+	assert.InDelta(t, 1.4, report.Utilization, 1e-9)
+	assert.InDelta(t, 0.84, report.AverageUtilization, 1e-9)
+	assert.Equal(t, 4, report.SuggestedReplicas)
+
+	// exactly on target keeps the replica count
+	report = newCapacityReport(AdmissionLoad{MaxConcurrent: 10, AverageLoad: 7}, 0.7, 2)
+	assert.Equal(t, 2, report.SuggestedReplicas)
+
+	// idle replicas scale down to one
+	report = newCapacityReport(AdmissionLoad{MaxConcurrent: 10}, 0.7, 5)
+	assert.Equal(t, 1, report.SuggestedReplicas)
+}
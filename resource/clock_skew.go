@@ -0,0 +1,118 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"crypto/x509"
+	"intel/isecl/sqvs/v4/config"
+	"time"
+)
+
+const (
+	skewCheckPckChain   = "pck_cert_chain"
+	skewCheckTcbInfo    = "tcb_info"
+	skewCheckQeIdentity = "qe_identity"
+)
+
+// ClockSkew reports the checks that only passed because the trusted time was moved within the clock skew
+// tolerance. SkewSeconds is positive when the trusted time was moved forward, the local clock being behind
+// the issuer's, and negative when it was moved back.
+type ClockSkew struct {
+	ToleranceSeconds int           `json:"ToleranceSeconds"`
+	Checks           []SkewedCheck `json:"Checks"`
+}
+
+type SkewedCheck struct {
+	Check       string  `json:"Check"`
+	SkewSeconds float64 `json:"SkewSeconds"`
+}
+
+func clockSkewTolerance() time.Duration {
+	conf := config.Global()
+	if conf == nil || conf.ClockSkewToleranceSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(conf.ClockSkewToleranceSeconds) * time.Second
+}
+
+// certValidity returns the period during which all the certificates are valid
+func certValidity(certs ...*x509.Certificate) (time.Time, time.Time) {
+	var notBefore, notAfter time.Time
+	for _, cert := range certs {
+		if cert == nil {
+			continue
+		}
+		if notBefore.IsZero() || cert.NotBefore.After(notBefore) {
+			notBefore = cert.NotBefore
+		}
+		if notAfter.IsZero() || cert.NotAfter.Before(notAfter) {
+			notAfter = cert.NotAfter
+		}
+	}
+	return notBefore, notAfter
+}
+
+// intersectValidity returns the period within both [notBefore, notAfter] and [from, until], zero times are
+// unbounded
+func intersectValidity(notBefore, notAfter, from, until time.Time) (time.Time, time.Time) {
+	if notBefore.IsZero() || from.After(notBefore) {
+		notBefore = from
+	}
+	if notAfter.IsZero() || (!until.IsZero() && until.Before(notAfter)) {
+		notAfter = until
+	}
+	return notBefore, notAfter
+}
+
+// skewedTime returns the time within [notBefore, notAfter] closest to t along with the skew between them, or t
+// when it is within the period already or the skew would exceed the tolerance. Certificates are valid up to
+// and including NotAfter, collateral until nextUpdate excluded, so t is moved to a second before notAfter.
+func skewedTime(t, notBefore, notAfter time.Time, tolerance time.Duration) (time.Time, time.Duration) {
+	if tolerance <= 0 || notBefore.IsZero() || notAfter.IsZero() || !notBefore.Before(notAfter) {
+		return t, 0
+	}
+	var skewed time.Time
+	switch {
+	case t.Before(notBefore):
+		skewed = notBefore.Add(time.Second)
+		if skewed.After(notAfter) {
+			skewed = notBefore
+		}
+	case !t.Before(notAfter):
+		skewed = notAfter.Add(-time.Second)
+		if skewed.Before(notBefore) {
+			skewed = notBefore
+		}
+	default:
+		return t, 0
+	}
+	skew := skewed.Sub(t)
+	if skew > tolerance || skew < -tolerance {
+		return t, 0
+	}
+	return skewed, skew
+}
+
+// add records a check that needed the trusted time to be skewed to pass
+func (c *ClockSkew) add(check string, skew time.Duration) *ClockSkew {
+	if skew == 0 {
+		return c
+	}
+	if c == nil {
+		c = &ClockSkew{ToleranceSeconds: int(clockSkewTolerance() / time.Second)}
+	}
+	log.Warnf("resource/clock_skew:add() %s only valid with a clock skew of %s", check, skew)
+	c.Checks = append(c.Checks, SkewedCheck{Check: check, SkewSeconds: skew.Seconds()})
+	return c
+}
+
+// parseCollateralDate parses an issueDate or nextUpdate of collateral, the zero time when it is invalid
+func parseCollateralDate(date string) time.Time {
+	t, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
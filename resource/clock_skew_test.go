@@ -0,0 +1,52 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkewedTime(t *testing.T) {
+	notBefore := time.Date(2021, 6, 15, 10, 0, 0, 0, time.UTC)
+	notAfter := notBefore.Add(24 * time.Hour)
+	tolerance := 30 * time.Second
+
+	within := notBefore.Add(time.Hour)
+	skewed, skew := skewedTime(within, notBefore, notAfter, tolerance)
+	assert.Equal(t, within, skewed)
+	assert.Zero(t, skew)
+
+	// freshly issued certificate, the local clock is 5 seconds behind
+	skewed, skew = skewedTime(notBefore.Add(-5*time.Second), notBefore, notAfter, tolerance)
+	assert.Equal(t, notBefore.Add(time.Second), skewed)
+	assert.Equal(t, 6*time.Second, skew)
+
+	skewed, skew = skewedTime(notAfter.Add(10*time.Second), notBefore, notAfter, tolerance)
+	assert.Equal(t, notAfter.Add(-time.Second), skewed)
+	assert.Equal(t, -11*time.Second, skew)
+
+	late := notAfter.Add(time.Minute)
+	skewed, skew = skewedTime(late, notBefore, notAfter, tolerance)
+	assert.Equal(t, late, skewed)
+	assert.Zero(t, skew)
+
+	skewed, skew = skewedTime(notBefore.Add(-5*time.Second), notBefore, notAfter, 0)
+	assert.Equal(t, notBefore.Add(-5*time.Second), skewed)
+	assert.Zero(t, skew)
+}
+
+func TestIntersectValidity(t *testing.T) {
+	notBefore := time.Date(2021, 6, 15, 10, 0, 0, 0, time.UTC)
+	notAfter := notBefore.Add(24 * time.Hour)
+	from, until := intersectValidity(notBefore, notAfter, notBefore.Add(time.Hour), time.Time{})
+	assert.Equal(t, notBefore.Add(time.Hour), from)
+	assert.Equal(t, notAfter, until)
+	from, until = intersectValidity(time.Time{}, time.Time{}, notBefore, notAfter)
+	assert.Equal(t, notBefore, from)
+	assert.Equal(t, notAfter, until)
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/metrics"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"sync"
+	"time"
+)
+
+var collateralFallbacksTotal = metrics.NewCounter("sqvs_collateral_fallbacks_total",
+	"Quote verifications that used cached collateral because fresh collateral could not be fetched, by "+
+		"collateral and collateral failure mode", "collateral", "mode")
+
+// CollateralFallback reports the collateral a quote was verified against from the cache because SCS could not
+// be reached for fresh collateral, and the collateral failure mode that allowed it
+type CollateralFallback struct {
+	Mode       string   `json:"Mode"`
+	Collateral []string `json:"Collateral"`
+	Warning    string   `json:"Warning"`
+}
+
+// verifiedCollateral is the last TCB info of an FMSPC or the last QE identity verified, with the time it was
+// verified at
+type verifiedCollateral struct {
+	tcbInfo    *parser.TcbInfoStruct
+	qeIdentity *parser.QeIdentityData
+	nextUpdate time.Time
+	verifiedAt time.Time
+}
+
+// lastVerifiedCollateral holds the last verified collateral by collateral and FMSPC, for the verifications
+// that cannot fetch fresh collateral
+var lastVerifiedCollateral = struct {
+	sync.Mutex
+	entries map[string]*verifiedCollateral
+}{entries: map[string]*verifiedCollateral{}}
+
+// collateralFailureMode returns the collateral failure mode and the grace period of the use-cached mode
+func collateralFailureMode() (string, time.Duration) {
+	conf := config.Global()
+	if conf == nil || conf.CollateralFailure.Mode == "" {
+		return constants.DefaultCollateralFailureMode, constants.DefaultCollateralGracePeriod
+	}
+	return conf.CollateralFailure.Mode, conf.CollateralFailure.GracePeriod
+}
+
+func verifiedCollateralKey(collateral, fmspc string) string {
+	return collateral + "\x00" + fmspc
+}
+
+// keepVerifiedTcbInfo keeps the TCB info verified at as the fallback of its FMSPC, unless collateral failures
+// fail closed
+func keepVerifiedTcbInfo(tcbObj *parser.TcbInfoStruct, at time.Time) {
+	keepVerifiedCollateral(verifiedCollateralKey(collateralTcbInfo, tcbObj.GetTcbInfoFmspc()),
+		&verifiedCollateral{tcbInfo: tcbObj, nextUpdate: parseCollateralDate(tcbObj.GetTcbInfoNextUpdate()),
+			verifiedAt: at})
+}
+
+// keepVerifiedQeIdentity keeps the QE identity verified at as the fallback QE identity, unless collateral
+// failures fail closed
+func keepVerifiedQeIdentity(qeIDObj *parser.QeIdentityData, at time.Time) {
+	keepVerifiedCollateral(verifiedCollateralKey(collateralQeIdentity, ""),
+		&verifiedCollateral{qeIdentity: qeIDObj, nextUpdate: parseCollateralDate(qeIDObj.GetQeIDNextUpdate()),
+			verifiedAt: at})
+}
+
+func keepVerifiedCollateral(key string, entry *verifiedCollateral) {
+	if mode, _ := collateralFailureMode(); mode == config.CollateralFailClosed {
+		return
+	}
+	lastVerifiedCollateral.Lock()
+	defer lastVerifiedCollateral.Unlock()
+	// collateral taken from the cache is not verified again
+	if kept, ok := lastVerifiedCollateral.entries[key]; ok && kept.tcbInfo == entry.tcbInfo &&
+		kept.qeIdentity == entry.qeIdentity {
+		return
+	}
+	lastVerifiedCollateral.entries[key] = entry
+}
+
+// fallBackOnCollateral returns the last verified collateral of the FMSPC, the time to verify it at and the
+// fallback of the verification reporting its use, when err is a failure to reach SCS for fresh collateral the
+// collateral failure mode tolerates. Otherwise it returns err, reporting the mode when SCS could not be
+// reached. Collateral past its next update is verified at the time it was last verified.
+func fallBackOnCollateral(fallback *CollateralFallback, collateral, fmspc string, now time.Time,
+	err error) (*verifiedCollateral, time.Time, *CollateralFallback, error) {
+	rerr, ok := err.(*resourceError)
+	if !ok || !rerr.collateralUnreachable {
+		// the request is rejected, its client disconnected, the store failed or the fetched collateral is
+		// invalid: cached collateral must not hide it
+		return nil, time.Time{}, fallback, err
+	}
+	mode, grace := collateralFailureMode()
+	var entry *verifiedCollateral
+	if mode != config.CollateralFailClosed {
+		lastVerifiedCollateral.Lock()
+		entry = lastVerifiedCollateral.entries[verifiedCollateralKey(collateral, fmspc)]
+		lastVerifiedCollateral.Unlock()
+	}
+	// the cache only holds the collateral verified since the service started, neither mode verifies quotes
+	// without collateral
+	if entry == nil && mode != config.CollateralFailClosed {
+		rerr.Message += " (collateral failure mode " + mode + ", no " + collateral + " was verified since SQVS started)"
+		return nil, time.Time{}, fallback, rerr
+	}
+	if entry == nil || (mode == config.CollateralUseCached && now.After(entry.nextUpdate.Add(grace))) {
+		rerr.Message += " (collateral failure mode " + mode + ")"
+		return nil, time.Time{}, fallback, rerr
+	}
+
+	log.Warnf("resource/collateral_fallback:fallBackOnCollateral() Fresh %s could not be fetched, using the "+
+		"%s verified at %s in %s mode", collateral, collateral, entry.verifiedAt.Format(time.RFC3339), mode)
+	collateralFallbacksTotal.Inc(collateral, mode)
+	if fallback == nil {
+		fallback = &CollateralFallback{Mode: mode,
+			Warning: "Fresh collateral could not be fetched, the quote was verified against cached collateral"}
+	}
+	fallback.Collateral = append(fallback.Collateral, collateral)
+	at := now
+	if !now.Before(entry.nextUpdate) {
+		at = entry.verifiedAt
+	}
+	return entry, at, fallback, nil
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFallBackOnCollateral(t *testing.T) {
+	conf := config.Global()
+	defer func() { conf.CollateralFailure = config.CollateralFailureConfig{} }()
+	now := time.Now()
+	verifiedAt := now.Add(-2 * time.Hour)
+	tcbInfo := &parser.TcbInfoStruct{}
+	lastVerifiedCollateral.Lock()
+	lastVerifiedCollateral.entries[verifiedCollateralKey(collateralTcbInfo, "00906ea10000")] = &verifiedCollateral{
+		tcbInfo: tcbInfo, nextUpdate: now.Add(-time.Hour), verifiedAt: verifiedAt}
+	lastVerifiedCollateral.Unlock()
+	fetchFailed := func() error {
+		return fetchFailure(errors.Wrap(parser.ErrCollateralUnreachable, "connection refused"),
+			"Get TCB Info data parsing/fetch failed", http.StatusInternalServerError)
+	}
+
+	// fail closed by default
+	_, _, fallback, err := fallBackOnCollateral(nil, collateralTcbInfo, "00906ea10000", now, fetchFailed())
+	assert.Nil(t, fallback)
+	assert.Contains(t, err.(*resourceError).Message, "collateral failure mode fail-closed")
+
+	// the cached TCB info is past its next update, and its grace period
+	conf.CollateralFailure = config.CollateralFailureConfig{Mode: config.CollateralUseCached, GracePeriod: time.Minute}
+	_, _, _, err = fallBackOnCollateral(nil, collateralTcbInfo, "00906ea10000", now, fetchFailed())
+	assert.Error(t, err)
+
+	conf.CollateralFailure.GracePeriod = 24 * time.Hour
+	cached, at, fallback, err := fallBackOnCollateral(nil, collateralTcbInfo, "00906ea10000", now, fetchFailed())
+	assert.NoError(t, err)
+	assert.Equal(t, tcbInfo, cached.tcbInfo)
+	assert.Equal(t, verifiedAt, at)
+	assert.Equal(t, config.CollateralUseCached, fallback.Mode)
+	assert.Equal(t, []string{collateralTcbInfo}, fallback.Collateral)
+
+	conf.CollateralFailure = config.CollateralFailureConfig{Mode: config.CollateralFailOpen}
+	_, _, fallback, err = fallBackOnCollateral(nil, collateralTcbInfo, "00906ea10000", now, fetchFailed())
+	assert.NoError(t, err)
+	assert.Equal(t, config.CollateralFailOpen, fallback.Mode)
+
+	// there is no QE identity to fall back on, and rejected requests do not fall back
+	_, _, _, err = fallBackOnCollateral(nil, collateralQeIdentity, "", now, fetchFailed())
+	assert.Contains(t, err.(*resourceError).Message, "collateral failure mode fail-open")
+	_, _, _, err = fallBackOnCollateral(nil, collateralTcbInfo, "00906ea10000", now,
+		&resourceError{Message: "Platform is not enrolled", StatusCode: http.StatusForbidden})
+	assert.Equal(t, "Platform is not enrolled", err.(*resourceError).Message)
+
+	// neither do the failures of the store nor those of the collateral SCS served
+	_, _, _, err = fallBackOnCollateral(nil, collateralTcbInfo, "00906ea10000", now,
+		&resourceError{Message: "Platform enrollment is not available", StatusCode: http.StatusInternalServerError})
+	assert.Equal(t, "Platform enrollment is not available", err.(*resourceError).Message)
+	_, _, _, err = fallBackOnCollateral(nil, collateralTcbInfo, "00906ea10000", now,
+		fetchFailure(errors.New("getTcbInfoStruct: TcbInfo Unmarshal Failed"), "Get TCB Info data parsing/fetch failed",
+			http.StatusInternalServerError))
+	assert.Equal(t, "Get TCB Info data parsing/fetch failed", err.(*resourceError).Message)
+	// fetches that timed out could not reach SCS
+	_, _, fallback, err = fallBackOnCollateral(nil, collateralTcbInfo, "00906ea10000", now,
+		fetchFailure(errors.Wrap(parser.ErrFetchTimeout, "getTcbInfoStruct"), "Get TCB Info data parsing/fetch failed",
+			http.StatusInternalServerError))
+	assert.NoError(t, err)
+	assert.Equal(t, config.CollateralFailOpen, fallback.Mode)
+}
+
+func TestFallBackOnCollateralFailOpenWithoutCache(t *testing.T) {
+	conf := config.Global()
+	conf.CollateralFailure = config.CollateralFailureConfig{Mode: config.CollateralFailOpen}
+	defer func() { conf.CollateralFailure = config.CollateralFailureConfig{} }()
+	lastVerifiedCollateral.Lock()
+	saved := lastVerifiedCollateral.entries
+	lastVerifiedCollateral.entries = map[string]*verifiedCollateral{}
+	lastVerifiedCollateral.Unlock()
+	defer func() {
+		lastVerifiedCollateral.Lock()
+		lastVerifiedCollateral.entries = saved
+		lastVerifiedCollateral.Unlock()
+	}()
+
+	// right after a restart there is no collateral to fail open on, the verification fails
+	cached, _, fallback, err := fallBackOnCollateral(nil, collateralTcbInfo, "00906ea10000", time.Now(),
+		fetchFailure(errors.Wrap(parser.ErrCollateralUnreachable, "connection refused"),
+			"Get TCB Info data parsing/fetch failed", http.StatusInternalServerError))
+	assert.Nil(t, cached)
+	assert.Nil(t, fallback)
+	assert.Equal(t, http.StatusInternalServerError, err.(*resourceError).StatusCode)
+	assert.Equal(t, "Get TCB Info data parsing/fetch failed (collateral failure mode fail-open, no tcb_info "+
+		"was verified since SQVS started)", err.(*resourceError).Message)
+}
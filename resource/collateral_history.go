@@ -0,0 +1,184 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	commLogMsg "intel/isecl/lib/common/v4/log/message"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"intel/isecl/sqvs/v4/types"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	tcbEvaluationDateFormField = "tcbEvaluationDate"
+	collateralVersionFormField = "collateralVersion"
+)
+
+// recordedCollateral holds the keys of the collateral versions recorded since SQVS started, so the collateral of
+// every verification is not written to the store again
+var recordedCollateral sync.Map
+
+// collateralSelection selects the recorded collateral a quote is re-evaluated against, the collateral current at
+// date or that of the TCB evaluation data number version
+type collateralSelection struct {
+	date    time.Time
+	version uint
+	repo    repository.CollateralSnapshotRepository
+}
+
+// collateralHistoryRepository returns the repository of the collateral versions, nil when they are not kept
+func collateralHistoryRepository() repository.CollateralSnapshotRepository {
+	conf := config.Global()
+	if sqvsDB == nil || conf == nil || !conf.EnableCollateralHistory {
+		return nil
+	}
+	return sqvsDB.CollateralSnapshotRepository()
+}
+
+// selectCollateral returns the collateral selection of the request, nil when the quote is verified against the
+// current collateral. The TCB evaluation date is the time the certificates are validated at unless an
+// evaluation time is requested as well.
+func selectCollateral(data *QuoteData) (*collateralSelection, error) {
+	if data.TcbEvaluationDate == "" && data.CollateralVersion == 0 {
+		return nil, nil
+	}
+	if data.TcbEvaluationDate != "" && data.CollateralVersion != 0 {
+		slog.Errorf("resource/collateral_history:selectCollateral() %s: Both tcbEvaluationDate and "+
+			"collateralVersion requested", commLogMsg.InvalidInputBadParam)
+		return nil, &resourceError{Message: "tcbEvaluationDate and collateralVersion cannot be combined",
+			StatusCode: http.StatusBadRequest}
+	}
+	repo := collateralHistoryRepository()
+	if repo == nil {
+		return nil, &resourceError{Message: "Collateral history is not enabled", StatusCode: http.StatusBadRequest}
+	}
+
+	selection := &collateralSelection{version: data.CollateralVersion, repo: repo}
+	if data.TcbEvaluationDate != "" {
+		date, err := time.Parse(time.RFC3339, data.TcbEvaluationDate)
+		if err != nil {
+			slog.WithError(err).Errorf("resource/collateral_history:selectCollateral() %s: Invalid TCB evaluation "+
+				"date", commLogMsg.InvalidInputBadParam)
+			return nil, &resourceError{Message: "Invalid tcbEvaluationDate provided",
+				StatusCode: http.StatusBadRequest}
+		}
+		selection.date = date.UTC()
+		if data.EvaluationTime == "" {
+			data.EvaluationTime = data.TcbEvaluationDate
+		}
+	}
+	return selection, nil
+}
+
+// parseCollateralVersion parses the collateralVersion form field or query parameter of a request
+func parseCollateralVersion(value string) (uint, error) {
+	if value == "" {
+		return 0, nil
+	}
+	version, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		slog.WithError(err).Errorf("resource/collateral_history:parseCollateralVersion() %s: Invalid collateral "+
+			"version", commLogMsg.InvalidInputBadParam)
+		return 0, &resourceError{Message: "Invalid collateralVersion provided", StatusCode: http.StatusBadRequest}
+	}
+	return uint(version), nil
+}
+
+// snapshot returns the recorded version of the collateral and the time it is evaluated at, the TCB evaluation
+// date or the issue date of the version
+func (c *collateralSelection) snapshot(collateral, fmspc string) (*types.CollateralSnapshot, time.Time, error) {
+	var snapshot *types.CollateralSnapshot
+	var err error
+	if c.version != 0 {
+		snapshot, err = c.repo.RetrieveVersion(collateral, fmspc, c.version)
+	} else {
+		snapshot, err = c.repo.RetrieveAt(collateral, fmspc, c.date)
+		if err == nil && !c.date.Before(snapshot.NextUpdate) {
+			err = repository.ErrRecordNotFound
+		}
+	}
+	if err == repository.ErrRecordNotFound {
+		return nil, time.Time{}, &resourceError{Message: "No " + collateral + " recorded for the requested " +
+			"TCB evaluation date or collateral version", StatusCode: http.StatusNotFound}
+	} else if err != nil {
+		log.WithError(err).Error("resource/collateral_history:snapshot() Error retrieving collateral snapshot")
+		return nil, time.Time{}, &resourceError{Message: "Error retrieving collateral history",
+			StatusCode: http.StatusInternalServerError}
+	}
+	if c.version != 0 {
+		return snapshot, snapshot.IssueDate, nil
+	}
+	return snapshot, c.date, nil
+}
+
+func (c *collateralSelection) tcbInfo(fmspc string) (*parser.TcbInfoStruct, time.Time, error) {
+	snapshot, at, err := c.snapshot(collateralTcbInfo, fmspc)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	tcbObj, err := parser.ParseTcbInfo([]byte(snapshot.Content), snapshot.IssuerChain)
+	if err != nil {
+		log.WithError(err).Error("resource/collateral_history:tcbInfo() Error parsing recorded TCB info")
+		return nil, time.Time{}, &resourceError{Message: "Error parsing recorded TCB info",
+			StatusCode: http.StatusInternalServerError}
+	}
+	return tcbObj, at, nil
+}
+
+func (c *collateralSelection) qeIdentity() (*parser.QeIdentityData, time.Time, error) {
+	snapshot, at, err := c.snapshot(collateralQeIdentity, "")
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	qeIDObj, err := parser.ParseQeIdentity([]byte(snapshot.Content), snapshot.IssuerChain)
+	if err != nil || qeIDObj == nil {
+		log.WithError(err).Error("resource/collateral_history:qeIdentity() Error parsing recorded QE identity")
+		return nil, time.Time{}, &resourceError{Message: "Error parsing recorded QE identity",
+			StatusCode: http.StatusInternalServerError}
+	}
+	return qeIDObj, at, nil
+}
+
+// recordTcbInfo keeps the verified TCB info version when collateral history is enabled
+func recordTcbInfo(tcbObj *parser.TcbInfoStruct) {
+	info := tcbObj.TcbInfoData.TcbInfo
+	recordCollateral(&types.CollateralSnapshot{Collateral: collateralTcbInfo, Fmspc: tcbObj.GetTcbInfoFmspc(),
+		TcbEvaluationDataNumber: info.TcbEvaluationDataNumber, IssueDate: parseCollateralDate(info.IssueDate),
+		NextUpdate: parseCollateralDate(info.NextUpdate), Content: string(tcbObj.RawBlob),
+		IssuerChain: tcbObj.IssuerChain})
+}
+
+// recordQeIdentity keeps the verified QE identity version when collateral history is enabled
+func recordQeIdentity(qeIDObj *parser.QeIdentityData) {
+	recordCollateral(&types.CollateralSnapshot{Collateral: collateralQeIdentity,
+		TcbEvaluationDataNumber: uint(qeIDObj.GetQeIDTcbEvaluationDataNumber()),
+		IssueDate:               parseCollateralDate(qeIDObj.GetQeIDIssueDate()),
+		NextUpdate:              parseCollateralDate(qeIDObj.GetQeIDNextUpdate()),
+		Content:                 string(qeIDObj.RawBlob),
+		IssuerChain:             qeIDObj.IssuerChain})
+}
+
+func recordCollateral(snapshot *types.CollateralSnapshot) {
+	repo := collateralHistoryRepository()
+	if repo == nil || snapshot.IssueDate.IsZero() {
+		return
+	}
+	key := snapshot.Collateral + "\x00" + snapshot.Fmspc + "\x00" + strconv.FormatInt(snapshot.IssueDate.Unix(), 10)
+	if _, recorded := recordedCollateral.LoadOrStore(key, true); recorded {
+		return
+	}
+	snapshot.ID = newRecordID()
+	snapshot.RecordedTime = time.Now().UTC()
+	err := repo.Save(snapshot)
+	if err != nil {
+		recordedCollateral.Delete(key)
+		log.WithError(err).Error("resource/collateral_history:recordCollateral() Error recording collateral version")
+	}
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/json"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/repository/memory"
+	"intel/isecl/sqvs/v4/types"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollateralSelection(t *testing.T) {
+	selection, err := selectCollateral(&QuoteData{})
+	assert.NoError(t, err)
+	assert.Nil(t, selection)
+	_, err = selectCollateral(&QuoteData{CollateralVersion: 10})
+	if assert.Error(t, err) {
+		assert.Equal(t, "Collateral history is not enabled", err.(*resourceError).Message)
+	}
+
+	db, err := memory.New("")
+	assert.NoError(t, err)
+	SetRepository(db)
+	defer SetRepository(nil)
+	config.Global().EnableCollateralHistory = true
+	defer func() { config.Global().EnableCollateralHistory = false }()
+
+	_, err = selectCollateral(&QuoteData{TcbEvaluationDate: "2021-06-15T10:00:00Z", CollateralVersion: 10})
+	assert.Error(t, err)
+	_, err = selectCollateral(&QuoteData{TcbEvaluationDate: "15 June"})
+	assert.Error(t, err)
+
+	var request QuoteData
+	assert.NoError(t, json.Unmarshal([]byte(`{"tcbEvaluationDate": "2021-06-15T10:00:00Z", "collateralVersion": 10}`),
+		&request))
+	assert.Equal(t, "2021-06-15T10:00:00Z", request.TcbEvaluationDate)
+	assert.Equal(t, uint(10), request.CollateralVersion)
+
+	issued := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	recordCollateral(&types.CollateralSnapshot{Collateral: collateralTcbInfo, Fmspc: "00906ea10000",
+		TcbEvaluationDataNumber: 10, IssueDate: issued, NextUpdate: issued.Add(30 * 24 * time.Hour), Content: "{}"})
+	data := &QuoteData{TcbEvaluationDate: "2021-06-15T10:00:00Z"}
+	selection, err = selectCollateral(data)
+	assert.NoError(t, err)
+	// the certificates are validated at the TCB evaluation date
+	assert.Equal(t, data.TcbEvaluationDate, data.EvaluationTime)
+	snapshot, at, err := selection.snapshot(collateralTcbInfo, "00906ea10000")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(10), snapshot.TcbEvaluationDataNumber)
+	assert.Equal(t, time.Date(2021, 6, 15, 10, 0, 0, 0, time.UTC), at)
+
+	// the recorded TCB info is outdated at a later date
+	selection, err = selectCollateral(&QuoteData{TcbEvaluationDate: "2021-08-01T00:00:00Z"})
+	assert.NoError(t, err)
+	_, _, err = selection.snapshot(collateralTcbInfo, "00906ea10000")
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusNotFound, err.(*resourceError).StatusCode)
+	}
+
+	selection, err = selectCollateral(&QuoteData{CollateralVersion: 10})
+	assert.NoError(t, err)
+	_, at, err = selection.snapshot(collateralTcbInfo, "00906ea10000")
+	assert.NoError(t, err)
+	assert.Equal(t, issued, at)
+	_, _, err = selection.snapshot(collateralQeIdentity, "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,275 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/pem"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"intel/isecl/sqvs/v4/resource/verifier"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// Headers of the collateral served to relying parties verifying quotes locally, the issuer chains are named
+// as by SCS
+const (
+	tcbInfoIssuerChainHeader    = "SGX-TCB-Info-Issuer-Chain"
+	qeIdentityIssuerChainHeader = "SGX-Qe-Identity-Issuer-Chain"
+	pckCrlIssuerChainHeader     = "SGX-PCK-CRL-Issuer-Chain"
+	collateralSourceHeader      = "SQVS-Collateral-Source"
+
+	contentTypePEM = "application/x-pem-file"
+	contentTypeCRL = "application/pkix-crl"
+)
+
+// servedCollateral is collateral served by the collateral endpoints, cached until expires
+type servedCollateral struct {
+	content     []byte
+	issuerChain string
+	source      string
+	expires     time.Time
+}
+
+// collateralProxyCache caches the collateral served to relying parties, so their local verifications do not
+// reach SCS more often than the verifications of SQVS
+var collateralProxyCache = struct {
+	sync.Mutex
+	entries map[string]*servedCollateral
+}{entries: map[string]*servedCollateral{}}
+
+func CollateralProxyCB(router *mux.Router) {
+	router.Handle("/collateral/root-ca", getCollateralRootCA()).Methods("GET")
+	router.Handle("/collateral/tcb/{fmspc}", getCollateralTcbInfo()).Methods("GET")
+	router.Handle("/collateral/qe-identity", getCollateralQeIdentity()).Methods("GET")
+	router.Handle("/collateral/pck-crl/{ca}", getCollateralPckCrl()).Methods("GET")
+}
+
+func getCollateralRootCA() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/collateral_proxy:getCollateralRootCA() Entering")
+		defer log.Trace("resource/collateral_proxy:getCollateralRootCA() Leaving")
+
+		err := authorizeCollateralReader(r)
+		if err != nil {
+			return err
+		}
+		rootCA, err := readSGXRootCaCert()
+		if err != nil {
+			log.WithError(err).Error("resource/collateral_proxy:getCollateralRootCA() Error reading trusted SGX root CA")
+			return &resourceError{Message: "Error reading trusted SGX root CA", StatusCode: http.StatusInternalServerError}
+		}
+		collateral := &servedCollateral{
+			content: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rootCA.Raw}),
+			source:  "trusted",
+			expires: time.Now().Add(collateralCacheTTL()),
+		}
+		return writeCollateral(w, r, contentTypePEM, "", collateral)
+	}
+}
+
+func getCollateralTcbInfo() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/collateral_proxy:getCollateralTcbInfo() Entering")
+		defer log.Trace("resource/collateral_proxy:getCollateralTcbInfo() Leaving")
+
+		err := authorizeCollateralReader(r)
+		if err != nil {
+			return err
+		}
+		fmspc := strings.ToLower(mux.Vars(r)["fmspc"])
+		if !fmspcRegex.MatchString(fmspc) {
+			return &resourceError{Message: "Invalid FMSPC, must be 12 hexadecimal characters",
+				StatusCode: http.StatusBadRequest}
+		}
+		collateral, err := cachedCollateral("tcb/"+fmspc, func() (*servedCollateral, error) {
+			if pinned := pinnedTcbInfo(fmspc, time.Now()); pinned != nil {
+				return pinned, nil
+			}
+			tcbObj, err := parser.NewTcbInfo(r.Context(), fmspc)
+			if err != nil {
+				return nil, err
+			}
+			_, err = verifier.VerifyCollateralSignature(tcbObj.RawBlob, verifier.CollateralTcbInfo,
+				tcbObj.TcbInfoData.Signature, tcbObj.GetTcbInfoInterCaList())
+			if err != nil {
+				return nil, errors.Wrap(err, "Invalid TCB info signature")
+			}
+			return &servedCollateral{content: tcbObj.RawBlob, issuerChain: tcbObj.IssuerChain,
+				source: collateralSourceSCS, expires: nextUpdate(tcbObj.GetTcbInfoNextUpdate())}, nil
+		})
+		if err != nil {
+			log.WithError(err).Error("resource/collateral_proxy:getCollateralTcbInfo() Error fetching TCB info")
+			return fetchFailure(err, "Error fetching TCB info", http.StatusBadGateway)
+		}
+		return writeCollateral(w, r, contentTypeJSON, tcbInfoIssuerChainHeader, collateral)
+	}
+}
+
+func getCollateralQeIdentity() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/collateral_proxy:getCollateralQeIdentity() Entering")
+		defer log.Trace("resource/collateral_proxy:getCollateralQeIdentity() Leaving")
+
+		err := authorizeCollateralReader(r)
+		if err != nil {
+			return err
+		}
+		collateral, err := cachedCollateral("qe-identity", func() (*servedCollateral, error) {
+			qeIDObj, err := parser.NewQeIdentity(r.Context())
+			if err != nil {
+				return nil, err
+			}
+			_, err = verifier.VerifyCollateralSignature(qeIDObj.RawBlob, verifier.CollateralQeIdentity,
+				qeIDObj.QEJson.Signature, qeIDObj.GetQeInfoInterCaList())
+			if err != nil {
+				return nil, errors.Wrap(err, "Invalid QE identity signature")
+			}
+			return &servedCollateral{content: qeIDObj.RawBlob, issuerChain: qeIDObj.IssuerChain,
+				source: collateralSourceSCS, expires: nextUpdate(qeIDObj.GetQeIDNextUpdate())}, nil
+		})
+		if err != nil {
+			log.WithError(err).Error("resource/collateral_proxy:getCollateralQeIdentity() Error fetching QE identity")
+			return fetchFailure(err, "Error fetching QE identity", http.StatusBadGateway)
+		}
+		return writeCollateral(w, r, contentTypeJSON, qeIdentityIssuerChainHeader, collateral)
+	}
+}
+
+func getCollateralPckCrl() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/collateral_proxy:getCollateralPckCrl() Entering")
+		defer log.Trace("resource/collateral_proxy:getCollateralPckCrl() Leaving")
+
+		err := authorizeCollateralReader(r)
+		if err != nil {
+			return err
+		}
+		ca := strings.ToLower(mux.Vars(r)["ca"])
+		if ca != parser.PckCAProcessor && ca != parser.PckCAPlatform {
+			return &resourceError{Message: "Invalid PCK CA, must be processor or platform",
+				StatusCode: http.StatusBadRequest}
+		}
+		collateral, err := cachedCollateral("pck-crl/"+ca, func() (*servedCollateral, error) {
+			rootCA, err := readSGXRootCaCert()
+			if err != nil {
+				return nil, err
+			}
+			crl, issuerChain, source, err := parser.FetchPckCrl(r.Context(), ca, rootCA)
+			if err != nil {
+				return nil, err
+			}
+			return &servedCollateral{content: crl, issuerChain: issuerChain, source: source.Source,
+				expires: nextUpdate(source.NextUpdate)}, nil
+		})
+		if err != nil {
+			log.WithError(err).Error("resource/collateral_proxy:getCollateralPckCrl() Error fetching PCK CRL")
+			return fetchFailure(err, "Error fetching PCK CRL", http.StatusBadGateway)
+		}
+		return writeCollateral(w, r, contentTypeCRL, pckCrlIssuerChainHeader, collateral)
+	}
+}
+
+// cachedCollateral returns the collateral cached under key or, once it expired, the collateral fetched again.
+// Collateral is cached for SQVS_COLLATERAL_CACHE_TTL at most and never past its next update.
+func cachedCollateral(key string, fetch func() (*servedCollateral, error)) (*servedCollateral, error) {
+	now := time.Now()
+	collateralProxyCache.Lock()
+	cached, ok := collateralProxyCache.entries[key]
+	collateralProxyCache.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached, nil
+	}
+
+	collateral, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	if maxExpires := now.Add(collateralCacheTTL()); collateral.expires.IsZero() || collateral.expires.After(maxExpires) {
+		collateral.expires = maxExpires
+	}
+	collateralProxyCache.Lock()
+	collateralProxyCache.entries[key] = collateral
+	collateralProxyCache.Unlock()
+	return collateral, nil
+}
+
+// pinnedTcbInfo returns the TCB info pinned for an enrolled platform of the FMSPC while it is current, so relying
+// parties verify the quotes of enrolled platforms against the same TCB info as SQVS
+func pinnedTcbInfo(fmspc string, now time.Time) *servedCollateral {
+	if sqvsDB == nil {
+		return nil
+	}
+	platforms, err := sqvsDB.EnrolledPlatformRepository().RetrieveAll()
+	if err != nil {
+		log.WithError(err).Error("resource/collateral_proxy:pinnedTcbInfo() Error retrieving enrolled platforms")
+		return nil
+	}
+	for _, platform := range platforms {
+		if platform.Fmspc == fmspc && platform.TcbInfo != "" && now.Before(platform.TcbInfoNextUpdate) {
+			return &servedCollateral{content: []byte(platform.TcbInfo), issuerChain: platform.TcbInfoIssuerChain,
+				source: collateralSourceEnrollment, expires: platform.TcbInfoNextUpdate}
+		}
+	}
+	return nil
+}
+
+func nextUpdate(date string) time.Time {
+	t, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func collateralCacheTTL() time.Duration {
+	if conf := config.Global(); conf != nil && conf.CollateralCacheTTL > 0 {
+		return conf.CollateralCacheTTL
+	}
+	return constants.DefaultCollateralCacheTTL
+}
+
+// authorizeCollateralReader authorizes access to the collateral endpoints, restricted to the CollateralReader role
+func authorizeCollateralReader(r *http.Request) error {
+	conf := config.Global()
+	if conf == nil {
+		return &resourceError{Message: "Could not read config", StatusCode: http.StatusInternalServerError}
+	}
+	if conf.IncludeToken {
+		return AuthorizeEndpoint(r, constants.CollateralReaderGroupName, true)
+	}
+	return nil
+}
+
+func writeCollateral(w http.ResponseWriter, r *http.Request, contentType, issuerChainHeader string,
+	collateral *servedCollateral) error {
+	maxAge := int(time.Until(collateral.expires).Seconds())
+	if maxAge < 0 {
+		maxAge = 0
+	}
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(maxAge))
+	if notModified(w, r, entityTag(collateral.content)) {
+		return nil
+	}
+	w.Header().Set("Content-Type", contentType)
+	if issuerChainHeader != "" && collateral.issuerChain != "" {
+		w.Header().Set(issuerChainHeader, collateral.issuerChain)
+	}
+	w.Header().Set(collateralSourceHeader, collateral.source)
+	w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write(collateral.content)
+	if err != nil {
+		return &resourceError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+	return nil
+}
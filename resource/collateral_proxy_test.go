@@ -0,0 +1,51 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachedCollateral(t *testing.T) {
+	fetches := 0
+	fetch := func(expires time.Time) func() (*servedCollateral, error) {
+		return func() (*servedCollateral, error) {
+			fetches++
+			return &servedCollateral{content: []byte("{}"), source: collateralSourceSCS, expires: expires}, nil
+		}
+	}
+
+	// collateral is not cached past the cache TTL
+	collateral, err := cachedCollateral("test/ttl", fetch(time.Now().Add(24*time.Hour)))
+	assert.NoError(t, err)
+	assert.True(t, collateral.expires.Before(time.Now().Add(collateralCacheTTL()+time.Second)))
+	_, err = cachedCollateral("test/ttl", fetch(time.Now().Add(24*time.Hour)))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fetches)
+
+	// nor past its next update
+	_, err = cachedCollateral("test/next-update", fetch(time.Now().Add(-time.Second)))
+	assert.NoError(t, err)
+	_, err = cachedCollateral("test/next-update", fetch(time.Now().Add(-time.Second)))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, fetches)
+}
+
+func TestCollateralProxyInvalidRequests(t *testing.T) {
+	router := mux.NewRouter()
+	CollateralProxyCB(router)
+	for _, path := range []string{"/collateral/tcb/00906ed5", "/collateral/tcb/zz906ed50000",
+		"/collateral/pck-crl/root"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+	}
+}
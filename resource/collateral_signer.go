@@ -0,0 +1,38 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"intel/isecl/sqvs/v4/resource/verifier"
+)
+
+const (
+	collateralTcbInfo    = "tcb_info"
+	collateralQeIdentity = "qe_identity"
+)
+
+// CollateralSigner identifies the Intel SGX TCB Signing certificate a collateral was signed with and the
+// public key hash of the root CA its chain was verified against
+type CollateralSigner struct {
+	Collateral        string `json:"Collateral"`
+	Subject           string `json:"Subject"`
+	SerialNumber      string `json:"SerialNumber"`
+	SHA256Fingerprint string `json:"SHA256Fingerprint"`
+	RootKeyHash       string `json:"RootKeyHash"`
+}
+
+func newCollateralSigner(collateral string, signer, rootCA *x509.Certificate) *CollateralSigner {
+	fingerprint := sha256.Sum256(signer.Raw)
+	return &CollateralSigner{
+		Collateral:        collateral,
+		Subject:           signer.Subject.String(),
+		SerialNumber:      signer.SerialNumber.Text(16),
+		SHA256Fingerprint: hex.EncodeToString(fingerprint[:]),
+		RootKeyHash:       verifier.RootKeyHash(rootCA),
+	}
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/json"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ConfigHistory is the audit of the configuration changes, newest first. PendingChanges are the changes of
+// config.yml not in effect yet, until the service is restarted.
+type ConfigHistory struct {
+	PendingChanges []config.ConfigChange       `json:"pendingChanges"`
+	Changes        []config.ConfigHistoryEntry `json:"changes"`
+}
+
+func ConfigHistoryCB(router *mux.Router) {
+	router.Handle("/admin/config/history", getConfigHistory()).Methods("GET")
+}
+
+func getConfigHistory() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/config_history:getConfigHistory() Entering")
+		defer log.Trace("resource/config_history:getConfigHistory() Leaving")
+
+		err := authorizeAdministrator(r)
+		if err != nil {
+			return err
+		}
+		var since time.Time
+		if value := r.URL.Query().Get("since"); value != "" {
+			since, err = time.Parse(time.RFC3339, value)
+			if err != nil {
+				return &resourceError{Message: "Invalid since query parameter, must be an RFC 3339 time",
+					StatusCode: http.StatusBadRequest}
+			}
+		}
+		limit := 0
+		if value := r.URL.Query().Get("limit"); value != "" {
+			limit, err = strconv.Atoi(value)
+			if err != nil || limit < 1 {
+				return &resourceError{Message: "Invalid limit query parameter", StatusCode: http.StatusBadRequest}
+			}
+		}
+
+		entries, err := config.ReadHistory(configHistoryFile())
+		if err != nil {
+			log.WithError(err).Error("resource/config_history:getConfigHistory() Error reading configuration history")
+			return &resourceError{Message: "Error reading configuration history", StatusCode: http.StatusInternalServerError}
+		}
+		history := ConfigHistory{
+			PendingChanges: config.Diff(config.Global(), config.Load(configFile())),
+			Changes:        []config.ConfigHistoryEntry{},
+		}
+		for i := len(entries) - 1; i >= 0 && (limit == 0 || len(history.Changes) < limit); i-- {
+			if entries[i].Time.Before(since) {
+				break
+			}
+			history.Changes = append(history.Changes, entries[i])
+		}
+		if history.PendingChanges == nil {
+			history.PendingChanges = []config.ConfigChange{}
+		}
+
+		body, err := json.Marshal(history)
+		if err != nil {
+			return &resourceError{Message: "Error marshalling configuration history in JSON",
+				StatusCode: http.StatusInternalServerError}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(body)
+		if err != nil {
+			return &resourceError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+		return nil
+	}
+}
+
+// recordAPIChanges records the configuration changes an administrator made through the API
+func recordAPIChanges(r *http.Request, changes []config.ConfigChange) {
+	author, _ := tokenClaim(r, "sub").(string)
+	if author == "" {
+		author = callerOf(r).Address
+	}
+	err := config.RecordChanges(configHistoryFile(), config.ChangeSourceAPI, author, changes)
+	if err != nil {
+		log.WithError(err).Error("resource/config_history:recordAPIChanges() Error recording configuration changes")
+	}
+}
+
+func configFile() string {
+	return path.Join(constants.ConfigDir, constants.ConfigFile)
+}
+
+func configHistoryFile() string {
+	return config.HistoryFile(configFile())
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/json"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/dependencies"
+	"intel/isecl/sqvs/v4/resilience"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// DependencyStatus is the diagnostic view of a service SQVS relies on: whether it is reachable now, and the
+// outcome and latency of the recent requests made to it
+type DependencyStatus struct {
+	Name              string `json:"name"`
+	URL               string `json:"url"`
+	Reachable         bool   `json:"reachable"`
+	ReachabilityError string `json:"reachabilityError,omitempty"`
+	resilience.HostStatus
+}
+
+// DependenciesResponse lists the status of the services SQVS relies on
+type DependenciesResponse struct {
+	CheckedAt    time.Time          `json:"checkedAt"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
+
+func DependenciesCB(router *mux.Router) {
+	router.Handle("/admin/dependencies", getDependencies()).Methods("GET")
+}
+
+func getDependencies() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/dependencies:getDependencies() Entering")
+		defer log.Trace("resource/dependencies:getDependencies() Leaving")
+
+		err := authorizeAdministrator(r)
+		if err != nil {
+			return err
+		}
+		resp := DependenciesResponse{
+			CheckedAt:    time.Now().UTC(),
+			Dependencies: dependencyStatuses(dependencies.FromConfig(config.Global())),
+		}
+
+		body, err := json.Marshal(resp)
+		if err != nil {
+			return &resourceError{Message: "Error marshalling dependencies in JSON",
+				StatusCode: http.StatusInternalServerError}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(body)
+		if err != nil {
+			return &resourceError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+		return nil
+	}
+}
+
+// dependencyStatuses checks the reachability of the dependencies concurrently and collects the statistics of
+// the requests made to them
+func dependencyStatuses(deps []dependencies.Dependency) []DependencyStatus {
+	statuses := make([]DependencyStatus, len(deps))
+	var wg sync.WaitGroup
+	for i, dep := range deps {
+		var host string
+		if u, err := url.Parse(dep.URL); err == nil {
+			host = u.Host
+		}
+		statuses[i] = DependencyStatus{
+			Name:       dep.Name,
+			URL:        dep.URL,
+			HostStatus: resilience.Default().HostStatus(host),
+		}
+
+		wg.Add(1)
+		go func(status *DependencyStatus, dep dependencies.Dependency) {
+			defer wg.Done()
+			err := dep.Check()
+			status.Reachable = err == nil
+			if err != nil {
+				status.ReachabilityError = err.Error()
+			}
+		}(&statuses[i], dep)
+	}
+	wg.Wait()
+	return statuses
+}
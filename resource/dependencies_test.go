@@ -0,0 +1,43 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/json"
+	"intel/isecl/sqvs/v4/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDependencies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	conf := config.Global()
+	conf.IncludeToken = false
+	scsBaseURL, cmsBaseURL := conf.SCSBaseURL, conf.CMSBaseURL
+	defer func() { conf.SCSBaseURL, conf.CMSBaseURL = scsBaseURL, cmsBaseURL }()
+	conf.SCSBaseURL = server.URL + "/scs/sgx/certification/v1/"
+	conf.CMSBaseURL = "https://127.0.0.1:1/cms/v1/"
+
+	router := mux.NewRouter()
+	DependenciesCB(router.PathPrefix("/svs/v1/").Subrouter())
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/svs/v1/admin/dependencies", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var resp DependenciesResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	reachable := map[string]bool{}
+	for _, dep := range resp.Dependencies {
+		reachable[dep.Name] = dep.Reachable
+		assert.Equal(t, "closed", dep.BreakerState)
+	}
+	assert.Equal(t, map[string]bool{"CMS": false, "SCS": true}, reachable)
+}
@@ -0,0 +1,311 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	commLogMsg "intel/isecl/lib/common/v4/log/message"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/logformat"
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"intel/isecl/sqvs/v4/sigstruct"
+	"intel/isecl/sqvs/v4/types"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// enclaveFormField is the multipart form file field of the SIGSTRUCT files or signed enclaves to import
+const enclaveFormField = "enclave"
+
+var measurementRegex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+var enclaveIdentityListSpec = listSpec{
+	FilterFields: []string{"mrEnclave", "mrSigner"},
+	SortFields:   []string{"mrEnclave", "mrSigner", "registeredTime"},
+}
+
+func EnclaveIdentityCB(router *mux.Router) {
+	router.Handle("/admin/enclaves", listEnclaveIdentities()).Methods("GET")
+	router.Handle("/admin/enclaves", importEnclaveIdentities()).Methods("POST")
+	router.Handle("/admin/enclaves/{mrEnclave}/{mrSigner}", deleteEnclaveIdentity()).Methods("DELETE")
+}
+
+func listEnclaveIdentities() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/enclave_identities:listEnclaveIdentities() Entering")
+		defer log.Trace("resource/enclave_identities:listEnclaveIdentities() Leaving")
+
+		repo, err := enclaveIdentityRepository(r)
+		if err != nil {
+			return err
+		}
+		criteria, err := parseListQuery(r, enclaveIdentityListSpec)
+		if err != nil {
+			return err
+		}
+		enclaves, total, err := repo.Search(criteria)
+		if err != nil {
+			log.WithError(err).Error("resource/enclave_identities:listEnclaveIdentities() Error retrieving enclave identities")
+			return &resourceError{Message: "Error retrieving enclave identities", StatusCode: http.StatusInternalServerError}
+		}
+		return writeListResponse(w, r, enclaves, criteria, total)
+	}
+}
+
+// importEnclaveIdentities registers the enclaves signed by the SIGSTRUCT files or signed enclave binaries of
+// the request, the raw file for application/octet-stream or the "enclave" files of a multipart form. Either
+// all enclaves are registered or, when any of the files is invalid, none is.
+func importEnclaveIdentities() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/enclave_identities:importEnclaveIdentities() Entering")
+		defer log.Trace("resource/enclave_identities:importEnclaveIdentities() Leaving")
+
+		repo, err := enclaveIdentityRepository(r)
+		if err != nil {
+			return err
+		}
+		files, err := readEnclaveFiles(w, r)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		enclaves := make(types.EnclaveIdentities, 0, len(files))
+		for _, file := range files {
+			s, err := sigstruct.Parse(file.data)
+			if err != nil {
+				slog.WithFields(adminEventFields(logformat.OutcomeFailure)).WithError(err).Errorf(
+					"resource/enclave_identities:importEnclaveIdentities() %s: Invalid SIGSTRUCT in %s",
+					commLogMsg.InvalidInputBadParam, file.name)
+				return &resourceError{Message: fmt.Sprintf("Invalid SIGSTRUCT or signed enclave %s", file.name),
+					StatusCode: http.StatusBadRequest}
+			}
+			enclaves = append(enclaves, NewEnclaveIdentity(s, file.description, now))
+		}
+		for i := range enclaves {
+			err = repo.Save(&enclaves[i])
+			if err != nil {
+				log.WithError(err).Error("resource/enclave_identities:importEnclaveIdentities() Error saving enclave identity")
+				return &resourceError{Message: "Error saving enclave identity", StatusCode: http.StatusInternalServerError}
+			}
+			slog.WithFields(adminEventFields(logformat.OutcomeSuccess)).Infof(
+				"resource/enclave_identities:importEnclaveIdentities() Registered enclave with MRENCLAVE %s and "+
+					"MRSIGNER %s", enclaves[i].MrEnclave, enclaves[i].MrSigner)
+		}
+		return writeEnclaveIdentities(w, r, http.StatusOK, enclaves)
+	}
+}
+
+func deleteEnclaveIdentity() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/enclave_identities:deleteEnclaveIdentity() Entering")
+		defer log.Trace("resource/enclave_identities:deleteEnclaveIdentity() Leaving")
+
+		repo, err := enclaveIdentityRepository(r)
+		if err != nil {
+			return err
+		}
+		vars := mux.Vars(r)
+		mrEnclave, mrSigner := strings.ToLower(vars["mrEnclave"]), strings.ToLower(vars["mrSigner"])
+		if !measurementRegex.MatchString(mrEnclave) || !measurementRegex.MatchString(mrSigner) {
+			return &resourceError{Message: "Invalid enclave, mrEnclave and mrSigner must be 64 hexadecimal characters",
+				StatusCode: http.StatusBadRequest}
+		}
+		err = repo.Delete(mrEnclave, mrSigner)
+		if err == repository.ErrRecordNotFound {
+			return &resourceError{Message: "Enclave is not registered", StatusCode: http.StatusNotFound}
+		} else if err != nil {
+			log.WithError(err).Error("resource/enclave_identities:deleteEnclaveIdentity() Error deleting enclave identity")
+			return &resourceError{Message: "Error deleting enclave identity", StatusCode: http.StatusInternalServerError}
+		}
+		slog.WithFields(adminEventFields(logformat.OutcomeSuccess)).Infof(
+			"resource/enclave_identities:deleteEnclaveIdentity() Removed enclave with MRENCLAVE %s and MRSIGNER %s",
+			mrEnclave, mrSigner)
+		w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+}
+
+// NewEnclaveIdentity returns the registration of the enclave signed by the SIGSTRUCT
+func NewEnclaveIdentity(s *sigstruct.SigStruct, description string, registered time.Time) types.EnclaveIdentity {
+	return types.EnclaveIdentity{
+		MrEnclave:      s.MrEnclave,
+		MrSigner:       s.MrSigner,
+		IsvProdID:      s.IsvProdID,
+		IsvSvn:         s.IsvSvn,
+		Attributes:     s.Attributes,
+		AttributesMask: s.AttributesMask,
+		Description:    description,
+		RegisteredTime: registered,
+	}
+}
+
+type enclaveFile struct {
+	name        string
+	description string
+	data        []byte
+}
+
+func readEnclaveFiles(w http.ResponseWriter, r *http.Request) ([]enclaveFile, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, constants.MaxEnclaveUpload)
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, &resourceError{Message: "Invalid Content-Type", StatusCode: http.StatusUnsupportedMediaType}
+	}
+
+	switch mediaType {
+	case contentTypeOctet:
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return nil, &resourceError{Message: "Error reading enclave file", StatusCode: http.StatusBadRequest}
+		}
+		return []enclaveFile{{name: "request body", description: r.URL.Query().Get("description"), data: data}}, nil
+
+	case contentTypeMultipart:
+		err = r.ParseMultipartForm(constants.MaxEnclaveUpload)
+		if err != nil {
+			slog.WithError(err).Errorf("resource/enclave_identities:readEnclaveFiles() %s: Failed to parse "+
+				"multipart form", commLogMsg.InvalidInputBadEncoding)
+			return nil, &resourceError{Message: "Invalid multipart form provided", StatusCode: http.StatusBadRequest}
+		}
+		defer func() {
+			derr := r.MultipartForm.RemoveAll()
+			if derr != nil {
+				log.WithError(derr).Error("Error removing multipart form temporary files")
+			}
+		}()
+
+		headers := r.MultipartForm.File[enclaveFormField]
+		if len(headers) == 0 {
+			return nil, &resourceError{Message: "enclave form file not provided", StatusCode: http.StatusBadRequest}
+		}
+		files := make([]enclaveFile, 0, len(headers))
+		for _, header := range headers {
+			file, err := header.Open()
+			if err != nil {
+				return nil, &resourceError{Message: "Error reading enclave form file", StatusCode: http.StatusBadRequest}
+			}
+			data, err := ioutil.ReadAll(file)
+			derr := file.Close()
+			if derr != nil {
+				log.WithError(derr).Error("Error closing enclave form file")
+			}
+			if err != nil {
+				return nil, &resourceError{Message: "Error reading enclave form file", StatusCode: http.StatusBadRequest}
+			}
+			// the enclaves are described by their file name unless a description is provided
+			description := r.FormValue("description")
+			if description == "" {
+				description = header.Filename
+			}
+			files = append(files, enclaveFile{name: header.Filename, description: description, data: data})
+		}
+		return files, nil
+	}
+	return nil, &resourceError{Message: "Content-Type must be " + contentTypeOctet + " or " + contentTypeMultipart,
+		StatusCode: http.StatusUnsupportedMediaType}
+}
+
+// enclaveIdentityRepository authorizes access to the registered enclaves and returns their repository
+func enclaveIdentityRepository(r *http.Request) (repository.EnclaveIdentityRepository, error) {
+	err := authorizeAdministrator(r)
+	if err != nil {
+		return nil, err
+	}
+	if sqvsDB == nil {
+		return nil, &resourceError{Message: "Enclave registration is not enabled", StatusCode: http.StatusNotFound}
+	}
+	return sqvsDB.EnclaveIdentityRepository(), nil
+}
+
+func writeEnclaveIdentities(w http.ResponseWriter, r *http.Request, status int, enclaves types.EnclaveIdentities) error {
+	body, err := json.Marshal(enclaves)
+	if err != nil {
+		return &resourceError{Message: "Error marshalling enclave identities in JSON",
+			StatusCode: http.StatusInternalServerError}
+	}
+	if notModified(w, r, entityTag(body)) {
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+	w.WriteHeader(status)
+	_, err = w.Write(body)
+	if err != nil {
+		return &resourceError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+	return nil
+}
+
+// checkEnclaveIdentityPolicy rejects the quotes of enclaves that have not been registered when registered
+// enclaves are required
+func checkEnclaveIdentityPolicy(report *parser.ReportBody) error {
+	if conf := config.Global(); conf == nil || !conf.RequireRegisteredEnclaves {
+		return nil
+	}
+	if sqvsDB == nil {
+		log.Error("resource/enclave_identities:checkEnclaveIdentityPolicy() Registered enclaves are required but no store is configured")
+		return &resourceError{Message: "Enclave registration is not available", StatusCode: http.StatusInternalServerError}
+	}
+	mrEnclave, mrSigner := hex.EncodeToString(report.MrEnclave[:]), hex.EncodeToString(report.MrSigner[:])
+	enclave, err := sqvsDB.EnclaveIdentityRepository().Retrieve(mrEnclave, mrSigner)
+	if err == repository.ErrRecordNotFound {
+		slog.Errorf("resource/enclave_identities:checkEnclaveIdentityPolicy() Quote received from enclave with "+
+			"MRENCLAVE %s and MRSIGNER %s that is not registered", mrEnclave, mrSigner)
+		return &resourceError{Message: "Enclave is not registered", StatusCode: http.StatusForbidden}
+	} else if err != nil {
+		log.WithError(err).Error("resource/enclave_identities:checkEnclaveIdentityPolicy() Error retrieving enclave identity")
+		return &resourceError{Message: "Error retrieving enclave identity", StatusCode: http.StatusInternalServerError}
+	}
+	err = matchEnclaveIdentity(enclave, report)
+	if err != nil {
+		slog.WithError(err).Errorf("resource/enclave_identities:checkEnclaveIdentityPolicy() Quote of enclave with "+
+			"MRENCLAVE %s and MRSIGNER %s does not match its registration", mrEnclave, mrSigner)
+		return &resourceError{Message: "Enclave does not match its registration: " + err.Error(),
+			StatusCode: http.StatusForbidden}
+	}
+	return nil
+}
+
+// matchEnclaveIdentity checks the product, the security version and the attributes of an enclave report against
+// the registration of the enclave, as the launch of the enclave checks them against its SIGSTRUCT
+func matchEnclaveIdentity(enclave *types.EnclaveIdentity, report *parser.ReportBody) error {
+	if report.SgxIsvProdID != enclave.IsvProdID {
+		return errors.Errorf("ISVPRODID %d is not the registered %d", report.SgxIsvProdID, enclave.IsvProdID)
+	}
+	if report.SgxIsvSvn < enclave.IsvSvn {
+		return errors.Errorf("ISVSVN %d is below the registered %d", report.SgxIsvSvn, enclave.IsvSvn)
+	}
+	attributes, err := hex.DecodeString(enclave.Attributes)
+	if err != nil || len(attributes) != len(report.SgxAttributes) {
+		return errors.New("registered attributes are invalid")
+	}
+	mask, err := hex.DecodeString(enclave.AttributesMask)
+	if err != nil || len(mask) != len(report.SgxAttributes) {
+		return errors.New("registered attributes mask is invalid")
+	}
+	for i := range mask {
+		attributes[i] &= mask[i]
+		mask[i] &= report.SgxAttributes[i]
+	}
+	if !bytes.Equal(mask, attributes) {
+		return errors.Errorf("attributes %x do not match the registered %s under mask %s", report.SgxAttributes,
+			enclave.Attributes, enclave.AttributesMask)
+	}
+	return nil
+}
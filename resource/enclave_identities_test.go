@@ -0,0 +1,36 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"intel/isecl/sqvs/v4/resource/parser"
+	"intel/isecl/sqvs/v4/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchEnclaveIdentity(t *testing.T) {
+	enclave := &types.EnclaveIdentity{IsvProdID: 7, IsvSvn: 2, Attributes: "04000000000000000300000000000000",
+		AttributesMask: "fdffffffffffffff0000000000000000"}
+	report := &parser.ReportBody{SgxIsvProdID: 7, SgxIsvSvn: 2}
+	// the debug flag and the XFRM are masked out
+	report.SgxAttributes[0] = 0x06
+	report.SgxAttributes[8] = 0xe7
+	assert.NoError(t, matchEnclaveIdentity(enclave, report))
+
+	report.SgxIsvSvn = 3
+	assert.NoError(t, matchEnclaveIdentity(enclave, report))
+	report.SgxIsvSvn = 1
+	assert.Error(t, matchEnclaveIdentity(enclave, report))
+
+	report.SgxIsvSvn = 2
+	report.SgxIsvProdID = 8
+	assert.Error(t, matchEnclaveIdentity(enclave, report))
+
+	report.SgxIsvProdID = 7
+	report.SgxAttributes[0] = 0x07 | 0x10
+	assert.Error(t, matchEnclaveIdentity(enclave, report))
+}
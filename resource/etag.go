@@ -0,0 +1,54 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// entityTag returns the strong entity tag of a response body, the truncated SHA-256 of the body
+func entityTag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// weakEntityTag returns the weak entity tag of content, for responses that are semantically unchanged while
+// some of their fields, such as the uptime, change on every request
+func weakEntityTag(content []byte) string {
+	return "W/" + entityTag(content)
+}
+
+// notModified sets the ETag of the response to a GET request and reports whether the request already holds
+// the response, its If-None-Match header matching the ETag. The response is then written as 304 Not Modified
+// without a body, so polling clients do not transfer unchanged responses again.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	w.Header().Set("ETag", etag)
+	if !etagMatches(r.Header.Get("If-None-Match"), etag) {
+		return false
+	}
+	w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatches reports whether an If-None-Match header matches etag, using the weak comparison of RFC 7232
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
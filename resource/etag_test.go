@@ -0,0 +1,44 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotModified(t *testing.T) {
+	etag := entityTag([]byte("{}"))
+	assert.Equal(t, etag, entityTag([]byte("{}")))
+	assert.NotEqual(t, etag, entityTag([]byte("[]")))
+
+	for ifNoneMatch, matches := range map[string]bool{
+		"":             false,
+		`"0"`:          false,
+		etag:           true,
+		"W/" + etag:    true,
+		`"0", ` + etag: true,
+		"*":            true,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/svs/v1/usage", nil)
+		r.Header.Set("If-None-Match", ifNoneMatch)
+		w := httptest.NewRecorder()
+		assert.Equal(t, matches, notModified(w, r, etag), ifNoneMatch)
+		assert.Equal(t, etag, w.Header().Get("ETag"))
+		if matches {
+			assert.Equal(t, http.StatusNotModified, w.Code)
+		}
+	}
+
+	// only GET responses are conditional
+	r := httptest.NewRequest(http.MethodPost, "/svs/v1/admin/enclaves", nil)
+	r.Header.Set("If-None-Match", "*")
+	w := httptest.NewRecorder()
+	assert.False(t, notModified(w, r, etag))
+	assert.Empty(t, w.Header().Get("ETag"))
+}
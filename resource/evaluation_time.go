@@ -0,0 +1,42 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	commLogMsg "intel/isecl/lib/common/v4/log/message"
+	"intel/isecl/sqvs/v4/trustedtime"
+	"net/http"
+	"time"
+)
+
+// evaluationTime returns the current trusted time, that collateral fetched from SCS is checked against, and
+// the time the certificates of the quote are validated at. The requested evaluation time cannot be later
+// than the current trusted time.
+func evaluationTime(requested string) (time.Time, time.Time, error) {
+	now, err := trustedtime.Now()
+	if err != nil {
+		log.WithError(err).Error("resource/evaluation_time:evaluationTime() Trusted time is not available")
+		return time.Time{}, time.Time{}, &resourceError{Message: "Trusted time is not available",
+			StatusCode: http.StatusServiceUnavailable}
+	}
+	if requested == "" {
+		return now, now, nil
+	}
+
+	at, err := time.Parse(time.RFC3339, requested)
+	if err != nil {
+		slog.WithError(err).Errorf("resource/evaluation_time:evaluationTime() %s: Invalid evaluation time",
+			commLogMsg.InvalidInputBadParam)
+		return time.Time{}, time.Time{}, &resourceError{Message: "Invalid evaluationTime provided",
+			StatusCode: http.StatusBadRequest}
+	}
+	if at.After(now) {
+		slog.Errorf("resource/evaluation_time:evaluationTime() %s: Evaluation time is in the future",
+			commLogMsg.InvalidInputBadParam)
+		return time.Time{}, time.Time{}, &resourceError{Message: "evaluationTime cannot be in the future",
+			StatusCode: http.StatusBadRequest}
+	}
+	return now, at.UTC(), nil
+}
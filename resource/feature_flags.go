@@ -0,0 +1,42 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/metrics"
+	"net/http"
+)
+
+var featureEnabled = metrics.NewGauge("sqvs_feature_enabled",
+	"Feature flags of the instance, 1 when enabled and 0 when disabled", "feature")
+
+// PublishFeatureFlags reports the feature flags conf enables in the metrics, and warns about the flags it sets
+// that are not known
+func PublishFeatureFlags(conf *config.Configuration) {
+	for name, enabled := range conf.FeatureFlagStates() {
+		value := 0.0
+		if enabled {
+			value = 1
+		}
+		featureEnabled.Set(value, name)
+	}
+	for _, name := range conf.UnknownFeatures() {
+		log.Warnf("resource/feature_flags:PublishFeatureFlags() Ignoring unknown feature flag %s", name)
+	}
+}
+
+// featureGate serves the requests of next only while the feature flag name is enabled, the endpoints of
+// disabled features are not found
+func featureGate(name string, next http.Handler) errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if !config.Global().FeatureEnabled(name) {
+			log.Debugf("resource/feature_flags:featureGate() Feature %s is disabled", name)
+			return &resourceError{Message: "Feature " + name + " is not enabled", StatusCode: http.StatusNotFound}
+		}
+		next.ServeHTTP(w, r)
+		return nil
+	}
+}
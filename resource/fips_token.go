@@ -0,0 +1,49 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	commLogMsg "intel/isecl/lib/common/v4/log/message"
+	"intel/isecl/sqvs/v4/fips"
+	"net/http"
+	"strings"
+)
+
+// FipsTokenMiddleware rejects the bearer tokens signed with an algorithm that is not FIPS approved, before
+// their signature is verified
+func FipsTokenMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if alg := tokenAlgorithm(r); alg != "" && !fips.IsApprovedJWTAlgorithm(alg) {
+			slog.Warnf("resource/fips_token:FipsTokenMiddleware() %s: Rejecting request %s %s from %s, token "+
+				"signed with %s which is not FIPS approved", commLogMsg.UnauthorizedAccess, r.Method, r.URL.Path,
+				r.RemoteAddr, alg)
+			writeProblem(w, r, newProblem(http.StatusUnauthorized, "Token signature algorithm is not FIPS approved"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tokenAlgorithm returns the alg header of the bearer token of the request, empty when there is none
+func tokenAlgorithm(r *http.Request) string {
+	token := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return ""
+	}
+	var fields struct {
+		Alg string `json:"alg"`
+	}
+	if json.Unmarshal(header, &fields) != nil {
+		return ""
+	}
+	return fields.Alg
+}
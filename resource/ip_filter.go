@@ -0,0 +1,109 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	commLogMsg "intel/isecl/lib/common/v4/log/message"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// IPFilter restricts the clients allowed to reach SQVS by their address. Clients matching the deny list are
+// always rejected and, when the allow list is not empty, clients not matching it are rejected too.
+type IPFilter struct {
+	allow []*net.IPNet
+	deny  []*net.IPNet
+}
+
+// NewIPFilter parses the allow and deny lists, entries are CIDR blocks or single addresses. It returns nil
+// when both lists are empty.
+func NewIPFilter(allow, deny []string) (*IPFilter, error) {
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil, nil
+	}
+	f := &IPFilter{}
+	var err error
+	f.allow, err = parseNetworks(allow)
+	if err != nil {
+		return nil, errors.Wrap(err, "resource/ip_filter:NewIPFilter() Invalid allow list")
+	}
+	f.deny, err = parseNetworks(deny)
+	if err != nil {
+		return nil, errors.Wrap(err, "resource/ip_filter:NewIPFilter() Invalid deny list")
+	}
+	return f, nil
+}
+
+func parseNetworks(entries []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, errors.Errorf("invalid address %s", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, errors.Errorf("invalid CIDR block %s", entry)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// Allowed reports whether a client address may reach SQVS
+func (f *IPFilter) Allowed(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	if containsIP(f.deny, ip) {
+		return false
+	}
+	return len(f.allow) == 0 || containsIP(f.allow, ip)
+}
+
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware returns the IP filtering middleware, it passes all requests through for a nil filter
+func (f *IPFilter) Middleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if f == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				host = r.RemoteAddr
+			}
+			if !f.Allowed(net.ParseIP(host)) {
+				slog.Warnf("resource/ip_filter:Middleware() %s: Rejecting request %s %s from %s, client address not allowed",
+					commLogMsg.UnauthorizedAccess, r.Method, r.URL.Path, r.RemoteAddr)
+				writeProblem(w, r, newProblem(http.StatusForbidden, "Client address not allowed"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIPFilter(t *testing.T) {
+	filter, err := NewIPFilter([]string{"10.0.0.0/8", "2001:db8::/32"}, []string{"10.1.0.0/16", "10.2.3.4"})
+	assert.NoError(t, err)
+
+	handler := filter.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for remoteAddr, code := range map[string]int{
+		"10.3.0.1:4000":      http.StatusOK,
+		"[2001:db8::1]:4000": http.StatusOK,
+		"10.1.2.3:4000":      http.StatusForbidden,
+		"10.2.3.4:4000":      http.StatusForbidden,
+		"192.168.1.1:4000":   http.StatusForbidden,
+		"[2001:db9::1]:4000": http.StatusForbidden,
+	} {
+		req := httptest.NewRequest("GET", "/svs/v1/version", nil)
+		req.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		assert.Equal(t, code, recorder.Code, remoteAddr)
+	}
+
+	_, err = NewIPFilter([]string{"10.0.0.0/33"}, nil)
+	assert.Error(t, err)
+	filter, err = NewIPFilter(nil, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, filter)
+}
+
+func TestTrustedProxies(t *testing.T) {
+	proxies, err := NewTrustedProxies([]string{"10.0.0.0/8", "2001:db8::1"})
+	assert.NoError(t, err)
+
+	for _, test := range []struct {
+		remoteAddr   string
+		forwardedFor []string
+		client       string
+	}{
+		{"192.0.2.1:4000", []string{"198.51.100.1"}, "192.0.2.1:4000"},
+		{"10.0.0.1:4000", nil, "10.0.0.1:4000"},
+		{"10.0.0.1:4000", []string{"198.51.100.1"}, "198.51.100.1:0"},
+		{"10.0.0.1:4000", []string{"203.0.113.9, 198.51.100.1, 10.0.0.2"}, "198.51.100.1:0"},
+		{"10.0.0.1:4000", []string{"203.0.113.9", "10.0.0.2"}, "203.0.113.9:0"},
+		{"[2001:db8::1]:4000", []string{"2001:db8::2"}, "[2001:db8::2]:0"},
+		{"10.0.0.1:4000", []string{"10.0.0.3, 10.0.0.2"}, "10.0.0.3:0"},
+		{"10.0.0.1:4000", []string{"garbage"}, "10.0.0.1:4000"},
+	} {
+		assert.Equal(t, test.client, proxies.ClientAddress(test.remoteAddr, test.forwardedFor), test.forwardedFor)
+	}
+
+	handler := proxies.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.RemoteAddr))
+	}))
+	req := httptest.NewRequest("GET", "/svs/v1/version", nil)
+	req.RemoteAddr = "10.0.0.1:4000"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, "198.51.100.1:0", recorder.Body.String())
+
+	_, err = NewTrustedProxies([]string{"not an address"})
+	assert.Error(t, err)
+	proxies, err = NewTrustedProxies(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, proxies)
+}
@@ -0,0 +1,45 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/json"
+	"intel/isecl/sqvs/v4/config"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// SetJWKSRoutes registers the JSON Web Key Set of the response signing keys. The keys are public, relying
+// parties fetch them without a token to verify signed responses offline.
+func SetJWKSRoutes(router *mux.Router) {
+	router.Handle("/.well-known/jwks.json", getJWKS()).Methods("GET")
+}
+
+func getJWKS() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/jwks_ops:getJWKS() Entering")
+		defer log.Trace("resource/jwks_ops:getJWKS() Leaving")
+
+		body, err := json.Marshal(resultJWKSet(config.Global()))
+		if err != nil {
+			return &resourceError{Message: "Error marshalling JWKS in JSON", StatusCode: http.StatusInternalServerError}
+		}
+
+		// relying parties refetch the keys after a rotation, which keeps the previous key published
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		if notModified(w, r, entityTag(body)) {
+			return nil
+		}
+		w.Header().Set("Content-Type", "application/jwk-set+json")
+		w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(body)
+		if err != nil {
+			return &resourceError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+		return nil
+	}
+}
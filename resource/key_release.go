@@ -0,0 +1,95 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"context"
+	commLogMsg "intel/isecl/lib/common/v4/log/message"
+	"intel/isecl/sqvs/v4/keyrelease"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var keyBroker keyrelease.Broker
+
+// SetKeyBroker sets the key broker keys are released to verified enclaves with, key release being disabled
+// when it is nil
+func SetKeyBroker(b keyrelease.Broker) {
+	keyBroker = b
+}
+
+// KeyReleaseRequest names the key of the key broker released to the enclave once its quote is verified
+type KeyReleaseRequest struct {
+	KeyID string `json:"keyId"`
+}
+
+func keyReleaseOf(keyID string) *KeyReleaseRequest {
+	if keyID == "" {
+		return nil
+	}
+	return &KeyReleaseRequest{KeyID: keyID}
+}
+
+// checkKeyRelease rejects the key release requests that cannot be served before the quote is verified
+func checkKeyRelease(req *KeyReleaseRequest) error {
+	if req == nil {
+		return nil
+	}
+	if keyBroker == nil {
+		slog.Errorf("resource/key_release:checkKeyRelease() %s: Key release requested but no key broker is "+
+			"configured", commLogMsg.InvalidInputBadParam)
+		return &resourceError{Message: "Key release is not enabled", StatusCode: http.StatusBadRequest}
+	}
+	if strings.TrimSpace(req.KeyID) == "" {
+		slog.Errorf("resource/key_release:checkKeyRelease() %s: Key release without a key ID",
+			commLogMsg.InvalidInputBadParam)
+		return &resourceError{Message: "Key release requires a keyId", StatusCode: http.StatusBadRequest}
+	}
+	return nil
+}
+
+// releaseKey releases the requested key to the enclave of the verified quote through the key broker. The user
+// data is handed to the broker, to wrap the key with, only when the enclave bound it to the quote.
+func releaseKey(ctx context.Context, data QuoteDataWithChallenge, resp *SGXResponse) error {
+	if data.KeyRelease == nil || keyBroker == nil {
+		return nil
+	}
+	req := keyrelease.Request{
+		KeyID: data.KeyRelease.KeyID,
+		Identity: keyrelease.Identity{
+			MrEnclave: resp.EnclaveMeasurement,
+			MrSigner:  resp.EnclaveIssuer,
+			IsvProdID: resp.EnclaveIssuerProdID,
+			IsvSvn:    resp.IsvSvn,
+			TcbStatus: resp.TcbLevel,
+			Debug:     resp.EnclaveDebugMode,
+		},
+	}
+	if resp.PckExtensions != nil {
+		req.Identity.Fmspc = resp.PckExtensions.FMSPC
+	}
+	if resp.QuoteHashes != nil {
+		req.Identity.QuoteSHA256 = resp.QuoteHashes.QuoteSHA256
+	}
+	if resp.UserDataHashMatch == "true" {
+		req.UserData = data.UserData
+	}
+
+	release, err := keyBroker.Release(ctx, req)
+	if errors.Cause(err) == keyrelease.ErrDenied {
+		slog.Warnf("resource/key_release:releaseKey() Key broker denied the release of key %s to enclave %s",
+			req.KeyID, req.Identity.MrEnclave)
+		return &resourceError{Message: "Key release denied by the key broker", StatusCode: http.StatusForbidden}
+	}
+	if err != nil {
+		log.WithError(err).Errorf("resource/key_release:releaseKey() Error releasing key %s", req.KeyID)
+		return &resourceError{Message: "Error releasing the key", StatusCode: http.StatusBadGateway}
+	}
+	slog.Infof("resource/key_release:releaseKey() Key %s released to enclave %s", req.KeyID, req.Identity.MrEnclave)
+	resp.KeyRelease = release
+	return nil
+}
@@ -0,0 +1,32 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/hex"
+	"intel/isecl/sqvs/v4/resource/parser"
+)
+
+// KssFields are the Key Separation and Sharing fields of the enclave report, hex encoded. Multi-tenant
+// enclaves set CONFIGID to isolate their tenants.
+type KssFields struct {
+	ConfigID     string `json:"ConfigID"`
+	ConfigSvn    uint16 `json:"ConfigSvn"`
+	IsvExtProdID string `json:"IsvExtProdID"`
+	IsvFamilyID  string `json:"IsvFamilyID"`
+}
+
+// newKssFields returns the KSS fields of the report, nil when the enclave was launched without KSS
+func newKssFields(report *parser.ReportBody) *KssFields {
+	if !report.UsesKSS() {
+		return nil
+	}
+	return &KssFields{
+		ConfigID:     hex.EncodeToString(report.ConfigID[:]),
+		ConfigSvn:    report.ConfigSvn,
+		IsvExtProdID: hex.EncodeToString(report.IsvExtProdID[:]),
+		IsvFamilyID:  hex.EncodeToString(report.IsvFamilyID[:]),
+	}
+}
@@ -0,0 +1,196 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/repository"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Query parameters shared by all list endpoints, any other parameter must name a filterable field
+const (
+	listParamLimit  = "limit"
+	listParamOffset = "offset"
+	listParamCursor = "cursor"
+	listParamSort   = "sort"
+)
+
+// listSpec describes the fields a list endpoint can be filtered and sorted by. DefaultSort
+// is used when no sort parameter is given, a leading "-" sorts in descending order.
+type listSpec struct {
+	FilterFields []string
+	SortFields   []string
+	DefaultSort  string
+}
+
+// parseListQuery builds the list criteria from the limit, offset or cursor, sort and field filter query
+// parameters of a list request
+func parseListQuery(r *http.Request, spec listSpec) (repository.ListCriteria, error) {
+	criteria := repository.ListCriteria{
+		Filters: make(map[string]string),
+		Limit:   constants.DefaultListLimit,
+	}
+
+	query := r.URL.Query()
+	for name, values := range query {
+		if len(values) != 1 {
+			return criteria, &resourceError{Message: fmt.Sprintf("Query parameter %s must be given once", name),
+				StatusCode: http.StatusBadRequest}
+		}
+		value := values[0]
+
+		var err error
+		switch name {
+		case listParamLimit:
+			criteria.Limit, err = strconv.Atoi(value)
+			if err != nil || criteria.Limit < 1 || criteria.Limit > constants.MaxListLimit {
+				return criteria, &resourceError{Message: fmt.Sprintf("limit must be between 1 and %d", constants.MaxListLimit),
+					StatusCode: http.StatusBadRequest}
+			}
+		case listParamOffset:
+			if _, ok := query[listParamCursor]; ok {
+				return criteria, &resourceError{Message: "offset and cursor cannot be used together",
+					StatusCode: http.StatusBadRequest}
+			}
+			criteria.Offset, err = strconv.Atoi(value)
+			if err != nil || criteria.Offset < 0 {
+				return criteria, &resourceError{Message: "offset must be a non-negative integer",
+					StatusCode: http.StatusBadRequest}
+			}
+		case listParamCursor:
+			criteria.Offset, err = decodeCursor(value)
+			if err != nil {
+				return criteria, &resourceError{Message: "Invalid cursor", StatusCode: http.StatusBadRequest}
+			}
+		case listParamSort:
+			criteria.SortBy, criteria.SortDescending = parseSort(value)
+			if !containsString(spec.SortFields, criteria.SortBy) {
+				return criteria, &resourceError{Message: fmt.Sprintf("Cannot sort by %s, must be one of %s",
+					criteria.SortBy, strings.Join(spec.SortFields, ", ")), StatusCode: http.StatusBadRequest}
+			}
+		default:
+			if !containsString(spec.FilterFields, name) {
+				return criteria, &resourceError{Message: fmt.Sprintf("Unsupported query parameter %s", name),
+					StatusCode: http.StatusBadRequest}
+			}
+			criteria.Filters[name] = value
+		}
+	}
+
+	if criteria.SortBy == "" && spec.DefaultSort != "" {
+		criteria.SortBy, criteria.SortDescending = parseSort(spec.DefaultSort)
+	}
+	return criteria, nil
+}
+
+// writeListResponse writes a page of a collection as a JSON array. The total number of matches is returned
+// in the X-Total-Count header and the neighbouring pages are linked with an RFC 5988 Link header. The page is
+// tagged with an ETag, it is not transferred again to the clients holding it.
+func writeListResponse(w http.ResponseWriter, r *http.Request, items interface{}, criteria repository.ListCriteria,
+	total int) error {
+
+	body, err := json.Marshal(items)
+	if err != nil {
+		log.WithError(err).Error("Error marshalling list response in JSON")
+		return &resourceError{Message: "Error marshalling list response in JSON", StatusCode: http.StatusInternalServerError}
+	}
+
+	if links := listLinks(r, criteria, total); len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if notModified(w, r, entityTag(append(body, strconv.Itoa(total)...))) {
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(body)
+	if err != nil {
+		return &resourceError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+	return nil
+}
+
+// listLinks returns the first, prev, next and last page links for the request. Requests paging with
+// a cursor get cursor links, all others get offset links.
+func listLinks(r *http.Request, criteria repository.ListCriteria, total int) []string {
+	if criteria.Limit <= 0 {
+		return nil
+	}
+	query := r.URL.Query()
+	_, useCursor := query[listParamCursor]
+
+	link := func(offset int, rel string) string {
+		query.Del(listParamOffset)
+		query.Del(listParamCursor)
+		if useCursor {
+			query.Set(listParamCursor, encodeCursor(offset))
+		} else {
+			query.Set(listParamOffset, strconv.Itoa(offset))
+		}
+		query.Set(listParamLimit, strconv.Itoa(criteria.Limit))
+		return fmt.Sprintf("<%s?%s>; rel=\"%s\"", r.URL.Path, query.Encode(), rel)
+	}
+
+	lastOffset := 0
+	if total > 0 {
+		lastOffset = ((total - 1) / criteria.Limit) * criteria.Limit
+	}
+	links := []string{link(0, "first")}
+	if criteria.Offset > 0 {
+		prevOffset := criteria.Offset - criteria.Limit
+		if prevOffset < 0 {
+			prevOffset = 0
+		}
+		links = append(links, link(prevOffset, "prev"))
+	}
+	if criteria.Offset+criteria.Limit < total {
+		links = append(links, link(criteria.Offset+criteria.Limit, "next"))
+	}
+	return append(links, link(lastOffset, "last"))
+}
+
+// parseSort splits a sort parameter into the field name and the sort direction
+func parseSort(sort string) (string, bool) {
+	if strings.HasPrefix(sort, "-") {
+		return strings.TrimPrefix(sort, "-"), true
+	}
+	return sort, false
+}
+
+// encodeCursor returns the opaque cursor clients pass back to fetch the page starting at offset
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(decoded), "offset:"))
+	if err != nil || offset < 0 || !strings.HasPrefix(string(decoded), "offset:") {
+		return 0, errors.New("invalid cursor")
+	}
+	return offset, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
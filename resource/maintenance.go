@@ -0,0 +1,292 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/json"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/logformat"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// DefaultMaintenanceMessage is returned to clients when maintenance mode is enabled without a message
+const DefaultMaintenanceMessage = "Service is under maintenance, retry on another instance or later"
+
+// MaintenanceState is the maintenance mode of the service, persisted to the maintenance file while enabled
+// so that it is shared with the CLI and survives restarts
+type MaintenanceState struct {
+	Enabled bool       `json:"enabled"`
+	Message string     `json:"message,omitempty"`
+	Since   *time.Time `json:"since,omitempty"`
+	// InFlight is the number of verification requests still being processed, the node is drained once it
+	// drops to zero
+	InFlight int64 `json:"inFlight"`
+}
+
+// MaintenanceError is the problem details returned to requests rejected in maintenance mode
+type MaintenanceError struct {
+	Problem
+	Maintenance bool       `json:"maintenance"`
+	Since       *time.Time `json:"since,omitempty"`
+}
+
+// MaintenanceMode rejects new verification requests with 503 while enabled, letting the requests in flight
+// complete. Administration requests are still served so that the mode can be turned off.
+type MaintenanceMode struct {
+	file     string
+	mu       sync.RWMutex
+	state    MaintenanceState
+	inFlight int64
+}
+
+var maintenance = &MaintenanceMode{}
+
+// SetMaintenanceMode sets the maintenance mode checked by the verification endpoints
+func SetMaintenanceMode(m *MaintenanceMode) {
+	maintenance = m
+}
+
+// NewMaintenanceMode returns the maintenance mode persisted to file
+func NewMaintenanceMode(file string) (*MaintenanceMode, error) {
+	m := &MaintenanceMode{file: file}
+	err := m.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ReadMaintenanceState reads the maintenance state persisted to file, maintenance mode is off when the file
+// does not exist
+func ReadMaintenanceState(file string) (MaintenanceState, error) {
+	var state MaintenanceState
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, errors.Wrap(err, "resource/maintenance:ReadMaintenanceState() Error reading maintenance file")
+	}
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		return state, errors.Wrap(err, "resource/maintenance:ReadMaintenanceState() Error decoding maintenance file")
+	}
+	state.InFlight = 0
+	return state, nil
+}
+
+// WriteMaintenanceState persists the maintenance state to file, removing the file when maintenance is off
+func WriteMaintenanceState(file string, state MaintenanceState) error {
+	if !state.Enabled {
+		err := os.Remove(file)
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "resource/maintenance:WriteMaintenanceState() Error removing maintenance file")
+		}
+		return nil
+	}
+	state.InFlight = 0
+	data, err := json.Marshal(state)
+	if err != nil {
+		return errors.Wrap(err, "resource/maintenance:WriteMaintenanceState() Error encoding maintenance state")
+	}
+	err = ioutil.WriteFile(file, data, 0644)
+	return errors.Wrap(err, "resource/maintenance:WriteMaintenanceState() Error writing maintenance file")
+}
+
+// Reload reads the maintenance state from the maintenance file, after it is changed by the CLI
+func (m *MaintenanceMode) Reload() error {
+	if m.file == "" {
+		return nil
+	}
+	state, err := ReadMaintenanceState(m.file)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	changed := state.Enabled != m.state.Enabled
+	m.state = state
+	m.mu.Unlock()
+	if changed {
+		slog.WithFields(adminEventFields(logformat.OutcomeSuccess)).Infof(
+			"resource/maintenance:Reload() Maintenance mode turned %s", onOff(state.Enabled))
+	}
+	return nil
+}
+
+// Set enables or disables maintenance mode and persists the state
+func (m *MaintenanceMode) Set(enabled bool, message string) (MaintenanceState, error) {
+	state := MaintenanceState{Enabled: enabled}
+	if enabled {
+		now := time.Now().UTC()
+		state.Since = &now
+		state.Message = message
+	}
+	if m.file != "" {
+		err := WriteMaintenanceState(m.file, state)
+		if err != nil {
+			return m.State(), err
+		}
+	}
+	m.mu.Lock()
+	m.state = state
+	m.mu.Unlock()
+	slog.WithFields(adminEventFields(logformat.OutcomeSuccess)).Infof(
+		"resource/maintenance:Set() Maintenance mode turned %s", onOff(enabled))
+	return m.State(), nil
+}
+
+// State returns the current maintenance state
+func (m *MaintenanceMode) State() MaintenanceState {
+	m.mu.RLock()
+	state := m.state
+	m.mu.RUnlock()
+	state.InFlight = atomic.LoadInt64(&m.inFlight)
+	return state
+}
+
+// Middleware rejects verification requests while maintenance mode is enabled
+func (m *MaintenanceMode) Middleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.Contains(r.URL.Path, "/admin/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			state := m.State()
+			if state.Enabled {
+				log.Debugf("resource/maintenance:Middleware() Rejecting request %s %s, service under maintenance",
+					r.Method, r.URL.Path)
+				writeMaintenanceError(w, r, state)
+				return
+			}
+			atomic.AddInt64(&m.inFlight, 1)
+			defer atomic.AddInt64(&m.inFlight, -1)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func writeMaintenanceError(w http.ResponseWriter, r *http.Request, state MaintenanceState) {
+	message := state.Message
+	if message == "" {
+		message = DefaultMaintenanceMessage
+	}
+	writeProblem(w, r, &MaintenanceError{
+		Problem:     *newCategoryProblem(ProblemMaintenance, http.StatusServiceUnavailable, message),
+		Maintenance: true,
+		Since:       state.Since,
+	})
+}
+
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
+
+// MaintenanceRequest enables or disables maintenance mode, Message is returned to the rejected clients
+type MaintenanceRequest struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message,omitempty"`
+}
+
+func MaintenanceCB(router *mux.Router) {
+	router.Handle("/admin/maintenance", getMaintenance()).Methods("GET")
+	router.Handle("/admin/maintenance", setMaintenance()).Methods("PUT")
+}
+
+func getMaintenance() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/maintenance:getMaintenance() Entering")
+		defer log.Trace("resource/maintenance:getMaintenance() Leaving")
+
+		err := authorizeAdministrator(r)
+		if err != nil {
+			return err
+		}
+		return writeMaintenanceState(w, maintenance.State())
+	}
+}
+
+func setMaintenance() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/maintenance:setMaintenance() Entering")
+		defer log.Trace("resource/maintenance:setMaintenance() Leaving")
+
+		err := authorizeAdministrator(r)
+		if err != nil {
+			return err
+		}
+
+		var req MaintenanceRequest
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&req)
+		if err != nil {
+			return &resourceError{Message: "Invalid JSON input provided", StatusCode: http.StatusBadRequest}
+		}
+		before := maintenance.State()
+		state, err := maintenance.Set(req.Enabled, req.Message)
+		if err != nil {
+			log.WithError(err).Error("resource/maintenance:setMaintenance() Error changing maintenance mode")
+			return &resourceError{Message: "Error changing maintenance mode", StatusCode: http.StatusInternalServerError}
+		}
+		recordAPIChanges(r, maintenanceChanges(before, state))
+		return writeMaintenanceState(w, state)
+	}
+}
+
+// maintenanceChanges returns the changes of the maintenance settings, recorded in the configuration history
+func maintenanceChanges(before, after MaintenanceState) []config.ConfigChange {
+	var changes []config.ConfigChange
+	if before.Enabled != after.Enabled {
+		changes = append(changes, config.ConfigChange{Setting: "maintenance.enabled",
+			Before: strconv.FormatBool(before.Enabled), After: strconv.FormatBool(after.Enabled)})
+	}
+	if before.Message != after.Message {
+		changes = append(changes, config.ConfigChange{Setting: "maintenance.message", Before: before.Message,
+			After: after.Message})
+	}
+	return changes
+}
+
+func authorizeAdministrator(r *http.Request) error {
+	conf := config.Global()
+	if conf == nil {
+		return &resourceError{Message: "Could not read config", StatusCode: http.StatusInternalServerError}
+	}
+	if conf.IncludeToken {
+		return AuthorizeEndpoint(r, constants.AdministratorGroupName, true)
+	}
+	return nil
+}
+
+func writeMaintenanceState(w http.ResponseWriter, state MaintenanceState) error {
+	body, err := json.Marshal(state)
+	if err != nil {
+		return &resourceError{Message: "Error marshalling maintenance state in JSON",
+			StatusCode: http.StatusInternalServerError}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(body)
+	if err != nil {
+		return &resourceError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "maintenance")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "maintenance.json")
+
+	m, err := NewMaintenanceMode(file)
+	assert.NoError(t, err)
+	handler := m.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("POST", path, nil))
+		return recorder
+	}
+	assert.Equal(t, http.StatusOK, serve("/svs/v1/sgx_qv_verify_quote").Code)
+
+	_, err = m.Set(true, "upgrading")
+	assert.NoError(t, err)
+	recorder := serve("/svs/v1/sgx_qv_verify_quote")
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	var body MaintenanceError
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.True(t, body.Maintenance)
+	assert.Equal(t, "upgrading", body.Detail)
+	assert.Equal(t, ProblemType(ProblemMaintenance), body.Type)
+	assert.NotNil(t, body.Since)
+	assert.Equal(t, http.StatusOK, serve("/svs/v1/admin/maintenance").Code)
+
+	// the state is shared with the CLI through the maintenance file
+	state, err := ReadMaintenanceState(file)
+	assert.NoError(t, err)
+	assert.True(t, state.Enabled)
+	assert.NoError(t, WriteMaintenanceState(file, MaintenanceState{}))
+	assert.NoError(t, m.Reload())
+	assert.False(t, m.State().Enabled)
+	assert.Equal(t, http.StatusOK, serve("/svs/v1/sgx_qv_verify_quote").Code)
+	_, err = os.Stat(file)
+	assert.True(t, os.IsNotExist(err))
+}
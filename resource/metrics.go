@@ -0,0 +1,16 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"intel/isecl/sqvs/v4/metrics"
+
+	"github.com/gorilla/mux"
+)
+
+// SetMetricsRoutes exposes the service metrics in the Prometheus text format
+func SetMetricsRoutes(router *mux.Router) {
+	router.Handle("/metrics", metrics.Handler()).Methods("GET")
+}
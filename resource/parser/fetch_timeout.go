@@ -0,0 +1,94 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package parser
+
+import (
+	"context"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// ErrFetchTimeout is the cause of the errors of the collateral and PCK CRL fetches that did not complete
+// within their timeout
+var ErrFetchTimeout = errors.New("fetch timed out")
+
+// ErrFetchCanceled is the cause of the errors of the collateral and PCK CRL fetches abandoned because the
+// request they were made for was canceled, its client having disconnected
+var ErrFetchCanceled = errors.New("fetch canceled")
+
+// ErrCollateralUnreachable is the cause of the errors of the collateral fetches that could not reach SCS: the
+// connection failed or was refused by an open circuit breaker, or SCS answered it could not reach PCS
+var ErrCollateralUnreachable = errors.New("collateral service unreachable")
+
+// collateralFetchContext bounds the fetch of the TCB info or QE identity, all attempts included, and cancels it
+// with its parent
+func collateralFetchContext(parent context.Context) (context.Context, context.CancelFunc) {
+	timeout := constants.DefaultCollateralFetchTimeout
+	if conf := config.Global(); conf != nil && conf.CollateralFetchTimeout > 0 {
+		timeout = conf.CollateralFetchTimeout
+	}
+	return context.WithTimeout(parent, timeout)
+}
+
+// crlFetchContext bounds the fetch of the PCK CRLs of a PCK certificate, all attempts included, and cancels it
+// with its parent
+func crlFetchContext(parent context.Context) (context.Context, context.CancelFunc) {
+	timeout := constants.DefaultCRLFetchTimeout
+	if conf := config.Global(); conf != nil && conf.CRLFetchTimeout > 0 {
+		timeout = conf.CRLFetchTimeout
+	}
+	return context.WithTimeout(parent, timeout)
+}
+
+// fetchError wraps the error of a fetch, with ErrFetchTimeout as its cause when the fetch timed out and
+// ErrFetchCanceled when it was canceled
+func fetchError(ctx context.Context, err error, message string) error {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return errors.Wrap(ErrFetchTimeout, message)
+	case context.Canceled:
+		return errors.Wrap(ErrFetchCanceled, message)
+	}
+	return errors.Wrap(err, message)
+}
+
+// requestError wraps the error of a collateral request that got no response, with ErrFetchTimeout as its cause
+// when the fetch timed out, ErrFetchCanceled when it was canceled and ErrCollateralUnreachable otherwise
+func requestError(ctx context.Context, err error, message string) error {
+	if ctx.Err() != nil {
+		return fetchError(ctx, err, message)
+	}
+	return errors.Wrapf(ErrCollateralUnreachable, "%s: %v", message, err)
+}
+
+// statusError is the error of a collateral response with an unexpected status code, with
+// ErrCollateralUnreachable as its cause when SCS answered as a gateway that could not reach PCS
+func statusError(message string, statusCode int) error {
+	switch statusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return errors.Wrapf(ErrCollateralUnreachable, "%s: %d", message, statusCode)
+	}
+	return errors.Errorf("%s: %d", message, statusCode)
+}
+
+// IsFetchTimeout tells whether the error is that of a collateral or PCK CRL fetch that timed out
+func IsFetchTimeout(err error) bool {
+	return errors.Cause(err) == ErrFetchTimeout
+}
+
+// IsFetchCanceled tells whether the error is that of a collateral or PCK CRL fetch that was canceled
+func IsFetchCanceled(err error) bool {
+	return errors.Cause(err) == ErrFetchCanceled
+}
+
+// IsCollateralUnreachable tells whether the error is that of a collateral fetch that could not reach SCS, in
+// time or at all, rather than a failure of the collateral it fetched
+func IsCollateralUnreachable(err error) bool {
+	cause := errors.Cause(err)
+	return cause == ErrCollateralUnreachable || cause == ErrFetchTimeout
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package parser
+
+import (
+	"context"
+	"crypto/sha512"
+	"crypto/tls"
+	"fmt"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/egress"
+	"intel/isecl/sqvs/v4/metrics"
+	"intel/isecl/sqvs/v4/netfamily"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// CollateralCheckEnforce fails the verifications using collateral that does not match the secondary source
+	CollateralCheckEnforce = "enforce"
+	// CollateralCheckWarn logs a warning and uses the collateral of SCS
+	CollateralCheckWarn = "warn"
+
+	collateralTcbInfo    = "tcb_info"
+	collateralQeIdentity = "qe_identity"
+)
+
+var collateralChecksTotal = metrics.NewCounter("sqvs_collateral_checks_total",
+	"Cross-checks of the collateral of SCS against the secondary source by collateral and result, match, mismatch "+
+		"or error", "collateral", "result")
+
+// secondaryClient fetches the collateral of the secondary source, Intel PCS being served by a public CA
+var secondaryClient = &http.Client{
+	Timeout: constants.CollateralCheckTimeout,
+	Transport: &http.Transport{
+		Proxy:           egress.Proxy,
+		DialContext:     netfamily.DialContext,
+		TLSClientConfig: &tls.Config{MinVersion: tls.VersionTLS12},
+	},
+}
+
+type secondaryDigest struct {
+	digest  [sha512.Size384]byte
+	fetched time.Time
+}
+
+// secondaryDigests caches the digests of the collateral of the secondary source by URL
+var secondaryDigests = struct {
+	sync.Mutex
+	entries map[string]secondaryDigest
+}{entries: map[string]secondaryDigest{}}
+
+// crossCheckCollateral compares the SHA-384 digest of the collateral SCS returned for path with the one of the
+// secondary source. A mismatch or an unreachable secondary source is an error in enforce mode and a warning
+// otherwise. Collateral is not checked when no secondary source is configured.
+func crossCheckCollateral(ctx context.Context, collateral, path string, query url.Values, content []byte) error {
+	conf := config.Global()
+	if conf == nil || conf.CollateralCheck.URL == "" {
+		return nil
+	}
+	check := conf.CollateralCheck
+
+	secondaryURL := strings.TrimSuffix(check.URL, "/") + path
+	if len(query) > 0 {
+		secondaryURL += "?" + query.Encode()
+	}
+	digest, err := fetchSecondaryDigest(ctx, secondaryURL, check.CacheTTL)
+	result := "match"
+	switch {
+	case err != nil:
+		result = "error"
+	case digest != sha512.Sum384(content):
+		result = "mismatch"
+		err = errors.Errorf("crossCheckCollateral: %s of SCS does not match %s", collateral, secondaryURL)
+	}
+	collateralChecksTotal.Inc(collateral, result)
+	if err == nil {
+		return nil
+	}
+	if check.Mode == CollateralCheckWarn {
+		log.WithError(err).Warnf("crossCheckCollateral: Using the %s of SCS that could not be cross-checked",
+			collateral)
+		return nil
+	}
+	return err
+}
+
+func fetchSecondaryDigest(ctx context.Context, secondaryURL string, ttl time.Duration) ([sha512.Size384]byte, error) {
+	secondaryDigests.Lock()
+	entry, ok := secondaryDigests.entries[secondaryURL]
+	secondaryDigests.Unlock()
+	if ok && time.Since(entry.fetched) < ttl {
+		return entry.digest, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, secondaryURL, nil)
+	if err != nil {
+		return entry.digest, errors.Wrapf(err, "fetchSecondaryDigest: Invalid secondary URL %s", secondaryURL)
+	}
+	resp, err := secondaryClient.Do(req)
+	if err != nil {
+		return entry.digest, errors.Wrapf(err, "fetchSecondaryDigest: Error fetching %s", secondaryURL)
+	}
+	defer func() {
+		derr := resp.Body.Close()
+		if derr != nil {
+			log.WithError(derr).Error("Error closing secondary collateral response")
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return entry.digest, errors.New(fmt.Sprintf("fetchSecondaryDigest: Invalid Status code received from %s: %d",
+			secondaryURL, resp.StatusCode))
+	}
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, constants.MaxCollateralSize))
+	if err != nil {
+		return entry.digest, errors.Wrapf(err, "fetchSecondaryDigest: Error reading %s", secondaryURL)
+	}
+
+	entry = secondaryDigest{digest: sha512.Sum384(content), fetched: time.Now()}
+	secondaryDigests.Lock()
+	secondaryDigests.entries[secondaryURL] = entry
+	secondaryDigests.Unlock()
+	return entry.digest, nil
+}
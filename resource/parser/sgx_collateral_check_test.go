@@ -0,0 +1,58 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package parser
+
+import (
+	"context"
+	"intel/isecl/sqvs/v4/config"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCrossCheckCollateral(t *testing.T) {
+	tcbInfo := []byte(`{"tcbInfo":{"version":2,"fmspc":"00906ea10000"},"signature":"00"}`)
+	requests := 0
+	pcs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/tcb":
+			if r.URL.Query().Get("fmspc") == "00906ea10000" {
+				_, _ = w.Write(tcbInfo)
+				return
+			}
+		case "/qe/identity":
+			_, _ = w.Write([]byte(`{"qeIdentity":{}}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer pcs.Close()
+
+	conf := config.Global()
+	saved := conf.CollateralCheck
+	defer func() {
+		conf.CollateralCheck = saved
+	}()
+	conf.CollateralCheck = config.CollateralCheckConfig{URL: pcs.URL, Mode: CollateralCheckEnforce, CacheTTL: time.Minute}
+
+	fmspc := url.Values{"fmspc": []string{"00906ea10000"}}
+	assert.NoError(t, crossCheckCollateral(context.Background(), collateralTcbInfo, "/tcb", fmspc, tcbInfo))
+	assert.NoError(t, crossCheckCollateral(context.Background(), collateralTcbInfo, "/tcb", fmspc, tcbInfo))
+	assert.Equal(t, 1, requests)
+	assert.Error(t, crossCheckCollateral(context.Background(), collateralTcbInfo, "/tcb", fmspc, []byte(`{"tcbInfo":{"version":1}}`)))
+	assert.Error(t, crossCheckCollateral(context.Background(), collateralQeIdentity, "/qe/identity", nil, []byte(`{}`)))
+	assert.Error(t, crossCheckCollateral(context.Background(), collateralTcbInfo, "/tcb", url.Values{"fmspc": []string{"20606a000000"}},
+		tcbInfo))
+
+	conf.CollateralCheck.Mode = CollateralCheckWarn
+	assert.NoError(t, crossCheckCollateral(context.Background(), collateralQeIdentity, "/qe/identity", nil, []byte(`{}`)))
+	conf.CollateralCheck.URL = ""
+	assert.NoError(t, crossCheckCollateral(context.Background(), collateralQeIdentity, "/qe/identity", nil, []byte(`{}`)))
+}
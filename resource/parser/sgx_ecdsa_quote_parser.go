@@ -22,10 +22,14 @@ import (
 var log = clog.GetDefaultLogger()
 
 const (
-	ReportReserved1Bytes     = 28
+	QuoteHeaderLength        = 48
+	ReportReserved1Bytes     = 12
 	ReportReserved2Bytes     = 32
-	ReportReserved3Bytes     = 96
-	ReportReserved4Bytes     = 60
+	ReportReserved3Bytes     = 32
+	ReportReserved4Bytes     = 42
+	IsvExtProdIDSize         = 16
+	IsvFamilyIDSize          = 16
+	ConfigIDSize             = 64
 	EnclaveReportLength      = 384
 	AttributeSize            = 16
 	UserDataSize             = 20
@@ -35,6 +39,10 @@ const (
 	HashSize                 = 32
 	Ecdsa256BitSignatureSize = 64
 	Ecdsa256BitPubkeySize    = 64
+	ReportKeyIDSize          = 32
+	ReportMacSize            = 16
+	SgxReportLength          = EnclaveReportLength + ReportKeyIDSize + ReportMacSize
+	SgxFlagsDebug            = 0x02 // DEBUG bit in the flags of the enclave attributes
 )
 
 // Ecdsa Quote Header
@@ -54,17 +62,40 @@ type ReportBody struct {
 	CPUSvn        [CPUsvnSize]byte           /* (0) Security Version of the CPU */
 	MiscSelect    uint32                     /* (16) Which fields defined in SSA.MISC */
 	Reserved1     [ReportReserved1Bytes]byte /* (20) */
+	IsvExtProdID  [IsvExtProdIDSize]byte     /* (32) Extended Product ID of the Enclave (KSS) */
 	SgxAttributes [AttributeSize]byte        /* (48) Any special Capabilities the Enclave possess */
 	MrEnclave     [HashSize]byte             /* (64) The value of the enclave's ENCLAVE measurement */
 	Reserved2     [ReportReserved2Bytes]byte /* (96) */
 	MrSigner      [HashSize]byte             /* (128) The value of the enclave's SIGNER measurement */
 	Reserved3     [ReportReserved3Bytes]byte /* (160) */
+	ConfigID      [ConfigIDSize]byte         /* (192) CONFIGID of the Enclave (KSS) */
 	SgxIsvProdID  uint16                     /* (256) Product ID of the Enclave */
 	SgxIsvSvn     uint16                     /* (258) Security Version of the Enclave */
-	Reserved4     [ReportReserved4Bytes]byte /* (260) */
+	ConfigSvn     uint16                     /* (260) CONFIGSVN of the Enclave (KSS) */
+	Reserved4     [ReportReserved4Bytes]byte /* (262) */
+	IsvFamilyID   [IsvFamilyIDSize]byte      /* (304) Family ID of the Enclave (KSS) */
 	ReportData    [ReportDataSize]byte       /* (320) Data provided by the user */
 }
 
+// IsDebug reports whether the enclave was launched in debug mode
+func (r *ReportBody) IsDebug() bool {
+	return r.SgxAttributes[0]&SgxFlagsDebug != 0
+}
+
+// UsesKSS reports whether any of the Key Separation and Sharing fields of the report is set, enclaves
+// launched without KSS having them all zero
+func (r *ReportBody) UsesKSS() bool {
+	return r.ConfigSvn != 0 || r.IsvExtProdID != [IsvExtProdIDSize]byte{} ||
+		r.IsvFamilyID != [IsvFamilyIDSize]byte{} || r.ConfigID != [ConfigIDSize]byte{}
+}
+
+// SGX REPORT produced by EREPORT for local attestation
+type SgxReport struct {
+	Body  ReportBody            /* (0) Report body */
+	KeyID [ReportKeyIDSize]byte /* (384) KeyID used for diversifying the report key */
+	Mac   [ReportMacSize]byte   /* (416) CMAC over the report body using the report key */
+}
+
 // QE Authentication Data
 type QEAuthData struct {
 	ParsedDataSize uint16
@@ -128,6 +159,19 @@ func (e *SkcBlobParsed) GetQuoteBlob() []byte {
 	return e.QuoteBlob
 }
 
+// ParseSgxReport parses a raw SGX REPORT structure used for local attestation
+func ParseSgxReport(rawBlob []byte) (*SgxReport, error) {
+	if len(rawBlob) != SgxReportLength {
+		return nil, errors.New(fmt.Sprintf("ParseSgxReport: Invalid SGX report size: %d", len(rawBlob)))
+	}
+	report := new(SgxReport)
+	err := restruct.Unpack(rawBlob, binary.LittleEndian, report)
+	if err != nil {
+		return nil, errors.Wrap(err, "ParseSgxReport: Failed to extract SGX report")
+	}
+	return report, nil
+}
+
 func ParseEcdsaQuoteBlob(rawBlob []byte) *SgxQuoteParsed {
 	parsedObj := new(SgxQuoteParsed)
 	err := parsedObj.parseRawECDSAQuote(rawBlob)
@@ -171,6 +215,10 @@ func (e *SgxQuoteParsed) GetHeaderAndEnclaveReportBlob() ([]byte, error) {
 	return append(HeaderBlob, EnclaveReportBlob...), nil
 }
 
+func (e *SgxQuoteParsed) IsDebugEnclave() bool {
+	return e.EnclaveReport.IsDebug()
+}
+
 func (e *SgxQuoteParsed) GetQeReportAttributes() [AttributeSize]byte {
 	return e.QuoteSignatureData.QeReport.SgxAttributes
 }
@@ -336,7 +384,7 @@ func (e *SgxQuoteParsed) parseRawECDSAQuote(decodedQuote []byte) error {
 	}()
 
 	// Enclave Report Starts at offset of 48 bytes after the quote header
-	encReportStart := 48
+	encReportStart := QuoteHeaderLength
 	err = restruct.Unpack(decodedQuote[encReportStart:], binary.LittleEndian, &e.EnclaveReport)
 	if err != nil {
 		log.Error("Failed to extract Enclave Report from quote")
@@ -6,6 +6,7 @@ package parser
 
 import (
 	"bytes"
+	"encoding/binary"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -46,3 +47,39 @@ func TestGetSgxQuote(t *testing.T) {
 	}
 	ExecuteSGXQuoteTest(input)
 }
+
+func TestParseSgxReportInvalidLength(t *testing.T) {
+	_, err := ParseSgxReport(make([]byte, SgxReportLength-1))
+	assert.Error(t, err)
+
+	_, err = ParseSgxReport(make([]byte, SgxReportLength))
+	assert.NoError(t, err)
+}
+
+func TestReportBodyIsDebug(t *testing.T) {
+	var body ReportBody
+	assert.False(t, body.IsDebug())
+
+	body.SgxAttributes[0] = 0x07
+	assert.True(t, body.IsDebug())
+}
+
+func TestReportBodyKSS(t *testing.T) {
+	var body ReportBody
+	assert.Equal(t, EnclaveReportLength, binary.Size(body))
+	assert.False(t, body.UsesKSS())
+
+	raw := make([]byte, EnclaveReportLength)
+	raw[32] = 0x01  // ISVEXTPRODID
+	raw[192] = 0x02 // CONFIGID
+	raw[260] = 0x03 // CONFIGSVN
+	raw[304] = 0x04 // ISVFAMILYID
+	raw[320] = 0x05 // REPORTDATA
+	assert.NoError(t, binary.Read(bytes.NewReader(raw), binary.LittleEndian, &body))
+	assert.True(t, body.UsesKSS())
+	assert.Equal(t, byte(0x01), body.IsvExtProdID[0])
+	assert.Equal(t, byte(0x02), body.ConfigID[0])
+	assert.Equal(t, uint16(3), body.ConfigSvn)
+	assert.Equal(t, byte(0x04), body.IsvFamilyID[0])
+	assert.Equal(t, byte(0x05), body.ReportData[0])
+}
@@ -5,23 +5,28 @@
 package parser
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
-	"encoding/base64"
 	"encoding/hex"
 	"encoding/pem"
 	"fmt"
-	"intel/isecl/lib/clients/v4"
+	"intel/isecl/sqvs/v4/aasclient"
 	"intel/isecl/sqvs/v4/config"
 	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/resilience"
 	"intel/isecl/sqvs/v4/resource/utils"
 	"intel/isecl/sqvs/v4/resource/verifier"
+	"intel/isecl/sqvs/v4/truststore"
 	"io/ioutil"
+	"math/big"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -31,18 +36,47 @@ type PckCRL struct {
 	PckCRLObjs     []*pkix.CertificateList
 	RootCA         map[string]*x509.Certificate
 	IntermediateCA map[string]*x509.Certificate
+	Sources        []PckCrlSource
+}
+
+// PlatformConfiguration holds the configuration of multi-package platforms found in PCK certificates
+// issued by the Intel SGX PCK Platform CA, settings not present in the certificate are nil
+type PlatformConfiguration struct {
+	DynamicPlatform *bool
+	CachedKeys      *bool
+	SMTEnabled      *bool
 }
 
 type PckCert struct {
 	PckCertObj           *x509.Certificate
 	FmspcStr             string
+	PPIDStr              string
+	PceIDStr             string
+	SgxType              int
+	PlatformInstanceID   string
+	Configuration        *PlatformConfiguration
 	TcbCompLevels        []byte
+	Tcb                  PckTcb
 	PckCRL               PckCRL
 	RequiredExtension    map[string]asn1.ObjectIdentifier
 	RequiredSGXExtension map[string]asn1.ObjectIdentifier
 }
 
 func NewPCKCertObj(certBlob []byte) *PckCert {
+	parsedPck := ParsePCKCertObj(certBlob)
+	if parsedPck == nil {
+		return nil
+	}
+	err := parsedPck.FetchPckCrl(context.Background())
+	if err != nil {
+		log.Error("NewPCKCertObj: PCK CRL Parse error", err.Error())
+		return nil
+	}
+	return parsedPck
+}
+
+// ParsePCKCertObj parses the PCK certificate and its SGX extensions, without fetching its CRLs
+func ParsePCKCertObj(certBlob []byte) *PckCert {
 	parsedPck := new(PckCert)
 	err := parsedPck.genCertObj(certBlob)
 	if err != nil {
@@ -62,26 +96,51 @@ func NewPCKCertObj(certBlob []byte) *PckCert {
 		return nil
 	}
 
+	err = parsedPck.validateSgxExtensions()
+	if err != nil {
+		log.Error("NewPCKCertObj: SGX Extensions validation error", err.Error())
+		return nil
+	}
+
 	err = parsedPck.parseFMSPCValue()
 	if err != nil {
 		log.Error("NewPCKCertObj: Fmspc Parse error", err.Error())
 		return nil
 	}
 
-	err = parsedPck.parseTcbExtensions()
+	err = parsedPck.parsePPIDValue()
 	if err != nil {
-		log.Error("NewPCKCertObj: Tcb Extensions Parse error", err.Error())
+		log.Error("NewPCKCertObj: PPID Parse error", err.Error())
 		return nil
 	}
 
-	err = parsedPck.parsePckCrl()
+	err = parsedPck.parsePlatformExtensions()
 	if err != nil {
-		log.Error("NewPCKCertObj: PCK CRL Parse error", err.Error())
+		log.Error("NewPCKCertObj: Platform Extensions Parse error", err.Error())
 		return nil
 	}
+
+	err = parsedPck.checkPlatformCAExtensions()
+	if err != nil {
+		log.Error("NewPCKCertObj: Platform CA Extensions check error", err.Error())
+		return nil
+	}
+
+	err = parsedPck.parseTcbExtensions()
+	if err != nil {
+		log.Error("NewPCKCertObj: Tcb Extensions Parse error", err.Error())
+		return nil
+	}
+
 	return parsedPck
 }
 
+// FetchPckCrl fetches the CRLs of the CAs in the CRL distribution points of the PCK certificate, the fetch
+// being canceled with ctx
+func (e *PckCert) FetchPckCrl(ctx context.Context) error {
+	return e.parsePckCrl(ctx)
+}
+
 func (e *PckCert) genPckCertRequiredExtMap() {
 	e.RequiredExtension = make(map[string]asn1.ObjectIdentifier)
 	e.RequiredExtension[verifier.ExtAuthorityKeyIdentifierOid.String()] = verifier.ExtAuthorityKeyIdentifierOid
@@ -125,9 +184,140 @@ func (e *PckCert) GetPckCertTcbLevels() []byte {
 	return e.TcbCompLevels
 }
 
-func (e *PckCert) parseFMSPCValue() error {
+func (e *PckCert) GetPPIDValue() string {
+	return e.PPIDStr
+}
+
+func (e *PckCert) GetPceIDValue() string {
+	return e.PceIDStr
+}
+
+// GetSgxType returns the SGX type of the platform, 0 for Standard, 1 for Scalable and 2 for Scalable with
+// integrity
+func (e *PckCert) GetSgxType() int {
+	return e.SgxType
+}
+
+func (e *PckCert) GetPlatformInstanceID() string {
+	return e.PlatformInstanceID
+}
+
+// GetPlatformConfiguration returns the multi-package platform configuration, or nil for single package platforms
+func (e *PckCert) GetPlatformConfiguration() *PlatformConfiguration {
+	return e.Configuration
+}
+
+// GetPckCrlNumber returns the CRL number of the PCK CRL, or nil if the CRL does not carry one
+func (e *PckCert) GetPckCrlNumber() *big.Int {
+	for _, crl := range e.PckCRL.PckCRLObjs {
+		if crl == nil {
+			continue
+		}
+		if number := crlNumber(crl); number != nil {
+			return number
+		}
+	}
+	return nil
+}
+
+// sgxExtension is an element of the SGX extension sequence, Value is an OCTET STRING, INTEGER,
+// ENUMERATED, BOOLEAN or a nested SEQUENCE depending on the OID
+type sgxExtension struct {
+	ID    asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+// getSgxExtensionRawValue returns the undecoded value of the SGX extension with the given OID
+func (e *PckCert) getSgxExtensionRawValue(oid asn1.ObjectIdentifier) (*asn1.RawValue, error) {
+	for _, ext := range e.PckCertObj.Extensions {
+		if !verifier.ExtSgxOid.Equal(ext.Id) {
+			continue
+		}
+		var sgxExtensions []sgxExtension
+		_, err := asn1.Unmarshal(ext.Value, &sgxExtensions)
+		if err != nil {
+			return nil, errors.Wrap(err, "Asn1 Extension Unmarshal failed")
+		}
+		for i := range sgxExtensions {
+			if oid.Equal(sgxExtensions[i].ID) {
+				return &sgxExtensions[i].Value, nil
+			}
+		}
+	}
+	return nil, errors.New("SGX Extension " + oid.String() + " not found")
+}
+
+// parsePlatformExtensions reads the PCE ID, SGX type and, for multi-package platforms, the platform
+// instance ID and configuration from the PCK certificate
+func (e *PckCert) parsePlatformExtensions() error {
+	pceID, err := e.getSgxExtensionValue(verifier.ExtSgxPCEIDOid)
+	if err != nil {
+		return errors.Wrap(err, "PCEID Value not found in Extension")
+	}
+	e.PceIDStr = hex.EncodeToString(pceID)
+
+	sgxType, err := e.getSgxExtensionRawValue(verifier.ExtSgxSGXTypeOid)
+	if err != nil {
+		return errors.Wrap(err, "SGX Type Value not found in Extension")
+	}
+	var sgxTypeValue asn1.Enumerated
+	_, err = asn1.Unmarshal(sgxType.FullBytes, &sgxTypeValue)
+	if err != nil {
+		return errors.Wrap(err, "SGX Type Value could not be decoded")
+	}
+	e.SgxType = int(sgxTypeValue)
+
+	platformInstanceID, err := e.getSgxExtensionValue(verifier.ExtSgxPlatformInstanceIDOid)
+	if err == nil {
+		e.PlatformInstanceID = hex.EncodeToString(platformInstanceID)
+	}
+
+	configuration, err := e.getSgxExtensionRawValue(verifier.ExtSgxConfigurationOid)
+	if err != nil {
+		return nil
+	}
+	var settings []sgxExtension
+	_, err = asn1.Unmarshal(configuration.FullBytes, &settings)
+	if err != nil {
+		return errors.Wrap(err, "Configuration Value could not be decoded")
+	}
+	e.Configuration = &PlatformConfiguration{}
+	for _, setting := range settings {
+		var enabled bool
+		_, err = asn1.Unmarshal(setting.Value.FullBytes, &enabled)
+		if err != nil {
+			return errors.Wrap(err, "Configuration setting "+setting.ID.String()+" could not be decoded")
+		}
+		switch {
+		case verifier.ExtSgxDynamicPlatformOid.Equal(setting.ID):
+			e.Configuration.DynamicPlatform = &enabled
+		case verifier.ExtSgxCachedKeysOid.Equal(setting.ID):
+			e.Configuration.CachedKeys = &enabled
+		case verifier.ExtSgxSMTEnabledOid.Equal(setting.ID):
+			e.Configuration.SMTEnabled = &enabled
+		}
+	}
+	return nil
+}
+
+// checkPlatformCAExtensions ensures PCK certificates issued by the PCK Platform CA, to the packages of
+// multi-package platforms, identify the platform instance and its configuration
+func (e *PckCert) checkPlatformCAExtensions() error {
+	if e.PckCertObj.Issuer.CommonName != constants.SGXPCKPlatformCACommonName {
+		return nil
+	}
+	if e.PlatformInstanceID == "" {
+		return errors.New("Platform Instance ID not found in Platform CA issued certificate")
+	}
+	if e.Configuration == nil {
+		return errors.New("Configuration not found in Platform CA issued certificate")
+	}
+	return nil
+}
+
+// getSgxExtensionValue returns the value of the SGX extension with the given OID from the PCK certificate
+func (e *PckCert) getSgxExtensionValue(oid asn1.ObjectIdentifier) ([]byte, error) {
 	var ext pkix.Extension
-	var err error
 	for i := 0; i < len(e.PckCertObj.Extensions); i++ {
 		ext = e.PckCertObj.Extensions[i]
 		if verifier.ExtSgxOid.Equal(ext.Id) {
@@ -135,7 +325,7 @@ func (e *PckCert) parseFMSPCValue() error {
 			var asn1Extensions []asn1.RawValue
 			_, err := asn1.Unmarshal(ext.Value, &asn1Extensions)
 			if err != nil {
-				return errors.Wrap(err, "Asn1 Extension Unmarshal failed")
+				return nil, errors.Wrap(err, "Asn1 Extension Unmarshal failed")
 			}
 
 			var sgxExtension pkix.Extension
@@ -145,16 +335,34 @@ func (e *PckCert) parseFMSPCValue() error {
 				if err != nil {
 					log.Info("Asn1 Extension Unmarshal failed for index:", j)
 				}
-				if verifier.ExtSgxFMSPCOid.Equal(sgxExtension.Id) {
-					e.FmspcStr = hex.EncodeToString(sgxExtension.Value)
-					log.WithField("FMSPC hex value", e.FmspcStr).Debug("Fmspc Value from cert")
-					return nil
+				if oid.Equal(sgxExtension.Id) {
+					return sgxExtension.Value, nil
 				}
 			}
 		}
 	}
-	log.Error("Fmspc Value not found in Extension")
-	return errors.Wrap(err, "Fmspc Value not found in Extension")
+	return nil, errors.New("SGX Extension " + oid.String() + " not found")
+}
+
+func (e *PckCert) parseFMSPCValue() error {
+	fmspc, err := e.getSgxExtensionValue(verifier.ExtSgxFMSPCOid)
+	if err != nil {
+		log.Error("Fmspc Value not found in Extension")
+		return errors.Wrap(err, "Fmspc Value not found in Extension")
+	}
+	e.FmspcStr = hex.EncodeToString(fmspc)
+	log.WithField("FMSPC hex value", e.FmspcStr).Debug("Fmspc Value from cert")
+	return nil
+}
+
+func (e *PckCert) parsePPIDValue() error {
+	ppid, err := e.getSgxExtensionValue(verifier.ExtSgxPPIDOid)
+	if err != nil {
+		log.Error("PPID Value not found in Extension")
+		return errors.Wrap(err, "PPID Value not found in Extension")
+	}
+	e.PPIDStr = hex.EncodeToString(ppid)
+	return nil
 }
 
 type TcbExtn struct {
@@ -240,82 +448,71 @@ func (e *PckCert) GetPckCrlRootCaList() []*x509.Certificate {
 	return rootCAArr
 }
 
-func (e *PckCert) parsePckCrl() error {
+func (e *PckCert) parsePckCrl(parent context.Context) error {
 	e.PckCRL.PckCRLURLs = e.PckCertObj.CRLDistributionPoints
 	e.PckCRL.PckCRLObjs = make([]*pkix.CertificateList, len(e.PckCRL.PckCRLURLs))
+	e.PckCRL.Sources = make([]PckCrlSource, len(e.PckCRL.PckCRLURLs))
 
 	conf := config.Global()
 	if conf == nil {
 		return errors.Wrap(errors.New("parsePckCrl: Configuration pointer is null"), "Config error")
 	}
+	overrides, err := ParseCrlOverrides(conf.CrlURLOverrides)
+	if err != nil {
+		return errors.Wrap(err, "parsePckCrl: Invalid CRL URL overrides")
+	}
 
-	client, err := clients.HTTPClientWithCADir(constants.TrustedCAsStoreDir)
+	httpClient, err := truststore.HTTPClient(constants.TrustedCAsStoreDir)
 	if err != nil {
 		return errors.Wrap(err, "parsePckCrl: Error in getting client object")
 	}
+	client := resilience.Default().Client(httpClient)
 
-	for i := 0; i < len(e.PckCRL.PckCRLURLs); i++ {
-		url := e.PckCRL.PckCRLURLs[i]
-
-		scsURL := conf.SCSBaseURL
-		if !strings.Contains(url, scsURL) {
-			a := regexp.MustCompile(`v\d`)
-			splitURL := a.Split(url, -1)
-			if len(splitURL) != 2 {
-				return errors.Wrap(err, "parsePckCrl: Invalid PCK CRL URL")
-			}
-			finalURL := strings.Trim(splitURL[1], "&encoding")
-			url = scsURL + finalURL
-		}
+	e.PckCRL.RootCA = make(map[string]*x509.Certificate)
+	e.PckCRL.IntermediateCA = make(map[string]*x509.Certificate)
 
-		req, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			return errors.Wrap(err, "parsePckCrl: Failed to Get New request")
+	// a single timeout covers the CRLs of both PCK CAs
+	ctx, cancel := crlFetchContext(parent)
+	defer cancel()
+	for i := 0; i < len(e.PckCRL.PckCRLURLs); i++ {
+		ca := pckCrlCA(e.PckCRL.PckCRLURLs[i])
+		if crlObj := loadImportedCrl(constants.PckCrlDir, ca, time.Now()); crlObj != nil {
+			e.PckCRL.PckCRLObjs[i] = crlObj
+			e.PckCRL.Sources[i] = crlSourceOf(ca, CrlSourceImported, "", crlObj)
+			continue
 		}
 
-		req.Header.Set("Accept", "application/json")
-		resp, err := client.Do(req)
-		if resp != nil {
-			defer func() {
-				derr := resp.Body.Close()
-				if derr != nil {
-					log.WithError(derr).Error("Error closing pckcrl response")
+		source := CrlSourceSCS
+		crlURL, ok := overrides[ca]
+		if ok {
+			source = CrlSourceOverride
+		} else {
+			crlURL = e.PckCRL.PckCRLURLs[i]
+			if !strings.Contains(crlURL, conf.SCSBaseURL) {
+				crlURL, err = scsPckCrlURL(crlURL, conf.SCSBaseURL)
+				if err != nil {
+					return errors.Wrap(err, "parsePckCrl: Invalid PCK CRL URL")
 				}
-			}()
-		}
-
-		if err != nil {
-			return errors.Wrap(err, "failed to get pckcrl response from scs")
-		}
-
-		if resp.StatusCode != 200 {
-			return errors.New(fmt.Sprintf("parsePckCrl: Invalid status code received:%d", resp.StatusCode))
+			}
 		}
 
-		crlBody, err := ioutil.ReadAll(resp.Body)
+		crlObj, issuerChain, err := fetchPckCrl(ctx, client, crlURL)
 		if err != nil {
-			return errors.Wrap(err, "parsePckCrl: failed to read pckcrl response body")
+			return err
 		}
+		e.PckCRL.PckCRLObjs[i] = crlObj
+		e.PckCRL.Sources[i] = crlSourceOf(ca, source, crlURL, crlObj)
 
-		crlDer, err := base64.StdEncoding.DecodeString(string(crlBody))
-		if err != nil {
-			return errors.Wrap(err, "parsePckCrl: failed to base64 decode crl blob")
+		// mirrors may serve the CRL without its issuer chain, the CRL is then verified against the PCK
+		// certificate chain of the quote
+		if source == CrlSourceOverride && issuerChain == "" {
+			continue
 		}
-
-		crlObj, err := x509.ParseDERCRL(crlDer)
-		if err != nil {
-			return errors.Wrap(err, "parsePckCrl: failed to Parse der encoded crl")
-		}
-
-		e.PckCRL.PckCRLObjs[i] = crlObj
-		certChainList, err := utils.GetCertObjList(resp.Header.Get("SGX-PCK-CRL-Issuer-Chain"))
+		certChainList, err := utils.GetCertObjList(issuerChain)
 		if err != nil {
 			return errors.Wrap(err, "parsePckCrl: failed to get cert list")
 		}
 
-		e.PckCRL.RootCA = make(map[string]*x509.Certificate)
-		e.PckCRL.IntermediateCA = make(map[string]*x509.Certificate)
-
 		var intermediateCACount int
 		var rootCACount int
 		for i := 0; i < len(certChainList); i++ {
@@ -333,8 +530,149 @@ func (e *PckCert) parsePckCrl() error {
 		}
 
 		if intermediateCACount == 0 || rootCACount == 0 {
-			return errors.Wrap(err, "parsePckCrl: PCK CRL- Root CA/Intermediate CA Invalid count")
+			return errors.New("parsePckCrl: PCK CRL- Root CA/Intermediate CA Invalid count")
+		}
+	}
+	return nil
+}
+
+// fetchPckCrl downloads the PCK CRL, served in DER, PEM or base64, along with its issuer chain header
+func fetchPckCrl(ctx context.Context, client *resilience.Client, crlURL string) (*pkix.CertificateList, string,
+	error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", crlURL, nil)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "parsePckCrl: Failed to Get New request")
+	}
+
+	req.Header.Set("Accept", "application/json")
+	// only SCS gets the bearer token, CRLs may also be served by the PCS
+	if conf := config.Global(); conf != nil && conf.SCSBaseURL != "" && strings.HasPrefix(crlURL, conf.SCSBaseURL) {
+		err = aasclient.Authorize(req)
+		if err != nil {
+			return nil, "", errors.Wrap(err, "parsePckCrl: Failed to get bearer token")
+		}
+	}
+	resp, err := client.Do(req)
+	if resp != nil {
+		defer func() {
+			derr := resp.Body.Close()
+			if derr != nil {
+				log.WithError(derr).Error("Error closing pckcrl response")
+			}
+		}()
+	}
+
+	if err != nil {
+		return nil, "", fetchError(ctx, err, "failed to get pckcrl response from "+crlURL)
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, "", errors.New(fmt.Sprintf("parsePckCrl: Invalid status code received:%d", resp.StatusCode))
+	}
+
+	crlBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fetchError(ctx, err, "parsePckCrl: failed to read pckcrl response body")
+	}
+
+	crlObj, err := DecodeCrl(crlBody)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "parsePckCrl: failed to Parse der encoded crl")
+	}
+	return crlObj, resp.Header.Get("SGX-PCK-CRL-Issuer-Chain"), nil
+}
+
+// GetPckCrlSources returns where the PCK CRLs of the certificate were obtained from
+func (e *PckCert) GetPckCrlSources() []PckCrlSource {
+	return e.PckCRL.Sources
+}
+
+// FetchPckCrl returns the current PCK CRL of the processor or platform CA, DER encoded, along with its PEM
+// encoded issuer chain and where it was obtained from. The CRL imported for the CA is used while it is current,
+// otherwise it is fetched from the distribution point override of the CA or from SCS. A CRL served with its
+// issuer chain is verified against trustedRoot, the chain is empty for imported CRLs and for CRLs a mirror
+// serves without one.
+func FetchPckCrl(parent context.Context, ca string, trustedRoot *x509.Certificate) ([]byte, string, PckCrlSource,
+	error) {
+	if ca != PckCAProcessor && ca != PckCAPlatform {
+		return nil, "", PckCrlSource{}, errors.Errorf("FetchPckCrl: Unknown PCK CA %s", ca)
+	}
+	if crlObj := loadImportedCrl(constants.PckCrlDir, ca, time.Now()); crlObj != nil {
+		der, err := asn1.Marshal(*crlObj)
+		if err != nil {
+			return nil, "", PckCrlSource{}, errors.Wrap(err, "FetchPckCrl: Error encoding imported CRL")
 		}
+		return der, "", crlSourceOf(ca, CrlSourceImported, "", crlObj), nil
+	}
+
+	conf := config.Global()
+	if conf == nil {
+		return nil, "", PckCrlSource{}, errors.New("FetchPckCrl: Configuration pointer is null")
+	}
+	overrides, err := ParseCrlOverrides(conf.CrlURLOverrides)
+	if err != nil {
+		return nil, "", PckCrlSource{}, errors.Wrap(err, "FetchPckCrl: Invalid CRL URL overrides")
+	}
+	source := CrlSourceSCS
+	crlURL, ok := overrides[ca]
+	if ok {
+		source = CrlSourceOverride
+	} else {
+		crlURL = strings.TrimSuffix(conf.SCSBaseURL, "/") + "/pckcrl?" + url.Values{"ca": {ca}}.Encode()
+	}
+
+	httpClient, err := truststore.HTTPClient(constants.TrustedCAsStoreDir)
+	if err != nil {
+		return nil, "", PckCrlSource{}, errors.Wrap(err, "FetchPckCrl: Error in getting client object")
+	}
+	ctx, cancel := crlFetchContext(parent)
+	defer cancel()
+	crlObj, issuerChain, err := fetchPckCrl(ctx, resilience.Default().Client(httpClient), crlURL)
+	if err != nil {
+		return nil, "", PckCrlSource{}, err
+	}
+	if crlIssuerCA(crlObj) != ca {
+		return nil, "", PckCrlSource{}, errors.Errorf("FetchPckCrl: CRL served for the PCK %s CA is issued by %s", ca,
+			crlObj.TBSCertList.Issuer.String())
+	}
+	// mirrors may serve the CRL without its issuer chain, relying parties then verify it against the PCK
+	// certificate chain of the quote
+	if source != CrlSourceOverride || issuerChain != "" {
+		chain, err := utils.GetCertObjList(issuerChain)
+		if err != nil {
+			return nil, "", PckCrlSource{}, errors.Wrap(err, "FetchPckCrl: Invalid CRL issuer chain")
+		}
+		err = verifyCrlIssuerChain(crlObj, chain, trustedRoot, time.Now())
+		if err != nil {
+			return nil, "", PckCrlSource{}, err
+		}
+	}
+	der, err := asn1.Marshal(*crlObj)
+	if err != nil {
+		return nil, "", PckCrlSource{}, errors.Wrap(err, "FetchPckCrl: Error encoding CRL")
+	}
+	return der, issuerChain, crlSourceOf(ca, source, crlURL, crlObj), nil
+}
+
+// scsPckCrlURL maps the Intel PCS CRL distribution point of a PCK certificate to the SCS endpoint serving
+// the CRL. The ca parameter selects the CRL of the PCK Processor CA or of the PCK Platform CA, the CRL is
+// always served base64 encoded by SCS so the encoding parameter is dropped.
+func scsPckCrlURL(crlURL, scsBaseURL string) (string, error) {
+	u, err := url.Parse(crlURL)
+	if err != nil {
+		return "", errors.Wrap(err, "scsPckCrlURL: Failed to parse CRL URL")
+	}
+	loc := regexp.MustCompile(`/v\d+/`).FindStringIndex(u.Path)
+	if loc == nil {
+		return "", errors.New("scsPckCrlURL: CRL URL does not carry an API version: " + crlURL)
+	}
+	query := url.Values{}
+	if ca := u.Query().Get("ca"); ca != "" {
+		query.Set("ca", ca)
+	}
+	scsURL := strings.TrimSuffix(scsBaseURL, "/") + "/" + u.Path[loc[1]:]
+	if len(query) > 0 {
+		scsURL += "?" + query.Encode()
 	}
-	return err
+	return scsURL, nil
 }
@@ -0,0 +1,128 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package parser
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"intel/isecl/sqvs/v4/resource/verifier"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func marshalSgxExtension(t *testing.T, oid asn1.ObjectIdentifier, value interface{}) asn1.RawValue {
+	valueBytes, err := asn1.Marshal(value)
+	assert.NoError(t, err)
+	ext, err := asn1.Marshal(sgxExtension{ID: oid, Value: asn1.RawValue{FullBytes: valueBytes}})
+	assert.NoError(t, err)
+	return asn1.RawValue{FullBytes: ext}
+}
+
+func TestParsePlatformExtensions(t *testing.T) {
+	configuration := []asn1.RawValue{
+		marshalSgxExtension(t, verifier.ExtSgxDynamicPlatformOid, true),
+		marshalSgxExtension(t, verifier.ExtSgxSMTEnabledOid, false),
+	}
+	sgxExt, err := asn1.Marshal([]asn1.RawValue{
+		marshalSgxExtension(t, verifier.ExtSgxPCEIDOid, []byte{0x00, 0x00}),
+		marshalSgxExtension(t, verifier.ExtSgxSGXTypeOid, asn1.Enumerated(1)),
+		marshalSgxExtension(t, verifier.ExtSgxPlatformInstanceIDOid, []byte{0xab, 0xcd}),
+		marshalSgxExtension(t, verifier.ExtSgxConfigurationOid, configuration),
+	})
+	assert.NoError(t, err)
+
+	pck := &PckCert{PckCertObj: &x509.Certificate{
+		Extensions: []pkix.Extension{{Id: verifier.ExtSgxOid, Value: sgxExt}},
+	}}
+	assert.NoError(t, pck.parsePlatformExtensions())
+	assert.Equal(t, "0000", pck.GetPceIDValue())
+	assert.Equal(t, 1, pck.GetSgxType())
+	assert.Equal(t, "abcd", pck.GetPlatformInstanceID())
+
+	config := pck.GetPlatformConfiguration()
+	if assert.NotNil(t, config) {
+		assert.True(t, *config.DynamicPlatform)
+		assert.Nil(t, config.CachedKeys)
+		assert.False(t, *config.SMTEnabled)
+	}
+}
+
+func TestCheckPlatformCAExtensions(t *testing.T) {
+	pck := &PckCert{PckCertObj: &x509.Certificate{Issuer: pkix.Name{CommonName: "Intel SGX PCK Platform CA"}}}
+	assert.Error(t, pck.checkPlatformCAExtensions())
+
+	pck.PlatformInstanceID = "abcd"
+	pck.Configuration = &PlatformConfiguration{}
+	assert.NoError(t, pck.checkPlatformCAExtensions())
+
+	pck = &PckCert{PckCertObj: &x509.Certificate{Issuer: pkix.Name{CommonName: "Intel SGX PCK Processor CA"}}}
+	assert.NoError(t, pck.checkPlatformCAExtensions())
+}
+
+func TestScsPckCrlURL(t *testing.T) {
+	scsURL, err := scsPckCrlURL("https://api.trustedservices.intel.com/sgx/certification/v3/pckcrl?ca=platform&encoding=der",
+		"https://scs.example.com:9000/scs/sgx/certification/v1/")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://scs.example.com:9000/scs/sgx/certification/v1/pckcrl?ca=platform", scsURL)
+
+	_, err = scsPckCrlURL("https://example.com/pckcrl", "https://scs.example.com:9000/scs/sgx/certification/v1")
+	assert.Error(t, err)
+}
+
+func testTcbExtension(t *testing.T, cpuSvn []byte) []asn1.RawValue {
+	var components []asn1.RawValue
+	for i := 1; i <= TcbCompSvnCount; i++ {
+		oid := append(append(asn1.ObjectIdentifier{}, verifier.ExtSgxTCBOid...), i)
+		components = append(components, marshalSgxExtension(t, oid, int(cpuSvn[i-1])))
+	}
+	components = append(components, marshalSgxExtension(t, verifier.ExtSgxTcbPceSvnOid, 11))
+	cpuSvnOid := append(append(asn1.ObjectIdentifier{}, verifier.ExtSgxTCBOid...), 18)
+	return append(components, marshalSgxExtension(t, cpuSvnOid, cpuSvn))
+}
+
+func TestValidateSgxExtensions(t *testing.T) {
+	cpuSvn := []byte{2, 2, 2, 2, 3, 1, 0, 3, 0, 0, 0, 0, 0, 0, 0, 0}
+	newPck := func(exts ...asn1.RawValue) *PckCert {
+		sgxExt, err := asn1.Marshal(exts)
+		assert.NoError(t, err)
+		return &PckCert{PckCertObj: &x509.Certificate{
+			Extensions: []pkix.Extension{{Id: verifier.ExtSgxOid, Value: sgxExt}},
+		}}
+	}
+	valid := []asn1.RawValue{
+		marshalSgxExtension(t, verifier.ExtSgxPPIDOid, make([]byte, PPIDSize)),
+		marshalSgxExtension(t, verifier.ExtSgxTCBOid, testTcbExtension(t, cpuSvn)),
+		marshalSgxExtension(t, verifier.ExtSgxPCEIDOid, []byte{0x00, 0x00}),
+		marshalSgxExtension(t, verifier.ExtSgxFMSPCOid, []byte{0x00, 0x90, 0x6e, 0xa1, 0x00, 0x00}),
+		marshalSgxExtension(t, verifier.ExtSgxSGXTypeOid, asn1.Enumerated(SgxTypeScalable)),
+	}
+	pck := newPck(valid...)
+	assert.NoError(t, pck.validateSgxExtensions())
+	assert.Equal(t, uint16(11), pck.GetTcb().PceSvn)
+	assert.Equal(t, cpuSvn, pck.GetTcb().CPUSvn)
+	assert.Equal(t, uint8(3), pck.GetTcb().CompSvns[4])
+
+	invalid := map[string][]asn1.RawValue{
+		"short FMSPC": {marshalSgxExtension(t, verifier.ExtSgxFMSPCOid, []byte{0x00})},
+		"duplicate":   append(valid, valid[0]),
+		"SGX type":    {marshalSgxExtension(t, verifier.ExtSgxSGXTypeOid, asn1.Enumerated(7))},
+		"missing TCB component": {marshalSgxExtension(t, verifier.ExtSgxTCBOid,
+			testTcbExtension(t, cpuSvn)[1:])},
+		"CPUSVN mismatch": {marshalSgxExtension(t, verifier.ExtSgxTCBOid,
+			append(testTcbExtension(t, cpuSvn)[:TcbCompSvnCount+1], marshalSgxExtension(t,
+				append(append(asn1.ObjectIdentifier{}, verifier.ExtSgxTCBOid...), 18), make([]byte, CPUSvnSize))))},
+	}
+	for name, exts := range invalid {
+		assert.Error(t, newPck(exts...).validateSgxExtensions(), name)
+	}
+
+	sgxType, ok := ParseSgxType("scalablewithintegrity")
+	assert.True(t, ok)
+	assert.Equal(t, SgxTypeScalableWithIntegrity, sgxType)
+	_, ok = ParseSgxType("TDX")
+	assert.False(t, ok)
+}
@@ -0,0 +1,291 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package parser
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/resource/verifier"
+	"io/ioutil"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// PckCAProcessor and PckCAPlatform are the PCK CAs issuing CRLs, as named by the ca parameter of the
+	// CRL distribution points of PCK certificates
+	PckCAProcessor = "processor"
+	PckCAPlatform  = "platform"
+
+	// CrlSourceSCS, CrlSourceOverride and CrlSourceImported tell whether a PCK CRL was served by SCS,
+	// fetched from the distribution point override of its CA or imported with the crl import command
+	CrlSourceSCS      = "scs"
+	CrlSourceOverride = "override"
+	CrlSourceImported = "imported"
+
+	pckProcessorCACommonName = "Intel SGX PCK Processor CA"
+)
+
+// PckCrlSource records where the PCK CRL of a CA was obtained from
+type PckCrlSource struct {
+	CA         string `json:"CA"`
+	Source     string `json:"Source"`
+	URL        string `json:"URL,omitempty"`
+	NextUpdate string `json:"NextUpdate"`
+	// CrlNumber is the CRL number extension of the CRL, empty when it has none
+	CrlNumber string `json:"CrlNumber,omitempty"`
+}
+
+// ImportedCrl describes a PCK CRL imported for offline use
+type ImportedCrl struct {
+	CA         string    `json:"ca"`
+	File       string    `json:"file"`
+	Number     *big.Int  `json:"number,omitempty"`
+	ThisUpdate time.Time `json:"thisUpdate"`
+	NextUpdate time.Time `json:"nextUpdate"`
+	Revoked    int       `json:"revoked"`
+}
+
+// ParseCrlOverrides parses "ca=url" entries overriding the CRL distribution point of the processor or
+// platform PCK CA
+func ParseCrlOverrides(entries []string) (map[string]string, error) {
+	overrides := make(map[string]string)
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || (parts[0] != PckCAProcessor && parts[0] != PckCAPlatform) {
+			return nil, errors.Errorf("ParseCrlOverrides: Invalid CRL override %s, must be processor=<url> or "+
+				"platform=<url>", entry)
+		}
+		u, err := url.Parse(parts[1])
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, errors.Errorf("ParseCrlOverrides: Invalid URL in CRL override %s", entry)
+		}
+		overrides[parts[0]] = parts[1]
+	}
+	return overrides, nil
+}
+
+// pckCrlCA returns the PCK CA whose CRL is at the distribution point, processor when it is not named
+func pckCrlCA(crlURL string) string {
+	u, err := url.Parse(crlURL)
+	if err == nil && u.Query().Get("ca") == PckCAPlatform {
+		return PckCAPlatform
+	}
+	return PckCAProcessor
+}
+
+// crlIssuerCA returns the PCK CA that issued the CRL, an empty string when it is not a PCK CA
+func crlIssuerCA(crl *pkix.CertificateList) string {
+	issuer := crl.TBSCertList.Issuer.String()
+	switch {
+	case strings.Contains(issuer, "CN="+pckProcessorCACommonName):
+		return PckCAProcessor
+	case strings.Contains(issuer, "CN="+constants.SGXPCKPlatformCACommonName):
+		return PckCAPlatform
+	}
+	return ""
+}
+
+// DecodeCrl decodes a PEM, base64 or DER encoded CRL
+func DecodeCrl(data []byte) (*pkix.CertificateList, error) {
+	data = bytes.TrimSpace(data)
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	} else if der, err := base64.StdEncoding.DecodeString(string(data)); err == nil {
+		data = der
+	}
+	crl, err := x509.ParseDERCRL(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "DecodeCrl: failed to parse CRL")
+	}
+	return crl, nil
+}
+
+// crlNumber returns the CRL number of the CRL, or nil if it does not carry one
+func crlNumber(crl *pkix.CertificateList) *big.Int {
+	for _, ext := range crl.TBSCertList.Extensions {
+		if verifier.ExtCRLNumberOid.Equal(ext.Id) {
+			number := new(big.Int)
+			if _, err := asn1.Unmarshal(ext.Value, &number); err == nil {
+				return number
+			}
+		}
+	}
+	return nil
+}
+
+func importedCrlFile(dir, ca string) string {
+	return filepath.Join(dir, ca+".crl")
+}
+
+// loadImportedCrl returns the CRL imported for the CA, nil when there is none or it has expired
+func loadImportedCrl(dir, ca string, now time.Time) *pkix.CertificateList {
+	data, err := ioutil.ReadFile(importedCrlFile(dir, ca))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.WithError(err).Error("loadImportedCrl: Error reading imported CRL")
+		}
+		return nil
+	}
+	crl, err := DecodeCrl(data)
+	if err != nil {
+		log.WithError(err).Error("loadImportedCrl: Invalid imported CRL")
+		return nil
+	}
+	if crl.HasExpired(now) {
+		log.Warnf("loadImportedCrl: The CRL imported for the PCK %s CA has expired, fetching it", ca)
+		return nil
+	}
+	return crl
+}
+
+// ImportedPckCrls lists the CRLs imported in dir, expired ones included
+func ImportedPckCrls(dir string) []ImportedCrl {
+	var crls []ImportedCrl
+	for _, ca := range []string{PckCAProcessor, PckCAPlatform} {
+		file := importedCrlFile(dir, ca)
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		crl, err := DecodeCrl(data)
+		if err != nil {
+			log.WithError(err).Errorf("ImportedPckCrls: Invalid CRL imported for the PCK %s CA", ca)
+			continue
+		}
+		crls = append(crls, ImportedCrl{
+			CA:         ca,
+			File:       file,
+			Number:     crlNumber(crl),
+			ThisUpdate: crl.TBSCertList.ThisUpdate.UTC(),
+			NextUpdate: crl.TBSCertList.NextUpdate.UTC(),
+			Revoked:    len(crl.TBSCertList.RevokedCertificates),
+		})
+	}
+	return crls
+}
+
+// ImportPckCrl validates a PCK CRL and stores it in dir, where it is used instead of fetching the CRL of its
+// CA until it expires. The CRL must be issued by a PCK CA, unexpired and not older than the CRL imported
+// before. When the issuer chain is given the CRL signature is verified and the chain must lead to trustedRoot,
+// otherwise the signature is verified against the PCK certificate chain of every quote.
+func ImportPckCrl(data []byte, dir string, issuerChain []*x509.Certificate, trustedRoot *x509.Certificate,
+	now time.Time) (*ImportedCrl, error) {
+	crl, err := DecodeCrl(data)
+	if err != nil {
+		return nil, err
+	}
+	ca := crlIssuerCA(crl)
+	if ca == "" {
+		return nil, errors.Errorf("ImportPckCrl: CRL issuer %s is not a PCK CA", crl.TBSCertList.Issuer.String())
+	}
+	if crl.HasExpired(now) {
+		return nil, errors.Errorf("ImportPckCrl: CRL expired on %s", crl.TBSCertList.NextUpdate.Format(time.RFC3339))
+	}
+
+	if len(issuerChain) > 0 {
+		err = verifyCrlIssuerChain(crl, issuerChain, trustedRoot, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	number := crlNumber(crl)
+	file := importedCrlFile(dir, ca)
+	if previousData, err := ioutil.ReadFile(file); err == nil {
+		if previous, err := DecodeCrl(previousData); err == nil {
+			previousNumber := crlNumber(previous)
+			if previousNumber != nil && number != nil && number.Cmp(previousNumber) < 0 {
+				return nil, errors.Errorf("ImportPckCrl: CRL number %s is older than the imported CRL number %s",
+					number, previousNumber)
+			}
+		}
+	}
+
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, errors.Wrap(err, "ImportPckCrl: Error creating CRL directory")
+	}
+	err = ioutil.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: crlDER(data)}), 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "ImportPckCrl: Error writing CRL")
+	}
+	return &ImportedCrl{
+		CA:         ca,
+		File:       file,
+		Number:     number,
+		ThisUpdate: crl.TBSCertList.ThisUpdate.UTC(),
+		NextUpdate: crl.TBSCertList.NextUpdate.UTC(),
+		Revoked:    len(crl.TBSCertList.RevokedCertificates),
+	}, nil
+}
+
+// verifyCrlIssuerChain verifies the CRL is signed by a CA of the chain that leads to the trusted root
+func verifyCrlIssuerChain(crl *pkix.CertificateList, chain []*x509.Certificate, trustedRoot *x509.Certificate,
+	now time.Time) error {
+	if trustedRoot == nil {
+		return errors.New("verifyCrlIssuerChain: the trusted SGX root CA is not available")
+	}
+	opts := x509.VerifyOptions{
+		CurrentTime:   now,
+		Roots:         x509.NewCertPool(),
+		Intermediates: x509.NewCertPool(),
+	}
+	opts.Roots.AddCert(trustedRoot)
+	for _, cert := range chain {
+		opts.Intermediates.AddCert(cert)
+	}
+	for _, cert := range chain {
+		if cert.CheckCRLSignature(crl) != nil {
+			continue
+		}
+		_, err := cert.Verify(opts)
+		if err != nil {
+			return errors.Wrap(err, "verifyCrlIssuerChain: CRL issuer is not trusted")
+		}
+		return nil
+	}
+	return errors.New("verifyCrlIssuerChain: CRL is not signed by the issuer chain")
+}
+
+// crlDER returns the DER encoding of a CRL previously decoded by DecodeCrl
+func crlDER(data []byte) []byte {
+	data = bytes.TrimSpace(data)
+	if block, _ := pem.Decode(data); block != nil {
+		return block.Bytes
+	}
+	if der, err := base64.StdEncoding.DecodeString(string(data)); err == nil {
+		return der
+	}
+	return data
+}
+
+func crlSourceOf(ca, source, crlURL string, crl *pkix.CertificateList) PckCrlSource {
+	crlSource := PckCrlSource{
+		CA:         ca,
+		Source:     source,
+		URL:        crlURL,
+		NextUpdate: crl.TBSCertList.NextUpdate.UTC().Format(time.RFC3339),
+	}
+	if number := crlNumber(crl); number != nil {
+		crlSource.CrlNumber = number.String()
+	}
+	return crlSource
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package parser
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestCrl(t *testing.T, commonName string, number int64, now time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCRLSign | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	issuer, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	crl, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(number),
+		ThisUpdate: now.Add(-time.Minute),
+		NextUpdate: now.Add(time.Hour),
+	}, issuer, key)
+	assert.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: crl})
+}
+
+func TestParseCrlOverrides(t *testing.T) {
+	overrides, err := ParseCrlOverrides([]string{"processor=https://mirror.example.com/pckcrl?ca=processor", ""})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{PckCAProcessor: "https://mirror.example.com/pckcrl?ca=processor"}, overrides)
+
+	_, err = ParseCrlOverrides([]string{"root=https://mirror.example.com/rootcrl"})
+	assert.Error(t, err)
+	_, err = ParseCrlOverrides([]string{"platform=mirror.example.com"})
+	assert.Error(t, err)
+
+	assert.Equal(t, PckCAPlatform, pckCrlCA("https://api.trustedservices.intel.com/sgx/certification/v3/pckcrl?ca=platform"))
+	assert.Equal(t, PckCAProcessor, pckCrlCA("https://api.trustedservices.intel.com/sgx/certification/v3/pckcrl"))
+}
+
+func TestImportPckCrl(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crls")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	now := time.Now()
+
+	imported, err := ImportPckCrl(createTestCrl(t, "Intel SGX PCK Processor CA", 2, now), dir, nil, nil, now)
+	assert.NoError(t, err)
+	assert.Equal(t, PckCAProcessor, imported.CA)
+	assert.Equal(t, int64(2), imported.Number.Int64())
+	assert.NotNil(t, loadImportedCrl(dir, PckCAProcessor, now))
+	assert.Nil(t, loadImportedCrl(dir, PckCAProcessor, now.Add(2*time.Hour)))
+	assert.Nil(t, loadImportedCrl(dir, PckCAPlatform, now))
+
+	// older CRLs are rejected
+	_, err = ImportPckCrl(createTestCrl(t, "Intel SGX PCK Processor CA", 1, now), dir, nil, nil, now)
+	assert.Error(t, err)
+	// so are expired ones and those of other CAs
+	_, err = ImportPckCrl(createTestCrl(t, "Intel SGX PCK Platform CA", 3, now), dir, nil, nil, now.Add(2*time.Hour))
+	assert.Error(t, err)
+	_, err = ImportPckCrl(createTestCrl(t, "Intel SGX Root CA", 3, now), dir, nil, nil, now)
+	assert.Error(t, err)
+}
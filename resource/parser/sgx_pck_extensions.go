@@ -0,0 +1,183 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package parser
+
+import (
+	"encoding/asn1"
+	"intel/isecl/sqvs/v4/resource/verifier"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	PPIDSize               = 16
+	PceIDSize              = 2
+	FmspcSize              = 6
+	CPUSvnSize             = 16
+	PlatformInstanceIDSize = 16
+	TcbCompSvnCount        = 16
+
+	SgxTypeStandard              = 0
+	SgxTypeScalable              = 1
+	SgxTypeScalableWithIntegrity = 2
+)
+
+var sgxTypeNames = map[int]string{
+	SgxTypeStandard:              "Standard",
+	SgxTypeScalable:              "Scalable",
+	SgxTypeScalableWithIntegrity: "ScalableWithIntegrity",
+}
+
+// SgxTypeName returns the name of an SGX type, as used in the Intel SGX PCK certificate profile
+func SgxTypeName(sgxType int) string {
+	return sgxTypeNames[sgxType]
+}
+
+// PckTcb is the TCB of the platform certified by the PCK certificate
+type PckTcb struct {
+	CompSvns [TcbCompSvnCount]uint8
+	PceSvn   uint16
+	CPUSvn   []byte
+}
+
+// GetTcb returns the TCB components of the PCK certificate
+func (e *PckCert) GetTcb() PckTcb {
+	return e.Tcb
+}
+
+// validateSgxExtensions checks the SGX extensions of the PCK certificate strictly against the Intel SGX PCK
+// certificate profile: every extension appears once, with the ASN.1 type and size of the profile, and the
+// TCB extension holds all the TCB components. SGX extensions unknown to SQVS are ignored.
+func (e *PckCert) validateSgxExtensions() error {
+	found := false
+	for _, ext := range e.PckCertObj.Extensions {
+		if !verifier.ExtSgxOid.Equal(ext.Id) {
+			continue
+		}
+		if found {
+			return errors.New("SGX Extensions appear more than once")
+		}
+		found = true
+
+		var sgxExtensions []sgxExtension
+		rest, err := asn1.Unmarshal(ext.Value, &sgxExtensions)
+		if err != nil || len(rest) > 0 {
+			return errors.New("SGX Extensions are not a valid sequence")
+		}
+		seen := make(map[string]bool)
+		for _, sgxExt := range sgxExtensions {
+			if seen[sgxExt.ID.String()] {
+				return errors.New("SGX Extension " + sgxExt.ID.String() + " appears more than once")
+			}
+			seen[sgxExt.ID.String()] = true
+
+			switch {
+			case verifier.ExtSgxPPIDOid.Equal(sgxExt.ID):
+				err = checkOctetString(sgxExt, PPIDSize)
+			case verifier.ExtSgxPCEIDOid.Equal(sgxExt.ID):
+				err = checkOctetString(sgxExt, PceIDSize)
+			case verifier.ExtSgxFMSPCOid.Equal(sgxExt.ID):
+				err = checkOctetString(sgxExt, FmspcSize)
+			case verifier.ExtSgxPlatformInstanceIDOid.Equal(sgxExt.ID):
+				err = checkOctetString(sgxExt, PlatformInstanceIDSize)
+			case verifier.ExtSgxSGXTypeOid.Equal(sgxExt.ID):
+				var sgxType asn1.Enumerated
+				_, err = asn1.Unmarshal(sgxExt.Value.FullBytes, &sgxType)
+				if err == nil && SgxTypeName(int(sgxType)) == "" {
+					err = errors.Errorf("unknown SGX Type %d", sgxType)
+				}
+			case verifier.ExtSgxTCBOid.Equal(sgxExt.ID):
+				e.Tcb, err = parseTcbExtension(sgxExt)
+			}
+			if err != nil {
+				return errors.Wrap(err, "SGX Extension "+sgxExt.ID.String()+" is invalid")
+			}
+		}
+	}
+	if !found {
+		return errors.New("SGX Extensions not found")
+	}
+	return nil
+}
+
+func checkOctetString(ext sgxExtension, size int) error {
+	var value []byte
+	_, err := asn1.Unmarshal(ext.Value.FullBytes, &value)
+	if err != nil {
+		return errors.Wrap(err, "value is not an OCTET STRING")
+	}
+	if len(value) != size {
+		return errors.Errorf("value is %d bytes long instead of %d", len(value), size)
+	}
+	return nil
+}
+
+// parseTcbExtension decodes the TCB extension, a sequence of the 16 TCB component SVNs, the PCESVN and the
+// CPUSVN identified by the OIDs 1 to 18 under the TCB extension OID
+func parseTcbExtension(ext sgxExtension) (PckTcb, error) {
+	var tcb PckTcb
+	var components []sgxExtension
+	_, err := asn1.Unmarshal(ext.Value.FullBytes, &components)
+	if err != nil {
+		return tcb, errors.Wrap(err, "value is not a sequence of TCB components")
+	}
+
+	seen := make(map[int]bool)
+	for _, component := range components {
+		id := component.ID
+		if len(id) != len(verifier.ExtSgxTCBOid)+1 || !verifier.ExtSgxTCBOid.Equal(id[:len(id)-1]) {
+			return tcb, errors.New("unknown TCB component " + id.String())
+		}
+		index := id[len(id)-1]
+		if seen[index] {
+			return tcb, errors.New("TCB component " + id.String() + " appears more than once")
+		}
+		seen[index] = true
+
+		switch {
+		case index >= 1 && index <= TcbCompSvnCount:
+			var svn int
+			_, err = asn1.Unmarshal(component.Value.FullBytes, &svn)
+			if err != nil || svn < 0 || svn > 0xff {
+				return tcb, errors.New("TCB component " + id.String() + " is not a valid SVN")
+			}
+			tcb.CompSvns[index-1] = uint8(svn)
+		case index == TcbCompSvnCount+1:
+			var svn int
+			_, err = asn1.Unmarshal(component.Value.FullBytes, &svn)
+			if err != nil || svn < 0 || svn > 0xffff {
+				return tcb, errors.New("PCESVN is not a valid SVN")
+			}
+			tcb.PceSvn = uint16(svn)
+		case index == TcbCompSvnCount+2:
+			_, err = asn1.Unmarshal(component.Value.FullBytes, &tcb.CPUSvn)
+			if err != nil || len(tcb.CPUSvn) != CPUSvnSize {
+				return tcb, errors.New("CPUSVN is not a valid OCTET STRING")
+			}
+		default:
+			return tcb, errors.New("unknown TCB component " + id.String())
+		}
+	}
+	if len(seen) != TcbCompSvnCount+2 {
+		return tcb, errors.Errorf("%d TCB components found instead of %d", len(seen), TcbCompSvnCount+2)
+	}
+	for i, svn := range tcb.CompSvns {
+		if tcb.CPUSvn[i] != svn {
+			return tcb, errors.New("CPUSVN does not match the TCB component SVNs")
+		}
+	}
+	return tcb, nil
+}
+
+// ParseSgxType returns the SGX type of its name in the Intel SGX PCK certificate profile, case insensitive
+func ParseSgxType(name string) (int, bool) {
+	for sgxType, typeName := range sgxTypeNames {
+		if strings.EqualFold(name, typeName) {
+			return sgxType, true
+		}
+	}
+	return 0, false
+}
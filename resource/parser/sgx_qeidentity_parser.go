@@ -5,14 +5,17 @@
 package parser
 
 import (
+	"context"
 	"crypto/x509"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"intel/isecl/lib/clients/v4"
+	"intel/isecl/sqvs/v4/aasclient"
 	"intel/isecl/sqvs/v4/config"
 	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/resilience"
 	"intel/isecl/sqvs/v4/resource/utils"
+	"intel/isecl/sqvs/v4/truststore"
 	"io/ioutil"
 	"net/http"
 	"strings"
@@ -30,6 +33,7 @@ type QeIdentityData struct {
 	RootCA         map[string]*x509.Certificate
 	IntermediateCA map[string]*x509.Certificate
 	RawBlob        []byte
+	IssuerChain    string
 }
 
 type TcbInfo struct {
@@ -57,26 +61,32 @@ type EnclaveIdentityType struct {
 	TcbLevels               []TcbLevelsInfo `json:"tcbLevels"`
 }
 
-func NewQeIdentity() (*QeIdentityData, error) {
-	obj := new(QeIdentityData)
-
+// NewQeIdentity fetches the QE identity from SCS, the fetch being canceled with parent
+func NewQeIdentity(parent context.Context) (*QeIdentityData, error) {
 	conf := config.Global()
 	if conf == nil {
 		return nil, errors.Wrap(errors.New("NewQeIdentity: Configuration pointer is null"), "Config error")
 	}
 
-	client, err := clients.HTTPClientWithCADir(constants.TrustedCAsStoreDir)
+	httpClient, err := truststore.HTTPClient(constants.TrustedCAsStoreDir)
 	if err != nil {
 		return nil, errors.Wrap(err, "NewQeIdentity: Error in getting client object")
 	}
+	client := resilience.Default().Client(httpClient)
 
+	ctx, cancel := collateralFetchContext(parent)
+	defer cancel()
 	url := fmt.Sprintf("%s/qe/identity", conf.SCSBaseURL)
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, errors.Wrap(err, "NewQeIdentity: failed to get new request")
 	}
 
 	req.Header.Set("Accept", "application/json")
+	err = aasclient.Authorize(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "NewQeIdentity: failed to get bearer token")
+	}
 	q := req.URL.Query()
 	req.URL.RawQuery = q.Encode()
 
@@ -91,30 +101,41 @@ func NewQeIdentity() (*QeIdentityData, error) {
 	}
 
 	if err != nil {
-		return nil, errors.Wrap(err, "NewQeIdentity: failed to do client request")
+		return nil, requestError(ctx, err, "NewQeIdentity: failed to do client request")
 	}
 
 	if resp.StatusCode != 200 {
-		return nil, errors.New(fmt.Sprintf("NewQeIdentity: Invalid Status code received: %d", resp.StatusCode))
+		return nil, statusError("NewQeIdentity: Invalid Status code received", resp.StatusCode)
 	}
 
 	content, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, errors.Wrap(err, "read Response failed ")
+		return nil, fetchError(ctx, err, "read Response failed ")
 	}
 
 	if len(content) == 0 {
 		return nil, errors.Wrap(err, "NewQeIdentity: no qe identity data received")
 	}
+	err = crossCheckCollateral(ctx, collateralQeIdentity, "/qe/identity", nil, content)
+	if err != nil {
+		return nil, errors.Wrap(err, "NewQeIdentity: qe identity cross-check failed")
+	}
+	return ParseQeIdentity(content, resp.Header.Get("Sgx-Qe-Identity-Issuer-Chain"))
+}
 
+// ParseQeIdentity parses the QE identity JSON and the PEM encoded issuer chain it is signed with, as they are
+// returned by SCS
+func ParseQeIdentity(content []byte, issuerChain string) (*QeIdentityData, error) {
+	obj := new(QeIdentityData)
 	obj.RawBlob = make([]byte, len(content))
 	copy(obj.RawBlob, content)
+	obj.IssuerChain = issuerChain
 
 	if err := json.Unmarshal(content, &obj.QEJson); err != nil {
 		return nil, errors.Wrap(err, "NewQeIdentity: cannot unmarshal qeidentity data")
 	}
 
-	certChainList, err := utils.GetCertObjList(resp.Header.Get("Sgx-Qe-Identity-Issuer-Chain"))
+	certChainList, err := utils.GetCertObjList(issuerChain)
 	if err != nil {
 		return nil, errors.Wrap(err, "NewQeIdentity: failed to get QE Identity CertChain")
 	}
@@ -171,7 +192,7 @@ func (e *QeIdentityData) GetQeIdentityStatus() bool {
 	if err != nil {
 		return false
 	}
-	if !utils.IntToBool(int(e.getQeIDVer())) || !utils.IntToBool(len(e.GetQeIDIssueDate())) ||
+	if !utils.IntToBool(int(e.GetQeIDVersion())) || !utils.IntToBool(len(e.GetQeIDIssueDate())) ||
 		!utils.IntToBool(len(e.GetQeIDMiscSelect())) || !utils.IntToBool(len(e.GetQeIDMiscSelectMask())) ||
 		!utils.IntToBool(len(e.GetQeIDAttributes())) || !utils.IntToBool(len(e.GetQeIDAttributesMask())) ||
 		!utils.IntToBool(len(e.GetQeIDMrSigner())) || !utils.IntToBool(int(e.GetQeIDIsvProdID())) ||
@@ -181,10 +202,14 @@ func (e *QeIdentityData) GetQeIdentityStatus() bool {
 	return true
 }
 
-func (e *QeIdentityData) getQeIDVer() uint16 {
+func (e *QeIdentityData) GetQeIDVersion() uint16 {
 	return e.QEJson.EnclaveIdentity.Version
 }
 
+func (e *QeIdentityData) GetQeIDTcbEvaluationDataNumber() uint16 {
+	return e.QEJson.EnclaveIdentity.TcbEvaluationDataNumber
+}
+
 func (e *QeIdentityData) GetQeIDIssueDate() string {
 	return e.QEJson.EnclaveIdentity.IssueDate
 }
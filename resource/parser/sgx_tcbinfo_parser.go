@@ -5,14 +5,17 @@
 package parser
 
 import (
+	"context"
 	"crypto/x509"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
-	"intel/isecl/lib/clients/v4"
+	"intel/isecl/sqvs/v4/aasclient"
 	"intel/isecl/sqvs/v4/config"
 	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/resilience"
 	"intel/isecl/sqvs/v4/resource/utils"
+	"intel/isecl/sqvs/v4/truststore"
 	"io/ioutil"
 	"math/big"
 	"net/http"
@@ -28,6 +31,74 @@ const (
 	Undefined
 )
 
+const (
+	TcbStatusUpToDate                          = "UpToDate"
+	TcbStatusSWHardeningNeeded                 = "SWHardeningNeeded"
+	TcbStatusConfigurationNeeded               = "ConfigurationNeeded"
+	TcbStatusConfigurationAndSWHardeningNeeded = "ConfigurationAndSWHardeningNeeded"
+	TcbStatusOutOfDate                         = "OutOfDate"
+	TcbStatusOutOfDateConfigurationNeeded      = "OutOfDateConfigurationNeeded"
+	TcbStatusRevoked                           = "Revoked"
+)
+
+// tcbStatusSeverity orders the TCB statuses from the most to the least trustworthy
+var tcbStatusSeverity = map[string]int{
+	TcbStatusUpToDate:                          0,
+	TcbStatusSWHardeningNeeded:                 1,
+	TcbStatusConfigurationNeeded:               2,
+	TcbStatusConfigurationAndSWHardeningNeeded: 3,
+	TcbStatusOutOfDate:                         4,
+	TcbStatusOutOfDateConfigurationNeeded:      5,
+	TcbStatusRevoked:                           6,
+}
+
+// TcbStatusSeverity returns the rank of a TCB status, higher values are worse.
+// The second return value is false if the status is not known.
+func TcbStatusSeverity(status string) (int, bool) {
+	severity, ok := tcbStatusSeverity[status]
+	return severity, ok
+}
+
+// Verdicts a TCB status is mapped to: quotes are accepted, accepted with a warning or rejected
+const (
+	VerdictAllow = "allow"
+	VerdictWarn  = "warn"
+	VerdictDeny  = "deny"
+)
+
+// ParseTcbStatusVerdicts parses "Status=verdict" entries mapping TCB statuses to allow, warn or deny
+func ParseTcbStatusVerdicts(entries []string) (map[string]string, error) {
+	verdicts := map[string]string{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("parser/sgx_tcbinfo_parser:ParseTcbStatusVerdicts() Invalid TCB status "+
+				"verdict %s, must be Status=verdict", entry)
+		}
+		status := ""
+		for known := range tcbStatusSeverity {
+			if strings.EqualFold(known, strings.TrimSpace(parts[0])) {
+				status = known
+			}
+		}
+		if status == "" {
+			return nil, errors.Errorf("parser/sgx_tcbinfo_parser:ParseTcbStatusVerdicts() Unknown TCB status %s",
+				parts[0])
+		}
+		verdict := strings.ToLower(strings.TrimSpace(parts[1]))
+		if verdict != VerdictAllow && verdict != VerdictWarn && verdict != VerdictDeny {
+			return nil, errors.Errorf("parser/sgx_tcbinfo_parser:ParseTcbStatusVerdicts() Invalid verdict %s of "+
+				"TCB status %s, must be allow, warn or deny", parts[1], status)
+		}
+		verdicts[status] = verdict
+	}
+	return verdicts, nil
+}
+
 type TcbType struct {
 	SgxTcbComp01Svn uint8  `json:"sgxtcbcomp01svn"`
 	SgxTcbComp02Svn uint8  `json:"sgxtcbcomp02svn"`
@@ -75,25 +146,38 @@ type TcbInfoStruct struct {
 	RootCA         map[string]*x509.Certificate
 	IntermediateCA map[string]*x509.Certificate
 	RawBlob        []byte
+	IssuerChain    string
 }
 type ECDSASignature struct {
 	R, S *big.Int
 }
 
-func NewTcbInfo(fmspc string) (*TcbInfoStruct, error) {
+// NewTcbInfo fetches the TCB info of the FMSPC from SCS, the fetch being canceled with ctx
+func NewTcbInfo(ctx context.Context, fmspc string) (*TcbInfoStruct, error) {
 	var err error
 	if len(fmspc) < constants.FmspcLen {
 		return nil, errors.Wrap(err, "NewTcbInfo: FMSPC value not found")
 	}
 
 	tcbInfoStruct := new(TcbInfoStruct)
-	err = tcbInfoStruct.getTcbInfoStruct(fmspc)
+	err = tcbInfoStruct.getTcbInfoStruct(ctx, fmspc)
 	if err != nil {
 		return nil, errors.Wrap(err, "NewTcbInfo: Failed to get Tcb Info")
 	}
 	return tcbInfoStruct, nil
 }
 
+// ParseTcbInfo parses TCB info previously fetched from SCS, content being the TCB info JSON and issuerChain
+// the PEM encoded certificate chain it is signed with
+func ParseTcbInfo(content []byte, issuerChain string) (*TcbInfoStruct, error) {
+	tcbInfoStruct := new(TcbInfoStruct)
+	err := tcbInfoStruct.parseTcbInfo(content, issuerChain)
+	if err != nil {
+		return nil, errors.Wrap(err, "ParseTcbInfo: Failed to parse Tcb Info")
+	}
+	return tcbInfoStruct, nil
+}
+
 func (e *TcbInfoStruct) GetTcbInfoInterCaList() []*x509.Certificate {
 	interMediateCAArr := make([]*x509.Certificate, len(e.IntermediateCA))
 	var i int
@@ -122,25 +206,35 @@ func (e *TcbInfoStruct) GetTcbInfoNextUpdate() string {
 	return e.TcbInfoData.TcbInfo.NextUpdate
 }
 
-func (e *TcbInfoStruct) getTcbInfoStruct(fmspc string) error {
+func (e *TcbInfoStruct) getTcbInfoStruct(parent context.Context, fmspc string) error {
 	conf := config.Global()
 	if conf == nil {
 		return errors.Wrap(errors.New("getTcbInfoStruct: Configuration pointer is null"), "Config error")
 	}
 
-	client, err := clients.HTTPClientWithCADir(constants.TrustedCAsStoreDir)
+	httpClient, err := truststore.HTTPClient(constants.TrustedCAsStoreDir)
 	if err != nil {
 		return errors.Wrap(err, "getTcbInfoStruct: Error in getting client object")
 	}
+	client := resilience.Default().Client(httpClient)
 
+	ctx, cancel := collateralFetchContext(parent)
+	defer cancel()
 	url := fmt.Sprintf("%s/tcb", conf.SCSBaseURL)
-	req, err := http.NewRequest("GET", url, nil)
+	// the TCB info of each FMSPC has a circuit breaker of its own, so the broken collateral of a platform
+	// family does not stop the verifications of the others
+	req, err := http.NewRequestWithContext(resilience.WithScope(ctx, "fmspc="+strings.ToLower(fmspc)), "GET",
+		url, nil)
 	if err != nil {
 		log.Error("getTcbInfoStruct: req object error")
 		return errors.Wrap(err, "getTcbInfoStruct: Failed to Get http NewRequest")
 	}
 
 	req.Header.Set("Accept", "application/json")
+	err = aasclient.Authorize(req)
+	if err != nil {
+		return errors.Wrap(err, "getTcbInfoStruct: Failed to get bearer token")
+	}
 	q := req.URL.Query()
 	q.Add("fmspc", fmspc)
 	req.URL.RawQuery = q.Encode()
@@ -156,30 +250,41 @@ func (e *TcbInfoStruct) getTcbInfoStruct(fmspc string) error {
 	}
 
 	if err != nil {
-		return errors.Wrap(err, "getTcbInfoStruct: Failed to Get tcbinfo response from scs")
+		return requestError(ctx, err, "getTcbInfoStruct: Failed to Get tcbinfo response from scs")
 	}
 	log.Debug("getTcbInfoStruct: Got status:", resp.StatusCode, ", content-len:", resp.ContentLength, " resp body:", resp.Body)
 
 	if resp.StatusCode != 200 {
-		return errors.New(fmt.Sprintf("getTcbInfoStruct: Invalid Status code received: %d", resp.StatusCode))
+		return statusError("getTcbInfoStruct: Invalid Status code received", resp.StatusCode)
 	}
 
 	content, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return errors.Wrap(err, "getTcbInfoStruct: tcbinfo read response failed ")
+		return fetchError(ctx, err, "getTcbInfoStruct: tcbinfo read response failed ")
 	}
 
 	if len(content) == 0 {
 		return errors.Wrap(err, "getTcbInfoStruct: no tcbinfo data received")
 	}
 
-	e.RawBlob = make([]byte, len(content))
+	log.Debug("GetTcbInfoJSON: blob[", resp.ContentLength, "]:", len(content))
+	err = crossCheckCollateral(ctx, collateralTcbInfo, "/tcb", q, content)
+	if err != nil {
+		return errors.Wrap(err, "getTcbInfoStruct: tcbinfo cross-check failed")
+	}
+	return e.parseTcbInfo(content, resp.Header.Get("SGX-TCB-Info-Issuer-Chain"))
+}
 
-	copy(e.RawBlob, content)
+func (e *TcbInfoStruct) parseTcbInfo(content []byte, issuerChain string) error {
+	if len(content) == 0 {
+		return errors.New("parseTcbInfo: no tcbinfo data")
+	}
 
-	log.Debug("GetTcbInfoJSON: blob[", resp.ContentLength, "]:", len(e.RawBlob))
+	e.RawBlob = make([]byte, len(content))
+	copy(e.RawBlob, content)
+	e.IssuerChain = issuerChain
 
-	certChainList, err := utils.GetCertObjList(resp.Header.Get("SGX-TCB-Info-Issuer-Chain"))
+	certChainList, err := utils.GetCertObjList(issuerChain)
 	if err != nil {
 		return errors.Wrap(err, "getTcbInfoStruct: failed to get cert object")
 	}
@@ -271,10 +376,31 @@ func getTcbCompList(tcbLevelList *TcbType) []byte {
 }
 
 func (e *TcbInfoStruct) GetTcbUptoDateStatus(tcbLevels []byte) string {
+	level := e.matchTcbLevel(tcbLevels)
+	if level == nil {
+		return ""
+	}
+	return level.TcbStatus
+}
+
+// GetTcbLevelDate returns the date of the TCB level matching the PCK certificate TCB components
+func (e *TcbInfoStruct) GetTcbLevelDate(tcbLevels []byte) string {
+	level := e.matchTcbLevel(tcbLevels)
+	if level == nil {
+		return ""
+	}
+	return level.TcbDate
+}
+
+func (e *TcbInfoStruct) GetTcbEvaluationDataNumber() uint {
+	return e.TcbInfoData.TcbInfo.TcbEvaluationDataNumber
+}
+
+// matchTcbLevel returns the highest TCB level the PCK certificate TCB components are equal to or greater than
+func (e *TcbInfoStruct) matchTcbLevel(tcbLevels []byte) *TcbLevelsType {
 	pckComponents := tcbLevels[:16]
 	pckPceSvn := binary.LittleEndian.Uint16(tcbLevels[16:])
 
-	var status string
 	var tcbComponents []byte
 	// iterate through all TCB Levels present in TCBInfo
 	for i := 0; i < len(e.TcbInfoData.TcbInfo.TcbLevels); i++ {
@@ -282,11 +408,10 @@ func (e *TcbInfoStruct) GetTcbUptoDateStatus(tcbLevels []byte) string {
 		tcbComponents = getTcbCompList(&e.TcbInfoData.TcbInfo.TcbLevels[i].Tcb)
 		tcbError := compareTcbComponents(pckComponents, pckPceSvn, tcbComponents, tcbPceSvn)
 		if tcbError == EqualOrGreater {
-			status = e.TcbInfoData.TcbInfo.TcbLevels[i].TcbStatus
-			break
+			return &e.TcbInfoData.TcbInfo.TcbLevels[i]
 		}
 	}
-	return status
+	return nil
 }
 
 func (e *TcbInfoStruct) DumpTcbInfo() {
@@ -0,0 +1,168 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/json"
+	"intel/isecl/sqvs/v4/capture"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/logformat"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+var payloadCapture *capture.Recorder
+
+// SetPayloadCapture sets the recorder of the payload captures, captures are disabled when it is nil
+func SetPayloadCapture(c *capture.Recorder) {
+	payloadCapture = c
+}
+
+// PayloadCaptureMiddleware records the requests and responses of the running payload capture
+func PayloadCaptureMiddleware(next http.Handler) http.Handler {
+	if payloadCapture == nil {
+		return next
+	}
+	return payloadCapture.Middleware(func(r *http.Request) string {
+		return callerOf(r).Address
+	})(next)
+}
+
+// PayloadCaptureRequest starts a payload capture for Duration, of the requests of the Client address and to
+// the paths under PathPrefix when they are set
+type PayloadCaptureRequest struct {
+	Duration   string `json:"duration"`
+	Client     string `json:"client,omitempty"`
+	PathPrefix string `json:"pathPrefix,omitempty"`
+}
+
+// PayloadCaptureState is the running payload capture, if any
+type PayloadCaptureState struct {
+	Running bool             `json:"running"`
+	Session *capture.Session `json:"session,omitempty"`
+}
+
+func PayloadCaptureCB(router *mux.Router) {
+	router.Handle("/admin/payload-capture", getPayloadCapture()).Methods("GET")
+	router.Handle("/admin/payload-capture", startPayloadCapture()).Methods("PUT")
+	router.Handle("/admin/payload-capture", stopPayloadCapture()).Methods("DELETE")
+}
+
+func getPayloadCapture() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/payload_capture:getPayloadCapture() Entering")
+		defer log.Trace("resource/payload_capture:getPayloadCapture() Leaving")
+
+		err := authorizePayloadCapture(r)
+		if err != nil {
+			return err
+		}
+		session, running := payloadCapture.Session()
+		return writePayloadCaptureState(w, session, running)
+	}
+}
+
+func startPayloadCapture() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/payload_capture:startPayloadCapture() Entering")
+		defer log.Trace("resource/payload_capture:startPayloadCapture() Leaving")
+
+		err := authorizePayloadCapture(r)
+		if err != nil {
+			return err
+		}
+		var req PayloadCaptureRequest
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&req)
+		if err != nil {
+			return &resourceError{Message: "Invalid JSON input provided", StatusCode: http.StatusBadRequest}
+		}
+		maxDuration := constants.DefaultPayloadCaptureDuration
+		if conf := config.Global(); conf.PayloadCapture.MaxDuration > 0 {
+			maxDuration = conf.PayloadCapture.MaxDuration
+		}
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil || duration <= 0 || duration > maxDuration {
+			return &resourceError{Message: "Invalid capture duration, must be positive and at most " +
+				maxDuration.String(), StatusCode: http.StatusBadRequest}
+		}
+
+		startedBy, _ := tokenClaim(r, "sub").(string)
+		if startedBy == "" {
+			startedBy = callerOf(r).Address
+		}
+		session, err := payloadCapture.Start(duration, req.Client, req.PathPrefix, startedBy)
+		if err != nil {
+			log.WithError(err).Error("resource/payload_capture:startPayloadCapture() Error starting payload capture")
+			return &resourceError{Message: "Error starting payload capture", StatusCode: http.StatusInternalServerError}
+		}
+		slog.WithFields(adminEventFields(logformat.OutcomeSuccess)).Warnf(
+			"resource/payload_capture:startPayloadCapture() Payload capture started by %s until %s, client %q, "+
+				"path prefix %q", startedBy, session.Until.Format(time.RFC3339), req.Client, req.PathPrefix)
+		return writePayloadCaptureState(w, session, true)
+	}
+}
+
+func stopPayloadCapture() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/payload_capture:stopPayloadCapture() Entering")
+		defer log.Trace("resource/payload_capture:stopPayloadCapture() Leaving")
+
+		err := authorizePayloadCapture(r)
+		if err != nil {
+			return err
+		}
+		session, stopped := payloadCapture.Stop()
+		if stopped {
+			slog.WithFields(adminEventFields(logformat.OutcomeSuccess)).Infof(
+				"resource/payload_capture:stopPayloadCapture() Payload capture stopped after %d requests",
+				session.Captured)
+		}
+		return writePayloadCaptureState(w, capture.Session{}, false)
+	}
+}
+
+// authorizePayloadCapture restricts the payload captures to the holders of the PayloadCapture role, captures
+// are only available once the service is configured for them
+func authorizePayloadCapture(r *http.Request) error {
+	conf := config.Global()
+	if conf == nil {
+		return &resourceError{Message: "Could not read config", StatusCode: http.StatusInternalServerError}
+	}
+	if conf.IncludeToken {
+		err := AuthorizeEndpoint(r, constants.PayloadCaptureGroupName, true)
+		if err != nil {
+			return err
+		}
+	}
+	if payloadCapture == nil {
+		return &resourceError{Message: "Payload capture is not enabled", StatusCode: http.StatusNotFound}
+	}
+	return nil
+}
+
+func writePayloadCaptureState(w http.ResponseWriter, session capture.Session, running bool) error {
+	state := PayloadCaptureState{Running: running}
+	if running {
+		state.Session = &session
+	}
+	body, err := json.Marshal(state)
+	if err != nil {
+		return &resourceError{Message: "Error marshalling payload capture state in JSON",
+			StatusCode: http.StatusInternalServerError}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(body)
+	if err != nil {
+		return &resourceError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+	return nil
+}
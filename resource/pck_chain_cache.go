@@ -0,0 +1,135 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"intel/isecl/sqvs/v4/resource/utils"
+	"intel/isecl/sqvs/v4/resource/verifier"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// pckChainCache shares the verified PCK certificate chains between the quotes of a batch. Quotes of the
+// same platform carry the same PCK certificate chain, it is parsed, its CRLs fetched and verified once.
+type pckChainCache struct {
+	mu     sync.Mutex
+	chains map[string]*pckChainEntry
+}
+
+type pckChainEntry struct {
+	once    sync.Once
+	certObj *parser.PckCert
+	err     error
+}
+
+func newPCKChainCache() *pckChainCache {
+	return &pckChainCache{chains: map[string]*pckChainEntry{}}
+}
+
+// verify returns the parsed PCK certificate of the quote once its chain and CRLs are verified. The chain
+// is verified again for every quote when the cache is nil.
+func (c *pckChainCache) verify(quoteObj *parser.SgxQuoteParsed, sgxCaCert *x509.Certificate, now,
+	at time.Time, diag *diagnostics, clock *verificationClock) (*parser.PckCert, error) {
+	pckCertBytes, err := utils.GetCertPemData(quoteObj.GetQuotePckCertObj())
+	if err != nil {
+		log.WithError(err).Error("Cannot extract PCK cert data")
+		return nil, &resourceError{Message: "Cannot extract PCK cert data",
+			StatusCode: http.StatusBadRequest}
+	}
+	var chain []byte
+	for _, cert := range quoteObj.GetQuotePckCertInterCAList() {
+		chain = append(chain, cert.Raw...)
+	}
+	chainHash := fmt.Sprintf("%x", sha256.Sum256(append(pckCertBytes, chain...)))
+	if c == nil {
+		return verifyPCKChainCached(chainHash, quoteObj, pckCertBytes, sgxCaCert, now, at, diag, clock)
+	}
+	key := fmt.Sprintf("%s-%d", chainHash, at.UnixNano())
+
+	c.mu.Lock()
+	entry, ok := c.chains[key]
+	if !ok {
+		entry = &pckChainEntry{}
+		c.chains[key] = entry
+	}
+	c.mu.Unlock()
+
+	hit := true
+	entry.once.Do(func() {
+		hit = false
+		entry.certObj, entry.err = verifyPCKChainCached(chainHash, quoteObj, pckCertBytes, sgxCaCert, now, at, diag,
+			clock)
+	})
+	diag.cache(cachePckChain, hit)
+	return entry.certObj, entry.err
+}
+
+// verifyPCKChainCached verifies the PCK certificate chain of the quote unless it was verified for an earlier
+// quote of the platform against the current CRLs
+func verifyPCKChainCached(chainHash string, quoteObj *parser.SgxQuoteParsed, pckCertBytes []byte,
+	sgxCaCert *x509.Certificate, now, at time.Time, diag *diagnostics, clock *verificationClock) (*parser.PckCert,
+	error) {
+	if certObj := verifiedPckChains.lookup(chainHash, now, at); certObj != nil {
+		diag.cache(cacheVerifiedPckChain, true)
+		return certObj, nil
+	}
+	diag.cache(cacheVerifiedPckChain, false)
+	certObj, err := verifyPCKChain(quoteObj, pckCertBytes, sgxCaCert, now, at, diag, clock)
+	if err != nil {
+		return nil, err
+	}
+	chain := append(append([]*x509.Certificate{quoteObj.GetQuotePckCertObj()},
+		quoteObj.GetQuotePckCertInterCAList()...), quoteObj.GetQuotePckCertRootCAList()...)
+	verifiedPckChains.store(chainHash, certObj, chain, now)
+	return certObj, nil
+}
+
+// verifyPCKChain verifies the PCK certificate chain of the quote at the evaluation time and the PCK CRLs
+// at the current trusted time
+func verifyPCKChain(quoteObj *parser.SgxQuoteParsed, pckCertBytes []byte, sgxCaCert *x509.Certificate, now,
+	at time.Time, diag *diagnostics, clock *verificationClock) (*parser.PckCert, error) {
+	certObj := parser.ParsePCKCertObj(pckCertBytes)
+	if certObj == nil {
+		return nil, &resourceError{Message: "Invalid PCK Certificate Buffer", StatusCode: http.StatusBadRequest}
+	}
+	err := clock.fetch(StepCrlCheck, phaseCRLFetch, certObj.FetchPckCrl)
+	if err != nil {
+		log.WithError(err).Error("Cannot fetch PCK crl")
+		return nil, &stepError{step: StepCrlCheck, err: fetchFailure(err, "Cannot fetch PCK crl",
+			http.StatusBadRequest)}
+	}
+
+	err = verifier.VerifyPCKCertificate(quoteObj.GetQuotePckCertObj(), quoteObj.GetQuotePckCertInterCAList(),
+		quoteObj.GetQuotePckCertRootCAList(), certObj.GetPckCrlObj(), sgxCaCert, at)
+	if err != nil {
+		log.WithError(err).Error("Cannot verify pck cert")
+		return nil, &resourceError{Message: "Cannot verify pck cert",
+			StatusCode: http.StatusBadRequest}
+	}
+
+	log.Debug("PCK Certificate Chain Verified")
+	// imported CRLs and those of mirrors without an issuer chain are verified against the chain of the quote
+	crlInterCAs, crlRootCAs := certObj.GetPckCrlInterCaList(), certObj.GetPckCrlRootCaList()
+	if len(crlInterCAs) == 0 || len(crlRootCAs) == 0 {
+		crlInterCAs, crlRootCAs = quoteObj.GetQuotePckCertInterCAList(), quoteObj.GetQuotePckCertRootCAList()
+	}
+	crlStarted := time.Now()
+	err = verifier.VerifyPckCrl(certObj.GetPckCrlURL(), certObj.GetPckCrlObj(), crlInterCAs, crlRootCAs, sgxCaCert,
+		now)
+	diag.record(StepCrlCheck, time.Since(crlStarted))
+	if err != nil {
+		log.WithError(err).Error("Cannot verify PCK crl")
+		return nil, &stepError{step: StepCrlCheck, err: &resourceError{Message: "Cannot verify PCK crl",
+			StatusCode: http.StatusBadRequest}}
+	}
+
+	log.Debug("PCK Certificates checked against PCK Certificate Revocation List")
+	return certObj, nil
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/hex"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"net/http"
+	"strings"
+)
+
+// PckCertExtensions are the SGX extensions of the PCK certificate of a verified quote. The multi-package
+// platform settings are omitted when the PCK certificate does not define them.
+type PckCertExtensions struct {
+	PPID               string `json:"PPID,omitempty"`
+	FMSPC              string `json:"FMSPC"`
+	PCEID              string `json:"PCEID"`
+	SgxType            string `json:"SgxType"`
+	TcbCompSvns        []int  `json:"TcbCompSvns"`
+	PceSvn             uint16 `json:"PceSvn"`
+	CPUSvn             string `json:"CPUSvn"`
+	PlatformInstanceID string `json:"PlatformInstanceID,omitempty"`
+	DynamicPlatform    *bool  `json:"DynamicPlatform,omitempty"`
+	CachedKeys         *bool  `json:"CachedKeys,omitempty"`
+	SMTEnabled         *bool  `json:"SMTEnabled,omitempty"`
+}
+
+// newPckCertExtensions collects the SGX extensions of the PCK certificate, validated when it was parsed
+func newPckCertExtensions(certObj *parser.PckCert) *PckCertExtensions {
+	tcb := certObj.GetTcb()
+	ext := &PckCertExtensions{
+		PPID:               certObj.GetPPIDValue(),
+		FMSPC:              certObj.GetFmspcValue(),
+		PCEID:              certObj.GetPceIDValue(),
+		SgxType:            parser.SgxTypeName(certObj.GetSgxType()),
+		TcbCompSvns:        make([]int, len(tcb.CompSvns)),
+		PceSvn:             tcb.PceSvn,
+		CPUSvn:             hex.EncodeToString(tcb.CPUSvn),
+		PlatformInstanceID: certObj.GetPlatformInstanceID(),
+	}
+	for i, svn := range tcb.CompSvns {
+		ext.TcbCompSvns[i] = int(svn)
+	}
+	if configuration := certObj.GetPlatformConfiguration(); configuration != nil {
+		ext.DynamicPlatform = configuration.DynamicPlatform
+		ext.CachedKeys = configuration.CachedKeys
+		ext.SMTEnabled = configuration.SMTEnabled
+	}
+	return ext
+}
+
+// checkPckPolicy rejects quotes from platforms whose FMSPC or SGX type is not allowed by the configuration
+func checkPckPolicy(ext *PckCertExtensions) error {
+	conf := config.Global()
+	if conf == nil {
+		return nil
+	}
+	policy := conf.PckPolicy
+	if len(policy.AllowedFmspcs) > 0 && !containsFold(policy.AllowedFmspcs, ext.FMSPC) {
+		slog.Errorf("resource/pck_extensions:checkPckPolicy() FMSPC %s of the PCK certificate is not allowed", ext.FMSPC)
+		return &resourceError{Message: "FMSPC of the PCK certificate is not allowed", StatusCode: http.StatusBadRequest}
+	}
+	if len(policy.AllowedSgxTypes) > 0 && !containsFold(policy.AllowedSgxTypes, ext.SgxType) {
+		slog.Errorf("resource/pck_extensions:checkPckPolicy() SGX type %s of the PCK certificate is not allowed",
+			ext.SgxType)
+		return &resourceError{Message: "SGX type of the PCK certificate is not allowed", StatusCode: http.StatusBadRequest}
+	}
+	return nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
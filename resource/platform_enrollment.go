@@ -0,0 +1,397 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	commLogMsg "intel/isecl/lib/common/v4/log/message"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/expiry"
+	"intel/isecl/sqvs/v4/logformat"
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"intel/isecl/sqvs/v4/resource/verifier"
+	"intel/isecl/sqvs/v4/types"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+var (
+	fmspcRegex = regexp.MustCompile(`^[0-9a-f]{12}$`)
+	pceIDRegex = regexp.MustCompile(`^[0-9a-f]{4}$`)
+)
+
+// PlatformEnrollmentRequest registers a platform whose quotes are expected to be verified
+type PlatformEnrollmentRequest struct {
+	Fmspc       string `json:"fmspc"`
+	PceID       string `json:"pceId"`
+	Description string `json:"description,omitempty"`
+}
+
+// PinnedCollateral describes the TCB info pinned for an enrolled platform
+type PinnedCollateral struct {
+	TcbInfoNextUpdate time.Time `json:"tcbInfoNextUpdate"`
+	PinnedTime        time.Time `json:"pinnedTime"`
+}
+
+// PlatformEnrollment is an enrolled platform along with the collateral pinned for it, if any
+type PlatformEnrollment struct {
+	Fmspc        string            `json:"fmspc"`
+	PceID        string            `json:"pceId"`
+	Description  string            `json:"description"`
+	EnrolledTime time.Time         `json:"enrolledTime"`
+	Collateral   *PinnedCollateral `json:"collateral,omitempty"`
+}
+
+var enrolledPlatformListSpec = listSpec{
+	FilterFields: []string{"fmspc", "pceId"},
+	SortFields:   []string{"fmspc", "pceId", "enrolledTime"},
+}
+
+func PlatformEnrollmentCB(router *mux.Router) {
+	router.Handle("/admin/platforms", listEnrolledPlatforms()).Methods("GET")
+	router.Handle("/admin/platforms", enrollPlatform()).Methods("POST")
+	router.Handle("/admin/platforms/{fmspc}/{pceId}", retrieveEnrolledPlatform()).Methods("GET")
+	router.Handle("/admin/platforms/{fmspc}/{pceId}", unenrollPlatform()).Methods("DELETE")
+}
+
+func listEnrolledPlatforms() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/platform_enrollment:listEnrolledPlatforms() Entering")
+		defer log.Trace("resource/platform_enrollment:listEnrolledPlatforms() Leaving")
+
+		repo, err := enrolledPlatformRepository(r)
+		if err != nil {
+			return err
+		}
+		criteria, err := parseListQuery(r, enrolledPlatformListSpec)
+		if err != nil {
+			return err
+		}
+		platforms, total, err := repo.Search(criteria)
+		if err != nil {
+			log.WithError(err).Error("resource/platform_enrollment:listEnrolledPlatforms() Error retrieving enrolled platforms")
+			return &resourceError{Message: "Error retrieving enrolled platforms", StatusCode: http.StatusInternalServerError}
+		}
+		enrollments := make([]PlatformEnrollment, 0, len(platforms))
+		for i := range platforms {
+			enrollments = append(enrollments, newPlatformEnrollment(&platforms[i]))
+		}
+		return writeListResponse(w, r, enrollments, criteria, total)
+	}
+}
+
+func enrollPlatform() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/platform_enrollment:enrollPlatform() Entering")
+		defer log.Trace("resource/platform_enrollment:enrollPlatform() Leaving")
+
+		repo, err := enrolledPlatformRepository(r)
+		if err != nil {
+			return err
+		}
+
+		var req PlatformEnrollmentRequest
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&req)
+		if err != nil {
+			slog.WithFields(adminEventFields(logformat.OutcomeFailure)).WithError(err).Errorf(
+				"resource/platform_enrollment:enrollPlatform() %s: Failed to decode request body",
+				commLogMsg.InvalidInputBadEncoding)
+			return &resourceError{Message: "Invalid JSON input provided", StatusCode: http.StatusBadRequest}
+		}
+		platform := types.EnrolledPlatform{
+			Fmspc:        strings.ToLower(req.Fmspc),
+			PceID:        strings.ToLower(req.PceID),
+			Description:  req.Description,
+			EnrolledTime: time.Now().UTC(),
+		}
+		if !fmspcRegex.MatchString(platform.Fmspc) || !pceIDRegex.MatchString(platform.PceID) {
+			slog.WithFields(adminEventFields(logformat.OutcomeFailure)).Errorf(
+				"resource/platform_enrollment:enrollPlatform() %s: Invalid FMSPC %q or PCE ID %q",
+				commLogMsg.InvalidInputBadParam, req.Fmspc, req.PceID)
+			return &resourceError{Message: "Invalid platform, fmspc must be 12 and pceId 4 hexadecimal characters",
+				StatusCode: http.StatusBadRequest}
+		}
+
+		// enrolling a platform again keeps its enrollment time and refreshes its collateral
+		existing, err := repo.Retrieve(platform.Fmspc, platform.PceID)
+		if err == nil {
+			platform.EnrolledTime = existing.EnrolledTime
+		} else if err != repository.ErrRecordNotFound {
+			log.WithError(err).Error("resource/platform_enrollment:enrollPlatform() Error retrieving enrolled platform")
+			return &resourceError{Message: "Error retrieving enrolled platform", StatusCode: http.StatusInternalServerError}
+		}
+
+		_, err = pinCollateral(r.Context(), &platform)
+		if err != nil {
+			// the platform is still enrolled, its collateral is pinned on the first verification of its quotes
+			log.WithError(err).Warnf("resource/platform_enrollment:enrollPlatform() Could not pin the collateral "+
+				"of platform with FMSPC %s and PCE ID %s", platform.Fmspc, platform.PceID)
+		}
+		err = repo.Save(&platform)
+		if err != nil {
+			log.WithError(err).Error("resource/platform_enrollment:enrollPlatform() Error saving enrolled platform")
+			return &resourceError{Message: "Error saving enrolled platform", StatusCode: http.StatusInternalServerError}
+		}
+		slog.WithFields(adminEventFields(logformat.OutcomeSuccess)).Infof(
+			"resource/platform_enrollment:enrollPlatform() Enrolled platform with FMSPC %s and PCE ID %s",
+			platform.Fmspc, platform.PceID)
+
+		status := http.StatusCreated
+		if existing != nil {
+			status = http.StatusOK
+		}
+		return writePlatformEnrollment(w, r, status, newPlatformEnrollment(&platform))
+	}
+}
+
+func retrieveEnrolledPlatform() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/platform_enrollment:retrieveEnrolledPlatform() Entering")
+		defer log.Trace("resource/platform_enrollment:retrieveEnrolledPlatform() Leaving")
+
+		repo, err := enrolledPlatformRepository(r)
+		if err != nil {
+			return err
+		}
+		vars := mux.Vars(r)
+		platform, err := repo.Retrieve(strings.ToLower(vars["fmspc"]), strings.ToLower(vars["pceId"]))
+		if err == repository.ErrRecordNotFound {
+			return &resourceError{Message: "Platform is not enrolled", StatusCode: http.StatusNotFound}
+		} else if err != nil {
+			log.WithError(err).Error("resource/platform_enrollment:retrieveEnrolledPlatform() Error retrieving enrolled platform")
+			return &resourceError{Message: "Error retrieving enrolled platform", StatusCode: http.StatusInternalServerError}
+		}
+		return writePlatformEnrollment(w, r, http.StatusOK, newPlatformEnrollment(platform))
+	}
+}
+
+func unenrollPlatform() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/platform_enrollment:unenrollPlatform() Entering")
+		defer log.Trace("resource/platform_enrollment:unenrollPlatform() Leaving")
+
+		repo, err := enrolledPlatformRepository(r)
+		if err != nil {
+			return err
+		}
+		vars := mux.Vars(r)
+		fmspc, pceID := strings.ToLower(vars["fmspc"]), strings.ToLower(vars["pceId"])
+		err = repo.Delete(fmspc, pceID)
+		if err == repository.ErrRecordNotFound {
+			return &resourceError{Message: "Platform is not enrolled", StatusCode: http.StatusNotFound}
+		} else if err != nil {
+			log.WithError(err).Error("resource/platform_enrollment:unenrollPlatform() Error deleting enrolled platform")
+			return &resourceError{Message: "Error deleting enrolled platform", StatusCode: http.StatusInternalServerError}
+		}
+		slog.WithFields(adminEventFields(logformat.OutcomeSuccess)).Infof(
+			"resource/platform_enrollment:unenrollPlatform() Unenrolled platform with FMSPC %s and PCE ID %s",
+			fmspc, pceID)
+		w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+}
+
+// enrolledPlatformRepository authorizes access to the enrolled platforms and returns their repository
+func enrolledPlatformRepository(r *http.Request) (repository.EnrolledPlatformRepository, error) {
+	err := authorizeAdministrator(r)
+	if err != nil {
+		return nil, err
+	}
+	if sqvsDB == nil {
+		return nil, &resourceError{Message: "Platform enrollment is not enabled", StatusCode: http.StatusNotFound}
+	}
+	return sqvsDB.EnrolledPlatformRepository(), nil
+}
+
+func newPlatformEnrollment(platform *types.EnrolledPlatform) PlatformEnrollment {
+	enrollment := PlatformEnrollment{
+		Fmspc:        platform.Fmspc,
+		PceID:        platform.PceID,
+		Description:  platform.Description,
+		EnrolledTime: platform.EnrolledTime,
+	}
+	if platform.TcbInfo != "" {
+		enrollment.Collateral = &PinnedCollateral{
+			TcbInfoNextUpdate: platform.TcbInfoNextUpdate,
+			PinnedTime:        platform.PinnedTime,
+		}
+	}
+	return enrollment
+}
+
+func writePlatformEnrollment(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return &resourceError{Message: "Error marshalling enrolled platforms in JSON",
+			StatusCode: http.StatusInternalServerError}
+	}
+	if notModified(w, r, entityTag(body)) {
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+	w.WriteHeader(status)
+	_, err = w.Write(body)
+	if err != nil {
+		return &resourceError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+	return nil
+}
+
+// pinCollateral fetches the current TCB info of the platform from SCS and pins it to the platform
+func pinCollateral(ctx context.Context, platform *types.EnrolledPlatform) (*parser.TcbInfoStruct, error) {
+	tcbObj, err := parser.NewTcbInfo(ctx, platform.Fmspc)
+	if err != nil {
+		return nil, errors.Wrap(err, "resource/platform_enrollment:pinCollateral() Error fetching TCB info")
+	}
+	_, err = verifier.VerifyCollateralSignature(tcbObj.RawBlob, verifier.CollateralTcbInfo,
+		tcbObj.TcbInfoData.Signature, tcbObj.GetTcbInfoInterCaList())
+	if err != nil {
+		return nil, errors.Wrap(err, "resource/platform_enrollment:pinCollateral() Invalid TCB info signature")
+	}
+	nextUpdate, err := time.Parse(time.RFC3339, tcbObj.GetTcbInfoNextUpdate())
+	if err != nil {
+		return nil, errors.Wrap(err, "resource/platform_enrollment:pinCollateral() Invalid TCB info next update")
+	}
+	platform.TcbInfo = string(tcbObj.RawBlob)
+	platform.TcbInfoIssuerChain = tcbObj.IssuerChain
+	platform.TcbInfoNextUpdate = nextUpdate.UTC()
+	platform.PinnedTime = time.Now().UTC()
+	return tcbObj, nil
+}
+
+// platformTcbInfo returns the TCB info to verify a quote of the platform with, the collateral pinned at its
+// enrollment while it is current. Quotes of platforms that are not enrolled are reported to the security log
+// and rejected when platform enrollment is required.
+func platformTcbInfo(ctx context.Context, fmspc, pceID string, now time.Time, diag *diagnostics) (*parser.TcbInfoStruct, error) {
+	conf := config.Global()
+	required := conf != nil && conf.RequirePlatformEnrollment
+	if sqvsDB == nil {
+		if required {
+			log.Error("resource/platform_enrollment:platformTcbInfo() Platform enrollment is required but no store is configured")
+			return nil, &resourceError{Message: "Platform enrollment is not available",
+				StatusCode: http.StatusInternalServerError}
+		}
+		return fetchTcbInfo(ctx, fmspc)
+	}
+
+	repo := sqvsDB.EnrolledPlatformRepository()
+	platform, err := repo.Retrieve(fmspc, pceID)
+	if err == repository.ErrRecordNotFound {
+		slog.Warnf("resource/platform_enrollment:platformTcbInfo() Quote received from platform with FMSPC %s "+
+			"and PCE ID %s that is not enrolled", fmspc, pceID)
+		if required {
+			return nil, &resourceError{Message: "Platform is not enrolled", StatusCode: http.StatusForbidden}
+		}
+		return fetchTcbInfo(ctx, fmspc)
+	} else if err != nil {
+		log.WithError(err).Error("resource/platform_enrollment:platformTcbInfo() Error retrieving enrolled platform")
+		if required {
+			return nil, &resourceError{Message: "Error retrieving enrolled platform",
+				StatusCode: http.StatusInternalServerError}
+		}
+		return fetchTcbInfo(ctx, fmspc)
+	}
+
+	if platform.TcbInfo != "" && now.Before(platform.TcbInfoNextUpdate) {
+		tcbObj, err := parser.ParseTcbInfo([]byte(platform.TcbInfo), platform.TcbInfoIssuerChain)
+		if err == nil {
+			diag.cache(cacheEnrolledCollateral, true)
+			return tcbObj, nil
+		}
+		log.WithError(err).Error("resource/platform_enrollment:platformTcbInfo() Error parsing pinned TCB info")
+	}
+
+	// the pinned collateral is missing or outdated, pin the current one
+	diag.cache(cacheEnrolledCollateral, false)
+	tcbObj, err := pinCollateral(ctx, platform)
+	if err != nil {
+		log.WithError(err).Error("Get TCB Info data parsing/fetch failed")
+		return nil, fetchFailure(err, "Get TCB Info data parsing/fetch failed", http.StatusInternalServerError)
+	}
+	err = repo.Save(platform)
+	if err != nil {
+		log.WithError(err).Error("resource/platform_enrollment:platformTcbInfo() Error saving pinned TCB info")
+	}
+	return tcbObj, nil
+}
+
+func fetchTcbInfo(ctx context.Context, fmspc string) (*parser.TcbInfoStruct, error) {
+	tcbObj, err := parser.NewTcbInfo(ctx, fmspc)
+	if err != nil {
+		log.WithError(err).Error("Get TCB Info data parsing/fetch failed")
+		return nil, fetchFailure(err, "Get TCB Info data parsing/fetch failed", http.StatusInternalServerError)
+	}
+	return tcbObj, nil
+}
+
+// RefreshPinnedCollateral pins the current collateral of the enrolled platforms whose pinned collateral is
+// missing or outdated, so their first verifications do not wait on SCS
+func RefreshPinnedCollateral() {
+	log.Trace("resource/platform_enrollment:RefreshPinnedCollateral() Entering")
+	defer log.Trace("resource/platform_enrollment:RefreshPinnedCollateral() Leaving")
+
+	if sqvsDB == nil {
+		return
+	}
+	repo := sqvsDB.EnrolledPlatformRepository()
+	platforms, err := repo.RetrieveAll()
+	if err != nil {
+		log.WithError(err).Error("resource/platform_enrollment:RefreshPinnedCollateral() Error retrieving enrolled platforms")
+		return
+	}
+	now := time.Now()
+	for i := range platforms {
+		platform := &platforms[i]
+		if platform.TcbInfo != "" && now.Before(platform.TcbInfoNextUpdate) {
+			continue
+		}
+		_, err = pinCollateral(context.Background(), platform)
+		if err != nil {
+			log.WithError(err).Warnf("resource/platform_enrollment:RefreshPinnedCollateral() Could not pin the "+
+				"collateral of platform with FMSPC %s and PCE ID %s", platform.Fmspc, platform.PceID)
+			continue
+		}
+		err = repo.Save(platform)
+		if err != nil {
+			log.WithError(err).Error("resource/platform_enrollment:RefreshPinnedCollateral() Error saving pinned TCB info")
+		}
+	}
+}
+
+// CollateralExpiry lists the next update of the imported PCK CRLs and of the TCB info pinned for the enrolled
+// platforms, the collateral verifications fail with once it is out of date
+func CollateralExpiry() ([]expiry.Item, error) {
+	var items []expiry.Item
+	for _, crl := range parser.ImportedPckCrls(constants.PckCrlDir) {
+		items = append(items, expiry.Item{Kind: expiry.KindCrl, Name: crl.CA, Expiry: crl.NextUpdate})
+	}
+	if sqvsDB == nil {
+		return items, nil
+	}
+	platforms, err := sqvsDB.EnrolledPlatformRepository().RetrieveAll()
+	if err != nil {
+		return items, errors.Wrap(err, "resource/platform_enrollment:CollateralExpiry() Error retrieving enrolled platforms")
+	}
+	for _, platform := range platforms {
+		if platform.TcbInfo == "" {
+			continue
+		}
+		items = append(items, expiry.Item{Kind: expiry.KindTcbInfo, Name: platform.Fmspc + "/" + platform.PceID,
+			Expiry: platform.TcbInfoNextUpdate})
+	}
+	return items, nil
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/repository/memory"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlatformEnrollment(t *testing.T) {
+	db, err := memory.New("")
+	assert.NoError(t, err)
+	SetRepository(db)
+	defer SetRepository(nil)
+	config.Global().IncludeToken = false
+
+	router := mux.NewRouter()
+	PlatformEnrollmentCB(router.PathPrefix("/svs/v1/").Subrouter())
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return recorder
+	}
+
+	recorder := serve("POST", "/svs/v1/admin/platforms", `{"fmspc":"00906EA10000","pceId":"0000","description":"rack 1"}`)
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	var enrollment PlatformEnrollment
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &enrollment))
+	assert.Equal(t, "00906ea10000", enrollment.Fmspc)
+	assert.Equal(t, "rack 1", enrollment.Description)
+	assert.Equal(t, http.StatusOK, serve("POST", "/svs/v1/admin/platforms", `{"fmspc":"00906ea10000","pceId":"0000"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve("POST", "/svs/v1/admin/platforms", `{"fmspc":"00906ea1","pceId":"0000"}`).Code)
+
+	recorder = serve("GET", "/svs/v1/admin/platforms", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var enrollments []PlatformEnrollment
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &enrollments))
+	assert.Len(t, enrollments, 1)
+	assert.Equal(t, "1", recorder.Header().Get("X-Total-Count"))
+	assert.Equal(t, http.StatusOK, serve("GET", "/svs/v1/admin/platforms?fmspc=00906ea10000&limit=1", "").Code)
+	recorder = serve("GET", "/svs/v1/admin/platforms?pceId=0001", "")
+	assert.Equal(t, "0", recorder.Header().Get("X-Total-Count"))
+	assert.Equal(t, http.StatusBadRequest, serve("GET", "/svs/v1/admin/platforms?description=rack", "").Code)
+	assert.Equal(t, http.StatusOK, serve("GET", "/svs/v1/admin/platforms/00906ea10000/0000", "").Code)
+
+	config.Global().RequirePlatformEnrollment = true
+	defer func() { config.Global().RequirePlatformEnrollment = false }()
+	_, err = platformTcbInfo(context.Background(), "00906ea10001", "0000", time.Now(), nil)
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusForbidden, err.(*resourceError).StatusCode)
+	}
+
+	assert.Equal(t, http.StatusNoContent, serve("DELETE", "/svs/v1/admin/platforms/00906ea10000/0000", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve("GET", "/svs/v1/admin/platforms/00906ea10000/0000", "").Code)
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"net/http"
+	"strconv"
+)
+
+const platformIdentifiersQueryParam = "platform_identifiers"
+
+// platformIdentifiersRequested tells whether the request asks for the PPID and platform instance ID of the
+// platform as they are with platform_identifiers=true. They map quotes to physical hosts, so only
+// administrators and holders of the PlatformIdentityReader role get them when tokens are required.
+func platformIdentifiersRequested(r *http.Request) (bool, error) {
+	requested, err := strconv.ParseBool(r.URL.Query().Get(platformIdentifiersQueryParam))
+	if err != nil || !requested {
+		return false, nil
+	}
+	if conf := config.Global(); conf != nil && conf.IncludeToken &&
+		AuthorizeEndpoint(r, constants.AdministratorGroupName, true) != nil {
+		err = AuthorizeEndpoint(r, constants.PlatformIdentityGroupName, true)
+		if err != nil {
+			slog.WithError(err).Error("resource/platform_identifiers:platformIdentifiersRequested() Platform " +
+				"identifiers requested without the PlatformIdentityReader role")
+			return false, err
+		}
+	}
+	slog.Infof("resource/platform_identifiers:platformIdentifiersRequested() Platform identifiers requested by %s",
+		r.RemoteAddr)
+	return true, nil
+}
+
+// platformIdentifiersProfile returns the redaction profile of the platform identifiers of the results
+func platformIdentifiersProfile() string {
+	conf := config.Global()
+	if conf == nil || conf.PlatformIdentifiers == "" {
+		return constants.DefaultPlatformIdentifiers
+	}
+	return conf.PlatformIdentifiers
+}
+
+// pseudonymizePlatformIdentifier returns the hex SHA-256 digest of a platform identifier, which tells
+// verifications of the same platform apart without revealing it
+func pseudonymizePlatformIdentifier(id string) string {
+	if id == "" {
+		return ""
+	}
+	digest := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(digest[:])
+}
+
+// exportedPlatformIdentifier returns the platform identifier as events and logs carry it, pseudonymized
+// unless the profile is full. Records keyed by a platform need a stable value, so the redacted profile
+// pseudonymizes them too.
+func exportedPlatformIdentifier(id string) string {
+	if platformIdentifiersProfile() == config.PlatformIdentifiersFull {
+		return id
+	}
+	return pseudonymizePlatformIdentifier(id)
+}
+
+// redactPlatformIdentifiers applies the redaction profile to the PPID and platform instance ID of the
+// response, unless the client asked for them, and reports the profile applied
+func redactPlatformIdentifiers(resp *SGXResponse, requested bool) {
+	profile := platformIdentifiersProfile()
+	if requested || profile == config.PlatformIdentifiersFull {
+		return
+	}
+	redact := func(id string) string {
+		if profile == config.PlatformIdentifiersRedacted {
+			return ""
+		}
+		return pseudonymizePlatformIdentifier(id)
+	}
+	if ext := resp.PckExtensions; ext != nil {
+		ext.PPID = redact(ext.PPID)
+		ext.PlatformInstanceID = redact(ext.PlatformInstanceID)
+	}
+	if data := resp.SupplementalData; data != nil {
+		data.PckPpid = redact(data.PckPpid)
+		data.PlatformInstanceID = redact(data.PlatformInstanceID)
+	}
+	resp.PlatformIdentifiers = profile
+}
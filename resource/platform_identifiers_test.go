@@ -0,0 +1,57 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"intel/isecl/sqvs/v4/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactPlatformIdentifiers(t *testing.T) {
+	conf := config.Global()
+	defer func() { conf.PlatformIdentifiers = "" }()
+	const ppid = "20afa3c8fecb47c0a2311e4cbc4b6dd8"
+	newResponse := func() *SGXResponse {
+		return &SGXResponse{AdditionalQuoteData: AdditionalQuoteData{
+			PckExtensions:    &PckCertExtensions{PPID: ppid, FMSPC: "00906ea10000"},
+			SupplementalData: &SupplementalData{PckPpid: ppid}}}
+	}
+
+	// redacted by default
+	resp := newResponse()
+	redactPlatformIdentifiers(resp, false)
+	assert.Empty(t, resp.PckExtensions.PPID)
+	assert.Empty(t, resp.SupplementalData.PckPpid)
+	assert.Equal(t, "00906ea10000", resp.PckExtensions.FMSPC)
+	assert.Equal(t, config.PlatformIdentifiersRedacted, resp.PlatformIdentifiers)
+
+	// returned as they are to the clients asking for them
+	resp = newResponse()
+	redactPlatformIdentifiers(resp, true)
+	assert.Equal(t, ppid, resp.PckExtensions.PPID)
+	assert.Empty(t, resp.PlatformIdentifiers)
+
+	conf.PlatformIdentifiers = config.PlatformIdentifiersPseudonymized
+	resp = newResponse()
+	redactPlatformIdentifiers(resp, false)
+	digest := sha256.Sum256([]byte(ppid))
+	assert.Equal(t, hex.EncodeToString(digest[:]), resp.PckExtensions.PPID)
+	assert.Equal(t, resp.PckExtensions.PPID, resp.SupplementalData.PckPpid)
+	assert.Empty(t, resp.PckExtensions.PlatformInstanceID)
+
+	// events and logs carry the pseudonym of the PPID with the redacted profile too
+	assert.Equal(t, hex.EncodeToString(digest[:]), exportedPlatformIdentifier(ppid))
+
+	conf.PlatformIdentifiers = config.PlatformIdentifiersFull
+	resp = newResponse()
+	redactPlatformIdentifiers(resp, false)
+	assert.Equal(t, ppid, resp.SupplementalData.PckPpid)
+	assert.Empty(t, resp.PlatformIdentifiers)
+	assert.Equal(t, ppid, exportedPlatformIdentifier(ppid))
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// PolicySignatureSuffix names the detached JWS signing a policy file, next to the policy file
+const PolicySignatureSuffix = ".jws"
+
+// ErrUnsignedPolicy is returned for a policy file without a signature when signed policies are required
+var ErrUnsignedPolicy = errors.New("policy is not signed")
+
+// PolicySignature attributes a policy to the policy author who signed it. Signature is the detached compact
+// JWS of the policy file whose kid is the author ID.
+type PolicySignature struct {
+	File      string `json:"file"`
+	Author    string `json:"author"`
+	Signature string `json:"signature"`
+}
+
+var customClaimsSignature *PolicySignature
+
+// SetCustomClaimsSignature sets the signature of the custom claims policy in use, nil for an unsigned policy
+func SetCustomClaimsSignature(s *PolicySignature) {
+	customClaimsSignature = s
+}
+
+// VerifyPolicySignature verifies the detached JWS of a policy file, <policy file>.jws, against the keys of
+// the authors allowed to sign policies, registered like the relying parties by a PEM encoded public key or
+// certificate named <author ID>.pem. It returns nil for a missing policy file and for a policy without a
+// signature unless signed policies are required, and an error for a policy with an invalid signature whether
+// or not they are.
+func VerifyPolicySignature(policyFile string, authors *RelyingParties, required bool) (*PolicySignature, error) {
+	policy, err := ioutil.ReadFile(policyFile)
+	if os.IsNotExist(err) {
+		// a missing policy file is an empty policy, there is nothing to sign
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "resource/policy_signature:VerifyPolicySignature() Error reading policy")
+	}
+	jws, err := ioutil.ReadFile(policyFile + PolicySignatureSuffix)
+	if os.IsNotExist(err) {
+		if required {
+			return nil, errors.Wrapf(ErrUnsignedPolicy, "resource/policy_signature:VerifyPolicySignature() %s",
+				policyFile)
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "resource/policy_signature:VerifyPolicySignature() Error reading policy signature")
+	}
+	signature := strings.TrimSpace(string(jws))
+	header, err := verifyDetachedJWS(signature, policy, authors)
+	if err != nil {
+		return nil, errors.Wrapf(err, "resource/policy_signature:VerifyPolicySignature() Invalid signature of %s "+
+			"by policy author %q", policyFile, header.Kid)
+	}
+	return &PolicySignature{File: policyFile, Author: header.Kid, Signature: signature}, nil
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyPolicySignature(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqvs-policy-authors")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	authorKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	writeRelyingPartyKey(t, dir, "alice", authorKey.Public())
+	authors, err := NewRelyingParties(dir)
+	assert.NoError(t, err)
+
+	policyFile := filepath.Join(dir, "custom-claims.yml")
+	signature, err := VerifyPolicySignature(policyFile, authors, true)
+	assert.NoError(t, err)
+	assert.Nil(t, signature)
+
+	policy := "claims:\n- name: tenant\n  value: acme\n"
+	assert.NoError(t, ioutil.WriteFile(policyFile, []byte(policy), 0600))
+	signature, err = VerifyPolicySignature(policyFile, authors, false)
+	assert.NoError(t, err)
+	assert.Nil(t, signature)
+	_, err = VerifyPolicySignature(policyFile, authors, true)
+	assert.Equal(t, ErrUnsignedPolicy, errors.Cause(err))
+
+	jws := signRequest(t, "ES256", "alice", authorKey, policy)
+	assert.NoError(t, ioutil.WriteFile(policyFile+PolicySignatureSuffix, []byte(jws+"\n"), 0600))
+	signature, err = VerifyPolicySignature(policyFile, authors, true)
+	assert.NoError(t, err)
+	assert.Equal(t, &PolicySignature{File: policyFile, Author: "alice", Signature: jws}, signature)
+
+	// a signature by an unregistered author or of another policy is rejected even when not required
+	jws = signRequest(t, "ES256", "mallory", otherKey, policy)
+	assert.NoError(t, ioutil.WriteFile(policyFile+PolicySignatureSuffix, []byte(jws), 0600))
+	_, err = VerifyPolicySignature(policyFile, authors, false)
+	assert.Error(t, err)
+	jws = signRequest(t, "ES256", "alice", authorKey, policy+"- name: role\n  value: admin\n")
+	assert.NoError(t, ioutil.WriteFile(policyFile+PolicySignatureSuffix, []byte(jws), 0600))
+	_, err = VerifyPolicySignature(policyFile, authors, false)
+	assert.Error(t, err)
+}
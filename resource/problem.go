@@ -0,0 +1,220 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"bytes"
+	"encoding/json"
+	"intel/isecl/sqvs/v4/logformat"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ProblemContentType is the media type of the RFC 7807 problem details of the error responses
+	ProblemContentType = "application/problem+json"
+
+	problemTypePrefix = "urn:sqvs:problem:"
+	// maxProblemDetail bounds the plain text error bodies turned into problem details
+	maxProblemDetail = 4096
+)
+
+// Categories of the problems, the type of a problem is the URN of its category
+const (
+	ProblemInvalidRequest        = "invalid-request"
+	ProblemUnauthorized          = "unauthorized"
+	ProblemForbidden             = "forbidden"
+	ProblemNotFound              = "not-found"
+	ProblemMethodNotAllowed      = "method-not-allowed"
+	ProblemConflict              = "conflict"
+	ProblemVerificationFailed    = "verification-failed"
+	ProblemQuotaExceeded         = "quota-exceeded"
+	ProblemOverloaded            = "overloaded"
+	ProblemMaintenance           = "maintenance"
+	ProblemServiceUnavailable    = "service-unavailable"
+	ProblemDependencyUnavailable = "dependency-unavailable"
+	ProblemDependencyTimeout     = "dependency-timeout"
+	ProblemInternalError         = "internal-error"
+)
+
+var problemTitles = map[string]string{
+	ProblemInvalidRequest:        "Invalid request",
+	ProblemUnauthorized:          "Unauthorized",
+	ProblemForbidden:             "Forbidden",
+	ProblemNotFound:              "Not found",
+	ProblemMethodNotAllowed:      "Method not allowed",
+	ProblemConflict:              "Conflict",
+	ProblemVerificationFailed:    "Quote verification failed",
+	ProblemQuotaExceeded:         "Quota exceeded",
+	ProblemOverloaded:            "Service overloaded",
+	ProblemMaintenance:           "Service under maintenance",
+	ProblemServiceUnavailable:    "Service unavailable",
+	ProblemDependencyUnavailable: "Dependency unavailable",
+	ProblemDependencyTimeout:     "Dependency timed out",
+	ProblemInternalError:         "Internal error",
+}
+
+// Problem is the RFC 7807 problem details body of the error responses. The instance identifies the error in
+// the security log and, for failed verifications, is the ID of the recorded verification. Retryable and
+// RetryAfter are extension members hinting whether and when the request can be retried.
+type Problem struct {
+	Type       string `json:"type"`
+	Title      string `json:"title"`
+	Status     int    `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+	Instance   string `json:"instance,omitempty"`
+	Retryable  bool   `json:"retryable,omitempty"`
+	RetryAfter int    `json:"retryAfter,omitempty"`
+}
+
+// problemDetails is implemented by the problems with extension members, which embed Problem
+type problemDetails interface {
+	details() *Problem
+}
+
+func (p *Problem) details() *Problem {
+	return p
+}
+
+// ProblemType returns the type URI of the problems of a category
+func ProblemType(category string) string {
+	return problemTypePrefix + category
+}
+
+// newProblem returns the problem of the category the status code falls in
+func newProblem(statusCode int, detail string) *Problem {
+	return newCategoryProblem(problemCategory(statusCode), statusCode, detail)
+}
+
+func newCategoryProblem(category string, statusCode int, detail string) *Problem {
+	p := &Problem{
+		Type:   ProblemType(category),
+		Title:  problemTitles[category],
+		Status: statusCode,
+		Detail: detail,
+	}
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		p.Retryable = true
+	}
+	return p
+}
+
+func problemCategory(statusCode int) string {
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return ProblemUnauthorized
+	case http.StatusForbidden:
+		return ProblemForbidden
+	case http.StatusNotFound:
+		return ProblemNotFound
+	case http.StatusMethodNotAllowed:
+		return ProblemMethodNotAllowed
+	case http.StatusConflict:
+		return ProblemConflict
+	case http.StatusTooManyRequests:
+		return ProblemQuotaExceeded
+	case http.StatusBadGateway:
+		return ProblemDependencyUnavailable
+	case http.StatusServiceUnavailable:
+		return ProblemServiceUnavailable
+	case http.StatusGatewayTimeout:
+		return ProblemDependencyTimeout
+	}
+	if statusCode >= http.StatusInternalServerError {
+		return ProblemInternalError
+	}
+	return ProblemInvalidRequest
+}
+
+// problemInstance returns the instance URI of the error identified by id
+func problemInstance(id string) string {
+	return "urn:uuid:" + id
+}
+
+// writeProblem writes the problem details of an error response and records it in the security log under its
+// instance, a new instance being assigned to the problems not tied to a recorded verification
+func writeProblem(w http.ResponseWriter, r *http.Request, problem problemDetails) {
+	p := problem.details()
+	if p.Instance == "" {
+		p.Instance = problemInstance(newRecordID())
+	}
+	slog.WithFields(logrus.Fields{
+		logformat.OutcomeField: logformat.OutcomeFailure,
+		"instance":             p.Instance,
+		"type":                 p.Type,
+		"status":               p.Status,
+		"request":              r.Method + " " + r.URL.Path,
+		"source":               r.RemoteAddr,
+	}).Infof("resource/problem:writeProblem() Error response %d: %s", p.Status, p.Detail)
+
+	body, err := json.Marshal(problem)
+	if err != nil {
+		http.Error(w, p.Detail, p.Status)
+		return
+	}
+	if p.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(p.RetryAfter))
+	}
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	_, err = w.Write(body)
+	if err != nil {
+		log.WithError(err).Error("resource/problem:writeProblem() Error writing response")
+	}
+}
+
+// ProblemMiddleware turns the plain error responses written by the handlers it wraps, such as those of the
+// token authentication and of unknown routes, into problem details
+func ProblemMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pw := &problemWriter{ResponseWriter: w}
+		next.ServeHTTP(pw, r)
+		if pw.statusCode != 0 {
+			writeProblem(w, r, newProblem(pw.statusCode, strings.TrimSpace(pw.detail.String())))
+		}
+	})
+}
+
+// problemWriter holds back the error responses that are not problem details, keeping their body as the detail
+type problemWriter struct {
+	http.ResponseWriter
+	statusCode int
+	detail     bytes.Buffer
+}
+
+func (pw *problemWriter) WriteHeader(statusCode int) {
+	if statusCode >= http.StatusBadRequest && pw.Header().Get("Content-Type") != ProblemContentType {
+		pw.statusCode = statusCode
+		return
+	}
+	pw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (pw *problemWriter) Write(b []byte) (int, error) {
+	if pw.statusCode == 0 {
+		return pw.ResponseWriter.Write(b)
+	}
+	if room := maxProblemDetail - pw.detail.Len(); room > 0 {
+		if len(b) > room {
+			pw.detail.Write(b[:room])
+		} else {
+			pw.detail.Write(b)
+		}
+	}
+	return len(b), nil
+}
+
+// Flush lets streamed responses through
+func (pw *problemWriter) Flush() {
+	if flusher, ok := pw.ResponseWriter.(http.Flusher); ok && pw.statusCode == 0 {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProblemMiddleware(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/svs/v1/version", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("v4"))
+	}).Methods("GET")
+	router.HandleFunc("/svs/v1/token", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+	})
+	handler := ProblemMiddleware(router)
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+		return recorder
+	}
+
+	recorder := serve("GET", "/svs/v1/version")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "v4", recorder.Body.String())
+
+	// plain text errors become problem details, the text being their detail
+	recorder = serve("GET", "/svs/v1/token")
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.Equal(t, ProblemContentType, recorder.Header().Get("Content-Type"))
+	var problem Problem
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+	assert.Equal(t, ProblemType(ProblemUnauthorized), problem.Type)
+	assert.Equal(t, "Invalid token", problem.Detail)
+	assert.True(t, strings.HasPrefix(problem.Instance, "urn:uuid:"))
+
+	recorder = serve("GET", "/svs/v1/unknown")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+	assert.Equal(t, ProblemType(ProblemNotFound), problem.Type)
+
+	recorder = serve("DELETE", "/svs/v1/version")
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+	assert.Equal(t, ProblemType(ProblemMethodNotAllowed), problem.Type)
+	assert.False(t, problem.Retryable)
+}
+
+func TestProblemInstanceOfFailedVerification(t *testing.T) {
+	var recorded string
+	handler := errorHandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		err := &resourceError{Message: "Cannot verify pck cert", StatusCode: http.StatusBadRequest}
+		recorded = recordLinkedVerification(verificationCaller{}, "", "", SGXResponse{}, err).ID
+		return err
+	})
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/svs/v1/sgx_qv_verify_quote", nil))
+
+	var problem Problem
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+	assert.Equal(t, http.StatusBadRequest, problem.Status)
+	assert.Equal(t, "urn:uuid:"+recorded, problem.Instance)
+}
@@ -0,0 +1,290 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	commLogMsg "intel/isecl/lib/common/v4/log/message"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/quota"
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/types"
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultTenant is the tenant of the requests without a tenant claim
+	DefaultTenant = "default"
+
+	quotaWarningHeader = "X-Quota-Warning"
+)
+
+var (
+	dailyPeriodRegex   = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	monthlyPeriodRegex = regexp.MustCompile(`^\d{4}-\d{2}$`)
+)
+
+// QuotaTracker counts the verification requests of every tenant per route, day and month, and warns about
+// or rejects the requests beyond the configured limits
+type QuotaTracker struct {
+	repo        repository.UsageRepository
+	tenantClaim string
+	reject      bool
+	limits      []quota.Limit
+}
+
+// QuotaError is the problem details of the requests rejected because a quota is exceeded, retryable once
+// the period of the quota is over
+type QuotaError struct {
+	Problem
+	Tenant string `json:"tenant"`
+	Route  string `json:"route"`
+	Period string `json:"period"`
+	Limit  int64  `json:"limit"`
+}
+
+// QuotaUsage is the usage of a tenant with the limit it is enforced against, if any
+type QuotaUsage struct {
+	types.Usage
+	Limit *int64 `json:"limit,omitempty"`
+}
+
+// UsageResponse is the usage of the tenants over a day or a month
+type UsageResponse struct {
+	Period      string       `json:"period"`
+	Enforcement string       `json:"enforcement"`
+	Usages      []QuotaUsage `json:"usages"`
+}
+
+var quotas *QuotaTracker
+
+// SetQuotaTracker sets the tracker counting the requests of the tenants, quotas are not tracked when nil
+func SetQuotaTracker(t *QuotaTracker) {
+	quotas = t
+}
+
+// NewQuotaTracker returns a tracker recording the usage of the tenants in repo
+func NewQuotaTracker(conf config.QuotaConfig, repo repository.UsageRepository) (*QuotaTracker, error) {
+	limits, err := quota.ParseLimits(conf.Limits)
+	if err != nil {
+		return nil, errors.Wrap(err, "resource/quota:NewQuotaTracker() Error parsing quota limits")
+	}
+	tenantClaim := conf.TenantClaim
+	if tenantClaim == "" {
+		tenantClaim = constants.DefaultQuotaTenantClaim
+	}
+	return &QuotaTracker{
+		repo:        repo,
+		tenantClaim: tenantClaim,
+		reject:      conf.Enforcement == quota.EnforcementReject,
+		limits:      limits,
+	}, nil
+}
+
+// QuotaMiddleware counts the verification requests of the tenants against their quotas. It must run after
+// the token has been verified.
+func QuotaMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t := quotas
+		if t == nil || r.Method != http.MethodPost || strings.Contains(r.URL.Path, "/admin/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if qerr := t.track(w, r); qerr != nil {
+			writeProblem(w, r, qerr)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// track counts the request and checks the limits of its tenant, it returns the exceeded quota when the
+// request is rejected
+func (t *QuotaTracker) track(w http.ResponseWriter, r *http.Request) *QuotaError {
+	tenant := t.tenant(r)
+	route := quotaRoute(r.URL.Path)
+	now := time.Now()
+	periods := []string{quota.PeriodKey(quota.PeriodDaily, now), quota.PeriodKey(quota.PeriodMonthly, now)}
+
+	routeCounts, err := t.repo.Add(tenant, route, periods, 1)
+	if err != nil {
+		log.WithError(err).Error("resource/quota:track() Error recording request usage")
+		return nil
+	}
+	allCounts, err := t.repo.Add(tenant, quota.AllRoutes, periods, 1)
+	if err != nil {
+		log.WithError(err).Error("resource/quota:track() Error recording request usage")
+		return nil
+	}
+
+	for _, limit := range t.limits {
+		counts := allCounts
+		if limit.Route != quota.AllRoutes {
+			if limit.Route != route {
+				continue
+			}
+			counts = routeCounts
+		}
+		count := counts[1]
+		if limit.Period == quota.PeriodDaily {
+			count = counts[0]
+		}
+		if count <= limit.Max {
+			continue
+		}
+
+		message := fmt.Sprintf("Tenant %s exceeded the %s quota of %d requests to %s", tenant, limit.Period,
+			limit.Max, limit.Route)
+		if !t.reject {
+			log.Warnf("resource/quota:track() %s", message)
+			w.Header().Add(quotaWarningHeader, message)
+			continue
+		}
+
+		slog.Warnf("resource/quota:track() %s, rejecting request", message)
+		for _, counted := range []string{route, quota.AllRoutes} {
+			_, err = t.repo.Add(tenant, counted, periods, -1)
+			if err != nil {
+				log.WithError(err).Error("resource/quota:track() Error reverting request usage")
+			}
+		}
+		problem := newProblem(http.StatusTooManyRequests, message)
+		problem.RetryAfter = int(math.Ceil(quota.PeriodEnd(limit.Period, now).Sub(now).Seconds()))
+		return &QuotaError{
+			Problem: *problem,
+			Tenant:  tenant,
+			Route:   limit.Route,
+			Period:  limit.Period,
+			Limit:   limit.Max,
+		}
+	}
+	return nil
+}
+
+// tenant returns the tenant claim of the bearer token of the request, the token signature has already been
+// verified by the authentication middleware
+func (t *QuotaTracker) tenant(r *http.Request) string {
+	tenant, ok := tokenClaim(r, t.tenantClaim).(string)
+	if !ok || tenant == "" {
+		return DefaultTenant
+	}
+	return tenant
+}
+
+// tokenClaim returns the claim of the bearer token of the request, nil when there is no such claim
+func tokenClaim(r *http.Request, claim string) interface{} {
+	token := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil
+	}
+	var claims map[string]interface{}
+	if json.Unmarshal(payload, &claims) != nil {
+		return nil
+	}
+	return claims[claim]
+}
+
+// quotaRoute returns the route the usage of a request is counted for, its path without the service prefix
+func quotaRoute(path string) string {
+	return strings.TrimPrefix(path, "/svs")
+}
+
+func UsageCB(router *mux.Router) {
+	router.Handle("/usage", getUsage()).Methods("GET")
+}
+
+func getUsage() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/quota:getUsage() Entering")
+		defer log.Trace("resource/quota:getUsage() Leaving")
+
+		conf := config.Global()
+		if conf == nil {
+			return &resourceError{Message: "Could not read config", StatusCode: http.StatusInternalServerError}
+		}
+		t := quotas
+		tenant := r.URL.Query().Get("tenant")
+		if conf.IncludeToken && AuthorizeEndpoint(r, constants.AdministratorGroupName, true) != nil {
+			// quote verifiers only see the usage of their own tenant
+			err := AuthorizeEndpoint(r, constants.QuoteVerifierGroupName, true)
+			if err != nil {
+				return err
+			}
+			if t != nil {
+				tenant = t.tenant(r)
+			}
+		}
+		if !conf.Quota.Enabled || t == nil {
+			return &resourceError{Message: "Quotas are not enabled", StatusCode: http.StatusNotFound}
+		}
+
+		period := r.URL.Query().Get("period")
+		if period == "" {
+			period = quota.PeriodKey(quota.PeriodMonthly, time.Now())
+		}
+		limitPeriod := quota.PeriodMonthly
+		if dailyPeriodRegex.MatchString(period) {
+			limitPeriod = quota.PeriodDaily
+		} else if !monthlyPeriodRegex.MatchString(period) {
+			slog.Errorf("resource/quota:getUsage() %s: Invalid period %q", commLogMsg.InvalidInputBadParam, period)
+			return &resourceError{Message: "Invalid period, must be YYYY-MM-DD or YYYY-MM",
+				StatusCode: http.StatusBadRequest}
+		}
+
+		usages, err := t.repo.Search(tenant, period)
+		if err != nil {
+			log.WithError(err).Error("resource/quota:getUsage() Error searching usage")
+			return &resourceError{Message: "Error searching usage", StatusCode: http.StatusInternalServerError}
+		}
+		resp := UsageResponse{
+			Period:      period,
+			Enforcement: quota.EnforcementWarn,
+			Usages:      make([]QuotaUsage, 0, len(usages)),
+		}
+		if t.reject {
+			resp.Enforcement = quota.EnforcementReject
+		}
+		for _, usage := range usages {
+			quotaUsage := QuotaUsage{Usage: usage}
+			for i := range t.limits {
+				if t.limits[i].Route == usage.Route && t.limits[i].Period == limitPeriod {
+					quotaUsage.Limit = &t.limits[i].Max
+				}
+			}
+			resp.Usages = append(resp.Usages, quotaUsage)
+		}
+
+		body, err := json.Marshal(resp)
+		if err != nil {
+			return &resourceError{Message: "Error marshalling usage in JSON", StatusCode: http.StatusInternalServerError}
+		}
+		if notModified(w, r, entityTag(body)) {
+			return nil
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(body)
+		if err != nil {
+			return &resourceError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+		return nil
+	}
+}
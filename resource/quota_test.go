@@ -0,0 +1,75 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/quota"
+	"intel/isecl/sqvs/v4/repository/memory"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotaMiddleware(t *testing.T) {
+	db, err := memory.New("")
+	assert.NoError(t, err)
+	tracker, err := NewQuotaTracker(config.QuotaConfig{
+		Enabled:     true,
+		Enforcement: quota.EnforcementReject,
+		Limits:      []string{"/v1/sgx_qv_verify_quote:daily=2"},
+	}, db.UsageRepository())
+	assert.NoError(t, err)
+	SetQuotaTracker(tracker)
+	defer SetQuotaTracker(nil)
+
+	handler := QuotaMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	token := "e30." + base64.RawURLEncoding.EncodeToString([]byte(`{"tenant":"acme"}`)) + ".sig"
+	serve := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+	assert.Equal(t, http.StatusOK, serve("/svs/v1/sgx_qv_verify_quote").Code)
+	assert.Equal(t, http.StatusOK, serve("/svs/v1/sgx_qv_verify_quote").Code)
+	recorder := serve("/svs/v1/sgx_qv_verify_quote")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	var body QuotaError
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Equal(t, "acme", body.Tenant)
+	assert.Equal(t, quota.PeriodDaily, body.Period)
+	assert.Equal(t, http.StatusOK, serve("/svs/v1/sgx_qv_verify_report").Code)
+
+	config.Global().IncludeToken = false
+	config.Global().Quota.Enabled = true
+	defer func() { config.Global().Quota.Enabled = false }()
+	router := mux.NewRouter()
+	UsageCB(router.PathPrefix("/svs/v1/").Subrouter())
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/svs/v1/usage?tenant=acme&period="+
+		quota.PeriodKey(quota.PeriodDaily, time.Now()), nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var usage UsageResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &usage))
+	assert.Equal(t, quota.EnforcementReject, usage.Enforcement)
+	counts := map[string]int64{}
+	for _, u := range usage.Usages {
+		counts[u.Route] = u.Count
+		if u.Route == "/v1/sgx_qv_verify_quote" {
+			assert.Equal(t, int64(2), *u.Limit)
+		}
+	}
+	assert.Equal(t, map[string]int64{"*": 3, "/v1/sgx_qv_verify_quote": 2, "/v1/sgx_qv_verify_report": 1}, counts)
+}
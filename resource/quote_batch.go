@@ -0,0 +1,242 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	commLogMsg "intel/isecl/lib/common/v4/log/message"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/cpu"
+)
+
+// QuoteBatchRequest holds the quotes verified by a single batch request
+type QuoteBatchRequest struct {
+	Quotes []QuoteData `json:"quotes"`
+}
+
+// QuoteBatchResult is the outcome of the verification of the quote at Index of the batch request, either
+// the verification response or the error the quote was rejected with
+type QuoteBatchResult struct {
+	Index  int              `json:"index"`
+	Result *SGXResponse     `json:"result,omitempty"`
+	Error  *QuoteBatchError `json:"error,omitempty"`
+}
+
+// QuoteBatchError is the error a quote of a batch was rejected with, StatusCode is the status code the
+// single quote verification endpoint would have returned
+type QuoteBatchError struct {
+	Message    string            `json:"message"`
+	StatusCode int               `json:"status"`
+	FailedStep string            `json:"FailedStep,omitempty"`
+	Steps      VerificationSteps `json:"VerificationSteps,omitempty"`
+	QvResult   *QvResult         `json:"QvResult,omitempty"`
+
+	Diagnostics *VerificationDiagnostics `json:"Diagnostics,omitempty"`
+}
+
+// QuoteBatchResponse holds the results of a batch request in the order of its quotes
+type QuoteBatchResponse struct {
+	Results []QuoteBatchResult `json:"results"`
+}
+
+func sgxVerifyQuoteBatch() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/quote_batch:sgxVerifyQuoteBatch() Entering")
+		defer log.Trace("resource/quote_batch:sgxVerifyQuoteBatch() Leaving")
+
+		conf := config.Global()
+		if conf == nil {
+			return &resourceError{Message: "Could not read config", StatusCode: http.StatusInternalServerError}
+		}
+		if conf.IncludeToken {
+			err := AuthorizeEndpoint(r, constants.QuoteVerifierGroupName, true)
+			if err != nil {
+				slog.WithError(err).Error("resource/quote_batch: sgxVerifyQuoteBatch() Authorization Error")
+				return err
+			}
+		}
+
+		var batch QuoteBatchRequest
+		r.Body = http.MaxBytesReader(w, r.Body, constants.MaxBatchQuotes*constants.MaxQuoteUploadSize)
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		err := dec.Decode(&batch)
+		if err != nil {
+			slog.WithError(err).Errorf("resource/quote_batch: sgxVerifyQuoteBatch() %s: Failed to decode "+
+				"request body", commLogMsg.InvalidInputBadEncoding)
+			return &resourceError{Message: "Invalid JSON input provided", StatusCode: http.StatusBadRequest}
+		}
+		if len(batch.Quotes) == 0 || len(batch.Quotes) > constants.MaxBatchQuotes {
+			slog.Errorf("resource/quote_batch: sgxVerifyQuoteBatch() %s: Batch of %d quotes provided",
+				commLogMsg.InvalidInputBadParam, len(batch.Quotes))
+			return &resourceError{Message: "A batch must hold between 1 and " +
+				strconv.Itoa(constants.MaxBatchQuotes) + " quotes", StatusCode: http.StatusBadRequest}
+		}
+
+		observeQuoteBatch(len(batch.Quotes))
+
+		var options QuoteDataWithChallenge
+		options.Debug, err = debugRequested(r)
+		if err != nil {
+			return err
+		}
+		options.PlatformIdentifiers, err = platformIdentifiersRequested(r)
+		if err != nil {
+			return err
+		}
+		if strings.Contains(r.Header.Get("Accept"), contentTypeNDJSON) {
+			streamQuoteBatch(r.Context(), w, callerOf(r), batch.Quotes, batchWorkers(conf), options)
+			return nil
+		}
+		body, err := json.Marshal(QuoteBatchResponse{Results: verifyQuoteBatch(r.Context(), callerOf(r), batch.Quotes,
+			batchWorkers(conf), options)})
+		if err != nil {
+			log.WithError(err).Error("Error marshalling batch response in JSON")
+			return &resourceError{Message: "Error marshalling batch response in JSON",
+				StatusCode: http.StatusInternalServerError}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(body)
+		if err != nil {
+			return &resourceError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+		return nil
+	}
+}
+
+// verifyQuoteBatch verifies the quotes of caller across workers goroutines, sharing the PCK certificate chains of
+// quotes from the same platform. Every quote is verified with the Debug and PlatformIdentifiers options of
+// options, every result carrying its diagnostics when options.Debug is set.
+func verifyQuoteBatch(ctx context.Context, caller verificationCaller, quotes []QuoteData, workers int,
+	options QuoteDataWithChallenge) []QuoteBatchResult {
+	results := make([]QuoteBatchResult, len(quotes))
+	eachQuoteBatchResult(ctx, caller, quotes, workers, options, func(result QuoteBatchResult) {
+		results[result.Index] = result
+	})
+	return results
+}
+
+// streamQuoteBatch writes the results of the batch as NDJSON, one result per line in the order the quotes
+// complete, so neither end holds all the results of a large batch
+func streamQuoteBatch(ctx context.Context, w http.ResponseWriter, caller verificationCaller, quotes []QuoteData, workers int,
+	options QuoteDataWithChallenge) {
+	w.Header().Set("Content-Type", contentTypeNDJSON)
+	w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	eachQuoteBatchResult(ctx, caller, quotes, workers, options, func(result QuoteBatchResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		err := enc.Encode(result)
+		if err != nil {
+			log.WithError(err).Errorf("resource/quote_batch:streamQuoteBatch() Error writing result %d", result.Index)
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	})
+}
+
+// eachQuoteBatchResult verifies the quotes and calls emit with the result of every quote as it completes,
+// emit is called from the workers goroutines. The quotes left once ctx is canceled are not verified.
+func eachQuoteBatchResult(ctx context.Context, caller verificationCaller, quotes []QuoteData, workers int,
+	options QuoteDataWithChallenge, emit func(QuoteBatchResult)) {
+	chains := newPCKChainCache()
+	runParallel(len(quotes), workers, func(i int) {
+		if ctx.Err() != nil {
+			rerr := clientClosedRequest()
+			emit(QuoteBatchResult{Index: i, Error: &QuoteBatchError{Message: rerr.Message,
+				StatusCode: rerr.StatusCode}})
+			return
+		}
+		data := options
+		data.QuoteData = quotes[i]
+		resp, err := verifyQuoteIsolated(ctx, data, chains)
+		recordVerification(caller, quotes[i].QuoteBlob, resp, err)
+		result := QuoteBatchResult{Index: i}
+		if err != nil {
+			result.Error = &QuoteBatchError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+			if rerr, ok := err.(*resourceError); ok {
+				result.Error = &QuoteBatchError{Message: rerr.Message, StatusCode: rerr.StatusCode,
+					FailedStep: rerr.Steps.FailedStep(), Steps: rerr.Steps, QvResult: rerr.QvResult,
+					Diagnostics: rerr.Diagnostics}
+			}
+		} else {
+			result.Result = &resp
+		}
+		emit(result)
+	})
+}
+
+// runParallel calls fn for every index below n from at most workers goroutines
+func runParallel(n, workers int, fn func(i int)) {
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// batchWorkers returns the number of quotes of a batch verified in parallel. Unless configured, quotes are
+// verified on every CPU when the CPU accelerates P-256 ECDSA, and sequentially otherwise so the slow
+// generic verification of a large batch does not starve the other requests.
+func batchWorkers(conf *config.Configuration) int {
+	if conf.BatchWorkers > 0 {
+		return conf.BatchWorkers
+	}
+	if acceleratedECDSA() {
+		return runtime.GOMAXPROCS(0)
+	}
+	return 1
+}
+
+// acceleratedECDSA reports whether the CPU has the instructions the assembly P-256 implementation of the
+// Go crypto library relies on
+func acceleratedECDSA() bool {
+	switch runtime.GOARCH {
+	case "amd64":
+		return cpu.X86.HasAVX2 && cpu.X86.HasBMI2 && cpu.X86.HasADX
+	case "arm64", "ppc64le":
+		return true
+	case "s390x":
+		return cpu.S390X.HasVX
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/resource/verifier"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunParallel(t *testing.T) {
+	for _, workers := range []int{0, 1, 4, 64} {
+		var calls int32
+		seen := make([]int32, 10)
+		runParallel(len(seen), workers, func(i int) {
+			atomic.AddInt32(&calls, 1)
+			atomic.AddInt32(&seen[i], 1)
+		})
+		assert.Equal(t, int32(len(seen)), calls)
+		for i := range seen {
+			assert.Equal(t, int32(1), seen[i])
+		}
+	}
+}
+
+func TestSgxVerifyQuoteBatchSize(t *testing.T) {
+	config.Global().IncludeToken = false
+	router := setupRouter()
+
+	for _, n := range []int{0, constants.MaxBatchQuotes + 1} {
+		body, err := json.Marshal(QuoteBatchRequest{Quotes: make([]QuoteData, n)})
+		assert.NoError(t, err)
+		req := httptest.NewRequest("POST", "/svs/v1/sgx_qv_verify_quotes", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	}
+}
+
+func TestSgxVerifyQuoteBatchStream(t *testing.T) {
+	config.Global().IncludeToken = false
+	router := setupRouter()
+
+	body, err := json.Marshal(QuoteBatchRequest{Quotes: []QuoteData{{QuoteBlob: "bm90IGEgcXVvdGU="},
+		{QuoteBlob: "%%%"}, {QuoteBlob: "bm90IGEgcXVvdGU="}}})
+	assert.NoError(t, err)
+	req := httptest.NewRequest("POST", "/svs/v1/sgx_qv_verify_quotes", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/x-ndjson")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/x-ndjson", recorder.Header().Get("Content-Type"))
+
+	seen := map[int]bool{}
+	dec := json.NewDecoder(recorder.Body)
+	for dec.More() {
+		var result QuoteBatchResult
+		assert.NoError(t, dec.Decode(&result))
+		assert.NotNil(t, result.Error)
+		seen[result.Index] = true
+	}
+	assert.Equal(t, map[int]bool{0: true, 1: true, 2: true}, seen)
+}
+
+type signedReport struct {
+	report    []byte
+	signature []byte
+	publicKey []byte
+	key       *ecdsa.PublicKey
+}
+
+func newSignedReports(b *testing.B, n int) []signedReport {
+	reports := make([]signedReport, n)
+	for i := range reports {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			b.Fatal(err)
+		}
+		report := make([]byte, 384)
+		_, err = rand.Read(report)
+		if err != nil {
+			b.Fatal(err)
+		}
+		digest := sha256.Sum256(report)
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			b.Fatal(err)
+		}
+		reports[i] = signedReport{
+			report:    report,
+			signature: append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...),
+			publicKey: append(key.X.FillBytes(make([]byte, 32)), key.Y.FillBytes(make([]byte, 32))...),
+			key:       &key.PublicKey,
+		}
+	}
+	return reports
+}
+
+// benchmarkBatchSignatures verifies the enclave and QE report signatures of a batch of 64 quotes, the
+// share of the verification of a batch that runs in parallel
+func benchmarkBatchSignatures(b *testing.B, workers int) {
+	reports := newSignedReports(b, 64)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		runParallel(len(reports), workers, func(i int) {
+			err := verifier.VerifyEnclaveReportSignature(reports[i].signature, reports[i].report,
+				reports[i].publicKey)
+			if err != nil {
+				b.Error(err)
+			}
+			err = verifier.VerifyQeReportSignature(reports[i].signature, reports[i].report, reports[i].key)
+			if err != nil {
+				b.Error(err)
+			}
+		})
+	}
+}
+
+func BenchmarkBatchSignaturesSequential(b *testing.B) {
+	benchmarkBatchSignatures(b, 1)
+}
+
+func BenchmarkBatchSignaturesParallel(b *testing.B) {
+	benchmarkBatchSignatures(b, runtime.GOMAXPROCS(0))
+}
@@ -0,0 +1,196 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	commLogMsg "intel/isecl/lib/common/v4/log/message"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	constraintMrEnclave        = "mrEnclave"
+	constraintMrSigner         = "mrSigner"
+	constraintMinIsvSvn        = "minIsvSvn"
+	constraintIsvProdID        = "isvProdId"
+	constraintReportDataPrefix = "reportDataPrefix"
+	constraintFmspcs           = "fmspcs"
+	constraintSgxTypes         = "sgxTypes"
+	constraintConfigID         = "configId"
+	constraintMinConfigSvn     = "minConfigSvn"
+	constraintIsvExtProdID     = "isvExtProdId"
+	constraintIsvFamilyID      = "isvFamilyId"
+)
+
+// QuoteConstraints are the expectations of the caller on the enclave report of the quote, evaluated by
+// SQVS once the quote is verified. Measurements and report data are hex encoded, unset constraints are
+// not evaluated. Fmspcs and SgxTypes are allow-lists of the FMSPCs and SGX types of the PCK certificate.
+// ConfigID, MinConfigSvn, IsvExtProdID and IsvFamilyID constrain the KSS fields of the enclave report, the
+// ones of enclaves launched without KSS being zero.
+type QuoteConstraints struct {
+	MrEnclave        string   `json:"mrEnclave,omitempty"`
+	MrSigner         string   `json:"mrSigner,omitempty"`
+	MinIsvSvn        *uint16  `json:"minIsvSvn,omitempty"`
+	IsvProdID        *uint16  `json:"isvProdId,omitempty"`
+	ReportDataPrefix string   `json:"reportDataPrefix,omitempty"`
+	Fmspcs           []string `json:"fmspcs,omitempty"`
+	SgxTypes         []string `json:"sgxTypes,omitempty"`
+	ConfigID         string   `json:"configId,omitempty"`
+	MinConfigSvn     *uint16  `json:"minConfigSvn,omitempty"`
+	IsvExtProdID     string   `json:"isvExtProdId,omitempty"`
+	IsvFamilyID      string   `json:"isvFamilyId,omitempty"`
+}
+
+// ConstraintResult is the outcome of one constraint of the request
+type ConstraintResult struct {
+	Name     string `json:"Name"`
+	Expected string `json:"Expected"`
+	Actual   string `json:"Actual"`
+	Passed   bool   `json:"Passed"`
+}
+
+// ConstraintResults holds the outcome of every constraint of the request, Passed is set when all passed
+type ConstraintResults struct {
+	Passed  bool               `json:"Passed"`
+	Results []ConstraintResult `json:"Results"`
+}
+
+// parseQuoteConstraints decodes the JSON constraints passed as a query parameter or form field
+func parseQuoteConstraints(value string) (*QuoteConstraints, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var constraints QuoteConstraints
+	dec := json.NewDecoder(strings.NewReader(value))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&constraints)
+	if err != nil {
+		slog.WithError(err).Errorf("resource/quote_constraints:parseQuoteConstraints() %s: Failed to decode "+
+			"constraints", commLogMsg.InvalidInputBadEncoding)
+		return nil, &resourceError{Message: "Invalid constraints provided", StatusCode: http.StatusBadRequest}
+	}
+	return &constraints, nil
+}
+
+// validate checks the constraints are well formed before the quote is verified
+func (c *QuoteConstraints) validate() error {
+	if c == nil {
+		return nil
+	}
+	hexConstraints := []struct {
+		name   string
+		value  string
+		maxLen int
+		exact  bool
+	}{
+		{constraintMrEnclave, c.MrEnclave, parser.HashSize, true},
+		{constraintMrSigner, c.MrSigner, parser.HashSize, true},
+		{constraintReportDataPrefix, c.ReportDataPrefix, parser.ReportDataSize, false},
+		{constraintConfigID, c.ConfigID, parser.ConfigIDSize, true},
+		{constraintIsvExtProdID, c.IsvExtProdID, parser.IsvExtProdIDSize, true},
+		{constraintIsvFamilyID, c.IsvFamilyID, parser.IsvFamilyIDSize, true},
+	}
+	for _, hc := range hexConstraints {
+		if hc.value == "" {
+			continue
+		}
+		value, err := hex.DecodeString(hc.value)
+		if err != nil || len(value) > hc.maxLen || (hc.exact && len(value) != hc.maxLen) {
+			slog.Errorf("resource/quote_constraints:validate() %s: Invalid %s constraint",
+				commLogMsg.InvalidInputBadParam, hc.name)
+			return &resourceError{Message: "Invalid " + hc.name + " constraint", StatusCode: http.StatusBadRequest}
+		}
+	}
+	for _, fmspc := range c.Fmspcs {
+		value, err := hex.DecodeString(fmspc)
+		if err != nil || len(value) != parser.FmspcSize {
+			slog.Errorf("resource/quote_constraints:validate() %s: Invalid %s constraint",
+				commLogMsg.InvalidInputBadParam, constraintFmspcs)
+			return &resourceError{Message: "Invalid " + constraintFmspcs + " constraint", StatusCode: http.StatusBadRequest}
+		}
+	}
+	for _, sgxType := range c.SgxTypes {
+		if _, ok := parser.ParseSgxType(sgxType); !ok {
+			slog.Errorf("resource/quote_constraints:validate() %s: Invalid %s constraint",
+				commLogMsg.InvalidInputBadParam, constraintSgxTypes)
+			return &resourceError{Message: "Invalid " + constraintSgxTypes + " constraint, must be Standard, " +
+				"Scalable or ScalableWithIntegrity", StatusCode: http.StatusBadRequest}
+		}
+	}
+	return nil
+}
+
+// evaluate compares the enclave report and the PCK certificate extensions of a verified quote with the
+// constraints
+func (c *QuoteConstraints) evaluate(report *parser.ReportBody, ext *PckCertExtensions) *ConstraintResults {
+	if c == nil {
+		return nil
+	}
+	results := &ConstraintResults{Passed: true, Results: []ConstraintResult{}}
+	add := func(name, expected, actual string, passed bool) {
+		results.Results = append(results.Results, ConstraintResult{Name: name, Expected: expected,
+			Actual: actual, Passed: passed})
+		results.Passed = results.Passed && passed
+	}
+
+	if c.MrEnclave != "" {
+		actual := hex.EncodeToString(report.MrEnclave[:])
+		add(constraintMrEnclave, strings.ToLower(c.MrEnclave), actual, strings.EqualFold(c.MrEnclave, actual))
+	}
+	if c.MrSigner != "" {
+		actual := hex.EncodeToString(report.MrSigner[:])
+		add(constraintMrSigner, strings.ToLower(c.MrSigner), actual, strings.EqualFold(c.MrSigner, actual))
+	}
+	if c.MinIsvSvn != nil {
+		add(constraintMinIsvSvn, strconv.Itoa(int(*c.MinIsvSvn)), strconv.Itoa(int(report.SgxIsvSvn)),
+			report.SgxIsvSvn >= *c.MinIsvSvn)
+	}
+	if c.IsvProdID != nil {
+		add(constraintIsvProdID, strconv.Itoa(int(*c.IsvProdID)), strconv.Itoa(int(report.SgxIsvProdID)),
+			report.SgxIsvProdID == *c.IsvProdID)
+	}
+	if c.ReportDataPrefix != "" {
+		// validate ensured the prefix is valid hex no longer than the report data
+		prefix, _ := hex.DecodeString(c.ReportDataPrefix)
+		actual := report.ReportData[:len(prefix)]
+		add(constraintReportDataPrefix, hex.EncodeToString(prefix), hex.EncodeToString(actual),
+			bytes.Equal(prefix, actual))
+	}
+	kssConstraints := []struct {
+		name     string
+		expected string
+		actual   []byte
+	}{
+		{constraintConfigID, c.ConfigID, report.ConfigID[:]},
+		{constraintIsvExtProdID, c.IsvExtProdID, report.IsvExtProdID[:]},
+		{constraintIsvFamilyID, c.IsvFamilyID, report.IsvFamilyID[:]},
+	}
+	for _, kc := range kssConstraints {
+		if kc.expected != "" {
+			actual := hex.EncodeToString(kc.actual)
+			add(kc.name, strings.ToLower(kc.expected), actual, strings.EqualFold(kc.expected, actual))
+		}
+	}
+	if c.MinConfigSvn != nil {
+		add(constraintMinConfigSvn, strconv.Itoa(int(*c.MinConfigSvn)), strconv.Itoa(int(report.ConfigSvn)),
+			report.ConfigSvn >= *c.MinConfigSvn)
+	}
+	if len(c.Fmspcs) > 0 && ext != nil {
+		add(constraintFmspcs, strings.ToLower(strings.Join(c.Fmspcs, ",")), ext.FMSPC, containsFold(c.Fmspcs, ext.FMSPC))
+	}
+	if len(c.SgxTypes) > 0 && ext != nil {
+		add(constraintSgxTypes, strings.Join(c.SgxTypes, ","), ext.SgxType, containsFold(c.SgxTypes, ext.SgxType))
+	}
+
+	if !results.Passed {
+		log.Info("resource/quote_constraints:evaluate() Quote does not satisfy the constraints of the request")
+	}
+	return results
+}
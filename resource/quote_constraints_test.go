@@ -0,0 +1,124 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"bytes"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testReportBody() *parser.ReportBody {
+	report := &parser.ReportBody{SgxIsvProdID: 2, SgxIsvSvn: 5}
+	for i := range report.MrEnclave {
+		report.MrEnclave[i] = 0xaa
+		report.MrSigner[i] = 0xbb
+	}
+	copy(report.ReportData[:], []byte{0xde, 0xad, 0xbe, 0xef})
+	return report
+}
+
+func TestQuoteConstraintsEvaluate(t *testing.T) {
+	minIsvSvn, isvProdID := uint16(6), uint16(2)
+	constraints := &QuoteConstraints{
+		MrEnclave:        strings.Repeat("AA", parser.HashSize),
+		MrSigner:         strings.Repeat("bb", parser.HashSize),
+		MinIsvSvn:        &minIsvSvn,
+		IsvProdID:        &isvProdID,
+		ReportDataPrefix: "deadbeef",
+	}
+	assert.NoError(t, constraints.validate())
+
+	results := constraints.evaluate(testReportBody(), nil)
+	assert.False(t, results.Passed)
+	passed := map[string]bool{}
+	for _, result := range results.Results {
+		passed[result.Name] = result.Passed
+	}
+	assert.Equal(t, map[string]bool{constraintMrEnclave: true, constraintMrSigner: true, constraintMinIsvSvn: false,
+		constraintIsvProdID: true, constraintReportDataPrefix: true}, passed)
+
+	minIsvSvn = 5
+	assert.True(t, constraints.evaluate(testReportBody(), nil).Passed)
+
+	ext := &PckCertExtensions{FMSPC: "00906ea10000", SgxType: "Scalable"}
+	pckConstraints := &QuoteConstraints{Fmspcs: []string{"00906EA10000"}, SgxTypes: []string{"Standard"}}
+	assert.NoError(t, pckConstraints.validate())
+	results = pckConstraints.evaluate(testReportBody(), ext)
+	assert.False(t, results.Passed)
+	assert.True(t, results.Results[0].Passed)
+	assert.False(t, results.Results[1].Passed)
+	assert.Error(t, (&QuoteConstraints{Fmspcs: []string{"0090"}}).validate())
+	assert.Error(t, (&QuoteConstraints{SgxTypes: []string{"TDX"}}).validate())
+
+	var noConstraints *QuoteConstraints
+	assert.NoError(t, noConstraints.validate())
+	assert.Nil(t, noConstraints.evaluate(testReportBody(), nil))
+}
+
+func TestQuoteConstraintsKSS(t *testing.T) {
+	report := testReportBody()
+	report.ConfigID[0] = 0x7e
+	report.ConfigSvn = 4
+	report.IsvFamilyID[15] = 0x01
+
+	minConfigSvn := uint16(4)
+	constraints := &QuoteConstraints{
+		ConfigID:     "7E" + strings.Repeat("00", parser.ConfigIDSize-1),
+		MinConfigSvn: &minConfigSvn,
+		IsvExtProdID: strings.Repeat("00", parser.IsvExtProdIDSize),
+		IsvFamilyID:  strings.Repeat("00", parser.IsvFamilyIDSize-1) + "01",
+	}
+	assert.NoError(t, constraints.validate())
+	assert.True(t, constraints.evaluate(report, nil).Passed)
+
+	other := &QuoteConstraints{ConfigID: strings.Repeat("00", parser.ConfigIDSize)}
+	results := other.evaluate(report, nil)
+	assert.False(t, results.Passed)
+	assert.Equal(t, constraintConfigID, results.Results[0].Name)
+
+	minConfigSvn = 5
+	assert.False(t, constraints.evaluate(report, nil).Passed)
+	assert.Error(t, (&QuoteConstraints{ConfigID: "7e"}).validate())
+	assert.Error(t, (&QuoteConstraints{IsvFamilyID: strings.Repeat("00", parser.IsvFamilyIDSize+1)}).validate())
+
+	kss := newKssFields(report)
+	assert.Equal(t, uint16(4), kss.ConfigSvn)
+	assert.Equal(t, "7e"+strings.Repeat("00", parser.ConfigIDSize-1), kss.ConfigID)
+	assert.Nil(t, newKssFields(testReportBody()))
+}
+
+func TestQuoteConstraintsValidate(t *testing.T) {
+	assert.Error(t, (&QuoteConstraints{MrEnclave: "aa"}).validate())
+	assert.Error(t, (&QuoteConstraints{MrSigner: strings.Repeat("zz", parser.HashSize)}).validate())
+	assert.Error(t, (&QuoteConstraints{ReportDataPrefix: strings.Repeat("00", parser.ReportDataSize+1)}).validate())
+	assert.NoError(t, (&QuoteConstraints{ReportDataPrefix: strings.Repeat("00", parser.ReportDataSize)}).validate())
+}
+
+func TestDecodeQuoteRequestConstraintsQuery(t *testing.T) {
+	quote := bytes.Repeat([]byte{0xab}, constants.MinQuoteSize)
+	req := httptest.NewRequest("POST", "/svs/v1/sgx_qv_verify_quote?constraints="+
+		url.QueryEscape(`{"minIsvSvn":3,"reportDataPrefix":"00ff"}`), bytes.NewReader(quote))
+	req.Header.Set("Content-Type", contentTypeOctet)
+
+	data, err := decodeQuoteRequest(httptest.NewRecorder(), req, false)
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(3), *data.Constraints.MinIsvSvn)
+	assert.Equal(t, "00ff", data.Constraints.ReportDataPrefix)
+
+	req = httptest.NewRequest("POST", "/svs/v1/sgx_qv_verify_quote?constraints="+
+		url.QueryEscape(`{"expectedMrEnclave":"00"}`), bytes.NewReader(quote))
+	req.Header.Set("Content-Type", contentTypeOctet)
+	_, err = decodeQuoteRequest(httptest.NewRecorder(), req, false)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.(*resourceError).StatusCode)
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"strings"
+)
+
+// QuoteHashes carries the digests of the decoded quote and of its enclave report body, so relying parties can
+// bind a verification result to the quote they submitted. The quote digests are omitted for SGX reports, which
+// are not quotes.
+type QuoteHashes struct {
+	QuoteSHA256      string `json:"QuoteSHA256,omitempty"`
+	QuoteSHA384      string `json:"QuoteSHA384,omitempty"`
+	ReportBodySHA256 string `json:"ReportBodySHA256"`
+	ReportBodySHA384 string `json:"ReportBodySHA384"`
+}
+
+// NewQuoteHashes computes the digests of a raw, base64 decoded, SGX ECDSA quote
+func NewQuoteHashes(rawQuote []byte) *QuoteHashes {
+	hashes := &QuoteHashes{}
+	quoteSHA256 := sha256.Sum256(rawQuote)
+	quoteSHA384 := sha512.Sum384(rawQuote)
+	hashes.QuoteSHA256 = hex.EncodeToString(quoteSHA256[:])
+	hashes.QuoteSHA384 = hex.EncodeToString(quoteSHA384[:])
+
+	if len(rawQuote) >= parser.QuoteHeaderLength+parser.EnclaveReportLength {
+		hashes.setReportBody(rawQuote[parser.QuoteHeaderLength : parser.QuoteHeaderLength+parser.EnclaveReportLength])
+	}
+	return hashes
+}
+
+// NewReportHashes computes the digests of the body of a raw SGX report
+func NewReportHashes(rawReport []byte) *QuoteHashes {
+	hashes := &QuoteHashes{}
+	if len(rawReport) >= parser.EnclaveReportLength {
+		hashes.setReportBody(rawReport[:parser.EnclaveReportLength])
+	}
+	return hashes
+}
+
+func (h *QuoteHashes) setReportBody(reportBody []byte) {
+	reportSHA256 := sha256.Sum256(reportBody)
+	reportSHA384 := sha512.Sum384(reportBody)
+	h.ReportBodySHA256 = hex.EncodeToString(reportSHA256[:])
+	h.ReportBodySHA384 = hex.EncodeToString(reportSHA384[:])
+}
+
+// MatchesQuote reports whether the digests were computed over the given raw quote, checking
+// the SHA-384 digest when present and the SHA-256 digest otherwise
+func (h *QuoteHashes) MatchesQuote(rawQuote []byte) bool {
+	expected := NewQuoteHashes(rawQuote)
+	if h.QuoteSHA384 != "" {
+		return digestEqual(h.QuoteSHA384, expected.QuoteSHA384)
+	}
+	return h.QuoteSHA256 != "" && digestEqual(h.QuoteSHA256, expected.QuoteSHA256)
+}
+
+// MatchesReportBody reports whether the digests were computed over the given raw enclave report body
+func (h *QuoteHashes) MatchesReportBody(reportBody []byte) bool {
+	expected := &QuoteHashes{}
+	expected.setReportBody(reportBody)
+	if h.ReportBodySHA384 != "" {
+		return digestEqual(h.ReportBodySHA384, expected.ReportBodySHA384)
+	}
+	return h.ReportBodySHA256 != "" && digestEqual(h.ReportBodySHA256, expected.ReportBodySHA256)
+}
+
+func digestEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(strings.ToLower(a)), []byte(b)) == 1
+}
@@ -0,0 +1,33 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteHashes(t *testing.T) {
+	quote := make([]byte, 1024)
+	for i := range quote {
+		quote[i] = byte(i)
+	}
+	hashes := NewQuoteHashes(quote)
+
+	reportBody := quote[parser.QuoteHeaderLength : parser.QuoteHeaderLength+parser.EnclaveReportLength]
+	digest := sha256.Sum256(reportBody)
+	assert.Equal(t, hex.EncodeToString(digest[:]), hashes.ReportBodySHA256)
+	assert.Len(t, hashes.QuoteSHA384, 96)
+	assert.True(t, hashes.MatchesQuote(quote))
+	assert.True(t, hashes.MatchesReportBody(reportBody))
+
+	quote[0] ^= 0xff
+	assert.False(t, hashes.MatchesQuote(quote))
+	assert.False(t, (&QuoteHashes{}).MatchesQuote(quote))
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	commLogMsg "intel/isecl/lib/common/v4/log/message"
+	"intel/isecl/sqvs/v4/constants"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+)
+
+const (
+	contentTypeJSON      = "application/json"
+	contentTypeOctet     = "application/octet-stream"
+	contentTypeMultipart = "multipart/form-data"
+	contentTypeNDJSON    = "application/x-ndjson"
+
+	quoteFormField          = "quote"
+	userDataFormField       = "userData"
+	challengeFormField      = "challenge"
+	nonceFormField          = "nonce"
+	policyFormField         = "policy"
+	constraintsFormField    = "constraints"
+	bindingFormField        = "reportDataBinding"
+	evaluationTimeFormField = "evaluationTime"
+	keyReleaseFormField     = "keyReleaseKeyId"
+	sessionTokenFormField   = "sessionToken"
+	signingAlgFormField     = "signingAlgorithm"
+)
+
+// quoteContentTypes lists the request body formats accepted by the quote verification endpoints
+var quoteContentTypes = []string{contentTypeJSON, contentTypeOctet, contentTypeMultipart}
+
+// decodeQuoteRequest reads the quote verification request body in any of the supported content types.
+// For application/octet-stream, the body carries the raw quote bytes and the remaining fields are read
+// from query parameters. For multipart/form-data, the raw quote is read from the "quote" form file and
+// the remaining fields from form values. allowChallenge controls whether challenge/nonce are accepted.
+func decodeQuoteRequest(w http.ResponseWriter, r *http.Request, allowChallenge bool) (QuoteDataWithChallenge, error) {
+	log.Trace("resource/quote_request:decodeQuoteRequest() Entering")
+	defer log.Trace("resource/quote_request:decodeQuoteRequest() Leaving")
+
+	var data QuoteDataWithChallenge
+	if r.ContentLength == 0 {
+		slog.Error("resource/quote_request: decodeQuoteRequest() The request body was not provided")
+		return data, &resourceError{Message: "SGX_QL_ERROR_INVALID_PARAMETER", StatusCode: http.StatusBadRequest}
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		slog.WithError(err).Errorf("resource/quote_request: decodeQuoteRequest() %s: Invalid Content-Type",
+			commLogMsg.InvalidInputBadParam)
+		return data, &resourceError{Message: "Invalid Content-Type", StatusCode: http.StatusUnsupportedMediaType}
+	}
+
+	switch mediaType {
+	case contentTypeOctet:
+		quoteBytes, err := readRawQuote(r.Body)
+		if err != nil {
+			return data, err
+		}
+		data.QuoteBlob = base64.StdEncoding.EncodeToString(quoteBytes)
+		q := r.URL.Query()
+		data.UserData = q.Get(userDataFormField)
+		data.EvaluationTime = q.Get(evaluationTimeFormField)
+		data.TcbEvaluationDate = q.Get(tcbEvaluationDateFormField)
+		data.CollateralVersion, err = parseCollateralVersion(q.Get(collateralVersionFormField))
+		if err != nil {
+			return data, err
+		}
+		data.Constraints, err = parseQuoteConstraints(q.Get(constraintsFormField))
+		if err != nil {
+			return data, err
+		}
+		data.ReportDataBinding, err = parseReportDataBinding(q.Get(bindingFormField))
+		if err != nil {
+			return data, err
+		}
+		if allowChallenge {
+			data.Challenge = q.Get(challengeFormField)
+			data.Nonce = q.Get(nonceFormField)
+			data.Policy = q.Get(policyFormField)
+			data.KeyRelease = keyReleaseOf(q.Get(keyReleaseFormField))
+			data.SessionToken = q.Get(sessionTokenFormField)
+			data.SigningAlgorithm = q.Get(signingAlgFormField)
+		}
+
+	case contentTypeMultipart:
+		r.Body = http.MaxBytesReader(w, r.Body, constants.MaxQuoteUploadSize)
+		err = r.ParseMultipartForm(constants.MaxQuoteUploadSize)
+		if err != nil {
+			slog.WithError(err).Errorf("resource/quote_request: decodeQuoteRequest() %s: Failed to parse "+
+				"multipart form", commLogMsg.InvalidInputBadEncoding)
+			return data, &resourceError{Message: "Invalid multipart form provided", StatusCode: http.StatusBadRequest}
+		}
+		defer func() {
+			derr := r.MultipartForm.RemoveAll()
+			if derr != nil {
+				log.WithError(derr).Error("Error removing multipart form temporary files")
+			}
+		}()
+
+		file, _, err := r.FormFile(quoteFormField)
+		if err != nil {
+			slog.WithError(err).Error("resource/quote_request: decodeQuoteRequest() quote form file not provided")
+			return data, &resourceError{Message: "quote form file not provided", StatusCode: http.StatusBadRequest}
+		}
+		defer func() {
+			derr := file.Close()
+			if derr != nil {
+				log.WithError(derr).Error("Error closing quote form file")
+			}
+		}()
+
+		quoteBytes, err := readRawQuote(file)
+		if err != nil {
+			return data, err
+		}
+		data.QuoteBlob = base64.StdEncoding.EncodeToString(quoteBytes)
+		data.UserData = r.FormValue(userDataFormField)
+		data.EvaluationTime = r.FormValue(evaluationTimeFormField)
+		data.TcbEvaluationDate = r.FormValue(tcbEvaluationDateFormField)
+		data.CollateralVersion, err = parseCollateralVersion(r.FormValue(collateralVersionFormField))
+		if err != nil {
+			return data, err
+		}
+		data.Constraints, err = parseQuoteConstraints(r.FormValue(constraintsFormField))
+		if err != nil {
+			return data, err
+		}
+		data.ReportDataBinding, err = parseReportDataBinding(r.FormValue(bindingFormField))
+		if err != nil {
+			return data, err
+		}
+		if allowChallenge {
+			data.Challenge = r.FormValue(challengeFormField)
+			data.Nonce = r.FormValue(nonceFormField)
+			data.Policy = r.FormValue(policyFormField)
+			data.KeyRelease = keyReleaseOf(r.FormValue(keyReleaseFormField))
+			data.SessionToken = r.FormValue(sessionTokenFormField)
+			data.SigningAlgorithm = r.FormValue(signingAlgFormField)
+		}
+
+	default:
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if allowChallenge {
+			err = dec.Decode(&data)
+		} else {
+			err = dec.Decode(&data.QuoteData)
+		}
+		if err != nil {
+			slog.WithError(err).Errorf("resource/quote_request: decodeQuoteRequest() %s:Failed to decode "+
+				"request body", commLogMsg.InvalidInputBadEncoding)
+			return data, &resourceError{Message: "Invalid JSON input provided", StatusCode: http.StatusBadRequest}
+		}
+	}
+	return data, nil
+}
+
+// readRawQuote reads binary quote bytes, rejecting bodies larger than the maximum supported quote size
+func readRawQuote(body io.Reader) ([]byte, error) {
+	quoteBytes, err := ioutil.ReadAll(io.LimitReader(body, constants.MaxQuoteSize+1))
+	if err != nil {
+		slog.WithError(err).Error("resource/quote_request: readRawQuote() Failed to read quote")
+		return nil, &resourceError{Message: "Failed to read quote", StatusCode: http.StatusBadRequest}
+	}
+	if len(quoteBytes) < constants.MinQuoteSize || len(quoteBytes) > constants.MaxQuoteSize {
+		slog.Errorf("resource/quote_request: readRawQuote() %s: Invalid quote size %d",
+			commLogMsg.InvalidInputBadParam, len(quoteBytes))
+		return nil, &resourceError{Message: "Invalid quote size", StatusCode: http.StatusBadRequest}
+	}
+	return quoteBytes, nil
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"bytes"
+	"encoding/base64"
+	"intel/isecl/sqvs/v4/constants"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeQuoteRequestOctetStream(t *testing.T) {
+	quote := bytes.Repeat([]byte{0xab}, constants.MinQuoteSize)
+	req := httptest.NewRequest("POST", "/svs/v2/sgx_qv_verify_quote?userData=dGVzdA==&challenge=Y2hhbGxlbmdl",
+		bytes.NewReader(quote))
+	req.Header.Set("Content-Type", contentTypeOctet)
+
+	data, err := decodeQuoteRequest(httptest.NewRecorder(), req, true)
+	assert.NoError(t, err)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(quote), data.QuoteBlob)
+	assert.Equal(t, "dGVzdA==", data.UserData)
+	assert.Equal(t, "Y2hhbGxlbmdl", data.Challenge)
+}
+
+func TestDecodeQuoteRequestOctetStreamTooSmall(t *testing.T) {
+	req := httptest.NewRequest("POST", "/svs/v1/sgx_qv_verify_quote", bytes.NewReader([]byte{0x01, 0x02}))
+	req.Header.Set("Content-Type", contentTypeOctet)
+
+	_, err := decodeQuoteRequest(httptest.NewRecorder(), req, false)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.(*resourceError).StatusCode)
+}
+
+func TestDecodeQuoteRequestMultipart(t *testing.T) {
+	quote := bytes.Repeat([]byte{0xcd}, constants.MinQuoteSize)
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	fw, _ := mw.CreateFormFile(quoteFormField, "quote.dat")
+	_, _ = fw.Write(quote)
+	_ = mw.WriteField(userDataFormField, "dGVzdA==")
+	_ = mw.WriteField(challengeFormField, "Y2hhbGxlbmdl")
+	_ = mw.Close()
+
+	req := httptest.NewRequest("POST", "/svs/v1/sgx_qv_verify_quote", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	data, err := decodeQuoteRequest(httptest.NewRecorder(), req, false)
+	assert.NoError(t, err)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(quote), data.QuoteBlob)
+	assert.Equal(t, "dGVzdA==", data.UserData)
+	assert.Empty(t, data.Challenge)
+}
+
+func TestDecodeQuoteRequestJSONRejectsChallengeOnV1(t *testing.T) {
+	req := httptest.NewRequest("POST", "/svs/v1/sgx_qv_verify_quote",
+		strings.NewReader(`{"quote":"AAAA","challenge":"Y2hhbGxlbmdl"}`))
+	req.Header.Set("Content-Type", contentTypeJSON)
+
+	_, err := decodeQuoteRequest(httptest.NewRecorder(), req, false)
+	assert.Error(t, err)
+}
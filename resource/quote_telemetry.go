@@ -0,0 +1,98 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"intel/isecl/sqvs/v4/metrics"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"strconv"
+	"sync"
+)
+
+// maxFmspcsTracked bounds the FMSPCs remembered to count their diversity
+const maxFmspcsTracked = 10000
+
+// The quote telemetry is aggregate only, it carries no identifier of the quotes, the enclaves or the platforms,
+// to size the collateral caches and the batch workers after the actual traffic
+var (
+	quotesTotal = metrics.NewCounter("sqvs_quotes_total",
+		"Quotes parsed by quote version and certification data type", "version", "cert_data_type")
+	quoteBytesTotal = metrics.NewCounter("sqvs_quote_bytes_total",
+		"Size in bytes of the quotes parsed, divided by sqvs_quotes_total for the average quote size")
+	quoteBatchesTotal = metrics.NewCounter("sqvs_quote_batches_total",
+		"Batch verification requests by number of quotes, 1, 2-10, 11-50 or 51-100", "size")
+	quoteBatchQuotesTotal = metrics.NewCounter("sqvs_quote_batch_quotes_total",
+		"Quotes of the batch verification requests, divided by sqvs_quote_batches_total for the average batch size")
+	quoteFmspcsDistinct = metrics.NewGauge("sqvs_quote_fmspcs_distinct",
+		"Distinct FMSPCs of the platforms quotes were verified for since the service started")
+)
+
+var certDataTypes = map[uint16]string{
+	1: "ppid-cleartext",
+	2: "ppid-rsa2048-encrypted",
+	3: "ppid-rsa3072-encrypted",
+	4: "pck-cleartext",
+	5: "pck-cert-chain",
+	6: "qe-report-cert-data",
+	7: "platform-manifest",
+}
+
+// fmspcSet remembers a digest of the FMSPCs seen, only their number is exposed
+type fmspcSet struct {
+	mu     sync.Mutex
+	digest map[uint64]struct{}
+}
+
+var fmspcsSeen = &fmspcSet{digest: map[uint64]struct{}{}}
+
+// add counts the FMSPC and returns the number of distinct FMSPCs seen
+func (s *fmspcSet) add(fmspc string) int {
+	sum := sha256.Sum256([]byte(fmspc))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.digest) < maxFmspcsTracked {
+		s.digest[binary.BigEndian.Uint64(sum[:8])] = struct{}{}
+	}
+	return len(s.digest)
+}
+
+// observeQuote counts the version, certification data type and size of a parsed quote
+func observeQuote(quoteObj *parser.SgxQuoteParsed, size int) {
+	certDataType, ok := certDataTypes[quoteObj.QuoteSignatureData.QeCertData.Type]
+	if !ok {
+		certDataType = "unknown"
+	}
+	quotesTotal.Inc(strconv.Itoa(int(quoteObj.Header.Version)), certDataType)
+	quoteBytesTotal.Add(float64(size))
+}
+
+// observeFmspc counts the FMSPC of the platform of a quote among the distinct FMSPCs seen
+func observeFmspc(fmspc string) {
+	if fmspc == "" {
+		return
+	}
+	quoteFmspcsDistinct.Set(float64(fmspcsSeen.add(fmspc)))
+}
+
+// observeQuoteBatch counts a batch verification request of n quotes
+func observeQuoteBatch(n int) {
+	quoteBatchesTotal.Inc(batchSizeClass(n))
+	quoteBatchQuotesTotal.Add(float64(n))
+}
+
+func batchSizeClass(n int) string {
+	switch {
+	case n <= 1:
+		return "1"
+	case n <= 10:
+		return "2-10"
+	case n <= 50:
+		return "11-50"
+	default:
+		return "51-100"
+	}
+}
@@ -0,0 +1,33 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"bytes"
+	"intel/isecl/sqvs/v4/metrics"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteTelemetry(t *testing.T) {
+	quoteObj := &parser.SgxQuoteParsed{}
+	quoteObj.Header.Version = 3
+	quoteObj.QuoteSignatureData.QeCertData.Type = 5
+	observeQuote(quoteObj, 4600)
+	observeQuoteBatch(12)
+
+	set := &fmspcSet{digest: map[uint64]struct{}{}}
+	assert.Equal(t, 1, set.add("20606a000000"))
+	assert.Equal(t, 1, set.add("20606a000000"))
+	assert.Equal(t, 2, set.add("00906ea10000"))
+
+	var out bytes.Buffer
+	assert.NoError(t, metrics.Write(&out))
+	assert.Contains(t, out.String(), `sqvs_quotes_total{version="3",cert_data_type="pck-cert-chain"}`)
+	assert.Contains(t, out.String(), `sqvs_quote_batches_total{size="11-50"} 1`)
+	assert.NotContains(t, out.String(), "20606a000000")
+}
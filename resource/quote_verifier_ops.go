@@ -6,20 +6,22 @@
 package resource
 
 import (
+	"context"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
-	commLogMsg "intel/isecl/lib/common/v4/log/message"
 	"intel/isecl/sqvs/v4/config"
 	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/keyrelease"
 	"intel/isecl/sqvs/v4/resource/parser"
 	"intel/isecl/sqvs/v4/resource/utils"
 	"intel/isecl/sqvs/v4/resource/verifier"
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
@@ -32,21 +34,70 @@ type SGXResponse struct {
 	AdditionalQuoteData
 }
 
+// AdditionalQuoteData is the result of a quote verification. Its members are named after their fields,
+// but for the JWT claims scoping signed results.
 type AdditionalQuoteData struct {
 	Message             string
-	EnclaveIssuer       string `json:"EnclaveIssuer,omitempty"`
-	EnclaveMeasurement  string `json:"EnclaveMeasurement,omitempty"`
-	EnclaveIssuerProdID string `json:"EnclaveIssuerProdID,omitempty"`
-	IsvSvn              string `json:"IsvSvn,omitempty"`
-	TcbLevel            string `json:"TcbLevel,omitempty"`
-	Quote               string `json:"Quote,omitempty"`
-	Challenge           string `json:"Challenge,omitempty"`
+	EnclaveIssuer       string     `json:"EnclaveIssuer,omitempty"`
+	EnclaveMeasurement  string     `json:"EnclaveMeasurement,omitempty"`
+	EnclaveIssuerProdID string     `json:"EnclaveIssuerProdID,omitempty"`
+	IsvSvn              string     `json:"IsvSvn,omitempty"`
+	TcbLevel            string     `json:"TcbLevel,omitempty"`
+	QvResult            *QvResult  `json:"QvResult,omitempty"`
+	EnclaveDebugMode    bool       `json:"EnclaveDebugMode"`
+	KSS                 *KssFields `json:"KSS,omitempty"`
+	Quote               string     `json:"Quote,omitempty"`
+	Challenge           string     `json:"Challenge,omitempty"`
+
+	SupplementalData  *SupplementalData        `json:"SupplementalData,omitempty"`
+	PckExtensions     *PckCertExtensions       `json:"PckExtensions,omitempty"`
+	QuoteHashes       *QuoteHashes             `json:"QuoteHashes,omitempty"`
+	Constraints       *ConstraintResults       `json:"Constraints,omitempty"`
+	EvaluationTime    string                   `json:"EvaluationTime,omitempty"`
+	CustomClaims      map[string]string        `json:"CustomClaims,omitempty"`
+	ReportDataBinding *ReportDataBindingResult `json:"ReportDataBinding,omitempty"`
+	ClockSkew         *ClockSkew               `json:"ClockSkew,omitempty"`
+	Steps             VerificationSteps        `json:"VerificationSteps,omitempty"`
+	CollateralSigners []CollateralSigner       `json:"CollateralSigners,omitempty"`
+	Diagnostics       *VerificationDiagnostics `json:"Diagnostics,omitempty"`
+	// IssuedAt is the RFC 3339 time a signed result was issued at, results are revoked by issue time
+	IssuedAt string `json:"IssuedAt,omitempty"`
+	// ResultPolicy, Issuer, Audience and Expiry scope a signed result to the relying parties of the result
+	// policy it was issued under, Expiry being the Unix time of ExpiresAt
+	ResultPolicy string   `json:"ResultPolicy,omitempty"`
+	Issuer       string   `json:"iss,omitempty"`
+	Audience     []string `json:"aud,omitempty"`
+	Expiry       int64    `json:"exp,omitempty"`
+	// ExpiresAt is the RFC 3339 time relying parties should re-attest after, ExpirySource tells which of
+	// the collateral next update, the result validity or the result policy TTL it is
+	ExpiresAt    string `json:"ExpiresAt,omitempty"`
+	ExpirySource string `json:"ExpirySource,omitempty"`
+	// TcbEvaluationDate and CollateralVersion report the recorded collateral a quote was re-evaluated against,
+	// CollateralVersion being the TCB evaluation data number of its TCB info
+	TcbEvaluationDate string `json:"TcbEvaluationDate,omitempty"`
+	CollateralVersion uint   `json:"CollateralVersion,omitempty"`
+	// TcbStatusVerdict is the verdict the TCB status was mapped to by SQVS_TCB_STATUS_VERDICTS
+	TcbStatusVerdict *TcbStatusVerdict `json:"TcbStatusVerdict,omitempty"`
+	// KeyRelease is the key the key broker released to the enclave when the request asked for one
+	KeyRelease *keyrelease.Release `json:"KeyRelease,omitempty"`
+	// TestMode labels the canned verdicts of the test mode, never returned for real quotes
+	TestMode bool `json:"TestMode,omitempty"`
+	// SessionID is the ID of the verification session the quote was submitted for, the jti of its token
+	SessionID string `json:"SessionID,omitempty"`
+	// CollateralFallback reports the cached collateral the quote was verified against, fresh collateral
+	// could not be fetched
+	CollateralFallback *CollateralFallback `json:"CollateralFallback,omitempty"`
+	// PlatformIdentifiers is the redaction profile applied to the PPID and platform instance ID of the
+	// platform, empty when they are returned as they are
+	PlatformIdentifiers string `json:"PlatformIdentifiers,omitempty"`
 }
 
 type SignedSGXResponse struct {
 	QuoteData        string `json:"quoteData"`
 	Signature        string `json:"signature,omitempty"`
 	CertificateChain string `json:"certificateChain,omitempty"`
+	KeyID            string `json:"keyId,omitempty"`
+	Algorithm        string `json:"alg,omitempty"`
 }
 
 type UnsignedSGXResponse struct {
@@ -60,8 +111,20 @@ type QuoteInfo struct {
 }
 
 type QuoteData struct {
-	QuoteBlob string `json:"quote"`
-	UserData  string `json:"userData"`
+	QuoteBlob   string            `json:"quote"`
+	UserData    string            `json:"userData"`
+	Constraints *QuoteConstraints `json:"constraints,omitempty"`
+	// ReportDataBinding declares how the report data was built from the inputs it binds, SQVS checks the
+	// binding and returns the verdict
+	ReportDataBinding *ReportDataBinding `json:"reportDataBinding,omitempty"`
+	// EvaluationTime is the RFC 3339 time the certificates of the quote are validated at, to re-evaluate
+	// a quote at the time it was produced. It defaults to the current trusted time.
+	EvaluationTime string `json:"evaluationTime,omitempty"`
+	// TcbEvaluationDate and CollateralVersion re-evaluate the quote against the recorded collateral that was
+	// current at the RFC 3339 date or has the TCB evaluation data number, to settle disputes long after the
+	// quote was verified. They require collateral history to be enabled.
+	TcbEvaluationDate string `json:"tcbEvaluationDate,omitempty"`
+	CollateralVersion uint   `json:"collateralVersion,omitempty"`
 }
 
 type QuoteDataWithChallenge struct {
@@ -69,10 +132,28 @@ type QuoteDataWithChallenge struct {
 	Challenge string `json:"challenge"`
 	//For future use
 	Nonce string `json:"nonce"`
+	// Policy names the result policy of the custom claims file the signed result is scoped and timed by,
+	// the first one applying to the enclave when empty
+	Policy string `json:"policy,omitempty"`
+	// KeyRelease asks for a key to be released to the enclave once its quote is verified
+	KeyRelease *KeyReleaseRequest `json:"keyRelease,omitempty"`
+	// SessionToken is the token of the verification session the quote is submitted for, its report data
+	// must bind the session nonce
+	SessionToken string `json:"sessionToken,omitempty"`
+	// SigningAlgorithm is the JWS algorithm the result is signed with, SQVS_RESULT_SIGNING_ALGORITHM when empty
+	SigningAlgorithm string `json:"signingAlgorithm,omitempty"`
+	// Debug collects the VerificationDiagnostics of the verification, it is set from the debug=true request
+	// option once the client is authorized to get them
+	Debug bool `json:"-"`
+	// PlatformIdentifiers returns the PPID and platform instance ID of the platform as they are, it is set
+	// from the platform_identifiers=true request option once the client is authorized to get them
+	PlatformIdentifiers bool `json:"-"`
 }
 
 func QuoteVerifyCB(router *mux.Router) {
-	router.Handle("/sgx_qv_verify_quote", handlers.ContentTypeHandler(sgxVerifyQuote(), "application/json")).Methods("POST")
+	router.Handle("/sgx_qv_verify_quote", handlers.ContentTypeHandler(sgxVerifyQuote(), quoteContentTypes...)).Methods("POST")
+	router.Handle("/sgx_qv_verify_quotes", featureGate(config.FeatureBatch,
+		handlers.ContentTypeHandler(sgxVerifyQuoteBatch(), contentTypeJSON))).Methods("POST")
 }
 
 func sgxVerifyQuote() errorHandlerFunc {
@@ -92,23 +173,21 @@ func sgxVerifyQuote() errorHandlerFunc {
 			}
 		}
 
-		var data QuoteData
-		if r.ContentLength == 0 {
-			slog.Error("resource/quote_verifier_ops: sgxVerifyQuote() The request body was not provided")
-			return &resourceError{Message: "SGX_QL_ERROR_INVALID_PARAMETER", StatusCode: http.StatusBadRequest}
+		data, err := decodeQuoteRequest(w, r, false)
+		if err != nil {
+			return err
+		}
+		data.Debug, err = debugRequested(r)
+		if err != nil {
+			return err
 		}
-		dec := json.NewDecoder(r.Body)
-		dec.DisallowUnknownFields()
-		err := dec.Decode(&data)
+		data.PlatformIdentifiers, err = platformIdentifiersRequested(r)
 		if err != nil {
-			slog.WithError(err).Errorf("resource/quote_verifier_ops: sgxVerifyQuote() %s:Failed to decode "+
-				"request body", commLogMsg.InvalidInputBadEncoding)
-			return &resourceError{Message: "Invalid JSON input provided", StatusCode: http.StatusBadRequest}
+			return err
 		}
 
-		sgxResponse, err := SgxEcdsaQuoteVerify(QuoteDataWithChallenge{
-			QuoteData: data,
-		})
+		sgxResponse, err := SgxEcdsaQuoteVerify(r.Context(), data)
+		recordVerification(callerOf(r), data.QuoteBlob, sgxResponse, err)
 		if err != nil {
 			return err
 		}
@@ -130,91 +209,196 @@ func sgxVerifyQuote() errorHandlerFunc {
 	}
 }
 
-func SgxEcdsaQuoteVerify(data QuoteDataWithChallenge) (SGXResponse, error) {
-	log.Trace("resource/quote_verifier_ops:SgxEcdsaQuoteVerify() Entering")
-	log.Trace("resource/quote_verifier_ops:SgxEcdsaQuoteVerify() Leaving")
+// SgxEcdsaQuoteVerify verifies the quote, its collateral and CRL fetches and its computation being canceled
+// with ctx, the context of the request it is verified for
+func SgxEcdsaQuoteVerify(ctx context.Context, data QuoteDataWithChallenge) (SGXResponse, error) {
+	return verifyQuote(ctx, data, nil)
+}
+
+// verifyQuote verifies the quote, the PCK certificate chains verified for the earlier quotes of a batch
+// are shared through chains when it is not nil. The diagnostics of the verification are returned with the
+// response, or the error, when data.Debug is set.
+func verifyQuote(ctx context.Context, data QuoteDataWithChallenge, chains *pckChainCache) (SGXResponse, error) {
+	log.Trace("resource/quote_verifier_ops:verifyQuote() Entering")
+	defer log.Trace("resource/quote_verifier_ops:verifyQuote() Leaving")
+
+	diag := newDiagnostics(data.Debug)
+	clock := newVerificationClock(ctx, diag)
+	defer clock.stop()
+	resp, err := verifyQuoteSteps(data, chains, diag, clock)
+	if diag == nil {
+		return resp, err
+	}
+	if rerr, ok := err.(*resourceError); ok {
+		if step := rerr.Steps.FailedStep(); step != "" {
+			diag.lap(step)
+		}
+		rerr.Diagnostics = diag.collected()
+	} else if err == nil {
+		resp.Diagnostics = diag.collected()
+	}
+	return resp, err
+}
+
+func verifyQuoteSteps(data QuoteDataWithChallenge, chains *pckChainCache, diag *diagnostics,
+	clock *verificationClock) (SGXResponse, error) {
+	err := data.Constraints.validate()
+	if err != nil {
+		return SGXResponse{}, err
+	}
+	err = data.ReportDataBinding.validate()
+	if err != nil {
+		return SGXResponse{}, err
+	}
+	history, err := selectCollateral(&data.QuoteData)
+	if err != nil {
+		return SGXResponse{}, err
+	}
+	now, at, err := evaluationTime(data.EvaluationTime)
+	if err != nil {
+		return SGXResponse{}, err
+	}
+
+	steps := newVerificationSteps()
 	skcBlobParsed := parser.ParseQuoteBlob(data.QuoteBlob)
 	if skcBlobParsed == nil {
 		log.Error("Could not parse sgx ecdsa quote")
-		return SGXResponse{}, &resourceError{Message: "Could not parse sgx ecdsa quote",
-			StatusCode: http.StatusBadRequest}
+		return SGXResponse{}, steps.fail(StepQuoteParse, &resourceError{Message: "Could not parse sgx ecdsa quote",
+			StatusCode: http.StatusBadRequest})
 	}
 
 	quoteObj := parser.ParseEcdsaQuoteBlob(skcBlobParsed.GetQuoteBlob())
 	if quoteObj == nil {
 		log.Error("Cannot parse sgx ecdsa quote")
-		return SGXResponse{}, &resourceError{Message: "Cannot parse sgx ecdsa quote", StatusCode: http.StatusBadRequest}
+		return SGXResponse{}, steps.fail(StepQuoteParse, &resourceError{Message: "Cannot parse sgx ecdsa quote",
+			StatusCode: http.StatusBadRequest})
 	}
-
-	pckCertBytes, err := utils.GetCertPemData(quoteObj.GetQuotePckCertObj())
-	if err != nil {
-		log.WithError(err).Error("Cannot extract PCK cert data")
-		return SGXResponse{}, &resourceError{Message: "Cannot extract PCK cert data",
-			StatusCode: http.StatusBadRequest}
-	}
-
-	certObj := parser.NewPCKCertObj(pckCertBytes)
-	if certObj == nil {
-		return SGXResponse{}, &resourceError{Message: "Invalid PCK Certificate Buffer", StatusCode: http.StatusBadRequest}
+	if err = clock.lap(StepQuoteParse); err != nil {
+		return SGXResponse{}, steps.fail(StepQuoteParse, err)
 	}
+	steps.pass(StepQuoteParse, "")
+	diag.lap(StepQuoteParse)
+	observeQuote(quoteObj, len(skcBlobParsed.GetQuoteBlob()))
 
 	sgxCaCert, err := readSGXRootCaCert()
 	if err != nil {
 		log.WithError(err).Error("Cannot read SGX CA Cert")
-		return SGXResponse{}, &resourceError{Message: "Cannot read SGX CA Cert",
-			StatusCode: http.StatusBadRequest}
+		return SGXResponse{}, steps.fail(StepPckChain, &resourceError{Message: "Cannot read SGX CA Cert",
+			StatusCode: http.StatusBadRequest})
 	}
 
-	err = verifier.VerifyPCKCertificate(quoteObj.GetQuotePckCertObj(), quoteObj.GetQuotePckCertInterCAList(),
-		quoteObj.GetQuotePckCertRootCAList(), certObj.GetPckCrlObj(), sgxCaCert)
-	if err != nil {
-		log.WithError(err).Error("Cannot verify pck cert")
-		return SGXResponse{}, &resourceError{Message: "Cannot verify pck cert",
-			StatusCode: http.StatusBadRequest}
-	}
+	tolerance := clockSkewTolerance()
+	pckChain := append(append([]*x509.Certificate{quoteObj.GetQuotePckCertObj()},
+		quoteObj.GetQuotePckCertInterCAList()...), quoteObj.GetQuotePckCertRootCAList()...)
+	notBefore, notAfter := certValidity(pckChain...)
+	pckAt, skew := skewedTime(at, notBefore, notAfter, tolerance)
+	var clockSkew *ClockSkew
+	clockSkew = clockSkew.add(skewCheckPckChain, skew)
 
-	log.Info("PCK Certificate Chain Verified")
-	err = verifier.VerifyPckCrl(certObj.GetPckCrlURL(), certObj.GetPckCrlObj(), certObj.GetPckCrlInterCaList(),
-		certObj.GetPckCrlRootCaList(), sgxCaCert)
+	certObj, err := chains.verify(quoteObj, sgxCaCert, now, pckAt, diag, clock)
 	if err != nil {
-		log.WithError(err).Error("Cannot verify PCK crl")
-		return SGXResponse{}, &resourceError{Message: "Cannot verify PCK crl",
-			StatusCode: http.StatusBadRequest}
+		return SGXResponse{}, steps.fail(StepPckChain, err)
+	}
+	if err = clock.lap(StepPckChain); err != nil {
+		return SGXResponse{}, steps.fail(StepPckChain, err)
+	}
+	steps.pass(StepPckChain, "")
+	steps.pass(StepCrlCheck, crlSourcesDetails(certObj.GetPckCrlSources()))
+	diag.lap(StepPckChain)
+	diag.pckCrls(certObj)
+	observeFmspc(certObj.GetFmspcValue())
+
+	var tcbObj *parser.TcbInfoStruct
+	var fallback *CollateralFallback
+	tcbInfoAt := now
+	if history != nil {
+		tcbObj, tcbInfoAt, err = history.tcbInfo(certObj.GetFmspcValue())
+	} else {
+		err = clock.fetch(StepTcbEvaluation, phaseCollateralFetch, func(ctx context.Context) error {
+			tcbObj, err = platformTcbInfo(ctx, certObj.GetFmspcValue(), certObj.GetPceIDValue(), now, diag)
+			return err
+		})
+		if err != nil {
+			var cached *verifiedCollateral
+			cached, tcbInfoAt, fallback, err = fallBackOnCollateral(fallback, collateralTcbInfo,
+				certObj.GetFmspcValue(), now, err)
+			if err == nil {
+				tcbObj = cached.tcbInfo
+			}
+		}
 	}
-
-	log.Info("PCK Certificates checked against PCK Certificate Revocation List")
-	tcbObj, err := parser.NewTcbInfo(certObj.GetFmspcValue())
 	if err != nil {
-		log.WithError(err).Error("Get TCB Info data parsing/fetch failed")
-		return SGXResponse{}, &resourceError{Message: "Get TCB Info data parsing/fetch failed",
-			StatusCode: http.StatusInternalServerError}
+		return SGXResponse{}, steps.fail(StepTcbEvaluation, err)
 	}
 
-	err = verifyTcbInfo(certObj, tcbObj, sgxCaCert)
+	tcbInfoSigner, skew, err := verifyTcbInfo(certObj, tcbObj, sgxCaCert, tcbInfoAt, tolerance)
 	if err != nil {
 		log.WithError(err).Error("TCBInfo Verification failed")
-		return SGXResponse{}, &resourceError{Message: "TCBInfo Verification failed",
-			StatusCode: http.StatusInternalServerError}
+		return SGXResponse{}, steps.fail(StepTcbEvaluation, &resourceError{Message: "TCBInfo Verification failed",
+			StatusCode: http.StatusInternalServerError})
+	}
+	if history == nil {
+		recordTcbInfo(tcbObj)
+		keepVerifiedTcbInfo(tcbObj, tcbInfoAt)
 	}
 
-	log.Info("TCBInfo Structure Verified")
+	clockSkew = clockSkew.add(skewCheckTcbInfo, skew)
+	log.Debug("TCBInfo Structure Verified")
 	tcbUptoDateStatus := tcbObj.GetTcbUptoDateStatus(certObj.GetPckCertTcbLevels())
-	log.Info("Current Tcb-Upto-Date Status is : ", tcbUptoDateStatus)
-
-	qeIDObj, err := parser.NewQeIdentity()
+	log.Debug("Current Tcb-Upto-Date Status is : ", tcbUptoDateStatus)
+	tcbStatusVerdict, err := evaluateTcbStatus(tcbUptoDateStatus)
 	if err != nil {
-		log.WithError(err).Error("QEIdentity Parsing failed")
-		return SGXResponse{}, &resourceError{Message: "QEIdentity Parsing failed",
-			StatusCode: http.StatusInternalServerError}
+		return SGXResponse{}, steps.fail(StepTcbEvaluation, err)
+	}
+	if err = clock.lap(StepTcbEvaluation); err != nil {
+		return SGXResponse{}, steps.fail(StepTcbEvaluation, err)
+	}
+	steps.pass(StepTcbEvaluation, "TCB status "+tcbUptoDateStatus+", verdict "+tcbStatusVerdict.Verdict)
+	diag.lap(StepTcbEvaluation)
+	diag.tcbInfo(tcbObj)
+
+	var qeIDObj *parser.QeIdentityData
+	qeIdentityAt := now
+	if history != nil {
+		qeIDObj, qeIdentityAt, err = history.qeIdentity()
+		if err != nil {
+			return SGXResponse{}, steps.fail(StepQeIdentity, err)
+		}
+	} else {
+		err = clock.fetch(StepQeIdentity, phaseCollateralFetch, func(ctx context.Context) error {
+			qeIDObj, err = parser.NewQeIdentity(ctx)
+			return err
+		})
+		if err != nil {
+			log.WithError(err).Error("QEIdentity Parsing failed")
+			var cached *verifiedCollateral
+			cached, qeIdentityAt, fallback, err = fallBackOnCollateral(fallback, collateralQeIdentity, "", now,
+				fetchFailure(err, "QEIdentity Parsing failed", http.StatusInternalServerError))
+			if err != nil {
+				return SGXResponse{}, steps.fail(StepQeIdentity, err)
+			}
+			qeIDObj = cached.qeIdentity
+		}
 	}
 
-	err = verifyQeIdentity(qeIDObj, quoteObj, sgxCaCert)
+	qeIdentitySigner, skew, err := verifyQeIdentity(qeIDObj, quoteObj, sgxCaCert, qeIdentityAt, tolerance)
 	if err != nil {
 		log.WithError(err).Error("verifyQeIdentity failed")
-		return SGXResponse{}, &resourceError{Message: "Verification of QeIdentity failed",
-			StatusCode: http.StatusInternalServerError}
+		return SGXResponse{}, steps.fail(StepQeIdentity, &resourceError{Message: "Verification of QeIdentity failed",
+			StatusCode: http.StatusInternalServerError})
+	}
+	if history == nil {
+		recordQeIdentity(qeIDObj)
+		keepVerifiedQeIdentity(qeIDObj, qeIdentityAt)
 	}
-	log.Info("QEIdentity Structure Verified")
+	clockSkew = clockSkew.add(skewCheckQeIdentity, skew)
+	log.Debug("QEIdentity Structure Verified")
+	if err = clock.lap(StepQeIdentity); err != nil {
+		return SGXResponse{}, steps.fail(StepQeIdentity, err)
+	}
+	steps.pass(StepQeIdentity, "")
+	diag.lap(StepQeIdentity)
+	diag.qeIdentity(qeIDObj)
 	hashMatched := false
 
 	if data.UserData != "" {
@@ -227,38 +411,69 @@ func SgxEcdsaQuoteVerify(data QuoteDataWithChallenge) (SGXResponse, error) {
 			log.Error(err.Error())
 		} else {
 			hashMatched = true
-			log.Info("User Data Hash matches with the one in quote")
+			log.Debug("User Data Hash matches with the one in quote")
 		}
 	}
 
 	repBlob, err := quoteObj.GetHeaderAndEnclaveReportBlob()
 	if err != nil {
 		log.WithError(err).Error("Invalid Header and Enclave Report Blob in SGX ECDSA Quote")
-		return SGXResponse{}, &resourceError{Message: "Invalid Header and Enclave Report Blob in SGX ECDSA Quote",
-			StatusCode: http.StatusInternalServerError}
+		return SGXResponse{}, steps.fail(StepQuoteSignature, &resourceError{
+			Message: "Invalid Header and Enclave Report Blob in SGX ECDSA Quote", StatusCode: http.StatusInternalServerError})
 	}
 
 	err = verifier.VerifyEnclaveReportSignature(quoteObj.GetEnclaveReportSignature(), repBlob, quoteObj.GetAttestationPublicKey())
 	if err != nil {
 		log.WithError(err).Error("Enclave Report Signature Verification failed")
-		return SGXResponse{}, &resourceError{Message: "Enclave Report Signature Verification failed",
-			StatusCode: http.StatusInternalServerError}
+		return SGXResponse{}, steps.fail(StepQuoteSignature, &resourceError{
+			Message: "Enclave Report Signature Verification failed", StatusCode: http.StatusInternalServerError,
+			QvResult: newQvResult(QvResultInvalidSignature)})
 	}
 
-	log.Info("Enclave Report Signature Verified")
+	log.Debug("Enclave Report Signature Verified")
+	if err = clock.lap(StepQuoteSignature); err != nil {
+		return SGXResponse{}, steps.fail(StepQuoteSignature, err)
+	}
+	steps.pass(StepQuoteSignature, "")
+	diag.lap(StepQuoteSignature)
 	qeBlob, err := quoteObj.GetQeReportBlob()
 	if err != nil {
 		log.Error(err.Error())
-		return SGXResponse{}, &resourceError{Message: "Invalid QE Report Blob in SGX ECDSA Quote",
-			StatusCode: http.StatusInternalServerError}
+		return SGXResponse{}, steps.fail(StepQeReportSignature, &resourceError{
+			Message: "Invalid QE Report Blob in SGX ECDSA Quote", StatusCode: http.StatusInternalServerError})
 	}
 	err = verifier.VerifyQeReportSignature(quoteObj.GetQeReportSignature(), qeBlob, certObj.GetPCKPublicKey())
 	if err != nil {
 		log.WithError(err).Error("QE Report Signature Verification failed")
-		return SGXResponse{}, &resourceError{Message: "QE Report Signature Verification failed",
-			StatusCode: http.StatusInternalServerError}
+		return SGXResponse{}, steps.fail(StepQeReportSignature, &resourceError{
+			Message: "QE Report Signature Verification failed", StatusCode: http.StatusInternalServerError,
+			QvResult: newQvResult(QvResultInvalidSignature)})
+	}
+	log.Debug("QE Report Signature Verified")
+	if err = clock.lap(StepQeReportSignature); err != nil {
+		return SGXResponse{}, steps.fail(StepQeReportSignature, err)
+	}
+	steps.pass(StepQeReportSignature, "")
+	diag.lap(StepQeReportSignature)
+
+	err = checkDebugEnclavePolicy(quoteObj.IsDebugEnclave())
+	if err != nil {
+		return SGXResponse{}, steps.fail(StepPolicy, err)
 	}
-	log.Info("QE Report Signature Verified")
+	pckExtensions := newPckCertExtensions(certObj)
+	err = checkPckPolicy(pckExtensions)
+	if err != nil {
+		return SGXResponse{}, steps.fail(StepPolicy, err)
+	}
+	err = checkEnclaveIdentityPolicy(&quoteObj.EnclaveReport)
+	if err != nil {
+		return SGXResponse{}, steps.fail(StepPolicy, err)
+	}
+	if err = clock.lap(StepPolicy); err != nil {
+		return SGXResponse{}, steps.fail(StepPolicy, err)
+	}
+	steps.pass(StepPolicy, "")
+	diag.lap(StepPolicy)
 
 	var resp SGXResponse
 	resp.Message = "SGX_QL_QV_RESULT_OK"
@@ -271,12 +486,52 @@ func SgxEcdsaQuoteVerify(data QuoteDataWithChallenge) (SGXResponse, error) {
 	resp.EnclaveMeasurement = fmt.Sprintf("%02x", quoteObj.EnclaveReport.MrEnclave)
 	resp.IsvSvn = fmt.Sprintf("%02x", quoteObj.EnclaveReport.SgxIsvSvn)
 	resp.TcbLevel = tcbUptoDateStatus
-
-	log.Info("Sgx Ecdsa Quote Verification completed")
+	resp.QvResult = tcbStatusQvResult(tcbUptoDateStatus)
+	resp.TcbStatusVerdict = tcbStatusVerdict
+	resp.EnclaveDebugMode = quoteObj.IsDebugEnclave()
+	resp.KSS = newKssFields(&quoteObj.EnclaveReport)
+	resp.SupplementalData = newSupplementalData(certObj, tcbObj, qeIDObj, sgxCaCert)
+	resp.QuoteHashes = NewQuoteHashes(skcBlobParsed.GetQuoteBlob())
+	resp.PckExtensions = pckExtensions
+	resp.Constraints = data.Constraints.evaluate(&quoteObj.EnclaveReport, pckExtensions)
+	resp.ReportDataBinding = data.ReportDataBinding.evaluate(quoteObj.EnclaveReport.ReportData[:])
+	resp.EvaluationTime = at.Format(time.RFC3339)
+	resp.ClockSkew = clockSkew
+	resp.Steps = steps
+	resp.CollateralSigners = []CollateralSigner{*tcbInfoSigner, *qeIdentitySigner}
+	resp.CollateralFallback = fallback
+	if history != nil {
+		resp.TcbEvaluationDate = data.TcbEvaluationDate
+		resp.CollateralVersion = tcbObj.TcbInfoData.TcbInfo.TcbEvaluationDataNumber
+	}
+	setResultExpiry(&resp, at, 0)
+	redactPlatformIdentifiers(&resp, data.PlatformIdentifiers)
+
+	// re-evaluations against past collateral do not reflect the current TCB status of the platform
+	if conf := config.Global(); conf != nil && conf.EnableTcbDowngradeDetection && history == nil {
+		trackPlatformTcbStatus(certObj.GetPPIDValue(), certObj.GetFmspcValue(), tcbUptoDateStatus)
+	}
+
+	log.Debug("Sgx Ecdsa Quote Verification completed")
 
 	return resp, nil
 }
 
+// checkDebugEnclavePolicy rejects debug mode enclaves unless they are explicitly allowed in the configuration,
+// the memory of a debug enclave can be inspected so its attestation cannot be trusted in production
+func checkDebugEnclavePolicy(debug bool) error {
+	if !debug {
+		return nil
+	}
+	if conf := config.Global(); conf == nil || !conf.AllowDebugEnclaves {
+		slog.Error("resource/quote_verifier_ops: checkDebugEnclavePolicy() Enclave is in debug mode and debug enclaves are not allowed")
+		return &resourceError{Message: "Enclave is in debug mode and debug enclaves are not allowed",
+			StatusCode: http.StatusBadRequest}
+	}
+	slog.Warn("resource/quote_verifier_ops: checkDebugEnclavePolicy() Accepting quote from an enclave in debug mode")
+	return nil
+}
+
 func verifyQeIdentityReport(qeIdObj *parser.QeIdentityData, quoteObj *parser.SgxQuoteParsed) error {
 	log.Trace("resource/quote_verifier_ops:verifyQeIdentityReport() Entering")
 	log.Trace("resource/quote_verifier_ops:verifyQeIdentityReport() Leaving")
@@ -308,68 +563,114 @@ func verifyQeIdentityReport(qeIdObj *parser.QeIdentityData, quoteObj *parser.Sgx
 	return nil
 }
 
+// verifyQeIdentity verifies the QE identity is signed by the TCB signing CA and its certificate chain is valid at
+// the current trusted time, moved within the clock skew tolerance if needed. It returns the signer and the skew.
 func verifyQeIdentity(qeIDObj *parser.QeIdentityData, quoteObj *parser.SgxQuoteParsed,
-	trustedRootCA *x509.Certificate) error {
+	trustedRootCA *x509.Certificate, now time.Time, tolerance time.Duration) (*CollateralSigner, time.Duration, error) {
 	log.Trace("resource/quote_verifier_ops:verifyQeIdentity() Entering")
 	log.Trace("resource/quote_verifier_ops:verifyQeIdentity() Leaving")
 
 	if qeIDObj == nil || quoteObj == nil {
-		return errors.New("verifyQeIdentity: QEIdentity/Quote Object is empty")
+		return nil, 0, errors.New("verifyQeIdentity: QEIdentity/Quote Object is empty")
 	}
+	notBefore, notAfter := certValidity(append(qeIDObj.GetQeInfoInterCaList(), qeIDObj.GetQeInfoRootCaList()...)...)
+	notBefore, notAfter = intersectValidity(notBefore, notAfter, parseCollateralDate(qeIDObj.GetQeIDIssueDate()),
+		parseCollateralDate(qeIDObj.GetQeIDNextUpdate()))
+	now, skew := skewedTime(now, notBefore, notAfter, tolerance)
+
 	err := verifier.VerifyQeIDCertChain(qeIDObj.GetQeInfoInterCaList(), qeIDObj.GetQeInfoRootCaList(),
-		trustedRootCA)
+		trustedRootCA, now)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "verifyQeIdentity: VerifyQeIDCertChain")
+	}
+
+	signer, err := verifier.VerifyCollateralSignature(qeIDObj.RawBlob, verifier.CollateralQeIdentity,
+		qeIDObj.QEJson.Signature, qeIDObj.GetQeInfoInterCaList())
 	if err != nil {
-		return errors.Wrap(err, "verifyQeIdentity: VerifyQeIDCertChain")
+		return nil, 0, errors.Wrap(err, "verifyQeIdentity: VerifyCollateralSignature")
 	}
 
 	status := qeIDObj.GetQeIdentityStatus()
 	if !status {
-		return errors.New("verifyQeIdentity: GetQeIdentityStatus is invalid")
+		return nil, 0, errors.New("verifyQeIdentity: GetQeIdentityStatus is invalid")
 	}
 
-	if !utils.CheckDate(qeIDObj.GetQeIDIssueDate(), qeIDObj.GetQeIDNextUpdate()) {
-		return errors.New("verifyQeIdentity: Date Check validation failed")
+	if !utils.CheckDate(qeIDObj.GetQeIDIssueDate(), qeIDObj.GetQeIDNextUpdate(), now) {
+		return nil, 0, errors.New("verifyQeIdentity: Date Check validation failed")
 	}
 
-	return verifyQeIdentityReport(qeIDObj, quoteObj)
+	return newCollateralSigner(collateralQeIdentity, signer, trustedRootCA), skew,
+		verifyQeIdentityReport(qeIDObj, quoteObj)
 }
 
-func verifyTcbInfo(certObj *parser.PckCert, tcbObj *parser.TcbInfoStruct, trustedRootCA *x509.Certificate) error {
+// verifyTcbInfo verifies the TCB info is signed by the TCB signing CA and its certificate chain is valid at the
+// current trusted time, moved within the clock skew tolerance if needed. It returns the signer and the skew.
+func verifyTcbInfo(certObj *parser.PckCert, tcbObj *parser.TcbInfoStruct, trustedRootCA *x509.Certificate,
+	now time.Time, tolerance time.Duration) (*CollateralSigner, time.Duration, error) {
 	log.Trace("resource/quote_verifier_ops:verifyTcbInfo() Entering")
 	log.Trace("resource/quote_verifier_ops:verifyTcbInfo() Leaving")
 
 	if tcbObj.GetTcbInfoFmspc() != certObj.GetFmspcValue() {
-		return errors.New("verifyTcbInfo: FMSPC in TCBInfoStruct does not match with PCK Cert FMSPC")
+		return nil, 0, errors.New("verifyTcbInfo: FMSPC in TCBInfoStruct does not match with PCK Cert FMSPC")
 	}
 
+	notBefore, notAfter := certValidity(append(tcbObj.GetTcbInfoInterCaList(), tcbObj.GetTcbInfoRootCaList()...)...)
+	notBefore, notAfter = intersectValidity(notBefore, notAfter, parseCollateralDate(tcbObj.GetTcbInfoIssueDate()),
+		parseCollateralDate(tcbObj.GetTcbInfoNextUpdate()))
+	now, skew := skewedTime(now, notBefore, notAfter, tolerance)
+
 	err := verifier.VerifyTcbInfoCertChain(tcbObj.GetTcbInfoInterCaList(), tcbObj.GetTcbInfoRootCaList(),
-		trustedRootCA)
+		trustedRootCA, now)
 	if err != nil {
-		return errors.Wrap(err, "verifyTcbInfo: failed to verify Tcbinfo Certchain")
+		return nil, 0, errors.Wrap(err, "verifyTcbInfo: failed to verify Tcbinfo Certchain")
 	}
 
-	if !utils.CheckDate(tcbObj.GetTcbInfoIssueDate(), tcbObj.GetTcbInfoNextUpdate()) {
-		return errors.New("verifyTcbInfo: Date Check validation failed")
+	signer, err := verifier.VerifyCollateralSignature(tcbObj.RawBlob, verifier.CollateralTcbInfo,
+		tcbObj.TcbInfoData.Signature, tcbObj.GetTcbInfoInterCaList())
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "verifyTcbInfo: VerifyCollateralSignature")
 	}
 
-	return nil
+	if !utils.CheckDate(tcbObj.GetTcbInfoIssueDate(), tcbObj.GetTcbInfoNextUpdate(), now) {
+		return nil, 0, errors.New("verifyTcbInfo: Date Check validation failed")
+	}
+
+	return newCollateralSigner(collateralTcbInfo, signer, trustedRootCA), skew, nil
+}
+
+// sgxRootCAOverride replaces the trusted SGX root CA of constants.TrustedSGXRootCAFile when set
+var sgxRootCAOverride *x509.Certificate
+
+// SetSGXRootCA replaces the trusted SGX root CA quotes are verified against, for conformance runs against test
+// vectors issued by a test root CA. The installed root CA is trusted again when cert is nil.
+func SetSGXRootCA(cert *x509.Certificate) {
+	sgxRootCAOverride = cert
 }
 
 func readSGXRootCaCert() (*x509.Certificate, error) {
 	log.Trace("resource/quote_verifier_ops:readSGXRootCaCert() Entering")
 	log.Trace("resource/quote_verifier_ops:readSGXRootCaCert() Leaving")
 
-	certBytes, err := ioutil.ReadFile(constants.TrustedSGXRootCAFile)
-	if err != nil {
-		return nil, errors.Wrap(err, "readSGXRootCaCert: error reading SGX CA certificate")
-	}
-	pemBlock, _ := pem.Decode(certBytes)
-	if pemBlock == nil {
-		return nil, errors.New("readSGXRootCaCert: Pem Decode error")
+	x509Cert := sgxRootCAOverride
+	if x509Cert == nil {
+		certBytes, err := ioutil.ReadFile(constants.TrustedSGXRootCAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "readSGXRootCaCert: error reading SGX CA certificate")
+		}
+		pemBlock, _ := pem.Decode(certBytes)
+		if pemBlock == nil {
+			return nil, errors.New("readSGXRootCaCert: Pem Decode error")
+		}
+		x509Cert, err = x509.ParseCertificate(pemBlock.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "readSGXRootCaCert: error parsing SGX CA certificate")
+		}
 	}
-	x509Cert, err := x509.ParseCertificate(pemBlock.Bytes)
-	if err != nil {
-		return nil, errors.Wrap(err, "readSGXRootCaCert: error parsing SGX CA certificate")
+	if conf := config.Global(); conf != nil {
+		err := verifier.VerifyRootKeyPin(x509Cert, conf.SGXRootKeyPins)
+		if err != nil {
+			return nil, errors.Wrap(err, "readSGXRootCaCert: SGX CA certificate is not pinned")
+		}
 	}
 
 	return x509Cert, nil
@@ -8,19 +8,21 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	commLogMsg "intel/isecl/lib/common/v4/log/message"
+	"intel/isecl/sqvs/v4/claims"
 	"intel/isecl/sqvs/v4/config"
 	"intel/isecl/sqvs/v4/constants"
-	"intel/isecl/sqvs/v4/resource/utils"
-	"io/ioutil"
+	"intel/isecl/sqvs/v4/trustedtime"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 )
 
 func QuoteVerifyCBAndSign(router *mux.Router) {
-	router.Handle("/sgx_qv_verify_quote", handlers.ContentTypeHandler(sgxVerifyQuoteAndSign(), "application/json")).Methods("POST")
+	router.Handle("/sgx_qv_verify_quote", handlers.ContentTypeHandler(sgxVerifyQuoteAndSign(), quoteContentTypes...)).Methods("POST")
 }
 
 func sgxVerifyQuoteAndSign() errorHandlerFunc {
@@ -40,30 +42,88 @@ func sgxVerifyQuoteAndSign() errorHandlerFunc {
 			}
 		}
 
-		var data QuoteDataWithChallenge
-		if r.ContentLength == 0 {
-			slog.Error("resource/quote_verifier_ops: sgxVerifyQuoteAndSign() The request body was not provided")
-			return &resourceError{Message: "SGX_QL_ERROR_INVALID_PARAMETER", StatusCode: http.StatusBadRequest}
+		data, err := decodeQuoteRequest(w, r, true)
+		if err != nil {
+			return err
+		}
+		data.Debug, err = debugRequested(r)
+		if err != nil {
+			return err
+		}
+		data.PlatformIdentifiers, err = platformIdentifiersRequested(r)
+		if err != nil {
+			return err
 		}
-		dec := json.NewDecoder(r.Body)
-		dec.DisallowUnknownFields()
-		err := dec.Decode(&data)
+		err = checkKeyRelease(data.KeyRelease)
 		if err != nil {
-			slog.WithError(err).Errorf("resource/quote_verifier_ops: sgxVerifyQuoteAndSign() %s:Failed to decode "+
-				"request body", commLogMsg.InvalidInputBadEncoding)
-			return &resourceError{Message: "Invalid JSON input provided", StatusCode: http.StatusBadRequest}
+			return err
+		}
+		err = checkSigningAlgorithm(data.SigningAlgorithm)
+		if err != nil {
+			return err
+		}
+		var session *openSession
+		if token := strings.TrimSpace(data.SessionToken); token != "" {
+			if !conf.FeatureEnabled(config.FeatureVerificationSessions) {
+				return &resourceError{Message: "Feature " + config.FeatureVerificationSessions + " is not enabled",
+					StatusCode: http.StatusBadRequest}
+			}
+			subject, _ := tokenClaim(r, "sub").(string)
+			session, err = consumeSession(token, subject)
+			if err != nil {
+				return err
+			}
+			err = bindSession(&data, session)
+			if err != nil {
+				return err
+			}
 		}
 
-		sgxResponse, err := SgxEcdsaQuoteVerify(data)
+		sgxResponse, err := SgxEcdsaQuoteVerify(r.Context(), data)
+		if session != nil {
+			// the result is tied to the session, a quote not bound to the session nonce fails it
+			sgxResponse.SessionID = session.id
+			if err == nil {
+				err = checkSessionBound(&sgxResponse)
+			}
+		}
+		recordVerification(callerOf(r), data.QuoteBlob, sgxResponse, err)
+		if r.Context().Err() != nil {
+			// the client is gone, neither release a key nor sign a response it will never read
+			return clientClosedRequest()
+		}
+		if err == nil {
+			// the key is only released to enclaves whose quote is verified
+			rerr := releaseKey(r.Context(), data, &sgxResponse)
+			if rerr != nil {
+				return rerr
+			}
+		}
 
 		var quoteResponseBytes []byte
 		if strings.TrimSpace(data.Challenge) != "" && conf.SignQuoteResponse {
 			if err != nil {
 				sgxResponse.Message = err.Error()
 			}
-			log.Info("SgxEcdsaQuoteVerify: Signing the quote response")
+			log.Debug("SgxEcdsaQuoteVerify: Signing the quote response")
 			sgxResponse.Quote = data.QuoteBlob
 			sgxResponse.Challenge = data.Challenge
+			issuedAt, terr := trustedtime.Now()
+			if terr != nil {
+				log.WithError(terr).Error("Error reading the trusted time")
+				return &resourceError{Message: "Error reading the trusted time", StatusCode: http.StatusInternalServerError}
+			}
+			sgxResponse.IssuedAt = issuedAt.UTC().Format(time.RFC3339)
+			if err == nil {
+				sgxResponse.CustomClaims, err = evaluateCustomClaims(sgxResponse)
+				if err != nil {
+					return err
+				}
+				err = applyResultPolicy(&sgxResponse, data.Policy, issuedAt)
+				if err != nil {
+					return err
+				}
+			}
 
 			dataBytes, err := json.Marshal(QuoteInfo(sgxResponse))
 			if err != nil {
@@ -71,23 +131,18 @@ func sgxVerifyQuoteAndSign() errorHandlerFunc {
 					err.Error(), StatusCode: http.StatusInternalServerError}
 			}
 
-			signature, err := utils.GenerateSignature([]byte(base64.StdEncoding.EncodeToString(dataBytes)), constants.PrivateKeyLocation, conf.UsePSSPadding)
-			if err != nil {
-				return &resourceError{Message: "Failed to get signature for QVL response: " + err.Error(),
-					StatusCode: http.StatusInternalServerError}
-			}
-
-			certChain, err := ioutil.ReadFile(constants.PublicKeyLocation)
+			signature, signingKey, alg, err := signWithAlgorithm([]byte(base64.StdEncoding.EncodeToString(dataBytes)),
+				data.SigningAlgorithm)
 			if err != nil {
-				log.WithError(err).Error("Error reading signing public key from file")
-				return &resourceError{Message: "Error reading signing public key from file",
-					StatusCode: http.StatusInternalServerError}
+				return err
 			}
 
 			quoteResponseBytes, err = json.Marshal(SignedSGXResponse{
 				QuoteData:        base64.StdEncoding.EncodeToString(dataBytes),
 				Signature:        signature,
-				CertificateChain: string(certChain),
+				CertificateChain: string(signingKey.CertChain),
+				KeyID:            signingKey.ID,
+				Algorithm:        alg,
 			})
 			if err != nil {
 				log.WithError(err).Error("Error marshalling signed SGX response in JSON")
@@ -118,3 +173,52 @@ func sgxVerifyQuoteAndSign() errorHandlerFunc {
 		return nil
 	}
 }
+
+// applyResultPolicy scopes and times the signed result by the result policy the request named or, when it
+// named none, the first one applying to the enclave
+func applyResultPolicy(resp *SGXResponse, name string, issuedAt time.Time) error {
+	policy, err := customClaims.ResultPolicy(name, claimsResult(*resp))
+	if errors.Cause(err) == claims.ErrUnknownResultPolicy {
+		slog.WithError(err).Errorf("resource/quote_verifier_ops_v2:applyResultPolicy() %s: Invalid result policy %s",
+			commLogMsg.InvalidInputBadParam, name)
+		return &resourceError{Message: "Unknown result policy " + name, StatusCode: http.StatusBadRequest}
+	}
+	if err != nil {
+		return err
+	}
+	var ttl time.Duration
+	if policy != nil {
+		resp.ResultPolicy = policy.Name
+		resp.Issuer = policy.Issuer
+		resp.Audience = policy.Audience
+		ttl = policy.TTL
+	}
+	// exp and ExpiresAt are the same time, the earliest of the collateral next update, the result validity
+	// and the policy TTL
+	if expiresAt := setResultExpiry(resp, issuedAt, ttl); !expiresAt.IsZero() {
+		resp.Expiry = expiresAt.Unix()
+	}
+	return nil
+}
+
+func claimsResult(resp SGXResponse) claims.Result {
+	return claims.Result{
+		EnclaveMeasurement:  resp.EnclaveMeasurement,
+		EnclaveIssuer:       resp.EnclaveIssuer,
+		EnclaveIssuerProdID: resp.EnclaveIssuerProdID,
+		IsvSvn:              resp.IsvSvn,
+		TcbLevel:            resp.TcbLevel,
+		EnclaveDebugMode:    resp.EnclaveDebugMode,
+		ReportData:          resp.ReportData,
+	}
+}
+
+// evaluateCustomClaims returns the custom claims of the policy that apply to the verified enclave
+func evaluateCustomClaims(resp SGXResponse) (map[string]string, error) {
+	values, err := customClaims.Evaluate(claimsResult(resp))
+	if err != nil {
+		log.WithError(err).Error("Error evaluating custom claims")
+		return nil, &resourceError{Message: "Error evaluating custom claims", StatusCode: http.StatusInternalServerError}
+	}
+	return values, nil
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"intel/isecl/sqvs/v4/resource/parser"
+)
+
+// Quote verification results of sgx_ql_qv_result_t, as returned by sgx_qv_verify_quote of
+// libsgx_dcap_quoteverify
+const (
+	QvResultOK                         = "SGX_QL_QV_RESULT_OK"
+	QvResultConfigNeeded               = "SGX_QL_QV_RESULT_CONFIG_NEEDED"
+	QvResultOutOfDate                  = "SGX_QL_QV_RESULT_OUT_OF_DATE"
+	QvResultOutOfDateConfigNeeded      = "SGX_QL_QV_RESULT_OUT_OF_DATE_CONFIG_NEEDED"
+	QvResultInvalidSignature           = "SGX_QL_QV_RESULT_INVALID_SIGNATURE"
+	QvResultRevoked                    = "SGX_QL_QV_RESULT_REVOKED"
+	QvResultUnspecified                = "SGX_QL_QV_RESULT_UNSPECIFIED"
+	QvResultSWHardeningNeeded          = "SGX_QL_QV_RESULT_SW_HARDENING_NEEDED"
+	QvResultConfigAndSWHardeningNeeded = "SGX_QL_QV_RESULT_CONFIG_AND_SW_HARDENING_NEEDED"
+)
+
+var qvResultCodes = map[string]uint32{
+	QvResultOK:                         0x0000,
+	QvResultConfigNeeded:               0xA001,
+	QvResultOutOfDate:                  0xA002,
+	QvResultOutOfDateConfigNeeded:      0xA003,
+	QvResultInvalidSignature:           0xA004,
+	QvResultRevoked:                    0xA005,
+	QvResultUnspecified:                0xA006,
+	QvResultSWHardeningNeeded:          0xA007,
+	QvResultConfigAndSWHardeningNeeded: 0xA008,
+}
+
+var tcbStatusQvResults = map[string]string{
+	parser.TcbStatusUpToDate:                          QvResultOK,
+	parser.TcbStatusSWHardeningNeeded:                 QvResultSWHardeningNeeded,
+	parser.TcbStatusConfigurationNeeded:               QvResultConfigNeeded,
+	parser.TcbStatusConfigurationAndSWHardeningNeeded: QvResultConfigAndSWHardeningNeeded,
+	parser.TcbStatusOutOfDate:                         QvResultOutOfDate,
+	parser.TcbStatusOutOfDateConfigurationNeeded:      QvResultOutOfDateConfigNeeded,
+	parser.TcbStatusRevoked:                           QvResultRevoked,
+}
+
+// QvResult is the sgx_ql_qv_result_t equivalent of a verification, so clients ported from local quote
+// verification keep switching on the same values. Code is the numeric value of the enumerator Name.
+type QvResult struct {
+	Code uint32 `json:"Code"`
+	Name string `json:"Name"`
+}
+
+func newQvResult(name string) *QvResult {
+	code, ok := qvResultCodes[name]
+	if !ok {
+		name = QvResultUnspecified
+		code = qvResultCodes[name]
+	}
+	return &QvResult{Code: code, Name: name}
+}
+
+// tcbStatusQvResult returns the result sgx_qv_verify_quote reports for a platform of the TCB status
+func tcbStatusQvResult(tcbStatus string) *QvResult {
+	return newQvResult(tcbStatusQvResults[tcbStatus])
+}
@@ -0,0 +1,23 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"intel/isecl/sqvs/v4/resource/parser"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTcbStatusQvResult(t *testing.T) {
+	assert.Equal(t, &QvResult{Code: 0, Name: QvResultOK}, tcbStatusQvResult(parser.TcbStatusUpToDate))
+	assert.Equal(t, &QvResult{Code: 0xA007, Name: QvResultSWHardeningNeeded},
+		tcbStatusQvResult(parser.TcbStatusSWHardeningNeeded))
+	assert.Equal(t, &QvResult{Code: 0xA008, Name: QvResultConfigAndSWHardeningNeeded},
+		tcbStatusQvResult(parser.TcbStatusConfigurationAndSWHardeningNeeded))
+	assert.Equal(t, &QvResult{Code: 0xA003, Name: QvResultOutOfDateConfigNeeded},
+		tcbStatusQvResult(parser.TcbStatusOutOfDateConfigurationNeeded))
+	assert.Equal(t, &QvResult{Code: 0xA006, Name: QvResultUnspecified}, tcbStatusQvResult("Unknown"))
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/json"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/keystore"
+	"intel/isecl/sqvs/v4/logformat"
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/types"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// rawQuoteEncrypter encrypts the raw quotes retained with the verification history, none are retained
+// without it
+var rawQuoteEncrypter keystore.Encrypter
+
+// SetRawQuoteEncrypter sets the encrypter of the raw quotes retained with the verification history
+func SetRawQuoteEncrypter(encrypter keystore.Encrypter) {
+	rawQuoteEncrypter = encrypter
+}
+
+// RawQuoteResponse is a raw quote retained with the verification history, base64 encoded
+type RawQuoteResponse struct {
+	ID    string `json:"id"`
+	Quote string `json:"quote"`
+}
+
+// retainsRawQuote tells whether the raw quote of the caller is kept with its verification. The quotes of
+// re-verifications are kept along with the quote of the verification they follow up.
+func retainsRawQuote(conf *config.Configuration, caller verificationCaller, reverifiedFrom string) bool {
+	if !conf.RetainRawQuotes || rawQuoteEncrypter == nil {
+		return false
+	}
+	if reverifiedFrom != "" || len(conf.RawQuotes.Tenants) == 0 {
+		return true
+	}
+	for _, tenant := range conf.RawQuotes.Tenants {
+		if tenant == caller.Tenant {
+			return true
+		}
+	}
+	return false
+}
+
+// sealRawQuote encrypts the raw quote of a verification, bound to the verification
+func sealRawQuote(id, quote string) (string, error) {
+	return rawQuoteEncrypter.Encrypt([]byte(quote), []byte(id))
+}
+
+// openRawQuote decrypts the raw quote retained with the verification
+func openRawQuote(verification *types.Verification) (string, error) {
+	if rawQuoteEncrypter == nil {
+		return "", &resourceError{Message: "Raw quote encryption key is not configured",
+			StatusCode: http.StatusServiceUnavailable}
+	}
+	quote, err := rawQuoteEncrypter.Decrypt(verification.Quote, []byte(verification.ID))
+	if err != nil {
+		log.WithError(err).Error("resource/raw_quotes:openRawQuote() Error decrypting raw quote")
+		return "", &resourceError{Message: "Error decrypting raw quote", StatusCode: http.StatusInternalServerError}
+	}
+	return string(quote), nil
+}
+
+// retrieveRawQuote returns the decrypted raw quote of a verification to the holders of the QuoteAuditor role
+// only, each retrieval being a security event
+func retrieveRawQuote() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/raw_quotes:retrieveRawQuote() Entering")
+		defer log.Trace("resource/raw_quotes:retrieveRawQuote() Leaving")
+
+		conf := config.Global()
+		if conf == nil {
+			return &resourceError{Message: "Could not read config", StatusCode: http.StatusInternalServerError}
+		}
+		if conf.IncludeToken {
+			err := AuthorizeEndpoint(r, constants.QuoteAuditorGroupName, true)
+			if err != nil {
+				return err
+			}
+		}
+		if !conf.EnableVerificationHistory || sqvsDB == nil {
+			return &resourceError{Message: "Verification history is not enabled", StatusCode: http.StatusNotFound}
+		}
+
+		id := mux.Vars(r)["id"]
+		verification, err := sqvsDB.VerificationRepository().Retrieve(id)
+		if err == repository.ErrRecordNotFound {
+			return &resourceError{Message: "Verification not found", StatusCode: http.StatusNotFound}
+		} else if err != nil {
+			log.WithError(err).Error("resource/raw_quotes:retrieveRawQuote() Error retrieving verification")
+			return &resourceError{Message: "Error retrieving verification", StatusCode: http.StatusInternalServerError}
+		}
+		if verification.Quote == "" {
+			return &resourceError{Message: "Raw quote of verification " + id + " was not retained",
+				StatusCode: http.StatusNotFound}
+		}
+		quote, err := openRawQuote(verification)
+		if err != nil {
+			return err
+		}
+		slog.WithFields(logrus.Fields{
+			logformat.EventField:   logformat.EventVerification,
+			logformat.OutcomeField: logformat.OutcomeSuccess,
+			"verification":         id,
+			"caller":               callerOf(r).Address,
+		}).Infof("resource/raw_quotes:retrieveRawQuote() Raw quote of verification %s retrieved", id)
+
+		body, err := json.Marshal(RawQuoteResponse{ID: id, Quote: quote})
+		if err != nil {
+			return &resourceError{Message: "Error marshalling raw quote in JSON", StatusCode: http.StatusInternalServerError}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(body)
+		if err != nil {
+			return &resourceError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+		return nil
+	}
+}
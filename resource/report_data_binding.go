@@ -0,0 +1,143 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	commLogMsg "intel/isecl/lib/common/v4/log/message"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"net/http"
+	"strings"
+)
+
+const (
+	bindingHashSHA256 = "sha256"
+	bindingHashSHA384 = "sha384"
+	bindingHashSHA512 = "sha512"
+	// bindingHashNone binds the concatenated inputs as they are
+	bindingHashNone = "none"
+)
+
+// ReportDataBinding declares how the enclave built the report data of the quote from the inputs it binds,
+// such as a nonce and the public key of the enclave: the digest of the concatenation of the base64 encoded
+// Inputs with Hash, placed at the start of the report data. When ZeroPadded is set the report data bytes
+// following the digest must be zero.
+type ReportDataBinding struct {
+	Hash       string         `json:"hash"`
+	Inputs     []BindingInput `json:"inputs"`
+	ZeroPadded bool           `json:"zeroPadded,omitempty"`
+}
+
+// BindingInput is an input bound in the report data, Name only labels the input in logs
+type BindingInput struct {
+	Name  string `json:"name,omitempty"`
+	Value string `json:"value"`
+}
+
+// ReportDataBindingResult is the verdict of the report data binding of the request
+type ReportDataBindingResult struct {
+	Hash     string `json:"Hash"`
+	Expected string `json:"Expected"`
+	Actual   string `json:"Actual"`
+	Bound    bool   `json:"Bound"`
+}
+
+// parseReportDataBinding decodes the JSON report data binding passed as a query parameter or form field
+func parseReportDataBinding(value string) (*ReportDataBinding, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var binding ReportDataBinding
+	dec := json.NewDecoder(strings.NewReader(value))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&binding)
+	if err != nil {
+		slog.WithError(err).Errorf("resource/report_data_binding:parseReportDataBinding() %s: Failed to decode "+
+			"report data binding", commLogMsg.InvalidInputBadEncoding)
+		return nil, &resourceError{Message: "Invalid report data binding provided", StatusCode: http.StatusBadRequest}
+	}
+	return &binding, nil
+}
+
+// expected returns the report data prefix the binding requires
+func (b *ReportDataBinding) expected() ([]byte, error) {
+	var h hash.Hash
+	switch strings.ToLower(b.Hash) {
+	case bindingHashSHA256:
+		h = sha256.New()
+	case bindingHashSHA384:
+		h = sha512.New384()
+	case bindingHashSHA512:
+		h = sha512.New()
+	case bindingHashNone:
+	default:
+		slog.Errorf("resource/report_data_binding:expected() %s: Unsupported report data binding hash %q",
+			commLogMsg.InvalidInputBadParam, b.Hash)
+		return nil, &resourceError{Message: "Unsupported report data binding hash, must be one of sha256, " +
+			"sha384, sha512 or none", StatusCode: http.StatusBadRequest}
+	}
+	if len(b.Inputs) == 0 {
+		return nil, &resourceError{Message: "Report data binding requires inputs", StatusCode: http.StatusBadRequest}
+	}
+
+	var inputs []byte
+	for _, input := range b.Inputs {
+		value, err := base64.StdEncoding.DecodeString(input.Value)
+		if err != nil {
+			slog.WithError(err).Errorf("resource/report_data_binding:expected() %s: Invalid report data binding "+
+				"input %s", commLogMsg.InvalidInputBadEncoding, input.Name)
+			return nil, &resourceError{Message: "Invalid report data binding input " + input.Name,
+				StatusCode: http.StatusBadRequest}
+		}
+		inputs = append(inputs, value...)
+	}
+	if h == nil {
+		if len(inputs) > parser.ReportDataSize {
+			return nil, &resourceError{Message: "Report data binding inputs are larger than the report data",
+				StatusCode: http.StatusBadRequest}
+		}
+		return inputs, nil
+	}
+	_, _ = h.Write(inputs)
+	return h.Sum(nil), nil
+}
+
+// validate checks the binding is well formed before the quote is verified
+func (b *ReportDataBinding) validate() error {
+	if b == nil {
+		return nil
+	}
+	_, err := b.expected()
+	return err
+}
+
+// evaluate checks the report data of a verified quote binds the inputs
+func (b *ReportDataBinding) evaluate(reportData []byte) *ReportDataBindingResult {
+	if b == nil {
+		return nil
+	}
+	// validate ensured the binding is well formed
+	expected, _ := b.expected()
+	bound := bytes.Equal(expected, reportData[:len(expected)])
+	if b.ZeroPadded {
+		bound = bound && bytes.Count(reportData[len(expected):], []byte{0}) == len(reportData)-len(expected)
+		expected = append(expected, make([]byte, len(reportData)-len(expected))...)
+	}
+	if !bound {
+		log.Info("resource/report_data_binding:evaluate() Report data does not bind the inputs of the request")
+	}
+	return &ReportDataBindingResult{
+		Hash:     strings.ToLower(b.Hash),
+		Expected: hex.EncodeToString(expected),
+		Actual:   hex.EncodeToString(reportData),
+		Bound:    bound,
+	}
+}
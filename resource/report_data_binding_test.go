@@ -0,0 +1,57 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportDataBindingEvaluate(t *testing.T) {
+	nonce, publicKey := []byte("nonce"), []byte("public key")
+	digest := sha512.Sum512(append(append([]byte{}, nonce...), publicKey...))
+	reportData := make([]byte, parser.ReportDataSize)
+	copy(reportData, digest[:])
+
+	binding := &ReportDataBinding{Hash: "SHA512", Inputs: []BindingInput{
+		{Name: "nonce", Value: base64.StdEncoding.EncodeToString(nonce)},
+		{Name: "publicKey", Value: base64.StdEncoding.EncodeToString(publicKey)},
+	}}
+	assert.NoError(t, binding.validate())
+	result := binding.evaluate(reportData)
+	assert.True(t, result.Bound)
+	assert.Equal(t, "sha512", result.Hash)
+
+	// the inputs are bound in order
+	binding.Inputs[0], binding.Inputs[1] = binding.Inputs[1], binding.Inputs[0]
+	assert.False(t, binding.evaluate(reportData).Bound)
+
+	binding = &ReportDataBinding{Hash: "none", ZeroPadded: true, Inputs: []BindingInput{
+		{Value: base64.StdEncoding.EncodeToString([]byte{0xde, 0xad})},
+	}}
+	reportData = make([]byte, parser.ReportDataSize)
+	copy(reportData, []byte{0xde, 0xad})
+	assert.True(t, binding.evaluate(reportData).Bound)
+	reportData[parser.ReportDataSize-1] = 1
+	assert.False(t, binding.evaluate(reportData).Bound)
+
+	var noBinding *ReportDataBinding
+	assert.NoError(t, noBinding.validate())
+	assert.Nil(t, noBinding.evaluate(reportData))
+}
+
+func TestReportDataBindingValidate(t *testing.T) {
+	input := []BindingInput{{Value: "AAAA"}}
+	assert.Error(t, (&ReportDataBinding{Hash: "md5", Inputs: input}).validate())
+	assert.Error(t, (&ReportDataBinding{Hash: "sha256"}).validate())
+	assert.Error(t, (&ReportDataBinding{Hash: "sha256", Inputs: []BindingInput{{Value: "not base64"}}}).validate())
+	assert.Error(t, (&ReportDataBinding{Hash: "none", Inputs: []BindingInput{
+		{Value: base64.StdEncoding.EncodeToString(make([]byte, parser.ReportDataSize+1))}}}).validate())
+	assert.NoError(t, (&ReportDataBinding{Hash: "sha384", Inputs: input}).validate())
+}
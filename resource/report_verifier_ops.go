@@ -0,0 +1,186 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	commLogMsg "intel/isecl/lib/common/v4/log/message"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/handlers"
+	"github.com/gorilla/mux"
+)
+
+// ChainedReportData is the request payload for verifying an SGX REPORT via the quote of the enclave
+// that performed local attestation of it on the same platform
+type ChainedReportData struct {
+	// Base64 encoded SGX REPORT of the enclave being attested
+	Report string `json:"report"`
+	// Base64 encoded quote of the verifying enclave, its report data must carry SHA-256(report)
+	Quote string `json:"quote"`
+	// Base64 encoded user data bound in the report data of the attested enclave
+	UserData string `json:"userData,omitempty"`
+	// Optional hex encoded MRSIGNER/MRENCLAVE the verifying enclave must have
+	VerifierMrSigner  string `json:"verifierMrSigner,omitempty"`
+	VerifierMrEnclave string `json:"verifierMrEnclave,omitempty"`
+}
+
+type ChainedSGXResponse struct {
+	Message             string
+	VerifyingEnclave    SGXResponse
+	ReportData          string     `json:"ReportData,omitempty"`
+	UserDataHashMatch   string     `json:"UserDataMatch,omitempty"`
+	EnclaveIssuer       string     `json:"EnclaveIssuer,omitempty"`
+	EnclaveMeasurement  string     `json:"EnclaveMeasurement,omitempty"`
+	EnclaveIssuerProdID string     `json:"EnclaveIssuerProdID,omitempty"`
+	IsvSvn              string     `json:"IsvSvn,omitempty"`
+	EnclaveDebugMode    bool       `json:"EnclaveDebugMode"`
+	KSS                 *KssFields `json:"KSS,omitempty"`
+
+	ReportHashes *QuoteHashes `json:"ReportHashes,omitempty"`
+}
+
+// ReportVerifyCB registers the chained local attestation report verification route
+func ReportVerifyCB(router *mux.Router) {
+	router.Handle("/sgx_verify_report", handlers.ContentTypeHandler(sgxVerifyChainedReport(), "application/json")).Methods("POST")
+}
+
+func sgxVerifyChainedReport() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/report_verifier_ops:sgxVerifyChainedReport() Entering")
+		defer log.Trace("resource/report_verifier_ops:sgxVerifyChainedReport() Leaving")
+
+		conf := config.Global()
+		if conf == nil {
+			return &resourceError{Message: "Could not read config", StatusCode: http.StatusInternalServerError}
+		}
+		if conf.IncludeToken {
+			err := AuthorizeEndpoint(r, constants.QuoteVerifierGroupName, true)
+			if err != nil {
+				slog.WithError(err).Error("resource/report_verifier_ops: sgxVerifyChainedReport() Authorization Error")
+				return err
+			}
+		}
+
+		var data ChainedReportData
+		if r.ContentLength == 0 {
+			slog.Error("resource/report_verifier_ops: sgxVerifyChainedReport() The request body was not provided")
+			return &resourceError{Message: "SGX_QL_ERROR_INVALID_PARAMETER", StatusCode: http.StatusBadRequest}
+		}
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		err := dec.Decode(&data)
+		if err != nil {
+			slog.WithError(err).Errorf("resource/report_verifier_ops: sgxVerifyChainedReport() %s:Failed to decode "+
+				"request body", commLogMsg.InvalidInputBadEncoding)
+			return &resourceError{Message: "Invalid JSON input provided", StatusCode: http.StatusBadRequest}
+		}
+
+		resp, err := SgxChainedReportVerify(r.Context(), data)
+		if err != nil {
+			return err
+		}
+		respBytes, err := json.Marshal(resp)
+		if err != nil {
+			log.WithError(err).Error("Error marshalling SGX response in JSON")
+			return &resourceError{Message: "Error marshalling SGX response in JSON", StatusCode: http.StatusInternalServerError}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		w.WriteHeader(http.StatusOK)
+
+		_, err = w.Write(respBytes)
+		if err != nil {
+			return &resourceError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+		return nil
+	}
+}
+
+// SgxChainedReportVerify verifies the quote of the verifying enclave and checks that it binds the SGX REPORT
+// of the attested enclave. The REPORT MAC can only be checked on the platform, so the verifying enclave is
+// trusted to have done so before embedding SHA-256(report) in the first 32 bytes of its own report data.
+func SgxChainedReportVerify(ctx context.Context, data ChainedReportData) (ChainedSGXResponse, error) {
+	log.Trace("resource/report_verifier_ops:SgxChainedReportVerify() Entering")
+	defer log.Trace("resource/report_verifier_ops:SgxChainedReportVerify() Leaving")
+
+	reportBytes, err := base64.StdEncoding.DecodeString(data.Report)
+	if err != nil {
+		log.WithError(err).Error("Failed to Base64 Decode SGX report")
+		return ChainedSGXResponse{}, &resourceError{Message: "Failed to Base64 Decode SGX report",
+			StatusCode: http.StatusBadRequest}
+	}
+	report, err := parser.ParseSgxReport(reportBytes)
+	if err != nil {
+		log.WithError(err).Error("Could not parse SGX report")
+		return ChainedSGXResponse{}, &resourceError{Message: "Could not parse SGX report",
+			StatusCode: http.StatusBadRequest}
+	}
+
+	verifierResp, err := SgxEcdsaQuoteVerify(ctx, QuoteDataWithChallenge{
+		QuoteData: QuoteData{
+			QuoteBlob: data.Quote,
+			UserData:  base64.StdEncoding.EncodeToString(reportBytes),
+		},
+	})
+	if err != nil {
+		return ChainedSGXResponse{}, err
+	}
+	if verifierResp.UserDataHashMatch != "true" {
+		slog.Error("resource/report_verifier_ops: SgxChainedReportVerify() Verifying enclave quote does not bind the SGX report")
+		return ChainedSGXResponse{}, &resourceError{Message: "Verifying enclave quote does not bind the SGX report",
+			StatusCode: http.StatusBadRequest}
+	}
+	if data.VerifierMrSigner != "" && !strings.EqualFold(data.VerifierMrSigner, verifierResp.EnclaveIssuer) {
+		slog.Error("resource/report_verifier_ops: SgxChainedReportVerify() Verifying enclave MRSIGNER mismatch")
+		return ChainedSGXResponse{}, &resourceError{Message: "Verifying enclave MRSIGNER mismatch",
+			StatusCode: http.StatusBadRequest}
+	}
+	if data.VerifierMrEnclave != "" && !strings.EqualFold(data.VerifierMrEnclave, verifierResp.EnclaveMeasurement) {
+		slog.Error("resource/report_verifier_ops: SgxChainedReportVerify() Verifying enclave MRENCLAVE mismatch")
+		return ChainedSGXResponse{}, &resourceError{Message: "Verifying enclave MRENCLAVE mismatch",
+			StatusCode: http.StatusBadRequest}
+	}
+
+	err = checkDebugEnclavePolicy(report.Body.IsDebug())
+	if err != nil {
+		return ChainedSGXResponse{}, err
+	}
+
+	var resp ChainedSGXResponse
+	resp.Message = verifierResp.Message
+	resp.VerifyingEnclave = verifierResp
+	resp.ReportData = fmt.Sprintf("%02x", report.Body.ReportData[:sha256.Size])
+	resp.EnclaveIssuer = fmt.Sprintf("%02x", report.Body.MrSigner)
+	resp.EnclaveIssuerProdID = fmt.Sprintf("%02x", report.Body.SgxIsvProdID)
+	resp.EnclaveMeasurement = fmt.Sprintf("%02x", report.Body.MrEnclave)
+	resp.IsvSvn = fmt.Sprintf("%02x", report.Body.SgxIsvSvn)
+	resp.EnclaveDebugMode = report.Body.IsDebug()
+	resp.KSS = newKssFields(&report.Body)
+	resp.ReportHashes = NewReportHashes(reportBytes)
+
+	if data.UserData != "" {
+		userData, err := base64.StdEncoding.DecodeString(data.UserData)
+		if err != nil {
+			log.Error("Failed to Base64 Decode User Data")
+		}
+		hash := sha256.Sum256(userData)
+		resp.UserDataHashMatch = strconv.FormatBool(err == nil && bytes.Equal(hash[:], report.Body.ReportData[:sha256.Size]))
+	}
+
+	log.Info("Sgx chained report verification completed")
+	return resp, nil
+}
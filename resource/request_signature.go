@@ -0,0 +1,343 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	commLogMsg "intel/isecl/lib/common/v4/log/message"
+	"intel/isecl/sqvs/v4/bundle"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/fips"
+	"intel/isecl/sqvs/v4/logformat"
+	"intel/isecl/sqvs/v4/repository"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// RequestSignatureHeader carries the detached JWS (RFC 7515 appendix F) of the body of a quote appraisal
+// request, signed by a registered relying party whose ID is the kid of the JWS header. The iat and jti of the
+// protected header make the signature fresh and single-use.
+const RequestSignatureHeader = "X-SQVS-Request-Signature"
+
+// maxRequestSignatureIDLength bounds the jti of request signatures, which the SQVS store records
+const maxRequestSignatureIDLength = 128
+
+// RelyingParties are the relying parties registered to sign quote appraisal requests, each by a PEM encoded
+// public key or certificate named <relying party ID>.pem in their directory
+type RelyingParties struct {
+	dir  string
+	mu   sync.RWMutex
+	keys map[string]crypto.PublicKey
+}
+
+var relyingParties *RelyingParties
+
+// SetRelyingParties sets the relying parties the request signatures are verified against, no relying party
+// being registered when nil
+func SetRelyingParties(p *RelyingParties) {
+	relyingParties = p
+}
+
+// NewRelyingParties loads the public keys of the relying parties registered in dir, none when dir does not
+// exist
+func NewRelyingParties(dir string) (*RelyingParties, error) {
+	p := &RelyingParties{dir: dir, keys: map[string]crypto.PublicKey{}}
+	return p, p.Reload()
+}
+
+// Reload loads the public keys of the relying parties again, the keys that cannot be loaded are skipped
+func (p *RelyingParties) Reload() error {
+	files, err := ioutil.ReadDir(p.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "resource/request_signature:Reload() Error reading relying party keys")
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".pem" {
+			continue
+		}
+		key, err := bundle.LoadPublicKey(filepath.Join(p.dir, file.Name()))
+		if err != nil {
+			log.WithError(err).Errorf("resource/request_signature:Reload() Error loading the key of relying party "+
+				"%s, its requests will be rejected", file.Name())
+			continue
+		}
+		keys[strings.TrimSuffix(file.Name(), ".pem")] = key
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = keys
+	return nil
+}
+
+// Len returns the number of relying parties registered
+func (p *RelyingParties) Len() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.keys)
+}
+
+func (p *RelyingParties) key(id string) (crypto.PublicKey, bool) {
+	if p == nil {
+		return nil, false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	key, ok := p.keys[id]
+	return key, ok
+}
+
+// signedRequest is the relying party that signed a request and its signature, recorded with the verification
+// for non-repudiation
+type signedRequest struct {
+	RelyingParty string
+	Signature    string
+}
+
+type signedRequestKey struct{}
+
+// signedRequestOf returns the relying party whose signature of the request has been verified, if any
+func signedRequestOf(r *http.Request) signedRequest {
+	signed, _ := r.Context().Value(signedRequestKey{}).(signedRequest)
+	return signed
+}
+
+// RequestSignatureMiddleware verifies the signatures of the quote appraisal requests signed by relying
+// parties, rejecting the requests with an invalid signature, and the unsigned ones when signatures are
+// required. It must run after the token has been verified.
+func RequestSignatureMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || strings.Contains(r.URL.Path, "/admin/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		jws := strings.TrimSpace(r.Header.Get(RequestSignatureHeader))
+		if jws == "" {
+			if conf := config.Global(); conf != nil && conf.RequireSignedRequests {
+				slog.WithFields(signatureEventFields(logformat.OutcomeFailure, r, "")).Warnf("resource/request_signature:"+
+					"RequestSignatureMiddleware() %s: Rejecting unsigned request %s %s from %s",
+					commLogMsg.UnauthorizedAccess, r.Method, r.URL.Path, r.RemoteAddr)
+				writeProblem(w, r, newProblem(http.StatusUnauthorized, "Request signature is required"))
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, constants.MaxBatchQuotes*constants.MaxQuoteUploadSize))
+		if err != nil {
+			writeProblem(w, r, newProblem(http.StatusBadRequest, "Error reading request body"))
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		relyingParty, err := verifyRequestSignature(jws, body, time.Now())
+		if rerr, ok := err.(*resourceError); ok {
+			writeProblem(w, r, newProblem(rerr.StatusCode, rerr.Message))
+			return
+		}
+		if err != nil {
+			slog.WithError(err).WithFields(signatureEventFields(logformat.OutcomeFailure, r, relyingParty)).Warnf(
+				"resource/request_signature:RequestSignatureMiddleware() %s: Rejecting request %s %s from %s with an "+
+					"invalid signature", commLogMsg.UnauthorizedAccess, r.Method, r.URL.Path, r.RemoteAddr)
+			writeProblem(w, r, newProblem(http.StatusUnauthorized, "Invalid request signature"))
+			return
+		}
+		slog.WithFields(signatureEventFields(logformat.OutcomeSuccess, r, relyingParty)).Infof(
+			"resource/request_signature:RequestSignatureMiddleware() Request %s %s signed by relying party %s",
+			r.Method, r.URL.Path, relyingParty)
+		ctx := context.WithValue(r.Context(), signedRequestKey{}, signedRequest{RelyingParty: relyingParty,
+			Signature: jws})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func signatureEventFields(outcome string, r *http.Request, relyingParty string) logrus.Fields {
+	fields := logrus.Fields{
+		logformat.EventField:   logformat.EventAuth,
+		logformat.OutcomeField: outcome,
+		"request":              r.Method + " " + r.URL.Path,
+		"source":               r.RemoteAddr,
+	}
+	if relyingParty != "" {
+		fields["relyingParty"] = relyingParty
+	}
+	return fields
+}
+
+// verifyRequestSignature verifies the detached compact JWS of the request body and returns the ID of the
+// relying party that signed it, the kid of the JWS header. The signature must have been issued within the
+// maximum request signature age and its jti is recorded in the SQVS store so it cannot be replayed.
+func verifyRequestSignature(jws string, body []byte, now time.Time) (string, error) {
+	header, err := verifyDetachedJWS(jws, body, relyingParties)
+	if err != nil {
+		return header.Kid, errors.Wrap(err, "resource/request_signature:verifyRequestSignature() Invalid request signature")
+	}
+	if header.Iat == 0 || header.Jti == "" {
+		return header.Kid, errors.New("resource/request_signature:verifyRequestSignature() Request signature has no " +
+			"iat or jti in its protected header")
+	}
+	if len(header.Jti) > maxRequestSignatureIDLength {
+		return header.Kid, errors.Errorf("resource/request_signature:verifyRequestSignature() Request signature jti "+
+			"is longer than %d characters", maxRequestSignatureIDLength)
+	}
+	maxAge := requestSignatureMaxAge()
+	issued := time.Unix(header.Iat, 0)
+	if issued.Before(now.Add(-maxAge)) || issued.After(now.Add(maxAge)) {
+		return header.Kid, errors.Errorf("resource/request_signature:verifyRequestSignature() Request signature "+
+			"issued at %s is not within %s of the current time", issued.UTC().Format(time.RFC3339), maxAge)
+	}
+
+	if sqvsDB == nil {
+		log.Error("resource/request_signature:verifyRequestSignature() SQVS store is not available to record the " +
+			"request signature use")
+		return header.Kid, &resourceError{Message: "Request signatures cannot be verified",
+			StatusCode: http.StatusInternalServerError}
+	}
+	// the signature is rejected past the maximum age, its use does not need to be remembered longer
+	err = sqvsDB.UsedTokenRepository().Use(requestSignatureUseID(header.Kid, header.Jti), issued.Add(maxAge))
+	if err == repository.ErrTokenUsed {
+		return header.Kid, errors.Errorf("resource/request_signature:verifyRequestSignature() Request signature %s "+
+			"was already used", header.Jti)
+	} else if err != nil {
+		log.WithError(err).Error("resource/request_signature:verifyRequestSignature() Error recording the " +
+			"request signature use")
+		return header.Kid, &resourceError{Message: "Error recording the request signature use",
+			StatusCode: http.StatusInternalServerError}
+	}
+	return header.Kid, nil
+}
+
+// requestSignatureUseID is the ID the use of a request signature is recorded under, the jti being unique to
+// the relying party
+func requestSignatureUseID(relyingParty, jti string) string {
+	return "request:" + relyingParty + ":" + jti
+}
+
+func requestSignatureMaxAge() time.Duration {
+	if conf := config.Global(); conf != nil && conf.RequestSignatureMaxAge > 0 {
+		return conf.RequestSignatureMaxAge
+	}
+	return constants.DefaultRequestSignatureMaxAge
+}
+
+// jwsHeader is the protected header of a detached JWS. Iat and Jti are only required of request signatures.
+type jwsHeader struct {
+	Alg  string   `json:"alg"`
+	Kid  string   `json:"kid"`
+	Crit []string `json:"crit"`
+	Iat  int64    `json:"iat"`
+	Jti  string   `json:"jti"`
+}
+
+// verifyDetachedJWS verifies the detached compact JWS of the payload against the key registered under the kid
+// of its header and returns the header
+func verifyDetachedJWS(jws string, payload []byte, keys *RelyingParties) (jwsHeader, error) {
+	var header jwsHeader
+	parts := strings.Split(jws, ".")
+	if len(parts) != 3 || parts[1] != "" {
+		return header, errors.New("resource/request_signature:verifyDetachedJWS() Signature is not a detached " +
+			"compact JWS")
+	}
+	encoded, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return header, errors.Wrap(err, "resource/request_signature:verifyDetachedJWS() Invalid JWS header")
+	}
+	err = json.Unmarshal(encoded, &header)
+	if err != nil {
+		return jwsHeader{}, errors.Wrap(err, "resource/request_signature:verifyDetachedJWS() Invalid JWS header")
+	}
+	if len(header.Crit) != 0 {
+		return header, errors.Errorf("resource/request_signature:verifyDetachedJWS() Critical JWS header "+
+			"parameters %v are not supported", header.Crit)
+	}
+	if conf := config.Global(); conf != nil && conf.FipsMode && !fips.IsApprovedJWTAlgorithm(header.Alg) {
+		return header, errors.Errorf("resource/request_signature:verifyDetachedJWS() Signature "+
+			"algorithm %s is not FIPS approved", header.Alg)
+	}
+	key, ok := keys.key(header.Kid)
+	if !ok {
+		return header, errors.Errorf("resource/request_signature:verifyDetachedJWS() Key %q is not "+
+			"registered", header.Kid)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return header, errors.Wrap(err, "resource/request_signature:verifyDetachedJWS() Invalid JWS "+
+			"signature encoding")
+	}
+	signingInput := parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload)
+	err = verifyJWSSignature(header.Alg, key, []byte(signingInput), signature)
+	if err != nil {
+		return header, err
+	}
+	return header, nil
+}
+
+// verifyJWSSignature verifies a JWS signature with the RS256, RS384, PS256, PS384, ES256 or ES384 algorithm
+func verifyJWSSignature(alg string, key crypto.PublicKey, signingInput, signature []byte) error {
+	hash := crypto.SHA256
+	if strings.HasSuffix(alg, "384") {
+		hash = crypto.SHA384
+	}
+	h := hash.New()
+	h.Write(signingInput)
+	digest := h.Sum(nil)
+
+	valid := false
+	switch alg {
+	case "RS256", "RS384", "PS256", "PS384":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.Errorf("resource/request_signature:verifyJWSSignature() Algorithm %s needs an RSA key", alg)
+		}
+		if strings.HasPrefix(alg, "PS") {
+			valid = rsa.VerifyPSS(rsaKey, hash, digest, signature,
+				&rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+		} else {
+			valid = rsa.VerifyPKCS1v15(rsaKey, hash, digest, signature) == nil
+		}
+	case "ES256", "ES384":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		curve := elliptic.P256()
+		if alg == "ES384" {
+			curve = elliptic.P384()
+		}
+		if !ok || ecKey.Curve != curve {
+			return errors.Errorf("resource/request_signature:verifyJWSSignature() Algorithm %s needs an ECDSA %s "+
+				"key", alg, curve.Params().Name)
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(signature) == 2*size {
+			r := new(big.Int).SetBytes(signature[:size])
+			s := new(big.Int).SetBytes(signature[size:])
+			valid = ecdsa.Verify(ecKey, digest, r, s)
+		}
+	default:
+		return errors.Errorf("resource/request_signature:verifyJWSSignature() Signature algorithm %q is not "+
+			"supported", alg)
+	}
+	if !valid {
+		return errors.New("resource/request_signature:verifyJWSSignature() Signature verification failed")
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/repository/memory"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeRelyingPartyKey(t *testing.T, dir, id string, key crypto.PublicKey) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	assert.NoError(t, err)
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, id+".pem"), pemBytes, 0600))
+}
+
+// signRequest returns the detached JWS of body, signed now with a new jti
+func signRequest(t *testing.T, alg, kid string, key crypto.Signer, body string) string {
+	return signRequestAt(t, alg, kid, key, body, time.Now().Unix(), newRecordID())
+}
+
+// signRequestAt returns the detached JWS of body with the iat and jti, omitted when zero or empty
+func signRequestAt(t *testing.T, alg, kid string, key crypto.Signer, body string, iat int64, jti string) string {
+	protected := map[string]interface{}{"alg": alg, "kid": kid}
+	if iat != 0 {
+		protected["iat"] = iat
+	}
+	if jti != "" {
+		protected["jti"] = jti
+	}
+	headerJSON, err := json.Marshal(protected)
+	assert.NoError(t, err)
+	header := base64.RawURLEncoding.EncodeToString(headerJSON)
+	digest := sha256.Sum256([]byte(header + "." + base64.RawURLEncoding.EncodeToString([]byte(body))))
+	var signature []byte
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		assert.NoError(t, err)
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	case *rsa.PrivateKey:
+		var err error
+		signature, err = rsa.SignPSS(rand.Reader, k, crypto.SHA256, digest[:],
+			&rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		assert.NoError(t, err)
+	}
+	return header + ".." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestRequestSignatureMiddleware(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqvs-relying-parties")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	writeRelyingPartyKey(t, dir, "bank", ecKey.Public())
+	writeRelyingPartyKey(t, dir, "broker", rsaKey.Public())
+
+	parties, err := NewRelyingParties(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, parties.Len())
+	SetRelyingParties(parties)
+	defer SetRelyingParties(nil)
+	db, err := memory.New("")
+	assert.NoError(t, err)
+	SetRepository(db)
+	defer SetRepository(nil)
+
+	var caller verificationCaller
+	handler := RequestSignatureMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, `{"quote":"AwAC"}`, string(body))
+		caller = callerOf(r)
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(body, signature string) int {
+		caller = verificationCaller{}
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/svs/v1/sgx_qv_verify_quote", strings.NewReader(body))
+		if signature != "" {
+			req.Header.Set(RequestSignatureHeader, signature)
+		}
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	body := `{"quote":"AwAC"}`
+	signature := signRequest(t, "ES256", "bank", ecKey, body)
+	assert.Equal(t, http.StatusOK, serve(body, signature))
+	assert.Equal(t, "bank", caller.RelyingParty)
+	assert.Equal(t, signature, caller.Signature)
+
+	assert.Equal(t, http.StatusOK, serve(body, signRequest(t, "PS256", "broker", rsaKey, body)))
+	assert.Equal(t, "broker", caller.RelyingParty)
+
+	// the signature must be of the body, by the key of the relying party it names
+	assert.Equal(t, http.StatusUnauthorized, serve(`{"quote":"AwAD"}`, signature))
+	assert.Equal(t, http.StatusUnauthorized, serve(body, signRequest(t, "ES256", "broker", ecKey, body)))
+	assert.Equal(t, http.StatusUnauthorized, serve(body, signRequest(t, "ES256", "unknown", ecKey, body)))
+	assert.Equal(t, http.StatusUnauthorized, serve(body, "not-a-jws"))
+
+	// signatures are fresh and single-use
+	assert.Equal(t, http.StatusUnauthorized, serve(body, signature))
+	now := time.Now().Unix()
+	assert.Equal(t, http.StatusUnauthorized, serve(body, signRequestAt(t, "ES256", "bank", ecKey, body, 0, "j1")))
+	assert.Equal(t, http.StatusUnauthorized, serve(body, signRequestAt(t, "ES256", "bank", ecKey, body, now, "")))
+	assert.Equal(t, http.StatusUnauthorized, serve(body, signRequestAt(t, "ES256", "bank", ecKey, body,
+		now-int64(time.Hour/time.Second), "j2")))
+	assert.Equal(t, http.StatusUnauthorized, serve(body, signRequestAt(t, "ES256", "bank", ecKey, body,
+		now+int64(time.Hour/time.Second), "j3")))
+	// the jti of a relying party does not take that of another
+	assert.Equal(t, http.StatusOK, serve(body, signRequestAt(t, "ES256", "bank", ecKey, body, now, "j4")))
+	assert.Equal(t, http.StatusOK, serve(body, signRequestAt(t, "PS256", "broker", rsaKey, body, now, "j4")))
+
+	// unsigned requests are accepted unless signatures are required
+	assert.Equal(t, http.StatusOK, serve(body, ""))
+	assert.Empty(t, caller.RelyingParty)
+	config.Global().RequireSignedRequests = true
+	defer func() { config.Global().RequireSignedRequests = false }()
+	assert.Equal(t, http.StatusUnauthorized, serve(body, ""))
+}
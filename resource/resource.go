@@ -8,33 +8,91 @@ import (
 	"fmt"
 	"intel/isecl/lib/common/v4/auth"
 	"intel/isecl/lib/common/v4/context"
+	"intel/isecl/sqvs/v4/claims"
 	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/events"
+	"intel/isecl/sqvs/v4/keystore"
+	"intel/isecl/sqvs/v4/logformat"
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/signingkey"
 	"net/http"
 
 	clog "intel/isecl/lib/common/v4/log"
 	commLogMsg "intel/isecl/lib/common/v4/log/message"
 	ct "intel/isecl/lib/common/v4/types/aas"
+
+	"github.com/sirupsen/logrus"
 )
 
 var log = clog.GetDefaultLogger()
 var slog = clog.GetSecurityLogger()
 
+var sqvsDB repository.SQVSDatabase
+var eventPublisher events.Publisher = events.NoopPublisher{}
+var resultPublisher events.Publisher = events.NoopPublisher{}
+var customClaims *claims.Policy
+var signingKeys = signingkey.NewRing(keystore.FileKeyStore{}, constants.PrivateKeyLocation, constants.PublicKeyLocation,
+	"", 0, false)
+
+// SetRepository sets the persistence layer used by the resource handlers
+func SetRepository(db repository.SQVSDatabase) {
+	sqvsDB = db
+}
+
+// SetEventPublisher sets the publisher used to deliver alerts raised by the resource handlers
+func SetEventPublisher(p events.Publisher) {
+	eventPublisher = p
+}
+
+// SetResultPublisher sets the publisher the result of every quote verification is delivered to
+func SetResultPublisher(p events.Publisher) {
+	resultPublisher = p
+}
+
+// SetSigningKeys sets the key ring responses are signed with
+func SetSigningKeys(ring *signingkey.Ring) {
+	signingKeys = ring
+}
+
+// SetCustomClaims sets the policy of the custom claims embedded in signed responses
+func SetCustomClaims(p *claims.Policy) {
+	customClaims = p
+}
+
+// adminEventFields are the fields of the security log records of administrative actions
+func adminEventFields(outcome string) logrus.Fields {
+	return logrus.Fields{
+		logformat.EventField:   logformat.EventAdmin,
+		logformat.OutcomeField: outcome,
+	}
+}
+
+// authEventFields returns the fields of the security log records of the authorization of a request
+func authEventFields(outcome string, r *http.Request, roleName string) logrus.Fields {
+	return logrus.Fields{
+		logformat.EventField:   logformat.EventAuth,
+		logformat.OutcomeField: outcome,
+		"request":              r.Method + " " + r.URL.Path,
+		"role":                 roleName,
+		"source":               r.RemoteAddr,
+	}
+}
+
 type errorHandlerFunc func(w http.ResponseWriter, r *http.Request) error
 
 func (ehf errorHandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if err := ehf(w, r); err != nil {
-		slog.WithError(err).Error("HTTP Error")
 		switch t := err.(type) {
 		case *resourceError:
-			http.Error(w, t.Message, t.StatusCode)
+			writeResourceError(w, r, *t)
 		case resourceError:
-			http.Error(w, t.Message, t.StatusCode)
+			writeResourceError(w, r, t)
 		case *privilegeError:
-			http.Error(w, t.Message, t.StatusCode)
+			writeProblem(w, r, newProblem(t.StatusCode, t.Message))
 		case privilegeError:
-			http.Error(w, t.Message, t.StatusCode)
+			writeProblem(w, r, newProblem(t.StatusCode, t.Message))
 		default:
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeProblem(w, r, newProblem(http.StatusInternalServerError, err.Error()))
 		}
 	}
 }
@@ -51,12 +109,47 @@ func (e privilegeError) Error() string {
 type resourceError struct {
 	StatusCode int
 	Message    string
+	// Steps localize the failure of a quote verification
+	Steps VerificationSteps
+	// Diagnostics are set when the failed verification was requested with debug=true
+	Diagnostics *VerificationDiagnostics
+	// Instance is the problem instance of the recorded verification that failed
+	Instance string
+	// QvResult is the sgx_ql_qv_result_t equivalent of the failure, for the quotes sgx_qv_verify_quote would
+	// have verified with a result rather than an error
+	QvResult *QvResult
+	// collateralUnreachable is set on the failures to fetch collateral from SCS that could not be reached, the
+	// only failures the collateral failure mode falls back on cached collateral from
+	collateralUnreachable bool
 }
 
 func (e resourceError) Error() string {
 	return fmt.Sprintf("%d: %s", e.StatusCode, e.Message)
 }
 
+// VerificationError is the problem details of the responses to failed quote verifications, with the
+// verification steps, the sgx_ql_qv_result_t equivalent and the diagnostics asked for as extension members
+type VerificationError struct {
+	Problem
+	FailedStep string            `json:"FailedStep,omitempty"`
+	Steps      VerificationSteps `json:"VerificationSteps,omitempty"`
+	QvResult   *QvResult         `json:"QvResult,omitempty"`
+
+	Diagnostics *VerificationDiagnostics `json:"Diagnostics,omitempty"`
+}
+
+// writeResourceError writes the problem details of the error, a verification failure when a step of the
+// quote verification failed
+func writeResourceError(w http.ResponseWriter, r *http.Request, e resourceError) {
+	problem := newProblem(e.StatusCode, e.Message)
+	if e.Steps != nil && e.StatusCode < http.StatusInternalServerError {
+		problem = newCategoryProblem(ProblemVerificationFailed, e.StatusCode, e.Message)
+	}
+	problem.Instance = e.Instance
+	writeProblem(w, r, &VerificationError{Problem: *problem, FailedStep: e.Steps.FailedStep(), Steps: e.Steps,
+		QvResult: e.QvResult, Diagnostics: e.Diagnostics})
+}
+
 func AuthorizeEndpoint(r *http.Request, roleName string, retNilCtxForEmptyCtx bool) error {
 	log.Trace("resource/resource:AuthorizeEndpoint() Entering")
 	defer log.Trace("resource/resource:AuthorizeEndpoint() Leaving")
@@ -69,9 +162,9 @@ func AuthorizeEndpoint(r *http.Request, roleName string, retNilCtxForEmptyCtx bo
 
 	_, foundRole := auth.ValidatePermissionAndGetRoleContext(privileges, []ct.RoleInfo{{Service: constants.ServiceName, Name: roleName}}, retNilCtxForEmptyCtx)
 	if !foundRole {
-		slog.Infof("resource/resource: AuthorizeEndpoint() %s: endpoint access unauthorized, request role: %v", commLogMsg.UnauthorizedAccess, roleName)
+		slog.WithFields(authEventFields(logformat.OutcomeFailure, r, roleName)).Infof("resource/resource: AuthorizeEndpoint() %s: endpoint access unauthorized, request role: %v", commLogMsg.UnauthorizedAccess, roleName)
 		return &privilegeError{Message: "Endpoint access unauthorized", StatusCode: http.StatusForbidden}
 	}
-	slog.Infof("resource/resource: Authorized Endpoint() %s - %s", commLogMsg.AuthorizedAccess, r.RequestURI)
+	slog.WithFields(authEventFields(logformat.OutcomeSuccess, r, roleName)).Infof("resource/resource: Authorized Endpoint() %s - %s", commLogMsg.AuthorizedAccess, r.RequestURI)
 	return nil
 }
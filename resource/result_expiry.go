@@ -0,0 +1,50 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"intel/isecl/sqvs/v4/config"
+	"time"
+)
+
+// Sources of the expiry of a verification result, the earliest of the candidate lifetimes
+const (
+	// ExpirySourceCollateral is the earliest next update of the PCK CRLs, TCB info and QE identity the quote
+	// was verified with
+	ExpirySourceCollateral = "collateral_next_update"
+	// ExpirySourceValidity is the result validity period of the configuration, SQVS_RESULT_VALIDITY
+	ExpirySourceValidity = "result_validity"
+	// ExpirySourcePolicy is the TTL of the result policy the signed result was issued under
+	ExpirySourcePolicy = "result_policy_ttl"
+)
+
+// setResultExpiry sets the time relying parties should re-attest after, the earliest of the next update of
+// the collateral, the configured result validity and the TTL of the result policy, counted from issuedAt.
+// It returns the zero time, leaving the result without expiry, when none of them applies.
+func setResultExpiry(resp *SGXResponse, issuedAt time.Time, policyTTL time.Duration) time.Time {
+	var expiresAt time.Time
+	source := ""
+	candidate := func(t time.Time, s string) {
+		if !t.IsZero() && (expiresAt.IsZero() || t.Before(expiresAt)) {
+			expiresAt, source = t, s
+		}
+	}
+
+	if resp.SupplementalData != nil {
+		if t, err := time.Parse(time.RFC3339, resp.SupplementalData.EarliestExpirationDate); err == nil {
+			candidate(t, ExpirySourceCollateral)
+		}
+	}
+	if conf := config.Global(); conf != nil && conf.ResultValidity > 0 {
+		candidate(issuedAt.Add(conf.ResultValidity), ExpirySourceValidity)
+	}
+	if policyTTL > 0 {
+		candidate(issuedAt.Add(policyTTL), ExpirySourcePolicy)
+	}
+
+	resp.ExpiresAt = formatDate(expiresAt)
+	resp.ExpirySource = source
+	return expiresAt
+}
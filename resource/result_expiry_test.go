@@ -0,0 +1,40 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"intel/isecl/sqvs/v4/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetResultExpiry(t *testing.T) {
+	issuedAt := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	resp := SGXResponse{}
+	assert.True(t, setResultExpiry(&resp, issuedAt, 0).IsZero())
+	assert.Empty(t, resp.ExpiresAt)
+
+	resp.SupplementalData = &SupplementalData{EarliestExpirationDate: "2021-07-02T00:00:00Z"}
+	setResultExpiry(&resp, issuedAt, 0)
+	assert.Equal(t, "2021-07-02T00:00:00Z", resp.ExpiresAt)
+	assert.Equal(t, ExpirySourceCollateral, resp.ExpirySource)
+
+	config.Global().ResultValidity = 6 * time.Hour
+	defer func() { config.Global().ResultValidity = 0 }()
+	setResultExpiry(&resp, issuedAt, 0)
+	assert.Equal(t, "2021-07-01T18:00:00Z", resp.ExpiresAt)
+	assert.Equal(t, ExpirySourceValidity, resp.ExpirySource)
+
+	expiresAt := setResultExpiry(&resp, issuedAt, time.Hour)
+	assert.Equal(t, issuedAt.Add(time.Hour), expiresAt)
+	assert.Equal(t, ExpirySourcePolicy, resp.ExpirySource)
+
+	// a policy TTL longer than the collateral lifetime does not extend the result
+	config.Global().ResultValidity = 0
+	setResultExpiry(&resp, issuedAt, 48*time.Hour)
+	assert.Equal(t, ExpirySourceCollateral, resp.ExpirySource)
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/base64"
+	commLogMsg "intel/isecl/lib/common/v4/log/message"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/fips"
+	"intel/isecl/sqvs/v4/resource/utils"
+	"intel/isecl/sqvs/v4/signingkey"
+	"net/http"
+	"sort"
+)
+
+// algorithmKeys are the signing keys dedicated to an algorithm, for the relying parties that do not accept
+// the algorithm of the response signing key
+var algorithmKeys = map[string]*signingkey.Ring{}
+
+// SetAlgorithmSigningKeys sets the signing keys dedicated to an algorithm, by algorithm
+func SetAlgorithmSigningKeys(rings map[string]*signingkey.Ring) {
+	algorithmKeys = rings
+}
+
+// defaultSigningAlgorithm returns the algorithm results are signed with when the request does not name one,
+// SQVS_RESULT_SIGNING_ALGORITHM or the RSA algorithm of the padding of USE_PSS_PADDING
+func defaultSigningAlgorithm(conf *config.Configuration) string {
+	if conf == nil {
+		return signingkey.AlgorithmRS384
+	}
+	if conf.ResultSigningAlgorithm != "" {
+		return conf.ResultSigningAlgorithm
+	}
+	if conf.UsePSSPadding {
+		return signingkey.AlgorithmPS384
+	}
+	return signingkey.AlgorithmRS384
+}
+
+// checkSigningAlgorithm rejects the signing algorithm a request names when results cannot be signed with it
+func checkSigningAlgorithm(alg string) error {
+	if alg == "" {
+		return nil
+	}
+	if !signingkey.IsSupportedAlgorithm(alg) {
+		slog.Errorf("resource/result_signing:checkSigningAlgorithm() %s: Unsupported signing algorithm %q",
+			commLogMsg.InvalidInputBadParam, alg)
+		return &resourceError{Message: "Unsupported signing algorithm " + alg, StatusCode: http.StatusBadRequest}
+	}
+	if conf := config.Global(); conf != nil && conf.FipsMode && !fips.IsApprovedJWTAlgorithm(alg) {
+		return &resourceError{Message: "Signing algorithm " + alg + " is not FIPS approved",
+			StatusCode: http.StatusBadRequest}
+	}
+	if _, err := signingKeyFor(alg); err != nil {
+		return &resourceError{Message: "No signing key is configured for " + alg, StatusCode: http.StatusBadRequest}
+	}
+	return nil
+}
+
+// signingKeyFor returns the key signing with alg, the key dedicated to alg or else the response signing key
+// when it supports alg
+func signingKeyFor(alg string) (*signingkey.Key, error) {
+	if ring, ok := algorithmKeys[alg]; ok {
+		return ring.Current()
+	}
+	key, err := signingKeys.Current()
+	if err != nil {
+		return nil, err
+	}
+	if !key.Supports(alg) {
+		return nil, &resourceError{Message: "No signing key is configured for " + alg,
+			StatusCode: http.StatusInternalServerError}
+	}
+	return key, nil
+}
+
+// resultSigningKeyByID returns the current or retired result signing key with the key ID, nil when there is
+// none
+func resultSigningKeyByID(kid string) *signingkey.Key {
+	rings := []*signingkey.Ring{signingKeys}
+	for _, ring := range algorithmKeys {
+		rings = append(rings, ring)
+	}
+	for _, ring := range rings {
+		for _, key := range ring.Keys() {
+			if key.ID == kid {
+				return key
+			}
+		}
+	}
+	return nil
+}
+
+// signWithAlgorithm signs data with alg, the default algorithm when it is empty, and returns the base64
+// encoded signature, the key and the algorithm it was signed with
+func signWithAlgorithm(data []byte, alg string) (string, *signingkey.Key, string, error) {
+	conf := config.Global()
+	if alg == "" {
+		alg = defaultSigningAlgorithm(conf)
+	}
+	key, err := signingKeyFor(alg)
+	if err != nil {
+		log.WithError(err).Errorf("resource/result_signing:signWithAlgorithm() Error loading %s signing key", alg)
+		return "", nil, alg, &resourceError{Message: "Error loading response signing key",
+			StatusCode: http.StatusInternalServerError}
+	}
+	if _, dedicated := algorithmKeys[alg]; !dedicated && (alg == signingkey.AlgorithmRS384 ||
+		alg == signingkey.AlgorithmPS384) {
+		// results signed with the response signing key keep the padding they always had
+		signature, err := utils.GenerateSignature(data, key.Signer, alg == signingkey.AlgorithmPS384)
+		if err != nil {
+			return "", nil, alg, &resourceError{Message: "Failed to sign response: " + err.Error(),
+				StatusCode: http.StatusInternalServerError}
+		}
+		return signature, key, alg, nil
+	}
+	signature, err := key.Sign(alg, data)
+	if err != nil {
+		log.WithError(err).Error("resource/result_signing:signWithAlgorithm() Error signing response")
+		return "", nil, alg, &resourceError{Message: "Failed to sign response: " + err.Error(),
+			StatusCode: http.StatusInternalServerError}
+	}
+	return base64.StdEncoding.EncodeToString(signature), key, alg, nil
+}
+
+// resultJWKSet returns the JSON Web Key Set of the response signing keys and of the keys dedicated to an
+// algorithm, each key advertising the algorithm it signs with
+func resultJWKSet(conf *config.Configuration) signingkey.JWKSet {
+	rsaAlgorithm := signingkey.AlgorithmRS384
+	if conf != nil && conf.UsePSSPadding {
+		rsaAlgorithm = signingkey.AlgorithmPS384
+	}
+	set := signingKeys.JWKSet(rsaAlgorithm)
+	published := map[string]bool{}
+	for _, jwk := range set.Keys {
+		published[jwk.KeyID] = true
+	}
+
+	algorithms := make([]string, 0, len(algorithmKeys))
+	for alg := range algorithmKeys {
+		algorithms = append(algorithms, alg)
+	}
+	sort.Strings(algorithms)
+	for _, alg := range algorithms {
+		for _, jwk := range algorithmKeys[alg].JWKSet(alg).Keys {
+			if !published[jwk.KeyID] {
+				published[jwk.KeyID] = true
+				set.Keys = append(set.Keys, jwk)
+			}
+		}
+	}
+	return set
+}
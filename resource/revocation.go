@@ -0,0 +1,210 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	commLogMsg "intel/isecl/lib/common/v4/log/message"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/logformat"
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/resource/utils"
+	"intel/isecl/sqvs/v4/revocation"
+	"intel/isecl/sqvs/v4/types"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// RevocationRequest revokes the signed quote verification results issued within a time range, signed with a
+// key or of a set of quotes, those matching every criterion set
+type RevocationRequest struct {
+	Reason       string     `json:"reason"`
+	IssuedAfter  *time.Time `json:"issuedAfter,omitempty"`
+	IssuedBefore *time.Time `json:"issuedBefore,omitempty"`
+	KeyID        string     `json:"keyId,omitempty"`
+	QuoteHashes  []string   `json:"quoteHashes,omitempty"`
+}
+
+var revocationListSpec = listSpec{
+	FilterFields: []string{"keyId"},
+	SortFields:   []string{"revokedTime"},
+	DefaultSort:  "revokedTime",
+}
+
+func RevocationCB(router *mux.Router) {
+	router.Handle("/admin/revocations", listRevocations()).Methods("GET")
+	router.Handle("/admin/revocations", revokeResults()).Methods("POST")
+}
+
+// SetRevocationListRoutes registers the signed revocation list. Like the signing keys it is public, relying
+// parties check the results they hold against it without a token.
+func SetRevocationListRoutes(router *mux.Router) {
+	router.Handle(revocation.ListPath, getRevocationList()).Methods("GET")
+}
+
+func listRevocations() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/revocation:listRevocations() Entering")
+		defer log.Trace("resource/revocation:listRevocations() Leaving")
+
+		err := authorizeAdministrator(r)
+		if err != nil {
+			return err
+		}
+		repo, err := revocationRepository()
+		if err != nil {
+			return err
+		}
+		criteria, err := parseListQuery(r, revocationListSpec)
+		if err != nil {
+			return err
+		}
+		revocations, total, err := repo.Search(criteria)
+		if err != nil {
+			log.WithError(err).Error("resource/revocation:listRevocations() Error retrieving revocations")
+			return &resourceError{Message: "Error retrieving revocations", StatusCode: http.StatusInternalServerError}
+		}
+		return writeListResponse(w, r, revocations, criteria, total)
+	}
+}
+
+func revokeResults() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/revocation:revokeResults() Entering")
+		defer log.Trace("resource/revocation:revokeResults() Leaving")
+
+		err := authorizeAdministrator(r)
+		if err != nil {
+			return err
+		}
+		repo, err := revocationRepository()
+		if err != nil {
+			return err
+		}
+
+		var req RevocationRequest
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&req)
+		if err != nil {
+			slog.WithFields(adminEventFields(logformat.OutcomeFailure)).WithError(err).Errorf(
+				"resource/revocation:revokeResults() %s: Failed to decode request body",
+				commLogMsg.InvalidInputBadEncoding)
+			return &resourceError{Message: "Invalid JSON input provided", StatusCode: http.StatusBadRequest}
+		}
+		rev := types.Revocation{
+			ID:           newRecordID(),
+			Reason:       req.Reason,
+			RevokedTime:  time.Now().UTC(),
+			IssuedAfter:  req.IssuedAfter,
+			IssuedBefore: req.IssuedBefore,
+			KeyID:        req.KeyID,
+			QuoteHashes:  req.QuoteHashes,
+		}
+		err = revocation.Validate(&rev)
+		if err != nil {
+			slog.WithFields(adminEventFields(logformat.OutcomeFailure)).WithError(err).Errorf(
+				"resource/revocation:revokeResults() %s: Invalid revocation",
+				commLogMsg.InvalidInputBadParam)
+			return &resourceError{Message: strings.TrimPrefix(err.Error(), "revocation/revocation:Validate() "),
+				StatusCode: http.StatusBadRequest}
+		}
+
+		err = repo.Create(&rev)
+		if err != nil {
+			log.WithError(err).Error("resource/revocation:revokeResults() Error saving revocation")
+			return &resourceError{Message: "Error saving revocation", StatusCode: http.StatusInternalServerError}
+		}
+		slog.WithFields(adminEventFields(logformat.OutcomeSuccess)).Warnf(
+			"resource/revocation:revokeResults() Revoked signed results as %s: %s", rev.ID, rev.Reason)
+		return writeRevocations(w, http.StatusCreated, rev, "")
+	}
+}
+
+func getRevocationList() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/revocation:getRevocationList() Entering")
+		defer log.Trace("resource/revocation:getRevocationList() Leaving")
+
+		repo, err := revocationRepository()
+		if err != nil {
+			return err
+		}
+		revocations, err := repo.RetrieveAll()
+		if err != nil {
+			log.WithError(err).Error("resource/revocation:getRevocationList() Error retrieving revocations")
+			return &resourceError{Message: "Error retrieving revocations", StatusCode: http.StatusInternalServerError}
+		}
+		signed, err := signRevocationList(revocation.List{IssuedAt: time.Now().UTC(), Revocations: revocations})
+		if err != nil {
+			return err
+		}
+		// relying parties poll the list, keep revocations propagating within a minute
+		return writeRevocations(w, http.StatusOK, signed, "public, max-age=60")
+	}
+}
+
+// signRevocationList signs the list with the current response signing key, the way signed results are
+func signRevocationList(list revocation.List) (*revocation.SignedList, error) {
+	listBytes, err := json.Marshal(list)
+	if err != nil {
+		return nil, &resourceError{Message: "Error marshalling revocation list in JSON",
+			StatusCode: http.StatusInternalServerError}
+	}
+	signingKey, err := signingKeys.Current()
+	if err != nil {
+		log.WithError(err).Error("resource/revocation:signRevocationList() Error loading response signing key")
+		return nil, &resourceError{Message: "Error loading response signing key",
+			StatusCode: http.StatusInternalServerError}
+	}
+	usePSSPadding := false
+	if conf := config.Global(); conf != nil {
+		usePSSPadding = conf.UsePSSPadding
+	}
+	encoded := base64.StdEncoding.EncodeToString(listBytes)
+	signature, err := utils.GenerateSignature([]byte(encoded), signingKey.Signer, usePSSPadding)
+	if err != nil {
+		log.WithError(err).Error("resource/revocation:signRevocationList() Error signing revocation list")
+		return nil, &resourceError{Message: "Error signing revocation list", StatusCode: http.StatusInternalServerError}
+	}
+	return &revocation.SignedList{
+		RevocationList:   encoded,
+		Signature:        signature,
+		CertificateChain: string(signingKey.CertChain),
+		KeyID:            signingKey.ID,
+	}, nil
+}
+
+// revocationRepository returns the repository of the revocations, they are kept in the SQVS store
+func revocationRepository() (repository.RevocationRepository, error) {
+	conf := config.Global()
+	if sqvsDB == nil || conf == nil || !conf.EnableResultRevocation {
+		return nil, &resourceError{Message: "Result revocation is not enabled", StatusCode: http.StatusNotFound}
+	}
+	return sqvsDB.RevocationRepository(), nil
+}
+
+func writeRevocations(w http.ResponseWriter, status int, v interface{}, cacheControl string) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return &resourceError{Message: "Error marshalling revocations in JSON",
+			StatusCode: http.StatusInternalServerError}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if cacheControl != "" {
+		w.Header().Set("Cache-Control", cacheControl)
+	}
+	w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+	w.WriteHeader(status)
+	_, err = w.Write(body)
+	if err != nil {
+		return &resourceError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+	return nil
+}
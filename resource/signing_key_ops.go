@@ -0,0 +1,102 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/json"
+	"intel/isecl/sqvs/v4/aasclient"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/logformat"
+	"intel/isecl/sqvs/v4/signingkey"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// SigningKeyRotation is the outcome of a rotation of the response signing key
+type SigningKeyRotation struct {
+	KeyID            string     `json:"key_id"`
+	RetiredKeyID     string     `json:"retired_key_id,omitempty"`
+	RetiredKeyExpiry *time.Time `json:"retired_key_expiry,omitempty"`
+}
+
+func SigningKeyCB(router *mux.Router) {
+	router.Handle("/admin/signing-key/rotate", rotateSigningKey()).Methods("POST")
+}
+
+// rotateSigningKey replaces the response signing key with a new key certified by CMS. The bearer token of
+// the request is used to get the certificate, it must be accepted by CMS for signing certificates. Without
+// one, the token of the service account of SQVS is used when it is configured.
+func rotateSigningKey() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/signing_key_ops:rotateSigningKey() Entering")
+		defer log.Trace("resource/signing_key_ops:rotateSigningKey() Leaving")
+
+		conf := config.Global()
+		if conf == nil {
+			return &resourceError{Message: "Could not read config", StatusCode: http.StatusInternalServerError}
+		}
+		if conf.IncludeToken {
+			err := AuthorizeEndpoint(r, constants.AdministratorGroupName, true)
+			if err != nil {
+				return err
+			}
+		}
+
+		bearerToken := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if tokens := aasclient.Default(); bearerToken == "" && tokens != nil {
+			var err error
+			bearerToken, err = tokens.Token()
+			if err != nil {
+				log.WithError(err).Error("resource/signing_key_ops:rotateSigningKey() Error getting service account token")
+				return &resourceError{Message: "Error getting bearer token from AAS", StatusCode: http.StatusBadGateway}
+			}
+		}
+		if bearerToken == "" {
+			slog.Error("resource/signing_key_ops:rotateSigningKey() Bearer token is required to request the signing certificate")
+			return &resourceError{Message: "Bearer token is required to request the signing certificate from CMS",
+				StatusCode: http.StatusUnauthorized}
+		}
+
+		key, retired, err := signingKeys.Rotate(signingkey.RotateOptions{
+			CMSBaseURL:  conf.CMSBaseURL,
+			BearerToken: bearerToken,
+			CommonName:  constants.DefaultSQVSSigningCertCn,
+			KeyLength:   conf.ResponseSigningKeyLength,
+			CACertsDir:  constants.TrustedCAsStoreDir,
+		})
+		if err == signingkey.ErrRotationNotSupported {
+			return &resourceError{Message: err.Error(), StatusCode: http.StatusConflict}
+		} else if err != nil {
+			log.WithError(err).Error("resource/signing_key_ops:rotateSigningKey() Error rotating signing key")
+			return &resourceError{Message: "Error rotating signing key", StatusCode: http.StatusBadGateway}
+		}
+		slog.WithFields(adminEventFields(logformat.OutcomeSuccess)).Infof(
+			"resource/signing_key_ops:rotateSigningKey() Response signing key rotated to %s", key.ID)
+
+		rotation := SigningKeyRotation{KeyID: key.ID}
+		if retired != nil {
+			expiry := retired.RetiredTime.Add(signingKeys.Overlap()).UTC()
+			rotation.RetiredKeyID = retired.ID
+			rotation.RetiredKeyExpiry = &expiry
+		}
+		body, err := json.Marshal(rotation)
+		if err != nil {
+			return &resourceError{Message: "Error marshalling signing key rotation in JSON",
+				StatusCode: http.StatusInternalServerError}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(body)
+		if err != nil {
+			return &resourceError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+		return nil
+	}
+}
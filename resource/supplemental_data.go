@@ -0,0 +1,130 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"time"
+)
+
+// SupplementalData carries the information of the Intel SGX DCAP sgx_ql_qv_supplemental_t structure, so clients
+// moving from local DCAP quote verification get the same details. The multi-package platform settings are
+// omitted when the PCK certificate does not define them.
+type SupplementalData struct {
+	EarliestIssueDate      string `json:"EarliestIssueDate"`
+	LatestIssueDate        string `json:"LatestIssueDate"`
+	EarliestExpirationDate string `json:"EarliestExpirationDate"`
+	TcbLevelDateTag        string `json:"TcbLevelDateTag"`
+	PckCrlNum              int64  `json:"PckCrlNum"`
+	TcbEvalRefNum          uint   `json:"TcbEvalRefNum"`
+	RootKeyID              string `json:"RootKeyID"`
+	PckPpid                string `json:"PckPpid,omitempty"`
+	TcbCPUSvn              string `json:"TcbCPUSvn"`
+	TcbPceIsvSvn           uint16 `json:"TcbPceIsvSvn"`
+	PceID                  string `json:"PceID"`
+	SgxType                int    `json:"SgxType"`
+	PlatformInstanceID     string `json:"PlatformInstanceID,omitempty"`
+	DynamicPlatform        *bool  `json:"DynamicPlatform,omitempty"`
+	CachedKeys             *bool  `json:"CachedKeys,omitempty"`
+	SMTEnabled             *bool  `json:"SMTEnabled,omitempty"`
+	// PckCrlSources tells whether each PCK CRL was served by SCS, a distribution point override or imported
+	PckCrlSources []parser.PckCrlSource `json:"PckCrlSources,omitempty"`
+}
+
+// newSupplementalData collects the supplemental data of a verified quote from its PCK certificate and the
+// collateral used to verify it. Issue and expiration dates cover the PCK CRL, TCB Info and QE Identity.
+func newSupplementalData(certObj *parser.PckCert, tcbObj *parser.TcbInfoStruct, qeIDObj *parser.QeIdentityData,
+	rootCA *x509.Certificate) *SupplementalData {
+	log.Trace("resource/supplemental_data:newSupplementalData() Entering")
+	defer log.Trace("resource/supplemental_data:newSupplementalData() Leaving")
+
+	tcbLevels := certObj.GetPckCertTcbLevels()
+	data := &SupplementalData{
+		TcbLevelDateTag:    tcbObj.GetTcbLevelDate(tcbLevels),
+		TcbEvalRefNum:      tcbObj.GetTcbEvaluationDataNumber(),
+		RootKeyID:          rootKeyID(rootCA),
+		PckPpid:            certObj.GetPPIDValue(),
+		TcbCPUSvn:          hex.EncodeToString(tcbLevels[:16]),
+		TcbPceIsvSvn:       binary.LittleEndian.Uint16(tcbLevels[16:]),
+		PceID:              certObj.GetPceIDValue(),
+		SgxType:            certObj.GetSgxType(),
+		PlatformInstanceID: certObj.GetPlatformInstanceID(),
+		PckCrlSources:      certObj.GetPckCrlSources(),
+	}
+	if number := certObj.GetPckCrlNumber(); number != nil && number.IsInt64() {
+		data.PckCrlNum = number.Int64()
+	}
+	if configuration := certObj.GetPlatformConfiguration(); configuration != nil {
+		data.DynamicPlatform = configuration.DynamicPlatform
+		data.CachedKeys = configuration.CachedKeys
+		data.SMTEnabled = configuration.SMTEnabled
+	}
+
+	var issueDates, expirationDates []time.Time
+	for _, crl := range certObj.GetPckCrlObj() {
+		if crl != nil {
+			issueDates = append(issueDates, crl.TBSCertList.ThisUpdate)
+			expirationDates = append(expirationDates, crl.TBSCertList.NextUpdate)
+		}
+	}
+	for _, date := range []string{tcbObj.GetTcbInfoIssueDate(), qeIDObj.GetQeIDIssueDate()} {
+		if t, err := time.Parse(time.RFC3339, date); err == nil {
+			issueDates = append(issueDates, t)
+		}
+	}
+	for _, date := range []string{tcbObj.GetTcbInfoNextUpdate(), qeIDObj.GetQeIDNextUpdate()} {
+		if t, err := time.Parse(time.RFC3339, date); err == nil {
+			expirationDates = append(expirationDates, t)
+		}
+	}
+
+	earliest, latest := dateRange(issueDates)
+	data.EarliestIssueDate = formatDate(earliest)
+	data.LatestIssueDate = formatDate(latest)
+	earliest, _ = dateRange(expirationDates)
+	data.EarliestExpirationDate = formatDate(earliest)
+	return data
+}
+
+// rootKeyID returns the SHA-384 digest of the public key of the collateral root signer
+func rootKeyID(rootCA *x509.Certificate) string {
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	_, err := asn1.Unmarshal(rootCA.RawSubjectPublicKeyInfo, &spki)
+	if err != nil {
+		log.WithError(err).Error("resource/supplemental_data:rootKeyID() Error decoding root CA public key")
+		return ""
+	}
+	digest := sha512.Sum384(spki.PublicKey.Bytes)
+	return hex.EncodeToString(digest[:])
+}
+
+func dateRange(dates []time.Time) (time.Time, time.Time) {
+	var earliest, latest time.Time
+	for i, date := range dates {
+		if i == 0 || date.Before(earliest) {
+			earliest = date
+		}
+		if i == 0 || date.After(latest) {
+			latest = date
+		}
+	}
+	return earliest, latest
+}
+
+func formatDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.UTC().Format(time.RFC3339)
+}
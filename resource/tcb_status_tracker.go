@@ -0,0 +1,73 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"intel/isecl/sqvs/v4/events"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"intel/isecl/sqvs/v4/types"
+	"time"
+)
+
+// TcbStatusDowngrade is the payload of the tcb-status-downgraded event. PlatformID is the PPID pseudonymized
+// unless the platform identifiers profile is full.
+type TcbStatusDowngrade struct {
+	PlatformID        string    `json:"platformId"`
+	Fmspc             string    `json:"fmspc"`
+	PreviousTcbStatus string    `json:"previousTcbStatus"`
+	CurrentTcbStatus  string    `json:"currentTcbStatus"`
+	PreviousSeenTime  time.Time `json:"previousSeenTime"`
+}
+
+// trackPlatformTcbStatus records the TCB status observed for a platform and raises an alert when
+// it is worse than the status seen during the previous verification of the same platform
+func trackPlatformTcbStatus(platformID, fmspc, tcbStatus string) {
+	log.Trace("resource/tcb_status_tracker:trackPlatformTcbStatus() Entering")
+	defer log.Trace("resource/tcb_status_tracker:trackPlatformTcbStatus() Leaving")
+
+	if sqvsDB == nil || platformID == "" {
+		return
+	}
+
+	// concurrent verifications of the platform each compare against the status the other saved. The
+	// platforms are stored by their pseudonym, which is stable across redaction profiles.
+	previous, err := sqvsDB.PlatformTcbStatusRepository().Swap(&types.PlatformTcbStatus{
+		PlatformID:  pseudonymizePlatformIdentifier(platformID),
+		Fmspc:       fmspc,
+		TcbStatus:   tcbStatus,
+		UpdatedTime: time.Now().UTC(),
+	})
+	if err != nil {
+		log.WithError(err).Error("resource/tcb_status_tracker:trackPlatformTcbStatus() Error saving platform TCB status")
+		return
+	}
+
+	if previous != nil && isTcbStatusDowngrade(previous.TcbStatus, tcbStatus) {
+		exportedID := exportedPlatformIdentifier(platformID)
+		downgrade := TcbStatusDowngrade{
+			PlatformID:        exportedID,
+			Fmspc:             fmspc,
+			PreviousTcbStatus: previous.TcbStatus,
+			CurrentTcbStatus:  tcbStatus,
+			PreviousSeenTime:  previous.UpdatedTime,
+		}
+		slog.Warnf("resource/tcb_status_tracker:trackPlatformTcbStatus() TCB status of platform %s (fmspc %s) "+
+			"downgraded from %s to %s", exportedID, fmspc, previous.TcbStatus, tcbStatus)
+		events.PublishAsync(eventPublisher, events.NewEvent(events.TcbStatusDowngraded, downgrade))
+	}
+}
+
+// isTcbStatusDowngrade returns true if current is a worse TCB status than previous
+func isTcbStatusDowngrade(previous, current string) bool {
+	prevSeverity, ok := parser.TcbStatusSeverity(previous)
+	if !ok {
+		return false
+	}
+	curSeverity, ok := parser.TcbStatusSeverity(current)
+	if !ok {
+		return false
+	}
+	return curSeverity > prevSeverity
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"intel/isecl/sqvs/v4/events"
+	"intel/isecl/sqvs/v4/repository/memory"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testPublisher struct {
+	mu     sync.Mutex
+	events []events.Event
+}
+
+func (p *testPublisher) Publish(event events.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *testPublisher) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.events)
+}
+
+func TestIsTcbStatusDowngrade(t *testing.T) {
+	assert.True(t, isTcbStatusDowngrade("UpToDate", "OutOfDate"))
+	assert.True(t, isTcbStatusDowngrade("SWHardeningNeeded", "Revoked"))
+	assert.False(t, isTcbStatusDowngrade("OutOfDate", "UpToDate"))
+	assert.False(t, isTcbStatusDowngrade("UpToDate", "UpToDate"))
+	assert.False(t, isTcbStatusDowngrade("Unknown", "Revoked"))
+}
+
+func TestTrackPlatformTcbStatus(t *testing.T) {
+	db, err := memory.New("")
+	assert.NoError(t, err)
+	publisher := &testPublisher{}
+	SetRepository(db)
+	SetEventPublisher(publisher)
+	defer func() {
+		SetRepository(nil)
+		SetEventPublisher(events.NoopPublisher{})
+	}()
+
+	trackPlatformTcbStatus("platform1", "00906ed50000", "UpToDate")
+	trackPlatformTcbStatus("platform1", "00906ed50000", "UpToDate")
+	trackPlatformTcbStatus("platform1", "00906ed50000", "OutOfDate")
+
+	assert.Eventually(t, func() bool { return publisher.count() == 1 }, time.Second, 10*time.Millisecond)
+	status, err := db.PlatformTcbStatusRepository().Retrieve(pseudonymizePlatformIdentifier("platform1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "OutOfDate", status.TcbStatus)
+	// the PPID is pseudonymized unless the platform identifiers profile is full
+	publisher.mu.Lock()
+	downgrade := publisher.events[0].Data.(TcbStatusDowngrade)
+	publisher.mu.Unlock()
+	assert.Equal(t, pseudonymizePlatformIdentifier("platform1"), downgrade.PlatformID)
+
+	// concurrent verifications of a platform report its downgrade once
+	trackPlatformTcbStatus("platform2", "00906ed50000", "UpToDate")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			trackPlatformTcbStatus("platform2", "00906ed50000", "OutOfDate")
+		}()
+	}
+	wg.Wait()
+	assert.Eventually(t, func() bool { return publisher.count() == 2 }, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 2, publisher.count())
+}
@@ -0,0 +1,48 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"net/http"
+)
+
+// TcbStatusVerdict is the verdict the TCB status of the platform of a quote was mapped to, Configured is false
+// for the statuses allowed because they are not mapped
+type TcbStatusVerdict struct {
+	TcbStatus  string `json:"TcbStatus"`
+	Verdict    string `json:"Verdict"`
+	Configured bool   `json:"Configured"`
+}
+
+// evaluateTcbStatus maps the TCB status to its configured verdict, quotes with a denied status are rejected
+func evaluateTcbStatus(tcbStatus string) (*TcbStatusVerdict, error) {
+	verdict := &TcbStatusVerdict{TcbStatus: tcbStatus, Verdict: parser.VerdictAllow}
+	conf := config.Global()
+	if conf == nil || len(conf.TcbStatusVerdicts) == 0 {
+		return verdict, nil
+	}
+	verdicts, err := parser.ParseTcbStatusVerdicts(conf.TcbStatusVerdicts)
+	if err != nil {
+		log.WithError(err).Error("resource/tcb_status_verdict:evaluateTcbStatus() Invalid TCB status verdicts")
+		return nil, &resourceError{Message: "Invalid TCB status verdicts configured",
+			StatusCode: http.StatusInternalServerError}
+	}
+	if configured, ok := verdicts[tcbStatus]; ok {
+		verdict.Verdict = configured
+		verdict.Configured = true
+	}
+
+	switch verdict.Verdict {
+	case parser.VerdictDeny:
+		slog.Errorf("resource/tcb_status_verdict:evaluateTcbStatus() TCB status %s of the platform is denied", tcbStatus)
+		return nil, &resourceError{Message: "TCB status " + tcbStatus + " of the platform is not allowed",
+			StatusCode: http.StatusBadRequest, QvResult: tcbStatusQvResult(tcbStatus)}
+	case parser.VerdictWarn:
+		slog.Warnf("resource/tcb_status_verdict:evaluateTcbStatus() Accepting quote with TCB status %s", tcbStatus)
+	}
+	return verdict, nil
+}
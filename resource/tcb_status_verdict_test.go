@@ -0,0 +1,41 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateTcbStatus(t *testing.T) {
+	conf := config.Global()
+	defer func() {
+		conf.TcbStatusVerdicts = nil
+	}()
+
+	verdict, err := evaluateTcbStatus(parser.TcbStatusRevoked)
+	assert.NoError(t, err)
+	assert.Equal(t, &TcbStatusVerdict{TcbStatus: parser.TcbStatusRevoked, Verdict: parser.VerdictAllow}, verdict)
+
+	conf.TcbStatusVerdicts = []string{"outofdate=Warn", "Revoked=deny"}
+	verdict, err = evaluateTcbStatus(parser.TcbStatusOutOfDate)
+	assert.NoError(t, err)
+	assert.Equal(t, &TcbStatusVerdict{TcbStatus: parser.TcbStatusOutOfDate, Verdict: parser.VerdictWarn,
+		Configured: true}, verdict)
+	verdict, err = evaluateTcbStatus(parser.TcbStatusUpToDate)
+	assert.NoError(t, err)
+	assert.False(t, verdict.Configured)
+	_, err = evaluateTcbStatus(parser.TcbStatusRevoked)
+	assert.Equal(t, http.StatusBadRequest, err.(*resourceError).StatusCode)
+
+	for _, entry := range []string{"Revoked", "Unknown=deny", "Revoked=reject"} {
+		_, err = parser.ParseTcbStatusVerdicts([]string{entry})
+		assert.Error(t, err, entry)
+	}
+}
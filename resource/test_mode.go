@@ -0,0 +1,159 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	commLogMsg "intel/isecl/lib/common/v4/log/message"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/handlers"
+	"github.com/gorilla/mux"
+)
+
+// TestQuotePrefix starts the test quotes of the test mode, followed by the name of the canned verdict and
+// optionally padded with zeros or a newline, so they can be sent with every quote content type
+const TestQuotePrefix = "SQVS-TEST-QUOTE:"
+
+// TestModeHeader labels the responses of the test mode
+const TestModeHeader = "X-SQVS-Test-Mode"
+
+// Canned verdicts of the test mode
+const (
+	TestVerdictOK                  = "ok"
+	TestVerdictSWHardeningNeeded   = "sw_hardening_needed"
+	TestVerdictConfigurationNeeded = "configuration_needed"
+	TestVerdictOutOfDate           = "out_of_date"
+	TestVerdictRevoked             = "revoked"
+	TestVerdictInvalidSignature    = "invalid_signature"
+)
+
+// Deterministic enclave identity of the test quotes
+var (
+	testEnclaveIssuer      = strings.Repeat("5e", parser.HashSize)
+	testEnclaveMeasurement = strings.Repeat("7e", parser.HashSize)
+)
+
+// TestModeCB sets the routes of the test mode, served under their own prefix. They return deterministic
+// canned verdicts for test quotes, never verifying a real quote, so relying parties can run integration
+// tests without SGX hardware or live collateral.
+func TestModeCB(router *mux.Router) {
+	router.Handle("/sgx_qv_verify_quote", handlers.ContentTypeHandler(testModeVerifyQuote(), quoteContentTypes...)).Methods("POST")
+}
+
+func testModeVerifyQuote() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/test_mode:testModeVerifyQuote() Entering")
+		defer log.Trace("resource/test_mode:testModeVerifyQuote() Leaving")
+
+		conf := config.Global()
+		if conf == nil {
+			return &resourceError{Message: "Could not read config", StatusCode: http.StatusInternalServerError}
+		}
+		if conf.IncludeToken {
+			err := AuthorizeEndpoint(r, constants.QuoteVerifierGroupName, true)
+			if err != nil {
+				return err
+			}
+		}
+		w.Header().Set(TestModeHeader, "true")
+
+		data, err := decodeQuoteRequest(w, r, false)
+		if err != nil {
+			return err
+		}
+		verdict, ok := testQuoteVerdict(data.QuoteBlob)
+		if !ok {
+			slog.Errorf("resource/test_mode:testModeVerifyQuote() %s: Not a test quote", commLogMsg.InvalidInputBadParam)
+			return &resourceError{Message: "Test mode only verifies test quotes starting with " + TestQuotePrefix,
+				StatusCode: http.StatusBadRequest}
+		}
+		log.Infof("resource/test_mode:testModeVerifyQuote() Returning the canned %s verdict", verdict)
+
+		resp, err := cannedVerdict(verdict)
+		if err != nil {
+			return err
+		}
+		body, err := json.Marshal(resp)
+		if err != nil {
+			return &resourceError{Message: "Error marshalling SGX response in JSON", StatusCode: http.StatusInternalServerError}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(body)
+		if err != nil {
+			return &resourceError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+		return nil
+	}
+}
+
+// testQuoteVerdict returns the verdict a base64 encoded test quote asks for
+func testQuoteVerdict(quoteBlob string) (string, bool) {
+	quote, err := base64.StdEncoding.DecodeString(quoteBlob)
+	if err != nil || !bytes.HasPrefix(quote, []byte(TestQuotePrefix)) {
+		return "", false
+	}
+	verdict := quote[len(TestQuotePrefix):]
+	if end := bytes.IndexAny(verdict, "\x00\r\n"); end >= 0 {
+		verdict = verdict[:end]
+	}
+	return strings.ToLower(strings.TrimSpace(string(verdict))), true
+}
+
+// cannedVerdict returns the response, or the error with its verification steps, of the verdict, shaped like
+// the ones of real quotes
+func cannedVerdict(verdict string) (SGXResponse, error) {
+	steps := newVerificationSteps()
+	tcbStatus := map[string]string{
+		TestVerdictOK:                  parser.TcbStatusUpToDate,
+		TestVerdictSWHardeningNeeded:   parser.TcbStatusSWHardeningNeeded,
+		TestVerdictConfigurationNeeded: parser.TcbStatusConfigurationNeeded,
+		TestVerdictOutOfDate:           parser.TcbStatusOutOfDate,
+		TestVerdictRevoked:             parser.TcbStatusRevoked,
+		TestVerdictInvalidSignature:    parser.TcbStatusUpToDate,
+	}[verdict]
+	if tcbStatus == "" {
+		return SGXResponse{}, &resourceError{Message: "Unknown test verdict " + verdict, StatusCode: http.StatusBadRequest}
+	}
+
+	for _, step := range []string{StepQuoteParse, StepPckChain, StepCrlCheck} {
+		steps.pass(step, "test mode")
+	}
+	if verdict == TestVerdictRevoked {
+		return SGXResponse{}, steps.fail(StepTcbEvaluation, &resourceError{
+			Message: "TCB status " + tcbStatus + " of the platform is not allowed", StatusCode: http.StatusBadRequest,
+			QvResult: tcbStatusQvResult(tcbStatus)})
+	}
+	steps.pass(StepTcbEvaluation, "TCB status "+tcbStatus)
+	steps.pass(StepQeIdentity, "test mode")
+	if verdict == TestVerdictInvalidSignature {
+		return SGXResponse{}, steps.fail(StepQuoteSignature, &resourceError{
+			Message: "Enclave Report Signature Verification failed", StatusCode: http.StatusInternalServerError,
+			QvResult: newQvResult(QvResultInvalidSignature)})
+	}
+	for _, step := range []string{StepQuoteSignature, StepQeReportSignature, StepPolicy} {
+		steps.pass(step, "test mode")
+	}
+
+	var resp SGXResponse
+	resp.Message = "SGX_QL_QV_RESULT_OK"
+	resp.EnclaveIssuer = testEnclaveIssuer
+	resp.EnclaveIssuerProdID = "00"
+	resp.EnclaveMeasurement = testEnclaveMeasurement
+	resp.IsvSvn = "01"
+	resp.TcbLevel = tcbStatus
+	resp.QvResult = tcbStatusQvResult(tcbStatus)
+	resp.Steps = steps
+	resp.TestMode = true
+	return resp, nil
+}
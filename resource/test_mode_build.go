@@ -0,0 +1,11 @@
+//go:build sqvs_testmode
+// +build sqvs_testmode
+
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+// TestModeBuild tells whether the test mode is built in, test builds being made with the sqvs_testmode tag
+const TestModeBuild = true
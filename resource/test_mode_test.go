@@ -0,0 +1,83 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTestModeVerifyQuote(t *testing.T) {
+	config.Global().IncludeToken = false
+	r := mux.NewRouter()
+	TestModeCB(r.PathPrefix("/svs/test/v1/").Subrouter())
+
+	verify := func(contentType string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/svs/test/v1/sgx_qv_verify_quote", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, req)
+		assert.Equal(t, "true", recorder.Header().Get(TestModeHeader))
+		return recorder
+	}
+	quoteJSON := func(quote string) []byte {
+		body, _ := json.Marshal(QuoteData{QuoteBlob: base64.StdEncoding.EncodeToString([]byte(quote))})
+		return body
+	}
+
+	recorder := verify(contentTypeJSON, quoteJSON(TestQuotePrefix+TestVerdictOutOfDate))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var resp SGXResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	assert.True(t, resp.TestMode)
+	assert.Equal(t, parser.TcbStatusOutOfDate, resp.TcbLevel)
+	assert.Equal(t, &QvResult{Code: 0xA002, Name: QvResultOutOfDate}, resp.QvResult)
+	assert.Equal(t, strings.Repeat("7e", parser.HashSize), resp.EnclaveMeasurement)
+	// the members of results are named after their fields
+	var members map[string]json.RawMessage
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &members))
+	for _, member := range []string{"EnclaveDebugMode", "QvResult", "VerificationSteps", "TestMode"} {
+		assert.Contains(t, members, member)
+	}
+	assert.JSONEq(t, `{"Code":40962,"Name":"SGX_QL_QV_RESULT_OUT_OF_DATE"}`, string(members["QvResult"]))
+	var steps []map[string]json.RawMessage
+	assert.NoError(t, json.Unmarshal(members["VerificationSteps"], &steps))
+	for _, member := range []string{"Name", "Status", "Details"} {
+		assert.Contains(t, steps[0], member)
+	}
+
+	// raw test quotes are padded to the minimum quote size
+	raw := make([]byte, constants.MinQuoteSize)
+	copy(raw, TestQuotePrefix+"OK")
+	recorder = verify(contentTypeOctet, raw)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	assert.Equal(t, parser.TcbStatusUpToDate, resp.TcbLevel)
+
+	recorder = verify(contentTypeJSON, quoteJSON(TestQuotePrefix+TestVerdictRevoked+"\n"))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	var verificationErr VerificationError
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &verificationErr))
+	assert.Equal(t, &QvResult{Code: 0xA005, Name: QvResultRevoked}, verificationErr.QvResult)
+	_, err := cannedVerdict(TestVerdictInvalidSignature)
+	assert.Equal(t, StepQuoteSignature, err.(*resourceError).Steps.FailedStep())
+	assert.Equal(t, QvResultInvalidSignature, err.(*resourceError).QvResult.Name)
+
+	recorder = verify(contentTypeJSON, quoteJSON(TestQuotePrefix+"unknown"))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	recorder = verify(contentTypeOctet, bytes.Repeat([]byte{0xab}, constants.MinQuoteSize))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// TrustedProxies are the load balancers whose X-Forwarded-For header is honored, so the address of the caller
+// rather than of the load balancer is found in security logs, anomaly detection and IP filtering
+type TrustedProxies struct {
+	networks []*net.IPNet
+}
+
+// NewTrustedProxies parses the trusted proxies, entries are CIDR blocks or single addresses. It returns nil
+// when there are none.
+func NewTrustedProxies(entries []string) (*TrustedProxies, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	networks, err := parseNetworks(entries)
+	if err != nil {
+		return nil, errors.Wrap(err, "resource/trusted_proxies:NewTrustedProxies() Invalid trusted proxies")
+	}
+	return &TrustedProxies{networks: networks}, nil
+}
+
+// Trusted reports whether the peer address is the one of a trusted proxy
+func (p *TrustedProxies) Trusted(ip net.IP) bool {
+	return p != nil && ip != nil && containsIP(p.networks, ip)
+}
+
+// ClientAddress returns the address of the caller of a request received from remoteAddr. X-Forwarded-For is
+// read from right to left while the hops are trusted proxies, the first other hop being the caller. Entries
+// left of it are ignored as the caller may forge them.
+func (p *TrustedProxies) ClientAddress(remoteAddr string, forwardedFor []string) string {
+	client := remoteAddr
+	if !p.Trusted(net.ParseIP(hostOf(remoteAddr))) {
+		return client
+	}
+	var hops []string
+	for _, header := range forwardedFor {
+		for _, hop := range strings.Split(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(hostOf(hops[i]))
+		if ip == nil {
+			break
+		}
+		client = net.JoinHostPort(ip.String(), "0")
+		if !p.Trusted(ip) {
+			break
+		}
+	}
+	return client
+}
+
+func hostOf(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return strings.Trim(addr, "[]")
+	}
+	return host
+}
+
+// Middleware returns the middleware setting the remote address of the requests forwarded by trusted proxies to
+// the address of their caller, it passes all requests through for nil trusted proxies
+func (p *TrustedProxies) Middleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if p == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.RemoteAddr = p.ClientAddress(r.RemoteAddr, r.Header.Values("X-Forwarded-For"))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -14,7 +14,6 @@ import (
 	"encoding/pem"
 	"fmt"
 	commLog "intel/isecl/lib/common/v4/log"
-	"io/ioutil"
 	"net/url"
 	"strings"
 	"time"
@@ -76,7 +75,7 @@ func IntToBool(i int) bool {
 	}
 }
 
-func CheckDate(issueDate, nextUpdate string) bool {
+func CheckDate(issueDate, nextUpdate string, now time.Time) bool {
 	iDate, err := time.Parse(time.RFC3339, issueDate)
 	if err != nil {
 		log.Error("CheckData: IssueDate parse:" + err.Error())
@@ -89,7 +88,7 @@ func CheckDate(issueDate, nextUpdate string) bool {
 		return false
 	}
 
-	universalTime := time.Now().UTC()
+	universalTime := now.UTC()
 
 	curTimeAfterIssDate := universalTime.After(iDate)
 	curTimeBeforeNextUpdate := universalTime.Before(nUpdate)
@@ -103,42 +102,26 @@ func CheckDate(issueDate, nextUpdate string) bool {
 	}
 }
 
-func GenerateSignature(responseBytes []byte, keyFilePath string, usePSSPadding bool) (string, error) {
+// GenerateSignature signs the SHA-384 digest of responseBytes with an RSA signing key
+func GenerateSignature(responseBytes []byte, signer crypto.Signer, usePSSPadding bool) (string, error) {
 	log.Trace("resource/utils:GenerateSignature() Entering")
 	defer log.Trace("resource/utils:GenerateSignature() Leaving")
 
-	var privateKey *rsa.PrivateKey
-
-	//Get the private key from path
-	priv, err := ioutil.ReadFile(keyFilePath)
-	if err != nil {
-		log.WithError(err).Info("error reading signing private key from file")
-		return "", errors.Wrap(err, "error reading signing private key from file")
-	}
-
-	privPem, _ := pem.Decode(priv)
-	parsedKey, err := x509.ParsePKCS8PrivateKey(privPem.Bytes)
-	if err != nil {
-		log.WithError(err).Info("Cannot parse RSA private key from file")
-		return "", errors.New("Cannot parse RSA private key from file")
-	}
-	privateKey, ok := parsedKey.(*rsa.PrivateKey)
-	if !ok {
-		log.Error("Unable to parse RSA private key")
-		return "", errors.New("Unable to parse RSA private key")
+	if _, ok := signer.Public().(*rsa.PublicKey); !ok {
+		log.Error("Signing key is not an RSA key")
+		return "", errors.New("Signing key is not an RSA key")
 	}
 
 	hash := sha512.Sum384(responseBytes)
-	var signature []byte
+	var opts crypto.SignerOpts = crypto.SHA384
 	if usePSSPadding {
-		signature, err = rsa.SignPSS(rand.Reader, privateKey, crypto.SHA384, hash[:], &rsa.PSSOptions{
+		opts = &rsa.PSSOptions{
 			SaltLength: rsa.PSSSaltLengthAuto,
 			Hash:       crypto.SHA384,
-		})
-	} else {
-		signature, err = rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA384, hash[:])
+		}
 	}
 
+	signature, err := signer.Sign(rand.Reader, hash[:], opts)
 	if err != nil {
 		log.WithError(err).Info("Error signing quote response")
 		return "", errors.Wrap(err, "Error signing quote response")
@@ -0,0 +1,178 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	debugQueryParam = "debug"
+
+	collateralSourceSCS        = "scs"
+	collateralSourceEnrollment = "enrollment"
+
+	cachePckChain           = "pck_chain"
+	cacheEnrolledCollateral = "enrolled_collateral"
+	collateralPckCrlPrefix  = "pck_crl_"
+)
+
+// VerificationDiagnostics are the extended diagnostics of a quote verification returned inline when it is
+// requested with debug=true, to troubleshoot the failures of a client without raising the global log level
+type VerificationDiagnostics struct {
+	TotalTime   string                `json:"TotalTime"`
+	StepTimings []StepTiming          `json:"StepTimings"`
+	Collateral  []CollateralDiagnosis `json:"Collateral,omitempty"`
+	CacheHits   map[string]bool       `json:"CacheHits,omitempty"`
+}
+
+// StepTiming is the time spent in a verification step, Phase telling the collateral and CRL fetches of a
+// step from its computation
+type StepTiming struct {
+	Step     string `json:"Step"`
+	Phase    string `json:"Phase,omitempty"`
+	Duration string `json:"Duration"`
+}
+
+// CollateralDiagnosis identifies a collateral a quote was verified with, where it was obtained from and
+// its version
+type CollateralDiagnosis struct {
+	Collateral              string `json:"Collateral"`
+	Source                  string `json:"Source"`
+	URL                     string `json:"URL,omitempty"`
+	Version                 string `json:"Version,omitempty"`
+	IssueDate               string `json:"IssueDate,omitempty"`
+	NextUpdate              string `json:"NextUpdate,omitempty"`
+	TcbEvaluationDataNumber uint   `json:"TcbEvaluationDataNumber,omitempty"`
+}
+
+// diagnostics collects the VerificationDiagnostics of a quote verification, a nil diagnostics collects
+// nothing so the verification does not pay for diagnostics it was not asked for
+type diagnostics struct {
+	started time.Time
+	lapped  time.Time
+	nested  time.Duration
+	report  VerificationDiagnostics
+}
+
+func newDiagnostics(enabled bool) *diagnostics {
+	if !enabled {
+		return nil
+	}
+	now := time.Now()
+	return &diagnostics{started: now, lapped: now, report: VerificationDiagnostics{CacheHits: map[string]bool{}}}
+}
+
+// lap records the time spent in the step since the previous lap, less the time of the steps recorded
+// within it
+func (d *diagnostics) lap(step string) {
+	if d == nil {
+		return
+	}
+	now := time.Now()
+	d.add(step, now.Sub(d.lapped)-d.nested)
+	d.lapped, d.nested = now, 0
+}
+
+// record records the time spent in a step run within the current lap
+func (d *diagnostics) record(step string, elapsed time.Duration) {
+	if d == nil {
+		return
+	}
+	d.add(step, elapsed)
+	d.nested += elapsed
+}
+
+// recordPhase records the time spent in a collateral or CRL fetch of a step run within the current lap
+func (d *diagnostics) recordPhase(step, phase string, elapsed time.Duration) {
+	if d == nil {
+		return
+	}
+	d.report.StepTimings = append(d.report.StepTimings, StepTiming{Step: step, Phase: phase,
+		Duration: elapsed.String()})
+	d.nested += elapsed
+}
+
+func (d *diagnostics) add(step string, elapsed time.Duration) {
+	d.report.StepTimings = append(d.report.StepTimings, StepTiming{Step: step, Duration: elapsed.String()})
+}
+
+func (d *diagnostics) cache(name string, hit bool) {
+	if d == nil {
+		return
+	}
+	d.report.CacheHits[name] = hit
+}
+
+func (d *diagnostics) tcbInfo(tcbObj *parser.TcbInfoStruct) {
+	if d == nil || tcbObj == nil {
+		return
+	}
+	source := collateralSourceSCS
+	if d.report.CacheHits[cacheEnrolledCollateral] {
+		source = collateralSourceEnrollment
+	}
+	info := tcbObj.TcbInfoData.TcbInfo
+	d.report.Collateral = append(d.report.Collateral, CollateralDiagnosis{Collateral: collateralTcbInfo,
+		Source: source, Version: strconv.Itoa(info.Version), IssueDate: info.IssueDate, NextUpdate: info.NextUpdate,
+		TcbEvaluationDataNumber: info.TcbEvaluationDataNumber})
+}
+
+func (d *diagnostics) qeIdentity(qeIDObj *parser.QeIdentityData) {
+	if d == nil || qeIDObj == nil {
+		return
+	}
+	d.report.Collateral = append(d.report.Collateral, CollateralDiagnosis{Collateral: collateralQeIdentity,
+		Source: collateralSourceSCS, Version: strconv.Itoa(int(qeIDObj.GetQeIDVersion())),
+		IssueDate: qeIDObj.GetQeIDIssueDate(), NextUpdate: qeIDObj.GetQeIDNextUpdate(),
+		TcbEvaluationDataNumber: uint(qeIDObj.GetQeIDTcbEvaluationDataNumber())})
+}
+
+func (d *diagnostics) pckCrls(certObj *parser.PckCert) {
+	if d == nil || certObj == nil {
+		return
+	}
+	for _, source := range certObj.GetPckCrlSources() {
+		d.report.Collateral = append(d.report.Collateral, CollateralDiagnosis{
+			Collateral: collateralPckCrlPrefix + source.CA, Source: source.Source, URL: source.URL,
+			Version: source.CrlNumber, NextUpdate: source.NextUpdate})
+	}
+}
+
+// collected returns the diagnostics collected, nil when they were not requested
+func (d *diagnostics) collected() *VerificationDiagnostics {
+	if d == nil {
+		return nil
+	}
+	d.report.TotalTime = time.Since(d.started).String()
+	return &d.report
+}
+
+// debugRequested tells whether the request asks for diagnostics with debug=true. They reveal the collateral
+// sources and cache state of the service, so only administrators and holders of the QuoteDiagnostics role
+// get them when tokens are required.
+func debugRequested(r *http.Request) (bool, error) {
+	debug, err := strconv.ParseBool(r.URL.Query().Get(debugQueryParam))
+	if err != nil || !debug {
+		return false, nil
+	}
+	if conf := config.Global(); conf != nil && conf.IncludeToken &&
+		AuthorizeEndpoint(r, constants.AdministratorGroupName, true) != nil {
+		err = AuthorizeEndpoint(r, constants.QuoteDiagnosticsGroupName, true)
+		if err != nil {
+			slog.WithError(err).Error("resource/verification_diagnostics: debugRequested() Diagnostics requested " +
+				"without the QuoteDiagnostics role")
+			return false, err
+		}
+	}
+	slog.Infof("resource/verification_diagnostics: debugRequested() Quote verification diagnostics requested by %s",
+		r.RemoteAddr)
+	return true, nil
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnostics(t *testing.T) {
+	var disabled *diagnostics
+	disabled.lap(StepQuoteParse)
+	disabled.cache(cachePckChain, true)
+	assert.Nil(t, disabled.collected())
+	assert.Nil(t, newDiagnostics(false))
+
+	diag := newDiagnostics(true)
+	diag.lap(StepQuoteParse)
+	diag.record(StepCrlCheck, time.Hour)
+	diag.cache(cachePckChain, true)
+	diag.lap(StepPckChain)
+	collected := diag.collected()
+	assert.Len(t, collected.StepTimings, 3)
+	assert.Equal(t, StepCrlCheck, collected.StepTimings[1].Step)
+	pckChain, err := time.ParseDuration(collected.StepTimings[2].Duration)
+	assert.NoError(t, err)
+	assert.True(t, pckChain < time.Minute)
+	assert.True(t, collected.CacheHits[cachePckChain])
+}
+
+func TestDiagnosticsErrorResponse(t *testing.T) {
+	steps := newVerificationSteps()
+	diag := newDiagnostics(true)
+	handler := errorHandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		err := steps.fail(StepQuoteParse, &resourceError{Message: "Cannot parse sgx ecdsa quote",
+			StatusCode: http.StatusBadRequest})
+		diag.lap(StepQuoteParse)
+		err.(*resourceError).Diagnostics = diag.collected()
+		return err
+	})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/svs/v1/sgx_qv_verify_quote?debug=true", nil))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	var body VerificationError
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Equal(t, StepQuoteParse, body.FailedStep)
+	if assert.NotNil(t, body.Diagnostics) {
+		assert.Equal(t, StepQuoteParse, body.Diagnostics.StepTimings[0].Step)
+	}
+}
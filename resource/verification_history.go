@@ -0,0 +1,306 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	commLogMsg "intel/isecl/lib/common/v4/log/message"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/events"
+	"intel/isecl/sqvs/v4/logformat"
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/trustedtime"
+	"intel/isecl/sqvs/v4/types"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+var verificationListSpec = listSpec{
+	FilterFields: []string{"status", "enclaveIssuer", "enclaveMeasurement", "enclaveIssuerProdId", "isvSvn",
+		"tcbLevel", "enclaveDebugMode", "reverifiedFrom", "relyingParty"},
+	SortFields:  []string{"createdTime", "status", "enclaveIssuer", "enclaveMeasurement", "tcbLevel"},
+	DefaultSort: "-createdTime",
+}
+
+func VerificationHistoryCB(router *mux.Router) {
+	router.Handle("/verifications", searchVerifications()).Methods("GET")
+	router.Handle("/verifications/{id}", retrieveVerification()).Methods("GET")
+	router.Handle("/verifications/{id}/reverify", reverifyVerification()).Methods("POST")
+	router.Handle("/verifications/{id}/quote", retrieveRawQuote()).Methods("GET")
+}
+
+// ReverificationRequest chooses the constraints and the result policy a stored quote is re-verified under,
+// none being applied when the request has no body
+type ReverificationRequest struct {
+	Constraints *QuoteConstraints `json:"constraints,omitempty"`
+	Policy      string            `json:"policy,omitempty"`
+}
+
+// ReverificationResponse is the follow-up verification recorded for the re-verification and, when the quote
+// verified, its result
+type ReverificationResponse struct {
+	Verification types.Verification `json:"verification"`
+	Result       *SGXResponse       `json:"result,omitempty"`
+}
+
+func searchVerifications() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/verification_history:searchVerifications() Entering")
+		defer log.Trace("resource/verification_history:searchVerifications() Leaving")
+
+		repo, err := verificationRepository(r)
+		if err != nil {
+			return err
+		}
+		criteria, err := parseListQuery(r, verificationListSpec)
+		if err != nil {
+			return err
+		}
+
+		verifications, total, err := repo.Search(criteria)
+		if err != nil {
+			log.WithError(err).Error("resource/verification_history:searchVerifications() Error searching verifications")
+			return &resourceError{Message: "Error searching verifications", StatusCode: http.StatusInternalServerError}
+		}
+		// raw quotes are only returned, decrypted, to auditors
+		for i := range verifications {
+			verifications[i].Quote = ""
+		}
+		return writeListResponse(w, r, verifications, criteria, total)
+	}
+}
+
+func retrieveVerification() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/verification_history:retrieveVerification() Entering")
+		defer log.Trace("resource/verification_history:retrieveVerification() Leaving")
+
+		repo, err := verificationRepository(r)
+		if err != nil {
+			return err
+		}
+
+		verification, err := repo.Retrieve(mux.Vars(r)["id"])
+		if err == repository.ErrRecordNotFound {
+			return &resourceError{Message: "Verification not found", StatusCode: http.StatusNotFound}
+		} else if err != nil {
+			log.WithError(err).Error("resource/verification_history:retrieveVerification() Error retrieving verification")
+			return &resourceError{Message: "Error retrieving verification", StatusCode: http.StatusInternalServerError}
+		}
+		verification.Quote = ""
+
+		body, err := json.Marshal(verification)
+		if err != nil {
+			return &resourceError{Message: "Error marshalling verification in JSON", StatusCode: http.StatusInternalServerError}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(body)
+		if err != nil {
+			return &resourceError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+		return nil
+	}
+}
+
+func reverifyVerification() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/verification_history:reverifyVerification() Entering")
+		defer log.Trace("resource/verification_history:reverifyVerification() Leaving")
+
+		repo, err := verificationRepository(r)
+		if err != nil {
+			return err
+		}
+
+		var req ReverificationRequest
+		r.Body = http.MaxBytesReader(w, r.Body, constants.MaxQuoteUploadSize)
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&req)
+		if err != nil && err != io.EOF {
+			slog.WithError(err).Errorf("resource/verification_history:reverifyVerification() %s: Failed to decode request body",
+				commLogMsg.InvalidInputBadEncoding)
+			return &resourceError{Message: "Invalid JSON input provided", StatusCode: http.StatusBadRequest}
+		}
+
+		id := mux.Vars(r)["id"]
+		original, err := repo.Retrieve(id)
+		if err == repository.ErrRecordNotFound {
+			return &resourceError{Message: "Verification not found", StatusCode: http.StatusNotFound}
+		} else if err != nil {
+			log.WithError(err).Error("resource/verification_history:reverifyVerification() Error retrieving verification")
+			return &resourceError{Message: "Error retrieving verification", StatusCode: http.StatusInternalServerError}
+		}
+		if original.Quote == "" {
+			return &resourceError{Message: "Raw quote of verification " + id + " was not retained",
+				StatusCode: http.StatusConflict}
+		}
+		quote, err := openRawQuote(original)
+		if err != nil {
+			return err
+		}
+
+		data := QuoteDataWithChallenge{QuoteData: QuoteData{QuoteBlob: quote, Constraints: req.Constraints},
+			Policy: req.Policy}
+		resp, verifyErr := SgxEcdsaQuoteVerify(r.Context(), data)
+		if verifyErr == nil && req.Policy != "" {
+			issuedAt, err := trustedtime.Now()
+			if err != nil {
+				log.WithError(err).Error("Error reading the trusted time")
+				return &resourceError{Message: "Error reading the trusted time", StatusCode: http.StatusInternalServerError}
+			}
+			err = applyResultPolicy(&resp, req.Policy, issuedAt)
+			if err != nil {
+				return err
+			}
+		}
+
+		verification := recordLinkedVerification(callerOf(r), quote, id, resp, verifyErr)
+		reverification := ReverificationResponse{Verification: verification}
+		if verifyErr == nil {
+			reverification.Result = &resp
+		}
+		body, err := json.Marshal(reverification)
+		if err != nil {
+			return &resourceError{Message: "Error marshalling verification in JSON", StatusCode: http.StatusInternalServerError}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(body)
+		if err != nil {
+			return &resourceError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+		return nil
+	}
+}
+
+// verificationRepository authorizes access to the verification history and returns its repository
+func verificationRepository(r *http.Request) (repository.VerificationRepository, error) {
+	conf := config.Global()
+	if conf == nil {
+		return nil, &resourceError{Message: "Could not read config", StatusCode: http.StatusInternalServerError}
+	}
+	if conf.IncludeToken {
+		err := AuthorizeEndpoint(r, constants.AdministratorGroupName, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !conf.EnableVerificationHistory || sqvsDB == nil {
+		return nil, &resourceError{Message: "Verification history is not enabled", StatusCode: http.StatusNotFound}
+	}
+	return sqvsDB.VerificationRepository(), nil
+}
+
+// recordVerification adds the outcome of a quote verification to the verification history, publishes it to
+// the result event consumers and checks it for anomalous quote patterns of the caller. The error of a failed
+// verification is tied to the recorded verification as its problem instance. The base64 encoded
+// quote is kept with the history, encrypted, when raw quote retention is enabled for the tenant of the caller.
+func recordVerification(caller verificationCaller, quote string, resp SGXResponse, verifyErr error) {
+	recordLinkedVerification(caller, quote, "", resp, verifyErr)
+}
+
+// recordLinkedVerification records the outcome of a quote verification as recordVerification does, linked to
+// the verification reverifiedFrom when it re-verified the quote of one, and returns the recorded verification
+func recordLinkedVerification(caller verificationCaller, quote, reverifiedFrom string, resp SGXResponse,
+	verifyErr error) types.Verification {
+	verification := types.Verification{
+		ID:                  newRecordID(),
+		CreatedTime:         time.Now().UTC(),
+		Status:              types.VerificationStatusVerified,
+		Message:             resp.Message,
+		EnclaveIssuer:       resp.EnclaveIssuer,
+		EnclaveMeasurement:  resp.EnclaveMeasurement,
+		EnclaveIssuerProdID: resp.EnclaveIssuerProdID,
+		IsvSvn:              resp.IsvSvn,
+		TcbLevel:            resp.TcbLevel,
+		EnclaveDebugMode:    resp.EnclaveDebugMode,
+		ReverifiedFrom:      reverifiedFrom,
+		RelyingParty:        caller.RelyingParty,
+		RequestSignature:    caller.Signature,
+	}
+	var failedStep string
+	if verifyErr != nil {
+		verification.Status = types.VerificationStatusFailed
+		verification.Message = verifyErr.Error()
+		if rerr, ok := verifyErr.(*resourceError); ok {
+			verification.Message = rerr.Message
+			failedStep = rerr.Steps.FailedStep()
+			// the error response is the problem instance of the recorded verification
+			rerr.Instance = problemInstance(verification.ID)
+		}
+	}
+
+	outcome := logformat.OutcomeSuccess
+	if verifyErr != nil {
+		outcome = logformat.OutcomeFailure
+	}
+	fields := logrus.Fields{
+		logformat.EventField:   logformat.EventVerification,
+		logformat.OutcomeField: outcome,
+		"verification":         verification.ID,
+		"enclaveMeasurement":   verification.EnclaveMeasurement,
+		"tcbLevel":             verification.TcbLevel,
+	}
+	if reverifiedFrom != "" {
+		fields["reverifiedFrom"] = reverifiedFrom
+	}
+	if caller.RelyingParty != "" {
+		fields["relyingParty"] = caller.RelyingParty
+	}
+	if failedStep != "" {
+		fields["failedStep"] = failedStep
+	}
+	slog.WithFields(fields).Infof("resource/verification_history:recordVerification() Quote verification %s: %s", verification.Status,
+		verification.Message)
+	observeVerification(caller, resp, verifyErr)
+	verificationStats.record(verification, failedStep)
+
+	err := resultPublisher.Publish(events.NewEvent(events.VerificationResult, verification))
+	if err != nil {
+		log.WithError(err).Error("resource/verification_history:recordVerification() Error publishing verification result")
+	}
+
+	conf := config.Global()
+	if conf == nil || !conf.EnableVerificationHistory || sqvsDB == nil {
+		return verification
+	}
+	// the raw quote is stored only, not published nor returned with the outcome
+	stored := verification
+	if retainsRawQuote(conf, caller, reverifiedFrom) {
+		stored.Quote, err = sealRawQuote(verification.ID, quote)
+		if err != nil {
+			log.WithError(err).Error("resource/verification_history:recordVerification() Error encrypting raw quote, " +
+				"it is not retained")
+		}
+	}
+	err = sqvsDB.VerificationRepository().Create(&stored)
+	if err != nil {
+		log.WithError(err).Error("resource/verification_history:recordVerification() Error saving verification")
+	}
+	return verification
+}
+
+// newRecordID returns a random (version 4) UUID
+func newRecordID() string {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		log.WithError(err).Error("resource/verification_history:newRecordID() Error reading random bytes")
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/keystore"
+	"intel/isecl/sqvs/v4/repository/memory"
+	"intel/isecl/sqvs/v4/types"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchVerificationsPaging(t *testing.T) {
+	db, err := memory.New("")
+	assert.NoError(t, err)
+	SetRepository(db)
+	defer SetRepository(nil)
+
+	conf := config.Global()
+	conf.IncludeToken = false
+	conf.EnableVerificationHistory = true
+	defer func() { conf.EnableVerificationHistory = false }()
+
+	for i := 0; i < 5; i++ {
+		recordVerification(verificationCaller{}, "", SGXResponse{AdditionalQuoteData: AdditionalQuoteData{Message: "SGX_QL_QV_RESULT_OK"}}, nil)
+	}
+	recordVerification(verificationCaller{}, "", SGXResponse{}, &resourceError{Message: "Cannot verify pck cert", StatusCode: http.StatusBadRequest})
+
+	r := mux.NewRouter()
+	VerificationHistoryCB(r.PathPrefix("/svs/v1/").Subrouter())
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest("GET", "/svs/v1/verifications?status=verified&limit=2&offset=2", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "5", recorder.Header().Get("X-Total-Count"))
+	link := recorder.Header().Get("Link")
+	assert.True(t, strings.Contains(link, `offset=0&status=verified>; rel="prev"`))
+	assert.True(t, strings.Contains(link, `offset=4&status=verified>; rel="next"`))
+
+	var verifications types.Verifications
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &verifications))
+	assert.Len(t, verifications, 2)
+
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest("GET", "/svs/v1/verifications?status=failed", nil))
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &verifications))
+	assert.Len(t, verifications, 1)
+	assert.Equal(t, "Cannot verify pck cert", verifications[0].Message)
+
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest("GET", "/svs/v1/verifications/"+verifications[0].ID, nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest("GET", "/svs/v1/verifications?sort=message", nil))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestReverifyVerification(t *testing.T) {
+	db, err := memory.New("")
+	assert.NoError(t, err)
+	SetRepository(db)
+	defer SetRepository(nil)
+
+	conf := config.Global()
+	conf.IncludeToken = false
+	conf.EnableVerificationHistory = true
+	defer func() { conf.EnableVerificationHistory = false }()
+
+	keyFile, err := ioutil.TempFile("", "sqvs-raw-quote-key")
+	assert.NoError(t, err)
+	defer os.Remove(keyFile.Name())
+	_, err = keyFile.WriteString(base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	assert.NoError(t, err)
+	assert.NoError(t, keyFile.Close())
+	encrypter, err := keystore.NewAESEncrypter(keyFile.Name())
+	assert.NoError(t, err)
+	SetRawQuoteEncrypter(encrypter)
+	defer SetRawQuoteEncrypter(nil)
+
+	recordVerification(verificationCaller{}, "bm90LWEtcXVvdGU=", SGXResponse{}, nil)
+	conf.RetainRawQuotes = true
+	conf.RawQuotes.Tenants = []string{"forensics"}
+	defer func() { conf.RetainRawQuotes, conf.RawQuotes.Tenants = false, nil }()
+	// only the quotes of the tenants retention is enabled for are kept
+	recordVerification(verificationCaller{Tenant: "other"}, "bm90LWEtcXVvdGU=", SGXResponse{}, nil)
+	recordVerification(verificationCaller{Tenant: "forensics"}, "bm90LWEtcXVvdGU=", SGXResponse{}, nil)
+
+	r := mux.NewRouter()
+	VerificationHistoryCB(r.PathPrefix("/svs/v1/").Subrouter())
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest("GET", "/svs/v1/verifications?sort=createdTime", nil))
+	var verifications types.Verifications
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &verifications))
+	assert.Len(t, verifications, 3)
+	for _, v := range verifications {
+		assert.Empty(t, v.Quote)
+	}
+	verifications = append(verifications[:1], verifications[2])
+
+	// raw quotes are encrypted at rest
+	stored, err := db.VerificationRepository().Retrieve(verifications[1].ID)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, stored.Quote)
+	assert.NotContains(t, stored.Quote, "bm90LWEtcXVvdGU=")
+
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest("POST", "/svs/v1/verifications/"+verifications[0].ID+"/reverify", nil))
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest("POST", "/svs/v1/verifications/unknown/reverify", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest("POST", "/svs/v1/verifications/"+verifications[1].ID+"/reverify",
+		strings.NewReader(`{"unknown": true}`)))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest("POST", "/svs/v1/verifications/"+verifications[1].ID+"/reverify", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var reverification ReverificationResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &reverification))
+	assert.Equal(t, types.VerificationStatusFailed, reverification.Verification.Status)
+	assert.Equal(t, verifications[1].ID, reverification.Verification.ReverifiedFrom)
+	assert.Nil(t, reverification.Result)
+
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest("GET", "/svs/v1/verifications/"+reverification.Verification.ID, nil))
+	var followUp types.Verification
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &followUp))
+	assert.Equal(t, verifications[1].ID, followUp.ReverifiedFrom)
+	assert.Empty(t, followUp.Quote)
+
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest("GET", "/svs/v1/verifications/"+followUp.ID+"/quote", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var rawQuote RawQuoteResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rawQuote))
+	assert.Equal(t, "bm90LWEtcXVvdGU=", rawQuote.Quote)
+
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest("GET", "/svs/v1/verifications/"+verifications[0].ID+"/quote", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestListCursor(t *testing.T) {
+	offset, err := decodeCursor(encodeCursor(40))
+	assert.NoError(t, err)
+	assert.Equal(t, 40, offset)
+
+	_, err = decodeCursor("bm90LWEtY3Vyc29y")
+	assert.Error(t, err)
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"intel/isecl/sqvs/v4/metrics"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// panicFingerprintFrames is the number of innermost frames of the code that panicked a fingerprint covers
+const panicFingerprintFrames = 5
+
+var verificationPanicsTotal = metrics.NewCounter("sqvs_verification_panics_total",
+	"Panics recovered while verifying a quote of a batch, by fingerprint of the code that panicked", "fingerprint")
+
+// verifyQuoteIsolated verifies a quote like verifyQuote, the panic of one quote of a batch failing that quote
+// with an internal error rather than the batch and the handler
+func verifyQuoteIsolated(ctx context.Context, data QuoteDataWithChallenge, chains *pckChainCache) (resp SGXResponse,
+	err error) {
+	defer recoverVerificationPanic(&err)
+	return verifyQuote(ctx, data, chains)
+}
+
+// recoverVerificationPanic, deferred, recovers from a panic of the verification and sets err to the internal
+// error the quote fails with. The error and the sqvs_verification_panics_total metric carry the fingerprint of
+// the panic, which the stack logged with it is found by.
+func recoverVerificationPanic(err *error) {
+	p := recover()
+	if p == nil {
+		return
+	}
+	fingerprint := panicFingerprint()
+	verificationPanicsTotal.Inc(fingerprint)
+	log.Errorf("resource/verification_panics:recoverVerificationPanic() Panic verifying quote, fingerprint %s: %v\n%s",
+		fingerprint, p, debug.Stack())
+	*err = &resourceError{Message: "Internal error verifying quote, panic fingerprint " + fingerprint,
+		StatusCode: http.StatusInternalServerError}
+}
+
+// panicFingerprint identifies the code that panicked by the functions of its innermost frames, so the panics of
+// the same bug share their fingerprint across quotes, builds and replicas. It is called by the deferred function
+// recovering from the panic, whose stack still holds the frames that panicked.
+func panicFingerprint() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	var functions []string
+	for len(functions) < panicFingerprintFrames {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			functions = append(functions, frame.Function)
+		}
+		if !more {
+			break
+		}
+	}
+	sum := sha256.Sum256([]byte(strings.Join(functions, "\n")))
+	return hex.EncodeToString(sum[:6])
+}
@@ -0,0 +1,34 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func panickingVerification(index int) (err error) {
+	defer recoverVerificationPanic(&err)
+	quotes := make([]QuoteData, 1)
+	_ = quotes[index]
+	return nil
+}
+
+func TestRecoverVerificationPanic(t *testing.T) {
+	assert.NoError(t, panickingVerification(0))
+
+	err := panickingVerification(3)
+	rerr, ok := err.(*resourceError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusInternalServerError, rerr.StatusCode)
+	fingerprint := rerr.Message[strings.LastIndex(rerr.Message, " ")+1:]
+	assert.Len(t, fingerprint, 12)
+
+	// the fingerprint is that of the code that panicked, whatever the panic value
+	assert.Equal(t, err, panickingVerification(5))
+}
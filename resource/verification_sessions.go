@@ -0,0 +1,324 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	commLogMsg "intel/isecl/lib/common/v4/log/message"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/signingkey"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// sessionNonceInput names the session nonce among the inputs of the report data binding of a quote
+const sessionNonceInput = "sessionNonce"
+
+// VerificationSession is a freshness challenge: the enclave binds Nonce in the report data of its quote, by
+// default the SHA-256 digest of the nonce at the start of the report data as ReportDataBinding declares, and
+// the client submits the quote with SessionToken before ExpiresAt. SessionToken is a JWT signed with the
+// response signing key, whose jti is ID, and can be used once on any replica sharing the SQVS store.
+type VerificationSession struct {
+	ID                string             `json:"sessionId"`
+	SessionToken      string             `json:"sessionToken"`
+	Nonce             string             `json:"nonce"`
+	ExpiresAt         string             `json:"expiresAt"`
+	ReportDataBinding *ReportDataBinding `json:"reportDataBinding"`
+}
+
+// openSession is a session, bound to the subject of the bearer token it was opened with, if any
+type openSession struct {
+	id      string
+	nonce   string
+	subject string
+	expires time.Time
+}
+
+// sessionClaims are the claims of a session token
+type sessionClaims struct {
+	ID        string `json:"jti"`
+	Nonce     string `json:"nonce"`
+	Subject   string `json:"sub"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+func VerificationSessionCB(router *mux.Router) {
+	router.Handle("/sessions", featureGate(config.FeatureVerificationSessions, openVerificationSession())).Methods("POST")
+}
+
+func openVerificationSession() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/verification_sessions:openVerificationSession() Entering")
+		defer log.Trace("resource/verification_sessions:openVerificationSession() Leaving")
+
+		conf := config.Global()
+		if conf == nil {
+			return &resourceError{Message: "Could not read config", StatusCode: http.StatusInternalServerError}
+		}
+		if conf.IncludeToken {
+			err := AuthorizeEndpoint(r, constants.QuoteVerifierGroupName, true)
+			if err != nil {
+				slog.WithError(err).Error("resource/verification_sessions:openVerificationSession() Authorization Error")
+				return err
+			}
+		}
+
+		subject, _ := tokenClaim(r, "sub").(string)
+		session, err := newVerificationSession(subject, sessionTTL())
+		if err != nil {
+			return err
+		}
+		body, err := json.Marshal(session)
+		if err != nil {
+			return &resourceError{Message: "Error marshalling verification session in JSON",
+				StatusCode: http.StatusInternalServerError}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		w.WriteHeader(http.StatusCreated)
+		_, err = w.Write(body)
+		if err != nil {
+			return &resourceError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+		return nil
+	}
+}
+
+// newVerificationSession opens a session valid for ttl and signs its token
+func newVerificationSession(subject string, ttl time.Duration) (*VerificationSession, error) {
+	nonce := make([]byte, constants.SessionNonceSize)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		log.WithError(err).Error("resource/verification_sessions:newVerificationSession() Error reading random bytes")
+		return nil, &resourceError{Message: "Error generating session nonce", StatusCode: http.StatusInternalServerError}
+	}
+	now := time.Now()
+	session := &openSession{
+		id:      newRecordID(),
+		nonce:   base64.StdEncoding.EncodeToString(nonce),
+		subject: subject,
+		expires: now.Add(ttl),
+	}
+	token, err := signSessionToken(session, now)
+	if err != nil {
+		return nil, err
+	}
+	return &VerificationSession{
+		ID:                session.id,
+		SessionToken:      token,
+		Nonce:             session.nonce,
+		ExpiresAt:         session.expires.UTC().Format(time.RFC3339),
+		ReportDataBinding: session.binding(),
+	}, nil
+}
+
+// signSessionToken returns the session token, a JWT signed with the default result signing algorithm whose
+// nonce claim is the session nonce
+func signSessionToken(session *openSession, now time.Time) (string, error) {
+	alg := defaultSigningAlgorithm(config.Global())
+	signingKey, err := signingKeyFor(alg)
+	if err != nil {
+		log.WithError(err).Error("resource/verification_sessions:signSessionToken() Error loading response signing key")
+		return "", &resourceError{Message: "Error loading response signing key",
+			StatusCode: http.StatusInternalServerError}
+	}
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": signingKey.ID})
+	if err != nil {
+		return "", &resourceError{Message: "Error marshalling session token", StatusCode: http.StatusInternalServerError}
+	}
+	claims := map[string]interface{}{
+		"jti":   session.id,
+		"nonce": session.nonce,
+		"iat":   now.Unix(),
+		"exp":   session.expires.Unix(),
+	}
+	if session.subject != "" {
+		claims["sub"] = session.subject
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", &resourceError{Message: "Error marshalling session token", StatusCode: http.StatusInternalServerError}
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature, _, _, err := signWithAlgorithm([]byte(signingInput), alg)
+	if err != nil {
+		return "", err
+	}
+	// signatures are encoded in standard base64, JWS in URL-safe base64
+	raw, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return "", &resourceError{Message: "Failed to sign session token: " + err.Error(),
+			StatusCode: http.StatusInternalServerError}
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// binding returns the report data binding of the session nonce the enclave is expected to use
+func (s *openSession) binding() *ReportDataBinding {
+	return &ReportDataBinding{Hash: bindingHashSHA256, Inputs: []BindingInput{{Name: sessionNonceInput, Value: s.nonce}}}
+}
+
+// consumeSession verifies the session token submitted with a quote by the subject and records its use in
+// the SQVS store, so it cannot be used again on any replica whatever the verdict of the quote
+func consumeSession(token, subject string) (*openSession, error) {
+	session, err := parseSessionToken(token, time.Now())
+	if err != nil {
+		slog.WithError(err).Warnf("resource/verification_sessions:consumeSession() %s: Invalid session token",
+			commLogMsg.InvalidInputBadParam)
+		return nil, &resourceError{Message: "Session token is invalid or expired", StatusCode: http.StatusUnauthorized}
+	}
+	// the use of another subject is refused before it is recorded, which would burn the session of its owner
+	if session.subject != "" && session.subject != subject {
+		slog.Warnf("resource/verification_sessions:consumeSession() %s: Session %s was opened by %s, not %s",
+			commLogMsg.UnauthorizedAccess, session.id, session.subject, subject)
+		return nil, &resourceError{Message: "Session was opened by another subject",
+			StatusCode: http.StatusForbidden}
+	}
+	if sqvsDB == nil {
+		log.Error("resource/verification_sessions:consumeSession() SQVS store is not available to record the " +
+			"session token use")
+		return nil, &resourceError{Message: "Verification sessions are not available",
+			StatusCode: http.StatusInternalServerError}
+	}
+	err = sqvsDB.UsedTokenRepository().Use(sessionTokenUseID(session.id), session.expires)
+	if err == repository.ErrTokenUsed {
+		slog.Warnf("resource/verification_sessions:consumeSession() %s: Session %s was already used",
+			commLogMsg.InvalidInputBadParam, session.id)
+		return nil, &resourceError{Message: "Session token was already used", StatusCode: http.StatusUnauthorized}
+	} else if err != nil {
+		log.WithError(err).Error("resource/verification_sessions:consumeSession() Error recording the session " +
+			"token use")
+		return nil, &resourceError{Message: "Error recording the session token use",
+			StatusCode: http.StatusInternalServerError}
+	}
+	return session, nil
+}
+
+// sessionTokenUseID is the ID the use of the session token is recorded under, apart from other single-use
+// tokens
+func sessionTokenUseID(sessionID string) string {
+	return "session:" + sessionID
+}
+
+// parseSessionToken verifies the signature of the session token with the current or a retired result
+// signing key and returns its session unless it expired
+func parseSessionToken(token string, now time.Time) (*openSession, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("resource/verification_sessions:parseSessionToken() Session token is not a JWT")
+	}
+	encoded, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "resource/verification_sessions:parseSessionToken() Invalid JWT header")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	err = json.Unmarshal(encoded, &header)
+	if err != nil {
+		return nil, errors.Wrap(err, "resource/verification_sessions:parseSessionToken() Invalid JWT header")
+	}
+	key := resultSigningKeyByID(header.Kid)
+	if key == nil || !key.Supports(header.Alg) {
+		return nil, errors.Errorf("resource/verification_sessions:parseSessionToken() Session token is not signed "+
+			"with a result signing key supporting %s", header.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrap(err, "resource/verification_sessions:parseSessionToken() Invalid JWT signature "+
+			"encoding")
+	}
+	err = verifySessionSignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err = base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.Wrap(err, "resource/verification_sessions:parseSessionToken() Invalid JWT claims")
+	}
+	var claims sessionClaims
+	err = json.Unmarshal(encoded, &claims)
+	if err != nil {
+		return nil, errors.Wrap(err, "resource/verification_sessions:parseSessionToken() Invalid JWT claims")
+	}
+	if claims.ID == "" || claims.Nonce == "" {
+		return nil, errors.New("resource/verification_sessions:parseSessionToken() Session token has no jti or nonce")
+	}
+	expires := time.Unix(claims.ExpiresAt, 0)
+	if !now.Before(expires) {
+		return nil, errors.Errorf("resource/verification_sessions:parseSessionToken() Session %s expired at %s",
+			claims.ID, expires.UTC().Format(time.RFC3339))
+	}
+	return &openSession{id: claims.ID, nonce: claims.Nonce, subject: claims.Subject, expires: expires}, nil
+}
+
+// verifySessionSignature verifies the signature of a session token. Tokens signed with PS384 by the response
+// signing key have the salt length of the results it signs rather than that of JWS.
+func verifySessionSignature(alg string, key *signingkey.Key, signingInput, signature []byte) error {
+	switch alg {
+	case signingkey.AlgorithmEdDSA:
+		pub, _ := key.Certificate.PublicKey.(ed25519.PublicKey)
+		if !ed25519.Verify(pub, signingInput, signature) {
+			return errors.New("resource/verification_sessions:verifySessionSignature() Signature verification failed")
+		}
+		return nil
+	case signingkey.AlgorithmPS384:
+		pub, _ := key.Certificate.PublicKey.(*rsa.PublicKey)
+		digest := sha512.Sum384(signingInput)
+		err := rsa.VerifyPSS(pub, crypto.SHA384, digest[:], signature,
+			&rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto, Hash: crypto.SHA384})
+		return errors.Wrap(err, "resource/verification_sessions:verifySessionSignature() Signature verification failed")
+	}
+	return verifyJWSSignature(alg, key.Certificate.PublicKey, signingInput, signature)
+}
+
+// bindSession requires the quote to bind the session nonce in its report data. The binding of the request
+// defaults to that of the session and must otherwise have the nonce among its inputs.
+func bindSession(data *QuoteDataWithChallenge, session *openSession) error {
+	if data.ReportDataBinding == nil {
+		data.ReportDataBinding = session.binding()
+		return nil
+	}
+	for _, input := range data.ReportDataBinding.Inputs {
+		if input.Value == session.nonce {
+			return nil
+		}
+	}
+	return &resourceError{Message: "Report data binding does not bind the session nonce",
+		StatusCode: http.StatusBadRequest}
+}
+
+// checkSessionBound fails the verification of a quote submitted with a session whose report data does not
+// bind the session nonce, the quote may have been produced before the session was opened
+func checkSessionBound(resp *SGXResponse) error {
+	if resp.ReportDataBinding == nil || !resp.ReportDataBinding.Bound {
+		log.Info("resource/verification_sessions:checkSessionBound() Report data does not bind the session nonce")
+		return &resourceError{Message: "Report data does not bind the session nonce",
+			StatusCode: http.StatusBadRequest}
+	}
+	return nil
+}
+
+func sessionTTL() time.Duration {
+	if conf := config.Global(); conf != nil && conf.SessionTTL > 0 {
+		return conf.SessionTTL
+	}
+	return constants.DefaultSessionTTL
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"intel/isecl/sqvs/v4/keystore"
+	"intel/isecl/sqvs/v4/repository/memory"
+	"intel/isecl/sqvs/v4/signingkey"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// useTestSigningKey makes a new RSA key the response signing key until the returned function is called
+func useTestSigningKey(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "sqvs-signing")
+	assert.NoError(t, err)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "SQVS Signing Certificate"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	keyFile := filepath.Join(dir, "signing.key")
+	certFile := filepath.Join(dir, "signing.pem")
+	assert.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer}), 0600))
+	assert.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+
+	previous := signingKeys
+	SetSigningKeys(signingkey.NewRing(keystore.FileKeyStore{}, keyFile, certFile, "", 0, false))
+	return func() {
+		SetSigningKeys(previous)
+		os.RemoveAll(dir)
+	}
+}
+
+func openTestSession(t *testing.T, subject string, ttl time.Duration) *VerificationSession {
+	session, err := newVerificationSession(subject, ttl)
+	assert.NoError(t, err)
+	return session
+}
+
+func TestConsumeSession(t *testing.T) {
+	defer useTestSigningKey(t)()
+	db, err := memory.New("")
+	assert.NoError(t, err)
+	SetRepository(db)
+	defer SetRepository(nil)
+
+	session := openTestSession(t, "", time.Minute)
+	consumed, err := consumeSession(session.SessionToken, "")
+	assert.NoError(t, err)
+	assert.Equal(t, session.ID, consumed.id)
+	assert.Equal(t, session.Nonce, consumed.nonce)
+	_, err = consumeSession(session.SessionToken, "")
+	assert.Equal(t, http.StatusUnauthorized, err.(*resourceError).StatusCode)
+
+	session = openTestSession(t, "", -time.Second)
+	_, err = consumeSession(session.SessionToken, "")
+	assert.Equal(t, http.StatusUnauthorized, err.(*resourceError).StatusCode)
+
+	// the claims of the token cannot be changed
+	session = openTestSession(t, "client-a", time.Minute)
+	parts := strings.Split(session.SessionToken, ".")
+	claims, err := json.Marshal(map[string]interface{}{"jti": "forged", "nonce": session.Nonce,
+		"exp": time.Now().Add(time.Hour).Unix()})
+	assert.NoError(t, err)
+	forged := parts[0] + "." + base64.RawURLEncoding.EncodeToString(claims) + "." + parts[2]
+	_, err = consumeSession(forged, "client-b")
+	assert.Equal(t, http.StatusUnauthorized, err.(*resourceError).StatusCode)
+
+	_, err = consumeSession(session.SessionToken, "client-b")
+	assert.Equal(t, http.StatusForbidden, err.(*resourceError).StatusCode)
+	// the use of another subject does not burn the session of its owner, which can use it once
+	used, err := consumeSession(session.SessionToken, "client-a")
+	assert.NoError(t, err)
+	assert.Equal(t, "client-a", used.subject)
+	_, err = consumeSession(session.SessionToken, "client-a")
+	assert.Equal(t, http.StatusUnauthorized, err.(*resourceError).StatusCode)
+}
+
+func TestBindSession(t *testing.T) {
+	session := &openSession{id: newRecordID(), nonce: base64.StdEncoding.EncodeToString([]byte("binding")),
+		expires: time.Now().Add(time.Minute)}
+
+	// the session binding is the default, the SHA-256 digest of the nonce
+	var data QuoteDataWithChallenge
+	assert.NoError(t, bindSession(&data, session))
+	nonce, _ := base64.StdEncoding.DecodeString(session.nonce)
+	digest := sha256.Sum256(nonce)
+	reportData := append(digest[:], make([]byte, 32)...)
+	result := data.ReportDataBinding.evaluate(reportData)
+	assert.NoError(t, checkSessionBound(&SGXResponse{AdditionalQuoteData: AdditionalQuoteData{ReportDataBinding: result}}))
+
+	result = data.ReportDataBinding.evaluate(make([]byte, 64))
+	assert.Error(t, checkSessionBound(&SGXResponse{AdditionalQuoteData: AdditionalQuoteData{ReportDataBinding: result}}))
+
+	// a binding of the request must bind the nonce
+	data.ReportDataBinding = &ReportDataBinding{Hash: bindingHashSHA256, Inputs: []BindingInput{{Value: "a2V5"}}}
+	assert.Error(t, bindSession(&data, session))
+	data.ReportDataBinding.Inputs = append(data.ReportDataBinding.Inputs, BindingInput{Value: session.nonce})
+	assert.NoError(t, bindSession(&data, session))
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"intel/isecl/sqvs/v4/resource/parser"
+	"net/http"
+	"strings"
+)
+
+const (
+	StepQuoteParse        = "quote_parse"
+	StepPckChain          = "pck_chain"
+	StepCrlCheck          = "crl_check"
+	StepTcbEvaluation     = "tcb_evaluation"
+	StepQeIdentity        = "qe_identity"
+	StepQuoteSignature    = "quote_signature"
+	StepQeReportSignature = "qe_report_signature"
+	StepPolicy            = "policy"
+
+	StepPassed  = "passed"
+	StepFailed  = "failed"
+	StepSkipped = "skipped"
+)
+
+// verificationStepOrder lists the steps of a quote verification in the order they are reported
+var verificationStepOrder = []string{StepQuoteParse, StepPckChain, StepCrlCheck, StepTcbEvaluation, StepQeIdentity,
+	StepQuoteSignature, StepQeReportSignature, StepPolicy}
+
+// VerificationStep is the outcome of a step of a quote verification, steps not run because an earlier one
+// failed are skipped
+type VerificationStep struct {
+	Name    string `json:"Name"`
+	Status  string `json:"Status"`
+	Details string `json:"Details,omitempty"`
+}
+
+// VerificationSteps are the steps of a quote verification, in the order of verificationStepOrder
+type VerificationSteps []VerificationStep
+
+// stepError attributes an error to the step it occurred in, for helpers running several steps
+type stepError struct {
+	step string
+	err  error
+}
+
+func (e *stepError) Error() string {
+	return e.err.Error()
+}
+
+func newVerificationSteps() VerificationSteps {
+	steps := make(VerificationSteps, len(verificationStepOrder))
+	for i, name := range verificationStepOrder {
+		steps[i] = VerificationStep{Name: name, Status: StepSkipped}
+	}
+	return steps
+}
+
+func (s VerificationSteps) set(name, status, details string) {
+	for i := range s {
+		if s[i].Name == name {
+			s[i].Status = status
+			s[i].Details = details
+			return
+		}
+	}
+}
+
+func (s VerificationSteps) pass(name, details string) {
+	s.set(name, StepPassed, details)
+}
+
+// fail records the failure of the step, or of the step a stepError is attributed to, and returns the error
+// as a resourceError carrying a copy of the steps
+func (s VerificationSteps) fail(name string, err error) error {
+	if serr, ok := err.(*stepError); ok {
+		name, err = serr.step, serr.err
+	}
+	var failed resourceError
+	switch t := err.(type) {
+	case *resourceError:
+		failed = *t
+	default:
+		failed = resourceError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+	s.set(name, StepFailed, failed.Message)
+	failed.Steps = append(VerificationSteps{}, s...)
+	return &failed
+}
+
+// FailedStep returns the name of the failed step, an empty string when none failed
+func (s VerificationSteps) FailedStep() string {
+	for _, step := range s {
+		if step.Status == StepFailed {
+			return step.Name
+		}
+	}
+	return ""
+}
+
+// FailureOf returns the status code of the error a quote verification failed with and its steps, nil when it
+// failed before the quote was parsed
+func FailureOf(err error) (int, VerificationSteps) {
+	if rerr, ok := err.(*resourceError); ok {
+		return rerr.StatusCode, rerr.Steps
+	}
+	return http.StatusInternalServerError, nil
+}
+
+// crlSourcesDetails describes where the PCK CRLs checked were obtained from
+func crlSourcesDetails(sources []parser.PckCrlSource) string {
+	details := make([]string, 0, len(sources))
+	for _, source := range sources {
+		details = append(details, source.CA+" CA CRL from "+source.Source)
+	}
+	return strings.Join(details, ", ")
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerificationStepsFail(t *testing.T) {
+	steps := newVerificationSteps()
+	steps.pass(StepQuoteParse, "")
+	err := steps.fail(StepPckChain, &stepError{step: StepCrlCheck, err: &resourceError{Message: "Cannot verify PCK crl",
+		StatusCode: http.StatusBadRequest}})
+
+	rerr, ok := err.(*resourceError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusBadRequest, rerr.StatusCode)
+	assert.Equal(t, StepCrlCheck, rerr.Steps.FailedStep())
+	assert.Len(t, rerr.Steps, len(verificationStepOrder))
+	assert.Equal(t, StepPassed, rerr.Steps[0].Status)
+	assert.Equal(t, StepSkipped, rerr.Steps[1].Status)
+	assert.Equal(t, VerificationStep{Name: StepCrlCheck, Status: StepFailed, Details: "Cannot verify PCK crl"}, rerr.Steps[2])
+	assert.Equal(t, StepSkipped, rerr.Steps[len(rerr.Steps)-1].Status)
+}
+
+func TestVerificationStepsErrorResponse(t *testing.T) {
+	steps := newVerificationSteps()
+	handler := errorHandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return steps.fail(StepQuoteParse, &resourceError{Message: "Cannot parse sgx ecdsa quote",
+			StatusCode: http.StatusBadRequest})
+	})
+
+	req := httptest.NewRequest("POST", "/svs/v1/sgx_qv_verify_quote", nil)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, ProblemContentType, recorder.Header().Get("Content-Type"))
+	var body VerificationError
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Equal(t, ProblemType(ProblemVerificationFailed), body.Type)
+	assert.Equal(t, "Cannot parse sgx ecdsa quote", body.Detail)
+	assert.Equal(t, StepQuoteParse, body.FailedStep)
+	assert.Len(t, body.Steps, len(verificationStepOrder))
+	// the extension members are named like the members of successful results
+	var members map[string]json.RawMessage
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &members))
+	for _, member := range []string{"type", "detail", "FailedStep", "VerificationSteps"} {
+		assert.Contains(t, members, member)
+	}
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"context"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/metrics"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"net/http"
+	"time"
+)
+
+const (
+	phaseCollateralFetch = "collateral_fetch"
+	phaseCRLFetch        = "crl_fetch"
+	phaseCompute         = "compute"
+)
+
+var (
+	verificationPhaseSeconds = metrics.NewCounter("sqvs_verification_phase_seconds_total",
+		"Time spent by the quote verifications in each step fetching collateral, fetching CRLs and computing",
+		"step", "phase")
+	verificationTimeoutsTotal = metrics.NewCounter("sqvs_verification_timeouts_total",
+		"Quote verifications that exceeded the collateral fetch, CRL fetch or compute timeout", "phase")
+	verificationsCanceledTotal = metrics.NewCounter("sqvs_verifications_canceled_total",
+		"Quote verifications abandoned in each step because their client disconnected", "step")
+)
+
+// statusClientClosedRequest is the status, as nginx logs it, of the verifications abandoned because their
+// client disconnected. It never reaches the client, it tells the access log and metrics why they stopped.
+const statusClientClosedRequest = 499
+
+// verificationClock splits the time of a quote verification between the collateral and CRL fetches, bounded
+// by their own timeouts, and the computation. The computation is bounded by a context whose deadline is the
+// compute time left, renewed after every fetch so the fetches are not charged to it. Both are canceled with
+// the context of the request, so the verification stops when its client disconnects.
+type verificationClock struct {
+	parent   context.Context
+	limit    time.Duration
+	computed time.Duration
+	resumed  time.Time
+	lapped   time.Time
+	fetched  time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+	diag     *diagnostics
+}
+
+func newVerificationClock(ctx context.Context, diag *diagnostics) *verificationClock {
+	limit := constants.DefaultVerificationComputeTimeout
+	if conf := config.Global(); conf != nil && conf.VerificationComputeTimeout > 0 {
+		limit = conf.VerificationComputeTimeout
+	}
+	c := &verificationClock{parent: ctx, limit: limit, lapped: time.Now(), diag: diag}
+	c.resume()
+	return c
+}
+
+func (c *verificationClock) resume() {
+	c.resumed = time.Now()
+	c.ctx, c.cancel = context.WithTimeout(c.parent, c.limit-c.computed)
+}
+
+func (c *verificationClock) pause() {
+	c.computed += time.Since(c.resumed)
+	c.cancel()
+}
+
+// stop releases the compute context once the verification is over
+func (c *verificationClock) stop() {
+	c.cancel()
+}
+
+// fetch runs the collateral or CRL fetch of the step, phase telling which, off the compute time. The fetch
+// is canceled with the request.
+func (c *verificationClock) fetch(step, phase string, fn func(ctx context.Context) error) error {
+	c.pause()
+	started := time.Now()
+	err := fn(c.parent)
+	elapsed := time.Since(started)
+	c.resume()
+
+	c.fetched += elapsed
+	c.diag.recordPhase(step, phase, elapsed)
+	verificationPhaseSeconds.Add(elapsed.Seconds(), step, phase)
+	if parser.IsFetchTimeout(err) {
+		verificationTimeoutsTotal.Inc(phase)
+	} else if parser.IsFetchCanceled(err) {
+		verificationsCanceledTotal.Inc(step)
+	}
+	return err
+}
+
+// lap accounts the compute time of the step since the previous lap and fails it when the compute timeout
+// has been exceeded
+func (c *verificationClock) lap(step string) error {
+	now := time.Now()
+	verificationPhaseSeconds.Add((now.Sub(c.lapped) - c.fetched).Seconds(), step, phaseCompute)
+	c.lapped, c.fetched = now, 0
+	if c.parent.Err() != nil {
+		verificationsCanceledTotal.Inc(step)
+		log.Warnf("resource/verification_timeouts:lap() Quote verification abandoned in step %s, the client "+
+			"disconnected", step)
+		return clientClosedRequest()
+	}
+	if c.ctx.Err() != nil {
+		verificationTimeoutsTotal.Inc(phaseCompute)
+		log.Errorf("resource/verification_timeouts:lap() Quote verification exceeded the compute timeout of %v "+
+			"in step %s", c.limit, step)
+		return &resourceError{Message: "Quote verification exceeded the compute timeout",
+			StatusCode: http.StatusServiceUnavailable}
+	}
+	return nil
+}
+
+// clientClosedRequest is the error of a verification abandoned because its client disconnected
+func clientClosedRequest() *resourceError {
+	return &resourceError{Message: "Quote verification canceled, the client closed the request",
+		StatusCode: statusClientClosedRequest}
+}
+
+// fetchFailure is the error returned for a failed collateral or CRL fetch, a gateway timeout when the fetch
+// timed out. It tells whether SCS could not be reached, in time or at all.
+func fetchFailure(err error, message string, statusCode int) *resourceError {
+	if parser.IsFetchCanceled(err) {
+		return clientClosedRequest()
+	}
+	if parser.IsFetchTimeout(err) {
+		return &resourceError{Message: message + ": timed out", StatusCode: http.StatusGatewayTimeout,
+			collateralUnreachable: true}
+	}
+	return &resourceError{Message: message, StatusCode: statusCode,
+		collateralUnreachable: parser.IsCollateralUnreachable(err)}
+}
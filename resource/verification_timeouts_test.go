@@ -0,0 +1,73 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"context"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerificationClock(t *testing.T) {
+	conf := config.Global()
+	defer func(timeout time.Duration) { conf.VerificationComputeTimeout = timeout }(conf.VerificationComputeTimeout)
+	conf.VerificationComputeTimeout = 50 * time.Millisecond
+
+	diag := newDiagnostics(true)
+	clock := newVerificationClock(context.Background(), diag)
+	defer clock.stop()
+
+	// fetches are not charged to the compute time
+	err := clock.fetch(StepCrlCheck, phaseCRLFetch, func(ctx context.Context) error {
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, clock.lap(StepPckChain))
+	diag.lap(StepPckChain)
+	assert.Equal(t, StepCrlCheck, diag.report.StepTimings[0].Step)
+	assert.Equal(t, phaseCRLFetch, diag.report.StepTimings[0].Phase)
+	assert.Empty(t, diag.report.StepTimings[1].Phase)
+
+	time.Sleep(100 * time.Millisecond)
+	err = clock.lap(StepTcbEvaluation)
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusServiceUnavailable, err.(*resourceError).StatusCode)
+	}
+}
+
+func TestVerificationClockCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	clock := newVerificationClock(ctx, nil)
+	defer clock.stop()
+
+	// the fetches and the computation are canceled with the request
+	err := clock.fetch(StepQeIdentity, phaseCollateralFetch, func(ctx context.Context) error {
+		cancel()
+		<-ctx.Done()
+		return nil
+	})
+	assert.NoError(t, err)
+	err = clock.lap(StepQeIdentity)
+	if assert.Error(t, err) {
+		assert.Equal(t, statusClientClosedRequest, err.(*resourceError).StatusCode)
+	}
+}
+
+func TestFetchFailure(t *testing.T) {
+	timedOut := errors.Wrap(errors.Wrap(parser.ErrFetchTimeout, "fetch"), "NewTcbInfo")
+	assert.Equal(t, http.StatusGatewayTimeout, fetchFailure(timedOut, "Cannot fetch", http.StatusBadRequest).StatusCode)
+	assert.Equal(t, http.StatusBadRequest, fetchFailure(errors.New("refused"), "Cannot fetch",
+		http.StatusBadRequest).StatusCode)
+	canceled := errors.Wrap(parser.ErrFetchCanceled, "NewQeIdentity")
+	assert.Equal(t, statusClientClosedRequest, fetchFailure(canceled, "Cannot fetch",
+		http.StatusBadRequest).StatusCode)
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"crypto/x509"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/metrics"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"sync"
+	"time"
+)
+
+const cacheVerifiedPckChain = "verified_pck_chain"
+
+var verifiedPckChainsTotal = metrics.NewCounter("sqvs_verified_pck_chains_total",
+	"Lookups of the verified PCK certificate chains by result, hit, miss or invalidated", "result")
+
+// verifiedPckChains caches the PCK certificate chains verified for the quotes of hot platforms, keyed by
+// the hash of the PCK leaf certificate and its issuer chain. A chain is reused while its CRLs are the latest
+// seen for their CA, are not due for update and the cache TTL has not elapsed, so revocations are picked up
+// at the latest when the chain is verified again.
+var verifiedPckChains = &verifiedPckChainCache{entries: map[string]*verifiedPckChain{}, crlNumbers: map[string]string{}}
+
+type verifiedPckChainCache struct {
+	mu      sync.Mutex
+	entries map[string]*verifiedPckChain
+	// crlNumbers are the latest CRL numbers seen by PCK CA
+	crlNumbers map[string]string
+}
+
+type verifiedPckChain struct {
+	certObj   *parser.PckCert
+	crls      []parser.PckCrlSource
+	notBefore time.Time
+	notAfter  time.Time
+	expires   time.Time
+}
+
+func pckChainCacheTTL() time.Duration {
+	if conf := config.Global(); conf != nil {
+		return conf.PckChainCacheTTL
+	}
+	return constants.DefaultPckChainCacheTTL
+}
+
+// lookup returns the parsed PCK certificate of a chain verified earlier, when it still holds at the time
+// the chain is evaluated at and its CRLs are current
+func (c *verifiedPckChainCache) lookup(key string, now, at time.Time) *parser.PckCert {
+	if pckChainCacheTTL() <= 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		verifiedPckChainsTotal.Inc("miss")
+		return nil
+	}
+	if !now.Before(entry.expires) || !c.currentCrls(entry.crls) {
+		delete(c.entries, key)
+		verifiedPckChainsTotal.Inc("invalidated")
+		return nil
+	}
+	if at.Before(entry.notBefore) || at.After(entry.notAfter) {
+		verifiedPckChainsTotal.Inc("miss")
+		return nil
+	}
+	verifiedPckChainsTotal.Inc("hit")
+	return entry.certObj
+}
+
+// store caches a verified chain and records its CRL numbers, a newer CRL invalidating the chains verified
+// against the previous one
+func (c *verifiedPckChainCache) store(key string, certObj *parser.PckCert, chain []*x509.Certificate, now time.Time) {
+	ttl := pckChainCacheTTL()
+	if ttl <= 0 {
+		return
+	}
+	crls := certObj.GetPckCrlSources()
+	expires := now.Add(ttl)
+	for _, crl := range crls {
+		nextUpdate, err := time.Parse(time.RFC3339, crl.NextUpdate)
+		if err != nil {
+			return
+		}
+		if nextUpdate.Before(expires) {
+			expires = nextUpdate
+		}
+	}
+	notBefore, notAfter := certValidity(chain...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, crl := range crls {
+		c.crlNumbers[crl.CA] = crl.CrlNumber
+	}
+	if len(c.entries) >= constants.MaxVerifiedPckChains {
+		c.purge(now)
+		if len(c.entries) >= constants.MaxVerifiedPckChains {
+			return
+		}
+	}
+	c.entries[key] = &verifiedPckChain{certObj: certObj, crls: crls, notBefore: notBefore, notAfter: notAfter,
+		expires: expires}
+}
+
+func (c *verifiedPckChainCache) currentCrls(crls []parser.PckCrlSource) bool {
+	for _, crl := range crls {
+		if c.crlNumbers[crl.CA] != crl.CrlNumber {
+			return false
+		}
+	}
+	return true
+}
+
+// purge drops the chains that expired or whose CRLs were superseded
+func (c *verifiedPckChainCache) purge(now time.Time) {
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) || !c.currentCrls(entry.crls) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// InvalidateVerifiedPckChains drops the cached PCK certificate chains, for the PCK CRLs imported with the crl
+// import command to apply to the next quotes
+func InvalidateVerifiedPckChains() {
+	verifiedPckChains.invalidate()
+}
+
+func (c *verifiedPckChainCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]*verifiedPckChain{}
+	c.crlNumbers = map[string]string{}
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"crypto/x509"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifiedPckChains(t *testing.T) {
+	conf := config.Global()
+	defer func(ttl time.Duration) { conf.PckChainCacheTTL = ttl }(conf.PckChainCacheTTL)
+	conf.PckChainCacheTTL = time.Hour
+
+	now := time.Now()
+	certObj := func(number string, nextUpdate time.Time) *parser.PckCert {
+		obj := &parser.PckCert{}
+		obj.PckCRL.Sources = []parser.PckCrlSource{{CA: parser.PckCAProcessor, Source: parser.CrlSourceSCS,
+			CrlNumber: number, NextUpdate: nextUpdate.Format(time.RFC3339)}}
+		return obj
+	}
+	chain := []*x509.Certificate{{NotBefore: now.Add(-time.Hour), NotAfter: now.Add(24 * time.Hour)}}
+
+	cache := &verifiedPckChainCache{entries: map[string]*verifiedPckChain{}, crlNumbers: map[string]string{}}
+	assert.Nil(t, cache.lookup("a", now, now))
+	first := certObj("1", now.Add(24*time.Hour))
+	cache.store("a", first, chain, now)
+	assert.Equal(t, first, cache.lookup("a", now, now))
+	// outside of the validity of the chain
+	assert.Nil(t, cache.lookup("a", now, now.Add(48*time.Hour)))
+	// past the TTL
+	assert.Nil(t, cache.lookup("a", now.Add(2*time.Hour), now))
+
+	// a newer CRL seen for another chain invalidates the chains verified against the previous one
+	cache.store("a", first, chain, now)
+	cache.store("b", certObj("2", now.Add(24*time.Hour)), chain, now)
+	assert.Nil(t, cache.lookup("a", now, now))
+	assert.NotNil(t, cache.lookup("b", now, now))
+
+	// chains are verified again once their CRLs are due for update
+	cache.store("c", certObj("2", now.Add(time.Minute)), chain, now)
+	assert.Nil(t, cache.lookup("c", now.Add(2*time.Minute), now))
+
+	cache.invalidate()
+	assert.Nil(t, cache.lookup("b", now, now))
+
+	conf.PckChainCacheTTL = 0
+	cache.store("d", certObj("2", now.Add(24*time.Hour)), chain, now)
+	assert.Nil(t, cache.lookup("d", now, now))
+}
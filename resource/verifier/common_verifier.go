@@ -12,6 +12,7 @@ import (
 	"encoding/asn1"
 	clog "intel/isecl/lib/common/v4/log"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -28,6 +29,12 @@ var ExtSgxPCEIDOid = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 3}
 var ExtSgxFMSPCOid = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 4}
 var ExtSgxSGXTypeOid = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 5}
 var ExtSgxTcbPceSvnOid = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 2, 17}
+var ExtSgxPlatformInstanceIDOid = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 6}
+var ExtSgxConfigurationOid = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 7}
+var ExtSgxDynamicPlatformOid = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 7, 1}
+var ExtSgxCachedKeysOid = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 7, 2}
+var ExtSgxSMTEnabledOid = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 7, 3}
+var ExtCRLNumberOid = asn1.ObjectIdentifier{2, 5, 29, 20}
 
 var log = clog.GetDefaultLogger()
 
@@ -105,7 +112,8 @@ func verifyCaSubject(input, cmpStr string) bool {
 	return false
 }
 
-func verifyInterCaCert(interCA *x509.Certificate, rootCA []*x509.Certificate, subjectStr string) error {
+// verifyInterCaCert verifies the intermediate CA certificate was valid at the given time
+func verifyInterCaCert(interCA *x509.Certificate, rootCA []*x509.Certificate, subjectStr string, at time.Time) error {
 	if !verifyCaSubject(interCA.Subject.String(), subjectStr) {
 		return errors.New("verifyInterCaCert: Invalid Certificate Subject: " + interCA.Subject.String() +
 			"did not match with " + subjectStr)
@@ -116,6 +124,7 @@ func verifyInterCaCert(interCA *x509.Certificate, rootCA []*x509.Certificate, su
 	}
 
 	var opts x509.VerifyOptions
+	opts.CurrentTime = at
 	opts.Roots = x509.NewCertPool()
 	for i := 0; i < len(rootCA); i++ {
 		opts.Roots.AddCert(rootCA[i])
@@ -127,8 +136,10 @@ func verifyInterCaCert(interCA *x509.Certificate, rootCA []*x509.Certificate, su
 	return nil
 }
 
-func verifyRootCaCert(rootCA *x509.Certificate, subjectStr string) error {
+// verifyRootCaCert verifies the root CA certificate was valid at the given time
+func verifyRootCaCert(rootCA *x509.Certificate, subjectStr string, at time.Time) error {
 	var opts x509.VerifyOptions
+	opts.CurrentTime = at
 
 	if strings.Compare(subjectStr, rootCA.Subject.String()) != 0 {
 		return errors.New("verifyRootCaCert: Invalid Certificate Subject: " + rootCA.Subject.String())
@@ -0,0 +1,83 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package verifier
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"intel/isecl/sqvs/v4/constants"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// CollateralTcbInfo and CollateralQeIdentity are the fields of the TCB info and QE identity JSON the
+	// signature is computed over
+	CollateralTcbInfo    = "tcbInfo"
+	CollateralQeIdentity = "enclaveIdentity"
+
+	collateralSignatureSize = 64
+)
+
+// VerifyCollateralSignature verifies the hex encoded signature of the field of the raw TCB info or QE identity
+// JSON, as served by SCS, was made by an Intel SGX TCB Signing certificate of the issuer chain. The signature
+// covers the exact bytes of the field. It returns the certificate the collateral was signed with.
+func VerifyCollateralSignature(raw []byte, field, signature string, interCA []*x509.Certificate) (*x509.Certificate, error) {
+	var collateral map[string]json.RawMessage
+	err := json.Unmarshal(raw, &collateral)
+	if err != nil {
+		return nil, errors.Wrap(err, "VerifyCollateralSignature: Invalid collateral")
+	}
+	body, ok := collateral[field]
+	if !ok {
+		return nil, errors.Errorf("VerifyCollateralSignature: Collateral has no %s", field)
+	}
+	sig, err := hex.DecodeString(signature)
+	if err != nil || len(sig) != collateralSignatureSize {
+		return nil, errors.Errorf("VerifyCollateralSignature: Invalid %s signature", field)
+	}
+
+	for _, cert := range interCA {
+		if !verifyCaSubject(cert.Subject.String(), constants.SGXTCBInfoSubjectStr) {
+			continue
+		}
+		pubKey, ok := cert.PublicKey.(*ecdsa.PublicKey)
+		if !ok {
+			continue
+		}
+		if verifyECDSA256Signature(body, pubKey, sig) {
+			return cert, nil
+		}
+	}
+	return nil, errors.Errorf("VerifyCollateralSignature: %s is not signed by the Intel SGX TCB Signing CA", field)
+}
+
+// RootKeyHash returns the hex encoded SHA-256 digest of the public key of the certificate
+func RootKeyHash(cert *x509.Certificate) string {
+	digest := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return hex.EncodeToString(digest[:])
+}
+
+// VerifyRootKeyPin verifies the public key hash of the root CA is one of the pins, any root CA is accepted
+// when there are no pins
+func VerifyRootKeyPin(rootCA *x509.Certificate, pins []string) error {
+	if len(pins) == 0 {
+		return nil
+	}
+	if rootCA == nil {
+		return errors.New("VerifyRootKeyPin: Root CA is missing")
+	}
+	hash := RootKeyHash(rootCA)
+	for _, pin := range pins {
+		if strings.EqualFold(strings.TrimSpace(pin), hash) {
+			return nil
+		}
+	}
+	return errors.Errorf("VerifyRootKeyPin: Root CA public key hash %s is not pinned", hash)
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package verifier
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyCollateralSignature(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{CommonName: "Intel SGX TCB Signing", Organization: []string{"Intel Corporation"},
+			Locality: []string{"Santa Clara"}, Province: []string{"CA"}, Country: []string{"US"}},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	body := `{"version":2,"fmspc":"00606a000000"}`
+	digest := sha256.Sum256([]byte(body))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	assert.NoError(t, err)
+	signature := hex.EncodeToString(append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...))
+	raw := []byte(`{"tcbInfo":` + body + `,"signature":"` + signature + `"}`)
+
+	signer, err := VerifyCollateralSignature(raw, CollateralTcbInfo, signature, []*x509.Certificate{cert})
+	assert.NoError(t, err)
+	assert.Equal(t, cert, signer)
+
+	// the signature covers the exact bytes of the collateral
+	tampered := []byte(`{"tcbInfo":{"version":2, "fmspc":"00606a000000"},"signature":"` + signature + `"}`)
+	_, err = VerifyCollateralSignature(tampered, CollateralTcbInfo, signature, []*x509.Certificate{cert})
+	assert.Error(t, err)
+	_, err = VerifyCollateralSignature(raw, CollateralQeIdentity, signature, []*x509.Certificate{cert})
+	assert.Error(t, err)
+	_, err = VerifyCollateralSignature(raw, CollateralTcbInfo, signature[:10], []*x509.Certificate{cert})
+	assert.Error(t, err)
+
+	assert.NoError(t, VerifyRootKeyPin(cert, nil))
+	assert.NoError(t, VerifyRootKeyPin(cert, []string{"00", RootKeyHash(cert)}))
+	assert.Error(t, VerifyRootKeyPin(cert, []string{"00"}))
+}
@@ -5,16 +5,20 @@
 package verifier
 
 import (
+	"bytes"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"intel/isecl/sqvs/v4/constants"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
+// VerifyPCKCertificate verifies the PCK certificate chain of the quote was valid and not revoked at the
+// evaluation time, the current trusted time unless an earlier time is requested
 func VerifyPCKCertificate(pckCert *x509.Certificate, interCA, rootCA []*x509.Certificate,
-	crl []*pkix.CertificateList, trustedRootCA *x509.Certificate) error {
+	crl []*pkix.CertificateList, trustedRootCA *x509.Certificate, at time.Time) error {
 	numInterCA := len(interCA)
 	numRootCA := len(rootCA)
 	numCrl := len(crl)
@@ -36,9 +40,10 @@ func VerifyPCKCertificate(pckCert *x509.Certificate, interCA, rootCA []*x509.Cer
 	}
 
 	var opts x509.VerifyOptions
+	opts.CurrentTime = at
 	opts.Intermediates = x509.NewCertPool()
 	for i := 0; i < numInterCA; i++ {
-		err := verifyInterCaCert(interCA[i], rootCA, constants.SGXInterCACertSubjectStr)
+		err := verifyInterCaCert(interCA[i], rootCA, constants.SGXInterCACertSubjectStr, at)
 		if err != nil {
 			return errors.Wrap(err, "Invalid Intermediate CA Certificate")
 		}
@@ -46,7 +51,7 @@ func VerifyPCKCertificate(pckCert *x509.Certificate, interCA, rootCA []*x509.Cer
 	}
 	opts.Roots = x509.NewCertPool()
 	for i := 0; i < numRootCA; i++ {
-		err := verifyRootCaCert(rootCA[i], constants.SGXRootCACertSubjectStr)
+		err := verifyRootCaCert(rootCA[i], constants.SGXRootCACertSubjectStr, at)
 		if err != nil {
 			return errors.Wrap(err, "Invalid Root CA Certificate")
 		}
@@ -59,14 +64,40 @@ func VerifyPCKCertificate(pckCert *x509.Certificate, interCA, rootCA []*x509.Cer
 		return errors.Wrap(err, "VerifyPCKCertificate: verify certificate")
 	}
 
+	// the PCK certificates of multi-package platforms are issued by the PCK Platform CA, only the CRLs
+	// of the CA that issued the certificate can revoke it
+	issuerCA := pckIssuerCA(pckCert, interCA)
+	if issuerCA == nil {
+		return errors.New("VerifyPCKCertificate: PCK Certificate issuer not found in the certificate chain")
+	}
+	issuerCrlCount := 0
 	for i := 0; i < numCrl; i++ {
+		if crl[i] == nil || issuerCA.CheckCRLSignature(crl[i]) != nil {
+			log.Debug("Skipping CRL not issued by ", issuerCA.Subject.String())
+			continue
+		}
+		issuerCrlCount++
 		log.Debug("CRL Revoked Certificate Count:", len(crl[i].TBSCertList.RevokedCertificates))
 		for _, crlObj := range crl[i].TBSCertList.RevokedCertificates {
-			if pckCert.SerialNumber.Cmp(crlObj.SerialNumber) == 0 {
+			// a certificate revoked after the evaluation time was valid at that time
+			if pckCert.SerialNumber.Cmp(crlObj.SerialNumber) == 0 && !crlObj.RevocationTime.After(at) {
 				log.Error("PCK Certificate is Revoked")
 				return errors.New("VerifyPCKCertificate: PCK Certificate is Revoked")
 			}
 		}
 	}
+	if issuerCrlCount == 0 {
+		return errors.New("VerifyPCKCertificate: No CRL issued by " + issuerCA.Subject.String())
+	}
+	return nil
+}
+
+// pckIssuerCA returns the intermediate CA that issued the PCK certificate
+func pckIssuerCA(pckCert *x509.Certificate, interCA []*x509.Certificate) *x509.Certificate {
+	for _, ca := range interCA {
+		if bytes.Equal(ca.RawSubject, pckCert.RawIssuer) && pckCert.CheckSignatureFrom(ca) == nil {
+			return ca
+		}
+	}
 	return nil
 }
@@ -0,0 +1,104 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package verifier
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func intelName(commonName string) pkix.Name {
+	return pkix.Name{CommonName: commonName, Organization: []string{"Intel Corporation"},
+		Locality: []string{"Santa Clara"}, Province: []string{"CA"}, Country: []string{"US"}}
+}
+
+func newTestCert(t *testing.T, serial int64, subject pkix.Name, isCA bool, parent *testCA) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               subject,
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+		SubjectKeyId:          big.NewInt(serial).Bytes(),
+		CRLDistributionPoints: []string{"https://certificates.example.com/crl"},
+	}
+	if isCA {
+		template.KeyUsage |= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	}
+	parentCert, parentKey := template, key
+	template.AuthorityKeyId = template.SubjectKeyId
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+		template.AuthorityKeyId = parent.cert.SubjectKeyId
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) crl(t *testing.T, revoked ...*x509.Certificate) *pkix.CertificateList {
+	var revokedCerts []pkix.RevokedCertificate
+	for _, cert := range revoked {
+		revokedCerts = append(revokedCerts, pkix.RevokedCertificate{SerialNumber: cert.SerialNumber,
+			RevocationTime: time.Now()})
+	}
+	der, err := ca.cert.CreateCRL(rand.Reader, ca.key, revokedCerts, time.Now(), time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	crl, err := x509.ParseDERCRL(der)
+	assert.NoError(t, err)
+	return crl
+}
+
+func TestVerifyPlatformCAIssuedPCKCertificate(t *testing.T) {
+	root := newTestCert(t, 1, intelName("Intel SGX Root CA"), true, nil)
+	processorCA := newTestCert(t, 2, intelName("Intel SGX PCK Processor CA"), true, root)
+	platformCA := newTestCert(t, 3, intelName("Intel SGX PCK Platform CA"), true, root)
+	pck := newTestCert(t, 4, intelName("Intel SGX PCK Certificate"), false, platformCA)
+
+	rootCA := []*x509.Certificate{root.cert}
+	interCA := []*x509.Certificate{processorCA.cert, platformCA.cert}
+
+	err := VerifyPCKCertificate(pck.cert, interCA, rootCA, []*pkix.CertificateList{platformCA.crl(t)}, root.cert, time.Now())
+	assert.NoError(t, err)
+
+	// a CRL of the processor CA cannot vouch for a certificate of the platform CA
+	err = VerifyPCKCertificate(pck.cert, interCA, rootCA, []*pkix.CertificateList{processorCA.crl(t, pck.cert)}, root.cert, time.Now())
+	assert.Error(t, err)
+
+	err = VerifyPCKCertificate(pck.cert, interCA, rootCA, []*pkix.CertificateList{platformCA.crl(t, pck.cert)}, root.cert, time.Now())
+	assert.Error(t, err)
+
+	// revoked after the evaluation time, the certificate was valid at that time
+	err = VerifyPCKCertificate(pck.cert, interCA, rootCA, []*pkix.CertificateList{platformCA.crl(t, pck.cert)}, root.cert,
+		time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+
+	// before the certificates were issued
+	err = VerifyPCKCertificate(pck.cert, interCA, rootCA, []*pkix.CertificateList{platformCA.crl(t)}, root.cert,
+		time.Now().Add(-2*time.Hour))
+	assert.Error(t, err)
+
+	assert.Equal(t, platformCA.cert, crlSignerCA(platformCA.crl(t), interCA))
+	assert.Nil(t, crlSignerCA(root.crl(t), interCA))
+}
@@ -14,8 +14,8 @@ import (
 	"github.com/pkg/errors"
 )
 
-func checkExpiry(crl *pkix.CertificateList) bool {
-	if crl.HasExpired(time.Now()) {
+func checkExpiry(crl *pkix.CertificateList, now time.Time) bool {
+	if crl.HasExpired(now) {
 		log.Error("Certificate Revocation List Has Expired")
 		return false
 	}
@@ -27,8 +27,21 @@ func verifyPckCrlIssuer(crl *pkix.CertificateList) bool {
 	return verifyCaSubject(issuer, constants.SGXCRLIssuerStr)
 }
 
+// crlSignerCA returns the intermediate CA that signed the CRL, the PCK Processor CA or, for multi-package
+// platforms, the PCK Platform CA. It returns nil when none of the CAs signed the CRL.
+func crlSignerCA(crl *pkix.CertificateList, interCA []*x509.Certificate) *x509.Certificate {
+	for _, ca := range interCA {
+		if ca.CheckCRLSignature(crl) == nil {
+			return ca
+		}
+	}
+	return nil
+}
+
+// VerifyPckCrl verifies the PCK CRLs and their issuer chain. The CRLs are fetched from SCS when the quote is
+// verified, they are checked against the current trusted time.
 func VerifyPckCrl(crlURL []string, crlList []*pkix.CertificateList, interCA,
-	rootCA []*x509.Certificate, trustedRootCA *x509.Certificate) error {
+	rootCA []*x509.Certificate, trustedRootCA *x509.Certificate, now time.Time) error {
 	numInterCA := len(interCA)
 	numRootCA := len(rootCA)
 	numCrlList := len(crlList)
@@ -42,21 +55,21 @@ func VerifyPckCrl(crlURL []string, crlList []*pkix.CertificateList, interCA,
 	}
 
 	for i := 0; i < numInterCA; i++ {
-		err := verifyInterCaCert(interCA[i], rootCA, constants.SGXInterCACertSubjectStr)
+		err := verifyInterCaCert(interCA[i], rootCA, constants.SGXInterCACertSubjectStr, now)
 		if err != nil {
 			return errors.Wrap(err, "VerifyPckCrl: verifyInterCaCert failed")
 		}
 	}
 
 	for i := 0; i < numRootCA; i++ {
-		err := verifyRootCaCert(rootCA[i], constants.SGXRootCACertSubjectStr)
+		err := verifyRootCaCert(rootCA[i], constants.SGXRootCACertSubjectStr, now)
 		if err != nil {
 			return errors.Wrap(err, "VerifyPckCrl: verifyRootCaCert failed ")
 		}
 	}
 
 	for i := 0; i < numCrlList; i++ {
-		ret := checkExpiry(crlList[i])
+		ret := checkExpiry(crlList[i], now)
 		if !ret {
 			return errors.New("VerifyPckCrl: Revocation List has Expired" + crlURL[i])
 		}
@@ -65,11 +78,8 @@ func VerifyPckCrl(crlURL []string, crlList []*pkix.CertificateList, interCA,
 			return errors.New("VerifyPckCrl: CRL Issuer info is Invalid: " + crlURL[i])
 		}
 
-		for j := 0; j < numInterCA; j++ {
-			err := interCA[i].CheckCRLSignature(crlList[i])
-			if err != nil {
-				return errors.New("VerifyPckCrl: Signature Verification failed")
-			}
+		if crlSignerCA(crlList[i], interCA) == nil {
+			return errors.New("VerifyPckCrl: Signature Verification failed: " + crlURL[i])
 		}
 	}
 	return nil
@@ -11,6 +11,7 @@ import (
 	"encoding/hex"
 	"intel/isecl/sqvs/v4/constants"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -20,7 +21,7 @@ const (
 	HashSize      = 32
 )
 
-func VerifyQeIDCertChain(interCA, rootCA []*x509.Certificate, trustedRootCA *x509.Certificate) error {
+func VerifyQeIDCertChain(interCA, rootCA []*x509.Certificate, trustedRootCA *x509.Certificate, now time.Time) error {
 	numInterCA := len(interCA)
 	numRootCA := len(rootCA)
 
@@ -33,13 +34,13 @@ func VerifyQeIDCertChain(interCA, rootCA []*x509.Certificate, trustedRootCA *x50
 	}
 
 	for i := 0; i < numInterCA; i++ {
-		err := verifyInterCaCert(interCA[i], rootCA, constants.SGXQEInfoSubjectStr)
+		err := verifyInterCaCert(interCA[i], rootCA, constants.SGXQEInfoSubjectStr, now)
 		if err != nil {
 			return errors.Wrap(err, "VerifyQeIDCertChain: verifyInterCaCert failed")
 		}
 	}
 	for i := 0; i < numRootCA; i++ {
-		err := verifyRootCaCert(rootCA[i], constants.SGXRootCACertSubjectStr)
+		err := verifyRootCaCert(rootCA[i], constants.SGXRootCACertSubjectStr, now)
 		if err != nil {
 			return errors.Wrap(err, "VerifyQeIDCertChain: verifyRootCaCert failed")
 		}
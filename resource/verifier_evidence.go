@@ -0,0 +1,128 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/quoteprovider"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// VerifierEvidence is the SGX quote of the enclave SQVS runs in. The report data of the quote is the
+// SHA-512 digest of the DER encoded TLS certificate of SQVS followed by the nonce, if any, so relying
+// parties can check that the verifier they connect to is the attested one.
+type VerifierEvidence struct {
+	Quote         string    `json:"quote"`
+	TLSCertSha384 string    `json:"tlsCertSha384"`
+	Nonce         string    `json:"nonce,omitempty"`
+	GeneratedTime time.Time `json:"generatedTime"`
+}
+
+var verifierEvidence struct {
+	mu       sync.Mutex
+	provider quoteprovider.Provider
+	tlsCert  []byte
+	cached   *VerifierEvidence
+}
+
+// SetVerifierEvidence sets the provider of the quotes of the SQVS enclave and the DER encoded TLS
+// certificate they are bound to, a nil provider disables the verifier evidence
+func SetVerifierEvidence(provider quoteprovider.Provider, tlsCert []byte) {
+	verifierEvidence.mu.Lock()
+	defer verifierEvidence.mu.Unlock()
+	verifierEvidence.provider = provider
+	verifierEvidence.tlsCert = tlsCert
+	verifierEvidence.cached = nil
+}
+
+func VerifierEvidenceCB(router *mux.Router) {
+	router.Handle("/verifier-evidence", getVerifierEvidence()).Methods("GET")
+}
+
+func getVerifierEvidence() errorHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Trace("resource/verifier_evidence:getVerifierEvidence() Entering")
+		defer log.Trace("resource/verifier_evidence:getVerifierEvidence() Leaving")
+
+		conf := config.Global()
+		if conf == nil {
+			return &resourceError{Message: "Could not read config", StatusCode: http.StatusInternalServerError}
+		}
+		if conf.IncludeToken {
+			err := AuthorizeEndpoint(r, constants.QuoteVerifierGroupName, true)
+			if err != nil {
+				return err
+			}
+		}
+
+		nonce := r.URL.Query().Get("nonce")
+		if len(nonce) > constants.MaxVerifierEvidenceNonceLength {
+			return &resourceError{Message: "Nonce is too long", StatusCode: http.StatusBadRequest}
+		}
+
+		evidence, err := verifierEvidenceFor(nonce, conf.VerifierEvidence.RefreshInterval)
+		if err != nil {
+			return err
+		}
+
+		body, err := json.Marshal(evidence)
+		if err != nil {
+			return &resourceError{Message: "Error marshalling verifier evidence in JSON", StatusCode: http.StatusInternalServerError}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(body)
+		if err != nil {
+			return &resourceError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+		return nil
+	}
+}
+
+// verifierEvidenceFor returns evidence bound to the nonce, evidence without a nonce is reused until it is
+// older than the refresh interval
+func verifierEvidenceFor(nonce string, refresh time.Duration) (*VerifierEvidence, error) {
+	verifierEvidence.mu.Lock()
+	defer verifierEvidence.mu.Unlock()
+
+	if verifierEvidence.provider == nil {
+		return nil, &resourceError{Message: "Verifier evidence is not enabled", StatusCode: http.StatusNotFound}
+	}
+	if refresh <= 0 {
+		refresh = constants.DefaultVerifierEvidenceRefresh
+	}
+	if cached := verifierEvidence.cached; nonce == "" && cached != nil && time.Since(cached.GeneratedTime) < refresh {
+		return cached, nil
+	}
+
+	reportData := sha512.Sum512(append(append([]byte{}, verifierEvidence.tlsCert...), nonce...))
+	quote, err := verifierEvidence.provider.Quote(reportData[:])
+	if err != nil {
+		log.WithError(err).Error("resource/verifier_evidence:verifierEvidenceFor() Error generating verifier quote")
+		return nil, &resourceError{Message: "Error generating verifier evidence", StatusCode: http.StatusServiceUnavailable}
+	}
+
+	certDigest := sha512.Sum384(verifierEvidence.tlsCert)
+	evidence := &VerifierEvidence{
+		Quote:         base64.StdEncoding.EncodeToString(quote),
+		TLSCertSha384: hex.EncodeToString(certDigest[:]),
+		Nonce:         nonce,
+		GeneratedTime: time.Now().UTC(),
+	}
+	if nonce == "" {
+		verifierEvidence.cached = evidence
+	}
+	return evidence, nil
+}
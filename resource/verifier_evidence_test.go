@@ -0,0 +1,61 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"crypto/sha512"
+	"encoding/json"
+	"intel/isecl/sqvs/v4/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubQuoteProvider struct {
+	calls      int
+	reportData []byte
+}
+
+func (p *stubQuoteProvider) Quote(reportData []byte) ([]byte, error) {
+	p.calls++
+	p.reportData = reportData
+	return []byte("quote"), nil
+}
+
+func TestVerifierEvidence(t *testing.T) {
+	config.Global().IncludeToken = false
+	r := mux.NewRouter()
+	VerifierEvidenceCB(r.PathPrefix("/svs/v1/").Subrouter())
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest("GET", "/svs/v1/verifier-evidence", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	provider := &stubQuoteProvider{}
+	SetVerifierEvidence(provider, []byte("tls-cert"))
+	defer SetVerifierEvidence(nil, nil)
+
+	for i := 0; i < 2; i++ {
+		recorder = httptest.NewRecorder()
+		r.ServeHTTP(recorder, httptest.NewRequest("GET", "/svs/v1/verifier-evidence", nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	}
+	assert.Equal(t, 1, provider.calls)
+
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest("GET", "/svs/v1/verifier-evidence?nonce=abc", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, 2, provider.calls)
+	expected := sha512.Sum512([]byte("tls-certabc"))
+	assert.Equal(t, expected[:], provider.reportData)
+
+	var evidence VerifierEvidence
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &evidence))
+	assert.Equal(t, "abc", evidence.Nonce)
+	assert.Equal(t, "cXVvdGU=", evidence.Quote)
+}
@@ -6,8 +6,11 @@
 package resource
 
 import (
+	"encoding/json"
+	"intel/isecl/sqvs/v4/config"
 	"intel/isecl/sqvs/v4/version"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
 )
@@ -16,16 +19,45 @@ func SetVersionRoutes(router *mux.Router) {
 	router.Handle("/version", getVersion()).Methods("GET")
 }
 
+// getVersion returns the version as text, or the build provenance, the version along with the Go version, the
+// SBOM hash, the enabled features and the feature flags, to clients accepting application/json
 func getVersion() http.HandlerFunc {
 	log.Trace("resource/version:getVersion() Entering")
 	defer log.Trace("resource/version:getVersion() Leaving")
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		verStr := version.GetVersion()
 		w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
-		_, err := w.Write([]byte(verStr))
+		if !strings.Contains(r.Header.Get("Accept"), contentTypeJSON) {
+			_, err := w.Write([]byte(version.GetVersion()))
+			if err != nil {
+				log.WithError(err).Error("Could not write version to response")
+			}
+			return
+		}
+
+		body, err := json.Marshal(BuildProvenance(config.Global()))
+		if err != nil {
+			log.WithError(err).Error("resource/version:getVersion() Error marshalling build provenance in JSON")
+			http.Error(w, "Error marshalling build provenance in JSON", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", contentTypeJSON)
+		_, err = w.Write(body)
 		if err != nil {
 			log.WithError(err).Error("Could not write version to response")
 		}
 	}
 }
+
+// BuildProvenance returns the version of the build along with the features and feature flags conf enables
+func BuildProvenance(conf *config.Configuration) version.VersionInfo {
+	info := version.GetVersionInfo()
+	if conf != nil {
+		info.Features = conf.EnabledFeatures()
+		info.FeatureFlags = conf.FeatureFlagStates()
+		if TestModeBuild && conf.EnableTestMode {
+			info.Features = append(info.Features, "test-mode")
+		}
+	}
+	return info
+}
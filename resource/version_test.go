@@ -0,0 +1,35 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package resource
+
+import (
+	"encoding/json"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/version"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetVersion(t *testing.T) {
+	w := httptest.NewRecorder()
+	getVersion()(w, httptest.NewRequest(http.MethodGet, "/svs/v1/version", nil))
+	assert.Contains(t, w.Body.String(), "Go Version: "+runtime.Version())
+
+	conf := config.Global()
+	conf.IncludeToken, conf.EnableVerificationHistory = true, true
+	defer func() { conf.IncludeToken, conf.EnableVerificationHistory = false, false }()
+	r := httptest.NewRequest(http.MethodGet, "/svs/v1/version", nil)
+	r.Header.Set("Accept", "application/json")
+	w = httptest.NewRecorder()
+	getVersion()(w, r)
+	var provenance version.VersionInfo
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &provenance))
+	assert.Equal(t, runtime.Version(), provenance.GoVersion)
+	assert.Equal(t, []string{"token-auth", "verification-history"}, provenance.Features)
+}
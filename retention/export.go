@@ -0,0 +1,186 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package retention
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/truststore"
+	"intel/isecl/sqvs/v4/types"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// exportTimeFormat names export files after the time they were written, so they sort chronologically
+const exportTimeFormat = "20060102T150405.000000000Z"
+
+// Exporter archives verification records before they are pruned
+type Exporter interface {
+	Export(verifications types.Verifications) error
+}
+
+// NewExporter returns the exporter of the configured export target, or nil when pruned records are not exported
+func NewExporter(conf config.RetentionConfig) (Exporter, error) {
+	switch {
+	case conf.ExportDir != "" && conf.ExportS3URL != "":
+		return nil, errors.New("retention/export:NewExporter() Only one of the export directory and S3 URL can be configured")
+	case conf.ExportDir != "":
+		return &DirectoryExporter{Dir: conf.ExportDir}, nil
+	case conf.ExportS3URL != "":
+		return NewS3Exporter(conf.ExportS3URL, conf.ExportS3Region, conf.ExportS3CredentialsFile)
+	}
+	return nil, nil
+}
+
+func exportName() string {
+	return "sqvs-verifications-" + time.Now().UTC().Format(exportTimeFormat) + ".json"
+}
+
+// DirectoryExporter writes each batch of pruned records as a JSON file to a local or NFS mounted directory
+type DirectoryExporter struct {
+	Dir string
+}
+
+func (e *DirectoryExporter) Export(verifications types.Verifications) error {
+	content, err := json.Marshal(verifications)
+	if err != nil {
+		return errors.Wrap(err, "retention/export:Export() Error encoding verification records")
+	}
+	err = os.MkdirAll(e.Dir, 0700)
+	if err != nil {
+		return errors.Wrap(err, "retention/export:Export() Error creating export directory")
+	}
+	exportFile := filepath.Join(e.Dir, exportName())
+	tmpFile := exportFile + ".tmp"
+	err = ioutil.WriteFile(tmpFile, content, 0600)
+	if err != nil {
+		return errors.Wrap(err, "retention/export:Export() Error writing export file")
+	}
+	return os.Rename(tmpFile, exportFile)
+}
+
+// S3Exporter uploads each batch of pruned records as a JSON object under a path style S3 URL,
+// https://<endpoint>/<bucket>/<prefix>, signing requests with AWS Signature Version 4
+type S3Exporter struct {
+	URL    *url.URL
+	Region string
+	Client *http.Client
+
+	accessKeyID     string
+	secretAccessKey string
+}
+
+func NewS3Exporter(s3URL, region, credentialsFile string) (*S3Exporter, error) {
+	u, err := url.ParseRequestURI(s3URL)
+	if err != nil {
+		return nil, errors.Wrap(err, "retention/export:NewS3Exporter() Invalid S3 URL")
+	}
+	if region == "" {
+		return nil, errors.New("retention/export:NewS3Exporter() S3 region must be configured")
+	}
+	credentials, err := ioutil.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "retention/export:NewS3Exporter() Error reading S3 credentials")
+	}
+	fields := strings.Fields(string(credentials))
+	if len(fields) != 2 {
+		return nil, errors.New("retention/export:NewS3Exporter() S3 credentials file must hold the access key ID and the secret access key")
+	}
+	client, err := truststore.HTTPClient(constants.TrustedCAsStoreDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "retention/export:NewS3Exporter() Error in getting client object")
+	}
+	return &S3Exporter{
+		URL:             u,
+		Region:          region,
+		Client:          client,
+		accessKeyID:     fields[0],
+		secretAccessKey: fields[1],
+	}, nil
+}
+
+func (e *S3Exporter) Export(verifications types.Verifications) error {
+	content, err := json.Marshal(verifications)
+	if err != nil {
+		return errors.Wrap(err, "retention/export:Export() Error encoding verification records")
+	}
+	objectURL := *e.URL
+	objectURL.Path = strings.TrimSuffix(objectURL.Path, "/") + "/" + exportName()
+	req, err := http.NewRequest(http.MethodPut, objectURL.String(), bytes.NewReader(content))
+	if err != nil {
+		return errors.Wrap(err, "retention/export:Export() Error creating S3 request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	e.sign(req, content, time.Now())
+
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "retention/export:Export() Error uploading verification records")
+	}
+	defer func() {
+		derr := resp.Body.Close()
+		if derr != nil {
+			log.WithError(derr).Error("Error closing response")
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return errors.Errorf("retention/export:Export() S3 upload failed with status %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// sign adds the AWS Signature Version 4 authorization header to an S3 request
+func (e *S3Exporter) sign(req *http.Request, payload []byte, now time.Time) {
+	payloadHash := sha256Hex(payload)
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\nx-amz-content-sha256:" + payloadHash + "\nx-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + e.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+e.secretAccessKey), date)
+	key = hmacSHA256(key, e.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		e.accessKeyID, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
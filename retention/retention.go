@@ -0,0 +1,108 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package retention
+
+import (
+	commLog "intel/isecl/lib/common/v4/log"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/repository"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var log = commLog.GetDefaultLogger()
+
+// Pruner removes verification history records that fall outside the retention policy, exporting them
+// first when an export target is configured. Records are only deleted once they have been exported.
+type Pruner struct {
+	repo       repository.VerificationRepository
+	exporter   Exporter
+	maxAge     time.Duration
+	maxRecords int
+}
+
+func NewPruner(conf config.RetentionConfig, repo repository.VerificationRepository) (*Pruner, error) {
+	exporter, err := NewExporter(conf)
+	if err != nil {
+		return nil, errors.Wrap(err, "retention/retention:NewPruner() Error initializing history exporter")
+	}
+	return &Pruner{
+		repo:       repo,
+		exporter:   exporter,
+		maxAge:     time.Duration(conf.RetentionDays) * 24 * time.Hour,
+		maxRecords: conf.MaxRecords,
+	}, nil
+}
+
+// Enabled reports whether the retention policy limits the verification history
+func (p *Pruner) Enabled() bool {
+	return p.maxAge > 0 || p.maxRecords > 0
+}
+
+// Prune removes the records created before the given time and, when maxRecords is positive, those beyond
+// the newest maxRecords. It returns the number of records removed.
+func (p *Pruner) Prune(before time.Time, maxRecords int) (int, error) {
+	log.Trace("retention/retention:Prune() Entering")
+	defer log.Trace("retention/retention:Prune() Leaving")
+
+	expired, err := p.repo.Expired(before, maxRecords)
+	if err != nil {
+		return 0, errors.Wrap(err, "retention/retention:Prune() Error selecting expired verification records")
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+
+	if p.exporter != nil {
+		err = p.exporter.Export(expired)
+		if err != nil {
+			return 0, errors.Wrap(err, "retention/retention:Prune() Error exporting verification records")
+		}
+	}
+
+	ids := make([]string, len(expired))
+	for i, verification := range expired {
+		ids[i] = verification.ID
+	}
+	deleted, err := p.repo.Delete(ids)
+	if err != nil {
+		return deleted, errors.Wrap(err, "retention/retention:Prune() Error deleting verification records")
+	}
+	log.Infof("retention/retention:Prune() Pruned %d verification records", deleted)
+	return deleted, nil
+}
+
+// PruneExpired applies the configured retention policy
+func (p *Pruner) PruneExpired() (int, error) {
+	var before time.Time
+	if p.maxAge > 0 {
+		before = time.Now().Add(-p.maxAge)
+	}
+	return p.Prune(before, p.maxRecords)
+}
+
+// Run applies the retention policy every interval until stop is closed
+func (p *Pruner) Run(interval time.Duration, stop <-chan struct{}) {
+	if !p.Enabled() {
+		return
+	}
+	if interval <= 0 {
+		interval = constants.DefaultHistoryPruneInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := p.PruneExpired(); err != nil {
+			log.WithError(err).Error("retention/retention:Run() Error pruning verification history")
+		}
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
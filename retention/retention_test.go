@@ -0,0 +1,63 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package retention
+
+import (
+	"encoding/json"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/repository/memory"
+	"intel/isecl/sqvs/v4/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPruneExportsBeforeDeleting(t *testing.T) {
+	exportDir, err := ioutil.TempDir("", "sqvs-export")
+	assert.NoError(t, err)
+	defer os.RemoveAll(exportDir)
+
+	db, err := memory.New("")
+	assert.NoError(t, err)
+	repo := db.VerificationRepository()
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, repo.Create(&types.Verification{
+			ID:          strconv.Itoa(i),
+			CreatedTime: now.Add(time.Duration(i-10) * 24 * time.Hour),
+		}))
+	}
+
+	pruner, err := NewPruner(config.RetentionConfig{RetentionDays: 8, MaxRecords: 4, ExportDir: exportDir}, repo)
+	assert.NoError(t, err)
+	deleted, err := pruner.PruneExpired()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, deleted)
+
+	remaining, total, err := repo.Search(repository.ListCriteria{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, "3", remaining[0].ID)
+
+	files, err := filepath.Glob(filepath.Join(exportDir, "*.json"))
+	assert.NoError(t, err)
+	if assert.Len(t, files, 1) {
+		content, err := ioutil.ReadFile(files[0])
+		assert.NoError(t, err)
+		var exported types.Verifications
+		assert.NoError(t, json.Unmarshal(content, &exported))
+		assert.Len(t, exported, 3)
+	}
+
+	deleted, err = pruner.Prune(now, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+}
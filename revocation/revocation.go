@@ -0,0 +1,225 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package revocation
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"intel/isecl/sqvs/v4/types"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ListPath is the path, under /svs/v1, of the signed revocation list SQVS publishes
+const ListPath = "/.well-known/revocations.json"
+
+// Result identifies a signed quote verification result held by a relying party
+type Result struct {
+	IssuedAt    time.Time
+	KeyID       string
+	QuoteSHA256 string
+	QuoteSHA384 string
+}
+
+// List holds the revocations of the signed quote verification results issued by SQVS
+type List struct {
+	IssuedAt    time.Time         `json:"issuedAt"`
+	Revocations types.Revocations `json:"revocations"`
+}
+
+// SignedList is the published revocation list, signed with the key the quote verification results are signed
+// with. The signature covers the base64 encoded list, as it does the results.
+type SignedList struct {
+	RevocationList   string `json:"revocationList"`
+	Signature        string `json:"signature"`
+	CertificateChain string `json:"certificateChain"`
+	KeyID            string `json:"keyId"`
+}
+
+// Validate checks the revocation selects results by at least one criterion and that its criteria are valid
+func Validate(revocation *types.Revocation) error {
+	if strings.TrimSpace(revocation.Reason) == "" {
+		return errors.New("revocation/revocation:Validate() The reason of the revocation is required")
+	}
+	if revocation.IssuedAfter == nil && revocation.IssuedBefore == nil && revocation.KeyID == "" &&
+		len(revocation.QuoteHashes) == 0 {
+		return errors.New("revocation/revocation:Validate() A time range, key ID or quote hashes is required")
+	}
+	if revocation.IssuedAfter != nil && revocation.IssuedBefore != nil &&
+		!revocation.IssuedAfter.Before(*revocation.IssuedBefore) {
+		return errors.New("revocation/revocation:Validate() issuedAfter must be before issuedBefore")
+	}
+	for i, hash := range revocation.QuoteHashes {
+		hash = strings.ToLower(strings.TrimSpace(hash))
+		if _, err := hex.DecodeString(hash); err != nil || (len(hash) != 2*sha256.Size && len(hash) != 2*sha512.Size384) {
+			return errors.Errorf("revocation/revocation:Validate() %s is not a SHA-256 or SHA-384 quote hash", hash)
+		}
+		revocation.QuoteHashes[i] = hash
+	}
+	return nil
+}
+
+// Matches tells whether the result meets every criterion of the revocation
+func Matches(revocation *types.Revocation, result Result) bool {
+	if revocation.IssuedAfter != nil && result.IssuedAt.Before(*revocation.IssuedAfter) {
+		return false
+	}
+	if revocation.IssuedBefore != nil && !result.IssuedAt.Before(*revocation.IssuedBefore) {
+		return false
+	}
+	if revocation.KeyID != "" && revocation.KeyID != result.KeyID {
+		return false
+	}
+	if len(revocation.QuoteHashes) == 0 {
+		return true
+	}
+	for _, hash := range revocation.QuoteHashes {
+		if (result.QuoteSHA256 != "" && strings.EqualFold(hash, result.QuoteSHA256)) ||
+			(result.QuoteSHA384 != "" && strings.EqualFold(hash, result.QuoteSHA384)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns the first revocation of the list the result matches, nil when the result is not revoked
+func (l *List) Check(result Result) *types.Revocation {
+	for i := range l.Revocations {
+		if Matches(&l.Revocations[i], result) {
+			return &l.Revocations[i]
+		}
+	}
+	return nil
+}
+
+// ParseResult reads the Result of a signed quote verification response of /svs/v2/sgx_qv_verify_quote. It does
+// not verify the signature of the response.
+func ParseResult(signedResponse []byte) (Result, error) {
+	var response struct {
+		QuoteData string `json:"quoteData"`
+		KeyID     string `json:"keyId"`
+	}
+	err := json.Unmarshal(signedResponse, &response)
+	if err != nil {
+		return Result{}, errors.Wrap(err, "revocation/revocation:ParseResult() Invalid signed response")
+	}
+	quoteData, err := base64.StdEncoding.DecodeString(response.QuoteData)
+	if err != nil {
+		return Result{}, errors.Wrap(err, "revocation/revocation:ParseResult() Invalid quote data")
+	}
+	var data struct {
+		IssuedAt    string `json:"IssuedAt"`
+		QuoteHashes struct {
+			QuoteSHA256 string `json:"QuoteSHA256"`
+			QuoteSHA384 string `json:"QuoteSHA384"`
+		} `json:"QuoteHashes"`
+	}
+	err = json.Unmarshal(quoteData, &data)
+	if err != nil {
+		return Result{}, errors.Wrap(err, "revocation/revocation:ParseResult() Invalid quote data")
+	}
+	result := Result{KeyID: response.KeyID, QuoteSHA256: data.QuoteHashes.QuoteSHA256,
+		QuoteSHA384: data.QuoteHashes.QuoteSHA384}
+	if data.IssuedAt != "" {
+		result.IssuedAt, err = time.Parse(time.RFC3339, data.IssuedAt)
+		if err != nil {
+			return Result{}, errors.Wrap(err, "revocation/revocation:ParseResult() Invalid issue time")
+		}
+	}
+	return result, nil
+}
+
+// Verify verifies the certificate chain of the signed list against the roots, the system roots when nil,
+// then the signature of the list and returns it
+func (s *SignedList) Verify(roots *x509.CertPool) (*List, error) {
+	var chain []*x509.Certificate
+	rest := []byte(s.CertificateChain)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "revocation/revocation:Verify() Invalid certificate chain")
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("revocation/revocation:Verify() Signed list has no certificate chain")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := chain[0].Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}})
+	if err != nil {
+		return nil, errors.Wrap(err, "revocation/revocation:Verify() Untrusted signing certificate")
+	}
+
+	publicKey, ok := chain[0].PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("revocation/revocation:Verify() Signing key is not an RSA key")
+	}
+	signature, err := base64.StdEncoding.DecodeString(s.Signature)
+	if err != nil {
+		return nil, errors.Wrap(err, "revocation/revocation:Verify() Invalid signature")
+	}
+	digest := sha512.Sum384([]byte(s.RevocationList))
+	if rsa.VerifyPKCS1v15(publicKey, crypto.SHA384, digest[:], signature) != nil &&
+		rsa.VerifyPSS(publicKey, crypto.SHA384, digest[:], signature, nil) != nil {
+		return nil, errors.New("revocation/revocation:Verify() Invalid signature")
+	}
+
+	content, err := base64.StdEncoding.DecodeString(s.RevocationList)
+	if err != nil {
+		return nil, errors.Wrap(err, "revocation/revocation:Verify() Invalid revocation list")
+	}
+	var list List
+	err = json.Unmarshal(content, &list)
+	if err != nil {
+		return nil, errors.Wrap(err, "revocation/revocation:Verify() Invalid revocation list")
+	}
+	return &list, nil
+}
+
+// Fetch downloads the revocation list SQVS publishes at baseURL, https://<host>:<port>/svs/v1, and verifies
+// it against the roots
+func Fetch(client *http.Client, baseURL string, roots *x509.CertPool) (*List, error) {
+	resp, err := client.Get(strings.TrimSuffix(baseURL, "/") + ListPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "revocation/revocation:Fetch() Error fetching revocation list")
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("revocation/revocation:Fetch() Revocation list request failed with status %d",
+			resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "revocation/revocation:Fetch() Error reading revocation list")
+	}
+	var signed SignedList
+	err = json.Unmarshal(body, &signed)
+	if err != nil {
+		return nil, errors.Wrap(err, "revocation/revocation:Fetch() Invalid revocation list")
+	}
+	return signed.Verify(roots)
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package revocation
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"intel/isecl/sqvs/v4/types"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevocationMatches(t *testing.T) {
+	now := time.Now().UTC()
+	after, before := now.Add(-time.Hour), now.Add(time.Hour)
+	hash := strings.Repeat("ab", 32)
+
+	assert.Error(t, Validate(&types.Revocation{Reason: "no criteria"}))
+	assert.Error(t, Validate(&types.Revocation{Reason: "range", IssuedAfter: &before, IssuedBefore: &after}))
+	assert.Error(t, Validate(&types.Revocation{Reason: "hash", QuoteHashes: []string{"abcd"}}))
+	byHash := types.Revocation{Reason: "bad collateral", QuoteHashes: []string{strings.ToUpper(hash)}}
+	assert.NoError(t, Validate(&byHash))
+	assert.Equal(t, hash, byHash.QuoteHashes[0])
+
+	byRange := types.Revocation{Reason: "key compromised", IssuedAfter: &after, IssuedBefore: &before, KeyID: "k1"}
+	list := List{Revocations: types.Revocations{byRange, byHash}}
+	assert.Equal(t, "key compromised", list.Check(Result{IssuedAt: now, KeyID: "k1"}).Reason)
+	assert.Nil(t, list.Check(Result{IssuedAt: now, KeyID: "k2"}))
+	assert.Nil(t, list.Check(Result{IssuedAt: before, KeyID: "k1"}))
+	assert.Equal(t, "bad collateral", list.Check(Result{IssuedAt: before, QuoteSHA256: hash}).Reason)
+}
+
+func TestParseResult(t *testing.T) {
+	quoteData, err := json.Marshal(map[string]interface{}{
+		"IssuedAt":    "2021-07-14T10:00:00Z",
+		"QuoteHashes": map[string]string{"QuoteSHA256": "aa", "QuoteSHA384": "bb"},
+	})
+	assert.NoError(t, err)
+	response, err := json.Marshal(map[string]string{"quoteData": base64.StdEncoding.EncodeToString(quoteData),
+		"keyId": "k1"})
+	assert.NoError(t, err)
+
+	result, err := ParseResult(response)
+	assert.NoError(t, err)
+	assert.Equal(t, Result{IssuedAt: time.Date(2021, 7, 14, 10, 0, 0, 0, time.UTC), KeyID: "k1", QuoteSHA256: "aa",
+		QuoteSHA384: "bb"}, result)
+}
+
+func TestSignedListVerify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "SQVS QVL Response Signing Certificate"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	listBytes, err := json.Marshal(List{IssuedAt: time.Now().UTC(), Revocations: types.Revocations{{ID: "r1",
+		Reason: "key compromised", KeyID: "k1"}}})
+	assert.NoError(t, err)
+	encoded := base64.StdEncoding.EncodeToString(listBytes)
+	digest := sha512.Sum384([]byte(encoded))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA384, digest[:])
+	assert.NoError(t, err)
+	signed := SignedList{RevocationList: encoded, Signature: base64.StdEncoding.EncodeToString(signature),
+		CertificateChain: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))}
+
+	list, err := signed.Verify(roots)
+	assert.NoError(t, err)
+	if assert.Len(t, list.Revocations, 1) {
+		assert.Equal(t, "r1", list.Revocations[0].ID)
+	}
+
+	signed.RevocationList = base64.StdEncoding.EncodeToString([]byte(`{"revocations":[]}`))
+	_, err = signed.Verify(roots)
+	assert.Error(t, err)
+	_, err = signed.Verify(x509.NewCertPool())
+	assert.Error(t, err)
+}
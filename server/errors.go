@@ -0,0 +1,79 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package server
+
+import (
+	stderrors "errors"
+	"intel/isecl/sqvs/v4/constants"
+	"os"
+	"path"
+
+	"github.com/pkg/errors"
+)
+
+// Kind classifies the errors the service fails to start with
+type Kind int
+
+const (
+	KindOther Kind = iota
+	// KindConfig is an invalid configuration
+	KindConfig
+	// KindDependency is an unavailable dependency, CMS, AAS, SCS, the SQVS store, the key store or the trusted
+	// time source
+	KindDependency
+	// KindSetupIncomplete is a missing file created by setup
+	KindSetupIncomplete
+)
+
+// Error is an error of a Kind
+type Error struct {
+	Kind Kind
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Cause() error {
+	return e.Err
+}
+
+// KindOf returns the kind of the outermost Error wrapped by err, KindOther when there is none
+func KindOf(err error) Kind {
+	var serverErr *Error
+	if stderrors.As(err, &serverErr) {
+		return serverErr.Kind
+	}
+	return KindOther
+}
+
+func configError(err error) error {
+	return &Error{Kind: KindConfig, Err: err}
+}
+
+func dependencyError(err error) error {
+	return &Error{Kind: KindDependency, Err: err}
+}
+
+// CheckSetupComplete returns a KindSetupIncomplete error when the files created by setup are missing
+func CheckSetupComplete(tlsCertFile string) error {
+	var missing []string
+	for _, file := range []string{path.Join(constants.ConfigDir, constants.ConfigFile), tlsCertFile,
+		constants.TrustedSGXRootCAFile} {
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			missing = append(missing, file)
+		}
+	}
+	if len(missing) > 0 {
+		return &Error{Kind: KindSetupIncomplete, Err: errors.Errorf("Setup has not been completed, %q missing, "+
+			"run sqvs setup all", missing)}
+	}
+	return nil
+}
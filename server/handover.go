@@ -0,0 +1,170 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package server
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// ListenerFDEnv names the descriptor of the listening socket the service binary of an upgrade inherits
+	// from the running one
+	ListenerFDEnv = "SQVS_LISTENER_FD"
+	// upgradeReadyFDEnv names the descriptor the service binary of an upgrade writes to once it serves
+	upgradeReadyFDEnv = "SQVS_UPGRADE_READY_FD"
+	// UpgradeReadyTimeout bounds the time the service binary of an upgrade is given to serve
+	UpgradeReadyTimeout = 30 * time.Second
+)
+
+// listen listens on the address, with SO_REUSEPORT when reusePort is set so another service binary can
+// listen on the same port
+func listen(network, address string, reusePort bool) (net.Listener, error) {
+	lc := net.ListenConfig{}
+	if reusePort {
+		lc.Control = func(network, address string, c syscall.RawConn) error {
+			var serr error
+			err := c.Control(func(fd uintptr) {
+				serr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+			})
+			if err != nil {
+				return err
+			}
+			return serr
+		}
+	}
+	return lc.Listen(context.Background(), network, address)
+}
+
+// inheritedListener returns the listening socket handed over by the service binary being upgraded, nil when
+// the service was not started by an upgrade
+func inheritedListener() (net.Listener, error) {
+	value := os.Getenv(ListenerFDEnv)
+	if value == "" {
+		return nil, nil
+	}
+	os.Unsetenv(ListenerFDEnv)
+	fd, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, errors.Errorf("server/handover:inheritedListener() Invalid %s %q", ListenerFDEnv, value)
+	}
+	file := os.NewFile(uintptr(fd), "listener")
+	defer file.Close()
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "server/handover:inheritedListener() Error using the inherited listener")
+	}
+	return listener, nil
+}
+
+// notifyUpgradeReady tells the service binary being upgraded that this one serves, so it drains its requests
+// and exits. It does nothing when the service was not started by an upgrade.
+func notifyUpgradeReady() {
+	value := os.Getenv(upgradeReadyFDEnv)
+	if value == "" {
+		return
+	}
+	os.Unsetenv(upgradeReadyFDEnv)
+	fd, err := strconv.Atoi(value)
+	if err != nil {
+		log.Errorf("server/handover:notifyUpgradeReady() Invalid %s %q", upgradeReadyFDEnv, value)
+		return
+	}
+	ready := os.NewFile(uintptr(fd), "upgrade-ready")
+	defer ready.Close()
+	if _, err = ready.Write([]byte{1}); err != nil {
+		log.WithError(err).Error("server/handover:notifyUpgradeReady() Error notifying the upgraded service")
+	}
+	// systemd tracks the new process as the main process of the service
+	sdNotify("MAINPID=" + strconv.Itoa(os.Getpid()))
+}
+
+// handOver starts the service binary, the one installed by an upgrade, on the listening socket of listener
+// with the arguments of this process, and returns once it serves. The new binary is stopped when it does not
+// serve within UpgradeReadyTimeout.
+func handOver(listener net.Listener) error {
+	tcpListener, ok := listener.(*net.TCPListener)
+	if !ok {
+		return errors.Errorf("server/handover:handOver() Cannot hand over a %T listener", listener)
+	}
+	file, err := tcpListener.File()
+	if err != nil {
+		return errors.Wrap(err, "server/handover:handOver() Error duplicating the listening socket")
+	}
+	defer file.Close()
+	readyReader, readyWriter, err := os.Pipe()
+	if err != nil {
+		return errors.Wrap(err, "server/handover:handOver() Error creating the readiness pipe")
+	}
+	defer readyReader.Close()
+
+	executable, err := os.Executable()
+	if err != nil {
+		readyWriter.Close()
+		return errors.Wrap(err, "server/handover:handOver() Error locating the service binary")
+	}
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), ListenerFDEnv+"=3", upgradeReadyFDEnv+"=4")
+	cmd.ExtraFiles = []*os.File{file, readyWriter}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Start()
+	readyWriter.Close()
+	if err != nil {
+		return errors.Wrap(err, "server/handover:handOver() Error starting the service binary")
+	}
+	log.Infof("server/handover:handOver() Started %s with PID %d on the listening socket", executable,
+		cmd.Process.Pid)
+
+	// the read fails once the new binary exits without serving
+	ready := make(chan error, 1)
+	go func() {
+		_, rerr := readyReader.Read(make([]byte, 1))
+		ready <- rerr
+	}()
+	select {
+	case err = <-ready:
+		if err == nil {
+			go cmd.Wait()
+			return nil
+		}
+		err = errors.Errorf("server/handover:handOver() The service binary exited before serving: %v", cmd.Wait())
+	case <-time.After(UpgradeReadyTimeout):
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		err = errors.Errorf("server/handover:handOver() The service binary did not serve within %v",
+			UpgradeReadyTimeout)
+	}
+	return err
+}
+
+// sdNotify sends the state to systemd when it started the service with a notification socket
+func sdNotify(state string) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return
+	}
+	if socket[0] == '@' {
+		// abstract socket
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		log.WithError(err).Warn("server/handover:sdNotify() Error connecting to the systemd notification socket")
+		return
+	}
+	defer conn.Close()
+	if _, err = conn.Write([]byte(state)); err != nil {
+		log.WithError(err).Warn("server/handover:sdNotify() Error notifying systemd")
+	}
+}
@@ -0,0 +1,48 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package server
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListenReusePort(t *testing.T) {
+	first, err := listen("tcp", "127.0.0.1:0", true)
+	assert.NoError(t, err)
+	defer first.Close()
+	second, err := listen("tcp", first.Addr().String(), true)
+	assert.NoError(t, err)
+	second.Close()
+
+	_, err = listen("tcp", first.Addr().String(), false)
+	assert.Error(t, err)
+}
+
+func TestInheritedListener(t *testing.T) {
+	listener, err := inheritedListener()
+	assert.NoError(t, err)
+	assert.Nil(t, listener)
+
+	original, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer original.Close()
+	file, err := original.(*net.TCPListener).File()
+	assert.NoError(t, err)
+	os.Setenv(ListenerFDEnv, strconv.Itoa(int(file.Fd())))
+	listener, err = inheritedListener()
+	assert.NoError(t, err)
+	assert.Equal(t, original.Addr().String(), listener.Addr().String())
+	assert.Empty(t, os.Getenv(ListenerFDEnv))
+	listener.Close()
+
+	os.Setenv(ListenerFDEnv, "listener")
+	_, err = inheritedListener()
+	assert.Error(t, err)
+}
@@ -0,0 +1,827 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/tls"
+	"encoding/pem"
+	"intel/isecl/lib/common/v4/crypt"
+	commLog "intel/isecl/lib/common/v4/log"
+	commLogMsg "intel/isecl/lib/common/v4/log/message"
+	"intel/isecl/lib/common/v4/middleware"
+	"intel/isecl/sqvs/v4/aasclient"
+	"intel/isecl/sqvs/v4/anomaly"
+	"intel/isecl/sqvs/v4/capture"
+	"intel/isecl/sqvs/v4/claims"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/dependencies"
+	"intel/isecl/sqvs/v4/egress"
+	"intel/isecl/sqvs/v4/events"
+	"intel/isecl/sqvs/v4/expiry"
+	"intel/isecl/sqvs/v4/fips"
+	"intel/isecl/sqvs/v4/keyrelease"
+	"intel/isecl/sqvs/v4/keystore"
+	"intel/isecl/sqvs/v4/logformat"
+	"intel/isecl/sqvs/v4/netfamily"
+	"intel/isecl/sqvs/v4/proxyproto"
+	"intel/isecl/sqvs/v4/quoteprovider"
+	"intel/isecl/sqvs/v4/repository"
+	_ "intel/isecl/sqvs/v4/repository/memory"
+	_ "intel/isecl/sqvs/v4/repository/postgres"
+	_ "intel/isecl/sqvs/v4/repository/sqlite"
+	"intel/isecl/sqvs/v4/resilience"
+	"intel/isecl/sqvs/v4/resolver"
+	"intel/isecl/sqvs/v4/resource"
+	"intel/isecl/sqvs/v4/retention"
+	"intel/isecl/sqvs/v4/signingkey"
+	"intel/isecl/sqvs/v4/tlspolicy"
+	"intel/isecl/sqvs/v4/trustedtime"
+	"intel/isecl/sqvs/v4/truststore"
+	"io"
+	"io/ioutil"
+	stdlog "log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gorilla/handlers"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+var log = commLog.GetDefaultLogger()
+var slog = commLog.GetSecurityLogger()
+
+// DefaultShutdownTimeout bounds the time in flight requests are given to complete once the service is stopped
+const DefaultShutdownTimeout = 5 * time.Second
+
+// Server runs the quote verification service in process. The service of the sqvs run command is one, integration
+// tests and embedders run their own, typically on an ephemeral port:
+//
+//	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+//	srv := server.New(c)
+//	srv.Listener = listener
+//	go srv.Start(ctx)
+//	<-srv.Ready()
+//
+// The files created by setup, under /etc/sqvs, are still required.
+type Server struct {
+	// Listener accepts the HTTPS connections, a listener on the configured address and port when nil
+	Listener net.Listener
+	// HTTPLogWriter receives the HTTP access and error log, os.Stderr when nil
+	HTTPLogWriter io.Writer
+	// ShutdownTimeout bounds the graceful shutdown, DefaultShutdownTimeout when zero
+	ShutdownTimeout time.Duration
+	// Upgrade receives a value when the service binary was upgraded. The server hands its listening socket over
+	// to the new binary, then drains its requests and returns once the new binary serves. Upgrades are refused
+	// while the SQVS store of the memory driver is open.
+	Upgrade <-chan os.Signal
+
+	config *config.Configuration
+	ready  chan struct{}
+	addr   net.Addr
+}
+
+// New returns the server of the configuration, started with Start
+func New(c *config.Configuration) *Server {
+	return &Server{config: c, ready: make(chan struct{})}
+}
+
+// Ready returns a channel closed once the server accepts connections
+func (s *Server) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Addr returns the address the server accepts connections on, nil until it is ready
+func (s *Server) Addr() net.Addr {
+	select {
+	case <-s.ready:
+		return s.addr
+	default:
+		return nil
+	}
+}
+
+func (s *Server) httpLogWriter() io.Writer {
+	if s.HTTPLogWriter != nil {
+		return s.HTTPLogWriter
+	}
+	return os.Stderr
+}
+
+func (s *Server) shutdownTimeout() time.Duration {
+	if s.ShutdownTimeout > 0 {
+		return s.ShutdownTimeout
+	}
+	return DefaultShutdownTimeout
+}
+
+// Start initializes the service and serves requests until the context is done, then shuts the server down
+// gracefully. It returns an *Error classifying why the service could not start, and is called once per Server.
+func (s *Server) Start(ctx context.Context) error {
+	c := s.config
+	log.Info("Starting SGX Quote Verification Server")
+	provenance := resource.BuildProvenance(c)
+	log.Infof("server/server:Start() %s %s-%s built %s with %s, SBOM SHA-256 %q, features: %s",
+		provenance.ServiceName, provenance.Version, provenance.GitHash, provenance.BuildDate, provenance.GoVersion,
+		provenance.SBOMHash, strings.Join(provenance.Features, ", "))
+
+	if err := CheckSetupComplete(c.TLSCertFile); err != nil {
+		return err
+	}
+
+	if c.FipsMode {
+		err := fips.Validate(c)
+		if err != nil {
+			return configError(errors.Wrap(err, "server/server:Start() Error enabling FIPS mode"))
+		}
+		module, _ := fips.CryptoModule()
+		log.Infof("server/server:Start() Running in FIPS mode with the %s", module)
+	}
+
+	// dependencies are reached over the configured address family from here on
+	netfamily.SetDefault(c.AddressFamily)
+	dnsResolver, err := resolver.New(c.DNS.HostOverrides, c.DNS.Resolver, c.DNS.Servers, c.DNS.CacheTTL)
+	if err != nil {
+		return configError(errors.Wrap(err, "server/server:Start() Invalid DNS configuration"))
+	}
+	resolver.Set(dnsResolver)
+	for _, override := range c.DNS.HostOverrides {
+		log.Infof("server/server:Start() Host override %s, the host is not resolved through DNS", override)
+	}
+	allowList, err := egress.Parse(c.EgressAllowList)
+	if err != nil {
+		return configError(errors.Wrap(err, "server/server:Start() Invalid egress allow-list"))
+	}
+	egress.Set(allowList)
+	if allowList != nil {
+		for _, destination := range []string{c.SCSBaseURL, c.CMSBaseURL, c.AuthServiceURL, c.WebhookURL,
+			c.CollateralCheck.URL} {
+			if u, err := url.Parse(destination); err == nil && u.Host != "" && !allowList.AllowsHost(u.Hostname()) {
+				log.Warnf("server/server:Start() %s is not allowed by name or address by the egress allow-list, "+
+					"connections to it are blocked unless it resolves into an allowed CIDR block", u.Host)
+			}
+		}
+	}
+
+	truststore.DisableLegacyCiphers(c.TLSPolicy.DisableLegacyCiphers)
+
+	if c.WaitForDependencies {
+		err := dependencies.WaitFor(dependencies.FromConfig(c), c.DependencyWaitTimeout, c.DependencyRetryInterval,
+			c.DependencyMaxRetryInterval)
+		if err != nil {
+			return dependencyError(errors.Wrap(err, "server/server:Start() Dependencies did not become reachable"))
+		}
+	}
+
+	// Create Router, set routes
+	r := mux.NewRouter()
+	r.SkipClean(true)
+
+	// set version endpoint
+	sr := r.PathPrefix("/svs/v{version:[1-2]}/").Subrouter()
+	func(setters ...func(*mux.Router)) {
+		for _, setter := range setters {
+			setter(sr)
+		}
+	}(resource.SetVersionRoutes, resource.SetMetricsRoutes, resource.SetJWKSRoutes, resource.SetRevocationListRoutes,
+		resource.SetAdminUIRoutes)
+
+	// Reload the trusted CAs and JWT signing certificates when they are rotated on disk
+	watchStop := make(chan struct{})
+	defer close(watchStop)
+	caStore, err := truststore.New(constants.TrustedCAsStoreDir)
+	if err != nil {
+		return errors.Wrap(err, "server/server:Start() Error loading trusted CA certificates")
+	}
+	truststore.Register(caStore)
+	err = truststore.WatchStore(caStore, constants.TrustStoreReloadDelay, watchStop)
+	if err != nil {
+		log.WithError(err).Warn("server/server:Start() Trusted CA certificates will not be reloaded on change")
+	}
+
+	tokenAuth := truststore.NewReloadableMiddleware(func() mux.MiddlewareFunc {
+		return middleware.NewTokenAuth(constants.TrustedJWTSigningCertsDir, constants.TrustedCAsStoreDir, s.fetchJwtCerts,
+			time.Minute*constants.DefaultJwtValidateCacheKeyMins)
+	})
+	if c.IncludeToken {
+		caStore.OnReload(tokenAuth.Reload)
+		err = truststore.Watch(constants.TrustedJWTSigningCertsDir, constants.TrustStoreReloadDelay, func() {
+			log.Info("server/server:Start() Trusted JWT signing certificates changed, reloading token authentication")
+			tokenAuth.Reload()
+		}, watchStop)
+		if err != nil {
+			log.WithError(err).Warn("server/server:Start() Trusted JWT signing certificates will not be reloaded on change")
+		}
+	}
+
+	// PCK CRLs imported by the CLI apply to the chains verified before the import too
+	err = truststore.Watch(constants.PckCrlDir, constants.TrustStoreReloadDelay, func() {
+		log.Info("server/server:Start() Imported PCK CRLs changed, verifying PCK certificate chains again")
+		resource.InvalidateVerifiedPckChains()
+	}, watchStop)
+	if err != nil {
+		log.WithError(err).Warn("server/server:Start() PCK CRLs imported will only apply to PCK certificate chains " +
+			"not verified yet")
+	}
+
+	// Relying parties are registered and rotate their request signing keys in the relying party key directory
+	relyingParties, err := resource.NewRelyingParties(constants.RelyingPartyKeysDir)
+	if err != nil {
+		return errors.Wrap(err, "server/server:Start() Error loading relying party keys")
+	}
+	resource.SetRelyingParties(relyingParties)
+	// the uses of request signatures are recorded in the SQVS store whenever relying parties can sign
+	signedRequests := c.RequireSignedRequests
+	if _, err = os.Stat(constants.RelyingPartyKeysDir); err == nil {
+		signedRequests = true
+		err = truststore.Watch(constants.RelyingPartyKeysDir, constants.TrustStoreReloadDelay, func() {
+			log.Info("server/server:Start() Relying party keys changed, reloading them")
+			rerr := relyingParties.Reload()
+			if rerr != nil {
+				log.WithError(rerr).Error("server/server:Start() Error reloading relying party keys")
+				slog.WithFields(policyEventFields(logformat.OutcomeFailure)).Error(
+					"server/server:Start() Changed relying party registrations rejected")
+				return
+			}
+			slog.WithFields(policyEventFields(logformat.OutcomeSuccess)).Infof(
+				"server/server:Start() Relying party registrations reloaded, %d relying parties", relyingParties.Len())
+		}, watchStop)
+		if err != nil {
+			log.WithError(err).Warn("server/server:Start() Relying party keys will not be reloaded on change")
+		}
+	}
+	if c.RequireSignedRequests && relyingParties.Len() == 0 {
+		log.Warnf("server/server:Start() Signed requests are required but no relying party is registered in %s, "+
+			"quote appraisal requests will be rejected", constants.RelyingPartyKeysDir)
+	}
+
+	resilience.SetDefault(resilience.NewPolicy(c.Outbound))
+
+	tokens, err := aasclient.FromConfig(c)
+	if err != nil {
+		return errors.Wrap(err, "server/server:Start() Error initializing AAS token client")
+	}
+	if tokens != nil {
+		aasclient.SetDefault(tokens)
+		go tokens.KeepFresh(watchStop)
+	}
+
+	clock, err := trustedtime.New(c.TrustedTime, watchStop)
+	if err != nil {
+		return dependencyError(errors.Wrap(err, "server/server:Start() Error initializing the trusted time source"))
+	}
+	trustedtime.SetDefault(clock)
+
+	admission := resource.NewAdmissionController(c.MaxConcurrentRequests, c.MaxQueuedRequests, c.MaxQueueWait)
+
+	// Maintenance mode is turned on and off by the CLI through the maintenance file
+	maintenance, err := resource.NewMaintenanceMode(constants.MaintenanceFile)
+	if err != nil {
+		return errors.Wrap(err, "server/server:Start() Error loading maintenance mode")
+	}
+	resource.SetMaintenanceMode(maintenance)
+	resource.PublishFeatureFlags(c)
+	// Edits of config.yml are recorded in the configuration history, they are applied on restart
+	configChanges := config.NewChangeDetector(path.Join(constants.ConfigDir, constants.ConfigFile), c)
+	// The data store files also live in the configuration directory, their writes must not postpone the reload
+	err = truststore.WatchFiles(constants.ConfigDir, []string{path.Base(constants.MaintenanceFile), constants.ConfigFile},
+		constants.TrustStoreReloadDelay, func() {
+			rerr := maintenance.Reload()
+			if rerr != nil {
+				log.WithError(rerr).Error("server/server:Start() Error reloading maintenance mode")
+			}
+			rerr = configChanges.Check()
+			if rerr != nil {
+				log.WithError(rerr).Error("server/server:Start() Error recording configuration changes")
+			}
+		}, watchStop)
+	if err != nil {
+		log.WithError(err).Warn("server/server:Start() Maintenance mode changes made by the CLI will not be applied")
+	}
+
+	// The capacity is reported to the autoscalers outside of maintenance and admission control, which would
+	// shed its requests when the replicas are overloaded
+	resource.SetAdmissionController(admission)
+	sr = r.PathPrefix("/svs/v1/").Subrouter()
+	if c.IncludeToken {
+		if c.FipsMode {
+			sr.Use(resource.FipsTokenMiddleware)
+		}
+		sr.Use(tokenAuth.Middleware)
+	}
+	resource.CapacityCB(sr)
+
+	auth := []mux.MiddlewareFunc{maintenance.Middleware()}
+	if c.IncludeToken {
+		if c.FipsMode {
+			auth = append(auth, resource.FipsTokenMiddleware)
+		}
+		auth = append(auth, tokenAuth.Middleware)
+	}
+	checks := []mux.MiddlewareFunc{resource.RequestSignatureMiddleware, resource.QuotaMiddleware}
+
+	verification, other := apiRouters(r, "/svs/v1/", auth, admission.Middleware(), checks)
+	for _, setter := range []func(*mux.Router){resource.QuoteVerifyCB, resource.ReportVerifyCB, resource.AttestCB} {
+		setter(verification)
+	}
+	for _, setter := range []func(*mux.Router){resource.VerificationHistoryCB, resource.VerifierEvidenceCB,
+		resource.SigningKeyCB, resource.MaintenanceCB, resource.UsageCB, resource.DependenciesCB,
+		resource.PlatformEnrollmentCB, resource.RevocationCB, resource.AdminUICB, resource.PayloadCaptureCB,
+		resource.EnclaveIdentityCB, resource.CollateralProxyCB, resource.ConfigHistoryCB} {
+		setter(other)
+	}
+
+	verification, _ = apiRouters(r, "/svs/v2/", auth, admission.Middleware(), checks)
+	for _, setter := range []func(*mux.Router){resource.QuoteVerifyCBAndSign, resource.VerificationSessionCB} {
+		setter(verification)
+	}
+
+	if c.EnableTestMode && resource.TestModeBuild {
+		log.Warn("server/server:Start() Test mode is enabled, canned verdicts of test quotes are served under /svs/test/v1/")
+		verification, _ = apiRouters(r, "/svs/test/v1/", auth, admission.Middleware(), checks)
+		resource.TestModeCB(verification)
+	} else if c.EnableTestMode {
+		log.Warn("server/server:Start() Test mode is not built in, SQVS_ENABLE_TEST_MODE is ignored")
+	}
+
+	// the snapshot and journal of the memory driver are written by the process that loaded them, the service
+	// binary of an upgrade would load them while this one still writes them
+	memoryStore := false
+	if c.EnableTcbDowngradeDetection || c.EnableVerificationHistory || c.Quota.Enabled || c.RequirePlatformEnrollment ||
+		c.EnableResultRevocation || c.EnableCollateralHistory || c.RequireRegisteredEnclaves ||
+		c.FeatureEnabled(config.FeatureVerificationSessions) || signedRequests {
+		db, err := repository.Open(c.Database)
+		if errors.Cause(err) == repository.ErrSchemaVersion {
+			return errors.Wrap(err, "server/server:Start() SQVS store must be migrated")
+		} else if err != nil {
+			return dependencyError(errors.Wrap(err, "server/server:Start() Error initializing SQVS store"))
+		}
+		defer db.Close()
+		resource.SetRepository(db)
+		memoryStore = c.Database.Driver == "" || c.Database.Driver == repository.DriverMemory
+		if c.RetainRawQuotes {
+			encrypter, err := keystore.NewEncrypter(c.RawQuotes, c.KeyStore, constants.TrustedCAsStoreDir)
+			if err != nil {
+				return errors.Wrap(err, "server/server:Start() Error initializing raw quote encryption")
+			}
+			resource.SetRawQuoteEncrypter(encrypter)
+		}
+		go resource.RefreshPinnedCollateral()
+
+		if c.EnableVerificationHistory {
+			retentionConf := c.Retention
+			// the memory driver keeps the whole history in memory, it is bounded even when no limit is configured
+			if memoryStore && retentionConf.MaxRecords == 0 {
+				retentionConf.MaxRecords = constants.DefaultMemoryHistoryMaxRecords
+				log.Infof("server/server:Start() The verification history of the memory driver is limited to the newest %d records",
+					retentionConf.MaxRecords)
+			}
+			pruner, err := retention.NewPruner(retentionConf, db.VerificationRepository())
+			if err != nil {
+				return configError(errors.Wrap(err, "server/server:Start() Error initializing verification history retention"))
+			}
+			go pruner.Run(c.Retention.PruneInterval, watchStop)
+		}
+
+		if c.Quota.Enabled {
+			tracker, err := resource.NewQuotaTracker(c.Quota, db.UsageRepository())
+			if err != nil {
+				return configError(errors.Wrap(err, "server/server:Start() Error initializing quota tracker"))
+			}
+			resource.SetQuotaTracker(tracker)
+		}
+	}
+
+	var alertPublisher events.Publisher = events.NoopPublisher{}
+	if c.WebhookURL != "" {
+		publisher, err := events.NewWebhookPublisher(c.WebhookURL, constants.TrustedCAsStoreDir, constants.DefaultWebhookTimeout)
+		if err != nil {
+			return errors.Wrap(err, "server/server:Start() Error initializing webhook publisher")
+		}
+		resource.SetEventPublisher(publisher)
+		alertPublisher = publisher
+	}
+
+	if c.Anomaly.Enabled {
+		anomalyPublisher := alertPublisher
+		if c.Anomaly.WebhookURL != "" {
+			anomalyPublisher, err = events.NewWebhookPublisher(c.Anomaly.WebhookURL, constants.TrustedCAsStoreDir,
+				constants.DefaultWebhookTimeout)
+			if err != nil {
+				return errors.Wrap(err, "server/server:Start() Error initializing anomaly webhook publisher")
+			}
+		}
+		resource.SetAnomalyDetector(anomaly.NewDetector(anomaly.Thresholds{
+			Window:               c.Anomaly.Window,
+			FailedVerifications:  c.Anomaly.FailedVerifications,
+			DebugQuotes:          c.Anomaly.DebugQuotes,
+			DistinctMeasurements: c.Anomaly.DistinctMeasurements,
+		}), anomalyPublisher)
+	}
+
+	expirySources := []expiry.Source{
+		expiry.CertificateFile(expiry.KindTLSCertificate, c.TLSCertFile),
+		expiry.CertificateFile(expiry.KindSigningCertificate, constants.PublicKeyLocation),
+		expiry.CertificateFile(expiry.KindRootCA, constants.TrustedSGXRootCAFile),
+		resource.CollateralExpiry,
+	}
+	if c.TLSSecondaryCertFile != "" {
+		expirySources = append(expirySources, expiry.CertificateFile(expiry.KindTLSCertificate, c.TLSSecondaryCertFile))
+	}
+	expiryChecker := expiry.NewChecker(c.Expiry.AlertThresholds, alertPublisher, expirySources...)
+	go expiryChecker.Run(c.Expiry.CheckInterval, watchStop)
+
+	if c.ResultEvents.Broker != "" {
+		resultPublisher, err := events.NewQueuePublisher(c.ResultEvents, constants.TrustedCAsStoreDir)
+		if err != nil {
+			return dependencyError(errors.Wrap(err, "server/server:Start() Error initializing verification result publisher"))
+		}
+		defer func() {
+			derr := resultPublisher.Close()
+			if derr != nil {
+				log.WithError(derr).Error("Error closing verification result publisher")
+			}
+		}()
+		resource.SetResultPublisher(resultPublisher)
+	}
+
+	keyBroker, err := keyrelease.Open(c.KeyRelease, constants.TrustedCAsStoreDir)
+	if err != nil {
+		return configError(errors.Wrap(err, "server/server:Start() Error initializing key broker"))
+	}
+	resource.SetKeyBroker(keyBroker)
+
+	if c.PayloadCapture.Enabled {
+		captureFile := c.PayloadCapture.File
+		if captureFile == "" {
+			captureFile = constants.DefaultPayloadCaptureFile
+		}
+		maxBodySize := c.PayloadCapture.MaxBodySize
+		if maxBodySize <= 0 {
+			maxBodySize = constants.DefaultPayloadCaptureBodySize
+		}
+		resource.SetPayloadCapture(capture.NewRecorder(captureFile, maxBodySize,
+			capture.NewRedactor(c.PayloadCapture.RedactFields)))
+	}
+
+	ks, err := keystore.New(c.KeyStore, constants.TrustedCAsStoreDir)
+	if err != nil {
+		return dependencyError(errors.Wrap(err, "server/server:Start() Error initializing key store"))
+	}
+	defer func() {
+		derr := ks.Close()
+		if derr != nil {
+			log.WithError(derr).Error("Error closing key store")
+		}
+	}()
+	signingKeyID := c.KeyStore.SigningKeyID
+	if signingKeyID == "" {
+		signingKeyID = constants.PrivateKeyLocation
+	}
+	resource.SetSigningKeys(signingkey.NewRing(ks, signingKeyID, constants.PublicKeyLocation,
+		constants.RetiredSigningCerts, c.SigningKeyOverlap, c.KeyStore.Type == "" || c.KeyStore.Type == keystore.TypeFile))
+	algorithmKeys, err := loadAlgorithmSigningKeys(ks, c.ResultSigningKeys)
+	if err != nil {
+		return configError(err)
+	}
+	resource.SetAlgorithmSigningKeys(algorithmKeys)
+
+	customClaimsFile := c.CustomClaimsFile
+	if customClaimsFile == "" {
+		customClaimsFile = constants.CustomClaimsFile
+	}
+	customClaims, err := claims.Load(customClaimsFile)
+	if err != nil {
+		return configError(errors.Wrap(err, "server/server:Start() Error loading custom claims"))
+	}
+	policyAuthors, err := resource.NewRelyingParties(constants.PolicyAuthorKeysDir)
+	if err != nil {
+		return errors.Wrap(err, "server/server:Start() Error loading policy author keys")
+	}
+	customClaimsSignature, err := resource.VerifyPolicySignature(customClaimsFile, policyAuthors,
+		c.RequireSignedPolicies)
+	if err != nil {
+		slog.WithError(err).WithFields(policyEventFields(logformat.OutcomeFailure)).Error(
+			"server/server:Start() Custom claims policy rejected")
+		return configError(errors.Wrap(err, "server/server:Start() Error verifying custom claims signature"))
+	}
+	resource.SetCustomClaims(customClaims)
+	resource.SetCustomClaimsSignature(customClaimsSignature)
+	if customClaimsSignature != nil {
+		slog.WithFields(policyEventFields(logformat.OutcomeSuccess)).WithField("author", customClaimsSignature.Author).
+			Infof("server/server:Start() Custom claims policy signed by %s loaded from %s",
+				customClaimsSignature.Author, customClaimsFile)
+	} else {
+		slog.WithFields(policyEventFields(logformat.OutcomeSuccess)).Infof(
+			"server/server:Start() Custom claims policy loaded from %s", customClaimsFile)
+	}
+
+	keyID := c.KeyStore.TLSKeyID
+	if keyID == "" {
+		keyID = c.TLSKeyFile
+	}
+	tlsCert, err := loadTLSCertificate(ks, c.TLSCertFile, keyID)
+	if err != nil {
+		return errors.Wrap(err, "server/server:Start() Error loading TLS certificate")
+	}
+	tlsCerts := []tls.Certificate{tlsCert}
+	if c.TLSSecondaryCertFile != "" {
+		secondaryCert, err := loadTLSCertificate(ks, c.TLSSecondaryCertFile, c.TLSSecondaryKeyID)
+		if err != nil {
+			return errors.Wrap(err, "server/server:Start() Error loading secondary TLS certificate")
+		}
+		tlsCerts, err = orderTLSCertificates(tlsCert, secondaryCert)
+		if err != nil {
+			return configError(errors.Wrap(err, "server/server:Start() Invalid secondary TLS certificate"))
+		}
+	}
+
+	err = tlspolicy.Validate(c.TLSPolicy, tlsCerts, constants.TrustedCAsStoreDir)
+	if err != nil {
+		return configError(errors.Wrap(err, "server/server:Start() TLS certificates do not meet the TLS policy"))
+	}
+
+	quoteProvider, err := quoteprovider.New(c.VerifierEvidence)
+	if err != nil {
+		return errors.Wrap(err, "server/server:Start() Error initializing verifier evidence")
+	}
+	if quoteProvider != nil {
+		resource.SetVerifierEvidence(quoteProvider, tlsCert.Certificate[0])
+	}
+
+	tlsconfig := &tls.Config{
+		Certificates: tlsCerts,
+		MinVersion:   tls.VersionTLS13,
+		CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+	}
+	if c.FipsMode {
+		tlsconfig.CipherSuites = fips.CipherSuites
+		tlsconfig.CurvePreferences = fips.CurvePreferences
+	}
+	var handler http.Handler = r
+	if len(c.CorsAllowedOrigins) > 0 {
+		handler = handlers.CORS(
+			handlers.AllowedOrigins(c.CorsAllowedOrigins),
+			handlers.AllowedMethods(c.CorsAllowedMethods),
+			handlers.AllowedHeaders(c.CorsAllowedHeaders),
+		)(r)
+	}
+	ipFilter, err := resource.NewIPFilter(c.IPAllowList, c.IPDenyList)
+	if err != nil {
+		return configError(errors.Wrap(err, "server/server:Start() Error initializing client address filter"))
+	}
+	handler = ipFilter.Middleware()(handler)
+	proxies, err := resource.NewTrustedProxies(c.TrustedProxies)
+	if err != nil {
+		return configError(errors.Wrap(err, "server/server:Start() Error initializing trusted proxies"))
+	}
+	handler = fips.Middleware(c.FipsMode)(handler)
+	// error responses are RFC 7807 problem details, including those of the token authentication and unknown routes
+	handler = resource.ProblemMiddleware(handler)
+	// captured responses are those the clients get, problem details included
+	handler = resource.PayloadCaptureMiddleware(handler)
+
+	httpLog := stdlog.New(s.httpLogWriter(), "", 0)
+	accessLog := s.httpLogWriter()
+	if c.AccessLogSampleSuccesses > 1 {
+		accessLog = &logformat.AccessLogSampler{Writer: accessLog, Every: uint64(c.AccessLogSampleSuccesses)}
+	}
+	listener := s.Listener
+	if listener == nil {
+		// the service binary of an upgrade serves on the listening socket of the one it replaces
+		listener, err = inheritedListener()
+		if err != nil {
+			return errors.Wrap(err, "server/server:Start() Error listening for HTTPS connections")
+		}
+	}
+	if listener == nil {
+		listener, err = listen(netfamily.ListenNetwork(c.AddressFamily), netfamily.ListenAddress(c.ListenAddress, c.Port),
+			c.ReusePort)
+		if err != nil {
+			return errors.Wrap(err, "server/server:Start() Error listening for HTTPS connections")
+		}
+	}
+	// the socket handed over on upgrades is the one below the PROXY protocol
+	baseListener := listener
+	if c.ProxyProtocol {
+		var fromProxy func(net.IP) bool
+		if proxies != nil {
+			fromProxy = proxies.Trusted
+		}
+		listener = proxyproto.NewListener(listener, fromProxy)
+	}
+	h := &http.Server{
+		Addr:              listener.Addr().String(),
+		Handler:           handlers.RecoveryHandler(handlers.RecoveryLogger(httpLog), handlers.PrintRecoveryStack(true))(proxies.Middleware()(handlers.CombinedLoggingHandler(accessLog, handler))),
+		ErrorLog:          httpLog,
+		TLSConfig:         tlsconfig,
+		ReadTimeout:       c.ReadTimeout,
+		ReadHeaderTimeout: c.ReadHeaderTimeout,
+		WriteTimeout:      c.WriteTimeout,
+		IdleTimeout:       c.IdleTimeout,
+		MaxHeaderBytes:    c.MaxHeaderBytes,
+	}
+
+	// dispatch web server go routine
+	served := make(chan error, 1)
+	go func() {
+		served <- h.ServeTLS(listener, "", "")
+	}()
+	s.addr = listener.Addr()
+	close(s.ready)
+	notifyUpgradeReady()
+	sdNotify("READY=1")
+
+	slog.Info(commLogMsg.ServiceStart)
+	for served != nil {
+		select {
+		case err := <-served:
+			log.WithError(err).Info("Failed to start HTTPS server")
+			return errors.Wrap(err, "server/server:Start() Error serving HTTPS connections")
+		case <-s.Upgrade:
+			if memoryStore {
+				log.Error("server/server:Start() Upgrade refused, the SQVS store of the memory driver cannot be handed " +
+					"over, restart the service to run the upgraded service binary")
+				continue
+			}
+			log.Info("server/server:Start() Handing the listening socket over to the upgraded service binary")
+			if err := handOver(baseListener); err != nil {
+				log.WithError(err).Error("server/server:Start() Upgrade failed, the running service binary keeps serving")
+				continue
+			}
+			log.Info("server/server:Start() The upgraded service binary serves, draining the in flight requests")
+			served = nil
+		case <-ctx.Done():
+			served = nil
+		}
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout())
+	defer cancel()
+	if err := h.Shutdown(shutdownCtx); err != nil {
+		log.WithError(err).Info("Failed to gracefully shutdown webserver")
+		return err
+	}
+	slog.Info(commLogMsg.ServiceStop)
+	return nil
+}
+
+// apiRouters returns the subrouters of the API routes under prefix: the verification routes, admitted by
+// admission, and the other ones, which operators need to answer while verifications overload the service.
+// Requests are admitted once authenticated by the middlewares of auth, unauthenticated clients cannot take
+// the slots of others, and checks apply after admission.
+func apiRouters(r *mux.Router, prefix string, auth []mux.MiddlewareFunc, admission mux.MiddlewareFunc,
+	checks []mux.MiddlewareFunc) (verification, other *mux.Router) {
+	verification = r.PathPrefix(prefix).Subrouter()
+	verification.Use(auth...)
+	verification.Use(admission)
+	verification.Use(checks...)
+
+	other = r.PathPrefix(prefix).Subrouter()
+	other.Use(auth...)
+	other.Use(checks...)
+	return verification, other
+}
+
+// policyEventFields are the fields of the security log records of policy changes
+func policyEventFields(outcome string) logrus.Fields {
+	return logrus.Fields{
+		logformat.EventField:   logformat.EventPolicy,
+		logformat.OutcomeField: outcome,
+	}
+}
+
+// loadTLSCertificate pairs the TLS certificate chain on disk with its private key keyID from the key store
+func loadTLSCertificate(ks keystore.KeyStore, certFile, keyID string) (tls.Certificate, error) {
+	var tlsCert tls.Certificate
+	certPem, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return tlsCert, errors.Wrap(err, "server/server:loadTLSCertificate() Error reading TLS certificate")
+	}
+	for {
+		var block *pem.Block
+		block, certPem = pem.Decode(certPem)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			tlsCert.Certificate = append(tlsCert.Certificate, block.Bytes)
+		}
+	}
+	if len(tlsCert.Certificate) == 0 {
+		return tlsCert, errors.New("server/server:loadTLSCertificate() No certificate found in TLS certificate file")
+	}
+
+	tlsCert.PrivateKey, err = ks.Signer(keyID)
+	if err != nil {
+		return tlsCert, errors.Wrap(err, "server/server:loadTLSCertificate() Error loading TLS key")
+	}
+	return tlsCert, nil
+}
+
+// orderTLSCertificates returns the RSA and ECDSA certificate chains with the ECDSA one first. With several
+// certificates the TLS stack serves the first one the client supports the signature algorithms of, so legacy
+// clients unable to negotiate ECDSA get the RSA chain.
+func orderTLSCertificates(primary, secondary tls.Certificate) ([]tls.Certificate, error) {
+	switch primaryKey, secondaryKey := tlsKeyOf(primary), tlsKeyOf(secondary); {
+	case isECDSAKey(primaryKey) && isRSAKey(secondaryKey):
+		return []tls.Certificate{primary, secondary}, nil
+	case isRSAKey(primaryKey) && isECDSAKey(secondaryKey):
+		return []tls.Certificate{secondary, primary}, nil
+	}
+	return nil, errors.New("server/server:orderTLSCertificates() One TLS key must be RSA and the other ECDSA")
+}
+
+func tlsKeyOf(cert tls.Certificate) crypto.PublicKey {
+	if signer, ok := cert.PrivateKey.(crypto.Signer); ok {
+		return signer.Public()
+	}
+	return nil
+}
+
+func isRSAKey(key crypto.PublicKey) bool {
+	_, ok := key.(*rsa.PublicKey)
+	return ok
+}
+
+func isECDSAKey(key crypto.PublicKey) bool {
+	_, ok := key.(*ecdsa.PublicKey)
+	return ok
+}
+
+func (s *Server) fetchJwtCerts() error {
+	conf := s.config
+	if !strings.HasSuffix(conf.AuthServiceURL, "/") {
+		conf.AuthServiceURL += "/"
+	}
+	url := conf.AuthServiceURL + "jwt-certificates"
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return errors.Wrap(err, "Could not create http request")
+	}
+	req.Header.Add("accept", "application/x-pem-file")
+	httpClient, err := truststore.HTTPClient(constants.TrustedCAsStoreDir)
+	if err != nil {
+		return errors.Wrap(err, "Could not create http client")
+	}
+
+	res, err := resilience.Default().Client(httpClient).Do(req)
+	if err != nil {
+		log.Error("Failed to fetch JWT cert")
+		return errors.Wrap(err, "Could not retrieve jwt certificate")
+	}
+	if res != nil {
+		defer func() {
+			derr := res.Body.Close()
+			if derr != nil {
+				log.WithError(derr).Error("Error closing jwtcert response")
+			}
+		}()
+	}
+
+	body, _ := ioutil.ReadAll(res.Body)
+	err = crypt.SavePemCertWithShortSha1FileName(body, constants.TrustedJWTSigningCertsDir)
+	if err != nil {
+		return errors.Wrap(err, "Could not store Certificate")
+	}
+
+	return nil
+}
+
+// loadAlgorithmSigningKeys loads the signing keys dedicated to an algorithm and checks they can sign with it
+func loadAlgorithmSigningKeys(ks keystore.KeyStore, entries []string) (map[string]*signingkey.Ring, error) {
+	rings := map[string]*signingkey.Ring{}
+	for _, entry := range entries {
+		algorithmKey, err := signingkey.ParseAlgorithmKey(entry)
+		if err != nil {
+			return nil, errors.Wrap(err, "server/server:loadAlgorithmSigningKeys() Invalid result signing key")
+		}
+		ring := signingkey.NewRing(ks, algorithmKey.KeyID, algorithmKey.CertFile, "", 0, false)
+		key, err := ring.Current()
+		if err != nil {
+			return nil, errors.Wrapf(err, "server/server:loadAlgorithmSigningKeys() Error loading %s signing key",
+				algorithmKey.Algorithm)
+		}
+		if !key.Supports(algorithmKey.Algorithm) {
+			return nil, errors.Errorf("server/server:loadAlgorithmSigningKeys() Signing key %s cannot sign with %s",
+				algorithmKey.KeyID, algorithmKey.Algorithm)
+		}
+		rings[algorithmKey.Algorithm] = ring
+	}
+	return rings, nil
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/resource"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartSetupIncomplete(t *testing.T) {
+	srv := New(&config.Configuration{TLSCertFile: "/nonexistent/tls-cert.pem"})
+	err := srv.Start(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, KindSetupIncomplete, KindOf(err))
+	assert.Nil(t, srv.Addr())
+}
+
+func TestKindOf(t *testing.T) {
+	assert.Equal(t, KindOther, KindOf(errors.New("failure")))
+	assert.Equal(t, KindConfig, KindOf(errors.Wrap(configError(errors.New("invalid")), "wrapped")))
+	assert.Equal(t, KindDependency, KindOf(dependencyError(errors.New("unavailable"))))
+}
+
+func TestOrderTLSCertificates(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+	rsaCert := tls.Certificate{PrivateKey: rsaKey}
+	ecCert := tls.Certificate{PrivateKey: ecKey}
+
+	certs, err := orderTLSCertificates(rsaCert, ecCert)
+	assert.NoError(t, err)
+	assert.Equal(t, ecKey, certs[0].PrivateKey)
+	_, err = orderTLSCertificates(rsaCert, rsaCert)
+	assert.Error(t, err)
+}
+
+func TestAPIRoutersAdmitVerificationsOnly(t *testing.T) {
+	admission := resource.NewAdmissionController(1, 0, time.Minute)
+	r := mux.NewRouter()
+	verification, other := apiRouters(r, "/svs/v1/", nil, admission.Middleware(), nil)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	verification.HandleFunc("/sgx_qv_verify_quote", func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}).Methods(http.MethodPost)
+	other.HandleFunc("/verifications", func(w http.ResponseWriter, r *http.Request) {}).Methods(http.MethodGet)
+
+	done := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/svs/v1/sgx_qv_verify_quote", nil))
+		done <- rec.Code
+	}()
+	<-started
+
+	// the queue is full: verifications are shed, the history still answers
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/svs/v1/sgx_qv_verify_quote", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/svs/v1/verifications", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-done)
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package signingkey
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"encoding/asn1"
+	"math/big"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// JWS algorithms signed results can be signed with
+const (
+	AlgorithmRS384 = "RS384"
+	AlgorithmPS384 = "PS384"
+	AlgorithmES384 = "ES384"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// Algorithms lists the supported result signing algorithms
+var Algorithms = []string{AlgorithmRS384, AlgorithmPS384, AlgorithmES384, AlgorithmEdDSA}
+
+// IsSupportedAlgorithm reports whether results can be signed with the JWS algorithm alg
+func IsSupportedAlgorithm(alg string) bool {
+	for _, supported := range Algorithms {
+		if alg == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// AlgorithmKey is a signing key dedicated to an algorithm, the key KeyID of the key store whose certificate
+// chain is stored in CertFile
+type AlgorithmKey struct {
+	Algorithm string
+	KeyID     string
+	CertFile  string
+}
+
+// ParseAlgorithmKey parses an <algorithm>=<key ID>:<certificate file> entry, the key ID being a private key
+// file with the file key store
+func ParseAlgorithmKey(entry string) (AlgorithmKey, error) {
+	parts := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+	if len(parts) != 2 || !IsSupportedAlgorithm(parts[0]) {
+		return AlgorithmKey{}, errors.Errorf("signingkey/algorithm:ParseAlgorithmKey() Invalid signing key %q, "+
+			"must be <algorithm>=<key ID>:<certificate file> with an algorithm of %s", entry,
+			strings.Join(Algorithms, ", "))
+	}
+	sep := strings.LastIndex(parts[1], ":")
+	if sep <= 0 || sep == len(parts[1])-1 {
+		return AlgorithmKey{}, errors.Errorf("signingkey/algorithm:ParseAlgorithmKey() Invalid signing key %q, "+
+			"must be <algorithm>=<key ID>:<certificate file>", entry)
+	}
+	return AlgorithmKey{Algorithm: parts[0], KeyID: parts[1][:sep], CertFile: parts[1][sep+1:]}, nil
+}
+
+// Supports reports whether the key can sign with the JWS algorithm alg: RSA keys sign with RS384 and PS384,
+// P-384 ECDSA keys with ES384 and Ed25519 keys with EdDSA
+func (k *Key) Supports(alg string) bool {
+	switch pub := k.Certificate.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return alg == AlgorithmRS384 || alg == AlgorithmPS384
+	case *ecdsa.PublicKey:
+		return alg == AlgorithmES384 && pub.Curve == elliptic.P384()
+	case ed25519.PublicKey:
+		return alg == AlgorithmEdDSA
+	}
+	return false
+}
+
+// Sign signs data with the JWS algorithm alg, the signature is encoded as JWS signatures are, ECDSA
+// signatures being the concatenation of R and S
+func (k *Key) Sign(alg string, data []byte) ([]byte, error) {
+	if k.Signer == nil || !k.Supports(alg) {
+		return nil, errors.Errorf("signingkey/algorithm:Sign() Key %s cannot sign with %s", k.ID, alg)
+	}
+	if alg == AlgorithmEdDSA {
+		signature, err := k.Signer.Sign(rand.Reader, data, crypto.Hash(0))
+		return signature, errors.Wrap(err, "signingkey/algorithm:Sign() Error signing with EdDSA")
+	}
+
+	digest := sha512.Sum384(data)
+	var opts crypto.SignerOpts = crypto.SHA384
+	if alg == AlgorithmPS384 {
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA384}
+	}
+	signature, err := k.Signer.Sign(rand.Reader, digest[:], opts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "signingkey/algorithm:Sign() Error signing with %s", alg)
+	}
+	if alg != AlgorithmES384 {
+		return signature, nil
+	}
+	var sig struct {
+		R, S *big.Int
+	}
+	_, err = asn1.Unmarshal(signature, &sig)
+	if err != nil {
+		return nil, errors.Wrap(err, "signingkey/algorithm:Sign() Invalid ECDSA signature")
+	}
+	size := (elliptic.P384().Params().BitSize + 7) / 8
+	return append(sig.R.FillBytes(make([]byte, size)), sig.S.FillBytes(make([]byte, size))...), nil
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package signingkey
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testAlgorithmKey(t *testing.T, signer crypto.Signer) *Key {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "SQVS Signing Certificate"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, signer.Public(), signer)
+	assert.NoError(t, err)
+	key, err := newKey(signer, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	assert.NoError(t, err)
+	return key
+}
+
+func TestParseAlgorithmKey(t *testing.T) {
+	key, err := ParseAlgorithmKey("ES384=/etc/sqvs/es384.key:/etc/sqvs/es384.pem")
+	assert.NoError(t, err)
+	assert.Equal(t, AlgorithmKey{Algorithm: AlgorithmES384, KeyID: "/etc/sqvs/es384.key",
+		CertFile: "/etc/sqvs/es384.pem"}, key)
+
+	for _, entry := range []string{"HS256=key:cert.pem", "ES384", "ES384=key", "ES384=key:", "EdDSA=:cert.pem"} {
+		_, err = ParseAlgorithmKey(entry)
+		assert.Error(t, err, entry)
+	}
+}
+
+func TestKeySign(t *testing.T) {
+	data := []byte("signing input")
+	digest := sha512.Sum384(data)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+	key := testAlgorithmKey(t, ecKey)
+	assert.False(t, key.Supports(AlgorithmRS384))
+	signature, err := key.Sign(AlgorithmES384, data)
+	assert.NoError(t, err)
+	assert.Len(t, signature, 96)
+	assert.True(t, ecdsa.Verify(&ecKey.PublicKey, digest[:], new(big.Int).SetBytes(signature[:48]),
+		new(big.Int).SetBytes(signature[48:])))
+	jwk, err := key.JWK(AlgorithmRS384)
+	assert.NoError(t, err)
+	assert.Equal(t, AlgorithmES384, jwk.Algorithm)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	key = testAlgorithmKey(t, edKey)
+	signature, err = key.Sign(AlgorithmEdDSA, data)
+	assert.NoError(t, err)
+	assert.True(t, ed25519.Verify(edKey.Public().(ed25519.PublicKey), data, signature))
+	jwk, err = key.JWK(AlgorithmRS384)
+	assert.NoError(t, err)
+	assert.Equal(t, "OKP", jwk.KeyType)
+	assert.Equal(t, AlgorithmEdDSA, jwk.Algorithm)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	key = testAlgorithmKey(t, rsaKey)
+	signature, err = key.Sign(AlgorithmPS384, data)
+	assert.NoError(t, err)
+	assert.NoError(t, rsa.VerifyPSS(&rsaKey.PublicKey, crypto.SHA384, digest[:], signature,
+		&rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}))
+	_, err = key.Sign(AlgorithmES384, data)
+	assert.Error(t, err)
+}
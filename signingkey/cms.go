@@ -0,0 +1,67 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package signingkey
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"intel/isecl/sqvs/v4/truststore"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// RequestCertificate gets a CMS signing certificate for the key. The certificate signing request is signed
+// by signer so the private key never leaves the key store holding it.
+func RequestCertificate(cmsBaseURL string, signer crypto.Signer, commonName, bearerToken, caCertsDir string) ([]byte, error) {
+	log.Trace("signingkey/cms:RequestCertificate() Entering")
+	defer log.Trace("signingkey/cms:RequestCertificate() Leaving")
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:            pkix.Name{CommonName: commonName},
+		SignatureAlgorithm: x509.SHA384WithRSA,
+	}, signer)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating certificate signing request")
+	}
+
+	client, err := truststore.HTTPClient(caCertsDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error in getting client object")
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(cmsBaseURL, "/")+"/certificates?certType=Signing",
+		bytes.NewReader(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr})))
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating CMS certificate request")
+	}
+	req.Header.Set("Accept", "application/x-pem-file")
+	req.Header.Set("Content-Type", "application/x-pem-file")
+	req.Header.Set("Authorization", "Bearer "+bearerToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error requesting certificate from CMS")
+	}
+	defer func() {
+		derr := resp.Body.Close()
+		if derr != nil {
+			log.WithError(derr).Error("Error closing CMS response")
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("CMS returned status code %d", resp.StatusCode)
+	}
+	cert, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error reading certificate from CMS response")
+	}
+	return cert, nil
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package signingkey
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// JWK is the RFC 7517 JSON Web Key of the public key of a signing key
+type JWK struct {
+	KeyType   string   `json:"kty"`
+	KeyID     string   `json:"kid"`
+	Use       string   `json:"use"`
+	Algorithm string   `json:"alg,omitempty"`
+	N         string   `json:"n,omitempty"`
+	E         string   `json:"e,omitempty"`
+	Curve     string   `json:"crv,omitempty"`
+	X         string   `json:"x,omitempty"`
+	Y         string   `json:"y,omitempty"`
+	X5c       []string `json:"x5c,omitempty"`
+}
+
+// JWKSet is the RFC 7517 JSON Web Key Set of the signing keys
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK returns the JSON Web Key of the key, rsaAlgorithm is the algorithm advertised for RSA keys. The
+// certificate chain of the key is included in x5c.
+func (k *Key) JWK(rsaAlgorithm string) (JWK, error) {
+	jwk := JWK{KeyID: k.ID, Use: "sig"}
+	switch pub := k.Certificate.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.Algorithm = rsaAlgorithm
+		jwk.N = base64URL(pub.N.Bytes())
+		jwk.E = base64URL(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.KeyType = "EC"
+		jwk.Curve = pub.Curve.Params().Name
+		jwk.X = base64URL(pub.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64URL(pub.Y.FillBytes(make([]byte, size)))
+		switch jwk.Curve {
+		case "P-256":
+			jwk.Algorithm = "ES256"
+		case "P-384":
+			jwk.Algorithm = "ES384"
+		case "P-521":
+			jwk.Algorithm = "ES512"
+		}
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Algorithm = AlgorithmEdDSA
+		jwk.Curve = "Ed25519"
+		jwk.X = base64URL(pub)
+	default:
+		return JWK{}, errors.Errorf("signingkey/jwk:JWK() Unsupported public key type of key %s", k.ID)
+	}
+
+	rest := k.CertChain
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			jwk.X5c = append(jwk.X5c, base64.StdEncoding.EncodeToString(block.Bytes))
+		}
+	}
+	return jwk, nil
+}
+
+// JWKSet returns the JSON Web Key Set of the current key and of the retired keys still within their
+// overlap window
+func (r *Ring) JWKSet(rsaAlgorithm string) JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range r.Keys() {
+		jwk, err := key.JWK(rsaAlgorithm)
+		if err != nil {
+			log.WithError(err).Warn("signingkey/jwk:JWKSet() Signing key is not published")
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func base64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,305 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package signingkey
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"intel/isecl/lib/common/v4/crypt"
+	commLog "intel/isecl/lib/common/v4/log"
+	"intel/isecl/sqvs/v4/keystore"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var log = commLog.GetDefaultLogger()
+
+// ErrRotationNotSupported is returned when the signing key is held in a key store SQVS cannot create keys in
+var ErrRotationNotSupported = errors.New("signing key rotation is only supported with the file key store")
+
+// Key is a response signing key. Retired keys only keep their certificate, responses are no longer signed
+// with them but relying parties can still verify the responses signed before the rotation.
+type Key struct {
+	ID          string
+	Certificate *x509.Certificate
+	CertChain   []byte
+	Signer      crypto.Signer
+	RetiredTime time.Time
+}
+
+// KeyID returns the identifier of the key of the certificate, the base64url encoded SHA-256 digest of its
+// subject public key info
+func KeyID(cert *x509.Certificate) string {
+	digest := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+// RotateOptions holds what is needed to get the certificate of a new signing key from CMS
+type RotateOptions struct {
+	CMSBaseURL  string
+	BearerToken string
+	CommonName  string
+	KeyLength   int
+	CACertsDir  string
+}
+
+// Ring holds the current response signing key and the keys retired less than overlap ago. Certificates of
+// retired keys are kept in retiredDir so the overlap window survives restarts.
+type Ring struct {
+	keyStore   keystore.KeyStore
+	keyID      string
+	certFile   string
+	retiredDir string
+	overlap    time.Duration
+	rotatable  bool
+
+	rotateMu sync.Mutex
+	mu       sync.RWMutex
+	current  *Key
+	retired  []*Key
+}
+
+// NewRing creates the key ring of the signing key keyID of the key store, whose certificate chain is stored
+// in certFile. Keys can only be rotated when rotatable is set, the key ID is then the private key file.
+func NewRing(ks keystore.KeyStore, keyID, certFile, retiredDir string, overlap time.Duration, rotatable bool) *Ring {
+	r := &Ring{
+		keyStore:   ks,
+		keyID:      keyID,
+		certFile:   certFile,
+		retiredDir: retiredDir,
+		overlap:    overlap,
+		rotatable:  rotatable,
+	}
+	r.loadRetired()
+	return r
+}
+
+// Current returns the key responses are signed with, loading it from the key store on first use
+func (r *Ring) Current() (*Key, error) {
+	r.mu.RLock()
+	current := r.current
+	r.mu.RUnlock()
+	if current != nil {
+		return current, nil
+	}
+
+	signer, err := r.keyStore.Signer(r.keyID)
+	if err != nil {
+		return nil, errors.Wrap(err, "signingkey/signingkey:Current() Error loading response signing key")
+	}
+	certChain, err := ioutil.ReadFile(r.certFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "signingkey/signingkey:Current() Error reading signing certificate")
+	}
+	key, err := newKey(signer, certChain)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.current == nil {
+		r.current = key
+	}
+	return r.current, nil
+}
+
+// Keys returns the current key followed by the retired keys still within the overlap window
+func (r *Ring) Keys() []*Key {
+	var keys []*Key
+	current, err := r.Current()
+	if err != nil {
+		log.WithError(err).Warn("signingkey/signingkey:Keys() Current signing key is not available")
+	} else {
+		keys = append(keys, current)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pruneRetired(time.Now())
+	return append(keys, r.retired...)
+}
+
+// Overlap returns the time retired keys remain available for verification
+func (r *Ring) Overlap() time.Duration {
+	return r.overlap
+}
+
+// Rotate generates a new signing key, gets it certified by CMS and makes it the current key. The previous
+// key is retired and remains available for verification during the overlap window.
+func (r *Ring) Rotate(opts RotateOptions) (*Key, *Key, error) {
+	log.Trace("signingkey/signingkey:Rotate() Entering")
+	defer log.Trace("signingkey/signingkey:Rotate() Leaving")
+
+	if !r.rotatable {
+		return nil, nil, ErrRotationNotSupported
+	}
+	r.rotateMu.Lock()
+	defer r.rotateMu.Unlock()
+
+	previous, err := r.Current()
+	if err != nil {
+		log.WithError(err).Warn("signingkey/signingkey:Rotate() Previous signing key could not be loaded, it will not be retired")
+		previous = nil
+	}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, opts.KeyLength)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "signingkey/signingkey:Rotate() Error generating signing key")
+	}
+	certChain, err := RequestCertificate(opts.CMSBaseURL, privateKey, opts.CommonName, opts.BearerToken, opts.CACertsDir)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "signingkey/signingkey:Rotate() Error getting signing certificate from CMS")
+	}
+	key, err := newKey(privateKey, certChain)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyDer, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "signingkey/signingkey:Rotate() Error encoding signing key")
+	}
+	now := time.Now()
+	if previous != nil {
+		err = r.saveRetired(previous, now)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	err = crypt.SavePrivateKeyAsPKCS8(keyDer, r.keyID)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "signingkey/signingkey:Rotate() Error storing signing key")
+	}
+	err = ioutil.WriteFile(r.certFile, certChain, 0644)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "signingkey/signingkey:Rotate() Error storing signing certificate")
+	}
+
+	retired := r.rotateTo(key, previous, now)
+	log.Infof("signingkey/signingkey:Rotate() Signing key rotated to %s", key.ID)
+	return key, retired, nil
+}
+
+// rotateTo makes key the current key and retires the previous one
+func (r *Ring) rotateTo(key, previous *Key, now time.Time) *Key {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.current = key
+	r.pruneRetired(now)
+	if previous == nil {
+		return nil
+	}
+	retired := &Key{ID: previous.ID, Certificate: previous.Certificate, CertChain: previous.CertChain, RetiredTime: now}
+	r.retired = append([]*Key{retired}, r.retired...)
+	return retired
+}
+
+// pruneRetired drops the retired keys whose overlap window has ended, callers hold mu
+func (r *Ring) pruneRetired(now time.Time) {
+	kept := r.retired[:0]
+	for _, key := range r.retired {
+		if now.Sub(key.RetiredTime) < r.overlap {
+			kept = append(kept, key)
+			continue
+		}
+		log.Infof("signingkey/signingkey:pruneRetired() Overlap window of signing key %s ended", key.ID)
+		if r.retiredDir != "" {
+			err := os.Remove(r.retiredFile(key))
+			if err != nil && !os.IsNotExist(err) {
+				log.WithError(err).Warn("signingkey/signingkey:pruneRetired() Error removing retired signing certificate")
+			}
+		}
+	}
+	r.retired = kept
+}
+
+// saveRetired stores the certificate of a retired key, the file name holds the retirement time
+func (r *Ring) saveRetired(key *Key, retiredTime time.Time) error {
+	if r.retiredDir == "" {
+		return nil
+	}
+	err := os.MkdirAll(r.retiredDir, 0755)
+	if err != nil {
+		return errors.Wrap(err, "signingkey/signingkey:saveRetired() Error creating retired signing certificates directory")
+	}
+	retired := *key
+	retired.RetiredTime = retiredTime
+	err = ioutil.WriteFile(r.retiredFile(&retired), key.CertChain, 0644)
+	if err != nil {
+		return errors.Wrap(err, "signingkey/signingkey:saveRetired() Error storing retired signing certificate")
+	}
+	return nil
+}
+
+func (r *Ring) retiredFile(key *Key) string {
+	return filepath.Join(r.retiredDir, fmt.Sprintf("%d-%s.pem", key.RetiredTime.Unix(), key.ID))
+}
+
+// loadRetired reads the certificates of the keys retired by earlier rotations
+func (r *Ring) loadRetired() {
+	if r.retiredDir == "" {
+		return
+	}
+	files, err := ioutil.ReadDir(r.retiredDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.WithError(err).Warn("signingkey/signingkey:loadRetired() Error reading retired signing certificates")
+		}
+		return
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(file.Name(), ".pem")
+		parts := strings.SplitN(name, "-", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		retiredUnix, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		certChain, err := ioutil.ReadFile(filepath.Join(r.retiredDir, file.Name()))
+		if err != nil {
+			log.WithError(err).Warnf("signingkey/signingkey:loadRetired() Error reading %s", file.Name())
+			continue
+		}
+		key, err := newKey(nil, certChain)
+		if err != nil {
+			log.WithError(err).Warnf("signingkey/signingkey:loadRetired() Invalid certificate in %s", file.Name())
+			continue
+		}
+		key.RetiredTime = time.Unix(retiredUnix, 0)
+		r.retired = append(r.retired, key)
+	}
+	sort.Slice(r.retired, func(i, j int) bool { return r.retired[i].RetiredTime.After(r.retired[j].RetiredTime) })
+	r.pruneRetired(time.Now())
+}
+
+func newKey(signer crypto.Signer, certChain []byte) (*Key, error) {
+	block, _ := pem.Decode(certChain)
+	if block == nil {
+		return nil, errors.New("signingkey/signingkey:newKey() Failed to decode signing certificate PEM")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "signingkey/signingkey:newKey() Error parsing signing certificate")
+	}
+	return &Key{ID: KeyID(cert), Certificate: cert, CertChain: certChain, Signer: signer}, nil
+}
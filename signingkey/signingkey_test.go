@@ -0,0 +1,113 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package signingkey
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"intel/isecl/sqvs/v4/keystore"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTestKey(t *testing.T, dir string) (string, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "SQVS Signing Certificate"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+
+	keyFile := filepath.Join(dir, "signing.key")
+	certFile := filepath.Join(dir, "signing.pem")
+	assert.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer}), 0600))
+	assert.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	return keyFile, certFile
+}
+
+func TestRingRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signingkey")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	retiredDir := filepath.Join(dir, "retired")
+
+	keyFile, certFile := writeTestKey(t, dir)
+	ring := NewRing(keystore.FileKeyStore{}, keyFile, certFile, retiredDir, time.Hour, true)
+	previous, err := ring.Current()
+	assert.NoError(t, err)
+	assert.Equal(t, KeyID(previous.Certificate), previous.ID)
+	assert.NotNil(t, previous.Signer)
+
+	keyFile, certFile = writeTestKey(t, dir)
+	ring2 := NewRing(keystore.FileKeyStore{}, keyFile, certFile, retiredDir, time.Hour, true)
+	key, err := ring2.Current()
+	assert.NoError(t, err)
+
+	retiredTime := time.Now().Add(-30 * time.Minute)
+	assert.NoError(t, ring.saveRetired(previous, retiredTime))
+	retired := ring.rotateTo(key, previous, retiredTime)
+	assert.Nil(t, retired.Signer)
+
+	keys := ring.Keys()
+	assert.Len(t, keys, 2)
+	assert.Equal(t, key.ID, keys[0].ID)
+	assert.Equal(t, previous.ID, keys[1].ID)
+
+	// the retired key is reloaded after a restart until its overlap window ends
+	restarted := NewRing(keystore.FileKeyStore{}, keyFile, certFile, retiredDir, time.Hour, true)
+	assert.Len(t, restarted.Keys(), 2)
+	restarted = NewRing(keystore.FileKeyStore{}, keyFile, certFile, retiredDir, 10*time.Minute, true)
+	assert.Len(t, restarted.Keys(), 1)
+	files, err := ioutil.ReadDir(retiredDir)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+
+	_, _, err = NewRing(keystore.FileKeyStore{}, keyFile, certFile, "", time.Hour, false).Rotate(RotateOptions{})
+	assert.Equal(t, ErrRotationNotSupported, err)
+}
+
+func TestRingJWKSet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signingkey")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	keyFile, certFile := writeTestKey(t, dir)
+	ring := NewRing(keystore.FileKeyStore{}, keyFile, certFile, "", time.Hour, true)
+	key, err := ring.Current()
+	assert.NoError(t, err)
+
+	set := ring.JWKSet("PS384")
+	assert.Len(t, set.Keys, 1)
+	jwk := set.Keys[0]
+	assert.Equal(t, "RSA", jwk.KeyType)
+	assert.Equal(t, key.ID, jwk.KeyID)
+	assert.Equal(t, "PS384", jwk.Algorithm)
+	assert.Equal(t, "AQAB", jwk.E)
+	assert.Len(t, jwk.X5c, 1)
+
+	der, err := base64.StdEncoding.DecodeString(jwk.X5c[0])
+	assert.NoError(t, err)
+	assert.Equal(t, key.Certificate.Raw, der)
+
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	assert.NoError(t, err)
+	assert.Equal(t, key.Certificate.PublicKey.(*rsa.PublicKey).N.Bytes(), n)
+}
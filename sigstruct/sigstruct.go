@@ -0,0 +1,171 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package sigstruct
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"debug/elf"
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// Size is the size of a SIGSTRUCT, the enclave signature structure written by sgx_sign
+const Size = 1808
+
+const (
+	modulusSize = 384
+
+	offsetVendor        = 16
+	offsetDate          = 20
+	offsetModulus       = 128
+	offsetExponent      = 512
+	offsetSignature     = 516
+	offsetMiscSelect    = 900
+	offsetMiscMask      = 904
+	offsetAttributes    = 928
+	offsetAttributeMask = 944
+	offsetEnclaveHash   = 960
+	offsetIsvProdID     = 1024
+	offsetIsvSvn        = 1026
+
+	// the signature covers the header, up to the modulus, and the body, from MISCSELECT to ISVSVN
+	headerEnd = 128
+	bodyStart = 900
+	bodyEnd   = 1028
+
+	// metadataSection is the ELF section of signed enclaves holding the enclave metadata, whose SIGSTRUCT
+	// follows metadataCssOffset bytes after the metadata magic
+	metadataSection   = ".note.sgxmeta"
+	metadataCssOffset = 64
+)
+
+var (
+	header1       = []byte{0x06, 0x00, 0x00, 0x00, 0xe1, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00}
+	header2       = []byte{0x01, 0x01, 0x00, 0x00, 0x60, 0x00, 0x00, 0x00, 0x60, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00}
+	metadataMagic = []byte{0x4c, 0x0e, 0x5d, 0x63, 0x94, 0x02, 0xa8, 0x86}
+)
+
+// SigStruct is the enclave identity signed by a SIGSTRUCT. Measurements and attributes are hex encoded the
+// way they are returned by quote verifications.
+type SigStruct struct {
+	MrEnclave      string `json:"mrEnclave"`
+	MrSigner       string `json:"mrSigner"`
+	IsvProdID      uint16 `json:"isvProdId"`
+	IsvSvn         uint16 `json:"isvSvn"`
+	Attributes     string `json:"attributes"`
+	AttributesMask string `json:"attributesMask"`
+	MiscSelect     uint32 `json:"miscSelect"`
+	MiscMask       uint32 `json:"miscMask"`
+	Date           string `json:"date"`
+	IntelSigned    bool   `json:"intelSigned"`
+}
+
+// Parse reads the SIGSTRUCT of an enclave and verifies its signature. data is either a SIGSTRUCT, as dumped
+// by sgx_sign dump -cssfile, or a signed enclave binary.
+func Parse(data []byte) (*SigStruct, error) {
+	if bytes.HasPrefix(data, []byte(elf.ELFMAG)) {
+		css, err := fromEnclave(data)
+		if err != nil {
+			return nil, err
+		}
+		data = css
+	}
+	if len(data) != Size {
+		return nil, errors.Errorf("sigstruct/sigstruct:Parse() SIGSTRUCT must be %d bytes, got %d", Size, len(data))
+	}
+	if !bytes.Equal(data[:16], header1) || !bytes.Equal(data[24:40], header2) {
+		return nil, errors.New("sigstruct/sigstruct:Parse() Invalid SIGSTRUCT header")
+	}
+	err := verify(data)
+	if err != nil {
+		return nil, err
+	}
+
+	mrSigner := sha256.Sum256(data[offsetModulus : offsetModulus+modulusSize])
+	return &SigStruct{
+		MrEnclave:      hex.EncodeToString(data[offsetEnclaveHash : offsetEnclaveHash+32]),
+		MrSigner:       hex.EncodeToString(mrSigner[:]),
+		IsvProdID:      binary.LittleEndian.Uint16(data[offsetIsvProdID:]),
+		IsvSvn:         binary.LittleEndian.Uint16(data[offsetIsvSvn:]),
+		Attributes:     hex.EncodeToString(data[offsetAttributes : offsetAttributes+16]),
+		AttributesMask: hex.EncodeToString(data[offsetAttributeMask : offsetAttributeMask+16]),
+		MiscSelect:     binary.LittleEndian.Uint32(data[offsetMiscSelect:]),
+		MiscMask:       binary.LittleEndian.Uint32(data[offsetMiscMask:]),
+		// the date is BCD encoded, 0x20210714 for July 14, 2021
+		Date:        formatDate(binary.LittleEndian.Uint32(data[offsetDate:])),
+		IntelSigned: binary.LittleEndian.Uint32(data[offsetVendor:]) == 0x8086,
+	}, nil
+}
+
+// ParseFile reads the SIGSTRUCT of a SIGSTRUCT file or a signed enclave binary
+func ParseFile(file string) (*SigStruct, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "sigstruct/sigstruct:ParseFile() Error reading SIGSTRUCT")
+	}
+	s, err := Parse(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "sigstruct/sigstruct:ParseFile() Invalid SIGSTRUCT in %s", file)
+	}
+	return s, nil
+}
+
+// fromEnclave returns the SIGSTRUCT embedded in the metadata of a signed enclave binary
+func fromEnclave(data []byte) ([]byte, error) {
+	f, err := elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "sigstruct/sigstruct:fromEnclave() Invalid enclave binary")
+	}
+	section := f.Section(metadataSection)
+	if section == nil {
+		return nil, errors.New("sigstruct/sigstruct:fromEnclave() Enclave binary is not signed, it has no " +
+			metadataSection + " section")
+	}
+	metadata, err := section.Data()
+	if err != nil {
+		return nil, errors.Wrap(err, "sigstruct/sigstruct:fromEnclave() Error reading enclave metadata")
+	}
+	start := bytes.Index(metadata, metadataMagic)
+	if start < 0 || len(metadata) < start+metadataCssOffset+Size {
+		return nil, errors.New("sigstruct/sigstruct:fromEnclave() Invalid enclave metadata")
+	}
+	start += metadataCssOffset
+	return metadata[start : start+Size], nil
+}
+
+// verify checks the RSA-3072 signature of the SIGSTRUCT, whose modulus and signature are little endian
+func verify(data []byte) error {
+	if binary.LittleEndian.Uint32(data[offsetExponent:]) != 3 {
+		return errors.New("sigstruct/sigstruct:verify() SIGSTRUCT exponent must be 3")
+	}
+	key := &rsa.PublicKey{N: new(big.Int).SetBytes(reverse(data[offsetModulus : offsetModulus+modulusSize])), E: 3}
+	signed := append(append([]byte{}, data[:headerEnd]...), data[bodyStart:bodyEnd]...)
+	digest := sha256.Sum256(signed)
+	err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], reverse(data[offsetSignature:offsetSignature+modulusSize]))
+	if err != nil {
+		return errors.Wrap(err, "sigstruct/sigstruct:verify() Invalid SIGSTRUCT signature")
+	}
+	return nil
+}
+
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
+
+func formatDate(bcd uint32) string {
+	digits := hex.EncodeToString([]byte{byte(bcd >> 24), byte(bcd >> 16), byte(bcd >> 8), byte(bcd)})
+	return digits[:4] + "-" + digits[4:6] + "-" + digits[6:]
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package sigstruct
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testSigningKey generates the RSA-3072 key with public exponent 3 SIGSTRUCTs are signed with
+func testSigningKey(t *testing.T) *rsa.PrivateKey {
+	three := big.NewInt(3)
+	prime := func() *big.Int {
+		for {
+			p, err := rand.Prime(rand.Reader, modulusSize*4)
+			assert.NoError(t, err)
+			if new(big.Int).Mod(p, three).Int64() == 2 {
+				return p
+			}
+		}
+	}
+	p, q := prime(), prime()
+	one := big.NewInt(1)
+	phi := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+	key := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{N: new(big.Int).Mul(p, q), E: 3},
+		D:         new(big.Int).ModInverse(three, phi),
+		Primes:    []*big.Int{p, q},
+	}
+	key.Precompute()
+	assert.NoError(t, key.Validate())
+	return key
+}
+
+func testSigStruct(t *testing.T, key *rsa.PrivateKey) []byte {
+	data := make([]byte, Size)
+	copy(data, header1)
+	binary.LittleEndian.PutUint32(data[offsetVendor:], 0)
+	binary.LittleEndian.PutUint32(data[offsetDate:], 0x20210714)
+	copy(data[24:], header2)
+	copy(data[offsetModulus:], reverse(key.N.FillBytes(make([]byte, modulusSize))))
+	binary.LittleEndian.PutUint32(data[offsetExponent:], 3)
+	data[offsetAttributes] = 0x04
+	data[offsetAttributes+8] = 0x03
+	copy(data[offsetAttributeMask:], []byte{0xfd, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	copy(data[offsetEnclaveHash:], []byte(strings.Repeat("\xab", 32)))
+	binary.LittleEndian.PutUint16(data[offsetIsvProdID:], 7)
+	binary.LittleEndian.PutUint16(data[offsetIsvSvn:], 2)
+
+	digest := sha256.Sum256(append(append([]byte{}, data[:headerEnd]...), data[bodyStart:bodyEnd]...))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	assert.NoError(t, err)
+	copy(data[offsetSignature:], reverse(signature))
+	return data
+}
+
+func TestParse(t *testing.T) {
+	key := testSigningKey(t)
+	data := testSigStruct(t, key)
+	s, err := Parse(data)
+	assert.NoError(t, err)
+	mrSigner := sha256.Sum256(reverse(key.N.FillBytes(make([]byte, modulusSize))))
+	assert.Equal(t, strings.Repeat("ab", 32), s.MrEnclave)
+	assert.Equal(t, hex.EncodeToString(mrSigner[:]), s.MrSigner)
+	assert.Equal(t, uint16(7), s.IsvProdID)
+	assert.Equal(t, uint16(2), s.IsvSvn)
+	assert.Equal(t, "04000000000000000300000000000000", s.Attributes)
+	assert.Equal(t, "fdffffffffffffff0000000000000000", s.AttributesMask)
+	assert.Equal(t, "2021-07-14", s.Date)
+	assert.False(t, s.IntelSigned)
+
+	// the ISVSVN is signed
+	tampered := append([]byte{}, data...)
+	tampered[offsetIsvSvn] = 3
+	_, err = Parse(tampered)
+	assert.Error(t, err)
+
+	_, err = Parse(data[:Size-1])
+	assert.Error(t, err)
+	tampered = append([]byte{}, data...)
+	tampered[0] = 0
+	_, err = Parse(tampered)
+	assert.Error(t, err)
+}
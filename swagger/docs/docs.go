@@ -5,6 +5,16 @@
 // SQVS contacts SGX Caching service (SCS) to make sure that PCKCRL, TCBInfo, and QEIdentity in the quote are correct.
 // SQVS listening port is user-configurable.
 //
+// Error responses are RFC 7807 problem details of media type application/problem+json. The "type" of a problem
+// is the URN of its category, urn:sqvs:problem: followed by invalid-request, unauthorized, forbidden, not-found,
+// method-not-allowed, conflict, verification-failed, quota-exceeded, overloaded, maintenance,
+// service-unavailable, dependency-unavailable, dependency-timeout or internal-error. The "instance" is logged
+// in the security log with the error and, for failed quote verifications, is the ID of the verification
+// recorded in the audit trail. "retryable" and "retryAfter" (seconds, also sent as Retry-After) hint whether
+// and when the request can be retried. Failed verifications carry "FailedStep", "VerificationSteps" and,
+// when sgx_qv_verify_quote would have returned one, the "QvResult" code, rejected requests over quota their
+// "tenant", "route", "period" and "limit".
+//
 //  License: Copyright (C) 2020 Intel Corporation. SPDX-License-Identifier: BSD-3-Clause
 //
 //  Version: 1.0
@@ -53,6 +63,20 @@ type SignedSGXResponseInfo struct {
 	Body resource.SignedSGXResponse
 }
 
+// ChainedReportData request payload
+// swagger:parameters ChainedReportData
+type ChainedReportDataInfo struct {
+	// in:body
+	Body resource.ChainedReportData
+}
+
+// ChainedSGXResponse response payload
+// swagger:response ChainedSGXResponse
+type ChainedSGXResponseInfo struct {
+	// in:body
+	Body resource.ChainedSGXResponse
+}
+
 // UnsignedSGXResponse response payload
 // swagger:response UnsignedSGXResponse
 type UnsignedSGXResponseInfo struct {
@@ -66,14 +90,105 @@ type UnsignedSGXResponseInfo struct {
 //   Verifies the SGX ECDSA quote provided in the request body.
 //   Quote verifier requests SGX Quote Verification Service (SQVS) to verify quote.
 //   SQVS parses the quote, verifies all the parameters in the quote and returns the response.
+//   The quote can be sent base64 encoded in a JSON body, as raw bytes with Content-Type
+//   application/octet-stream (userData passed as a query parameter), or as the "quote" file
+//   of a multipart/form-data upload (userData passed as a form field).
+//   The response carries the SHA-256 and SHA-384 digests of the decoded quote and of its enclave
+//   report body, so the result can be bound to the submitted quote.
+//   Optional constraints on the enclave report (expected mrEnclave and mrSigner, minimum isvSvn,
+//   exact isvProdId and expected hex prefix of the report data) are evaluated once the quote is
+//   verified and the outcome of each is returned in "Constraints". With application/octet-stream
+//   and multipart/form-data the constraints are passed as a JSON "constraints" query parameter or
+//   form field.
+//   An optional RFC 3339 "evaluationTime", not later than the current trusted time, validates the
+//   PCK certificate chain and its revocation as of that time, to re-evaluate a quote at the time it
+//   was produced. TCBInfo, QEIdentity and the CRLs are fetched from SCS when the quote is verified
+//   and are always checked against the current trusted time. The time used is returned in
+//   "EvaluationTime".
+//   With SQVS_ENABLE_COLLATERAL_HISTORY, the TCBInfo and QEIdentity versions quotes are verified with
+//   are kept, and an optional RFC 3339 "tcbEvaluationDate" or "collateralVersion" (a TCB evaluation
+//   data number) re-evaluates the quote against the recorded collateral that was current at that date or
+//   has that number, for dispute resolution long after the attestation. The date is also the default
+//   evaluationTime. The collateral used is returned in "TcbEvaluationDate" and "CollateralVersion",
+//   404 is returned when none was recorded. With application/octet-stream and multipart/form-data they
+//   are passed as query parameters or form fields.
+//   An optional "reportDataBinding" declares how the enclave built its report data from the inputs it
+//   binds: the "hash" (sha256, sha384, sha512 or none) of the concatenation of the base64 encoded
+//   "inputs", such as a nonce and a public key, placed at the start of the report data and followed
+//   by zeros when "zeroPadded" is set. SQVS checks the binding and returns the verdict in
+//   "ReportDataBinding". It is passed as a JSON query parameter or form field like the constraints.
+//   The SGX extensions of the PCK certificate (PPID, FMSPC, PCE ID, SGX type and TCB components) are
+//   strictly validated against the Intel SGX PCK certificate profile and returned in "PckExtensions".
+//   The PPID and platform instance ID, which map quotes to physical hosts, are returned only with the
+//   platform_identifiers=true query parameter, to administrators and holders of the PlatformIdentityReader
+//   role. Other results apply the SQVS_PLATFORM_IDENTIFIERS redaction profile to "PPID", "PckPpid" and
+//   "PlatformInstanceID" and report it in "PlatformIdentifiers": redacted omits them (the default),
+//   pseudonymized replaces them with the hex encoded SHA-256 digest of their hex encoding, full keeps them.
+//   Outside of the results, the PPID is pseudonymized in the "platformId" of tcb-status-downgraded events
+//   and in the logs unless the profile is full, in the platform TCB statuses SQVS stores whatever the
+//   profile, and redacted from payload captures.
+//   The "fmspcs" and "sgxTypes" constraints are allow-lists of the FMSPCs and SGX types of the
+//   platform. Quotes from platforms not allowed by SQVS_PCK_ALLOWED_FMSPCS or SQVS_PCK_ALLOWED_SGX_TYPES
+//   are rejected.
+//   The Key Separation and Sharing fields of enclaves launched with KSS (CONFIGID, CONFIGSVN, ISVEXTPRODID
+//   and ISVFAMILYID) are returned in "KSS". The "configId", "isvExtProdId" and "isvFamilyId" constraints
+//   match them exactly, hex encoded, and "minConfigSvn" is the lowest accepted CONFIGSVN, so a multi-tenant
+//   enclave isolating its tenants by CONFIGID can be bound to the one of a tenant.
+//   SQVS_TCB_STATUS_VERDICTS maps each TCB status of the platform to allow, warn or deny. Quotes with a
+//   denied status fail the tcb_evaluation step, those with a warned status are accepted and logged. The
+//   status, its verdict and whether it was configured are returned in "TcbStatusVerdict".
+//   "QvResult" is the sgx_ql_qv_result_t equivalent of the verification, the numeric "Code" and "Name" that
+//   sgx_qv_verify_quote of libsgx_dcap_quoteverify would return, such as 0 for SGX_QL_QV_RESULT_OK or 0xA007
+//   (40967) for SGX_QL_QV_RESULT_SW_HARDENING_NEEDED. Verifications failing on a denied TCB status or an
+//   invalid enclave or QE report signature carry it in the "QvResult" of their problem details.
+//   Certificate validity periods and TCBInfo and QEIdentity issue and next update dates that are missed
+//   by less than SQVS_CLOCK_SKEW_TOLERANCE_SECONDS still pass, the checks that needed the time to be
+//   skewed are returned in "ClockSkew" with the skew in seconds.
+//   "VerificationSteps" lists the quote_parse, pck_chain, crl_check, tcb_evaluation, qe_identity,
+//   quote_signature, qe_report_signature and policy steps in order, each passed, failed or skipped.
+//   Failed verifications return them in "VerificationSteps" along with the "FailedStep" in their
+//   problem details.
+//   The TCBInfo and QEIdentity signatures are verified against the Intel SGX TCB Signing certificate of
+//   their issuer chain, the signing certificates and the root CA public key hash are returned in
+//   "CollateralSigners". SQVS_SGX_ROOT_KEY_PINS pins the public key of the trusted Intel SGX root CA.
+//   With the debug=true query parameter, administrators and holders of the QuoteDiagnostics role get the
+//   time spent in each step, the sources and versions of the collateral and the cache hits in
+//   "Diagnostics", and in the "Diagnostics" of the problem details of failed verifications. The
+//   collateral and CRL fetches of a step are timed apart, with a "Phase" of collateral_fetch or crl_fetch.
+//   Collateral and CRL fetches exceeding SQVS_COLLATERAL_FETCH_TIMEOUT or SQVS_CRL_FETCH_TIMEOUT fail with
+//   504, verifications exceeding SQVS_VERIFICATION_COMPUTE_TIMEOUT outside of these fetches with 503.
+//   When SCS cannot be reached for fresh TCBInfo or QEIdentity, because the connection fails or times out,
+//   its circuit breaker is open or SCS answers 502, 503 or 504, SQVS_COLLATERAL_FAILURE_MODE decides:
+//   fail-closed (the default) fails the verification, use-cached verifies the quote against the last
+//   collateral SQVS verified up to SQVS_COLLATERAL_GRACE_PERIOD past its next update, and fail-open against
+//   it however old. Both modes fall back on the collateral verified by the SQVS process, kept in memory only:
+//   until it has verified the collateral of a platform since it started, even fail-open fails the
+//   verification. Invalid collateral and failures of the SQVS store always fail the verification.
+//   Results verified against cached collateral carry "CollateralFallback" with the "Mode", the cached
+//   "Collateral" and a "Warning", failures report the mode in their message.
+//   Relying parties registered with a public key in /etc/sqvs/certs/relying-parties/<id>.pem can sign the
+//   request body with a detached JWS (RS256, RS384, PS256, PS384, ES256 or ES384) whose kid is their ID,
+//   sent in the X-SQVS-Request-Signature header. The protected header must carry the Unix time "iat" the
+//   request was signed at, within SQVS_REQUEST_SIGNATURE_MAX_AGE (5 minutes by default) of the time of
+//   SQVS, and a "jti" unique to the relying party, of at most 128 characters, which SQVS records so the
+//   signature cannot be replayed. The relying party and its signature are recorded with the verification.
+//   Requests with an invalid, stale or replayed signature, and unsigned ones when
+//   SQVS_REQUIRE_SIGNED_REQUESTS is set, are rejected with 401. All the quote appraisal endpoints accept
+//   the signature.
 //
 // security:
 //  - bearerAuth: []
 // consumes:
 // - application/json
+// - application/octet-stream
+// - multipart/form-data
 // produces:
 // - application/json
 // parameters:
+// - name: X-SQVS-Request-Signature
+//   description: Detached JWS of the request body by a registered relying party.
+//   in: header
+//   type: string
 // - name: request body
 //   required: true
 //   in: body
@@ -100,7 +215,128 @@ type UnsignedSGXResponseInfo struct {
 //    "EnclaveMeasurement": "9270442d1bd1961fa39dbe1f2cdf4f87950a54fcaf9a2e5013875c3346542dca",
 //    "EnclaveIssuerProdID": "00",
 //    "IsvSvn": "01",
-//    "TcbLevel": "OutofDate"
+//    "TcbLevel": "OutofDate",
+//    "QvResult": {"Code": 40962, "Name": "SGX_QL_QV_RESULT_OUT_OF_DATE"},
+//    "TcbStatusVerdict": {"TcbStatus": "OutOfDate", "Verdict": "warn", "Configured": true},
+//    "EnclaveDebugMode": false,
+//    "SupplementalData": {
+//      "EarliestIssueDate": "2021-06-01T08:12:44Z",
+//      "LatestIssueDate": "2021-06-01T09:30:02Z",
+//      "EarliestExpirationDate": "2021-07-01T08:12:44Z",
+//      "TcbLevelDateTag": "2020-11-11T00:00:00Z",
+//      "PckCrlNum": 1,
+//      "TcbEvalRefNum": 10,
+//      "RootKeyID": "ed8c2a2b3f3c3c24e6cd2f5a3a2b3c3d6e0f88d1d0a11c4e6ba9d9b8d62d2b8b5f8b0a6e5d4e2c3b1a09f8e7d6c5b4a3",
+//      "PckPpid": "20afa3c8fecb47c0a2311e4cbc4b6dd8",
+//      "TcbCPUSvn": "02020000000000000000000000000000",
+//      "TcbPceIsvSvn": 10,
+//      "PceID": "0000",
+//      "SgxType": 0,
+//      "PckCrlSources": [
+//        {
+//          "CA": "processor",
+//          "Source": "scs",
+//          "URL": "https://scs.example.com:9000/scs/sgx/certification/v1/pckcrl?ca=processor",
+//          "NextUpdate": "2021-07-01T08:12:44Z"
+//        }
+//      ]
+//    },
+//    "PckExtensions": {
+//      "PPID": "20afa3c8fecb47c0a2311e4cbc4b6dd8",
+//      "FMSPC": "00606a000000",
+//      "PCEID": "0000",
+//      "SgxType": "Standard",
+//      "TcbCompSvns": [2, 2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0],
+//      "PceSvn": 10,
+//      "CPUSvn": "02020000000000000000000000000000"
+//    },
+//    "QuoteHashes": {
+//      "QuoteSHA256": "3c5e7b0f0b6f9f4d1c1fa1e4d0e1d4f6cf3f6a3bb2b0a0d9b0a7e4c6ad1b2f90",
+//      "QuoteSHA384": "5f1a0c6b9e3d2a8f07c4b1e0d9a8c7b6e5d4c3b2a1f0e9d8c7b6a5f4e3d2c1b0a9f8e7d6c5b4a3928170e6f5d4c3b2a1",
+//      "ReportBodySHA256": "7a4e1f0c3b2d5e6f8a9b0c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f",
+//      "ReportBodySHA384": "b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8"
+//    },
+//    "Constraints": {
+//      "Passed": true,
+//      "Results": [
+//        {
+//          "Name": "mrSigner",
+//          "Expected": "d412a4f07ef83892a5915fb2ab584be31e186e5a4f95ab5f6950fd4eb8694d7b",
+//          "Actual": "d412a4f07ef83892a5915fb2ab584be31e186e5a4f95ab5f6950fd4eb8694d7b",
+//          "Passed": true
+//        },
+//        {
+//          "Name": "minIsvSvn",
+//          "Expected": "1",
+//          "Actual": "1",
+//          "Passed": true
+//        }
+//      ]
+//    },
+//    "EvaluationTime": "2021-06-15T10:00:00Z"
+//  }
+// ---
+
+// swagger:operation POST /v1/sgx_verify_report Quote sgxVerifyChainedReport
+// ---
+// description: |
+//   Verifies an SGX REPORT produced for local attestation, chained to the quote of the enclave that
+//   verified it on the same platform. The verifying enclave's quote is verified as for
+//   /v1/sgx_qv_verify_quote and the first 32 bytes of its report data must be the SHA-256 of the raw
+//   SGX REPORT. The REPORT MAC cannot be checked remotely, so the verifying enclave is trusted to have
+//   checked it. Optionally, the MRSIGNER and MRENCLAVE of the verifying enclave can be pinned and the
+//   user data bound in the report data of the attested enclave can be checked.
+//
+// security:
+//  - bearerAuth: []
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// parameters:
+// - name: request body
+//   required: true
+//   in: body
+//   schema:
+//     "$ref": "#/definitions/ChainedReportData"
+// responses:
+//   '200':
+//     description: Successfully verified the verifying enclave quote and its binding to the SGX report.
+//     schema:
+//       "$ref": "#/definitions/ChainedSGXResponse"
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/sgx_verify_report
+// x-sample-call-input: |
+//  {
+//    "report": "<base64 encoded SGX REPORT>",
+//    "quote": "<base64 encoded quote of the verifying enclave>",
+//    "userData": "<base64 encoded user data>",
+//    "verifierMrSigner": "83d719e77deaca1470f6baf62a4d774303c899db69020f9c70ee1dfc08c7ce9e"
+//  }
+// x-sample-call-output: |
+//  {
+//    "Message": "SGX_QL_QV_RESULT_OK",
+//    "VerifyingEnclave": {
+//        "ReportData": "14f39d2b1dda32661b631c7cdeff10c5e9efe1370a209a845de34899641feff8",
+//        "UserDataMatch": "true",
+//        "Message": "SGX_QL_QV_RESULT_OK",
+//        "EnclaveIssuer": "83d719e77deaca1470f6baf62a4d774303c899db69020f9c70ee1dfc08c7ce9e",
+//        "EnclaveMeasurement": "ad46749ed41ebaa2327252041ee746d3791a9f2431830fee0883f7993caf316a",
+//        "EnclaveIssuerProdID": "00",
+//        "IsvSvn": "00",
+//        "TcbLevel": "OutOfDate",
+//        "EnclaveDebugMode": false
+//    },
+//    "ReportData": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+//    "UserDataMatch": "true",
+//    "EnclaveIssuer": "d412a4f07ef83892a5915fb2ab584be31e186e5a4f95ab5f6950fd4eb8694d7b",
+//    "EnclaveMeasurement": "9270442d1bd1961fa39dbe1f2cdf4f87950a54fcaf9a2e5013875c3346542dca",
+//    "EnclaveIssuerProdID": "00",
+//    "IsvSvn": "01",
+//    "ReportHashes": {
+//      "ReportBodySHA256": "0d6a2e9f4b1c8e3a7f5d2b9c6e1a4f8d3b7c0e5a9f2d6b1c4e8a3f7d0b5c9e2a",
+//      "ReportBodySHA384": "e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b7c6d5"
+//    }
 //  }
 // ---
 
@@ -111,11 +347,42 @@ type UnsignedSGXResponseInfo struct {
 //   Quote verifier requests SGX Quote Verification Service (SQVS) to verify quote.
 //   SQVS parses the quote, verifies all the parameters in the quote and returns the response.
 //   It signs the quote verification response in case it is configured to do so.
+//   The quote can be sent base64 encoded in a JSON body, as raw bytes with Content-Type
+//   application/octet-stream (userData, challenge and nonce passed as query parameters), or as the
+//   "quote" file of a multipart/form-data upload (userData, challenge and nonce passed as form fields).
+//   Signed responses carry the "keyId" of the signing key, published at /v1/.well-known/jwks.json, and
+//   the signed quoteData the "IssuedAt" time results are revoked by.
+//   The signed quoteData carries the "CustomClaims" of the custom claims file (SQVS_CUSTOM_CLAIMS_FILE)
+//   that apply to the verified enclave, a YAML list of claims with a name, a static or text/template value
+//   evaluated against the result, and optional mrEnclave and mrSigner the claim is restricted to.
+//   The "results" list of the custom claims file holds result policies scoping and timing signed results
+//   for relying parties, a name, an issuer, an audience, a ttl and optional mrEnclave and mrSigner. The
+//   result of a request naming a policy ("policy" field, query parameter or form field) is issued under
+//   it, otherwise under the first one applying to the enclave, and carries its "ResultPolicy", the JWT
+//   "iss" and "aud" and the Unix time "exp" it expires at. Unknown policies are rejected with 400.
+//   Verified results carry the RFC 3339 time "ExpiresAt" relying parties should re-attest after, the
+//   earliest of the next update of the collateral, the result validity (SQVS_RESULT_VALIDITY) and the TTL
+//   of the result policy, and the "ExpirySource" it was chosen from, collateral_next_update,
+//   result_validity or result_policy_ttl. The "exp" of signed results is the Unix time of "ExpiresAt".
+//   A quote submitted with the "sessionToken" of a verification session opened at /v2/sessions (field,
+//   query parameter or form field) must bind the session nonce in its report data. The token can be used
+//   once, before the session expires, and only by the subject that opened the session; its use is recorded
+//   in the SQVS store shared by the replicas. The result carries the "SessionID" of the session. Tokens
+//   with an invalid signature, used or expired are rejected with 401, and quotes whose report data does
+//   not bind the nonce fail verification. Session tokens are rejected with 400 while the
+//   verification-sessions feature flag is disabled.
+//   Signed responses carry the JWS "alg" they are signed with, SQVS_RESULT_SIGNING_ALGORITHM by default.
+//   Requests for relying parties accepting other algorithms name one of RS384, PS384, ES384 or EdDSA in
+//   "signingAlgorithm" (field, query parameter or form field), signed with the key dedicated to it in
+//   SQVS_RESULT_SIGNING_KEYS or else the response signing key. Algorithms without a key, and EdDSA in FIPS
+//   mode, are rejected with 400.
 //
 // security:
 //  - bearerAuth: []
 // consumes:
 // - application/json
+// - application/octet-stream
+// - multipart/form-data
 // produces:
 // - application/json
 // parameters:
@@ -146,7 +413,9 @@ type UnsignedSGXResponseInfo struct {
 //  {
 //    "quoteData": "eyJSZXBvcnREYXRhIjoiMTRmMzlkMmIxZGRhMzI2NjFiNjMxYzdjZGVmZjEwYzVlOWVmZTEzNzBhMjA5YTg0NWRlMzQ4OTk2NDFmZWZmOCIsIlVzZXJEYXRhTWF0Y2giOiJ0cnVlIiwiTWVzc2FnZSI6IlNHWF9RTF9RVl9SRVNVTFRfT0siLCJFbmNsYXZlSXNzdWVyIjoiODNkNzE5ZTc3ZGVhY2ExNDcwZjZiYWY2MmE0ZDc3NDMwM2M4OTlkYjY5MDIwZjljNzBlZTFkZmMwOGM3Y2U5ZSIsIkVuY2xhdmVNZWFzdXJlbWVudCI6ImFkNDY3NDllZDQxZWJhYTIzMjcyNTIwNDFlZTc0NmQzNzkxYTlmMjQzMTgzMGZlZTA4ODNmNzk5M2NhZjMxNmEiLCJFbmNsYXZlSXNzdWVyUHJvZElEIjoiMDAiLCJFbmNsYXZlSXNzdWVyRXh0UHJvZElEIjoiMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAiLCJDb25maWdTdm4iOiIwMCIsIklzdlN2biI6IjAwIiwiQ29uZmlnSUQiOiIwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMCIsIlRjYkxldmVsIjoiT3V0T2ZEYXRlIiwiUXVvdGUiOiJBd0FDQUFBQUFBQUZBQW9BazVweU0vZWNUS21VQ2cyemxYOEdCMWVQSHZUeWFKcTdLV3RadkVCNWk1UUFBQUFBQWdJQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUJ3QUFBQUFBQUFEbkFBQUFBQUFBQUsxR2RKN1VIcnFpTW5KU0JCN25SdE41R3A4a01ZTVA3Z2lEOTVrOHJ6RnFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFDRDF4bm5mZXJLRkhEMnV2WXFUWGREQThpWjIya0NENXh3N2gzOENNZk9uZ0FBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQVU4NTBySGRveVpodGpISHplL3hERjZlL2hOd29nbW9SZDQwaVpaQi92K0FBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUExQkFBQUdwMUlNbEk3UCtsVk1sdEFKM3hUeWVMbXJxc1pnSy8wV0JhamlJUHFDcmh4QWFnSUl1MGwrUVBvQXVZbUVtSG00b0JyZ2pIaFVzcFVtenFndUhIb2ZGTTVzZndiL1FVNGhSRlVodHdWQW5vMEdBZnlHejhuSFZ5NjR4QXRSTm52N1Z2ay9HamlzbEtENzNVYW1naHBkTmFINXB6MC91NUpoT3AzN1lvRE5WZkFnSUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFGUUFBQUFBQUFBRG5BQUFBQUFBQUFHRFlXdktMNk5IRUNnalppd0NkWDRyTUU0U2poYzlHQ0FEa2VIa2RHcGVjQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQ01UMWQxMTVaUVBwWVRmM2ZHaW9LYUFGYXNqZTF3RkFzSUd3bEVrTVY3L3dBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUVBQlFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUROV0RoNmR2SmVodzVzUVNaQnRObE9WQkdhZlFhTWVPUWt2bnhVQUlBdVlnQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBaGd1U1gvSnNDUmgrUmpiZytkVExoVDMvcnpIUG9NYm9hVUgyZlNXTnlrN2graFVQaDJRbG9LZDhzbEVpOFpQblhZenpoY1lYcVRVWHdsR0hrcjNua2lBQUFBRUNBd1FGQmdjSUNRb0xEQTBPRHhBUkVoTVVGUllYR0JrYUd4d2RIaDhGQUd3T0FBQXRMUzB0TFVKRlIwbE9JRU5GVWxSSlJrbERRVlJGTFMwdExTMEtUVWxKUlRsRVEwTkNTbkZuUVhkSlFrRm5TVlZrSzNwMVlpOTRXbGhhU1ZadGQwZDZNWEZEVXpCVmNHOXNObEYzUTJkWlNVdHZXa2w2YWpCRlFYZEpkMk5FUldsTlEwRkhRVEZWUlFwQmQzZGFVMWMxTUZwWGQyZFZNR1JaU1VaQ1JGTjVRbEZpUjBZd1dtMDVlV0pUUWtSUlZFVmhUVUpuUjBFeFZVVkRaM2RTVTFjMU1GcFhkMmRSTWpsNVkwYzVlVmxZVW5CaU1qUjRDa1pFUVZOQ1owNVdRa0ZqVFVNeFRtaGlibEpvU1VWT2MxbFlTbWhOVVhOM1ExRlpSRlpSVVVsRVFVcEVVVlJGVEUxQmEwZEJNVlZGUW1oTlExWldUWGRJYUdOT1RXcEZkMDE2UVRVS1RVUlplazVVU1RKWGFHTk9UV3BuZDAxNlFUVk5SRmw2VGxSSk1sZHFRbmROVTBsM1NVRlpSRlpSVVVSRVFteEtZbTVTYkdKRFFsUlNNV2RuVlVWT1RFbEZUbXhqYmxKd1dtMXNhZ3BaV0ZKc1RWSnZkMGRCV1VSV1VWRkxSRUpHU21KdVVteGlRMEpFWWpOS2QySXpTbWhrUjJ4MlltcEZWVTFDU1VkQk1WVkZRbmQzVEZVeVJuVmtSMFZuVVRKNGFHTnRSWGhEZWtGS0NrSm5UbFpDUVdkTlFXdE9RazFSYzNkRFVWbEVWbEZSUjBWM1NsWlZla0phVFVKTlIwSjVjVWRUVFRRNVFXZEZSME5EY1VkVFRUUTVRWGRGU0VFd1NVRkNUWGh1WVdKMGMwVnhSbFVLYmxOdlZFNTBZMGtyYUcxeFFsQTNlWGN2UjJGbGRsbGxTM1V6VFZOc2MyMVpRVmxvYzBSdU5XTlRjelJPYkZOYWJrSldRMUY0TlU5WGFXcEhOVFVyWlVkM1FUSnpXSFJDWjJWaGFncG5aMDFSVFVsSlJFUkVRV1pDWjA1V1NGTk5SVWRFUVZkblFsSmFTVGxQYmxOeGFHcFdRelExWTBzelowUjNZM0pXZVZGeGRIcENka0puVGxaSVVqaEZZVVJDYlUxSFUyZFpjVUpuQ21oc05XOWtTRkozWTNwdmRrd3pUbWxsUXpWb1kwZHJkV1JJU2pGak0xSnNXa2hPYkdOdVduQlpNbFo2VEcxc2RXUkhWbk5NYlU1MllsTTVlbG96WjNaWk1sWjVaRWRzYldGWFRtZ0taRWRzZG1KcE9USk5lVGwzV1RKMGFtTnRkeTlaTWtVNVkwZDRhR1JIV25aamJUQnRXbGMxYW1JeVVuQmliV001V2tkV2VVMUNNRWRCTVZWa1JHZFJWMEpDVTJsTVMySkxWSEZOU2dwdlNIZDJLMDFpUmpRMk5tTnNVR05RV1hwQlQwSm5UbFpJVVRoQ1FXWTRSVUpCVFVOQ2MwRjNSRUZaUkZaU01GUkJVVWd2UWtGSmQwRkVRME5CYW10SFExTnhSMU5KWWpSVVVVVk9Da0ZSVTBOQmFXOTNaMmRKYlUxQ05FZERhWEZIVTBsaU5GUlJSVTVCVVVWRlJVTkRkbTg0YWl0NU1HWkJiMnBGWlZSTWVFeGlaR2QzWjJkR2FrSm5iM0ZvYTJsSEswVXdRa1JSUlVNS1RVbEpRbFY2UVZGQ1ozTnhhR3RwUnl0Rk1FSkVVVVZEUVZGSlFrRnFRVkZDWjNOeGFHdHBSeXRGTUVKRVVVVkRRV2RKUWtGcVFWRkNaM054YUd0cFJ5dEZNRUpFVVVWRFFYZEpRZ3BCUkVGUlFtZHpjV2hyYVVjclJUQkNSRkZGUTBKQlNVSkJSRUZSUW1kemNXaHJhVWNyUlRCQ1JGRkZRMEpSU1VKQlJFRlJRbWR6Y1docmFVY3JSVEJDUkZGRlEwSm5TVUpCUkVGUkNrSm5jM0ZvYTJsSEswVXdRa1JSUlVOQ2QwbENRVVJCVVVKbmMzRm9hMmxISzBVd1FrUlJSVU5EUVVsQ1FVUkJVVUpuYzNGb2EybEhLMFV3UWtSUlJVTkRVVWxDUVVSQlVVSm5jM0VLYUd0cFJ5dEZNRUpFVVVWRFEyZEpRa0ZFUVZGQ1ozTnhhR3RwUnl0Rk1FSkVVVVZEUTNkSlFrRkVRVkZDWjNOeGFHdHBSeXRGTUVKRVVVVkRSRUZKUWtGRVFWRkNaM054YUd0cFJ3b3JSVEJDUkZGRlEwUlJTVUpCUkVGUlFtZHpjV2hyYVVjclJUQkNSRkZGUTBSblNVSkJSRUZSUW1kemNXaHJhVWNyUlRCQ1JGRkZRMFIzU1VKQlJFRlJRbWR6Y1docmFVY3JSVEJDQ2tSUlJVTkZRVWxDUVVSQlVVSm5jM0ZvYTJsSEswVXdRa1JSUlVORlVVbENRMnBCWmtKbmMzRm9hMmxISzBVd1FrUlJSVU5GWjFGUlFXZEpRVUZCUVVGQlFVRkJRVUZCUVVGQlFVRUtRVVJCVVVKbmIzRm9hMmxISzBVd1FrUlJSVVJDUVVsQlFVUkJWVUpuYjNGb2EybEhLMFV3UWtSUlJVVkNRVmxSV1VkdlFVRkJRWGRFZDFsTFMyOWFTV2gyYUU1QlVUQkNRbEZ2UWdwQlZFRmxRbWR2Y1docmFVY3JSVEJDUkZGRlIwSkNRV0ZuTlV4emIxZG5hUzlRUkZKTlQzSndOVmh6YUUxRlVVZERhWEZIVTBsaU5GUlJSVTVCVVdOM1RtcEJVVUpuYzNGb2EybEhDaXRGTUVKRVVVVklRVkZGUWk5NlFWRkNaM054YUd0cFJ5dEZNRUpFVVVWSVFXZEZRa0ZFUVZGQ1ozTnhhR3RwUnl0Rk1FSkVVVVZJUVhkRlFpOTZRVXRDWjJkeGFHdHFUMUJSVVVRS1FXZE9TVUZFUWtaQmFVVkJjVFZ6SzJoaFdIbGFSaXN4VkU1Q1VWVmhSRXhOYVRCbE4yMDRWMkpPVEdoUk5tNTRNSHBoWTNOdlVVTkpRUzlhUmpJeFZrOUVNVGRDZEhjd2NIQkhUd3AzUkVGNVZDOUxPRUppTVRaM1NqaERUVTFGV1ZsamNVRUtMUzB0TFMxRlRrUWdRMFZTVkVsR1NVTkJWRVV0TFMwdExTMHRMUzB0UWtWSFNVNGdRMFZTVkVsR1NVTkJWRVV0TFMwdExRcE5TVWxEYldwRFEwRnJRMmRCZDBsQ1FXZEpWVmRUVUZSd01IRnZXVEZSZFU5WVEzUTBRVGhJU3pGamEwdHlZM2REWjFsSlMyOWFTWHBxTUVWQmQwbDNDbUZFUldGTlFtZEhRVEZWUlVGM2QxSlRWelV3V2xkM1oxVXdaRmxKUmtwMllqTlJaMUV3UlhoSGFrRlpRbWRPVmtKQmIwMUZWV3gxWkVkV2MwbEZUbllLWTI1Q2RtTnRSakJoVnpsMVRWSlJkMFZuV1VSV1VWRklSRUYwVkZsWE5UQlpVMEpFWWtkR2VWbFVSVXhOUVd0SFFURlZSVU5CZDBOUk1FVjRRM3BCU2dwQ1owNVdRa0ZaVkVGc1ZsUk5RalJZUkZSRk5VMVVRWHBOVkVWNVRYcE5NRTR4YjFoRVZFMHdUVlJCZWsxVVJYbE5lazB3VGpGdmQyTkVSV2xOUTBGSENrRXhWVVZCZDNkYVUxYzFNRnBYZDJkVk1HUlpTVVpDUkZONVFsRmlSMFl3V20wNWVXSlRRa1JSVkVWaFRVSm5SMEV4VlVWRFozZFNVMWMxTUZwWGQyY0tVVEk1ZVdOSE9YbFpXRkp3WWpJMGVFWkVRVk5DWjA1V1FrRmpUVU14VG1oaWJsSm9TVVZPYzFsWVNtaE5VWE4zUTFGWlJGWlJVVWxFUVVwRVVWUkZUQXBOUVd0SFFURlZSVUpvVFVOV1ZrMTNWMVJCVkVKblkzRm9hMnBQVUZGSlFrSm5aM0ZvYTJwUFVGRk5Ra0ozVGtOQlFWRjNjQ3RNWXl0VVZVSjBaekZJQ2l0Vk9FcEpjMDF6WW1wSWFrTnJWSFJZWWpocVVFMDJjakprYUhVNWVrbGliR2hFV2pkSlRtWnhkRE5KZURoWVkwWkxSRGhyTUU1RldISnJXalkyY1VvS1dHRXhTM3BNU1V0dk5FY3ZUVWxIT0UxQ09FZEJNVlZrU1hkUldVMUNZVUZHVDI1dlVrWktWRTVzZUV4SFNtOVNMMFZOV1V4TFdHTkpTVUpKVFVaWlJ3cEJNVlZrU0hkU1VFMUZNSGRUTmtKS2IwVmxSMUpYYURCa1NFSjZUMms0ZG1NeVNqUk1WMDVzWTI1U2NGcHRiR3BaV0ZKc1kzazFNR051Vm5wa1IxWnJDbU15Vm5sa2JXeHFXbGhOZFdGWE5UQmFWM2QxV1RJNWRFd3diSFZrUjFaelZUQmtXVlZ0T1haa1JVNUNURzFTYkdOcVFXUkNaMDVXU0ZFMFJVWm5VVlVLVjFOUVZIQXdjVzlaTVZGMVQxaERkRFJCT0VoTE1XTnJTM0pqZDBSbldVUldVakJRUVZGSUwwSkJVVVJCWjBWSFRVSkpSMEV4VldSRmQwVkNMM2RSU1FwTlFWbENRV1k0UTBGUlFYZERaMWxKUzI5YVNYcHFNRVZCZDBsRVUwRkJkMUpSU1doQlNqRnhLMFpVZWl0blZYVldaa0pSZFVOblNuTkdja3d5VkZSVENtVXhZVUphTlROUE5USlVha1pwWlRaQmFVRnlhVkJoVW1Gb1ZWZzVUMkU1YTBkTWJFRmphRmRZUzFRMmFqUlNWMU5TTlRCQ2NXaHlUak5WVkRSQlBUMEtMUzB0TFMxRlRrUWdRMFZTVkVsR1NVTkJWRVV0TFMwdExRb3RMUzB0TFVKRlIwbE9JRU5GVWxSSlJrbERRVlJGTFMwdExTMEtUVWxKUTJ4RVEwTkJhbTFuUVhkSlFrRm5TVlpCVDI1dlVrWktWRTVzZUV4SFNtOVNMMFZOV1V4TFdHTkpTVUpKVFVGdlIwTkRjVWRUVFRRNVFrRk5Rd3BOUjJkNFIycEJXVUpuVGxaQ1FVMU5SVlZzZFdSSFZuTkpSazVJVjBOQ1UySXlPVEJKUlU1Q1RWSnZkMGRCV1VSV1VWRkxSRUpHU21KdVVteGlRMEpFQ21JelNuZGlNMHBvWkVkc2RtSnFSVlZOUWtsSFFURlZSVUozZDB4Vk1rWjFaRWRGWjFFeWVHaGpiVVY0UTNwQlNrSm5UbFpDUVdkTlFXdE9RazFSYzNjS1ExRlpSRlpSVVVkRmQwcFdWWHBCWlVaM01IaFBWRVYzVFhwRmQwOVVVVFZOYWtaaFJuY3dNRTlVUlhsTmVrVjVUWHBWTlU1VWJHRk5SMmQ0UjJwQldRcENaMDVXUWtGTlRVVlZiSFZrUjFaelNVWk9TRmREUWxOaU1qa3dTVVZPUWsxU2IzZEhRVmxFVmxGUlMwUkNSa3BpYmxKc1lrTkNSR0l6U25kaU0wcG9DbVJIYkhaaWFrVlZUVUpKUjBFeFZVVkNkM2RNVlRKR2RXUkhSV2RSTW5ob1kyMUZlRU42UVVwQ1owNVdRa0ZuVFVGclRrSk5VWE4zUTFGWlJGWlJVVWNLUlhkS1ZsVjZRbHBOUWsxSFFubHhSMU5OTkRsQlowVkhRME54UjFOTk5EbEJkMFZJUVRCSlFVSkZMelpFTHpGWFNFNXlWM2RRYlU1TlNYbENTMDFYTlFwS05rcDZUWE5xYnpaNFVESjJhMHN4WTJSYVIySXhVRWRTVUM5REx6aEZRMmRwUkd0dGEyeHRlbmRNZWt4cEt6QXdNRzAzVEV4eWRFdEtRVE52UXpKcUNtZGlPSGRuWW5kM1NIZFpSRlpTTUdwQ1FtZDNSbTlCVlRabGFFVlZiRTB5V0VWeldXMW9TRGhSZUdkemNHUjNaMmRGWjNkV1oxbEVWbEl3WmtKRk9IY0tWRlJDVEc5RmJXZFNORnBHWVVoU01HTklUVFpNZVRsNldXNW5kRmt5Vm5sa1IyeHRZVmRPYUdSSFZucE1ibEo1WkZoT01GcFhVbnBhV0VveVlWZE9iQXBqZVRWd1ltNVNiR0pETldwaU1qQjJVMWMxTUZwWGVGUlNNV2hUWWpJNU1GRXdSWFZhUjFaNVRVSXdSMEV4VldSRVoxRlhRa0pVY0RaRlVsTlZlbHBqQ2xONGFXRkZabmhFUjBONWJETkRRMEZUUkVGUFFtZE9Wa2hST0VKQlpqaEZRa0ZOUTBGUldYZEZaMWxFVmxJd1ZFRlJTQzlDUVdkM1FtZEZRaTkzU1VJS1FWUkJTMEpuWjNGb2EycFBVRkZSUkVGblRrcEJSRUpIUVdsRlFYcDNPWHBrVldsVlNGQk5WV1F3UXpSdGVEUXhhbXhHV210eVRUTjVOV1l4YkdkdVZncFBOMFppYWs5dlEwbFJRMjlIZEZWdFZEUmpXSFEzVml0NVUwaGlTamhJYjJJNVFXRnVjSFpZVGtneFJWSXJMMmRhUml0dmNGRTlQUW90TFMwdExVVk9SQ0JEUlZKVVNVWkpRMEZVUlMwdExTMHRDZz09IiwiQ2hhbGxlbmdlIjoiYWJjZCJ9",
 //    "signature": "bm8GLCiO8vx6CcjzlHDdwfEviGxZ5thqEFHHjfjiR9vvYLQtfhYxpb8orikxZOy2hMcsoqiFfTQh8vFe33iJF04giMN+5MhQ99JNBF6j4x1SJl/HL1qQayuh7RdMErZg0FYYegVUW8picOitYiWpcTfEYDjut9xXAtj7NU5hvH6jFE+u6Pj2d7LzIRpAenyI4Z5dTA3CCknsr4FYFypAN+STRqloivPpJmFmp3/J0O17iQ5qAgaJK0Io3rGH9lzEhs6eKH9o5VNCCYNTM/PBoqlGVMRJ23w2ZEyNOcTszdLWFc+a/oQCjfDyYE9UuoHyU5blH2iic76DbCJPaERP0c4xz2ymziA2LjDryt5xVsZqAtQya5pff8rbf5glEORyJsYtkgaq4qLq4JFVW7pkHYVzMfQ3FAHjwkJKH5uNbZesoTTQIsbwNULee2DK3nYy1YEd7beGh64MEloE1M8aSZz4RFbCzLjlRdidFVrIgGJ1ky1HM9xyLCQahJQnSDZg",
-//    "certificateChain": "-----BEGIN CERTIFICATE-----\nMIIEDTCCAnWgAwIBAgIBCjANBgkqhkiG9w0BAQwFADBQMQswCQYDVQQGEwJVUzEL\nMAkGA1UECBMCU0YxCzAJBgNVBAcTAlNDMQ4wDAYDVQQKEwVJTlRFTDEXMBUGA1UE\nAxMOQ01TIFNpZ25pbmcgQ0EwHhcNMjEwNzI3MDUwMzU3WhcNMjIwNzI3MDUwMzU3\nWjAwMS4wLAYDVQQDEyVTUVZTIFFWTCBSZXNwb25zZSBTaWduaW5nIENlcnRpZmlj\nYXRlMIIBojANBgkqhkiG9w0BAQEFAAOCAY8AMIIBigKCAYEArriZkks30O7NLl7S\nDNXzJBBSnDtT8em1gUizIUP8RBgRt4hKs+/W8IuouZDX5SVJBhzFO+f+/tjNh2TN\ndW5mw6BA1u80rZXtUDS7rFGecMakuYLyWhbeSK+LIiA1ogCKHoh4YaLxBwqhX/FV\namE5LqVmwDjvFJGiw3c+OpuoVaSfKXiUmhXP6bP9y7k5AbRcm2dOrr/O3uv4A4mw\nUK3MFOwGif9yR8z3UUAiEhKFJBLIZXljyfZMRTTmyJWcNyS5V/R+zTZgYvJMxJVU\nQPwnUdN0HO0ubSkR+OSRgBrVlxzXjOgXMVRUq6kZAKZYs/PVomYB5G+sD6rkJ1/G\nmD3zoqULDmcwbIvxHUml504YbZvt9DB00bKWAMpkNaJCLEGahaN3oFtU51XfAf2T\nJZ8wsDdRYtVAm6zXrZ1zKrJ0WgrBYZeTemt+eRNSwwFziXEJZ5eUVm/bS+mrVkfM\nuPAon9LQH8Hfdy1T4bVONwo2Bc0dsGA3hOeiniDPmrhPSYDlAgMBAAGjEjAQMA4G\nA1UdDwEB/wQEAwIGwDANBgkqhkiG9w0BAQwFAAOCAYEAhpGdekrRgNB71opfBSuD\nyK6QUP81SEc7wOwbR6Ijkl4qxWSGYZQGK1nPLZfy+E278T1XzM55WLzB4ZnW/CYx\nG81w7/HlWI+IMsyDtYzIDJv1dsI5P6jaFb3WI1RZoTynMZ2AyT4xB9Cboxr0MMU0\nOtfexIbKNoIK4cGJKLPny8a6yZxhmCY4jOr5pGWddV1IwX0mCAyzF5y1Atnrys/X\nkcvsnD+iXZSTiL7mXgb06n/EJ5qMyoJ6/afqcNoFJBfcpZ6INOlK91sVc3B8tob+\n/PTX1fBzu5Tz4WFx0pZihcxREasO/YNSWzLWLXT6fE1NG5vk8jZBGeXXQ4AAwixX\nnpWfiwoxibe404GPkNC1nWl84e80nIK7Wt8N0AtfKZvV8RAo6O1yxHKkzUF5jUNG\nT4JYmNt20zlXHFs1WqQ2FBLprXE9nDVe9/nO/KEKUyRtpKMaoOK8s4Cgg73HyeOD\n9xjX/xST5w40Dtg6tN4UbMzchCLsAW2oZ9CVfqRlpEXo\n-----END CERTIFICATE-----\n-----BEGIN CERTIFICATE-----\nMIIENTCCAp2gAwIBAgIBAzANBgkqhkiG9w0BAQwFADBHMQswCQYDVQQGEwJVUzEL\nMAkGA1UECBMCU0YxCzAJBgNVBAcTAlNDMQ4wDAYDVQQKEwVJTlRFTDEOMAwGA1UE\nAxMFQ01TQ0EwHhcNMjEwNzE0MTUxMDI4WhcNMjYwNzE0MTUxMDI4WjBQMQswCQYD\nVQQGEwJVUzELMAkGA1UECBMCU0YxCzAJBgNVBAcTAlNDMQ4wDAYDVQQKEwVJTlRF\nTDEXMBUGA1UEAxMOQ01TIFNpZ25pbmcgQ0EwggGiMA0GCSqGSIb3DQEBAQUAA4IB\njwAwggGKAoIBgQDOp1Pb0o3Gly5apKTz0UTbcIvRrZHGhCKdB5sVprFI3CIiEVIJ\nU5ickQnIgE94f/bfzHhIWI7AZ9/FHdATLyx1PesGyCerD67GYfZxf8bjJGJIBcxs\nu31mgbletVBPXOx6Rz5ITXwybSCum+5cneLXPNRltz+VMF8BZlhUWSZ3kansLgpW\nwew7cLDtWpkBSRDVZNvF0k8TcDBHNLe2/X3THEZwwy5t81a+ZncsxIw0+Fi7Semn\nr9kGroAB8JeDq15u2GmjDvvD0pFQLYAsYo4WovMm02RXGt+zt9kncTHhyIukiEEl\nIRMxbtJCvVMz4/hM4Dz1dzn6pj+8bsCMGt/NIRx1PEtZjr9/52//BywONOEt7Vwj\niIhrdmhAc2oFCTyeAc6cf99vCCbh8ylOcN38OTyeSH9eySndu02rfLuB2hY3xISN\nb4V9XLMhj1qNaOBbJNTUaTghG2jnnzNYMV98cWMGr3w+n24Cs9EfOnXR9sfqb8S7\n0k6jCaH8B1rjVIsCAwEAAaMjMCEwDgYDVR0PAQH/BAQDAgEGMA8GA1UdEwEB/wQF\nMAMBAf8wDQYJKoZIhvcNAQEMBQADggGBAJ3yYQ7X+IdPQ1C/Mlk2tLN+HymUgUGw\nBQgd0vh+qbkDlas5hO5s1JMGABXoZXClMhTMWDOX4w5SAXWZVVh5fCfFPs/HvIQY\nYTQ5GRxjagSLIobpZ5ksPQUqodloH9OrNjR0PX2T9iNxhTkima8QjvKiDT0S1Xgc\n4d6qCwUfFnRJGCk8HL5K0+x+NueQYDKgbPMKDHgL0C8gSAih7KpqucSTOMo8gbC0\nmYKlgjm744n7k7Cs9VQQ03AmTq2w9U67Dl1tK29R1CfUV/ZukILtkxl1KTjyCzT5\n0rEE3/XKHQSWxdAA7q5hBDcQFjPFcGfMTLcyNRyEmybsC8iBbLSs1aO6cWkf5Wkm\nyJmiJZxBEU0MtsmLS7bA/aLCy9n4Qxg5vW2SEpba4poaOx0gL/axxhEIklBVUeUv\nk4xHlnEDB6oYtPCyJIYTrkZ8ofmfpILCa4t7TFtWd/KmiOgWenM8ZB5ATfUvXTEg\nIEFtWddYDybUsLQULFV7DrBcldWpSG72Kg==\n-----END CERTIFICATE-----\n"
+//    "certificateChain": "-----BEGIN CERTIFICATE-----\nMIIEDTCCAnWgAwIBAgIBCjANBgkqhkiG9w0BAQwFADBQMQswCQYDVQQGEwJVUzEL\nMAkGA1UECBMCU0YxCzAJBgNVBAcTAlNDMQ4wDAYDVQQKEwVJTlRFTDEXMBUGA1UE\nAxMOQ01TIFNpZ25pbmcgQ0EwHhcNMjEwNzI3MDUwMzU3WhcNMjIwNzI3MDUwMzU3\nWjAwMS4wLAYDVQQDEyVTUVZTIFFWTCBSZXNwb25zZSBTaWduaW5nIENlcnRpZmlj\nYXRlMIIBojANBgkqhkiG9w0BAQEFAAOCAY8AMIIBigKCAYEArriZkks30O7NLl7S\nDNXzJBBSnDtT8em1gUizIUP8RBgRt4hKs+/W8IuouZDX5SVJBhzFO+f+/tjNh2TN\ndW5mw6BA1u80rZXtUDS7rFGecMakuYLyWhbeSK+LIiA1ogCKHoh4YaLxBwqhX/FV\namE5LqVmwDjvFJGiw3c+OpuoVaSfKXiUmhXP6bP9y7k5AbRcm2dOrr/O3uv4A4mw\nUK3MFOwGif9yR8z3UUAiEhKFJBLIZXljyfZMRTTmyJWcNyS5V/R+zTZgYvJMxJVU\nQPwnUdN0HO0ubSkR+OSRgBrVlxzXjOgXMVRUq6kZAKZYs/PVomYB5G+sD6rkJ1/G\nmD3zoqULDmcwbIvxHUml504YbZvt9DB00bKWAMpkNaJCLEGahaN3oFtU51XfAf2T\nJZ8wsDdRYtVAm6zXrZ1zKrJ0WgrBYZeTemt+eRNSwwFziXEJZ5eUVm/bS+mrVkfM\nuPAon9LQH8Hfdy1T4bVONwo2Bc0dsGA3hOeiniDPmrhPSYDlAgMBAAGjEjAQMA4G\nA1UdDwEB/wQEAwIGwDANBgkqhkiG9w0BAQwFAAOCAYEAhpGdekrRgNB71opfBSuD\nyK6QUP81SEc7wOwbR6Ijkl4qxWSGYZQGK1nPLZfy+E278T1XzM55WLzB4ZnW/CYx\nG81w7/HlWI+IMsyDtYzIDJv1dsI5P6jaFb3WI1RZoTynMZ2AyT4xB9Cboxr0MMU0\nOtfexIbKNoIK4cGJKLPny8a6yZxhmCY4jOr5pGWddV1IwX0mCAyzF5y1Atnrys/X\nkcvsnD+iXZSTiL7mXgb06n/EJ5qMyoJ6/afqcNoFJBfcpZ6INOlK91sVc3B8tob+\n/PTX1fBzu5Tz4WFx0pZihcxREasO/YNSWzLWLXT6fE1NG5vk8jZBGeXXQ4AAwixX\nnpWfiwoxibe404GPkNC1nWl84e80nIK7Wt8N0AtfKZvV8RAo6O1yxHKkzUF5jUNG\nT4JYmNt20zlXHFs1WqQ2FBLprXE9nDVe9/nO/KEKUyRtpKMaoOK8s4Cgg73HyeOD\n9xjX/xST5w40Dtg6tN4UbMzchCLsAW2oZ9CVfqRlpEXo\n-----END CERTIFICATE-----\n-----BEGIN CERTIFICATE-----\nMIIENTCCAp2gAwIBAgIBAzANBgkqhkiG9w0BAQwFADBHMQswCQYDVQQGEwJVUzEL\nMAkGA1UECBMCU0YxCzAJBgNVBAcTAlNDMQ4wDAYDVQQKEwVJTlRFTDEOMAwGA1UE\nAxMFQ01TQ0EwHhcNMjEwNzE0MTUxMDI4WhcNMjYwNzE0MTUxMDI4WjBQMQswCQYD\nVQQGEwJVUzELMAkGA1UECBMCU0YxCzAJBgNVBAcTAlNDMQ4wDAYDVQQKEwVJTlRF\nTDEXMBUGA1UEAxMOQ01TIFNpZ25pbmcgQ0EwggGiMA0GCSqGSIb3DQEBAQUAA4IB\njwAwggGKAoIBgQDOp1Pb0o3Gly5apKTz0UTbcIvRrZHGhCKdB5sVprFI3CIiEVIJ\nU5ickQnIgE94f/bfzHhIWI7AZ9/FHdATLyx1PesGyCerD67GYfZxf8bjJGJIBcxs\nu31mgbletVBPXOx6Rz5ITXwybSCum+5cneLXPNRltz+VMF8BZlhUWSZ3kansLgpW\nwew7cLDtWpkBSRDVZNvF0k8TcDBHNLe2/X3THEZwwy5t81a+ZncsxIw0+Fi7Semn\nr9kGroAB8JeDq15u2GmjDvvD0pFQLYAsYo4WovMm02RXGt+zt9kncTHhyIukiEEl\nIRMxbtJCvVMz4/hM4Dz1dzn6pj+8bsCMGt/NIRx1PEtZjr9/52//BywONOEt7Vwj\niIhrdmhAc2oFCTyeAc6cf99vCCbh8ylOcN38OTyeSH9eySndu02rfLuB2hY3xISN\nb4V9XLMhj1qNaOBbJNTUaTghG2jnnzNYMV98cWMGr3w+n24Cs9EfOnXR9sfqb8S7\n0k6jCaH8B1rjVIsCAwEAAaMjMCEwDgYDVR0PAQH/BAQDAgEGMA8GA1UdEwEB/wQF\nMAMBAf8wDQYJKoZIhvcNAQEMBQADggGBAJ3yYQ7X+IdPQ1C/Mlk2tLN+HymUgUGw\nBQgd0vh+qbkDlas5hO5s1JMGABXoZXClMhTMWDOX4w5SAXWZVVh5fCfFPs/HvIQY\nYTQ5GRxjagSLIobpZ5ksPQUqodloH9OrNjR0PX2T9iNxhTkima8QjvKiDT0S1Xgc\n4d6qCwUfFnRJGCk8HL5K0+x+NueQYDKgbPMKDHgL0C8gSAih7KpqucSTOMo8gbC0\nmYKlgjm744n7k7Cs9VQQ03AmTq2w9U67Dl1tK29R1CfUV/ZukILtkxl1KTjyCzT5\n0rEE3/XKHQSWxdAA7q5hBDcQFjPFcGfMTLcyNRyEmybsC8iBbLSs1aO6cWkf5Wkm\nyJmiJZxBEU0MtsmLS7bA/aLCy9n4Qxg5vW2SEpba4poaOx0gL/axxhEIklBVUeUv\nk4xHlnEDB6oYtPCyJIYTrkZ8ofmfpILCa4t7TFtWd/KmiOgWenM8ZB5ATfUvXTEg\nIEFtWddYDybUsLQULFV7DrBcldWpSG72Kg==\n-----END CERTIFICATE-----\n",
+//    "keyId": "kM2Gc8v0WnY3b1tJ6q8xHc2oZp4dY9sFqL1uN7eR3aE",
+//    "alg": "RS384"
 //  }
 //
 // x-unsigned-sample-call-input: |
@@ -164,9 +433,1007 @@ type UnsignedSGXResponseInfo struct {
 //        "EnclaveMeasurement": "ad46749ed41ebaa2327252041ee746d3791a9f2431830fee0883f7993caf316a",
 //        "EnclaveIssuerProdID": "00",
 //        "IsvSvn": "00",
-//        "TcbLevel": "OutOfDate"
+//        "TcbLevel": "OutOfDate",
+//        "EnclaveDebugMode": false
+//    }
+//  }
+//
+
+// ---
+
+// swagger:operation GET /v1/version Version getVersion
+// ---
+// description: |
+//   Returns the version of the service as text or, to clients accepting application/json, the build
+//   provenance for fleet inventory to verify exactly what is deployed: the version, git hash, build date, Go
+//   version, the SHA-256 digest of the software bill of materials of the build and the optional features the
+//   configuration enables. The same provenance is logged at startup and printed by "sqvs version".
+//
+// produces:
+// - text/plain
+// - application/json
+// responses:
+//   '200':
+//     description: Successfully retrieved the version.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/version
+// x-sample-call-output: |
+//  {
+//    "serviceName": "SGX Quote Verification Service",
+//    "version": "v4.2.0",
+//    "gitHash": "9a0e9e0",
+//    "buildDate": "2021-07-14T10:12:03+0000",
+//    "goVersion": "go1.16.7",
+//    "sbomHash": "5d41402abc4b2a76b9719d911017c592ae2b5e2f1ea0fb0a4fd5d2a8e1d3c0a7",
+//    "features": [
+//      "token-auth",
+//      "signed-responses",
+//      "verification-history"
+//    ]
+//  }
+// ---
+
+// swagger:operation GET /v1/.well-known/jwks.json Keys getJWKS
+// ---
+// description: |
+//   Publishes the public keys of the quote response signing keys as a JSON Web Key Set, so relying parties
+//   can verify signed responses offline. The current key is listed first, followed by the keys rotated out
+//   less than SQVS_SIGNING_KEY_OVERLAP ago, then the keys dedicated to an algorithm by
+//   SQVS_RESULT_SIGNING_KEYS. Every key advertises the "alg" it signs with. The endpoint does not require
+//   a token.
+//
+// produces:
+// - application/jwk-set+json
+// parameters:
+// - name: If-None-Match
+//   description: ETag of the response held by the client, the response is not transferred again while it matches.
+//   in: header
+//   type: string
+// responses:
+//   '200':
+//     description: Successfully retrieved the signing keys.
+//   '304':
+//     description: The response is unchanged since the ETag of the If-None-Match header.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/.well-known/jwks.json
+// x-sample-call-output: |
+//  {
+//    "keys": [
+//      {
+//        "kty": "RSA",
+//        "kid": "kM2Gc8v0WnY3b1tJ6q8xHc2oZp4dY9sFqL1uN7eR3aE",
+//        "use": "sig",
+//        "alg": "RS384",
+//        "n": "xQ3nT0bJ...",
+//        "e": "AQAB",
+//        "x5c": ["MIIEDTCCAnWgAwIBAgIBCjANBgkqhkiG9w0BAQwFADBQ..."]
+//      }
+//    ]
+//  }
+// ---
+
+// swagger:operation POST /v1/sgx_qv_verify_quotes Quote sgxVerifyQuoteBatch
+// ---
+// description: |
+//   Verifies a batch of up to 100 base64 encoded quotes. The quotes are verified in parallel, on every CPU
+//   when the CPU accelerates ECDSA verification or on SQVS_BATCH_WORKERS workers, and quotes of the same
+//   platform share the verification of their PCK certificate chain. The results are returned in the order
+//   of the quotes, a quote failing verification carries the error and the status code the
+//   /v1/sgx_qv_verify_quote endpoint would have returned. A quote whose verification panics fails with a 500
+//   internal error carrying the fingerprint of the panic, counted by sqvs_verification_panics_total, while the
+//   other quotes of the batch are still verified.
+//   With "Accept: application/x-ndjson" the results are streamed as each quote completes instead, one
+//   result per line in completion order, every result carrying the index of its quote.
+//   The endpoint is gated by the batch feature flag (SQVS_FEATURES), enabled by default, and is not found
+//   while the flag is disabled.
+//
+// security:
+//  - bearerAuth: []
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// - application/x-ndjson
+// responses:
+//   '200':
+//     description: Successfully verified the batch.
+//   '400':
+//     description: Invalid request body or batch size.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/sgx_qv_verify_quotes
+// x-sample-call-input: |
+//  {
+//    "quotes": [
+//      {"quote": "AwACAAAAAAAFAAoAk5pyM/ecTKmUCg2zlX8GB1ePHvTyaJq7KWtZvEB5i5Q..."},
+//      {"quote": "AwACAAAAAAAFAAoAk5pyM/ecTKmUCg2zlX8GB1ePHvTyaJq7KWtZvEB5i5Q..."}
+//    ]
+//  }
+// x-sample-call-output: |
+//  {
+//    "results": [
+//      {"index": 0, "result": {"Message": "SGX_QL_QV_RESULT_OK", "TcbLevel": "UpToDate", "UserDataMatch": "true"}},
+//      {"index": 1, "error": {"message": "Cannot verify pck cert", "status": 400}}
+//    ]
+//  }
+// ---
+
+// swagger:operation POST /v1/attest Attestation attest
+// ---
+// description: |
+//   Verifies evidence of any supported TEE sent in a self-describing envelope. The "type" of the envelope is
+//   the media type of the evidence and selects the verifier, "evidence" is the base64 encoded evidence and
+//   "parameters" holds the parameters specific to the evidence type. Supported types:
+//   - application/vnd.intel.sgx.quote: SGX ECDSA quote, parameters "constraints" and "evaluationTime" as in
+//     /v1/sgx_qv_verify_quote.
+//   - application/vnd.intel.sgx.report: SGX report, parameters "quote", "verifierMrSigner" and
+//     "verifierMrEnclave" as in /v1/sgx_verify_report.
+//   Unsupported evidence types are rejected with 415.
+//
+// security:
+//  - bearerAuth: []
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// responses:
+//   '200':
+//     description: Successfully verified the evidence.
+//   '400':
+//     description: Invalid envelope, evidence or parameters.
+//   '415':
+//     description: Unsupported evidence type.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/attest
+// x-sample-call-input: |
+//  {
+//    "type": "application/vnd.intel.sgx.quote",
+//    "evidence": "AwACAAAAAAAFAAoAk5pyM/ecTKmUCg2zlX8GB1ePHvTyaJq7KWtZvEB5i5Q...",
+//    "userData": "dGVzdA==",
+//    "parameters": {"constraints": {"minIsvSvn": 1}}
+//  }
+// x-sample-call-output: |
+//  {
+//    "type": "application/vnd.intel.sgx.quote",
+//    "result": {"Message": "SGX_QL_QV_RESULT_OK", "TcbLevel": "UpToDate", "UserDataMatch": "true"}
+//  }
+// ---
+
+// swagger:operation GET /v1/usage Usage getUsage
+// ---
+// description: |
+//   Reports the number of verification requests of the tenants per route over a day or a month, when quotas
+//   are enabled with SQVS_QUOTA_ENABLED. The tenant of a request is read from the SQVS_QUOTA_TENANT_CLAIM
+//   claim of its token. The "*" route counts all the requests of a tenant. Administrators see the usage of
+//   every tenant, or of the tenant given in the query; quote verifiers only see the usage of their own tenant.
+//   Requests beyond a quota are either reported with an X-Quota-Warning header or rejected with 429,
+//   depending on SQVS_QUOTA_ENFORCEMENT.
+//
+// security:
+//  - bearerAuth: []
+// produces:
+// - application/json
+// parameters:
+// - name: tenant
+//   description: Tenant to report the usage of.
+//   in: query
+//   type: string
+// - name: period
+//   description: Day (YYYY-MM-DD) or month (YYYY-MM) to report the usage of, the current month by default.
+//   in: query
+//   type: string
+// - name: If-None-Match
+//   description: ETag of the response held by the client, the response is not transferred again while it matches.
+//   in: header
+//   type: string
+// responses:
+//   '200':
+//     description: Successfully retrieved the usage.
+//   '304':
+//     description: The response is unchanged since the ETag of the If-None-Match header.
+//   '400':
+//     description: Invalid period.
+//   '404':
+//     description: Quotas are not enabled.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/usage?tenant=acme&period=2021-06
+// x-sample-call-output: |
+//  {
+//    "period": "2021-06",
+//    "enforcement": "reject",
+//    "usages": [
+//      {"tenant": "acme", "route": "*", "period": "2021-06", "count": 1250},
+//      {"tenant": "acme", "route": "/v1/sgx_qv_verify_quote", "period": "2021-06", "count": 1200, "limit": 5000}
+//    ]
+//  }
+// ---
+
+// swagger:operation GET /v1/admin/dependencies Admin getDependencies
+// ---
+// description: |
+//   Summarizes the services SQVS relies on, CMS, AAS when tokens are required and SCS, to diagnose failing
+//   verifications. Each dependency is checked for reachability with a TCP connection when the endpoint is
+//   called, and reports the circuit breaker state, the time of the last successful and failed requests,
+//   the last error and the 50th, 90th and 99th percentiles of the latency of its most recent requests.
+//   PCS is not contacted by SQVS directly, its collateral is served by SCS.
+//   The TCB info of each FMSPC is fetched behind a circuit breaker of its own, so the broken collateral of
+//   one platform family does not stop the verifications of the others. The FMSPCs whose breakers are open
+//   or half-open are listed in "openScopes" as fmspc=<FMSPC>.
+//   Requires the Administrator role.
+//
+// security:
+//  - bearerAuth: []
+// produces:
+// - application/json
+// responses:
+//   '200':
+//     description: Successfully retrieved the status of the dependencies.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/admin/dependencies
+// x-sample-call-output: |
+//  {
+//    "checkedAt": "2021-06-15T10:00:00Z",
+//    "dependencies": [
+//      {
+//        "name": "SCS",
+//        "url": "https://scs.com:9000/scs/sgx/certification/v1/",
+//        "reachable": true,
+//        "host": "scs.com:9000",
+//        "breakerState": "closed",
+//        "requests": 1200,
+//        "failures": 3,
+//        "lastSuccess": "2021-06-15T09:59:58Z",
+//        "lastFailure": "2021-06-15T08:12:01Z",
+//        "lastError": "scs.com:9000 responded 503 Service Unavailable",
+//        "latencyP50Ms": 12.5,
+//        "latencyP90Ms": 40.1,
+//        "latencyP99Ms": 180.3
+//      }
+//    ]
+//  }
+// ---
+
+// swagger:operation GET /v1/admin/overview Admin getAdminOverview
+// ---
+// description: |
+//   Summarizes the service for operators: its version, uptime and maintenance mode, the status of its
+//   dependencies, the verifications made since it started by status, TCB level and failed step along with the
+//   20 most recent ones, the policies quotes are verified against and the collateral cache, the imported PCK
+//   CRLs and the TCB info pinned for the enrolled platforms. The custom claims policy signed by a policy
+//   author, by a detached JWS in <policy file>.jws whose kid is the author ID registered in
+//   /etc/sqvs/certs/policy-authors/, is attributed to its author in "customClaimsSignature". SQVS refuses to
+//   start with an invalid policy signature, and with an unsigned policy when SQVS_REQUIRE_SIGNED_POLICIES is
+//   set. The read-only admin UI at /svs/v1/admin/ui, served
+//   without authentication, asks for an administrator token and shows this overview.
+//   Requires the Administrator role.
+//
+// security:
+//  - bearerAuth: []
+// produces:
+// - application/json
+// parameters:
+// - name: If-None-Match
+//   description: ETag of the response held by the client, the response is not transferred again while it matches.
+//   in: header
+//   type: string
+// responses:
+//   '200':
+//     description: Successfully retrieved the overview of the service.
+//   '304':
+//     description: The response is unchanged since the ETag of the If-None-Match header.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/admin/overview
+// x-sample-call-output: |
+//  {
+//    "version": {"serviceName": "SGX Quote Verification Service", "version": "v4.0.0", "gitHash": "3b1f7a2",
+//      "buildDate": "2021-06-01T00:00:00Z"},
+//    "startedTime": "2021-06-15T08:00:00Z",
+//    "uptime": "2h0m0s",
+//    "maintenance": {"enabled": false, "inFlight": 0},
+//    "dependencies": [{"name": "SCS", "url": "https://scs.com:9000/scs/sgx/certification/v1/", "reachable": true,
+//      "host": "scs.com:9000", "breakerState": "closed", "requests": 1200, "failures": 3}],
+//    "verifications": {
+//      "since": "2021-06-15T08:00:00Z",
+//      "total": 1203,
+//      "verified": 1200,
+//      "failed": 3,
+//      "tcbLevels": {"UpToDate": 1150, "SWHardeningNeeded": 50},
+//      "failedSteps": {"pck_chain": 3},
+//      "recent": [{"id": "4f4a8bd5-5f8a-4a5b-9b0e-2e7c1d3f6a10", "createdTime": "2021-06-15T09:59:58Z",
+//        "status": "verified", "message": "SGX ECDSA Quote Verification is Successful",
+//        "enclaveMeasurement": "a5f5a5...", "tcbLevel": "UpToDate", "enclaveDebugMode": false}]
+//    },
+//    "policies": {"allowDebugEnclaves": false, "requirePlatformEnrollment": true, "requireRegisteredEnclaves": false,
+//      "allowedFmspcs": ["20606a000000"], "allowedSgxTypes": null, "tcbStatusVerdicts": ["OutOfDate=warn"], "ipAllowList": null, "ipDenyList": null,
+//      "customClaimsSignature": {"file": "/etc/sqvs/custom-claims.yml", "author": "alice",
+//        "signature": "eyJhbGciOiJFUzI1NiIsImtpZCI6ImFsaWNlIn0..MEUCIQ..."}},
+//    "collateral": {
+//      "importedPckCrls": [{"ca": "processor", "file": "/etc/sqvs/crls/processor.crl", "number": 2,
+//        "thisUpdate": "2021-06-14T00:00:00Z", "nextUpdate": "2021-07-14T00:00:00Z", "revoked": 12}],
+//      "enrolledPlatforms": [{"fmspc": "20606a000000", "pceId": "0000", "description": "rack 12",
+//        "enrolledTime": "2021-06-01T10:00:00Z", "collateral": {"tcbInfoNextUpdate": "2021-07-14T00:00:00Z",
+//        "pinnedTime": "2021-06-14T00:05:00Z"}}]
+//    }
+//  }
+// ---
+
+// swagger:operation POST /v1/admin/platforms Admin enrollPlatform
+// ---
+// description: |
+//   Enrolls a platform, identified by its FMSPC and PCE ID, whose quotes are expected to be verified. The
+//   current TCB info of the platform is fetched from SCS and pinned to it, verifications of its quotes use
+//   the pinned TCB info until its next update, when the current one is pinned again. Enrolling a platform
+//   again refreshes its pinned collateral. When SQVS_REQUIRE_PLATFORM_ENROLLMENT is true, quotes from
+//   platforms that are not enrolled are rejected, in any case they are reported to the security log.
+//   Requires the Administrator role.
+//
+// security:
+//  - bearerAuth: []
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// parameters:
+// - name: request body
+//   required: true
+//   in: body
+//   schema:
+//     "$ref": "#/definitions/PlatformEnrollmentRequest"
+// responses:
+//   '201':
+//     description: Successfully enrolled the platform.
+//   '200':
+//     description: The platform was already enrolled, its collateral was pinned again.
+//   '400':
+//     description: Invalid FMSPC or PCE ID.
+//   '404':
+//     description: No store is configured.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/admin/platforms
+// x-sample-call-input: |
+//  {
+//    "fmspc": "00906ea10000",
+//    "pceId": "0000",
+//    "description": "Rack 12 compute nodes"
+//  }
+// x-sample-call-output: |
+//  {
+//    "fmspc": "00906ea10000",
+//    "pceId": "0000",
+//    "description": "Rack 12 compute nodes",
+//    "enrolledTime": "2021-06-15T10:00:00Z",
+//    "collateral": {
+//      "tcbInfoNextUpdate": "2021-07-15T10:00:00Z",
+//      "pinnedTime": "2021-06-15T10:00:00Z"
+//    }
+//  }
+// ---
+
+// swagger:operation GET /v1/admin/platforms Admin listEnrolledPlatforms
+// ---
+// description: |
+//   Lists the enrolled platforms along with the collateral pinned for them, a page at a time, ordered by
+//   FMSPC and PCE ID unless sorted otherwise. The total number of platforms matching the filters is returned
+//   in the X-Total-Count header and the first, previous, next and last pages are linked in the Link header.
+//   A single platform is retrieved with GET /v1/admin/platforms/{fmspc}/{pceId} and unenrolled with
+//   DELETE /v1/admin/platforms/{fmspc}/{pceId}. Requires the Administrator role.
+//
+// security:
+//  - bearerAuth: []
+// produces:
+// - application/json
+// parameters:
+// - name: limit
+//   description: Number of platforms of the page, 1 to 1000.
+//   in: query
+//   type: integer
+// - name: offset
+//   description: Number of platforms skipped, or the cursor parameter of a Link header instead.
+//   in: query
+//   type: integer
+// - name: sort
+//   description: Field the platforms are sorted by, fmspc, pceId or enrolledTime, descending with a leading "-".
+//   in: query
+//   type: string
+// - name: fmspc
+//   description: Lists the platforms of the FMSPC only. pceId filters the platforms likewise.
+//   in: query
+//   type: string
+// - name: If-None-Match
+//   description: ETag of the response held by the client, the response is not transferred again while it matches.
+//   in: header
+//   type: string
+// responses:
+//   '200':
+//     description: Successfully listed the enrolled platforms.
+//   '304':
+//     description: The response is unchanged since the ETag of the If-None-Match header.
+//   '400':
+//     description: Invalid paging, sort or filter query parameter.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/admin/platforms
+// ---
+
+// swagger:operation POST /v1/admin/enclaves Admin importEnclaveIdentities
+// ---
+// description: |
+//   Registers the enclaves allowed to attest from their SIGSTRUCT, the enclave signature structure written
+//   by sgx_sign, or from the signed enclave binaries embedding it. The MRENCLAVE, MRSIGNER, ISVPRODID, ISVSVN
+//   and attributes of each enclave are read from its SIGSTRUCT once its signature is verified. The file is the
+//   raw request body for application/octet-stream, described by the "description" query parameter, or any
+//   number of "enclave" form files for multipart/form-data, described by their file name unless a
+//   "description" form value is provided. Either all enclaves of the request are registered or, when any file
+//   is invalid, none is. Importing an enclave again replaces its registration. When
+//   SQVS_REQUIRE_REGISTERED_ENCLAVES is true, quotes are rejected in the policy step unless their enclave is
+//   registered, has the registered ISVPRODID, at least the registered ISVSVN and the registered attributes
+//   under the registered attributes mask. The enclaves can also be imported with sqvs enclaves import.
+//   Requires the Administrator role.
+//
+// security:
+//  - bearerAuth: []
+// consumes:
+// - application/octet-stream
+// - multipart/form-data
+// produces:
+// - application/json
+// parameters:
+// - name: enclave
+//   in: formData
+//   type: file
+//   description: SIGSTRUCT file or signed enclave binary, may be repeated.
+// - name: description
+//   in: query
+//   type: string
+//   description: Description of the enclave of an application/octet-stream request.
+// responses:
+//   '200':
+//     description: Successfully registered the enclaves.
+//   '400':
+//     description: Invalid SIGSTRUCT or signed enclave.
+//   '404':
+//     description: No store is configured.
+//   '415':
+//     description: Unsupported Content-Type.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/admin/enclaves
+// x-sample-call-input: |
+//  curl -F enclave=@enclave.signed.so -F enclave=@enclave.sigstruct ...
+// x-sample-call-output: |
+//  [
+//    {
+//      "mrEnclave": "a5f5a51d3c5a8e4d3b9f1c6b3e0f2a7d9c8b1e4f6a2d0c3b5e7f9a1c2d4e6f80",
+//      "mrSigner": "83d719e77deaca1470f6baf62a4d774303c899db69020f9c70ee1dfc08c7ce9e",
+//      "isvProdId": 1,
+//      "isvSvn": 2,
+//      "attributes": "04000000000000000300000000000000",
+//      "attributesMask": "fdffffffffffffff0000000000000000",
+//      "description": "enclave.signed.so",
+//      "registeredTime": "2021-06-15T10:00:00Z"
 //    }
+//  ]
+// ---
+
+// swagger:operation GET /v1/admin/enclaves Admin listEnclaveIdentities
+// ---
+// description: |
+//   Lists the registered enclaves, a page at a time, ordered by MRENCLAVE and MRSIGNER unless sorted
+//   otherwise. The total number of enclaves matching the filters is returned in the X-Total-Count header and
+//   the first, previous, next and last pages are linked in the Link header. An enclave is removed with
+//   DELETE /v1/admin/enclaves/{mrEnclave}/{mrSigner}. Requires the Administrator role.
+//
+// security:
+//  - bearerAuth: []
+// produces:
+// - application/json
+// parameters:
+// - name: limit
+//   description: Number of enclaves of the page, 1 to 1000.
+//   in: query
+//   type: integer
+// - name: offset
+//   description: Number of enclaves skipped, or the cursor parameter of a Link header instead.
+//   in: query
+//   type: integer
+// - name: sort
+//   description: Field the enclaves are sorted by, mrEnclave, mrSigner or registeredTime, descending with a leading "-".
+//   in: query
+//   type: string
+// - name: mrSigner
+//   description: Lists the enclaves of the signer only. mrEnclave filters the enclaves likewise.
+//   in: query
+//   type: string
+// - name: If-None-Match
+//   description: ETag of the response held by the client, the response is not transferred again while it matches.
+//   in: header
+//   type: string
+// responses:
+//   '200':
+//     description: Successfully listed the registered enclaves.
+//   '304':
+//     description: The response is unchanged since the ETag of the If-None-Match header.
+//   '400':
+//     description: Invalid paging, sort or filter query parameter.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/admin/enclaves
+// ---
+
+// swagger:operation GET /v1/admin/revocations Admin listRevocations
+// ---
+// description: |
+//   Lists the revocations, a page at a time, oldest first unless sorted otherwise. The total number of
+//   revocations matching the filters is returned in the X-Total-Count header and the first, previous, next
+//   and last pages are linked in the Link header. Requires the Administrator role and
+//   SQVS_ENABLE_RESULT_REVOCATION.
+//
+// security:
+//  - bearerAuth: []
+// produces:
+// - application/json
+// parameters:
+// - name: limit
+//   description: Number of revocations of the page, 1 to 1000.
+//   in: query
+//   type: integer
+// - name: offset
+//   description: Number of revocations skipped, or the cursor parameter of a Link header instead.
+//   in: query
+//   type: integer
+// - name: sort
+//   description: revokedTime, or -revokedTime for the newest first.
+//   in: query
+//   type: string
+// - name: keyId
+//   description: Lists the revocations of the results signed with the key only.
+//   in: query
+//   type: string
+// responses:
+//   '200':
+//     description: Successfully listed the revocations.
+//   '400':
+//     description: Invalid paging, sort or filter query parameter.
+//   '404':
+//     description: Result revocation is not enabled.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/admin/revocations
+// ---
+
+// swagger:operation POST /v1/admin/revocations Admin revokeResults
+// ---
+// description: |
+//   Revokes the signed quote verification results issued earlier, after an incident such as a compromised
+//   signing key or bad collateral. The results revoked are those matching every criterion set: issued at or
+//   after "issuedAfter" and before "issuedBefore", signed with "keyId" or of one of "quoteHashes", the
+//   SHA-256 or SHA-384 digests of the quotes. Revocations are published at /v1/.well-known/revocations.json.
+//   Requires the Administrator role and SQVS_ENABLE_RESULT_REVOCATION.
+//
+// security:
+//  - bearerAuth: []
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// parameters:
+// - name: request body
+//   required: true
+//   in: body
+//   schema:
+//     "$ref": "#/definitions/RevocationRequest"
+// responses:
+//   '201':
+//     description: Successfully revoked the results.
+//   '400':
+//     description: Missing reason or criteria, or invalid quote hash.
+//   '404':
+//     description: Result revocation is not enabled.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/admin/revocations
+// x-sample-call-input: |
+//  {
+//    "reason": "Response signing key compromised",
+//    "issuedAfter": "2021-07-01T00:00:00Z",
+//    "issuedBefore": "2021-07-14T12:00:00Z",
+//    "keyId": "kM2Gc8v0WnY3b1tJ6q8xHc2oZp4dY9sFqL1uN7eR3aE"
 //  }
+// x-sample-call-output: |
+//  {
+//    "id": "7f5b0c1e-2a4d-4e8b-9c3f-1d2e3f4a5b6c",
+//    "reason": "Response signing key compromised",
+//    "revokedTime": "2021-07-14T12:05:00Z",
+//    "issuedAfter": "2021-07-01T00:00:00Z",
+//    "issuedBefore": "2021-07-14T12:00:00Z",
+//    "keyId": "kM2Gc8v0WnY3b1tJ6q8xHc2oZp4dY9sFqL1uN7eR3aE"
+//  }
+// ---
+
+// swagger:operation GET /v1/.well-known/revocations.json Keys getRevocationList
+// ---
+// description: |
+//   Publishes the revocations of signed results, signed with the response signing key. "revocationList" is
+//   the base64 encoded list, the signature covers it the way it covers the quoteData of signed results.
+//   Relying parties verify the list against the certificate chain and check the "IssuedAt", "keyId" and
+//   "QuoteHashes" of the results they hold against it, the intel/isecl/sqvs/v4/revocation package does
+//   both. The endpoint does not require a token.
+//
+// produces:
+// - application/json
+// responses:
+//   '200':
+//     description: Successfully retrieved the revocation list.
+//   '404':
+//     description: Result revocation is not enabled.
 //
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/.well-known/revocations.json
+// x-sample-call-output: |
+//  {
+//    "revocationList": "eyJpc3N1ZWRBdCI6IjIwMjEtMDctMTRUMTI6MTA6MDBaIiwicmV2b2NhdGlvbnMiOltdfQ==",
+//    "signature": "Yk9x3...",
+//    "certificateChain": "-----BEGIN CERTIFICATE-----\nMIIEDTCC...\n-----END CERTIFICATE-----\n",
+//    "keyId": "kM2Gc8v0WnY3b1tJ6q8xHc2oZp4dY9sFqL1uN7eR3aE"
+//  }
+// ---
+
+// swagger:operation PUT /v1/admin/payload-capture Admin startPayloadCapture
+// ---
+// description: |
+//   Starts capturing the requests and their responses for "duration", at most
+//   SQVS_PAYLOAD_CAPTURE_MAX_DURATION, to reproduce the failures of a client. Only the requests of the
+//   "client" address and to the paths under "pathPrefix" are captured when they are set. Captures are
+//   appended as JSON lines to SQVS_PAYLOAD_CAPTURE_FILE with bearer tokens, session tokens, cookies, request
+//   signatures, raw quotes and released keys redacted, along with SQVS_PAYLOAD_CAPTURE_REDACT_FIELDS; bodies
+//   other than JSON and text are replaced by their size. Starting a capture replaces the running one, GET
+//   returns it and DELETE stops it. Requires the PayloadCapture role and SQVS_PAYLOAD_CAPTURE_ENABLED.
+//
+// security:
+//  - bearerAuth: []
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// parameters:
+// - name: request body
+//   required: true
+//   in: body
+//   schema:
+//     "$ref": "#/definitions/PayloadCaptureRequest"
+// responses:
+//   '200':
+//     description: Successfully started the capture.
+//   '400':
+//     description: Invalid capture duration.
+//   '404':
+//     description: Payload capture is not enabled.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/admin/payload-capture
+// x-sample-call-input: |
+//  {"duration": "15m", "client": "10.0.4.17", "pathPrefix": "/svs/v2/"}
+// x-sample-call-output: |
+//  {
+//    "running": true,
+//    "session": {"started": "2021-07-14T12:00:00Z", "until": "2021-07-14T12:15:00Z", "client": "10.0.4.17",
+//      "pathPrefix": "/svs/v2/", "startedBy": "admin", "file": "/var/log/sqvs/sqvs-payload-capture.log",
+//      "captured": 0}
+//  }
+// ---
+
+// swagger:operation GET /v1/admin/capacity Admin getCapacity
+// ---
+// description: |
+//   Reports the verification load of the replica for HPA and KEDA scalers to scale SQVS on verification
+//   pressure rather than CPU. "inFlight" and "queued" are the requests processed and waiting under
+//   SQVS_MAX_CONCURRENT_REQUESTS and SQVS_MAX_QUEUED_REQUESTS, "utilization" their share of
+//   "maxConcurrent" and "averageUtilization" its moving average over about a minute. "suggestedReplicas" is
+//   the replica count bringing the average utilization to SQVS_TARGET_UTILIZATION, given the current
+//   "replicas" count the scaler passes, 1 by default. "maxQueued" is left out when SQVS_MAX_QUEUED_REQUESTS is
+//   -1, queueing any number of requests, and is 0 when the requests beyond "maxConcurrent" are rejected at
+//   once. The endpoint is not subject to maintenance mode nor admission control. Requires the CapacityReader
+//   role.
+//
+// security:
+//  - bearerAuth: []
+// produces:
+// - application/json
+// parameters:
+// - name: replicas
+//   description: Number of replicas currently serving the verification requests.
+//   in: query
+//   type: integer
+// responses:
+//   '200':
+//     description: Successfully retrieved the capacity report.
+//   '400':
+//     description: Invalid replicas count.
+//   '404':
+//     description: Admission control is not enabled.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/admin/capacity?replicas=3
+// x-sample-call-output: |
+//  {
+//    "maxConcurrent": 100,
+//    "inFlight": 100,
+//    "maxQueued": 200,
+//    "queued": 40,
+//    "averageLoad": 84,
+//    "utilization": 1.4,
+//    "averageUtilization": 0.84,
+//    "targetUtilization": 0.7,
+//    "replicas": 3,
+//    "suggestedReplicas": 4
+//  }
+// ---
 
+// swagger:operation POST /v2/sessions Quote openVerificationSession
+// ---
+// description: |
+//   Opens a verification session, a freshness challenge for the quote of an enclave. The enclave places
+//   the SHA-256 digest of the base64 decoded "nonce" at the start of the report data of its quote, as
+//   "reportDataBinding" declares, and the client submits the quote to /v2/sgx_qv_verify_quote with the
+//   "sessionToken" before "expiresAt" (SQVS_SESSION_TTL after the session is opened, 5 minutes by
+//   default). The session token is a JWT signed with the response signing key published at
+//   /v1/.well-known/jwks.json, whose "jti" is the "sessionId" and "nonce" the session nonce. It is bound to
+//   the subject of the bearer token the session was opened with. Sessions can be opened and used on
+//   different replicas signing with the same key. Requires the QuoteVerifier role.
+//   Verification sessions are experimental, the endpoint is not found unless the verification-sessions
+//   feature flag is enabled (SQVS_FEATURES).
+//
+// security:
+//  - bearerAuth: []
+// produces:
+// - application/json
+// responses:
+//   '201':
+//     description: Successfully opened the verification session.
+//   '404':
+//     description: The verification-sessions feature flag is disabled.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v2/sessions
+// x-sample-call-output: |
+//  {
+//    "sessionId": "6b1d3c57-8a3e-4d0c-9f55-2c3a1e7b9d40",
+//    "sessionToken": "eyJhbGciOiJSUzM4NCIsImtpZCI6IjFkYz...",
+//    "nonce": "q2C7pVNzKZ1hPY1B3a0sR7u2JxwH8E0zqS9mT4dLc1o=",
+//    "expiresAt": "2021-07-14T10:05:00Z",
+//    "reportDataBinding": {
+//      "hash": "sha256",
+//      "inputs": [{"name": "sessionNonce", "value": "q2C7pVNzKZ1hPY1B3a0sR7u2JxwH8E0zqS9mT4dLc1o="}]
+//    }
+//  }
+// ---
+
+// swagger:operation GET /v1/admin/config/history Admin getConfigHistory
+// ---
+// description: |
+//   Returns the audit of the configuration changes, newest first. Changes are recorded by the setup tasks
+//   updating config.yml from the environment ("setup"), by the service when config.yml is edited while it runs
+//   ("file") and by the admin API changes of maintenance mode ("api"), with the "author" of the change, the
+//   subject of the administrator token or the client address. Each change is the setting, by its path in
+//   config.yml, and its value before and after, empty when unset, and is logged as a security event of the
+//   config category. "pendingChanges" are the changes of config.yml not in effect until the service is
+//   restarted. Requires the Administrator role.
+//
+// security:
+//  - bearerAuth: []
+// produces:
+// - application/json
+// parameters:
+// - name: since
+//   description: RFC 3339 time of the oldest change returned.
+//   in: query
+//   type: string
+// - name: limit
+//   description: Maximum number of changes returned.
+//   in: query
+//   type: integer
+// responses:
+//   '200':
+//     description: Successfully retrieved the configuration history.
+//   '400':
+//     description: Invalid since or limit query parameter.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/admin/config/history?limit=1
+// x-sample-call-output: |
+//  {
+//    "pendingChanges": [],
+//    "changes": [
+//      {
+//        "time": "2021-07-14T10:12:03Z",
+//        "source": "api",
+//        "author": "admin@sqvs",
+//        "changes": [
+//          {
+//            "setting": "maintenance.enabled",
+//            "before": "false",
+//            "after": "true"
+//          }
+//        ]
+//      }
+//    ]
+//  }
+// ---
+
+// swagger:operation GET /v1/collateral/root-ca Collateral getCollateralRootCA
+// ---
+// description: |
+//   Returns the trusted SGX root CA quotes are verified against, honouring SGX_TRUSTED_ROOT_CA_PATH and the
+//   root key pins, so relying parties verifying quotes locally anchor their verification in the same root.
+//   Requires the CollateralReader role.
+//
+// security:
+//  - bearerAuth: []
+// produces:
+// - application/x-pem-file
+// parameters:
+// - name: If-None-Match
+//   description: ETag of the response held by the client, the response is not transferred again while it matches.
+//   in: header
+//   type: string
+// responses:
+//   '200':
+//     description: Successfully retrieved the trusted SGX root CA.
+//   '304':
+//     description: The response is unchanged since the ETag of the If-None-Match header.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/collateral/root-ca
+// ---
+
+// swagger:operation GET /v1/collateral/tcb/{fmspc} Collateral getCollateralTcbInfo
+// ---
+// description: |
+//   Returns the TCB info of the FMSPC, with its issuer chain in the SGX-TCB-Info-Issuer-Chain header, for
+//   relying parties verifying quotes locally to source collateral through SQVS. The TCB info pinned for an
+//   enrolled platform of the FMSPC is served while current, otherwise the TCB info of SCS once its signature
+//   is verified. The SQVS-Collateral-Source header is "enrollment" or "scs". Collateral is cached for
+//   SQVS_COLLATERAL_CACHE_TTL, never past its next update, and Cache-Control max-age is the time it remains
+//   cached. Requires the CollateralReader role.
+//
+// security:
+//  - bearerAuth: []
+// produces:
+// - application/json
+// parameters:
+// - name: fmspc
+//   description: FMSPC of the platform, 12 hexadecimal characters.
+//   in: path
+//   required: true
+//   type: string
+// - name: If-None-Match
+//   description: ETag of the response held by the client, the response is not transferred again while it matches.
+//   in: header
+//   type: string
+// responses:
+//   '200':
+//     description: Successfully retrieved the TCB info.
+//   '304':
+//     description: The response is unchanged since the ETag of the If-None-Match header.
+//   '400':
+//     description: Invalid FMSPC.
+//   '502':
+//     description: TCB info could not be fetched from SCS or has an invalid signature.
+//   '504':
+//     description: SCS did not respond in time.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/collateral/tcb/00906ed50000
+// ---
+
+// swagger:operation GET /v1/collateral/qe-identity Collateral getCollateralQeIdentity
+// ---
+// description: |
+//   Returns the QE identity of SCS, once its signature is verified, with its issuer chain in the
+//   SGX-Qe-Identity-Issuer-Chain header. It is cached like the TCB info. Requires the CollateralReader role.
+//
+// security:
+//  - bearerAuth: []
+// produces:
+// - application/json
+// parameters:
+// - name: If-None-Match
+//   description: ETag of the response held by the client, the response is not transferred again while it matches.
+//   in: header
+//   type: string
+// responses:
+//   '200':
+//     description: Successfully retrieved the QE identity.
+//   '304':
+//     description: The response is unchanged since the ETag of the If-None-Match header.
+//   '502':
+//     description: QE identity could not be fetched from SCS or has an invalid signature.
+//   '504':
+//     description: SCS did not respond in time.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/collateral/qe-identity
+// ---
+
+// swagger:operation GET /v1/collateral/pck-crl/{ca} Collateral getCollateralPckCrl
+// ---
+// description: |
+//   Returns the DER encoded PCK CRL of the processor or platform CA. The CRL imported with "sqvs crl import" is
+//   served while current, otherwise the CRL of the SQVS_CRL_URL_OVERRIDES distribution point or of SCS, whose
+//   issuer chain, returned in the SGX-PCK-CRL-Issuer-Chain header, is verified against the trusted SGX root
+//   CA. The SQVS-Collateral-Source header is "imported", "override" or "scs". It is cached like the TCB info.
+//   Requires the CollateralReader role.
+//
+// security:
+//  - bearerAuth: []
+// produces:
+// - application/pkix-crl
+// parameters:
+// - name: ca
+//   description: PCK CA of the CRL, processor or platform.
+//   in: path
+//   required: true
+//   type: string
+// - name: If-None-Match
+//   description: ETag of the response held by the client, the response is not transferred again while it matches.
+//   in: header
+//   type: string
+// responses:
+//   '200':
+//     description: Successfully retrieved the PCK CRL.
+//   '304':
+//     description: The response is unchanged since the ETag of the If-None-Match header.
+//   '400':
+//     description: Invalid PCK CA.
+//   '502':
+//     description: CRL could not be fetched or its issuer chain is invalid.
+//   '504':
+//     description: The CRL distribution point did not respond in time.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/collateral/pck-crl/processor
+// ---
+
+// swagger:operation POST /bootstrap Bootstrap bootstrap
+// ---
+// description: |
+//   Runs setup with the trust material of the request, so sqvs is provisioned without shell access. The
+//   bootstrap API is served by "sqvs bootstrap" on a loopback address, 127.0.0.1:12001 by default, only until
+//   setup completes: it is not served once config.yml, the TLS certificate and the trusted SGX root CA exist,
+//   and later requests are refused with 410. "cmsRootCa" is trusted instead of downloading the CMS root CA,
+//   "aasBaseUrl" and "bearerToken" are the AAS_API_URL and BEARER_TOKEN of setup. "settings" are the other
+//   setup variables, those prefixed with SQVS_ and CMS_BASE_URL, CMS_TLS_CERT_SHA384, SCS_BASE_URL, SAN_LIST,
+//   SAN_AUTO_DETECT, KEY_PATH, CERT_PATH, SGX_TRUSTED_ROOT_CA_PATH, SIGN_QUOTE_RESPONSE, USE_PSS_PADDING and
+//   RESPONSE_SIGNING_KEY_LENGTH. The response is the summary of the setup tasks. The request must carry the
+//   one-time token printed by "sqvs bootstrap" as its bearer token and a JSON body.
+//
+// security:
+//  - bearerAuth: []
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// parameters:
+// - name: request body
+//   required: true
+//   in: body
+//   schema:
+//     "$ref": "#/definitions/BootstrapRequest"
+// responses:
+//   '200':
+//     description: Setup completed, sqvs can be started.
+//   '400':
+//     description: Invalid CMS root CA, AAS URL, bearer token or setting.
+//   '401':
+//     description: The bootstrap token is missing or invalid.
+//   '403':
+//     description: The request was not sent from the local host.
+//   '410':
+//     description: Bootstrap has already completed.
+//   '415':
+//     description: The request body is not JSON.
+//   '500':
+//     description: A setup task failed, the request can be sent again.
+//
+// x-sample-call-endpoint: http://127.0.0.1:12001/bootstrap
+// x-sample-call-input: |
+//  {
+//    "cmsRootCa": "-----BEGIN CERTIFICATE-----\nMIIELDCC...\n-----END CERTIFICATE-----\n",
+//    "aasBaseUrl": "https://aas.com:8444/aas/v1",
+//    "bearerToken": "eyJhbGciOiJSUzM4NCIs...",
+//    "settings": {"CMS_BASE_URL": "https://cms.com:8445/cms/v1", "CMS_TLS_CERT_SHA384": "7ed1b2...",
+//      "SCS_BASE_URL": "https://scs.com:9000/scs/sgx/certification/v1", "SAN_LIST": "sqvs.com"}
+//  }
+// x-sample-call-output: |
+//  {
+//    "summary": {"results": [{"task": "download_cert", "status": "changed", "durationMs": 412},
+//      {"task": "update_service_config", "status": "changed", "durationMs": 3},
+//      {"task": "create_signing_key_pair", "status": "changed", "durationMs": 655}],
+//      "changed": 3, "unchanged": 0, "skipped": 0, "failed": 0}
+//  }
 // ---
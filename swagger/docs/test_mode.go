@@ -0,0 +1,67 @@
+/*
+ *  Copyright (C) 2021 Intel Corporation
+ *  SPDX-License-Identifier: BSD-3-Clause
+ */
+
+package docs
+
+// swagger:operation POST /test/v1/sgx_qv_verify_quote TestMode testModeVerifyQuote
+// ---
+// description: |
+//   TEST MODE ONLY. Returns deterministic canned verdicts for test quotes, for relying-party integration
+//   tests without SGX hardware or live collateral. The route is only served by builds made with the
+//   sqvs_testmode tag (make sqvs-testmode) when SQVS_ENABLE_TEST_MODE is set, production builds never
+//   serve it. Every response carries the X-SQVS-Test-Mode: true header and successful ones "TestMode".
+//   A test quote is the ASCII "SQVS-TEST-QUOTE:" followed by the verdict, ok, sw_hardening_needed,
+//   configuration_needed, out_of_date, revoked or invalid_signature, and optionally padded with zeros or
+//   a newline. It is sent like a real quote to /v1/sgx_qv_verify_quote, raw quotes being padded to 1020
+//   bytes. The verdicts are shaped like the ones of real quotes: revoked fails the tcb_evaluation step with
+//   400 and invalid_signature the quote_signature step with 500. Any other quote is refused with 400.
+//   Requests are authenticated, admitted, signature checked and counted against the quotas like those of
+//   /v1/sgx_qv_verify_quote.
+//
+// security:
+//  - bearerAuth: []
+// consumes:
+//  - application/json
+//  - application/octet-stream
+//  - multipart/form-data
+// produces:
+//  - application/json
+// responses:
+//   '200':
+//     description: Canned verdict of the test quote.
+//     schema:
+//       "$ref": "#/definitions/SGXResponse"
+//   '400':
+//     description: Not a test quote, unknown verdict or canned verification failure.
+//   '500':
+//     description: Canned verification failure.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/test/v1/sgx_qv_verify_quote
+// x-sample-call-input: |
+//  {
+//    "quote": "U1FWUy1URVNULVFVT1RFOm9r"
+//  }
+// x-sample-call-output: |
+//  {
+//    "Message": "SGX_QL_QV_RESULT_OK",
+//    "EnclaveIssuer": "5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e",
+//    "EnclaveMeasurement": "7e7e7e7e7e7e7e7e7e7e7e7e7e7e7e7e7e7e7e7e7e7e7e7e7e7e7e7e7e7e7e7e",
+//    "EnclaveIssuerProdID": "00",
+//    "IsvSvn": "01",
+//    "TcbLevel": "UpToDate",
+//    "EnclaveDebugMode": false,
+//    "VerificationSteps": [
+//      {"Name": "quote_parse", "Status": "passed", "Details": "test mode"},
+//      {"Name": "pck_chain", "Status": "passed", "Details": "test mode"},
+//      {"Name": "crl_check", "Status": "passed", "Details": "test mode"},
+//      {"Name": "tcb_evaluation", "Status": "passed", "Details": "TCB status UpToDate"},
+//      {"Name": "qe_identity", "Status": "passed", "Details": "test mode"},
+//      {"Name": "quote_signature", "Status": "passed", "Details": "test mode"},
+//      {"Name": "qe_report_signature", "Status": "passed", "Details": "test mode"},
+//      {"Name": "policy", "Status": "passed", "Details": "test mode"}
+//    ],
+//    "TestMode": true
+//  }
+// ---
@@ -0,0 +1,272 @@
+/*
+ *  Copyright (C) 2021 Intel Corporation
+ *  SPDX-License-Identifier: BSD-3-Clause
+ */
+
+package docs
+
+import (
+	"intel/isecl/sqvs/v4/resource"
+	"intel/isecl/sqvs/v4/types"
+)
+
+// Verifications response payload
+// swagger:response Verifications
+type VerificationsInfo struct {
+	// in:body
+	Body types.Verifications
+}
+
+// Verification response payload
+// swagger:response Verification
+type VerificationInfo struct {
+	// in:body
+	Body types.Verification
+}
+
+// swagger:parameters ReverifyVerification
+type ReverificationRequest struct {
+	// in:body
+	Body resource.ReverificationRequest
+}
+
+// Reverification response payload
+// swagger:response Reverification
+type ReverificationInfo struct {
+	// in:body
+	Body resource.ReverificationResponse
+}
+
+// Raw quote response payload
+// swagger:response RawQuote
+type RawQuoteInfo struct {
+	// in:body
+	Body resource.RawQuoteResponse
+}
+
+// swagger:operation GET /v1/verifications Verifications SearchVerifications
+// ---
+// description: |
+//   Lists the recorded quote verification outcomes, most recent first. Requires verification history to be
+//   enabled with SQVS_ENABLE_VERIFICATION_HISTORY.
+//   Results are paged with limit and offset, or with the opaque cursor returned in the Link header.
+//   The total number of matches is returned in the X-Total-Count header and the first, prev, next and
+//   last pages are linked in the Link header (RFC 5988).
+//
+// security:
+//  - bearerAuth: []
+// produces:
+//  - application/json
+// parameters:
+// - name: limit
+//   description: Maximum number of records returned, 1 to 1000. Defaults to 100.
+//   in: query
+//   type: integer
+// - name: offset
+//   description: Number of matching records to skip. Cannot be combined with cursor.
+//   in: query
+//   type: integer
+// - name: cursor
+//   description: Opaque cursor taken from a Link header.
+//   in: query
+//   type: string
+// - name: sort
+//   description: |
+//     Field to sort by, one of createdTime, status, enclaveIssuer, enclaveMeasurement or tcbLevel.
+//     Prefix with "-" for descending order. Defaults to -createdTime.
+//   in: query
+//   type: string
+// - name: status
+//   description: Only return verifications with this status, verified or failed.
+//   in: query
+//   type: string
+// - name: enclaveIssuer
+//   in: query
+//   type: string
+// - name: enclaveMeasurement
+//   in: query
+//   type: string
+// - name: enclaveIssuerProdId
+//   in: query
+//   type: string
+// - name: isvSvn
+//   in: query
+//   type: string
+// - name: tcbLevel
+//   in: query
+//   type: string
+// - name: enclaveDebugMode
+//   in: query
+//   type: boolean
+// - name: reverifiedFrom
+//   description: Only return the re-verifications of this verification.
+//   in: query
+//   type: string
+// - name: relyingParty
+//   description: Only return the verifications requested by this relying party with a signed request.
+//   in: query
+//   type: string
+// responses:
+//   '200':
+//     description: Successfully listed the verifications.
+//     schema:
+//       "$ref": "#/definitions/Verifications"
+//   '400':
+//     description: Invalid query parameter.
+//   '404':
+//     description: Verification history is not enabled.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/verifications?status=failed&limit=1
+// x-sample-call-output: |
+//  [
+//    {
+//      "id": "0b7f0e4c-6a4e-4f3c-9d55-1f6a8f1d2c3b",
+//      "createdTime": "2021-06-01T10:15:30.123456Z",
+//      "status": "failed",
+//      "message": "Cannot verify pck cert",
+//      "enclaveDebugMode": false
+//    }
+//  ]
+// ---
+
+// swagger:operation GET /v1/verifications/{id} Verifications RetrieveVerification
+// ---
+// description: |
+//   Retrieves a recorded quote verification outcome. Raw quotes kept with the verifications are only
+//   returned to auditors, by /v1/verifications/{id}/quote.
+//
+// security:
+//  - bearerAuth: []
+// produces:
+//  - application/json
+// parameters:
+// - name: id
+//   required: true
+//   in: path
+//   type: string
+// responses:
+//   '200':
+//     description: Successfully retrieved the verification.
+//     schema:
+//       "$ref": "#/definitions/Verification"
+//   '404':
+//     description: Verification not found or verification history is not enabled.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/verifications/5d2b3a1e-52c7-4b0e-8e0c-2a9b7d0f3e41
+// x-sample-call-output: |
+//  {
+//    "id": "5d2b3a1e-52c7-4b0e-8e0c-2a9b7d0f3e41",
+//    "createdTime": "2021-06-01T10:14:02.654321Z",
+//    "status": "verified",
+//    "message": "SGX_QL_QV_RESULT_OK",
+//    "enclaveIssuer": "83d719e77deaca1470f6baf62a4d774303c899db69020f9c70ee1dfc08c7ce9e",
+//    "enclaveMeasurement": "ad46749ed41ebaa2327252041ee746d3791a9f2431830fee0883f7993caf316a",
+//    "enclaveIssuerProdId": "00",
+//    "isvSvn": "00",
+//    "tcbLevel": "OutOfDate",
+//    "enclaveDebugMode": false
+//  }
+// ---
+
+// swagger:operation POST /v1/verifications/{id}/reverify Verifications ReverifyVerification
+// ---
+// description: |
+//   Re-verifies the raw quote kept with a recorded verification under the current collateral, the optional
+//   constraints and the optional result policy of the custom claims file named by "policy". Requires raw
+//   quote retention to be enabled with SQVS_RETAIN_RAW_QUOTES when the verification was recorded.
+//   The outcome is recorded as a follow-up verification linked to the original one by "reverifiedFrom" and
+//   returned with the result when the quote verified. A quote failing verification is a successful
+//   re-verification whose recorded status is failed.
+//
+// security:
+//  - bearerAuth: []
+// consumes:
+//  - application/json
+// produces:
+//  - application/json
+// parameters:
+// - name: id
+//   required: true
+//   in: path
+//   type: string
+// responses:
+//   '200':
+//     description: Successfully re-verified the quote.
+//     schema:
+//       "$ref": "#/definitions/ReverificationResponse"
+//   '400':
+//     description: Invalid request body or unknown result policy.
+//   '404':
+//     description: Verification not found or verification history is not enabled.
+//   '409':
+//     description: The raw quote of the verification was not retained.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/verifications/5d2b3a1e-52c7-4b0e-8e0c-2a9b7d0f3e41/reverify
+// x-sample-call-input: |
+//  {
+//    "policy": "payments"
+//  }
+// x-sample-call-output: |
+//  {
+//    "verification": {
+//      "id": "9c41e8d2-3f7a-4b61-a0d4-6e2f5b8c7a19",
+//      "createdTime": "2021-09-14T08:02:11.204518Z",
+//      "status": "verified",
+//      "message": "SGX_QL_QV_RESULT_OK",
+//      "enclaveIssuer": "83d719e77deaca1470f6baf62a4d774303c899db69020f9c70ee1dfc08c7ce9e",
+//      "enclaveMeasurement": "ad46749ed41ebaa2327252041ee746d3791a9f2431830fee0883f7993caf316a",
+//      "enclaveIssuerProdId": "00",
+//      "isvSvn": "00",
+//      "tcbLevel": "UpToDate",
+//      "enclaveDebugMode": false,
+//      "reverifiedFrom": "5d2b3a1e-52c7-4b0e-8e0c-2a9b7d0f3e41"
+//    },
+//    "result": {
+//      "Message": "SGX_QL_QV_RESULT_OK",
+//      "EnclaveIssuer": "83d719e77deaca1470f6baf62a4d774303c899db69020f9c70ee1dfc08c7ce9e",
+//      "EnclaveMeasurement": "ad46749ed41ebaa2327252041ee746d3791a9f2431830fee0883f7993caf316a",
+//      "EnclaveIssuerProdID": "00",
+//      "IsvSvn": "00",
+//      "TcbLevel": "UpToDate",
+//      "EnclaveDebugMode": false,
+//      "ResultPolicy": "payments"
+//    }
+//  }
+// ---
+
+// swagger:operation GET /v1/verifications/{id}/quote Verifications RetrieveRawQuote
+// ---
+// description: |
+//   Returns the raw quote kept with a recorded verification, decrypted. Raw quotes are retained, for the
+//   tenants of SQVS_RAW_QUOTE_TENANTS, when SQVS_RETAIN_RAW_QUOTES is enabled and encrypted at rest with the
+//   key of SQVS_RAW_QUOTE_KEY_FILE or the Vault transit key SQVS_RAW_QUOTE_VAULT_TRANSIT_KEY. Only holders of
+//   the QuoteAuditor role may retrieve them, every retrieval is logged to the security log.
+//
+// security:
+//  - bearerAuth: []
+// produces:
+//  - application/json
+// parameters:
+// - name: id
+//   required: true
+//   in: path
+//   type: string
+// responses:
+//   '200':
+//     description: Successfully retrieved the raw quote.
+//     schema:
+//       "$ref": "#/definitions/RawQuoteResponse"
+//   '401':
+//     description: Missing or invalid token.
+//   '403':
+//     description: The caller does not have the QuoteAuditor role.
+//   '404':
+//     description: Verification not found, its raw quote was not retained or verification history is not enabled.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/verifications/5d2b3a1e-52c7-4b0e-8e0c-2a9b7d0f3e41/quote
+// x-sample-call-output: |
+//  {
+//    "id": "5d2b3a1e-52c7-4b0e-8e0c-2a9b7d0f3e41",
+//    "quote": "AwACAAAAAAAHAAwAk5pyM/ecTKmUCg2zlX8GB..."
+//  }
+// ---
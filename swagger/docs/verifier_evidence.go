@@ -0,0 +1,55 @@
+/*
+ *  Copyright (C) 2021 Intel Corporation
+ *  SPDX-License-Identifier: BSD-3-Clause
+ */
+
+package docs
+
+import "intel/isecl/sqvs/v4/resource"
+
+// VerifierEvidence response payload
+// swagger:response VerifierEvidence
+type VerifierEvidenceInfo struct {
+	// in:body
+	Body resource.VerifierEvidence
+}
+
+// swagger:operation GET /v1/verifier-evidence VerifierEvidence GetVerifierEvidence
+// ---
+// description: |
+//   Returns the SGX quote of the enclave SQVS runs in, so relying parties can check with another verifier
+//   that SQVS runs where claimed. Requires SQVS_VERIFIER_QUOTE_SOURCE to be configured.
+//   The report data of the quote is the SHA-512 digest of the DER encoded TLS certificate of SQVS followed
+//   by the nonce. Without a nonce, the same evidence is returned until it is older than
+//   SQVS_VERIFIER_EVIDENCE_REFRESH.
+//
+// security:
+//  - bearerAuth: []
+// produces:
+//  - application/json
+// parameters:
+// - name: nonce
+//   description: Client chosen value, up to 64 characters, bound in the report data for freshness.
+//   in: query
+//   type: string
+// responses:
+//   '200':
+//     description: Successfully generated the verifier evidence.
+//     schema:
+//       "$ref": "#/definitions/VerifierEvidence"
+//   '400':
+//     description: Nonce is too long.
+//   '404':
+//     description: Verifier evidence is not enabled.
+//   '503':
+//     description: The quote of the SQVS enclave could not be generated.
+//
+// x-sample-call-endpoint: https://svs.com:12000/svs/v1/verifier-evidence?nonce=4f1c2a
+// x-sample-call-output: |
+//  {
+//    "quote": "AwACAAAAAAAFAAoAk5pyM/ecTKmUCg2zlX8GB1ePHvTyaJq7KWtZvEB5i5QAAAAAAgIAAAAAAAAAAAAAAAAAAAAAAAAAAAAA...",
+//    "tlsCertSha384": "4c8f2e0b7a1d9c6e3f5b2a8d0e7c4f1a9b6d3e0c8f5a2b7d4e1c9f6a3b0d8e5c2f7a4b1d9e6c3f0a8b5d2e7c4f1a9b6d",
+//    "nonce": "4f1c2a",
+//    "generatedTime": "2021-06-01T10:20:11.482913Z"
+//  }
+// ---
@@ -0,0 +1,93 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package tasks
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// LoadAnswers reads the inputs of the setup tasks from a YAML answers file. The file maps the environment
+// variables read by the tasks to their values, either at the top level or grouped by task name:
+//
+//	CMS_BASE_URL: https://cms.com:8445/cms/v1/
+//	update_service_config:
+//	  SQVS_PORT: 12000
+func LoadAnswers(file string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "tasks/answers:LoadAnswers() Error reading answers file")
+	}
+	var doc map[string]interface{}
+	err = yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, errors.Wrap(err, "tasks/answers:LoadAnswers() Error parsing answers file")
+	}
+
+	answers := make(map[string]string)
+	for key, value := range doc {
+		section, ok := value.(map[interface{}]interface{})
+		if !ok {
+			answer, err := answerValue(key, value)
+			if err != nil {
+				return nil, err
+			}
+			answers[key] = answer
+			continue
+		}
+		for name, value := range section {
+			answer, err := answerValue(fmt.Sprint(name), value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "tasks/answers:LoadAnswers() Invalid %s answers", key)
+			}
+			answers[fmt.Sprint(name)] = answer
+		}
+	}
+	return answers, nil
+}
+
+// answerValue converts a scalar or list answer to the value of its environment variable, lists are joined
+// with commas as the tasks expect
+func answerValue(name string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ","), nil
+	case map[interface{}]interface{}:
+		return "", errors.Errorf("tasks/answers:answerValue() %s must be a scalar or a list", name)
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// ApplyAnswers exports the answers to the environment the setup tasks read their inputs from. Variables
+// already set in the environment take precedence over the answers file. It returns the names of the
+// exported variables.
+func ApplyAnswers(answers map[string]string) ([]string, error) {
+	var applied []string
+	for name, value := range answers {
+		if _, ok := os.LookupEnv(name); ok {
+			continue
+		}
+		err := os.Setenv(name, value)
+		if err != nil {
+			return applied, errors.Wrapf(err, "tasks/answers:ApplyAnswers() Error setting %s", name)
+		}
+		applied = append(applied, name)
+	}
+	sort.Strings(applied)
+	return applied, nil
+}
@@ -0,0 +1,225 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package tasks
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// BootstrapRequest is the trust material and inputs the bootstrap API runs setup with. CMSRootCA is the PEM
+// encoded CMS root CA, trusted instead of downloading it from CMS, BearerToken the token the TLS certificate
+// is requested from CMS with. Settings are the other environment variables read by the setup tasks, such as
+// CMS_BASE_URL, CMS_TLS_CERT_SHA384, SCS_BASE_URL or SAN_LIST.
+type BootstrapRequest struct {
+	CMSRootCA   string            `json:"cmsRootCa"`
+	AASBaseURL  string            `json:"aasBaseUrl"`
+	BearerToken string            `json:"bearerToken"`
+	Settings    map[string]string `json:"settings,omitempty"`
+}
+
+// bootstrapVariables are the environment variables, besides those prefixed with SQVS_, the settings of a
+// bootstrap request may set
+var bootstrapVariables = map[string]bool{
+	"CERT_PATH":                   true,
+	"CMS_BASE_URL":                true,
+	"CMS_TLS_CERT_SHA384":         true,
+	"KEY_PATH":                    true,
+	"RESPONSE_SIGNING_KEY_LENGTH": true,
+	"SAN_AUTO_DETECT":             true,
+	"SAN_LIST":                    true,
+	"SCS_BASE_URL":                true,
+	"SGX_TRUSTED_ROOT_CA_PATH":    true,
+	"SIGN_QUOTE_RESPONSE":         true,
+	"USE_PSS_PADDING":             true,
+}
+
+// Validate checks the CMS root CA is a PEM encoded CA certificate, the AAS URL an absolute URL and the
+// settings variables read by the setup tasks
+func (r BootstrapRequest) Validate() error {
+	_, err := r.RootCA()
+	if err != nil {
+		return err
+	}
+	u, err := url.ParseRequestURI(r.AASBaseURL)
+	if err != nil || u.Host == "" {
+		return errors.New("tasks/bootstrap:Validate() aasBaseUrl must be an absolute URL")
+	}
+	if strings.TrimSpace(r.BearerToken) == "" {
+		return errors.New("tasks/bootstrap:Validate() bearerToken is required")
+	}
+	for name := range r.Settings {
+		if !strings.HasPrefix(name, "SQVS_") && !bootstrapVariables[name] {
+			return errors.Errorf("tasks/bootstrap:Validate() %s is not a setup setting", name)
+		}
+	}
+	return nil
+}
+
+// RootCA returns the PEM encoded CMS root CA certificate of the request
+func (r BootstrapRequest) RootCA() ([]byte, error) {
+	block, _ := pem.Decode([]byte(r.CMSRootCA))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("tasks/bootstrap:RootCA() cmsRootCa must be a PEM encoded certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "tasks/bootstrap:RootCA() Invalid cmsRootCa certificate")
+	}
+	if !cert.IsCA {
+		return nil, errors.New("tasks/bootstrap:RootCA() cmsRootCa is not a CA certificate")
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+// Apply exports the inputs of the request to the environment the setup tasks read them from, overriding the
+// variables already set. It returns the names of the exported variables.
+func (r BootstrapRequest) Apply() ([]string, error) {
+	variables := map[string]string{"AAS_API_URL": r.AASBaseURL, "BEARER_TOKEN": r.BearerToken}
+	for name, value := range r.Settings {
+		variables[name] = value
+	}
+	var applied []string
+	for name, value := range variables {
+		err := os.Setenv(name, value)
+		if err != nil {
+			return applied, errors.Wrapf(err, "tasks/bootstrap:Apply() Error setting %s", name)
+		}
+		applied = append(applied, name)
+	}
+	sort.Strings(applied)
+	return applied, nil
+}
+
+// BootstrapResponse is the outcome of the setup run by the bootstrap API
+type BootstrapResponse struct {
+	Summary Summary `json:"summary"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// bootstrapTokenSize is the number of random bytes of a bootstrap token
+const bootstrapTokenSize = 32
+
+// NewBootstrapToken returns a random token, which the bootstrap requests must present as their bearer token
+func NewBootstrapToken() (string, error) {
+	token := make([]byte, bootstrapTokenSize)
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", errors.Wrap(err, "tasks/bootstrap:NewBootstrapToken() Error reading random bytes")
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// Bootstrapper serves the one-time bootstrap API: the first request whose setup succeeds completes the
+// bootstrap, the later ones are refused with 410 Gone. Requests are only accepted from the loopback
+// interface, with the bootstrap token as their bearer token and a JSON body, and run one at a time.
+type Bootstrapper struct {
+	token string
+	run   func(BootstrapRequest) (Summary, error)
+	done  chan struct{}
+
+	mu        sync.Mutex
+	completed bool
+}
+
+// NewBootstrapper returns the bootstrap API accepting the requests presenting token and running setup with run
+func NewBootstrapper(token string, run func(BootstrapRequest) (Summary, error)) *Bootstrapper {
+	return &Bootstrapper{token: token, run: run, done: make(chan struct{})}
+}
+
+// Done is closed once the bootstrap has completed
+func (b *Bootstrapper) Done() <-chan struct{} {
+	return b.done
+}
+
+func (b *Bootstrapper) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if ip := net.ParseIP(host); err != nil || ip == nil || !ip.IsLoopback() {
+		http.Error(w, "Bootstrap is only available from the local host", http.StatusForbidden)
+		return
+	}
+	// only the operator running sqvs bootstrap sees the token, which keeps the other local processes out;
+	// requiring JSON keeps out the browsers, which cannot send it cross-origin without a preflight
+	credentials := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if b.token == "" || len(credentials) != 2 || !strings.EqualFold(credentials[0], "Bearer") ||
+		subtle.ConstantTimeCompare([]byte(credentials[1]), []byte(b.token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Invalid bootstrap token", http.StatusUnauthorized)
+		return
+	}
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var req BootstrapRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid JSON input provided", http.StatusBadRequest)
+		return
+	}
+	err = req.Validate()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.completed {
+		http.Error(w, "Bootstrap has already completed", http.StatusGone)
+		return
+	}
+	summary, err := b.run(req)
+	resp := BootstrapResponse{Summary: summary}
+	status := http.StatusOK
+	if err != nil {
+		resp.Error = err.Error()
+		status = http.StatusInternalServerError
+	} else {
+		b.completed = true
+		defer close(b.done)
+	}
+	body, _ := json.Marshal(resp)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// CheckLoopbackAddr returns an error unless the host of the listen address is a loopback IP address, the
+// bootstrap API accepting trust material in clear
+func CheckLoopbackAddr(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return errors.Wrapf(err, "tasks/bootstrap:CheckLoopbackAddr() Invalid listen address %s", addr)
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return errors.Errorf("tasks/bootstrap:CheckLoopbackAddr() Listen address %s is not a loopback address",
+			addr)
+	}
+	return nil
+}
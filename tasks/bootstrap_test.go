@@ -0,0 +1,124 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package tasks
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func testBootstrapRootCA(t *testing.T, isCA bool) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "CMS Root CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestBootstrapRequestValidate(t *testing.T) {
+	req := BootstrapRequest{CMSRootCA: testBootstrapRootCA(t, true), AASBaseURL: "https://aas.com:8444/aas/v1",
+		BearerToken: "token", Settings: map[string]string{"CMS_BASE_URL": "https://cms.com:8445/cms/v1",
+			"SQVS_PORT": "12000"}}
+	assert.NoError(t, req.Validate())
+
+	invalid := req
+	invalid.CMSRootCA = testBootstrapRootCA(t, false)
+	assert.Error(t, invalid.Validate())
+	invalid = req
+	invalid.AASBaseURL = "aas.com"
+	assert.Error(t, invalid.Validate())
+	invalid = req
+	invalid.BearerToken = " "
+	assert.Error(t, invalid.Validate())
+	invalid = req
+	invalid.Settings = map[string]string{"LD_PRELOAD": "/tmp/lib.so"}
+	assert.Error(t, invalid.Validate())
+}
+
+func TestBootstrapper(t *testing.T) {
+	runs := 0
+	fail := true
+	token, err := NewBootstrapToken()
+	assert.NoError(t, err)
+	b := NewBootstrapper(token, func(req BootstrapRequest) (Summary, error) {
+		runs++
+		if fail {
+			return Summary{Failed: 1}, errors.New("download_cert failed")
+		}
+		return Summary{Changed: 3}, nil
+	})
+	body, _ := json.Marshal(BootstrapRequest{CMSRootCA: testBootstrapRootCA(t, true),
+		AASBaseURL: "https://aas.com:8444/aas/v1", BearerToken: "token"})
+	send := func(remoteAddr, authorization, contentType string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/bootstrap", strings.NewReader(string(body)))
+		r.RemoteAddr = remoteAddr
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		r.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		b.ServeHTTP(w, r)
+		return w
+	}
+	post := func(remoteAddr string) *httptest.ResponseRecorder {
+		return send(remoteAddr, "Bearer "+token, "application/json; charset=utf-8")
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, send("127.0.0.1:4000", "", "application/json").Code)
+	assert.Equal(t, http.StatusUnauthorized, send("127.0.0.1:4000", "Bearer wrong", "application/json").Code)
+	// the token is only accepted with the Bearer scheme, whatever its case
+	w := send("127.0.0.1:4000", token, "application/json")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+	assert.Equal(t, http.StatusUnauthorized, send("127.0.0.1:4000", "Basic "+token, "application/json").Code)
+	assert.Equal(t, http.StatusUnsupportedMediaType, send("127.0.0.1:4000", "bearer "+token, "text/plain").Code)
+	assert.Equal(t, http.StatusUnsupportedMediaType, send("127.0.0.1:4000", "Bearer "+token, "").Code)
+	assert.Equal(t, http.StatusForbidden, post("10.0.0.1:4000").Code)
+	assert.Equal(t, 0, runs)
+
+	// a failed setup can be retried
+	w = post("127.0.0.1:4000")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "download_cert failed")
+	fail = false
+	assert.Equal(t, http.StatusOK, post("[::1]:4000").Code)
+	select {
+	case <-b.Done():
+	default:
+		t.Fatal("bootstrap is not done")
+	}
+	assert.Equal(t, http.StatusGone, post("127.0.0.1:4000").Code)
+	assert.Equal(t, 2, runs)
+}
+
+func TestCheckLoopbackAddr(t *testing.T) {
+	assert.NoError(t, CheckLoopbackAddr("127.0.0.1:12001"))
+	assert.NoError(t, CheckLoopbackAddr("[::1]:12001"))
+	assert.Error(t, CheckLoopbackAddr("0.0.0.0:12001"))
+	assert.Error(t, CheckLoopbackAddr(":12001"))
+	assert.Error(t, CheckLoopbackAddr("localhost:12001"))
+}
@@ -14,11 +14,13 @@ import (
 	"intel/isecl/lib/common/v4/crypt"
 	commLog "intel/isecl/lib/common/v4/log"
 	csetup "intel/isecl/lib/common/v4/setup"
+	"intel/isecl/sqvs/v4/aasclient"
 	"intel/isecl/sqvs/v4/config"
 	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/keystore"
+	"intel/isecl/sqvs/v4/signingkey"
 	"io"
 	"io/ioutil"
-
 	"os"
 
 	"github.com/pkg/errors"
@@ -37,6 +39,11 @@ func (cskp Create_Signing_Key_Pair) Validate(c csetup.Context) error {
 	defaultLog.Trace("tasks/create_signing_key_pair: Validate() Entering")
 	defer defaultLog.Trace("tasks/create_signing_key_pair: Validate() Leaving")
 
+	conf := config.Global()
+	if conf != nil && conf.KeyStore.Type != "" && conf.KeyStore.Type != keystore.TypeFile {
+		return cskp.validateKeyStoreSigningKey(conf)
+	}
+
 	_, err := os.Stat(constants.PrivateKeyLocation)
 	if os.IsNotExist(err) {
 		return errors.Wrap(err, "tasks/create_signing_key_pair: Validate() Private key does not exist")
@@ -100,10 +107,26 @@ func (cskp Create_Signing_Key_Pair) Run(c csetup.Context) error {
 
 		bearerToken, err := c.GetenvSecret("BEARER_TOKEN", "bearer token")
 		if err != nil || bearerToken == "" {
+			bearerToken, err = serviceAccountToken(conf)
+			if err != nil {
+				fmt.Fprintln(cskp.ConsoleWriter, "Error getting bearer token of the AAS service account:", err)
+			}
+		}
+		if bearerToken == "" {
 			fmt.Fprintln(cskp.ConsoleWriter, "BEARER_TOKEN not found in environment for downloading certificate")
 			return errors.New("Certificate setup: BEARER_TOKEN not found in environment for downloading certificate")
 		}
 
+		if conf.KeyStore.Type != "" && conf.KeyStore.Type != keystore.TypeFile {
+			err = cskp.certifyKeyStoreSigningKey(conf, bearerToken)
+			if err != nil {
+				fmt.Fprintln(cskp.ConsoleWriter, "Error getting signing certificate ")
+				return fmt.Errorf("certificate setup: %v", err)
+			}
+			fmt.Fprintln(cskp.ConsoleWriter, "Quote Signing Certificate Created")
+			return nil
+		}
+
 		key, cert, err := csetup.GetCertificateFromCMS("Signing", constants.DefaultKeyAlgorithm, conf.ResponseSigningKeyLength,
 			conf.CMSBaseURL, pkix.Name{CommonName: constants.DefaultSQVSSigningCertCn}, "",
 			constants.TrustedCAsStoreDir, bearerToken)
@@ -133,3 +156,68 @@ func (cskp Create_Signing_Key_Pair) Run(c csetup.Context) error {
 	fmt.Fprintln(cskp.ConsoleWriter, "Quote Signing Key Pair Created")
 	return nil
 }
+
+// validateKeyStoreSigningKey checks that the signing key is reachable in the configured key store and
+// that its certificate has been downloaded
+func (cskp Create_Signing_Key_Pair) validateKeyStoreSigningKey(conf *config.Configuration) error {
+	ks, err := keystore.New(conf.KeyStore, constants.TrustedCAsStoreDir)
+	if err != nil {
+		return errors.Wrap(err, "tasks/create_signing_key_pair: Validate() Error initializing key store")
+	}
+	defer func() {
+		derr := ks.Close()
+		if derr != nil {
+			defaultLog.WithError(derr).Error("Error closing key store")
+		}
+	}()
+
+	_, err = ks.Signer(conf.KeyStore.SigningKeyID)
+	if err != nil {
+		return errors.Wrap(err, "tasks/create_signing_key_pair: Validate() Signing key not found in key store")
+	}
+	_, err = ioutil.ReadFile(constants.PublicKeyLocation)
+	if err != nil {
+		return errors.Wrap(err, "error reading signing certificate from file")
+	}
+	return nil
+}
+
+// certifyKeyStoreSigningKey gets a CMS certificate for a signing key held in Vault or a PKCS#11 token.
+// The certificate signing request is signed by the key store so the private key never leaves it.
+func (cskp Create_Signing_Key_Pair) certifyKeyStoreSigningKey(conf *config.Configuration, bearerToken string) error {
+	ks, err := keystore.New(conf.KeyStore, constants.TrustedCAsStoreDir)
+	if err != nil {
+		return errors.Wrap(err, "Error initializing key store")
+	}
+	defer func() {
+		derr := ks.Close()
+		if derr != nil {
+			defaultLog.WithError(derr).Error("Error closing key store")
+		}
+	}()
+
+	signer, err := ks.Signer(conf.KeyStore.SigningKeyID)
+	if err != nil {
+		return errors.Wrap(err, "Signing key not found in key store")
+	}
+	cert, err := signingkey.RequestCertificate(conf.CMSBaseURL, signer, constants.DefaultSQVSSigningCertCn,
+		bearerToken, constants.TrustedCAsStoreDir)
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(constants.PublicKeyLocation, cert, 0644)
+	if err != nil {
+		return errors.Wrap(err, "Could not store Certificate")
+	}
+	return os.Chmod(constants.PublicKeyLocation, 0644)
+}
+
+// serviceAccountToken gets a bearer token of the AAS service account of SQVS, empty when none is configured
+func serviceAccountToken(conf *config.Configuration) (string, error) {
+	tokens, err := aasclient.FromConfig(conf)
+	if err != nil || tokens == nil {
+		return "", err
+	}
+	return tokens.Token()
+}
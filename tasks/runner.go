@@ -0,0 +1,278 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package tasks
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"intel/isecl/lib/common/v4/setup"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	StatusChanged   = "changed"
+	StatusUnchanged = "unchanged"
+	StatusSkipped   = "skipped"
+	StatusFailed    = "failed"
+)
+
+// Step is a setup task run by the Runner
+type Step struct {
+	Name string
+	Task setup.Task
+	// Outputs are the files and directories written by the task, compared before and after it runs to report
+	// whether it changed anything
+	Outputs []string
+	// Reconcile runs the task even when it validates, for tasks deriving their outputs from their inputs
+	Reconcile bool
+	// DependsOn names the steps that must have succeeded before the task runs, steps not depending on each
+	// other running in parallel. Dependencies on steps that are not run are ignored.
+	DependsOn []string
+}
+
+// StepResult is the outcome of a setup step
+type StepResult struct {
+	Task   string `json:"task"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// DurationMs is the time the task took to run and validate, in milliseconds
+	DurationMs int64 `json:"durationMs"`
+}
+
+// Summary reports the outcome of the setup steps that were run
+type Summary struct {
+	Results   []StepResult `json:"results"`
+	Changed   int          `json:"changed"`
+	Unchanged int          `json:"unchanged"`
+	Skipped   int          `json:"skipped"`
+	Failed    int          `json:"failed"`
+}
+
+// Runner runs setup steps once the steps they depend on succeeded, in parallel otherwise. Steps whose task
+// already validates are skipped unless Force is set, so that setup can be run again safely.
+type Runner struct {
+	Steps         []Step
+	Force         bool
+	ConsoleWriter io.Writer
+}
+
+// RunTasks runs the named steps, or all the steps when no name is given. Steps depending on a failing step are
+// skipped, the results are reported in the order of the steps.
+func (r *Runner) RunTasks(names ...string) (Summary, error) {
+	var summary Summary
+	steps, err := r.selectSteps(names)
+	if err != nil {
+		return summary, err
+	}
+	err = r.checkDependencies()
+	if err != nil {
+		return summary, err
+	}
+
+	index := make(map[string]int, len(steps))
+	for i, step := range steps {
+		index[step.Name] = i
+	}
+	results := make([]StepResult, len(steps))
+	errs := make([]error, len(steps))
+	done := make([]chan struct{}, len(steps))
+	for i := range steps {
+		done[i] = make(chan struct{})
+	}
+
+	var ctx setup.Context
+	for i := range steps {
+		go func(i int) {
+			defer close(done[i])
+			step := steps[i]
+			for _, dependency := range step.DependsOn {
+				d, ok := index[dependency]
+				if !ok {
+					continue
+				}
+				<-done[d]
+				if errs[d] != nil || results[d].Status == StatusFailed {
+					fmt.Fprintf(r.ConsoleWriter, "Setup task %s skipped, %s failed\n", step.Name, dependency)
+					results[i] = StepResult{Task: step.Name, Status: StatusSkipped,
+						Error: "dependency " + dependency + " failed"}
+					errs[i] = errors.Errorf("dependency %s failed", dependency)
+					return
+				}
+			}
+			start := time.Now()
+			status, err := r.runStep(ctx, step)
+			results[i] = StepResult{Task: step.Name, Status: status,
+				DurationMs: int64(time.Since(start) / time.Millisecond)}
+			if err != nil {
+				results[i].Error = err.Error()
+				errs[i] = err
+			}
+		}(i)
+	}
+	for i := range steps {
+		<-done[i]
+	}
+
+	for _, result := range results {
+		summary.add(result)
+	}
+	for i, err := range errs {
+		if err != nil {
+			return summary, errors.Wrapf(err, "tasks/runner:RunTasks() Setup task %s failed", steps[i].Name)
+		}
+	}
+	return summary, nil
+}
+
+// checkDependencies returns an error when a step depends on an unknown step or the dependencies form a cycle
+func (r *Runner) checkDependencies() error {
+	dependencies := make(map[string][]string, len(r.Steps))
+	for _, step := range r.Steps {
+		dependencies[step.Name] = step.DependsOn
+	}
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return errors.Errorf("tasks/runner:checkDependencies() Setup task %s depends on itself", name)
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dependency := range dependencies[name] {
+			if _, ok := dependencies[dependency]; !ok {
+				return errors.Errorf("tasks/runner:checkDependencies() Setup task %s depends on unknown task %s",
+					name, dependency)
+			}
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for _, step := range r.Steps {
+		if err := visit(step.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Runner) selectSteps(names []string) ([]Step, error) {
+	if len(names) == 0 {
+		return r.Steps, nil
+	}
+	var steps []Step
+	for _, name := range names {
+		found := false
+		for _, step := range r.Steps {
+			if strings.EqualFold(step.Name, name) {
+				steps = append(steps, step)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.Errorf("tasks/runner:selectSteps() Unknown setup task %s", name)
+		}
+	}
+	return steps, nil
+}
+
+func (r *Runner) runStep(ctx setup.Context, step Step) (string, error) {
+	if !r.Force && !step.Reconcile && step.Task.Validate(ctx) == nil {
+		fmt.Fprintf(r.ConsoleWriter, "Setup task %s already completed, skipping\n", step.Name)
+		return StatusSkipped, nil
+	}
+
+	before, err := digestOutputs(step.Outputs)
+	if err != nil {
+		return StatusFailed, err
+	}
+	err = step.Task.Run(ctx)
+	if err != nil {
+		return StatusFailed, err
+	}
+	err = step.Task.Validate(ctx)
+	if err != nil {
+		return StatusFailed, errors.Wrap(err, "tasks/runner:runStep() Validation failed")
+	}
+	after, err := digestOutputs(step.Outputs)
+	if err != nil {
+		return StatusFailed, err
+	}
+	if bytes.Equal(before, after) {
+		return StatusUnchanged, nil
+	}
+	return StatusChanged, nil
+}
+
+// digestOutputs returns the digest of the names and contents of the output files of a step, missing files
+// are part of the digest as such
+func digestOutputs(outputs []string) ([]byte, error) {
+	h := sha256.New()
+	for _, output := range outputs {
+		err := filepath.Walk(output, func(path string, info os.FileInfo, err error) error {
+			if os.IsNotExist(err) {
+				fmt.Fprintf(h, "%s:missing\n", path)
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "%s:%v\n", path, info.Mode())
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			_, _ = h.Write(data)
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "tasks/runner:digestOutputs() Error reading setup task outputs")
+		}
+	}
+	return h.Sum(nil), nil
+}
+
+func (s *Summary) add(result StepResult) {
+	s.Results = append(s.Results, result)
+	switch result.Status {
+	case StatusChanged:
+		s.Changed++
+	case StatusUnchanged:
+		s.Unchanged++
+	case StatusSkipped:
+		s.Skipped++
+	case StatusFailed:
+		s.Failed++
+	}
+}
+
+// Print writes the summary in the console, one line per step with the time it took followed by the totals
+func (s Summary) Print(w io.Writer) {
+	fmt.Fprintln(w, "Setup summary:")
+	for _, result := range s.Results {
+		fmt.Fprintf(w, "    %-25s %-10s %s\n", result.Task, result.Status, time.Duration(result.DurationMs)*time.Millisecond)
+	}
+	fmt.Fprintf(w, "changed=%d unchanged=%d skipped=%d failed=%d\n", s.Changed, s.Unchanged, s.Skipped, s.Failed)
+}
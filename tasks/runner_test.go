@@ -0,0 +1,193 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package tasks
+
+import (
+	"bytes"
+	"intel/isecl/lib/common/v4/setup"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// fileTask writes content to file, it validates once the file exists
+type fileTask struct {
+	file    string
+	content *string
+}
+
+func (t fileTask) Run(c setup.Context) error {
+	return ioutil.WriteFile(t.file, []byte(*t.content), 0600)
+}
+
+func (t fileTask) Validate(c setup.Context) error {
+	_, err := os.Stat(t.file)
+	return err
+}
+
+type failingTask struct{}
+
+func (failingTask) Run(c setup.Context) error      { return errors.New("failed") }
+func (failingTask) Validate(c setup.Context) error { return errors.New("not run") }
+
+// orderedTask records the order tasks run in, blocking until release is closed when it is set
+type orderedTask struct {
+	name    string
+	order   chan<- string
+	release <-chan struct{}
+	done    *bool
+}
+
+func (t orderedTask) Run(c setup.Context) error {
+	if t.release != nil {
+		<-t.release
+	}
+	t.order <- t.name
+	*t.done = true
+	return nil
+}
+
+func (t orderedTask) Validate(c setup.Context) error {
+	if !*t.done {
+		return errors.New("not run")
+	}
+	return nil
+}
+
+func withoutDurations(results []StepResult) []StepResult {
+	for i := range results {
+		results[i].DurationMs = 0
+	}
+	return results
+}
+
+func TestRunnerSkipsCompletedTasks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "runner")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	keyContent, configContent := "key", "port: 12000"
+	keyFile, configFile := filepath.Join(dir, "key.pem"), filepath.Join(dir, "config.yml")
+	runner := &Runner{
+		Steps: []Step{
+			{Name: "create_key", Task: fileTask{keyFile, &keyContent}, Outputs: []string{keyFile}},
+			{Name: "update_config", Task: fileTask{configFile, &configContent}, Outputs: []string{configFile},
+				Reconcile: true},
+		},
+		ConsoleWriter: ioutil.Discard,
+	}
+
+	summary, err := runner.RunTasks()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, summary.Changed)
+
+	// re-runs skip completed tasks and only report the tasks changing their outputs
+	configContent = "port: 12001"
+	summary, err = runner.RunTasks()
+	assert.NoError(t, err)
+	assert.Equal(t, []StepResult{{Task: "create_key", Status: StatusSkipped},
+		{Task: "update_config", Status: StatusChanged}}, withoutDurations(summary.Results))
+	summary, err = runner.RunTasks("update_config")
+	assert.NoError(t, err)
+	assert.Equal(t, []StepResult{{Task: "update_config", Status: StatusUnchanged}}, withoutDurations(summary.Results))
+
+	runner.Force = true
+	keyContent = "new key"
+	summary, err = runner.RunTasks("create_key")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, summary.Changed)
+
+	runner.Steps = append(runner.Steps, Step{Name: "fail", Task: failingTask{}})
+	summary, err = runner.RunTasks()
+	assert.Error(t, err)
+	assert.Equal(t, 1, summary.Failed)
+	var out bytes.Buffer
+	summary.Print(&out)
+	assert.Contains(t, out.String(), "changed=0 unchanged=2 skipped=0 failed=1")
+
+	_, err = runner.RunTasks("unknown")
+	assert.Error(t, err)
+}
+
+func TestRunnerDependencies(t *testing.T) {
+	order := make(chan string, 3)
+	release := make(chan struct{})
+	var caDone, configDone, certDone bool
+	runner := &Runner{
+		Steps: []Step{
+			{Name: "download_ca_cert", Task: orderedTask{"download_ca_cert", order, release, &caDone}},
+			{Name: "download_cert", Task: orderedTask{"download_cert", order, nil, &certDone},
+				DependsOn: []string{"download_ca_cert"}},
+			{Name: "update_service_config", Task: orderedTask{"update_service_config", order, nil, &configDone}},
+		},
+		ConsoleWriter: ioutil.Discard,
+	}
+
+	// update_service_config runs while download_ca_cert is blocked, download_cert waits for it
+	checked := make(chan struct{})
+	go func() {
+		defer close(checked)
+		assert.Equal(t, "update_service_config", <-order)
+		close(release)
+		assert.Equal(t, "download_ca_cert", <-order)
+		assert.Equal(t, "download_cert", <-order)
+	}()
+	summary, err := runner.RunTasks()
+	<-checked
+	assert.NoError(t, err)
+	assert.Equal(t, 3, summary.Unchanged)
+	assert.Equal(t, "download_ca_cert", summary.Results[0].Task)
+
+	runner.Steps[0].Task = failingTask{}
+	runner.Force = true
+	summary, err = runner.RunTasks("download_ca_cert", "download_cert")
+	assert.Error(t, err)
+	assert.Equal(t, []StepResult{{Task: "download_ca_cert", Status: StatusFailed, Error: "failed"},
+		{Task: "download_cert", Status: StatusSkipped, Error: "dependency download_ca_cert failed"}},
+		withoutDurations(summary.Results))
+
+	runner.Steps[0].DependsOn = []string{"download_cert"}
+	_, err = runner.RunTasks()
+	assert.Error(t, err)
+	runner.Steps[0].DependsOn = []string{"unknown"}
+	_, err = runner.RunTasks()
+	assert.Error(t, err)
+}
+
+func TestLoadAnswers(t *testing.T) {
+	file, err := ioutil.TempFile("", "answers*.yml")
+	assert.NoError(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(`CMS_BASE_URL: https://cms.com:8445/cms/v1/
+update_service_config:
+  SQVS_PORT: 12000
+  SQVS_IP_ALLOW_LIST:
+  - 10.0.0.0/8
+  - 192.168.0.0/16
+`)
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+
+	answers, err := LoadAnswers(file.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"CMS_BASE_URL":       "https://cms.com:8445/cms/v1/",
+		"SQVS_PORT":          "12000",
+		"SQVS_IP_ALLOW_LIST": "10.0.0.0/8,192.168.0.0/16",
+	}, answers)
+
+	os.Setenv("SQVS_PORT", "13000")
+	defer os.Unsetenv("SQVS_PORT")
+	defer os.Unsetenv("CMS_BASE_URL")
+	defer os.Unsetenv("SQVS_IP_ALLOW_LIST")
+	applied, err := ApplyAnswers(answers)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"CMS_BASE_URL", "SQVS_IP_ALLOW_LIST"}, applied)
+	assert.Equal(t, "13000", os.Getenv("SQVS_PORT"))
+}
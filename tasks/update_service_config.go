@@ -5,6 +5,9 @@
 package tasks
 
 import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/pem"
 	"flag"
 	"fmt"
@@ -12,8 +15,24 @@ import (
 	"intel/isecl/lib/common/v4/setup"
 	"intel/isecl/sqvs/v4/config"
 	"intel/isecl/sqvs/v4/constants"
+	"intel/isecl/sqvs/v4/egress"
+	"intel/isecl/sqvs/v4/events"
+	"intel/isecl/sqvs/v4/keyrelease"
+	"intel/isecl/sqvs/v4/keystore"
+	"intel/isecl/sqvs/v4/logformat"
+	"intel/isecl/sqvs/v4/netfamily"
+	"intel/isecl/sqvs/v4/quota"
+	"intel/isecl/sqvs/v4/quoteprovider"
+	"intel/isecl/sqvs/v4/repository"
+	"intel/isecl/sqvs/v4/resolver"
+	"intel/isecl/sqvs/v4/resource/parser"
+	"intel/isecl/sqvs/v4/resource/verifier"
+	"intel/isecl/sqvs/v4/signingkey"
+	"intel/isecl/sqvs/v4/tlspolicy"
+	"intel/isecl/sqvs/v4/trustedtime"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/url"
 	"os"
 	"strconv"
@@ -103,6 +122,164 @@ func (u Update_Service_Config) Run(c setup.Context) error {
 		u.Config.MaxHeaderBytes = maxHeaderBytes
 	}
 
+	maxConcurrentRequests, err := c.GetenvInt("SQVS_MAX_CONCURRENT_REQUESTS", "Maximum number of verification requests processed concurrently")
+	if err != nil {
+		u.Config.MaxConcurrentRequests = constants.DefaultMaxConcurrentRequests
+	} else {
+		u.Config.MaxConcurrentRequests = maxConcurrentRequests
+	}
+
+	maxQueuedRequests, err := c.GetenvInt("SQVS_MAX_QUEUED_REQUESTS", "Maximum number of verification requests waiting to be processed, -1 for no limit")
+	if err != nil {
+		u.Config.MaxQueuedRequests = constants.DefaultMaxQueuedRequests
+	} else {
+		u.Config.MaxQueuedRequests = maxQueuedRequests
+	}
+
+	maxQueueWait, err := c.GetenvString("SQVS_MAX_QUEUE_WAIT", "Maximum time a verification request waits to be processed")
+	if err != nil {
+		u.Config.MaxQueueWait = constants.DefaultMaxQueueWait
+	} else {
+		u.Config.MaxQueueWait, err = time.ParseDuration(maxQueueWait)
+		if err != nil {
+			fmt.Fprintf(u.ConsoleWriter, "Invalid duration provided for SQVS_MAX_QUEUE_WAIT setting it to the default value\n")
+			u.Config.MaxQueueWait = constants.DefaultMaxQueueWait
+		}
+	}
+
+	u.Config.TargetUtilization = constants.DefaultTargetUtilization
+	targetUtilization, err := c.GetenvString("SQVS_TARGET_UTILIZATION", "Share of the concurrent verification requests each replica is scaled to")
+	if err == nil && targetUtilization != "" {
+		utilization, err := strconv.ParseFloat(targetUtilization, 64)
+		if err != nil || utilization <= 0 || utilization > 1 {
+			fmt.Fprintf(u.ConsoleWriter, "Invalid value provided for SQVS_TARGET_UTILIZATION setting it to the default value\n")
+		} else {
+			u.Config.TargetUtilization = utilization
+		}
+	}
+
+	batchWorkers, err := c.GetenvInt("SQVS_BATCH_WORKERS", "Number of quotes of a batch request verified in parallel")
+	if err == nil && batchWorkers > 0 {
+		u.Config.BatchWorkers = batchWorkers
+	}
+
+	clockSkewTolerance, err := c.GetenvInt("SQVS_CLOCK_SKEW_TOLERANCE_SECONDS", "Number of seconds certificate and "+
+		"collateral validity periods may be missed by and still pass")
+	if err == nil && clockSkewTolerance >= 0 {
+		u.Config.ClockSkewToleranceSeconds = clockSkewTolerance
+	}
+
+	waitForDependencies, err := c.GetenvString("SQVS_WAIT_FOR_DEPENDENCIES", "Boolean value to wait until "+
+		"CMS, AAS and SCS are reachable before starting")
+	if err == nil && waitForDependencies != "" {
+		u.Config.WaitForDependencies, err = strconv.ParseBool(waitForDependencies)
+		if err != nil {
+			fmt.Fprintf(u.ConsoleWriter, "SQVS_WAIT_FOR_DEPENDENCIES is not defined properly, must be true/false. SQVS will start without waiting for its dependencies\n")
+			u.Config.WaitForDependencies = false
+		}
+	}
+	u.Config.DependencyWaitTimeout = u.getenvDuration(c, "SQVS_DEPENDENCY_WAIT_TIMEOUT",
+		"Maximum time to wait for the dependencies", constants.DefaultDependencyWaitTimeout)
+	u.Config.DependencyRetryInterval = u.getenvDuration(c, "SQVS_DEPENDENCY_RETRY_INTERVAL",
+		"Delay before checking the dependencies again", constants.DefaultDependencyRetryInterval)
+	u.Config.DependencyMaxRetryInterval = u.getenvDuration(c, "SQVS_DEPENDENCY_MAX_RETRY_INTERVAL",
+		"Maximum delay between dependency checks", constants.DefaultDependencyMaxRetryInterval)
+
+	serviceUsername, err := c.GetenvString("SQVS_SERVICE_USERNAME", "AAS service account of the outbound calls")
+	if err == nil && serviceUsername != "" {
+		u.Config.ServiceUsername = serviceUsername
+	}
+	servicePasswordFile, err := c.GetenvString("SQVS_SERVICE_PASSWORD_FILE", "File holding the password of the "+
+		"AAS service account")
+	if err == nil && servicePasswordFile != "" {
+		u.Config.ServicePasswordFile = servicePasswordFile
+	}
+	if u.Config.ServiceUsername != "" && u.Config.ServicePasswordFile == "" {
+		return errors.New("SaveConfiguration() SQVS_SERVICE_PASSWORD_FILE is required with SQVS_SERVICE_USERNAME")
+	}
+	u.Config.TokenRenewBefore = u.getenvDuration(c, "SQVS_TOKEN_RENEW_BEFORE",
+		"Time before their expiry the bearer tokens of the outbound calls are renewed", constants.DefaultTokenRenewBefore)
+
+	outboundMaxAttempts, err := c.GetenvInt("SQVS_OUTBOUND_MAX_ATTEMPTS", "Maximum number of attempts for collateral requests")
+	if err != nil {
+		u.Config.Outbound.MaxAttempts = constants.DefaultOutboundMaxAttempts
+	} else {
+		u.Config.Outbound.MaxAttempts = outboundMaxAttempts
+	}
+
+	u.Config.Outbound.InitialBackoff = u.getenvDuration(c, "SQVS_OUTBOUND_INITIAL_BACKOFF",
+		"Delay before the first retry of a collateral request", constants.DefaultOutboundInitialBackoff)
+	u.Config.Outbound.MaxBackoff = u.getenvDuration(c, "SQVS_OUTBOUND_MAX_BACKOFF",
+		"Maximum delay between retries of a collateral request", constants.DefaultOutboundMaxBackoff)
+	u.Config.Outbound.AttemptTimeout = u.getenvDuration(c, "SQVS_OUTBOUND_ATTEMPT_TIMEOUT",
+		"Timeout of a single collateral request attempt", constants.DefaultOutboundAttemptTimeout)
+	u.Config.CollateralFetchTimeout = u.getenvDuration(c, "SQVS_COLLATERAL_FETCH_TIMEOUT",
+		"Timeout of the TCB info and QE identity fetch of a quote", constants.DefaultCollateralFetchTimeout)
+	u.Config.CRLFetchTimeout = u.getenvDuration(c, "SQVS_CRL_FETCH_TIMEOUT",
+		"Timeout of the PCK CRL fetch of a quote", constants.DefaultCRLFetchTimeout)
+	u.Config.VerificationComputeTimeout = u.getenvDuration(c, "SQVS_VERIFICATION_COMPUTE_TIMEOUT",
+		"Timeout of the verification of a quote, collateral and CRL fetches excluded",
+		constants.DefaultVerificationComputeTimeout)
+	u.Config.PckChainCacheTTL = u.getenvDuration(c, "SQVS_PCK_CHAIN_CACHE_TTL",
+		"Time a verified PCK certificate chain is reused without fetching its CRLs, 0s disables it",
+		constants.DefaultPckChainCacheTTL)
+
+	u.Config.Outbound.RetryBudgetRatio = constants.DefaultOutboundRetryBudgetRatio
+	retryBudget, err := c.GetenvString("SQVS_OUTBOUND_RETRY_BUDGET", "Ratio of retries to collateral requests allowed")
+	if err == nil && retryBudget != "" {
+		ratio, err := strconv.ParseFloat(retryBudget, 64)
+		if err != nil || ratio <= 0 {
+			fmt.Fprintf(u.ConsoleWriter, "Invalid value provided for SQVS_OUTBOUND_RETRY_BUDGET setting it to the default value\n")
+		} else {
+			u.Config.Outbound.RetryBudgetRatio = ratio
+		}
+	}
+
+	breakerThreshold, err := c.GetenvInt("SQVS_OUTBOUND_BREAKER_THRESHOLD", "Consecutive failures after which requests to a host are stopped")
+	if err != nil {
+		u.Config.Outbound.BreakerFailureThreshold = constants.DefaultOutboundBreakerThreshold
+	} else {
+		u.Config.Outbound.BreakerFailureThreshold = breakerThreshold
+	}
+
+	u.Config.Outbound.BreakerOpenDuration = u.getenvDuration(c, "SQVS_OUTBOUND_BREAKER_OPEN_DURATION",
+		"Time requests to a failing host are stopped", constants.DefaultOutboundBreakerOpenDuration)
+	u.Config.Outbound.RetryLogInterval = u.getenvDuration(c, "SQVS_OUTBOUND_RETRY_LOG_INTERVAL",
+		"Interval the retries to a host are logged once per, the others being summarized",
+		constants.DefaultOutboundRetryLogInterval)
+
+	u.Config.TrustedTime.Source = constants.DefaultTrustedTimeSource
+	trustedTimeSource, err := c.GetenvString("SQVS_TRUSTED_TIME_SOURCE", "Time source of certificate and collateral validity checks, system or roughtime")
+	if err == nil && trustedTimeSource != "" {
+		switch trustedTimeSource {
+		case trustedtime.SourceSystem, trustedtime.SourceRoughtime:
+		default:
+			return errors.New("SaveConfiguration() SQVS_TRUSTED_TIME_SOURCE must be one of system, roughtime")
+		}
+		u.Config.TrustedTime.Source = trustedTimeSource
+	}
+	u.Config.TrustedTime.Offset = u.getenvDuration(c, "SQVS_TRUSTED_TIME_OFFSET",
+		"Offset added to the trusted time", 0)
+	u.Config.TrustedTime.RoughtimeRefresh = u.getenvDuration(c, "SQVS_TRUSTED_TIME_ROUGHTIME_REFRESH",
+		"Interval at which the Roughtime server is queried", constants.DefaultRoughtimeRefresh)
+	for _, env := range []struct {
+		name        string
+		description string
+		value       *string
+	}{
+		{"SQVS_TRUSTED_TIME_ROUGHTIME_SERVER", "Address of the Roughtime server", &u.Config.TrustedTime.RoughtimeServer},
+		{"SQVS_TRUSTED_TIME_ROUGHTIME_PUBLIC_KEY", "Public key of the Roughtime server", &u.Config.TrustedTime.RoughtimePublicKey},
+	} {
+		value, err := c.GetenvString(env.name, env.description)
+		if err == nil && value != "" {
+			*env.value = value
+		}
+	}
+	if u.Config.TrustedTime.Source == trustedtime.SourceRoughtime && (u.Config.TrustedTime.RoughtimeServer == "" ||
+		u.Config.TrustedTime.RoughtimePublicKey == "") {
+		return errors.New("SaveConfiguration() SQVS_TRUSTED_TIME_ROUGHTIME_SERVER and SQVS_TRUSTED_TIME_ROUGHTIME_PUBLIC_KEY must be set for the roughtime source")
+	}
+
 	logLevel, err := c.GetenvString(constants.SQVSLogLevel, "SQVS Log Level")
 	if err != nil {
 		slog.Infof("config/config:SaveConfiguration() %s not defined, using default log level: Info", constants.SQVSLogLevel)
@@ -125,6 +302,69 @@ func (u Update_Service_Config) Run(c setup.Context) error {
 		u.Config.LogMaxLength = logMaxLen
 	}
 
+	for _, format := range []struct {
+		env   string
+		field *string
+	}{
+		{"SQVS_LOG_FORMAT", &u.Config.LogFormat},
+		{"SQVS_SECURITY_LOG_FORMAT", &u.Config.SecurityLogFormat},
+	} {
+		value, err := c.GetenvString(format.env, "Format of the log records, text, json, cef or leef")
+		if err == nil && value != "" {
+			switch value {
+			case logformat.FormatText, logformat.FormatJSON, logformat.FormatCEF, logformat.FormatLEEF:
+			default:
+				return errors.New("SaveConfiguration() " + format.env + " must be one of text, json, cef, leef")
+			}
+			*format.field = value
+		} else if *format.field == "" {
+			*format.field = logformat.FormatText
+		}
+	}
+
+	logFileFormat, err := c.GetenvString("SQVS_LOG_FILE_FORMAT", "Format of the service log records, "+
+		"text, json, cef or leef")
+	if err == nil && logFileFormat != "" {
+		switch logFileFormat {
+		case logformat.FormatText, logformat.FormatJSON, logformat.FormatCEF, logformat.FormatLEEF:
+		default:
+			return errors.New("SaveConfiguration() SQVS_LOG_FILE_FORMAT must be one of text, json, cef, leef")
+		}
+		u.Config.LogFileFormat = logFileFormat
+	}
+
+	securityLogSinks, err := c.GetenvString("SQVS_SECURITY_LOG_SINKS", "Comma separated list of additional "+
+		"security log sinks, file=<file>;events=<event>|...;outcome=<outcome>;level=<level>;format=<format>")
+	if err == nil && securityLogSinks != "" {
+		u.Config.SecurityLogSinks = splitList(securityLogSinks)
+	}
+	for _, spec := range u.Config.SecurityLogSinks {
+		if _, err := logformat.ParseSinkSpec(spec); err != nil {
+			return errors.Wrap(err, "SaveConfiguration() Invalid SQVS_SECURITY_LOG_SINKS")
+		}
+	}
+
+	for _, sampling := range []struct {
+		env         string
+		description string
+		field       *int
+	}{
+		{"SQVS_LOG_SAMPLE_SUCCESSES", "Log 1 in N of the successful verification and authorization records",
+			&u.Config.LogSampleSuccesses},
+		{"SQVS_ACCESS_LOG_SAMPLE_SUCCESSES", "Log 1 in N of the successful requests in the access log",
+			&u.Config.AccessLogSampleSuccesses},
+	} {
+		rate, err := c.GetenvInt(sampling.env, sampling.description)
+		if err == nil {
+			if rate < 1 {
+				return errors.New("SaveConfiguration() " + sampling.env + " must be 1 or more")
+			}
+			*sampling.field = rate
+		} else if *sampling.field == 0 {
+			*sampling.field = constants.DefaultLogSampleRate
+		}
+	}
+
 	u.Config.LogEnableStdout = false
 	logEnableStdout, err := c.GetenvString("SQVS_ENABLE_CONSOLE_LOG", "SGX Verification Service Enable standard output")
 	if err != nil || len(logEnableStdout) == 0 {
@@ -142,6 +382,795 @@ func (u Update_Service_Config) Run(c setup.Context) error {
 		}
 	}
 
+	allowDebugEnclaves, err := c.GetenvString("SQVS_ALLOW_DEBUG_ENCLAVES", "Boolean value to accept quotes from "+
+		"enclaves running in debug mode")
+	if err == nil && allowDebugEnclaves != "" {
+		u.Config.AllowDebugEnclaves, err = strconv.ParseBool(allowDebugEnclaves)
+		if err != nil {
+			fmt.Fprintf(u.ConsoleWriter, "SQVS_ALLOW_DEBUG_ENCLAVES is not defined properly, must be true/false. Debug enclaves will be rejected\n")
+			u.Config.AllowDebugEnclaves = false
+		}
+	}
+
+	enableTcbDowngradeDetection, err := c.GetenvString("SQVS_ENABLE_TCB_DOWNGRADE_DETECTION", "Boolean value to "+
+		"enable tracking of per platform TCB status downgrades")
+	if err == nil && enableTcbDowngradeDetection != "" {
+		u.Config.EnableTcbDowngradeDetection, err = strconv.ParseBool(enableTcbDowngradeDetection)
+		if err != nil {
+			fmt.Fprintf(u.ConsoleWriter, "SQVS_ENABLE_TCB_DOWNGRADE_DETECTION is not defined properly, must be true/false. TCB downgrade detection will be disabled\n")
+			u.Config.EnableTcbDowngradeDetection = false
+		}
+	}
+
+	fipsMode, err := c.GetenvString("SQVS_FIPS_MODE", "Boolean value to restrict TLS, token and signature "+
+		"algorithms to FIPS approved ones")
+	if err == nil && fipsMode != "" {
+		u.Config.FipsMode, err = strconv.ParseBool(fipsMode)
+		if err != nil {
+			fmt.Fprintf(u.ConsoleWriter, "SQVS_FIPS_MODE is not defined properly, must be true/false. FIPS mode will be disabled\n")
+			u.Config.FipsMode = false
+		}
+	}
+
+	tlsPolicyMode, err := c.GetenvString("SQVS_TLS_POLICY_MODE", "Whether certificates below the TLS policy "+
+		"refuse the start, enforce, are logged, warn, or are not checked, off")
+	if err == nil && tlsPolicyMode != "" {
+		if err = tlspolicy.ValidateMode(tlsPolicyMode); err != nil {
+			return errors.Wrap(err, "SaveConfiguration() SQVS_TLS_POLICY_MODE provided is invalid")
+		}
+		u.Config.TLSPolicy.Mode = tlsPolicyMode
+	}
+	tlsMinRSAKeySize, err := c.GetenvInt("SQVS_TLS_MIN_RSA_KEY_SIZE", "Minimum size in bits of the RSA keys "+
+		"of the TLS and trusted CA certificates")
+	if err == nil {
+		if tlsMinRSAKeySize <= 0 {
+			fmt.Fprintf(u.ConsoleWriter, "Invalid value provided for SQVS_TLS_MIN_RSA_KEY_SIZE setting it to the default value\n")
+			tlsMinRSAKeySize = constants.DefaultTLSMinRSAKeySize
+		}
+		u.Config.TLSPolicy.MinRSAKeySize = tlsMinRSAKeySize
+	}
+	tlsMinECDSAKeySize, err := c.GetenvInt("SQVS_TLS_MIN_ECDSA_KEY_SIZE", "Minimum size in bits of the ECDSA "+
+		"keys of the TLS and trusted CA certificates")
+	if err == nil {
+		if tlsMinECDSAKeySize <= 0 {
+			fmt.Fprintf(u.ConsoleWriter, "Invalid value provided for SQVS_TLS_MIN_ECDSA_KEY_SIZE setting it to the default value\n")
+			tlsMinECDSAKeySize = constants.DefaultTLSMinECDSAKeySize
+		}
+		u.Config.TLSPolicy.MinECDSAKeySize = tlsMinECDSAKeySize
+	}
+	u.Config.TLSPolicy.MinValidity = u.getenvDuration(c, "SQVS_TLS_MIN_VALIDITY", "Minimum remaining validity "+
+		"of the TLS and trusted CA certificates", 0)
+	disableLegacyCiphers, err := c.GetenvString("SQVS_TLS_DISABLE_LEGACY_CIPHERS", "Boolean value to restrict "+
+		"the TLS 1.2 connections to the dependencies to ECDHE AEAD cipher suites")
+	if err == nil && disableLegacyCiphers != "" {
+		u.Config.TLSPolicy.DisableLegacyCiphers, err = strconv.ParseBool(disableLegacyCiphers)
+		if err != nil {
+			fmt.Fprintf(u.ConsoleWriter, "SQVS_TLS_DISABLE_LEGACY_CIPHERS is not defined properly, must be true/false. Legacy cipher suites will be disabled\n")
+			u.Config.TLSPolicy.DisableLegacyCiphers = true
+		}
+	}
+
+	requirePlatformEnrollment, err := c.GetenvString("SQVS_REQUIRE_PLATFORM_ENROLLMENT", "Boolean value to "+
+		"reject quotes from platforms that have not been enrolled")
+	if err == nil && requirePlatformEnrollment != "" {
+		u.Config.RequirePlatformEnrollment, err = strconv.ParseBool(requirePlatformEnrollment)
+		if err != nil {
+			fmt.Fprintf(u.ConsoleWriter, "SQVS_REQUIRE_PLATFORM_ENROLLMENT is not defined properly, must be true/false. Platform enrollment will not be required\n")
+			u.Config.RequirePlatformEnrollment = false
+		}
+	}
+
+	requireRegisteredEnclaves, err := c.GetenvString("SQVS_REQUIRE_REGISTERED_ENCLAVES", "Boolean value to "+
+		"reject quotes from enclaves that have not been registered")
+	if err == nil && requireRegisteredEnclaves != "" {
+		u.Config.RequireRegisteredEnclaves, err = strconv.ParseBool(requireRegisteredEnclaves)
+		if err != nil {
+			fmt.Fprintf(u.ConsoleWriter, "SQVS_REQUIRE_REGISTERED_ENCLAVES is not defined properly, must be true/false. Registered enclaves will not be required\n")
+			u.Config.RequireRegisteredEnclaves = false
+		}
+	}
+
+	requireSignedRequests, err := c.GetenvString("SQVS_REQUIRE_SIGNED_REQUESTS", "Boolean value to "+
+		"reject quote appraisal requests not signed by a registered relying party")
+	if err == nil && requireSignedRequests != "" {
+		u.Config.RequireSignedRequests, err = strconv.ParseBool(requireSignedRequests)
+		if err != nil {
+			fmt.Fprintf(u.ConsoleWriter, "SQVS_REQUIRE_SIGNED_REQUESTS is not defined properly, must be true/false. Request signatures will not be required\n")
+			u.Config.RequireSignedRequests = false
+		}
+	}
+	u.Config.RequestSignatureMaxAge = u.getenvDuration(c, "SQVS_REQUEST_SIGNATURE_MAX_AGE",
+		"Time a request signature is accepted for before and after its iat", constants.DefaultRequestSignatureMaxAge)
+
+	requireSignedPolicies, err := c.GetenvString("SQVS_REQUIRE_SIGNED_POLICIES", "Boolean value to "+
+		"reject the custom claims policy unless it is signed by a registered policy author")
+	if err == nil && requireSignedPolicies != "" {
+		u.Config.RequireSignedPolicies, err = strconv.ParseBool(requireSignedPolicies)
+		if err != nil {
+			fmt.Fprintf(u.ConsoleWriter, "SQVS_REQUIRE_SIGNED_POLICIES is not defined properly, must be true/false. Policy signatures will not be required\n")
+			u.Config.RequireSignedPolicies = false
+		}
+	}
+
+	enableVerificationHistory, err := c.GetenvString("SQVS_ENABLE_VERIFICATION_HISTORY", "Boolean value to "+
+		"record the outcome of each quote verification")
+	if err == nil && enableVerificationHistory != "" {
+		u.Config.EnableVerificationHistory, err = strconv.ParseBool(enableVerificationHistory)
+		if err != nil {
+			fmt.Fprintf(u.ConsoleWriter, "SQVS_ENABLE_VERIFICATION_HISTORY is not defined properly, must be true/false. Verification history will be disabled\n")
+			u.Config.EnableVerificationHistory = false
+		}
+	}
+
+	enableTestMode, err := c.GetenvString("SQVS_ENABLE_TEST_MODE", "Boolean value to serve canned verdicts "+
+		"of test quotes under /svs/test/v1/, in test builds only")
+	if err == nil && enableTestMode != "" {
+		u.Config.EnableTestMode, err = strconv.ParseBool(enableTestMode)
+		if err != nil {
+			fmt.Fprintf(u.ConsoleWriter, "SQVS_ENABLE_TEST_MODE is not defined properly, must be true/false. Test mode will be disabled\n")
+			u.Config.EnableTestMode = false
+		}
+	}
+
+	retainRawQuotes, err := c.GetenvString("SQVS_RETAIN_RAW_QUOTES", "Boolean value to keep the raw quote "+
+		"with each verification of the history")
+	if err == nil && retainRawQuotes != "" {
+		u.Config.RetainRawQuotes, err = strconv.ParseBool(retainRawQuotes)
+		if err != nil {
+			fmt.Fprintf(u.ConsoleWriter, "SQVS_RETAIN_RAW_QUOTES is not defined properly, must be true/false. Raw quote retention will be disabled\n")
+			u.Config.RetainRawQuotes = false
+		}
+	}
+	rawQuoteTenants, err := c.GetenvString("SQVS_RAW_QUOTE_TENANTS", "Tenants whose raw quotes are retained")
+	if err == nil {
+		u.Config.RawQuotes.Tenants = splitList(rawQuoteTenants)
+	}
+	rawQuoteKeyFile, err := c.GetenvString("SQVS_RAW_QUOTE_KEY_FILE", "File with the base64 encoded AES-256 key "+
+		"the raw quotes are encrypted with")
+	if err == nil && rawQuoteKeyFile != "" {
+		u.Config.RawQuotes.KeyFile = rawQuoteKeyFile
+	}
+	rawQuoteTransitKey, err := c.GetenvString("SQVS_RAW_QUOTE_VAULT_TRANSIT_KEY", "Vault transit key the raw "+
+		"quotes are encrypted with")
+	if err == nil && rawQuoteTransitKey != "" {
+		u.Config.RawQuotes.VaultTransitKey = rawQuoteTransitKey
+	}
+	rawQuoteTransitMount, err := c.GetenvString("SQVS_RAW_QUOTE_VAULT_TRANSIT_MOUNT", "Vault transit secrets "+
+		"engine mount of the raw quote key")
+	if err == nil && rawQuoteTransitMount != "" {
+		u.Config.RawQuotes.VaultTransitMount = rawQuoteTransitMount
+	}
+	if u.Config.RetainRawQuotes && u.Config.RawQuotes.KeyFile == "" && u.Config.RawQuotes.VaultTransitKey == "" {
+		fmt.Fprintf(u.ConsoleWriter, "SQVS_RETAIN_RAW_QUOTES requires SQVS_RAW_QUOTE_KEY_FILE or "+
+			"SQVS_RAW_QUOTE_VAULT_TRANSIT_KEY, raw quotes are not stored in plaintext. Raw quote retention will be disabled\n")
+		u.Config.RetainRawQuotes = false
+	}
+
+	enableResultRevocation, err := c.GetenvString("SQVS_ENABLE_RESULT_REVOCATION", "Boolean value to "+
+		"revoke signed results and publish the revocation list")
+	if err == nil && enableResultRevocation != "" {
+		u.Config.EnableResultRevocation, err = strconv.ParseBool(enableResultRevocation)
+		if err != nil {
+			fmt.Fprintf(u.ConsoleWriter, "SQVS_ENABLE_RESULT_REVOCATION is not defined properly, must be true/false. Result revocation will be disabled\n")
+			u.Config.EnableResultRevocation = false
+		}
+	}
+
+	enableCollateralHistory, err := c.GetenvString("SQVS_ENABLE_COLLATERAL_HISTORY", "Boolean value to keep "+
+		"the versions of the collateral quotes are verified with")
+	if err == nil && enableCollateralHistory != "" {
+		u.Config.EnableCollateralHistory, err = strconv.ParseBool(enableCollateralHistory)
+		if err != nil {
+			fmt.Fprintf(u.ConsoleWriter, "SQVS_ENABLE_COLLATERAL_HISTORY is not defined properly, must be true/false. Collateral history will be disabled\n")
+			u.Config.EnableCollateralHistory = false
+		}
+	}
+
+	enableQuota, err := c.GetenvString("SQVS_QUOTA_ENABLED", "Boolean value to count the verification requests "+
+		"of every tenant and enforce their quotas")
+	if err == nil && enableQuota != "" {
+		u.Config.Quota.Enabled, err = strconv.ParseBool(enableQuota)
+		if err != nil {
+			fmt.Fprintf(u.ConsoleWriter, "SQVS_QUOTA_ENABLED is not defined properly, must be true/false. Quotas will be disabled\n")
+			u.Config.Quota.Enabled = false
+		}
+	}
+	tenantClaim, err := c.GetenvString("SQVS_QUOTA_TENANT_CLAIM", "Token claim identifying the tenant of a request")
+	if err == nil && tenantClaim != "" {
+		u.Config.Quota.TenantClaim = tenantClaim
+	} else if u.Config.Quota.TenantClaim == "" {
+		u.Config.Quota.TenantClaim = constants.DefaultQuotaTenantClaim
+	}
+	quotaEnforcement, err := c.GetenvString("SQVS_QUOTA_ENFORCEMENT", "Action taken on requests beyond a quota, warn or reject")
+	if err == nil && quotaEnforcement != "" {
+		if quotaEnforcement != quota.EnforcementWarn && quotaEnforcement != quota.EnforcementReject {
+			return errors.New("SaveConfiguration() SQVS_QUOTA_ENFORCEMENT must be one of warn, reject")
+		}
+		u.Config.Quota.Enforcement = quotaEnforcement
+	} else if u.Config.Quota.Enforcement == "" {
+		u.Config.Quota.Enforcement = quota.EnforcementWarn
+	}
+	quotaLimits, err := c.GetenvString("SQVS_QUOTA_LIMITS", "Comma separated list of [route:]daily=N or "+
+		"[route:]monthly=N quota limits")
+	if err == nil && quotaLimits != "" {
+		u.Config.Quota.Limits = splitList(quotaLimits)
+	}
+	if _, err = quota.ParseLimits(u.Config.Quota.Limits); err != nil {
+		return errors.Wrap(err, "SaveConfiguration() SQVS_QUOTA_LIMITS provided is invalid")
+	}
+
+	enableAnomaly, err := c.GetenvString("SQVS_ANOMALY_DETECTION_ENABLED", "Boolean value to flag anomalous "+
+		"quote patterns as security events")
+	if err == nil && enableAnomaly != "" {
+		u.Config.Anomaly.Enabled, err = strconv.ParseBool(enableAnomaly)
+		if err != nil {
+			fmt.Fprintf(u.ConsoleWriter, "SQVS_ANOMALY_DETECTION_ENABLED is not defined properly, must be true/false. Anomaly detection will be disabled\n")
+			u.Config.Anomaly.Enabled = false
+		}
+	}
+	u.Config.Anomaly.Window = u.getenvDuration(c, "SQVS_ANOMALY_WINDOW",
+		"Sliding window anomalous quote patterns are detected in", constants.DefaultAnomalyWindow)
+	for _, threshold := range []struct {
+		name         string
+		description  string
+		value        *int
+		defaultValue int
+	}{
+		{"SQVS_ANOMALY_FAILED_VERIFICATIONS", "Failed verifications from one caller within the window flagged as anomalous",
+			&u.Config.Anomaly.FailedVerifications, constants.DefaultAnomalyFailedVerifications},
+		{"SQVS_ANOMALY_DEBUG_QUOTES", "Quotes of DEBUG enclaves within the window flagged as anomalous",
+			&u.Config.Anomaly.DebugQuotes, constants.DefaultAnomalyDebugQuotes},
+		{"SQVS_ANOMALY_DISTINCT_MEASUREMENTS", "Distinct MRENCLAVEs verified by one tenant within the window flagged as anomalous",
+			&u.Config.Anomaly.DistinctMeasurements, constants.DefaultAnomalyDistinctMeasurements},
+	} {
+		value, err := c.GetenvInt(threshold.name, threshold.description)
+		if err == nil && value < 0 {
+			fmt.Fprintf(u.ConsoleWriter, "Invalid value provided for %s setting it to the default value\n", threshold.name)
+		}
+		if err != nil || value < 0 {
+			*threshold.value = threshold.defaultValue
+		} else {
+			*threshold.value = value
+		}
+	}
+	anomalyWebhookURL, err := c.GetenvString("SQVS_ANOMALY_WEBHOOK_URL", "Webhook URL to which anomalous quote "+
+		"patterns are posted")
+	if err == nil && anomalyWebhookURL != "" {
+		if _, err = url.ParseRequestURI(anomalyWebhookURL); err != nil {
+			return errors.Wrap(err, "SaveConfiguration() SQVS_ANOMALY_WEBHOOK_URL provided is invalid")
+		}
+		u.Config.Anomaly.WebhookURL = anomalyWebhookURL
+	}
+
+	expiryAlertThresholds, err := c.GetenvString("SQVS_EXPIRY_ALERT_THRESHOLDS", "Comma separated list of durations "+
+		"before the expiry of certificates and collateral alerts are raised at")
+	if err == nil && expiryAlertThresholds != "" {
+		u.Config.Expiry.AlertThresholds, err = splitDurationList(expiryAlertThresholds)
+		if err != nil {
+			return errors.Wrap(err, "SaveConfiguration() SQVS_EXPIRY_ALERT_THRESHOLDS provided is invalid")
+		}
+	} else if len(u.Config.Expiry.AlertThresholds) == 0 {
+		u.Config.Expiry.AlertThresholds, _ = splitDurationList(constants.DefaultExpiryAlertThresholds)
+	}
+	u.Config.Expiry.CheckInterval = u.getenvDuration(c, "SQVS_EXPIRY_CHECK_INTERVAL",
+		"Interval certificates and collateral are checked for expiry at, 0 to disable", constants.DefaultExpiryCheckInterval)
+
+	collateralCheckURL, err := c.GetenvString("SQVS_COLLATERAL_CHECK_URL", "Base URL of the secondary source, "+
+		"Intel PCS, the TCB info and QE identity of SCS are cross-checked against")
+	if err == nil && collateralCheckURL != "" {
+		if _, err = url.ParseRequestURI(collateralCheckURL); err != nil {
+			return errors.Wrap(err, "SaveConfiguration() SQVS_COLLATERAL_CHECK_URL provided is invalid")
+		}
+		u.Config.CollateralCheck.URL = collateralCheckURL
+	}
+	collateralCheckMode, err := c.GetenvString("SQVS_COLLATERAL_CHECK_MODE", "Action taken on collateral that "+
+		"does not match the secondary source, enforce or warn")
+	if err == nil && collateralCheckMode != "" {
+		switch collateralCheckMode {
+		case parser.CollateralCheckEnforce, parser.CollateralCheckWarn:
+			u.Config.CollateralCheck.Mode = collateralCheckMode
+		default:
+			return errors.Errorf("SaveConfiguration() SQVS_COLLATERAL_CHECK_MODE provided is invalid, must be %s or %s",
+				parser.CollateralCheckEnforce, parser.CollateralCheckWarn)
+		}
+	} else if u.Config.CollateralCheck.Mode == "" {
+		u.Config.CollateralCheck.Mode = constants.DefaultCollateralCheckMode
+	}
+	u.Config.CollateralCheck.CacheTTL = u.getenvDuration(c, "SQVS_COLLATERAL_CHECK_CACHE_TTL",
+		"Time the collateral of the secondary source is cached for", constants.DefaultCollateralCheckCacheTTL)
+	collateralFailureMode, err := c.GetenvString("SQVS_COLLATERAL_FAILURE_MODE", "How quotes are verified when "+
+		"fresh collateral cannot be fetched, fail-closed, use-cached or fail-open")
+	if err == nil && collateralFailureMode != "" {
+		switch collateralFailureMode {
+		case config.CollateralFailClosed, config.CollateralUseCached, config.CollateralFailOpen:
+			u.Config.CollateralFailure.Mode = collateralFailureMode
+		default:
+			return errors.Errorf("SaveConfiguration() SQVS_COLLATERAL_FAILURE_MODE provided is invalid, must be %s, "+
+				"%s or %s", config.CollateralFailClosed, config.CollateralUseCached, config.CollateralFailOpen)
+		}
+	} else if u.Config.CollateralFailure.Mode == "" {
+		u.Config.CollateralFailure.Mode = constants.DefaultCollateralFailureMode
+	}
+	u.Config.CollateralFailure.GracePeriod = u.getenvDuration(c, "SQVS_COLLATERAL_GRACE_PERIOD",
+		"Time cached collateral is used for past its next update in use-cached mode",
+		constants.DefaultCollateralGracePeriod)
+	u.Config.CollateralCacheTTL = u.getenvDuration(c, "SQVS_COLLATERAL_CACHE_TTL",
+		"Time the collateral served to relying parties is cached for", constants.DefaultCollateralCacheTTL)
+	u.Config.SessionTTL = u.getenvDuration(c, "SQVS_SESSION_TTL",
+		"Time a verification session is open for", constants.DefaultSessionTTL)
+	platformIdentifiers, err := c.GetenvString("SQVS_PLATFORM_IDENTIFIERS", "Redaction profile of the PPID "+
+		"and platform instance ID in the results, redacted, pseudonymized or full")
+	if err == nil && platformIdentifiers != "" {
+		switch platformIdentifiers {
+		case config.PlatformIdentifiersRedacted, config.PlatformIdentifiersPseudonymized, config.PlatformIdentifiersFull:
+			u.Config.PlatformIdentifiers = platformIdentifiers
+		default:
+			return errors.Errorf("SaveConfiguration() SQVS_PLATFORM_IDENTIFIERS provided is invalid, must be %s, "+
+				"%s or %s", config.PlatformIdentifiersRedacted, config.PlatformIdentifiersPseudonymized,
+				config.PlatformIdentifiersFull)
+		}
+	} else if u.Config.PlatformIdentifiers == "" {
+		u.Config.PlatformIdentifiers = constants.DefaultPlatformIdentifiers
+	}
+	features, err := c.GetenvString("SQVS_FEATURES", "Comma separated list of <flag>=<true|false> feature flags")
+	if err == nil && features != "" {
+		u.Config.Features, err = config.ParseFeatures(features)
+		if err != nil {
+			return errors.Wrap(err, "SaveConfiguration() SQVS_FEATURES provided is invalid")
+		}
+	}
+
+	dbDriver, err := c.GetenvString("SQVS_DB_DRIVER", "Storage driver of the verification history, memory, sqlite or postgres")
+	if err == nil && dbDriver != "" {
+		switch dbDriver {
+		case repository.DriverMemory, repository.DriverSQLite, repository.DriverPostgres:
+		default:
+			return errors.New("SaveConfiguration() SQVS_DB_DRIVER must be one of memory, sqlite, postgres")
+		}
+		u.Config.Database.Driver = dbDriver
+	}
+
+	databaseEnv := []struct {
+		name        string
+		description string
+		value       *string
+	}{
+		{"SQVS_DB_FILE", "Snapshot file of the memory driver or database file of the sqlite driver", &u.Config.Database.File},
+		{"SQVS_DB_HOSTNAME", "Postgres database hostname", &u.Config.Database.Host},
+		{"SQVS_DB_NAME", "Postgres database name", &u.Config.Database.Name},
+		{"SQVS_DB_USERNAME", "Postgres database username", &u.Config.Database.Username},
+		{"SQVS_DB_PASSWORD_FILE", "File holding the Postgres database password", &u.Config.Database.PasswordFile},
+		{"SQVS_DB_SSLMODE", "Postgres SSL mode", &u.Config.Database.SSLMode},
+		{"SQVS_DB_SSLCERT", "CA certificate file the Postgres server certificate is verified with", &u.Config.Database.SSLCert},
+	}
+	for _, env := range databaseEnv {
+		value, err := c.GetenvString(env.name, env.description)
+		if err == nil && value != "" {
+			*env.value = value
+		}
+	}
+
+	dbPort, err := c.GetenvInt("SQVS_DB_PORT", "Postgres database port")
+	if err == nil {
+		if dbPort <= 0 || dbPort > 65535 {
+			fmt.Fprintf(u.ConsoleWriter, "Invalid value provided for SQVS_DB_PORT setting it to the default value\n")
+			dbPort = constants.DefaultDBPort
+		}
+		u.Config.Database.Port = dbPort
+	}
+	if u.Config.Database.Driver == repository.DriverPostgres &&
+		(u.Config.Database.Host == "" || u.Config.Database.Name == "" || u.Config.Database.Username == "") {
+		return errors.New("SaveConfiguration() SQVS_DB_HOSTNAME, SQVS_DB_NAME and SQVS_DB_USERNAME must be set for the postgres driver")
+	}
+
+	retentionDays, err := c.GetenvInt("SQVS_HISTORY_RETENTION_DAYS", "Number of days verification history is kept")
+	if err == nil {
+		if retentionDays < 0 {
+			fmt.Fprintf(u.ConsoleWriter, "Invalid value provided for SQVS_HISTORY_RETENTION_DAYS, verification history will be kept without a time limit\n")
+			retentionDays = 0
+		}
+		u.Config.Retention.RetentionDays = retentionDays
+	}
+
+	historyMaxRecords, err := c.GetenvInt("SQVS_HISTORY_MAX_RECORDS", "Maximum number of verification history records kept")
+	if err == nil {
+		if historyMaxRecords < 0 {
+			fmt.Fprintf(u.ConsoleWriter, "Invalid value provided for SQVS_HISTORY_MAX_RECORDS, verification history will be kept without a record limit\n")
+			historyMaxRecords = 0
+		}
+		u.Config.Retention.MaxRecords = historyMaxRecords
+	}
+
+	u.Config.Retention.PruneInterval = u.getenvDuration(c, "SQVS_HISTORY_PRUNE_INTERVAL",
+		"Interval at which the verification history is pruned", constants.DefaultHistoryPruneInterval)
+
+	retentionEnv := []struct {
+		name        string
+		description string
+		value       *string
+	}{
+		{"SQVS_HISTORY_EXPORT_DIR", "Directory pruned verification records are exported to", &u.Config.Retention.ExportDir},
+		{"SQVS_HISTORY_EXPORT_S3_URL", "S3 URL pruned verification records are exported to", &u.Config.Retention.ExportS3URL},
+		{"SQVS_HISTORY_EXPORT_S3_REGION", "Region of the S3 export bucket", &u.Config.Retention.ExportS3Region},
+		{"SQVS_HISTORY_EXPORT_S3_CREDENTIALS_FILE", "File holding the S3 credentials", &u.Config.Retention.ExportS3CredentialsFile},
+	}
+	for _, env := range retentionEnv {
+		value, err := c.GetenvString(env.name, env.description)
+		if err == nil && value != "" {
+			*env.value = value
+		}
+	}
+	if u.Config.Retention.ExportDir != "" && u.Config.Retention.ExportS3URL != "" {
+		return errors.New("SaveConfiguration() Only one of SQVS_HISTORY_EXPORT_DIR and SQVS_HISTORY_EXPORT_S3_URL can be set")
+	}
+	if u.Config.Retention.ExportS3URL != "" {
+		if _, err = url.ParseRequestURI(u.Config.Retention.ExportS3URL); err != nil {
+			return errors.Wrap(err, "SaveConfiguration() SQVS_HISTORY_EXPORT_S3_URL provided is invalid")
+		}
+	}
+
+	quoteSource, err := c.GetenvString("SQVS_VERIFIER_QUOTE_SOURCE", "Source of the quotes of the SQVS enclave, helper or gramine")
+	if err == nil && quoteSource != "" {
+		if quoteSource != quoteprovider.TypeHelper && quoteSource != quoteprovider.TypeGramine {
+			return errors.New("SaveConfiguration() SQVS_VERIFIER_QUOTE_SOURCE must be one of helper, gramine")
+		}
+		u.Config.VerifierEvidence.QuoteSource = quoteSource
+	}
+	quoteHelper, err := c.GetenvString("SQVS_VERIFIER_QUOTE_HELPER", "Program generating the quotes of the SQVS enclave")
+	if err == nil && quoteHelper != "" {
+		u.Config.VerifierEvidence.QuoteHelper = quoteHelper
+	}
+	if u.Config.VerifierEvidence.QuoteSource == quoteprovider.TypeHelper && u.Config.VerifierEvidence.QuoteHelper == "" {
+		return errors.New("SaveConfiguration() SQVS_VERIFIER_QUOTE_HELPER must be set when the quote source is helper")
+	}
+	u.Config.VerifierEvidence.RefreshInterval = u.getenvDuration(c, "SQVS_VERIFIER_EVIDENCE_REFRESH",
+		"Interval at which verifier evidence without a nonce is regenerated", constants.DefaultVerifierEvidenceRefresh)
+
+	webhookURL, err := c.GetenvString("SQVS_WEBHOOK_URL", "Webhook URL to which SQVS alerts are posted")
+	if err == nil && webhookURL != "" {
+		if _, err = url.ParseRequestURI(webhookURL); err != nil {
+			return errors.Wrap(err, "SaveConfiguration() SQVS_WEBHOOK_URL provided is invalid")
+		}
+		u.Config.WebhookURL = webhookURL
+	}
+
+	resultEventsBroker, err := c.GetenvString("SQVS_RESULT_EVENTS_BROKER", "Message queue verification results are "+
+		"published to, kafka or nats")
+	if err == nil && resultEventsBroker != "" {
+		if resultEventsBroker != events.BrokerKafka && resultEventsBroker != events.BrokerNATS {
+			return errors.New("SaveConfiguration() SQVS_RESULT_EVENTS_BROKER must be one of kafka, nats")
+		}
+		u.Config.ResultEvents.Broker = resultEventsBroker
+	}
+	resultEventsAddresses, err := c.GetenvString("SQVS_RESULT_EVENTS_ADDRESSES", "Comma separated list of "+
+		"the Kafka brokers or NATS server URLs")
+	if err == nil && resultEventsAddresses != "" {
+		u.Config.ResultEvents.Addresses = splitList(resultEventsAddresses)
+	}
+	resultEventsTopic, err := c.GetenvString("SQVS_RESULT_EVENTS_TOPIC", "Kafka topic or NATS subject "+
+		"verification results are published to")
+	if err == nil && resultEventsTopic != "" {
+		u.Config.ResultEvents.Topic = resultEventsTopic
+	}
+	resultEventsTLS, err := c.GetenvString("SQVS_RESULT_EVENTS_TLS", "Boolean value to connect to the "+
+		"message queue over TLS")
+	if err == nil && resultEventsTLS != "" {
+		u.Config.ResultEvents.TLS, err = strconv.ParseBool(resultEventsTLS)
+		if err != nil {
+			fmt.Fprintf(u.ConsoleWriter, "SQVS_RESULT_EVENTS_TLS is not defined properly, must be true/false. TLS will be enabled\n")
+			u.Config.ResultEvents.TLS = true
+		}
+	}
+	resultEventsEncoding, err := c.GetenvString("SQVS_RESULT_EVENTS_ENCODING", "Encoding of the verification "+
+		"result events, json or cbor")
+	if err == nil && resultEventsEncoding != "" {
+		if resultEventsEncoding != events.EncodingJSON && resultEventsEncoding != events.EncodingCBOR {
+			return errors.New("SaveConfiguration() SQVS_RESULT_EVENTS_ENCODING must be one of json, cbor")
+		}
+		u.Config.ResultEvents.Encoding = resultEventsEncoding
+	}
+	resultEventsBatchSize, err := c.GetenvInt("SQVS_RESULT_EVENTS_BATCH_SIZE", "Maximum number of verification "+
+		"results sent in a batch")
+	if err == nil {
+		if resultEventsBatchSize <= 0 {
+			fmt.Fprintf(u.ConsoleWriter, "Invalid value provided for SQVS_RESULT_EVENTS_BATCH_SIZE setting it to the default value\n")
+			resultEventsBatchSize = constants.DefaultResultEventsBatchSize
+		}
+		u.Config.ResultEvents.BatchSize = resultEventsBatchSize
+	}
+	u.Config.ResultEvents.FlushInterval = u.getenvDuration(c, "SQVS_RESULT_EVENTS_FLUSH_INTERVAL",
+		"Interval at which batches of verification results are sent", constants.DefaultResultEventsFlushInterval)
+	resultEventsQueueSize, err := c.GetenvInt("SQVS_RESULT_EVENTS_QUEUE_SIZE", "Maximum number of verification "+
+		"results buffered while the message queue is unreachable")
+	if err == nil {
+		if resultEventsQueueSize <= 0 {
+			fmt.Fprintf(u.ConsoleWriter, "Invalid value provided for SQVS_RESULT_EVENTS_QUEUE_SIZE setting it to the default value\n")
+			resultEventsQueueSize = constants.DefaultResultEventsQueueSize
+		}
+		u.Config.ResultEvents.QueueSize = resultEventsQueueSize
+	}
+	if u.Config.ResultEvents.Broker != "" &&
+		(len(u.Config.ResultEvents.Addresses) == 0 || u.Config.ResultEvents.Topic == "") {
+		return errors.New("SaveConfiguration() SQVS_RESULT_EVENTS_ADDRESSES and SQVS_RESULT_EVENTS_TOPIC must be set when a result events broker is configured")
+	}
+
+	keyReleaseBroker, err := c.GetenvString("SQVS_KEY_RELEASE_BROKER", "Key broker plugin keys are released "+
+		"to verified enclaves with, webhook")
+	if err == nil && keyReleaseBroker != "" {
+		u.Config.KeyRelease.Broker = keyReleaseBroker
+	}
+	if broker := u.Config.KeyRelease.Broker; broker != "" {
+		known := false
+		for _, name := range keyrelease.Brokers() {
+			known = known || name == broker
+		}
+		if !known {
+			return errors.Errorf("SaveConfiguration() SQVS_KEY_RELEASE_BROKER must be one of %s",
+				strings.Join(keyrelease.Brokers(), ", "))
+		}
+	}
+	keyReleaseURL, err := c.GetenvString("SQVS_KEY_RELEASE_URL", "URL of the key broker the verified enclave "+
+		"identities are posted to")
+	if err == nil && keyReleaseURL != "" {
+		if _, err = url.ParseRequestURI(keyReleaseURL); err != nil {
+			return errors.Wrap(err, "SaveConfiguration() SQVS_KEY_RELEASE_URL provided is invalid")
+		}
+		u.Config.KeyRelease.URL = keyReleaseURL
+	}
+	u.Config.KeyRelease.Timeout = u.getenvDuration(c, "SQVS_KEY_RELEASE_TIMEOUT",
+		"Time the key broker is waited for", constants.DefaultKeyReleaseTimeout)
+	if u.Config.KeyRelease.Broker == keyrelease.BrokerWebhook && u.Config.KeyRelease.URL == "" {
+		return errors.New("SaveConfiguration() SQVS_KEY_RELEASE_URL must be set when the webhook key broker is configured")
+	}
+
+	payloadCaptureEnabled, err := c.GetenvString("SQVS_PAYLOAD_CAPTURE_ENABLED", "Boolean value to allow "+
+		"administrators to capture redacted request and response payloads for a limited time")
+	if err == nil && payloadCaptureEnabled != "" {
+		u.Config.PayloadCapture.Enabled, err = strconv.ParseBool(payloadCaptureEnabled)
+		if err != nil {
+			fmt.Fprintf(u.ConsoleWriter, "SQVS_PAYLOAD_CAPTURE_ENABLED is not defined properly, must be true/false. Payload capture will be disabled\n")
+			u.Config.PayloadCapture.Enabled = false
+		}
+	}
+	payloadCaptureFile, err := c.GetenvString("SQVS_PAYLOAD_CAPTURE_FILE", "File the captured payloads are "+
+		"appended to")
+	if err == nil && payloadCaptureFile != "" {
+		u.Config.PayloadCapture.File = payloadCaptureFile
+	} else if u.Config.PayloadCapture.File == "" {
+		u.Config.PayloadCapture.File = constants.DefaultPayloadCaptureFile
+	}
+	u.Config.PayloadCapture.MaxDuration = u.getenvDuration(c, "SQVS_PAYLOAD_CAPTURE_MAX_DURATION",
+		"Longest payload capture administrators may start", constants.DefaultPayloadCaptureDuration)
+	payloadCaptureMaxBodySize, err := c.GetenvInt("SQVS_PAYLOAD_CAPTURE_MAX_BODY_SIZE", "Size in bytes the "+
+		"captured bodies are truncated at")
+	if err == nil {
+		if payloadCaptureMaxBodySize <= 0 {
+			fmt.Fprintf(u.ConsoleWriter, "Invalid value provided for SQVS_PAYLOAD_CAPTURE_MAX_BODY_SIZE setting it to the default value\n")
+			payloadCaptureMaxBodySize = constants.DefaultPayloadCaptureBodySize
+		}
+		u.Config.PayloadCapture.MaxBodySize = payloadCaptureMaxBodySize
+	}
+	payloadCaptureRedactFields, err := c.GetenvString("SQVS_PAYLOAD_CAPTURE_REDACT_FIELDS", "Comma separated "+
+		"list of the headers, query parameters and JSON fields redacted from the captures")
+	if err == nil && payloadCaptureRedactFields != "" {
+		u.Config.PayloadCapture.RedactFields = splitList(payloadCaptureRedactFields)
+	}
+
+	allowedFmspcs, err := c.GetenvString("SQVS_PCK_ALLOWED_FMSPCS", "Comma separated list of the FMSPCs of "+
+		"the platforms whose quotes are accepted")
+	if err == nil && allowedFmspcs != "" {
+		u.Config.PckPolicy.AllowedFmspcs = splitList(allowedFmspcs)
+	}
+	for _, fmspc := range u.Config.PckPolicy.AllowedFmspcs {
+		value, err := hex.DecodeString(fmspc)
+		if err != nil || len(value) != parser.FmspcSize {
+			return errors.Errorf("SaveConfiguration() SQVS_PCK_ALLOWED_FMSPCS entry %s is not a hex encoded FMSPC", fmspc)
+		}
+	}
+	allowedSgxTypes, err := c.GetenvString("SQVS_PCK_ALLOWED_SGX_TYPES", "Comma separated list of the SGX "+
+		"types of the platforms whose quotes are accepted")
+	if err == nil && allowedSgxTypes != "" {
+		u.Config.PckPolicy.AllowedSgxTypes = splitList(allowedSgxTypes)
+	}
+	for _, sgxType := range u.Config.PckPolicy.AllowedSgxTypes {
+		if _, ok := parser.ParseSgxType(sgxType); !ok {
+			return errors.New("SaveConfiguration() SQVS_PCK_ALLOWED_SGX_TYPES must be one of Standard, Scalable, " +
+				"ScalableWithIntegrity")
+		}
+	}
+	tcbStatusVerdicts, err := c.GetenvString("SQVS_TCB_STATUS_VERDICTS", "Comma separated list of Status=verdict "+
+		"entries mapping TCB statuses to allow, warn or deny")
+	if err == nil && tcbStatusVerdicts != "" {
+		u.Config.TcbStatusVerdicts = splitList(tcbStatusVerdicts)
+	}
+	if _, err = parser.ParseTcbStatusVerdicts(u.Config.TcbStatusVerdicts); err != nil {
+		return errors.Wrap(err, "SaveConfiguration() SQVS_TCB_STATUS_VERDICTS provided is invalid")
+	}
+
+	ipAllowList, err := c.GetenvString("SQVS_IP_ALLOW_LIST", "Comma separated list of CIDR blocks or addresses "+
+		"of the clients allowed to reach SQVS")
+	if err == nil && ipAllowList != "" {
+		u.Config.IPAllowList = splitList(ipAllowList)
+	}
+	ipDenyList, err := c.GetenvString("SQVS_IP_DENY_LIST", "Comma separated list of CIDR blocks or addresses "+
+		"of the clients rejected by SQVS")
+	if err == nil && ipDenyList != "" {
+		u.Config.IPDenyList = splitList(ipDenyList)
+	}
+	for _, entry := range append(append([]string{}, u.Config.IPAllowList...), u.Config.IPDenyList...) {
+		if _, _, err := net.ParseCIDR(entry); err != nil && net.ParseIP(entry) == nil {
+			return errors.Errorf("SaveConfiguration() Invalid CIDR block or address %s in SQVS_IP_ALLOW_LIST or SQVS_IP_DENY_LIST", entry)
+		}
+	}
+
+	trustedProxies, err := c.GetenvString("SQVS_TRUSTED_PROXIES", "Comma separated list of CIDR blocks or "+
+		"addresses of the load balancers X-Forwarded-For is honored from")
+	if err == nil && trustedProxies != "" {
+		u.Config.TrustedProxies = splitList(trustedProxies)
+	}
+	for _, entry := range u.Config.TrustedProxies {
+		if _, _, err := net.ParseCIDR(entry); err != nil && net.ParseIP(entry) == nil {
+			return errors.Errorf("SaveConfiguration() Invalid CIDR block or address %s in SQVS_TRUSTED_PROXIES", entry)
+		}
+	}
+	proxyProtocol, err := c.GetenvString("SQVS_PROXY_PROTOCOL", "Boolean value to require a PROXY protocol v2 "+
+		"header from the trusted proxies")
+	if err == nil && proxyProtocol != "" {
+		u.Config.ProxyProtocol, err = strconv.ParseBool(proxyProtocol)
+		if err != nil {
+			fmt.Fprintf(u.ConsoleWriter, "SQVS_PROXY_PROTOCOL is not defined properly, must be true/false. PROXY protocol will be disabled\n")
+			u.Config.ProxyProtocol = false
+		}
+	}
+	reusePort, err := c.GetenvString("SQVS_REUSE_PORT", "Boolean value to listen with SO_REUSEPORT, so "+
+		"another SQVS binary can listen on the same port during an upgrade")
+	if err == nil && reusePort != "" {
+		u.Config.ReusePort, err = strconv.ParseBool(reusePort)
+		if err != nil {
+			fmt.Fprintf(u.ConsoleWriter, "SQVS_REUSE_PORT is not defined properly, must be true/false. SO_REUSEPORT will not be used\n")
+			u.Config.ReusePort = false
+		}
+	}
+	egressAllowList, err := c.GetenvString("SQVS_EGRESS_ALLOW_LIST", "Comma separated list of host names, *. "+
+		"domains, addresses or CIDR blocks SQVS may connect to")
+	if err == nil && egressAllowList != "" {
+		u.Config.EgressAllowList = splitList(egressAllowList)
+	}
+	if _, err := egress.Parse(u.Config.EgressAllowList); err != nil {
+		return errors.Wrap(err, "SaveConfiguration() Invalid SQVS_EGRESS_ALLOW_LIST")
+	}
+	dnsHostOverrides, err := c.GetenvString("SQVS_DNS_HOST_OVERRIDES", "Comma separated list of host=address "+
+		"entries pinning dependency host names to addresses")
+	if err == nil && dnsHostOverrides != "" {
+		u.Config.DNS.HostOverrides = splitList(dnsHostOverrides)
+	}
+	dnsResolver, err := c.GetenvString("SQVS_DNS_RESOLVER", "Resolver of the dependency host names, system or go")
+	if err == nil && dnsResolver != "" {
+		u.Config.DNS.Resolver = strings.ToLower(strings.TrimSpace(dnsResolver))
+	}
+	dnsServers, err := c.GetenvString("SQVS_DNS_SERVERS", "Comma separated list of the DNS servers, address[:port], "+
+		"queried by the go resolver")
+	if err == nil && dnsServers != "" {
+		u.Config.DNS.Servers = splitList(dnsServers)
+	}
+	u.Config.DNS.CacheTTL = u.getenvDuration(c, "SQVS_DNS_CACHE_TTL", "Time resolved dependency addresses are "+
+		"reused for", u.Config.DNS.CacheTTL)
+	if _, err := resolver.New(u.Config.DNS.HostOverrides, u.Config.DNS.Resolver, u.Config.DNS.Servers,
+		u.Config.DNS.CacheTTL); err != nil {
+		return errors.Wrap(err, "SaveConfiguration() Invalid DNS configuration")
+	}
+
+	listenAddress, err := c.GetenvString("SQVS_LISTEN_ADDRESS", "IPv4 or IPv6 address or host name SQVS listens on")
+	if err == nil && listenAddress != "" {
+		u.Config.ListenAddress = netfamily.StripBrackets(listenAddress)
+	}
+	addressFamily, err := c.GetenvString("SQVS_ADDRESS_FAMILY", "Address family SQVS listens on and connects "+
+		"over, ipv4, ipv6, prefer-ipv4 or prefer-ipv6")
+	if err == nil && addressFamily != "" {
+		u.Config.AddressFamily = strings.ToLower(strings.TrimSpace(addressFamily))
+	}
+	if err := netfamily.Validate(u.Config.AddressFamily); err != nil {
+		return errors.Wrap(err, "SaveConfiguration() Invalid SQVS_ADDRESS_FAMILY")
+	}
+	if ip := net.ParseIP(u.Config.ListenAddress); ip != nil {
+		if (u.Config.AddressFamily == netfamily.IPv4 && ip.To4() == nil) ||
+			(u.Config.AddressFamily == netfamily.IPv6 && ip.To4() != nil) {
+			return errors.Errorf("SaveConfiguration() SQVS_LISTEN_ADDRESS %s is not an address of the %s family",
+				u.Config.ListenAddress, u.Config.AddressFamily)
+		}
+	}
+
+	crlURLOverrides, err := c.GetenvString("SQVS_CRL_URL_OVERRIDES", "Comma separated list of processor=<url> "+
+		"or platform=<url> entries overriding the CRL distribution point of the PCK CAs")
+	if err == nil && crlURLOverrides != "" {
+		u.Config.CrlURLOverrides = splitList(crlURLOverrides)
+	}
+	if _, err := parser.ParseCrlOverrides(u.Config.CrlURLOverrides); err != nil {
+		return errors.Wrap(err, "SaveConfiguration() Invalid SQVS_CRL_URL_OVERRIDES")
+	}
+
+	corsAllowedOrigins, err := c.GetenvString("SQVS_CORS_ALLOWED_ORIGINS", "Comma separated list of origins "+
+		"allowed to make cross-origin requests")
+	if err == nil && corsAllowedOrigins != "" {
+		u.Config.CorsAllowedOrigins = splitList(corsAllowedOrigins)
+	}
+
+	corsAllowedMethods, err := c.GetenvString("SQVS_CORS_ALLOWED_METHODS", "Comma separated list of methods "+
+		"allowed in cross-origin requests")
+	if err == nil && corsAllowedMethods != "" {
+		u.Config.CorsAllowedMethods = splitList(corsAllowedMethods)
+	} else if len(u.Config.CorsAllowedMethods) == 0 {
+		u.Config.CorsAllowedMethods = splitList(constants.DefaultCorsAllowedMethods)
+	}
+
+	corsAllowedHeaders, err := c.GetenvString("SQVS_CORS_ALLOWED_HEADERS", "Comma separated list of headers "+
+		"allowed in cross-origin requests")
+	if err == nil && corsAllowedHeaders != "" {
+		u.Config.CorsAllowedHeaders = splitList(corsAllowedHeaders)
+	} else if len(u.Config.CorsAllowedHeaders) == 0 {
+		u.Config.CorsAllowedHeaders = splitList(constants.DefaultCorsAllowedHeaders)
+	}
+
+	keyStoreType, err := c.GetenvString("SQVS_KEYSTORE_TYPE", "Backend TLS and signing keys are loaded from")
+	if err == nil && keyStoreType != "" {
+		if !keystore.IsValidType(keyStoreType) {
+			return errors.New("SaveConfiguration() SQVS_KEYSTORE_TYPE must be one of file, vault-kv, vault-transit, pkcs11")
+		}
+		u.Config.KeyStore.Type = keyStoreType
+	} else if u.Config.KeyStore.Type == "" {
+		u.Config.KeyStore.Type = keystore.TypeFile
+	}
+
+	keyStoreEnv := []struct {
+		name        string
+		description string
+		value       *string
+	}{
+		{"SQVS_SIGNING_KEY_ID", "Key store ID of the response signing key", &u.Config.KeyStore.SigningKeyID},
+		{"SQVS_TLS_KEY_ID", "Key store ID of the TLS key", &u.Config.KeyStore.TLSKeyID},
+		{"SQVS_VAULT_ADDR", "Vault server address", &u.Config.KeyStore.VaultAddress},
+		{"SQVS_VAULT_MOUNT", "Vault KV or transit secrets engine mount path", &u.Config.KeyStore.VaultMount},
+		{"SQVS_VAULT_TOKEN_FILE", "File holding the Vault token", &u.Config.KeyStore.VaultTokenFile},
+		{"SQVS_PKCS11_MODULE", "PKCS#11 module path", &u.Config.KeyStore.PKCS11Module},
+		{"SQVS_PKCS11_TOKEN_LABEL", "PKCS#11 token label", &u.Config.KeyStore.PKCS11TokenLabel},
+		{"SQVS_PKCS11_PIN_FILE", "File holding the PKCS#11 user PIN", &u.Config.KeyStore.PKCS11PinFile},
+	}
+	for _, env := range keyStoreEnv {
+		value, err := c.GetenvString(env.name, env.description)
+		if err == nil && value != "" {
+			*env.value = value
+		}
+	}
+	tlsSecondaryCertFile, err := c.GetenvString("SQVS_TLS_SECONDARY_CERT_FILE", "Second TLS certificate chain, "+
+		"of the other key algorithm than the TLS certificate")
+	if err == nil && tlsSecondaryCertFile != "" {
+		u.Config.TLSSecondaryCertFile = tlsSecondaryCertFile
+	}
+	tlsSecondaryKeyID, err := c.GetenvString("SQVS_TLS_SECONDARY_KEY_ID", "Key store ID of the key of the second "+
+		"TLS certificate chain")
+	if err == nil && tlsSecondaryKeyID != "" {
+		u.Config.TLSSecondaryKeyID = tlsSecondaryKeyID
+	}
+	if (u.Config.TLSSecondaryCertFile == "") != (u.Config.TLSSecondaryKeyID == "") {
+		return errors.New("SaveConfiguration() SQVS_TLS_SECONDARY_CERT_FILE and SQVS_TLS_SECONDARY_KEY_ID must be set together")
+	}
+	if u.Config.KeyStore.VaultAddress != "" {
+		if _, err = url.ParseRequestURI(u.Config.KeyStore.VaultAddress); err != nil {
+			return errors.Wrap(err, "SaveConfiguration() SQVS_VAULT_ADDR provided is invalid")
+		}
+	}
+	u.Config.SigningKeyOverlap = u.getenvDuration(c, "SQVS_SIGNING_KEY_OVERLAP",
+		"Time a rotated response signing key remains available for verification", constants.DefaultSigningKeyOverlap)
+	u.Config.ResultValidity = u.getenvDuration(c, "SQVS_RESULT_VALIDITY",
+		"Time verification results are valid for unless the collateral is updated earlier", 0)
+
+	customClaimsFile, err := c.GetenvString("SQVS_CUSTOM_CLAIMS_FILE", "File holding the custom claims of signed responses")
+	if err == nil && customClaimsFile != "" {
+		u.Config.CustomClaimsFile = customClaimsFile
+	} else if u.Config.CustomClaimsFile == "" {
+		u.Config.CustomClaimsFile = constants.CustomClaimsFile
+	}
+
 	scsBaseUrl, err := c.GetenvString("SCS_BASE_URL", "SGX Caching Service URL")
 	if err == nil && scsBaseUrl != "" {
 		if _, err = url.ParseRequestURI(scsBaseUrl); err != nil {
@@ -164,6 +1193,17 @@ func (u Update_Service_Config) Run(c setup.Context) error {
 		return errors.Wrap(errors.New("AAS_API_URL is not defined in environment"), "SaveConfiguration() ENV variable not found")
 	}
 
+	sgxRootKeyPins, err := c.GetenvString("SQVS_SGX_ROOT_KEY_PINS", "Comma separated list of the hex encoded "+
+		"SHA-256 digests of the public keys the Intel SGX root CA may have")
+	if err == nil && sgxRootKeyPins != "" {
+		u.Config.SGXRootKeyPins = splitList(sgxRootKeyPins)
+	}
+	for _, pin := range u.Config.SGXRootKeyPins {
+		if value, err := hex.DecodeString(pin); err != nil || len(value) != sha256.Size {
+			return errors.Errorf("SaveConfiguration() SQVS_SGX_ROOT_KEY_PINS entry %s is not a hex encoded SHA-256 digest", pin)
+		}
+	}
+
 	trustedRootPath, err := c.GetenvString("SGX_TRUSTED_ROOT_CA_PATH", "SQVS Trusted Root CA")
 	if err == nil && trustedRootPath != "" {
 		trustedRoot, err := ioutil.ReadFile(trustedRootPath)
@@ -174,6 +1214,14 @@ func (u Update_Service_Config) Run(c setup.Context) error {
 		if block == nil {
 			return errors.New("SaveConfiguration: Pem Decode error")
 		}
+		rootCA, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return errors.Wrap(err, "SaveConfiguration: Error parsing SGX root cert")
+		}
+		err = verifier.VerifyRootKeyPin(rootCA, u.Config.SGXRootKeyPins)
+		if err != nil {
+			return errors.Wrap(err, "SaveConfiguration: SGX root cert does not match SQVS_SGX_ROOT_KEY_PINS")
+		}
 		err = ioutil.WriteFile(u.TrustedSGXRootCAFilePath, trustedRoot, 0640)
 		if err != nil {
 			return errors.New("SaveConfiguration: Error writing SGX root cert to file: " + err.Error())
@@ -209,6 +1257,26 @@ func (u Update_Service_Config) Run(c setup.Context) error {
 		u.Config.UsePSSPadding = false
 	}
 
+	resultSigningAlgorithm, err := c.GetenvString("SQVS_RESULT_SIGNING_ALGORITHM", "Algorithm signed results "+
+		"are signed with, RS384, PS384, ES384 or EdDSA")
+	if err == nil && resultSigningAlgorithm != "" {
+		if !signingkey.IsSupportedAlgorithm(resultSigningAlgorithm) {
+			return errors.Errorf("SaveConfiguration() SQVS_RESULT_SIGNING_ALGORITHM provided is invalid, must be "+
+				"one of %s", strings.Join(signingkey.Algorithms, ", "))
+		}
+		u.Config.ResultSigningAlgorithm = resultSigningAlgorithm
+	}
+	resultSigningKeys, err := c.GetenvString("SQVS_RESULT_SIGNING_KEYS", "Comma separated list of "+
+		"<algorithm>=<key ID>:<certificate file> signing keys dedicated to an algorithm")
+	if err == nil && resultSigningKeys != "" {
+		u.Config.ResultSigningKeys = splitList(resultSigningKeys)
+	}
+	for _, entry := range u.Config.ResultSigningKeys {
+		if _, err := signingkey.ParseAlgorithmKey(entry); err != nil {
+			return errors.Wrap(err, "SaveConfiguration() Invalid SQVS_RESULT_SIGNING_KEYS")
+		}
+	}
+
 	u.Config.ResponseSigningKeyLength, err = c.GetenvInt("RESPONSE_SIGNING_KEY_LENGTH", "Response signing key length")
 	if err == nil {
 		switch u.Config.ResponseSigningKeyLength {
@@ -231,3 +1299,41 @@ func (u Update_Service_Config) Run(c setup.Context) error {
 func (u Update_Service_Config) Validate(c setup.Context) error {
 	return nil
 }
+
+// getenvDuration reads a duration from the environment, falling back to the default when it is unset or invalid
+func (u Update_Service_Config) getenvDuration(c setup.Context, name, description string, defaultValue time.Duration) time.Duration {
+	value, err := c.GetenvString(name, description)
+	if err != nil {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		fmt.Fprintf(u.ConsoleWriter, "Invalid duration provided for %s setting it to the default value\n", name)
+		return defaultValue
+	}
+	return duration
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// splitDurationList splits a comma separated list of positive durations
+func splitDurationList(list string) ([]time.Duration, error) {
+	var durations []time.Duration
+	for _, item := range splitList(list) {
+		duration, err := time.ParseDuration(item)
+		if err != nil || duration <= 0 {
+			return nil, errors.Errorf("%q is not a positive duration", item)
+		}
+		durations = append(durations, duration)
+	}
+	return durations, nil
+}
@@ -128,6 +128,41 @@ func TestServerSetupEnv(t *testing.T) {
 	assert.Equal(t, 12000, c.Port)
 }
 
+func TestServerSetupCorsEnv(t *testing.T) {
+
+	os.Setenv("AAS_API_URL", "https://localhost")
+	os.Setenv("SCS_BASE_URL", "https://localhost")
+	os.Setenv("SQVS_CORS_ALLOWED_ORIGINS", "https://dashboard.example.com, https://ui.example.com")
+	defer os.Clearenv()
+
+	c := config.Configuration{}
+
+	s := Update_Service_Config{
+		Flags:                    nil,
+		Config:                   &c,
+		ConsoleWriter:            os.Stdout,
+		TrustedSGXRootCAFilePath: rootCACertFile,
+	}
+
+	err := testGetRootCACert()
+	if err != nil {
+		t.Error("Cert generation failed")
+	}
+	defer func() {
+		_ = os.Remove(rootCACertFile)
+	}()
+	_ = os.Setenv("SGX_TRUSTED_ROOT_CA_PATH", rootCACertFile)
+
+	ctx := setup.Context{}
+	err = s.Run(ctx)
+	if err != nil {
+		assert.Contains(t, err.Error(), config.ErrNoConfigFile.Error())
+	}
+	assert.Equal(t, []string{"https://dashboard.example.com", "https://ui.example.com"}, c.CorsAllowedOrigins)
+	assert.Equal(t, []string{"GET", "POST"}, c.CorsAllowedMethods)
+	assert.Equal(t, []string{"Accept", "Authorization", "Content-Type"}, c.CorsAllowedHeaders)
+}
+
 func TestServerSetupInvalidAASUrl(t *testing.T) {
 	os.Setenv("AAS_API_URL", "invalidurl")
 	os.Setenv("SCS_BASE_URL", "http://localhost:12000/scs/v1")
@@ -0,0 +1,167 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package tlspolicy
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	commLog "intel/isecl/lib/common/v4/log"
+	"intel/isecl/sqvs/v4/config"
+	"intel/isecl/sqvs/v4/constants"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var log = commLog.GetDefaultLogger()
+
+// Modes of the certificate checks
+const (
+	ModeEnforce = "enforce"
+	ModeWarn    = "warn"
+	ModeOff     = "off"
+)
+
+// weakSignatureAlgorithms are the signature algorithms certificates signed with are not trusted, MD5 and SHA-1
+// being broken for collisions
+var weakSignatureAlgorithms = map[x509.SignatureAlgorithm]bool{
+	x509.UnknownSignatureAlgorithm: true,
+	x509.MD2WithRSA:                true,
+	x509.MD5WithRSA:                true,
+	x509.SHA1WithRSA:               true,
+	x509.DSAWithSHA1:               true,
+	x509.DSAWithSHA256:             true,
+	x509.ECDSAWithSHA1:             true,
+}
+
+// ValidateMode checks the mode of the configuration, empty being warn
+func ValidateMode(mode string) error {
+	switch mode {
+	case "", ModeEnforce, ModeWarn, ModeOff:
+		return nil
+	}
+	return errors.Errorf("tlspolicy/tlspolicy:ValidateMode() Unsupported mode %s, must be %s, %s or %s", mode,
+		ModeEnforce, ModeWarn, ModeOff)
+}
+
+// Violations describes how the certificate falls short of the policy at now, none when it complies
+func Violations(cert *x509.Certificate, conf config.TLSPolicyConfig, now time.Time) []string {
+	minRSA, minECDSA := conf.MinRSAKeySize, conf.MinECDSAKeySize
+	if minRSA <= 0 {
+		minRSA = constants.DefaultTLSMinRSAKeySize
+	}
+	if minECDSA <= 0 {
+		minECDSA = constants.DefaultTLSMinECDSAKeySize
+	}
+
+	var violations []string
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if size := key.N.BitLen(); size < minRSA {
+			violations = append(violations, fmt.Sprintf("RSA key of %d bits, below %d", size, minRSA))
+		}
+	case *ecdsa.PublicKey:
+		if size := key.Curve.Params().BitSize; size < minECDSA {
+			violations = append(violations, fmt.Sprintf("ECDSA key of %d bits, below %d", size, minECDSA))
+		}
+	case ed25519.PublicKey:
+	default:
+		violations = append(violations, fmt.Sprintf("unsupported %s key", cert.PublicKeyAlgorithm))
+	}
+	// the signature of a self-signed root is not relied on, it is trusted as is
+	if weakSignatureAlgorithms[cert.SignatureAlgorithm] && !bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+		violations = append(violations, "weak signature algorithm "+cert.SignatureAlgorithm.String())
+	}
+	switch {
+	case now.Before(cert.NotBefore):
+		violations = append(violations, "not valid before "+cert.NotBefore.UTC().Format(time.RFC3339))
+	case !now.Before(cert.NotAfter):
+		violations = append(violations, "expired on "+cert.NotAfter.UTC().Format(time.RFC3339))
+	case conf.MinValidity > 0 && cert.NotAfter.Sub(now) < conf.MinValidity:
+		violations = append(violations, fmt.Sprintf("expires on %s, in less than %v",
+			cert.NotAfter.UTC().Format(time.RFC3339), conf.MinValidity))
+	}
+	return violations
+}
+
+// Check lists the violations of the policy by the TLS certificate chains and the trusted CA certificates of
+// caDir, each prefixed with the certificate it applies to
+func Check(conf config.TLSPolicyConfig, chains []tls.Certificate, caDir string, now time.Time) ([]string, error) {
+	var violations []string
+	describe := func(source string, cert *x509.Certificate) {
+		for _, v := range Violations(cert, conf, now) {
+			violations = append(violations, fmt.Sprintf("%s %q: %s", source, cert.Subject.CommonName, v))
+		}
+	}
+	for _, chain := range chains {
+		for i, der := range chain.Certificate {
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				return nil, errors.Wrap(err, "tlspolicy/tlspolicy:Check() Invalid TLS certificate")
+			}
+			source := "TLS certificate"
+			if i > 0 {
+				source = "TLS issuer certificate"
+			}
+			describe(source, cert)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(caDir, "*.pem"))
+	if err != nil {
+		return nil, errors.Wrap(err, "tlspolicy/tlspolicy:Check() Error listing trusted CA certificates")
+	}
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "tlspolicy/tlspolicy:Check() Error reading trusted CA certificate %s", file)
+		}
+		for block, rest := pem.Decode(content); block != nil; block, rest = pem.Decode(rest) {
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				log.WithError(err).Warnf("tlspolicy/tlspolicy:Check() Invalid trusted CA certificate in %s", file)
+				continue
+			}
+			describe("trusted CA "+filepath.Base(file), cert)
+		}
+	}
+	return violations, nil
+}
+
+// Validate checks the TLS certificate chains and the trusted CA certificates of caDir against the policy. The
+// violations are returned as an error when the policy is enforced and logged otherwise.
+func Validate(conf config.TLSPolicyConfig, chains []tls.Certificate, caDir string) error {
+	err := ValidateMode(conf.Mode)
+	if err != nil || conf.Mode == ModeOff {
+		return err
+	}
+	violations, err := Check(conf, chains, caDir, time.Now())
+	if err != nil {
+		return err
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	if conf.Mode == ModeEnforce {
+		return errors.Errorf("tlspolicy/tlspolicy:Validate() Certificates below the TLS policy, %s",
+			strings.Join(violations, "; "))
+	}
+	for _, v := range violations {
+		log.Warnf("tlspolicy/tlspolicy:Validate() Certificate below the TLS policy, %s", v)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package tlspolicy
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"intel/isecl/sqvs/v4/config"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func newCert(t *testing.T, key crypto.Signer, notAfter time.Time, alg x509.SignatureAlgorithm) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber:       big.NewInt(1),
+		Subject:            pkix.Name{CommonName: "sqvs"},
+		NotBefore:          now.Add(-time.Hour),
+		NotAfter:           notAfter,
+		SignatureAlgorithm: alg,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return cert
+}
+
+func TestViolations(t *testing.T) {
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p224, _ := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	rsa2048, _ := rsa.GenerateKey(rand.Reader, 2048)
+	conf := config.TLSPolicyConfig{MinValidity: 30 * 24 * time.Hour}
+	year := now.Add(365 * 24 * time.Hour)
+
+	assert.Empty(t, Violations(newCert(t, p256, year, x509.ECDSAWithSHA256), conf, now))
+	assert.Equal(t, []string{"ECDSA key of 224 bits, below 256"},
+		Violations(newCert(t, p224, year, x509.ECDSAWithSHA256), conf, now))
+	assert.Equal(t, []string{"RSA key of 2048 bits, below 3072"},
+		Violations(newCert(t, rsa2048, year, x509.SHA256WithRSA), conf, now))
+	assert.Empty(t, Violations(newCert(t, rsa2048, year, x509.SHA256WithRSA),
+		config.TLSPolicyConfig{MinRSAKeySize: 2048}, now))
+	assert.Equal(t, []string{"expires on 2021-06-08T00:00:00Z, in less than 720h0m0s"},
+		Violations(newCert(t, p256, now.Add(7*24*time.Hour), x509.ECDSAWithSHA256), conf, now))
+	assert.Equal(t, []string{"expired on 2021-05-31T23:30:00Z"},
+		Violations(newCert(t, p256, now.Add(-30*time.Minute), x509.ECDSAWithSHA256), conf, now))
+}
+
+func TestValidate(t *testing.T) {
+	p224, _ := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	cert := newCert(t, p224, time.Now().Add(time.Hour), x509.ECDSAWithSHA256)
+	dir, err := ioutil.TempDir("", "sqvs-tlspolicy")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "ca.pem"),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0600))
+
+	violations, err := Check(config.TLSPolicyConfig{}, []tls.Certificate{{Certificate: [][]byte{cert.Raw}}}, dir,
+		time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`TLS certificate "sqvs": ECDSA key of 224 bits, below 256`,
+		`trusted CA ca.pem "sqvs": ECDSA key of 224 bits, below 256`}, violations)
+
+	assert.Error(t, Validate(config.TLSPolicyConfig{Mode: ModeEnforce}, nil, dir))
+	assert.NoError(t, Validate(config.TLSPolicyConfig{Mode: ModeWarn}, nil, dir))
+	assert.NoError(t, Validate(config.TLSPolicyConfig{Mode: ModeOff}, nil, dir))
+	assert.Error(t, Validate(config.TLSPolicyConfig{Mode: "strict"}, nil, dir))
+}
@@ -0,0 +1,310 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package trustedtime
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"intel/isecl/sqvs/v4/netfamily"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Tags and signature contexts of the Roughtime protocol
+const (
+	tagSIG  uint32 = 0x00474953
+	tagNONC uint32 = 0x434e4f4e
+	tagPATH uint32 = 0x48544150
+	tagSREP uint32 = 0x50455253
+	tagCERT uint32 = 0x54524543
+	tagINDX uint32 = 0x58444e49
+	tagROOT uint32 = 0x544f4f52
+	tagMIDP uint32 = 0x5044494d
+	tagRADI uint32 = 0x49444152
+	tagDELE uint32 = 0x454c4544
+	tagMINT uint32 = 0x544e494d
+	tagMAXT uint32 = 0x5458414d
+	tagPUBK uint32 = 0x4b425550
+	tagPAD  uint32 = 0xff444150
+
+	roughtimeRequestSize = 1024
+	roughtimeNonceSize   = 64
+	roughtimeTimeout     = 5 * time.Second
+
+	responseContext   = "RoughTime v1 response signature\x00"
+	delegationContext = "RoughTime v1 delegation signature--\x00"
+)
+
+// RoughtimeClock is the local clock corrected by the time of a Roughtime server. The signed responses
+// of the server are verified against its long term public key.
+type RoughtimeClock struct {
+	server    string
+	publicKey ed25519.PublicKey
+	offset    time.Duration
+
+	mu     sync.RWMutex
+	synced bool
+	delta  time.Duration
+}
+
+// NewRoughtimeClock creates a clock for the Roughtime server, publicKey is its base64 encoded Ed25519 key
+func NewRoughtimeClock(server, publicKey string, offset time.Duration) (*RoughtimeClock, error) {
+	if server == "" {
+		return nil, errors.New("trustedtime/roughtime:NewRoughtimeClock() Roughtime server must be set")
+	}
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("trustedtime/roughtime:NewRoughtimeClock() Invalid Roughtime public key")
+	}
+	return &RoughtimeClock{server: server, publicKey: key, offset: offset}, nil
+}
+
+// Now returns the corrected time, an error is returned until the clock was synchronized once
+func (c *RoughtimeClock) Now() (time.Time, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if !c.synced {
+		return time.Time{}, errors.New("trustedtime/roughtime:Now() Clock is not synchronized with the Roughtime server")
+	}
+	return time.Now().Add(c.delta + c.offset).UTC(), nil
+}
+
+// Run synchronizes the clock every interval until stop is closed. The last correction is kept when
+// the server cannot be reached.
+func (c *RoughtimeClock) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			err := c.Sync()
+			if err != nil {
+				log.WithError(err).Warn("trustedtime/roughtime:Run() Error synchronizing with Roughtime server")
+			}
+		}
+	}
+}
+
+// Sync queries the Roughtime server and updates the correction of the local clock
+func (c *RoughtimeClock) Sync() error {
+	log.Trace("trustedtime/roughtime:Sync() Entering")
+	defer log.Trace("trustedtime/roughtime:Sync() Leaving")
+
+	nonce := make([]byte, roughtimeNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return errors.Wrap(err, "trustedtime/roughtime:Sync() Error generating nonce")
+	}
+	request, err := encodeMessage(map[uint32][]byte{tagNONC: nonce}, roughtimeRequestSize)
+	if err != nil {
+		return err
+	}
+
+	conn, err := netfamily.DialContext(context.Background(), "udp", c.server)
+	if err != nil {
+		return errors.Wrap(err, "trustedtime/roughtime:Sync() Error connecting to Roughtime server")
+	}
+	defer conn.Close()
+	if err = conn.SetDeadline(time.Now().Add(roughtimeTimeout)); err != nil {
+		return errors.Wrap(err, "trustedtime/roughtime:Sync() Error setting deadline")
+	}
+
+	sent := time.Now()
+	if _, err = conn.Write(request); err != nil {
+		return errors.Wrap(err, "trustedtime/roughtime:Sync() Error sending request")
+	}
+	response := make([]byte, 4096)
+	n, err := conn.Read(response)
+	if err != nil {
+		return errors.Wrap(err, "trustedtime/roughtime:Sync() Error reading response")
+	}
+	received := time.Now()
+
+	midpoint, radius, err := verifyResponse(response[:n], nonce, c.publicKey)
+	if err != nil {
+		return err
+	}
+	// the server time was taken between sending the request and receiving the response
+	local := sent.Add(received.Sub(sent) / 2)
+	log.Debugf("trustedtime/roughtime:Sync() Roughtime server time %s, radius %s", midpoint, radius)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.delta = midpoint.Sub(local)
+	c.synced = true
+	return nil
+}
+
+// verifyResponse checks the signatures of the response and that it answers the nonce, it returns the
+// time of the server and its uncertainty
+func verifyResponse(response, nonce []byte, publicKey ed25519.PublicKey) (time.Time, time.Duration, error) {
+	msg, err := decodeMessage(response)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	cert, err := decodeMessage(msg[tagCERT])
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	dele, err := decodeMessage(cert[tagDELE])
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	if !ed25519.Verify(publicKey, append([]byte(delegationContext), cert[tagDELE]...), cert[tagSIG]) {
+		return time.Time{}, 0, errors.New("trustedtime/roughtime:verifyResponse() Invalid delegation signature")
+	}
+	delegatedKey := dele[tagPUBK]
+	if len(delegatedKey) != ed25519.PublicKeySize {
+		return time.Time{}, 0, errors.New("trustedtime/roughtime:verifyResponse() Invalid delegated key")
+	}
+	if !ed25519.Verify(delegatedKey, append([]byte(responseContext), msg[tagSREP]...), msg[tagSIG]) {
+		return time.Time{}, 0, errors.New("trustedtime/roughtime:verifyResponse() Invalid response signature")
+	}
+
+	srep, err := decodeMessage(msg[tagSREP])
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	if !verifyMerklePath(nonce, msg[tagPATH], msg[tagINDX], srep[tagROOT]) {
+		return time.Time{}, 0, errors.New("trustedtime/roughtime:verifyResponse() Response does not answer the request")
+	}
+
+	midp, err := uint64Value(srep[tagMIDP])
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	radi, err := uint32Value(srep[tagRADI])
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	mint, err := uint64Value(dele[tagMINT])
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	maxt, err := uint64Value(dele[tagMAXT])
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	if midp < mint || midp > maxt {
+		return time.Time{}, 0, errors.New("trustedtime/roughtime:verifyResponse() Response time is outside the delegation validity")
+	}
+	// times are in microseconds since the epoch
+	return time.Unix(0, int64(midp)*int64(time.Microsecond)).UTC(), time.Duration(radi) * time.Microsecond, nil
+}
+
+// verifyMerklePath checks the nonce is a leaf of the Merkle tree whose root the server signed
+func verifyMerklePath(nonce, path, index, root []byte) bool {
+	idx, err := uint32Value(index)
+	if err != nil || len(path)%sha512.Size != 0 {
+		return false
+	}
+	hash := sha512.Sum512(append([]byte{0}, nonce...))
+	for i := 0; i < len(path); i += sha512.Size {
+		node := []byte{1}
+		if idx&1 == 0 {
+			node = append(append(node, hash[:]...), path[i:i+sha512.Size]...)
+		} else {
+			node = append(append(node, path[i:i+sha512.Size]...), hash[:]...)
+		}
+		hash = sha512.Sum512(node)
+		idx >>= 1
+	}
+	return bytes.Equal(hash[:], root)
+}
+
+// encodeMessage encodes the tags of a Roughtime message in ascending order, the message is padded to
+// size when it is not zero
+func encodeMessage(values map[uint32][]byte, size int) ([]byte, error) {
+	tags := make([]uint32, 0, len(values)+1)
+	length := 0
+	for tag, value := range values {
+		if len(value)%4 != 0 {
+			return nil, errors.Errorf("trustedtime/roughtime:encodeMessage() Value of tag %08x is not 4 byte aligned", tag)
+		}
+		tags = append(tags, tag)
+		length += len(value)
+	}
+	if size > 0 {
+		header := 4 + 8*(len(tags)+1) - 4
+		padding := size - header - length
+		if padding < 0 {
+			return nil, errors.New("trustedtime/roughtime:encodeMessage() Message exceeds padded size")
+		}
+		values[tagPAD] = make([]byte, padding)
+		tags = append(tags, tagPAD)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i] < tags[j] })
+
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(tags)))
+	offset := uint32(0)
+	for i, tag := range tags {
+		if i > 0 {
+			_ = binary.Write(&buf, binary.LittleEndian, offset)
+		}
+		offset += uint32(len(values[tag]))
+	}
+	for _, tag := range tags {
+		_ = binary.Write(&buf, binary.LittleEndian, tag)
+	}
+	for _, tag := range tags {
+		buf.Write(values[tag])
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeMessage decodes the tags of a Roughtime message
+func decodeMessage(msg []byte) (map[uint32][]byte, error) {
+	invalid := errors.New("trustedtime/roughtime:decodeMessage() Invalid Roughtime message")
+	if len(msg) < 4 {
+		return nil, invalid
+	}
+	count := int(binary.LittleEndian.Uint32(msg))
+	if count == 0 || count > len(msg)/8 {
+		return nil, invalid
+	}
+	header := 4 + 8*count - 4
+	if len(msg) < header {
+		return nil, invalid
+	}
+	values := make(map[uint32][]byte, count)
+	start := header
+	for i := 0; i < count; i++ {
+		end := len(msg)
+		if i < count-1 {
+			end = header + int(binary.LittleEndian.Uint32(msg[4+4*i:]))
+		}
+		if end < start || end > len(msg) {
+			return nil, invalid
+		}
+		tag := binary.LittleEndian.Uint32(msg[4+4*(count-1)+4*i:])
+		values[tag] = msg[start:end]
+		start = end
+	}
+	return values, nil
+}
+
+func uint32Value(value []byte) (uint32, error) {
+	if len(value) != 4 {
+		return 0, errors.New("trustedtime/roughtime:uint32Value() Invalid Roughtime value")
+	}
+	return binary.LittleEndian.Uint32(value), nil
+}
+
+func uint64Value(value []byte) (uint64, error) {
+	if len(value) != 8 {
+		return 0, errors.New("trustedtime/roughtime:uint64Value() Invalid Roughtime value")
+	}
+	return binary.LittleEndian.Uint64(value), nil
+}
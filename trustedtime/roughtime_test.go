@@ -0,0 +1,102 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package trustedtime
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func le32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	return b
+}
+
+func le64(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, v)
+	return b
+}
+
+// serveRoughtime answers a single request with the server time shifted by skew
+func serveRoughtime(t *testing.T, rootKey ed25519.PrivateKey, skew time.Duration) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	onlinePub, onlineKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	go func() {
+		defer conn.Close()
+		request := make([]byte, 2048)
+		n, addr, err := conn.ReadFrom(request)
+		if err != nil {
+			return
+		}
+		msg, err := decodeMessage(request[:n])
+		if err != nil || n < roughtimeRequestSize {
+			return
+		}
+		now := uint64(time.Now().Add(skew).UnixNano() / int64(time.Microsecond))
+		// a tree of two leaves, the request nonce is the right one
+		sibling := make([]byte, sha512.Size)
+		leaf := sha512.Sum512(append([]byte{0}, msg[tagNONC]...))
+		root := sha512.Sum512(append(append([]byte{1}, sibling...), leaf[:]...))
+
+		srep, _ := encodeMessage(map[uint32][]byte{tagROOT: root[:], tagMIDP: le64(now),
+			tagRADI: le32(1000000)}, 0)
+		dele, _ := encodeMessage(map[uint32][]byte{tagMINT: le64(now - 1e9), tagMAXT: le64(now + 1e9),
+			tagPUBK: onlinePub}, 0)
+		cert, _ := encodeMessage(map[uint32][]byte{tagDELE: dele,
+			tagSIG: ed25519.Sign(rootKey, append([]byte(delegationContext), dele...))}, 0)
+		response, _ := encodeMessage(map[uint32][]byte{tagSREP: srep, tagCERT: cert, tagPATH: sibling,
+			tagINDX: le32(1), tagSIG: ed25519.Sign(onlineKey, append([]byte(responseContext), srep...))}, 0)
+		_, _ = conn.WriteTo(response, addr)
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestRoughtimeClock(t *testing.T) {
+	rootPub, rootKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	clock, err := NewRoughtimeClock(serveRoughtime(t, rootKey, 24*time.Hour),
+		base64.StdEncoding.EncodeToString(rootPub), time.Hour)
+	assert.NoError(t, err)
+	_, err = clock.Now()
+	assert.Error(t, err)
+
+	assert.NoError(t, clock.Sync())
+	now, err := clock.Now()
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(25*time.Hour), now, time.Minute)
+
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	clock, err = NewRoughtimeClock(serveRoughtime(t, rootKey, 0), base64.StdEncoding.EncodeToString(otherPub), 0)
+	assert.NoError(t, err)
+	assert.Error(t, clock.Sync())
+}
+
+func TestRoughtimeMessage(t *testing.T) {
+	request, err := encodeMessage(map[uint32][]byte{tagNONC: make([]byte, roughtimeNonceSize)}, roughtimeRequestSize)
+	assert.NoError(t, err)
+	assert.Len(t, request, roughtimeRequestSize)
+
+	msg, err := decodeMessage(request)
+	assert.NoError(t, err)
+	assert.Len(t, msg[tagNONC], roughtimeNonceSize)
+
+	_, err = decodeMessage([]byte{2, 0, 0, 0, 0xff, 0xff, 0, 0})
+	assert.Error(t, err)
+}
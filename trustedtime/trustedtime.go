@@ -0,0 +1,83 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package trustedtime
+
+import (
+	commLog "intel/isecl/lib/common/v4/log"
+	"intel/isecl/sqvs/v4/config"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var log = commLog.GetDefaultLogger()
+
+const (
+	SourceSystem    = "system"
+	SourceRoughtime = "roughtime"
+)
+
+// Clock provides the current time certificate and collateral validity is checked against
+type Clock interface {
+	Now() (time.Time, error)
+}
+
+// SystemClock is the local clock shifted by an operator configured offset
+type SystemClock struct {
+	Offset time.Duration
+}
+
+func (c SystemClock) Now() (time.Time, error) {
+	return time.Now().Add(c.Offset).UTC(), nil
+}
+
+var (
+	defaultMu    sync.RWMutex
+	defaultClock Clock = SystemClock{}
+)
+
+// Default returns the clock shared by all validity checks
+func Default() Clock {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultClock
+}
+
+// SetDefault replaces the clock shared by all validity checks
+func SetDefault(c Clock) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultClock = c
+}
+
+// Now returns the current time of the default clock
+func Now() (time.Time, error) {
+	return Default().Now()
+}
+
+// New creates the clock selected in the configuration. The Roughtime clock is synchronized before New
+// returns and then every RoughtimeRefresh until stop is closed.
+func New(conf config.TrustedTimeConfig, stop <-chan struct{}) (Clock, error) {
+	log.Trace("trustedtime/trustedtime:New() Entering")
+	defer log.Trace("trustedtime/trustedtime:New() Leaving")
+
+	switch conf.Source {
+	case "", SourceSystem:
+		return SystemClock{Offset: conf.Offset}, nil
+	case SourceRoughtime:
+		clock, err := NewRoughtimeClock(conf.RoughtimeServer, conf.RoughtimePublicKey, conf.Offset)
+		if err != nil {
+			return nil, err
+		}
+		err = clock.Sync()
+		if err != nil {
+			return nil, errors.Wrap(err, "trustedtime/trustedtime:New() Error synchronizing with Roughtime server")
+		}
+		go clock.Run(conf.RoughtimeRefresh, stop)
+		return clock, nil
+	}
+	return nil, errors.Errorf("trustedtime/trustedtime:New() Unsupported time source %s", conf.Source)
+}
@@ -0,0 +1,210 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package truststore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"intel/isecl/lib/clients/v4"
+	commLog "intel/isecl/lib/common/v4/log"
+	"intel/isecl/sqvs/v4/egress"
+	"intel/isecl/sqvs/v4/netfamily"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+var log = commLog.GetDefaultLogger()
+
+// stores holds the trust stores registered for use by HTTPClient, keyed by directory
+var stores sync.Map
+
+// modernCipherSuites are the TLS 1.2 cipher suites with forward secrecy and authenticated encryption, every
+// TLS 1.3 suite being such
+var modernCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+var legacyCiphersDisabled int32
+
+// DisableLegacyCiphers restricts the TLS 1.2 connections of the clients created from here on to the ECDHE AEAD
+// cipher suites, so dependencies cannot negotiate CBC or static RSA key exchange suites. Stores apply it on
+// their next reload.
+func DisableLegacyCiphers(disabled bool) {
+	var value int32
+	if disabled {
+		value = 1
+	}
+	atomic.StoreInt32(&legacyCiphersDisabled, value)
+}
+
+// clientTLSConfig returns the TLS configuration of the clients verifying servers against pool
+func clientTLSConfig(pool *x509.CertPool) *tls.Config {
+	conf := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    pool,
+	}
+	if atomic.LoadInt32(&legacyCiphersDisabled) == 1 {
+		conf.CipherSuites = modernCipherSuites
+	}
+	return conf
+}
+
+// Store is a CA certificate pool loaded from the PEM files in a directory. The pool and the HTTP
+// transport built on it are swapped atomically on Reload, so clients created from the store pick
+// up rotated CA certificates without being recreated.
+type Store struct {
+	dir       string
+	pool      atomic.Value
+	transport atomic.Value
+
+	mu        sync.Mutex
+	listeners []func()
+}
+
+// New creates a trust store from the PEM encoded certificates in dir
+func New(dir string) (*Store, error) {
+	s := &Store{dir: dir}
+	err := s.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Register makes the store the source of HTTP clients returned by HTTPClient for its directory
+func Register(s *Store) {
+	stores.Store(filepath.Clean(s.dir), s)
+}
+
+// HTTPClient returns a client trusting the CAs in dir. The client follows reloads of the trust store
+// registered for dir, without a registered store the directory is read once.
+func HTTPClient(dir string) (*http.Client, error) {
+	if s, ok := stores.Load(filepath.Clean(dir)); ok {
+		return s.(*Store).HTTPClient(), nil
+	}
+	client, err := clients.HTTPClientWithCADir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if transport, ok := client.Transport.(*http.Transport); ok {
+		transport.Proxy = egress.Proxy
+		transport.DialContext = netfamily.DialContext
+		if transport.TLSClientConfig != nil && atomic.LoadInt32(&legacyCiphersDisabled) == 1 {
+			transport.TLSClientConfig.CipherSuites = modernCipherSuites
+		}
+	}
+	return client, nil
+}
+
+// TLSConfig returns a TLS client configuration trusting the CAs in dir, for clients of protocols other
+// than HTTP. The certificate pool is the one current when TLSConfig is called.
+func TLSConfig(dir string) (*tls.Config, error) {
+	var pool *x509.CertPool
+	if s, ok := stores.Load(filepath.Clean(dir)); ok {
+		pool = s.(*Store).CertPool()
+	} else {
+		var err error
+		pool, _, err = loadCertPool(dir)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return clientTLSConfig(pool), nil
+}
+
+// Reload rebuilds the certificate pool from the directory and notifies the reload listeners.
+// The current pool is kept when the directory cannot be read.
+func (s *Store) Reload() error {
+	log.Trace("truststore/truststore:Reload() Entering")
+	defer log.Trace("truststore/truststore:Reload() Leaving")
+
+	pool, count, err := loadCertPool(s.dir)
+	if err != nil {
+		return err
+	}
+
+	old, _ := s.transport.Load().(*http.Transport)
+	s.pool.Store(pool)
+	s.transport.Store(&http.Transport{
+		Proxy:           egress.Proxy,
+		DialContext:     netfamily.DialContext,
+		TLSClientConfig: clientTLSConfig(pool),
+	})
+	if old != nil {
+		old.CloseIdleConnections()
+	}
+	log.Infof("truststore/truststore:Reload() Loaded %d certificates from %s", count, s.dir)
+
+	s.mu.Lock()
+	listeners := s.listeners
+	s.mu.Unlock()
+	for _, fn := range listeners {
+		fn()
+	}
+	return nil
+}
+
+// OnReload registers fn to be called after every successful reload
+func (s *Store) OnReload(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// Dir returns the directory the store is loaded from
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// CertPool returns the current certificate pool
+func (s *Store) CertPool() *x509.CertPool {
+	return s.pool.Load().(*x509.CertPool)
+}
+
+// HTTPClient returns a client verifying servers against the current certificate pool
+func (s *Store) HTTPClient() *http.Client {
+	return &http.Client{Transport: s}
+}
+
+// RoundTrip sends the request through the transport built on the current certificate pool
+func (s *Store) RoundTrip(req *http.Request) (*http.Response, error) {
+	return s.transport.Load().(*http.Transport).RoundTrip(req)
+}
+
+func loadCertPool(dir string) (*x509.CertPool, int, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "truststore/truststore:loadCertPool() Error listing certificate directory")
+	}
+
+	// Get the SystemCertPool, continue with an empty pool on error
+	pool, _ := x509.SystemCertPool()
+	if pool == nil {
+		pool = x509.NewCertPool()
+	}
+	count := 0
+	for _, file := range files {
+		certPem, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, 0, errors.Wrapf(err, "truststore/truststore:loadCertPool() Error reading certificate %s", file)
+		}
+		if !pool.AppendCertsFromPEM(certPem) {
+			log.Warnf("truststore/truststore:loadCertPool() No certificates found in %s", file)
+			continue
+		}
+		count++
+	}
+	return pool, count, nil
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package truststore
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeServerCert(t *testing.T, server *httptest.Server, dir string) {
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	err := ioutil.WriteFile(filepath.Join(dir, "server.pem"), certPem, 0600)
+	assert.NoError(t, err)
+}
+
+func TestStoreReloadTrustsNewCA(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "truststore")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store, err := New(dir)
+	assert.NoError(t, err)
+	client := store.HTTPClient()
+
+	_, err = client.Get(server.URL)
+	assert.Error(t, err)
+
+	writeServerCert(t, server, dir)
+	assert.NoError(t, store.Reload())
+
+	resp, err := client.Get(server.URL)
+	assert.NoError(t, err)
+	if resp != nil {
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp.Body.Close()
+	}
+}
+
+func TestWatchStoreReloadsOnChange(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "truststore")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store, err := New(dir)
+	assert.NoError(t, err)
+	reloaded := make(chan struct{}, 1)
+	store.OnReload(func() { reloaded <- struct{}{} })
+
+	stop := make(chan struct{})
+	defer close(stop)
+	assert.NoError(t, WatchStore(store, 50*time.Millisecond, stop))
+
+	writeServerCert(t, server, dir)
+	select {
+	case <-reloaded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("trust store was not reloaded")
+	}
+
+	_, err = store.HTTPClient().Get(server.URL)
+	assert.NoError(t, err)
+}
+
+func TestWatchFilesIgnoresOtherFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "truststore")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	changed := make(chan struct{}, 1)
+	stop := make(chan struct{})
+	defer close(stop)
+	assert.NoError(t, WatchFiles(dir, []string{"watched.json"}, 200*time.Millisecond, func() {
+		changed <- struct{}{}
+	}, stop))
+
+	// Writes of other files neither notify nor postpone the notification of the watched file
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "watched.json"), []byte("{}"), 0600))
+	deadline := time.After(5 * time.Second)
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-changed:
+			return
+		case <-ticker.C:
+			assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "store.json"), []byte("{}"), 0600))
+		case <-deadline:
+			t.Fatal("change of the watched file was not notified")
+		}
+	}
+}
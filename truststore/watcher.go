@@ -0,0 +1,116 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package truststore
+
+import (
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// Watch calls onChange when files in dir are created, written, removed or renamed. Bursts of events,
+// such as a certificate being replaced by a setup task, are coalesced into a single call made once
+// the directory has been quiet for delay. Watching stops when stop is closed.
+func Watch(dir string, delay time.Duration, onChange func(), stop <-chan struct{}) error {
+	return WatchFiles(dir, nil, delay, onChange, stop)
+}
+
+// WatchFiles is Watch restricted to the files of dir with the given names, changes of other files
+// neither call onChange nor postpone it. All the files are watched when names is empty.
+func WatchFiles(dir string, names []string, delay time.Duration, onChange func(), stop <-chan struct{}) error {
+	watched := make(map[string]bool, len(names))
+	for _, name := range names {
+		watched[name] = true
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errors.Wrap(err, "truststore/watcher:Watch() Error creating file system watcher")
+	}
+	err = watcher.Add(dir)
+	if err != nil {
+		_ = watcher.Close()
+		return errors.Wrapf(err, "truststore/watcher:Watch() Error watching %s", dir)
+	}
+
+	go func() {
+		defer func() {
+			cerr := watcher.Close()
+			if cerr != nil {
+				log.WithError(cerr).Error("Error closing file system watcher")
+			}
+		}()
+
+		timer := time.NewTimer(delay)
+		timer.Stop()
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+				if len(watched) > 0 && !watched[filepath.Base(event.Name)] {
+					continue
+				}
+				log.Debugf("truststore/watcher:Watch() %s changed: %s", dir, event)
+				timer.Reset(delay)
+			case werr, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.WithError(werr).Errorf("truststore/watcher:Watch() Error watching %s", dir)
+			case <-timer.C:
+				onChange()
+			case <-stop:
+				timer.Stop()
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// WatchStore reloads the store when its directory changes
+func WatchStore(s *Store, delay time.Duration, stop <-chan struct{}) error {
+	return Watch(s.Dir(), delay, func() {
+		err := s.Reload()
+		if err != nil {
+			log.WithError(err).Errorf("truststore/watcher:WatchStore() Failed to reload %s, keeping current certificates", s.Dir())
+		}
+	}, stop)
+}
+
+// ReloadableMiddleware wraps a middleware built from files on disk, such as the JWT token
+// authentication middleware which loads the trusted signing certificates when created.
+// Reload replaces the middleware for subsequent requests.
+type ReloadableMiddleware struct {
+	build   func() mux.MiddlewareFunc
+	current atomic.Value
+}
+
+func NewReloadableMiddleware(build func() mux.MiddlewareFunc) *ReloadableMiddleware {
+	m := &ReloadableMiddleware{build: build}
+	m.Reload()
+	return m
+}
+
+// Reload rebuilds the wrapped middleware
+func (m *ReloadableMiddleware) Reload() {
+	m.current.Store(m.build())
+}
+
+// Middleware returns a middleware delegating each request to the current wrapped middleware
+func (m *ReloadableMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.current.Load().(mux.MiddlewareFunc)(next).ServeHTTP(w, r)
+	})
+}
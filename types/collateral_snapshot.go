@@ -0,0 +1,24 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package types
+
+import "time"
+
+// CollateralSnapshot is a version of a collateral, TCB info or QE identity, as SCS returned it, kept so quotes
+// can be re-evaluated against the collateral that was current at a past time. Fmspc is empty for QE identities.
+type CollateralSnapshot struct {
+	ID                      string    `json:"id"`
+	Collateral              string    `json:"collateral"`
+	Fmspc                   string    `json:"fmspc,omitempty"`
+	TcbEvaluationDataNumber uint      `json:"tcbEvaluationDataNumber"`
+	IssueDate               time.Time `json:"issueDate"`
+	NextUpdate              time.Time `json:"nextUpdate"`
+	// Content and IssuerChain are the collateral JSON and the PEM encoded chain it is signed with
+	Content      string    `json:"content"`
+	IssuerChain  string    `json:"issuerChain"`
+	RecordedTime time.Time `json:"recordedTime"`
+}
+
+type CollateralSnapshots []CollateralSnapshot
@@ -0,0 +1,23 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package types
+
+import "time"
+
+// EnclaveIdentity is an enclave, identified by its MRENCLAVE and MRSIGNER, allowed to attest. Quotes of the
+// enclave must report the same ISVPRODID, an ISVSVN of at least IsvSvn and the Attributes under
+// AttributesMask. Measurements and attributes are hex encoded.
+type EnclaveIdentity struct {
+	MrEnclave      string    `json:"mrEnclave"`
+	MrSigner       string    `json:"mrSigner"`
+	IsvProdID      uint16    `json:"isvProdId"`
+	IsvSvn         uint16    `json:"isvSvn"`
+	Attributes     string    `json:"attributes"`
+	AttributesMask string    `json:"attributesMask"`
+	Description    string    `json:"description"`
+	RegisteredTime time.Time `json:"registeredTime"`
+}
+
+type EnclaveIdentities []EnclaveIdentity
@@ -0,0 +1,24 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package types
+
+import "time"
+
+// EnrolledPlatform is a platform, identified by its FMSPC and PCE ID, registered ahead of the verification of
+// its quotes along with the TCB info collateral pinned for it
+type EnrolledPlatform struct {
+	Fmspc        string    `json:"fmspc"`
+	PceID        string    `json:"pceId"`
+	Description  string    `json:"description"`
+	EnrolledTime time.Time `json:"enrolledTime"`
+	// TcbInfo and TcbInfoIssuerChain are the TCB info JSON and the PEM encoded chain it is signed with, as
+	// returned by SCS when the collateral was pinned
+	TcbInfo            string    `json:"tcbInfo,omitempty"`
+	TcbInfoIssuerChain string    `json:"tcbInfoIssuerChain,omitempty"`
+	TcbInfoNextUpdate  time.Time `json:"tcbInfoNextUpdate"`
+	PinnedTime         time.Time `json:"pinnedTime"`
+}
+
+type EnrolledPlatforms []EnrolledPlatform
@@ -0,0 +1,17 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package types
+
+import "time"
+
+// PlatformTcbStatus records the last TCB status observed for a platform during quote verification
+type PlatformTcbStatus struct {
+	PlatformID  string    `json:"platformId"`
+	Fmspc       string    `json:"fmspc"`
+	TcbStatus   string    `json:"tcbStatus"`
+	UpdatedTime time.Time `json:"updatedTime"`
+}
+
+type PlatformTcbStatuses []PlatformTcbStatus
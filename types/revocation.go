@@ -0,0 +1,23 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package types
+
+import "time"
+
+// Revocation invalidates signed quote verification results issued earlier, after an incident such as a
+// compromised signing key or bad collateral. A result is revoked when it matches every criterion set: it was
+// issued within [IssuedAfter, IssuedBefore), signed with KeyID or is the result of one of QuoteHashes, the
+// hex encoded SHA-256 or SHA-384 digests of the quotes.
+type Revocation struct {
+	ID           string     `json:"id"`
+	Reason       string     `json:"reason"`
+	RevokedTime  time.Time  `json:"revokedTime"`
+	IssuedAfter  *time.Time `json:"issuedAfter,omitempty"`
+	IssuedBefore *time.Time `json:"issuedBefore,omitempty"`
+	KeyID        string     `json:"keyId,omitempty"`
+	QuoteHashes  []string   `json:"quoteHashes,omitempty"`
+}
+
+type Revocations []Revocation
@@ -0,0 +1,16 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package types
+
+// Usage counts the verification requests of a tenant to a route over a period, a day (YYYY-MM-DD) or a
+// month (YYYY-MM)
+type Usage struct {
+	Tenant string `json:"tenant"`
+	Route  string `json:"route"`
+	Period string `json:"period"`
+	Count  int64  `json:"count"`
+}
+
+type Usages []Usage
@@ -0,0 +1,13 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package types
+
+import "time"
+
+// UsedToken records the use of a single-use token, such as a verification session token, until it expires
+type UsedToken struct {
+	ID          string    `json:"id"`
+	ExpiresTime time.Time `json:"expiresTime"`
+}
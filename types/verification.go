@@ -0,0 +1,36 @@
+/*
+ * Copyright (C) 2021 Intel Corporation
+ * SPDX-License-Identifier: BSD-3-Clause
+ */
+package types
+
+import "time"
+
+const (
+	VerificationStatusVerified = "verified"
+	VerificationStatusFailed   = "failed"
+)
+
+// Verification records the outcome of a quote verification request
+type Verification struct {
+	ID                  string    `json:"id"`
+	CreatedTime         time.Time `json:"createdTime"`
+	Status              string    `json:"status"`
+	Message             string    `json:"message"`
+	EnclaveIssuer       string    `json:"enclaveIssuer,omitempty"`
+	EnclaveMeasurement  string    `json:"enclaveMeasurement,omitempty"`
+	EnclaveIssuerProdID string    `json:"enclaveIssuerProdId,omitempty"`
+	IsvSvn              string    `json:"isvSvn,omitempty"`
+	TcbLevel            string    `json:"tcbLevel,omitempty"`
+	EnclaveDebugMode    bool      `json:"enclaveDebugMode"`
+	// ReverifiedFrom is the ID of the verification whose quote this one re-verified
+	ReverifiedFrom string `json:"reverifiedFrom,omitempty"`
+	// RelyingParty is the ID of the relying party that signed the request, RequestSignature its detached JWS
+	// of the request body
+	RelyingParty     string `json:"relyingParty,omitempty"`
+	RequestSignature string `json:"requestSignature,omitempty"`
+	// Quote is the encrypted base64 encoded raw quote, kept when raw quote retention is enabled
+	Quote string `json:"quote,omitempty"`
+}
+
+type Verifications []Verification
@@ -7,15 +7,49 @@ package version
 import (
 	"fmt"
 	"intel/isecl/sqvs/v4/constants"
+	"runtime"
 )
 
 var Version = ""
 var GitHash = ""
 var BuildDate = ""
 
+// SBOMHash is the hex encoded SHA-256 digest of the software bill of materials of the build, set at build time
+// like the version
+var SBOMHash = ""
+
 func GetVersion() string {
 	verStr := fmt.Sprintf("Service Name: %s\n", constants.ExplicitServiceName)
 	verStr = verStr + fmt.Sprintf("Version: %s-%s\n", Version, GitHash)
 	verStr = verStr + fmt.Sprintf("Build Date: %s\n", BuildDate)
+	verStr = verStr + fmt.Sprintf("Go Version: %s\n", runtime.Version())
+	if SBOMHash != "" {
+		verStr = verStr + fmt.Sprintf("SBOM SHA-256: %s\n", SBOMHash)
+	}
 	return verStr
 }
+
+// VersionInfo is the machine readable form of the service version
+type VersionInfo struct {
+	ServiceName string `json:"serviceName"`
+	Version     string `json:"version"`
+	GitHash     string `json:"gitHash"`
+	BuildDate   string `json:"buildDate"`
+	GoVersion   string `json:"goVersion"`
+	SBOMHash    string `json:"sbomHash,omitempty"`
+	// Features are the optional features enabled by the configuration of the instance, when known
+	Features []string `json:"features,omitempty"`
+	// FeatureFlags tell whether every feature flag of the instance is enabled, when known
+	FeatureFlags map[string]bool `json:"featureFlags,omitempty"`
+}
+
+func GetVersionInfo() VersionInfo {
+	return VersionInfo{
+		ServiceName: constants.ExplicitServiceName,
+		Version:     Version,
+		GitHash:     GitHash,
+		BuildDate:   BuildDate,
+		GoVersion:   runtime.Version(),
+		SBOMHash:    SBOMHash,
+	}
+}